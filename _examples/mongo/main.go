@@ -5,11 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/golibry/go-migrations/cli"
 	"github.com/golibry/go-migrations/execution/repository"
-	"github.com/golibry/go-migrations/migration"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -31,60 +29,35 @@ func main() {
 	}()
 
 	ctx := context.Background()
-	dirPath := createMigrationsDirPath()
-	dbDsn := getDbDsn()
-	dbName := getDbName()
+	loadEnvFile()
+	config, err := cli.ConfigFromEnv()
+	if err != nil {
+		panic(fmt.Errorf("invalid migrations configuration: %w", err))
+	}
 
+	dbName := getDbName()
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
-	opts := options.Client().ApplyURI(dbDsn).SetServerAPIOptions(serverAPI)
+	opts := options.Client().ApplyURI(getDbDsn()).SetServerAPIOptions(serverAPI)
 	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		panic(fmt.Errorf("failed to connect to migrations db: %w", err))
 	}
 
-	db := client.Database(dbName)
-
-	cli.Bootstrap(
-		ctx,
-		db,
-		os.Args[1:],
-		migration.NewAutoDirMigrationsRegistry(dirPath),
-		createMongoRepository(client, ctx, dbName),
-		dirPath,
-		nil,
-		os.Stdout,
-		os.Exit,
-		nil,
-	)
-}
-
-func createMigrationsDirPath() migration.MigrationsDirPath {
-	appBaseDir := os.Getenv("APP_BASE_DIR")
-
-	if appBaseDir == "" {
-		appBaseDir = "/go/src/migrations"
-	}
-
-	dirPath, err := migration.NewMigrationsDirPath(
-		filepath.Join(appBaseDir, "_examples/mongo/migrations"),
+	cli.BootstrapFromEnv(
+		ctx, client.Database(dbName), createMongoRepository(client, ctx, dbName, config.Table),
 	)
-
-	if err != nil {
-		panic(fmt.Errorf("invalid migrations path: %w", err))
-	}
-
-	return dirPath
 }
 
 func createMongoRepository(
 	client *mongo.Client,
 	ctx context.Context,
 	dbName string,
+	collectionName string,
 ) *repository.MongoHandler {
 	repo, err := repository.NewMongoHandler(
 		"",
 		dbName,
-		getCollectionName(),
+		collectionName,
 		ctx,
 		client,
 	)
@@ -97,34 +70,32 @@ func createMongoRepository(
 }
 
 func getDbName() string {
-	dbName := os.Getenv("MONGO_DATABASE")
-
-	if dbName == "" {
-		dbName = "migrations"
-	}
+	return mustGetEnv("MONGO_DATABASE")
+}
 
-	return dbName
+// getDbDsn Prepare the Mongo DSN. Running from the host machine, override MONGO_DSN in the
+// environment, since the one of the .env file targets the containers.
+func getDbDsn() string {
+	return mustGetEnv("MONGO_DSN")
 }
 
-func getCollectionName() string {
-	collectionName := os.Getenv("MONGO_MIGRATIONS_COLLECTION")
+// defaultEnvFile is the .env file of the project, relative to the example directory. It is the
+// single source of the configuration and of the DSN of the examples.
+const defaultEnvFile = "../../.env"
 
-	if collectionName == "" {
-		collectionName = "migrations"
+// loadEnvFile makes cli.ConfigFromEnv load the .env file of the project, unless
+// MIGRATIONS_ENV_FILE names another one
+func loadEnvFile() {
+	if os.Getenv(cli.EnvFileEnvVar) == "" {
+		_ = os.Setenv(cli.EnvFileEnvVar, defaultEnvFile)
 	}
-
-	return collectionName
 }
 
-// getDbDsn Prepare the Mongo DSN
-func getDbDsn() string {
-	dsn := os.Getenv("MONGO_DSN")
-
-	if dsn == "" {
-		// Needed if ran from the host machine because we are missing the env variables
-		// See pass and port in .env file
-		dsn = "mongodb://localhost:27017"
+// mustGetEnv returns the value of the environment variable, which the .env file provides
+func mustGetEnv(name string) string {
+	value := cli.EnvOrDefault(name, "")
+	if value == "" {
+		panic(fmt.Errorf("the %s environment variable is not set, see the .env file", name))
 	}
-
-	return dsn
+	return value
 }
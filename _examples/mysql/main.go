@@ -6,12 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 
 	_ "github.com/golibry/go-migrations/_examples/mysql/migrations"
 	"github.com/golibry/go-migrations/cli"
 	"github.com/golibry/go-migrations/execution/repository"
-	"github.com/golibry/go-migrations/migration"
 )
 
 func main() {
@@ -31,54 +29,26 @@ func main() {
 	}()
 
 	ctx := context.Background()
-	dirPath := createMigrationsDirPath()
-	dbDsn := getDbDsn()
-	db, err := sql.Open("mysql", dbDsn)
+	loadEnvFile()
+	config, err := cli.ConfigFromEnv()
 	if err != nil {
-		panic(fmt.Errorf("failed to connect to migrations db: %w", err))
-	}
-
-	cli.Bootstrap(
-		ctx,
-		db,
-		os.Args[1:],
-		migration.NewAutoDirMigrationsRegistry(dirPath),
-		createMysqlRepository(db, ctx),
-		dirPath,
-		nil,
-		os.Stdout,
-		os.Exit,
-		&cli.BootstrapSettings{
-			RunMigrationsExclusively: true,
-			RunLockFilesDirPath:      os.TempDir(),
-			MigrationsCmdLockName:    "my-app-migrations-lock",
-		},
-	)
-}
-
-func createMigrationsDirPath() migration.MigrationsDirPath {
-	appBaseDir := os.Getenv("APP_BASE_DIR")
-
-	if appBaseDir == "" {
-		appBaseDir = "/go/src/migrations"
+		panic(fmt.Errorf("invalid migrations configuration: %w", err))
 	}
 
-	dirPath, err := migration.NewMigrationsDirPath(
-		filepath.Join(appBaseDir, "_examples/mysql/migrations"),
-	)
-
+	db, err := sql.Open("mysql", getDbDsn())
 	if err != nil {
-		panic(fmt.Errorf("invalid migrations path: %w", err))
+		panic(fmt.Errorf("failed to connect to migrations db: %w", err))
 	}
 
-	return dirPath
+	cli.BootstrapFromEnv(ctx, db, createMysqlRepository(db, ctx, config.Table))
 }
 
 func createMysqlRepository(
 	db *sql.DB,
 	ctx context.Context,
+	tableName string,
 ) *repository.MysqlHandler {
-	repo, err := repository.NewMysqlHandler("", "migration_executions", ctx, db)
+	repo, err := repository.NewMysqlHandler("", tableName, ctx, db)
 
 	if err != nil {
 		panic(fmt.Errorf("failed to build executions repository: %w", err))
@@ -87,20 +57,29 @@ func createMysqlRepository(
 	return repo
 }
 
-// getDbDsn Prepare the Mysql DSN
+// getDbDsn Prepare the Mysql DSN. Running from the host machine, override MYSQL_DSN in the
+// environment, since the one of the .env file targets the containers.
 func getDbDsn() string {
-	dbName := os.Getenv("MYSQL_DATABASE")
-	dsn := os.Getenv("MYSQL_DSN")
+	return mustGetEnv("MYSQL_DSN")
+}
 
-	if dbName == "" {
-		dbName = "migrations"
-	}
+// defaultEnvFile is the .env file of the project, relative to the example directory. It is the
+// single source of the configuration and of the DSN of the examples.
+const defaultEnvFile = "../../.env"
 
-	if dsn == "" {
-		// Needed if ran from the host machine because we are missing the env variables
-		// See pass and port in .env file
-		dsn = "root:123456789@tcp(localhost:3306)/" + dbName
+// loadEnvFile makes cli.ConfigFromEnv load the .env file of the project, unless
+// MIGRATIONS_ENV_FILE names another one
+func loadEnvFile() {
+	if os.Getenv(cli.EnvFileEnvVar) == "" {
+		_ = os.Setenv(cli.EnvFileEnvVar, defaultEnvFile)
 	}
+}
 
-	return dsn
+// mustGetEnv returns the value of the environment variable, which the .env file provides
+func mustGetEnv(name string) string {
+	value := cli.EnvOrDefault(name, "")
+	if value == "" {
+		panic(fmt.Errorf("the %s environment variable is not set, see the .env file", name))
+	}
+	return value
 }
@@ -0,0 +1,163 @@
+// Package audit writes a structured record for each migration applied or rolled back, to
+// sinks outside the migrated database (syslog, journald or any io.Writer), for environments
+// where the executions table is not considered a sufficient audit trail.
+//
+// Records are produced by a handler.ExecutionListener, see NewListener.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golibry/go-migrations/handler"
+)
+
+// Statuses of an audit record
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// Record is the audit information about a migration Up()/Down() call
+type Record struct {
+	Time         time.Time
+	RunId        string
+	Version      uint64
+	Action       string
+	Forced       bool
+	Status       string
+	Duration     time.Duration
+	RowsAffected *int64
+	Error        string
+	Host         string
+	User         string
+}
+
+// NewRecord builds the audit record of an execution event
+func NewRecord(event handler.ExecutionEvent) Record {
+	record := Record{
+		Time:         time.Now(),
+		RunId:        event.RunId,
+		Action:       event.Direction,
+		Forced:       event.Forced,
+		Status:       StatusSuccess,
+		Duration:     event.Duration,
+		RowsAffected: event.Migration.RowsAffected,
+		Host:         hostName(),
+		User:         userName(),
+	}
+
+	if event.Migration.Migration != nil {
+		record.Version = event.Migration.Migration.Version()
+	}
+
+	if event.Err != nil {
+		record.Status = StatusFailure
+		record.Error = event.Err.Error()
+	}
+
+	return record
+}
+
+// Fields returns the record as ordered key/value pairs, the run ID, the error and the rows
+// affected being included only if set
+func (r Record) Fields() [][2]string {
+	var fields [][2]string
+	if r.RunId != "" {
+		fields = append(fields, [2]string{"run_id", r.RunId})
+	}
+
+	fields = append(
+		fields,
+		[2]string{"version", strconv.FormatUint(r.Version, 10)},
+		[2]string{"action", r.Action},
+		[2]string{"forced", strconv.FormatBool(r.Forced)},
+		[2]string{"status", r.Status},
+		[2]string{"duration_ms", strconv.FormatInt(r.Duration.Milliseconds(), 10)},
+	)
+
+	if r.RowsAffected != nil {
+		fields = append(fields, [2]string{"rows_affected", strconv.FormatInt(*r.RowsAffected, 10)})
+	}
+
+	fields = append(fields, [2]string{"host", r.Host}, [2]string{"user", r.User})
+
+	if r.Error != "" {
+		fields = append(fields, [2]string{"error", r.Error})
+	}
+
+	return fields
+}
+
+// String builds the single line, logfmt formatted, representation of the record
+func (r Record) String() string {
+	var parts []string
+	for _, field := range r.Fields() {
+		value := field[1]
+		if value == "" || strings.ContainsAny(value, " \"=\t\r\n") {
+			value = strconv.Quote(value)
+		}
+		parts = append(parts, field[0]+"="+value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Sink persists audit records
+type Sink interface {
+	Write(ctx context.Context, record Record) error
+}
+
+// NewListener builds a handler.ExecutionListener which writes an audit record to the sink
+// for each migration Up()/Down() call. If the sink fails, the migrations run is stopped.
+func NewListener(sink Sink) handler.ExecutionListener {
+	return func(ctx context.Context, event handler.ExecutionEvent) error {
+		if err := sink.Write(ctx, NewRecord(event)); err != nil {
+			return fmt.Errorf("failed to write audit record with error: %w", err)
+		}
+		return nil
+	}
+}
+
+// WriterSink writes each record as a timestamped line to an io.Writer (a file, for example)
+type WriterSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterSink builds a new WriterSink
+func NewWriterSink(writer io.Writer) *WriterSink {
+	return &WriterSink{writer: writer}
+}
+
+// Write implements the Sink.Write method
+func (s *WriterSink) Write(_ context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := fmt.Fprintf(
+		s.writer, "time=%s %s\n", record.Time.UTC().Format(time.RFC3339Nano), record,
+	)
+	return err
+}
+
+func hostName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+func userName() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditTestSuite struct {
+	suite.Suite
+}
+
+func TestAuditTestSuite(t *testing.T) {
+	suite.Run(t, new(AuditTestSuite))
+}
+
+type recordingSink struct {
+	records []Record
+	err     error
+}
+
+func (s *recordingSink) Write(_ context.Context, record Record) error {
+	s.records = append(s.records, record)
+	return s.err
+}
+
+func (suite *AuditTestSuite) TestItBuildsRecordsFromExecutionEvents() {
+	rows := int64(7)
+	record := NewRecord(
+		handler.ExecutionEvent{
+			Direction: handler.DirectionDown,
+			Forced:    true,
+			Migration: handler.ExecutedMigration{
+				Migration: migration.NewDummyMigration(123), RowsAffected: &rows,
+			},
+			Err:      errors.New("table \"users\" is locked"),
+			Duration: 1500 * time.Millisecond,
+			RunId:    "01J9Z8Q4X6V3N2M1K0H8G7F6E5",
+		},
+	)
+	record.Host = "db-runner"
+	record.User = "deploy"
+
+	suite.Assert().Equal(
+		`run_id=01J9Z8Q4X6V3N2M1K0H8G7F6E5 version=123 action=down forced=true status=failure`+
+			` duration_ms=1500 rows_affected=7 host=db-runner user=deploy error="table \"users\" is locked"`,
+		record.String(),
+	)
+}
+
+func (suite *AuditTestSuite) TestItWritesARecordPerHandledMigration() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	migrationsHandler, _ := handler.NewHandler(registry, &execution.InMemoryRepository{}, nil)
+
+	var buf bytes.Buffer
+	migrationsHandler.AddListener(NewListener(NewWriterSink(&buf)))
+	_, err := migrationsHandler.MigrateUp(context.Background(), handler.NumOfRuns(2))
+
+	suite.Require().NoError(err)
+	suite.Assert().Regexp(
+		`^time=\S+ run_id=\w{26} version=1 action=up forced=false status=success `+
+			`duration_ms=\d+ host=\S+ user=\S+\n`+
+			`time=\S+ run_id=\w{26} version=2 action=up .*\n$`,
+		buf.String(),
+	)
+}
+
+func (suite *AuditTestSuite) TestItStopsTheRunWhenTheSinkFails() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &execution.InMemoryRepository{}
+	migrationsHandler, _ := handler.NewHandler(registry, repo, nil)
+
+	sink := &recordingSink{err: errors.New("disk full")}
+	migrationsHandler.AddListener(NewListener(sink))
+	execs, err := migrationsHandler.MigrateUp(context.Background(), handler.NumOfRuns(2))
+
+	suite.Assert().ErrorContains(err, "failed to write audit record with error: disk full")
+	suite.Assert().Len(execs, 1)
+	suite.Assert().Len(sink.records, 1)
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+}
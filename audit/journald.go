@@ -0,0 +1,89 @@
+//go:build !windows && !plan9
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultJournaldSocketPath is the socket of the journald native protocol
+const DefaultJournaldSocketPath = "/run/systemd/journal/socket"
+
+// Journald priorities (syslog levels) used for the records
+const (
+	journaldPriorityErr  = 3
+	journaldPriorityInfo = 6
+)
+
+// JournaldSink writes each record as a structured journal entry, through the journald native
+// protocol. Record fields are sent as MIGRATION_* journal fields, so entries can be filtered
+// with journalctl (for example, journalctl MIGRATION_STATUS=failure).
+type JournaldSink struct {
+	socketPath string
+	identifier string
+}
+
+// NewJournaldSink builds a new JournaldSink. If socketPath is empty,
+// DefaultJournaldSocketPath is used. If identifier is empty, DefaultTag is used.
+func NewJournaldSink(socketPath, identifier string) *JournaldSink {
+	if socketPath == "" {
+		socketPath = DefaultJournaldSocketPath
+	}
+
+	if identifier == "" {
+		identifier = DefaultTag
+	}
+
+	return &JournaldSink{socketPath: socketPath, identifier: identifier}
+}
+
+// Write implements the Sink.Write method
+func (s *JournaldSink) Write(ctx context.Context, record Record) error {
+	priority := journaldPriorityInfo
+	if record.Status == StatusFailure {
+		priority = journaldPriorityErr
+	}
+
+	var entry bytes.Buffer
+	writeJournaldField(&entry, "MESSAGE", record.String())
+	writeJournaldField(&entry, "PRIORITY", strconv.Itoa(priority))
+	writeJournaldField(&entry, "SYSLOG_IDENTIFIER", s.identifier)
+	for _, field := range record.Fields() {
+		writeJournaldField(&entry, "MIGRATION_"+strings.ToUpper(field[0]), field[1])
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unixgram", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to journald with error: %w", err)
+	}
+
+	defer func(conn net.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	if _, err = conn.Write(entry.Bytes()); err != nil {
+		return fmt.Errorf("failed to write journal entry with error: %w", err)
+	}
+
+	return nil
+}
+
+// writeJournaldField serializes a field in the native protocol format. Values containing
+// new lines are written in the binary, length prefixed, format.
+func writeJournaldField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name + "=" + value + "\n")
+		return
+	}
+
+	buf.WriteString(name + "\n")
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
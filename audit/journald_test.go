@@ -0,0 +1,91 @@
+//go:build !windows && !plan9
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log/syslog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SinksTestSuite struct {
+	suite.Suite
+	socketPath string
+	conn       net.PacketConn
+}
+
+func TestSinksTestSuite(t *testing.T) {
+	suite.Run(t, new(SinksTestSuite))
+}
+
+func (suite *SinksTestSuite) SetupTest() {
+	// unix socket paths are limited in length, so the suite temp dir can't always be used
+	dir, err := os.MkdirTemp("", "audit")
+	suite.Require().NoError(err)
+	suite.T().Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	suite.socketPath = filepath.Join(dir, "sock")
+	suite.conn, err = net.ListenPacket("unixgram", suite.socketPath)
+	suite.Require().NoError(err)
+}
+
+func (suite *SinksTestSuite) TearDownTest() {
+	_ = suite.conn.Close()
+}
+
+func (suite *SinksTestSuite) read() string {
+	buf := make([]byte, 4096)
+	_ = suite.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := suite.conn.ReadFrom(buf)
+	suite.Require().NoError(err)
+	return string(buf[:n])
+}
+
+func (suite *SinksTestSuite) TestItWritesJournalEntries() {
+	sink := NewJournaldSink(suite.socketPath, "")
+	record := Record{Version: 12, Action: "up", Status: StatusFailure, Error: "line1\nline2"}
+
+	suite.Require().NoError(sink.Write(context.Background(), record))
+	entry := suite.read()
+
+	suite.Assert().Contains(entry, "PRIORITY=3\n")
+	suite.Assert().Contains(entry, "SYSLOG_IDENTIFIER="+DefaultTag+"\n")
+	suite.Assert().Contains(entry, "MIGRATION_VERSION=12\n")
+	suite.Assert().Contains(entry, "MIGRATION_STATUS=failure\n")
+
+	var length bytes.Buffer
+	_ = binary.Write(&length, binary.LittleEndian, uint64(len("line1\nline2")))
+	suite.Assert().Contains(entry, "MIGRATION_ERROR\n"+length.String()+"line1\nline2\n")
+}
+
+func (suite *SinksTestSuite) TestItFailsWhenJournaldIsNotAvailable() {
+	sink := NewJournaldSink(filepath.Join(suite.T().TempDir(), "missing"), "")
+	suite.Assert().ErrorContains(
+		sink.Write(context.Background(), Record{}), "failed to connect to journald",
+	)
+}
+
+func (suite *SinksTestSuite) TestItWritesSyslogMessages() {
+	sink, err := NewSyslogSink("unixgram", suite.socketPath, "")
+	suite.Require().NoError(err)
+	defer func() { _ = sink.Close() }()
+
+	record := Record{Version: 12, Action: "down", Status: StatusSuccess}
+	suite.Require().NoError(sink.Write(context.Background(), record))
+	message := suite.read()
+
+	// <priority> = facility (authpriv: 10) * 8 + severity (info: 6)
+	suite.Assert().True(strings.HasPrefix(message, "<86>"), message)
+	suite.Assert().Contains(message, DefaultTag)
+	suite.Assert().Contains(message, "version=12 action=down forced=false status=success")
+	suite.Assert().Equal(syslog.LOG_INFO|syslog.LOG_AUTHPRIV, syslog.Priority(86))
+}
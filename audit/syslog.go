@@ -0,0 +1,47 @@
+//go:build !windows && !plan9
+
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/syslog"
+)
+
+// DefaultTag is the syslog tag (and journald identifier) used when none is provided
+const DefaultTag = "go-migrations"
+
+// SyslogSink writes each record as a line to syslog, with the info priority for successful
+// executions and the err priority for failed ones
+type SyslogSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogSink connects to the syslog daemon at the given network address (for example
+// "udp", "logs.local:514"). If network is empty, the local syslog daemon is used. If tag is
+// empty, DefaultTag is used.
+func NewSyslogSink(network, raddr, tag string) (*SyslogSink, error) {
+	if tag == "" {
+		tag = DefaultTag
+	}
+
+	writer, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_AUTHPRIV, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog with error: %w", err)
+	}
+
+	return &SyslogSink{writer: writer}, nil
+}
+
+// Write implements the Sink.Write method
+func (s *SyslogSink) Write(_ context.Context, record Record) error {
+	if record.Status == StatusFailure {
+		return s.writer.Err(record.String())
+	}
+	return s.writer.Info(record.String())
+}
+
+// Close closes the connection to the syslog daemon
+func (s *SyslogSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// HandlerFactory builds the migrations handler of the cli (see handler.NewHandlerWithDB)
+type HandlerFactory func(
+	registry migration.MigrationsRegistry,
+	repository execution.Repository,
+	newExecutionPlan handler.ExecutionPlanBuilder,
+	db any,
+) (*handler.MigrationsHandler, error)
+
+// Option configures the cli application built by New
+type Option func(*App)
+
+// App is the cli application built by New. It holds the configuration Bootstrap is called
+// with, so new capabilities can be added as options without breaking the callers.
+type App struct {
+	db           any
+	args         []string
+	registry     migration.MigrationsRegistry
+	repository   execution.Repository
+	dirPath      migration.MigrationsDirPath
+	newHandler   HandlerFactory
+	outputWriter io.Writer
+	processExit  func(code int)
+	settings     *BootstrapSettings
+	commands     []CommandPlugin
+	modules      []Module
+}
+
+// New builds the cli application from the options. The executions repository (see
+// WithRepository) and the migrations directory (see WithMigrationsDir) are required, the
+// other options default to the command line arguments of the process, the migrations
+// registered to migration.DefaultRegistry, handler.NewHandlerWithDB, os.Stdout and os.Exit.
+//
+// Example:
+//
+//	cli.New(
+//		cli.WithDB(db),
+//		cli.WithRepository(repo),
+//		cli.WithMigrationsDir(dirPath),
+//		cli.WithSettings(&cli.BootstrapSettings{RunMigrationsExclusively: true}),
+//	).Run(ctx)
+func New(opts ...Option) *App {
+	app := &App{
+		args:         os.Args[1:],
+		outputWriter: os.Stdout,
+		processExit:  os.Exit,
+	}
+	for _, opt := range opts {
+		opt(app)
+	}
+	return app
+}
+
+// WithDB sets the database handle (or any other dependency) passed to the migrations
+func WithDB(db any) Option {
+	return func(app *App) {
+		app.db = db
+	}
+}
+
+// WithArgs sets the command line arguments, instead of the ones of the process
+func WithArgs(args []string) Option {
+	return func(app *App) {
+		app.args = args
+	}
+}
+
+// WithRegistry sets the registry of the migrations, instead of the one built from the
+// migrations registered to migration.DefaultRegistry (see
+// migration.NewAutoDirMigrationsRegistry)
+func WithRegistry(registry migration.MigrationsRegistry) Option {
+	return func(app *App) {
+		app.registry = registry
+	}
+}
+
+// WithRepository sets the repository which stores the migration executions
+func WithRepository(repository execution.Repository) Option {
+	return func(app *App) {
+		app.repository = repository
+	}
+}
+
+// WithMigrationsDir sets the directory of the migration files
+func WithMigrationsDir(dirPath migration.MigrationsDirPath) Option {
+	return func(app *App) {
+		app.dirPath = dirPath
+	}
+}
+
+// WithHandlerFactory sets the function which builds the migrations handler, instead of
+// handler.NewHandlerWithDB
+func WithHandlerFactory(newHandler HandlerFactory) Option {
+	return func(app *App) {
+		app.newHandler = newHandler
+	}
+}
+
+// WithOutput sets the writer of the commands output, instead of os.Stdout
+func WithOutput(outputWriter io.Writer) Option {
+	return func(app *App) {
+		app.outputWriter = outputWriter
+	}
+}
+
+// WithProcessExit sets the function called with the exit code of a failed command, instead
+// of os.Exit
+func WithProcessExit(processExit func(code int)) Option {
+	return func(app *App) {
+		app.processExit = processExit
+	}
+}
+
+// WithSettings sets the optional bootstrap settings (see BootstrapSettings)
+func WithSettings(settings *BootstrapSettings) Option {
+	return func(app *App) {
+		app.settings = settings
+	}
+}
+
+// WithCommands registers custom commands (see CommandPlugin), along with the ones of the
+// settings (see BootstrapSettings.Commands)
+func WithCommands(plugins ...CommandPlugin) Option {
+	return func(app *App) {
+		app.commands = append(app.commands, plugins...)
+	}
+}
+
+// WithModules sets the modules driven by the cli application (see BootstrapModules), instead
+// of the single registry, repository and migrations directory
+func WithModules(modules ...Module) Option {
+	return func(app *App) {
+		app.modules = append(app.modules, modules...)
+	}
+}
+
+// Run bootstraps the cli (see Bootstrap, and BootstrapModules for the modules given with
+// WithModules) and processes the command. An incomplete configuration is reported to the
+// output and exits the process with code 2.
+func (a *App) Run(ctx context.Context) {
+	if len(a.modules) > 0 {
+		BootstrapModules(
+			ctx, a.args, a.modules, a.newHandler, a.outputWriter, a.processExit, a.runSettings(),
+		)
+		return
+	}
+
+	if a.repository == nil {
+		a.fail("no executions repository, see WithRepository")
+		return
+	}
+
+	if a.dirPath == "" {
+		a.fail("no migrations directory, see WithMigrationsDir")
+		return
+	}
+
+	registry := a.registry
+	if registry == nil {
+		registry = migration.NewAutoDirMigrationsRegistry(a.dirPath)
+	}
+
+	Bootstrap(
+		ctx,
+		a.db,
+		a.args,
+		registry,
+		a.repository,
+		a.dirPath,
+		a.newHandler,
+		a.outputWriter,
+		a.processExit,
+		a.runSettings(),
+	)
+}
+
+// runSettings returns the settings of the run, with the commands given with WithCommands. The
+// settings given with WithSettings are copied, not changed.
+func (a *App) runSettings() *BootstrapSettings {
+	settings := a.settings
+	if len(a.commands) > 0 {
+		withCommands := BootstrapSettings{}
+		if settings != nil {
+			withCommands = *settings
+		}
+		withCommands.Commands = append(slices.Clip(withCommands.Commands), a.commands...)
+		settings = &withCommands
+	}
+	return settings
+}
+
+func (a *App) fail(reason string) {
+	_, _ = fmt.Fprintf(a.outputWriter, "Invalid configuration: %s\n", reason)
+	a.processExit(ExitCodeValidation)
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+)
+
+// ArchiveCommand implements the Command interface to move the old executions to the archive
+// of the repository (see execution.ArchivedRepository), keeping the executions storage small.
+// The archived versions stay applied. It is available when the repository implements
+// execution.Archiver.
+type ArchiveCommand struct {
+	outputFlags
+	confirmation
+	rawBefore string
+	olderThan time.Duration
+	before    time.Time
+	archiver  execution.Archiver
+}
+
+func (c *ArchiveCommand) Id() string {
+	return "archive"
+}
+
+func (c *ArchiveCommand) Description() string {
+	return "Moves the finished executions started before a cutoff to the executions archive.\n" +
+		"Examples: migrate archive --before=2024-01-01, migrate archive --older-than=8760h"
+}
+
+func (c *ArchiveCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.StringVar(
+		&c.rawBefore,
+		"before",
+		"",
+		`Archive the executions started before the given time (RFC 3339 or a date).
+		Examples: migrate archive --before=2024-01-01`,
+	)
+	flagSet.DurationVar(
+		&c.olderThan,
+		"older-than",
+		0,
+		`Archive the executions started longer ago than the given duration.
+		Examples: migrate archive --older-than=8760h`,
+	)
+	c.confirmation.DefineFlags(flagSet)
+}
+
+func (c *ArchiveCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
+	if (c.rawBefore == "") == (c.olderThan == 0) {
+		return errors.New("either the before or the older-than flag is required")
+	}
+	if c.olderThan < 0 {
+		return errors.New("the older-than duration must be positive")
+	}
+
+	var err error
+	c.before, err = parseTimeFlag("before", c.rawBefore)
+	return err
+}
+
+func (c *ArchiveCommand) Exec(stdWriter io.Writer) error {
+	cutoff := c.before
+	if c.olderThan > 0 {
+		cutoff = time.Now().Add(-c.olderThan)
+	}
+
+	action := fmt.Sprintf(
+		"move the finished executions started before %s to the archive",
+		cutoff.UTC().Format(time.RFC3339),
+	)
+	if err := c.confirm(stdWriter, action, false); err != nil {
+		return err
+	}
+
+	archived, err := c.archiver.Archive(cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to archive the executions with error: %w", err)
+	}
+
+	return c.output().FormatMessage(
+		stdWriter,
+		fmt.Sprintf(
+			"Archived %d executions started before %s",
+			len(archived), cutoff.UTC().Format(time.RFC3339),
+		),
+	)
+}
+
+// loadExecutions loads the executions of the repository, along with the versions of the ones
+// which are only in its archive, if it is an execution.Archiver (see the --archived flags of
+// the status and history commands)
+func loadExecutions(
+	repository execution.Repository,
+) ([]execution.MigrationExecution, map[uint64]bool, error) {
+	archiver, ok := repository.(execution.Archiver)
+	if !ok {
+		executions, err := repository.LoadExecutions()
+		return executions, nil, err
+	}
+
+	hot, err := archiver.LoadHotExecutions()
+	if err != nil {
+		return nil, nil, err
+	}
+	archived, err := archiver.LoadArchivedExecutions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hotVersions := make(map[uint64]bool, len(hot))
+	for _, exec := range hot {
+		hotVersions[exec.Version] = true
+	}
+
+	archivedVersions := make(map[uint64]bool, len(archived))
+	executions := make([]execution.MigrationExecution, 0, len(archived)+len(hot))
+	for _, exec := range archived {
+		if !hotVersions[exec.Version] {
+			archivedVersions[exec.Version] = true
+			executions = append(executions, exec)
+		}
+	}
+	return append(executions, hot...), archivedVersions, nil
+}
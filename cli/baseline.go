@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// baselineLatest is the baseline version which marks all the registered migrations
+const baselineLatest = "latest"
+
+// BaselineCommand implements the Command interface to adopt the library on an existing
+// database: all the registered migrations up to the chosen version are recorded as executed
+// at once, without calling their Up() method. Unlike mark-executed --up-to, it only baselines
+// a repository without any execution, so it can't hide the pending migrations of a database
+// already managed by the library.
+type BaselineCommand struct {
+	outputFlags
+	rawVersion string
+	migVersion uint64
+	registry   migration.MigrationsRegistry
+	repository execution.Repository
+	handler    *handler.MigrationsHandler
+	ctx        context.Context
+}
+
+func (c *BaselineCommand) Id() string {
+	return "baseline"
+}
+
+func (c *BaselineCommand) Description() string {
+	return "Records all the migrations up to the provided version as executed, without " +
+		"executing Up(), to adopt an existing database.\n" +
+		"Examples: migrate baseline --version=1712953077, migrate baseline --version=latest"
+}
+
+func (c *BaselineCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.StringVar(
+		&c.rawVersion,
+		"version",
+		"",
+		`Registered version the existing database is at, or "latest" for all the `+
+			"registered migrations.\n"+
+			"Examples: migrate baseline --version=1712953077",
+	)
+}
+
+func (c *BaselineCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
+	if c.rawVersion == "" {
+		return errors.New("the baseline version is required")
+	}
+	if c.rawVersion == baselineLatest {
+		return nil
+	}
+
+	version, err := getVersionFrom(c.rawVersion)
+	if err != nil {
+		return err
+	}
+	c.migVersion = version
+	return nil
+}
+
+func (c *BaselineCommand) Exec(stdWriter io.Writer) error {
+	errMsg := "failed to baseline the executions"
+	version := c.migVersion
+	if c.rawVersion == baselineLatest {
+		migrations := c.registry.OrderedMigrations()
+		if len(migrations) == 0 {
+			return fmt.Errorf("%s, no migration is registered", errMsg)
+		}
+		version = migrations[len(migrations)-1].Version()
+	} else if c.registry.Get(version) == nil {
+		return &ValidationError{
+			Err: fmt.Errorf("%s, the version %d is not registered", errMsg, version),
+		}
+	}
+
+	executions, err := c.repository.LoadExecutions()
+	if err != nil {
+		return fmt.Errorf("%s, failed to load executions with error: %w", errMsg, err)
+	}
+	if len(executions) > 0 {
+		return fmt.Errorf(
+			"%s, the repository already has %d executions, mark the versions with the "+
+				"mark-executed command instead", errMsg, len(executions),
+		)
+	}
+
+	marked, err := c.handler.MarkExecutedUpTo(c.ctx, version)
+	if err != nil {
+		return err
+	}
+
+	return c.output().FormatMessage(
+		stdWriter,
+		fmt.Sprintf("Baselined %d migration versions up to %d", len(marked), version),
+	)
+}
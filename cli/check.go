@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// The exit codes of the check command, besides ExitCodeOk for a fully migrated database and
+// ExitCodeRepository if the executions can't be loaded (see CheckError). The check command
+// has its own code space: they overlap the codes of the failure categories (ExitCodeCheckUnknown
+// is ExitCodeValidation), so the gates which run check should only rely on its codes.
+const (
+	// ExitCodeCheckPending is returned when there are pending migrations
+	ExitCodeCheckPending = 1
+
+	// ExitCodeCheckUnknown is returned when executed versions are not registered, even if
+	// there are pending migrations too
+	ExitCodeCheckUnknown = 2
+)
+
+// The states of the database reported by the check command
+const (
+	CheckMigrated = "migrated"
+	CheckPending  = "pending"
+	CheckUnknown  = "unknown"
+)
+
+// CheckReport is the result of the check command
+type CheckReport struct {
+	// State is CheckMigrated, CheckPending or CheckUnknown (which takes precedence)
+	State string `json:"state"`
+
+	// Pending holds the versions of the pending migrations, in the order they will be executed
+	Pending []uint64 `json:"pending"`
+
+	// Unknown holds the versions of the executions without a registered migration
+	Unknown []uint64 `json:"unknown"`
+}
+
+// CheckFormatter is an optional interface for the formatters which render the result of the
+// check command. The output of the formatters which don't implement it is rendered by the
+// TextFormatter.
+type CheckFormatter interface {
+	FormatCheck(w io.Writer, report CheckReport) error
+}
+
+// CheckError is returned by the check command when the database is not fully migrated. The
+// process exits with its ExitCode.
+type CheckError struct {
+	Report CheckReport
+}
+
+func (e *CheckError) Error() string {
+	if e.Report.State == CheckUnknown {
+		return fmt.Sprintf("%d executed versions are not registered", len(e.Report.Unknown))
+	}
+	return fmt.Sprintf("%d migrations are pending", len(e.Report.Pending))
+}
+
+// ExitCode returns ExitCodeCheckUnknown for unknown versions, ExitCodeCheckPending otherwise
+func (e *CheckError) ExitCode() int {
+	if e.Report.State == CheckUnknown {
+		return ExitCodeCheckUnknown
+	}
+	return ExitCodeCheckPending
+}
+
+// CheckCommand implements the Command interface to check whether the database is fully
+// migrated, exiting with a dedicated code (ExitCodeOk, ExitCodeCheckPending or
+// ExitCodeCheckUnknown), so readiness probes and deployment gates can run it as it is
+type CheckCommand struct {
+	outputFlags
+	registry   migration.MigrationsRegistry
+	repository execution.Repository
+}
+
+func (c *CheckCommand) Id() string {
+	return "check"
+}
+
+func (c *CheckCommand) Description() string {
+	return "Checks whether the database is fully migrated. Exits with code 0 if it is, 1 if " +
+		"there are pending migrations and 2 if executed versions are not registered.\n" +
+		"Examples: migrate check, migrate check --format=json, migrate check --quiet"
+}
+
+func (c *CheckCommand) Exec(stdWriter io.Writer) error {
+	executions, err := c.repository.LoadExecutions()
+	if err != nil {
+		return &handler.RepositoryError{
+			Err: fmt.Errorf("failed to load executions with error: %w", err),
+		}
+	}
+
+	status := newStatusReport(c.registry.OrderedMigrations(), executions)
+	report := CheckReport{
+		State: CheckMigrated, Pending: make([]uint64, 0, len(status.Pending)),
+		Unknown: status.Unknown,
+	}
+	for _, mig := range status.Pending {
+		report.Pending = append(report.Pending, mig.Version)
+	}
+	switch {
+	case len(report.Unknown) > 0:
+		report.State = CheckUnknown
+	case len(report.Pending) > 0:
+		report.State = CheckPending
+	}
+
+	if formatter, ok := c.output().(CheckFormatter); ok {
+		err = formatter.FormatCheck(stdWriter, report)
+	} else {
+		err = (&TextFormatter{}).FormatCheck(stdWriter, report)
+	}
+	if err != nil {
+		return err
+	}
+
+	if report.State != CheckMigrated {
+		return &CheckError{Report: report}
+	}
+	return nil
+}
+
+func (f *TextFormatter) FormatCheck(w io.Writer, report CheckReport) error {
+	var msg strings.Builder
+	switch report.State {
+	case CheckMigrated:
+		msg.WriteString(f.paint(colorGreen, "The database is fully migrated") + "\n")
+	case CheckPending:
+		_, _ = fmt.Fprintf(
+			&msg, "%s: %s\n",
+			f.paint(colorYellow, "Pending migrations"), joinVersions(report.Pending),
+		)
+	default:
+		_, _ = fmt.Fprintf(
+			&msg, "%s: %s\n",
+			f.paint(colorRed, "Unknown executed versions"), joinVersions(report.Unknown),
+		)
+		if len(report.Pending) > 0 {
+			_, _ = fmt.Fprintf(&msg, "Pending migrations: %s\n", joinVersions(report.Pending))
+		}
+	}
+
+	_, err := io.WriteString(w, msg.String())
+	return err
+}
+
+func (f *JsonFormatter) FormatCheck(w io.Writer, report CheckReport) error {
+	return json.NewEncoder(w).Encode(report)
+}
+
+func (f *QuietFormatter) FormatCheck(io.Writer, CheckReport) error { return nil }
+
+// joinVersions returns the comma separated versions
+func joinVersions(versions []uint64) string {
+	formatted := make([]string, 0, len(versions))
+	for _, version := range versions {
+		formatted = append(formatted, strconv.FormatUint(version, 10))
+	}
+	return strings.Join(formatted, ", ")
+}
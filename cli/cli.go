@@ -3,22 +3,42 @@
 // This package allows client code to bootstrap and handle user commands executed via the command
 // line.
 // It defines a Command interface that all migration commands must implement, and provides several
-// built-in commands for common migration operations like migrating up, down, generating blank
+// built-in commands for common migration operations like migrating up, down, generating new
 // migrations, and displaying migration statistics.
 package cli
 
 import (
+	"bufio"
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/golibry/go-cli-command/cli"
+	"github.com/golibry/go-migrations/audit"
+	"github.com/golibry/go-migrations/drift"
 	"github.com/golibry/go-migrations/execution"
 	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/history"
+	"github.com/golibry/go-migrations/lock"
+	"github.com/golibry/go-migrations/masking"
 	"github.com/golibry/go-migrations/migration"
+	"github.com/golibry/go-migrations/notify"
+	"github.com/golibry/go-migrations/repeatable"
+	"github.com/golibry/go-migrations/schemadiff"
 )
 
 const MigrationsCmdLockName = "app-go-migrations"
@@ -32,6 +52,301 @@ type BootstrapSettings struct {
 
 	// The name that will be used for generating the lock file name
 	MigrationsCmdLockName string
+
+	// The migrated target the lock is scoped to: a hash of it is added to the lock name, so
+	// runs against different databases from the same host don't exclude each other. The
+	// target itself never ends up in the file name. Defaults to the database identified by
+	// the repository (see execution.Targeter); set it to override the scope, for example, to
+	// the DSN of repositories which can't identify their database.
+	LockTarget string
+
+	// lockScope is added to the lock target, so the runs of the modules don't exclude each
+	// other (see BootstrapModules)
+	lockScope string
+
+	// Optional function which builds the locker used for exclusive runs, for the given lock
+	// name (see LockName). Use it to plug in distributed lockers (lock.NewRedisLocker,
+	// lock.NewConsulLocker, lock.NewEtcdLocker). Defaults to a file locker created in
+	// RunLockFilesDirPath.
+	NewLocker func(lockName string) lock.Locker
+
+	// Optional hooks which run before/after specific commands, indexed by command id
+	// (for example "up" or "down"). Hooks of lockable commands run while the lock is held.
+	CommandHooks map[string]CommandHooks
+
+	// The output format used when the --format flag is not provided. Defaults to FormatText.
+	DefaultFormat string
+
+	// Optional, extra output formatters, indexed by the name used with the --format flag.
+	// They can override the built-in ones (see BuiltinFormatters).
+	Formatters map[string]Formatter
+
+	// Optional sink which receives an audit record for each migration applied or rolled back
+	// (see the audit package for the syslog and journald sinks). If the sink fails, the
+	// migrations run is stopped.
+	AuditSink audit.Sink
+
+	// Optional store of the tamper-evident, hash-chained history of the applied/rolled-back
+	// migrations (see the history package). When set, a record is appended for each
+	// migration and the history:verify command is available.
+	HistoryStore history.Store
+
+	// The number of history records appended at once, when HistoryStore is set (see
+	// history.BufferedStore). The records of a run are buffered and flushed once the batch is
+	// full and before the process exits. Defaults to appending each record on its own.
+	HistoryBatchSize int
+
+	// The minimum interval between the writes of the executions (see
+	// execution.ThrottledRepository), to reduce the footprint of the runs of many small
+	// migrations on the databases billed or rate-limited per request (for example, the
+	// serverless ones). Defaults to no throttling.
+	WriteInterval time.Duration
+
+	// Optional router of the failure notifications to the teams which own the failed
+	// migrations (see the notify package)
+	Notifications *notify.Router
+
+	// If the repository privileges must be verified before bootstrapping (see
+	// execution.PermissionsChecker). When some are missing, the consolidated report is
+	// written to the output and the process exits with code 1, before creating the
+	// executions table or running any migration.
+	PermissionsPreflight bool
+
+	// If a failed initialization of the executions storage is described instead of panicking:
+	// the statements which create the storage (see execution.InitDescriber) are written to the
+	// output, or to the InitStatementsFile, for a privileged role (for example, a DBA) to run
+	// them, and the process exits with ExitCodeRepository. The runs proceed once the storage
+	// exists, without the privileges to create it.
+	DescribeInitFailures bool
+
+	// Optional file the statements of a failed initialization are written to, instead of the
+	// output, when DescribeInitFailures is set
+	InitStatementsFile string
+
+	// Optional source of the version the running app fleet is compatible with. When set, the
+	// up runs don't execute the contract migrations (see migration.TagContract) newer than it,
+	// while old app versions are still serving (see handler.WithDeploymentGuard and the fleet
+	// package).
+	FleetVersionSource handler.FleetVersionSource
+
+	// Optional gate asked about each migration before the up runs execute it (see
+	// handler.WithGate): it allows the migration, defers it to a later run (for example, a
+	// backfill waiting for the rollout of a feature flag, see featureflag.Gate) or denies it.
+	// The run stops before a deferred migration without failing, and before a denied one with
+	// a failure.
+	Gate handler.Gate
+
+	// Optional text/template used by the generate and blank commands instead of the built-in
+	// migration file skeleton (see migration.GenerateOptions.Template), for example embedded
+	// with go:embed. The --template flag and the TemplateEnvVar environment variable take
+	// precedence.
+	MigrationTemplate string
+
+	// Optional source of the live database schema, which enables the diff command (see the
+	// schemadiff package), for example schemadiff.NewDbSource(db, schemadiff.DialectMysql, "")
+	SchemaSource schemadiff.Source
+
+	// Optional sources of the declared target schema the diff command compares against,
+	// indexed by the name used with the --target-db flag (for example, a reference database)
+	SchemaTargets map[string]schemadiff.Source
+
+	// The tables the diff command doesn't compare, like the migrations executions table
+	SchemaIgnoredTables []string
+
+	// Optional environments (for example, "staging" and "production") the drift command
+	// compares, indexed by the name used with its --from and --to flags. At least 2 are needed
+	// to enable the command. Their schemas are compared without the SchemaIgnoredTables.
+	Environments map[string]drift.Environment
+
+	// The environments (see EnvironmentEnvVar) the reset and fresh commands are allowed in.
+	// Defaults to DefaultResetEnvironments.
+	ResetEnvironments []string
+
+	// The reader the confirmation and collision prompts are answered from. Defaults to
+	// os.Stdin.
+	Input io.Reader
+
+	// Optional timeout of each Up() or Down() call (see handler.WithMigrationTimeout): the
+	// context the migration gets is cancelled once it elapses
+	MigrationTimeout time.Duration
+
+	// The writer of the run logs (each handled migration, and the details the migrations log
+	// with migration.LoggerFrom). Defaults to os.Stderr.
+	LogWriter io.Writer
+
+	// Optional repeatable objects (views, functions, procedures, triggers, see the repeatable
+	// package), which enable the repeatable:sync command. RepeatablesStore must be set too.
+	// The objects are applied with the db given to Bootstrap, which must be a
+	// sqlhelper.Execer (for example, *sql.DB).
+	Repeatables *repeatable.Registry
+
+	// The store of the hashes of the applied repeatable objects, for example
+	// repeatable.NewSqlStore(db, "repeatable_objects", repeatable.DialectMysql)
+	RepeatablesStore repeatable.Store
+
+	// Optional data masking routines (see the masking package), run after each successful up
+	// and fresh run, and by the mask command, in the MaskingEnvironments only
+	MaskingRoutines []masking.Routine
+
+	// The non-production environments (see EnvironmentEnvVar) the data is masked in. Defaults
+	// to DefaultMaskingEnvironments.
+	MaskingEnvironments []string
+
+	// The minimum level of the run logs, when neither the --verbose (debug) nor the --quiet
+	// (error) flag is given. Defaults to slog.LevelWarn, or slog.LevelInfo for the JSON logs.
+	LogLevel slog.Leveler
+
+	// The format of the run logs (LogFormatText or LogFormatJson), when the --log-format flag
+	// is not given. Defaults to LogFormatText.
+	LogFormat string
+
+	// Optional reporter of the progress of the up and down runs. Defaults to a
+	// TextProgressReporter writing to the output, when it is rendered as text.
+	ProgressReporter handler.ProgressReporter
+
+	// Optional key which signs the plan artifacts exported by the plan:export command (see the
+	// plan package). Usually set only where the plans are approved.
+	PlanSigningKey ed25519.PrivateKey
+
+	// Optional key which verifies the signature of the plan artifacts. When set, the plan:apply
+	// command is available, and only executes the artifacts signed with the matching key.
+	PlanVerifyingKey ed25519.PublicKey
+
+	// The maximum duration the bootstrap waits for the database (its ping, if the db has a
+	// PingContext method, and the repository initialization), retrying with an exponential
+	// backoff, when the --wait-for-db flag is not given. For example, when the tool runs as a
+	// Kubernetes init container, started before the database is ready. Defaults to no wait.
+	WaitForDb time.Duration
+
+	// The deadline of the whole invocation, when the --timeout flag is not given: once it is
+	// exceeded, the context of the run is cancelled, which interrupts the executing migration
+	// (if it honours its context), stops the run before the next one and releases the lock of
+	// the exclusive runs. Defaults to no deadline.
+	Timeout time.Duration
+
+	// The maximum duration the exclusive runs wait for the lock held by another run, retrying
+	// with an exponential backoff (see lock.WaitingLocker), when the --lock-timeout flag is not
+	// given. For example, when several instances of a service run the migrations at startup.
+	// Defaults to no wait: the runs fail immediately, with ExitCodeLocked, if the lock is held.
+	LockTimeout time.Duration
+
+	// Disables the colors of the text output (see TextFormatter.Color), like the --no-color
+	// flag and the NoColorEnvVar environment variable. The colors are only enabled when the
+	// output is a terminal anyway.
+	NoColor bool
+
+	// Enables the read-only mode, like the --read-only flag, for example during a disaster
+	// recovery drill or while a read-only replica is promoted briefly: the read-only commands
+	// (status, pending, plan...) work as usual, while the commands which write the executions
+	// fail before doing anything, with execution.ErrReadOnlyRepository (and ExitCodeRepository).
+	// The mode is also enabled when the repository reports that its storage is read-only (see
+	// execution.ReadOnlyChecker).
+	ReadOnly bool
+
+	// Optional path of the lockfile (see the lockfile package) the up runs are restricted to,
+	// when the --lockfile flag is not given: they only execute the migrations it pins, with
+	// their pinned checksums
+	Lockfile string
+
+	// Optional custom commands (for example, seed or anonymize), registered along with the
+	// built-in ones and wired like them (see CommandPlugin)
+	Commands []CommandPlugin
+
+	// Allows the executions recorded out of order (see handler.NewOutOfOrderPlan), which
+	// enables up --only to skip the earlier migrations not executed yet. By default, the
+	// executions must follow the order of the registered versions.
+	OutOfOrder bool
+}
+
+// LockName returns the identity of the lock used for exclusive runs, derived from the
+// configured lock name (MigrationsCmdLockName by default) and the lock target
+func (s *BootstrapSettings) LockName() string {
+	lockName := MigrationsCmdLockName
+	if inputLockName := strings.TrimSpace(s.MigrationsCmdLockName); inputLockName != "" {
+		lockName = inputLockName
+	}
+
+	target := s.LockTarget
+	if s.lockScope != "" {
+		target = strings.TrimPrefix(target+"/"+s.lockScope, "/")
+	}
+	if target == "" {
+		return lockName
+	}
+
+	targetHash := sha256.Sum256([]byte(target))
+	return lockName + "-" + hex.EncodeToString(targetHash[:6])
+}
+
+// withRepositoryLockTarget returns the settings with the lock scoped to the database migrated
+// with the repository (see execution.Targeter), unless the lock target is set. If the
+// repository fails to identify its database, the lock stays unscoped, and the error is
+// returned with the unchanged settings.
+func (s *BootstrapSettings) withRepositoryLockTarget(
+	repository execution.Repository,
+) (*BootstrapSettings, error) {
+	if s.LockTarget != "" {
+		return s, nil
+	}
+
+	target, err := execution.TargetOf(repository)
+	if err != nil || target == "" {
+		return s, err
+	}
+
+	targetSettings := *s
+	targetSettings.LockTarget = target
+	return &targetSettings, nil
+}
+
+// executionPlanBuilder returns the builder of the execution plans, as configured by the
+// settings
+func (s *BootstrapSettings) executionPlanBuilder() handler.ExecutionPlanBuilder {
+	if s.OutOfOrder {
+		return handler.NewOutOfOrderPlan
+	}
+	return handler.NewPlan
+}
+
+// locker builds a new locker for exclusive runs, as configured by the settings
+func (s *BootstrapSettings) locker() lock.Locker {
+	if s.NewLocker != nil {
+		return s.NewLocker(s.LockName())
+	}
+
+	return lock.NewFileLocker(LockFilePath(s.RunLockFilesDirPath, s.LockName()))
+}
+
+// runLocker builds the locker of the exclusive runs, which waits for the lock for at most the
+// LockTimeout
+func (s *BootstrapSettings) runLocker() lock.Locker {
+	if s.LockTimeout > 0 {
+		return lock.NewWaitingLocker(s.locker(), s.LockTimeout)
+	}
+	return s.locker()
+}
+
+// readOnlyCommandIds are the ids of the commands which only read the executions (the empty id
+// runs the help command). They are never locked, and they run with a read-only repository
+// (see execution.ReadOnlyRepository), so they are safe while a run is in progress elsewhere.
+var readOnlyCommandIds = []string{
+	"", "help", "status", "pending", "stats", "version", "describe", "validate", "history:verify",
+	"drift", "diff", "summary", "plan", "plan:export", "lockfile", "history", "who", "check",
+}
+
+// isReadOnlyRun checks if the command invoked by the given args only reads the executions,
+// being a built-in read-only command or one of the given read-only custom commands
+func isReadOnlyRun(args []string, pluginIds ...string) bool {
+	args = versionFlagAlias(args)
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	cmdId := ""
+	if len(args) > 0 {
+		cmdId = strings.TrimSpace(args[0])
+	}
+	return slices.Contains(readOnlyCommandIds, cmdId) || slices.Contains(pluginIds, cmdId)
 }
 
 // Bootstrap initializes the CLI application and processes user commands.
@@ -39,6 +354,7 @@ type BootstrapSettings struct {
 // This function sets up all the necessary components for handling migration commands,
 // parses the command-line arguments, and executes the requested command.
 // If no command is specified or an invalid command is provided, it displays the help information.
+// Prefer New, whose options can grow without breaking the callers.
 //
 // Parameters:
 //   - ctx: Context for the migration execution
@@ -70,12 +386,7 @@ func Bootstrap(
 	registry migration.MigrationsRegistry,
 	repository execution.Repository,
 	dirPath migration.MigrationsDirPath,
-	newHandler func(
-		registry migration.MigrationsRegistry,
-		repository execution.Repository,
-		newExecutionPlan handler.ExecutionPlanBuilder,
-		db any,
-	) (*handler.MigrationsHandler, error),
+	newHandler HandlerFactory,
 	outputWriter io.Writer,
 	processExit func(code int),
 	settings *BootstrapSettings,
@@ -84,7 +395,174 @@ func Bootstrap(
 		newHandler = handler.NewHandlerWithDB
 	}
 
-	migrationsHandler, err := newHandler(registry, repository, nil, db)
+	// all the migrations handled by this invocation share the same run ID
+	ctx, runId := execution.EnsureRunId(ctx)
+
+	// the first interrupt or termination signal cancels the context of the run, so the
+	// executing migration can stop, its execution is recorded and the lock is released, the
+	// next one kills the process
+	parentCtx := ctx
+	ctx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	if settings == nil {
+		settings = &BootstrapSettings{}
+	}
+
+	args, logLevel, logFormat := logFlags(args)
+	if logFormat == "" {
+		logFormat = settings.LogFormat
+	}
+	if logLevel == nil {
+		logLevel = settings.LogLevel
+	} else if logLevel.Level() == slog.LevelError && settings.DefaultFormat == "" {
+		// --quiet silences the output too, unless a format is given
+		quietSettings := *settings
+		quietSettings.DefaultFormat = FormatQuiet
+		settings = &quietSettings
+	}
+	logger, err := newLogger(settings.LogWriter, logLevel, logFormat)
+	if err != nil {
+		_, _ = fmt.Fprintf(outputWriter, "Invalid log settings: %s\n", err)
+		processExit(ExitCodeValidation)
+		return
+	}
+	ctx = migration.WithLogger(ctx, logger.With("run_id", runId))
+	interruptLogged := make(chan struct{})
+	stopInterruptLog := context.AfterFunc(ctx, func() {
+		defer close(interruptLogged)
+		stopSignals()
+		if parentCtx.Err() == nil {
+			logger.Warn(
+				"interrupted, stopping the run once the executing migration is recorded,"+
+					" interrupt again to kill the process",
+				"run_id", runId,
+			)
+		}
+	})
+	defer func() {
+		if !stopInterruptLog() {
+			<-interruptLogged
+		}
+	}()
+
+	args, waitForDb, waitErr := durationFlag(args, waitForDbFlag)
+	args, timeout, timeoutErr := durationFlag(args, timeoutFlag)
+	args, lockTimeout, lockTimeoutErr := durationFlag(args, lockTimeoutFlag)
+	args, noColor := boolFlag(args, noColorFlag)
+	args, readOnlyMode := boolFlag(args, readOnlyFlag)
+	if err = errors.Join(waitErr, timeoutErr, lockTimeoutErr); err != nil {
+		_, _ = fmt.Fprintf(outputWriter, "Invalid arguments: %s\n", err)
+		processExit(ExitCodeValidation)
+		return
+	}
+	if waitForDb < 0 {
+		waitForDb = settings.WaitForDb
+	}
+	if timeout < 0 {
+		timeout = settings.Timeout
+	}
+	if lockTimeout >= 0 {
+		lockSettings := *settings
+		lockSettings.LockTimeout = lockTimeout
+		settings = &lockSettings
+	}
+
+	// the deadline bounds the whole invocation, including the wait for the database and the
+	// lock acquisition, so a stuck statement can't hold the run, and the lock, forever
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// the read-only commands must neither wait for nor interfere with a run in progress, so
+	// they don't create or upgrade the executions storage
+	readOnly := isReadOnlyRun(args, readOnlyPluginIds(settings.Commands)...)
+	readOnlyMode = readOnlyMode || settings.ReadOnly
+	if !readOnly && !readOnlyMode {
+		// a failed check is left to the handler, which reports the errors of the repository
+		readOnlyMode = errors.Is(
+			execution.CheckWritable(repository), execution.ErrReadOnlyRepository,
+		)
+	}
+	migratedRepository := repository
+	if readOnly || readOnlyMode {
+		repository = execution.NewReadOnlyRepository(repository)
+	}
+
+	if settings.MigrationTimeout > 0 {
+		ctx = handler.WithMigrationTimeout(ctx, settings.MigrationTimeout)
+	}
+
+	if settings.FleetVersionSource != nil {
+		ctx = handler.WithDeploymentGuard(ctx, settings.FleetVersionSource)
+	}
+
+	if settings.Gate != nil {
+		ctx = handler.WithGate(ctx, settings.Gate)
+	}
+
+	var rawInput io.Reader = os.Stdin
+	if settings.Input != nil {
+		rawInput = settings.Input
+	}
+	inputFile, isFile := rawInput.(*os.File)
+	input := bufio.NewReader(rawInput)
+
+	// the destructive commands ask for a confirmation only when a terminal can answer it
+	newConfirmation := func() confirmation {
+		return confirmation{input: input, interactive: isFile && isTerminal(inputFile)}
+	}
+
+	if !readOnly {
+		if err := resolveCollisions(registry, input, outputWriter); err != nil {
+			_, _ = fmt.Fprintf(outputWriter, "Collision resolution failed: %s\n", err)
+			processExit(ExitCodeValidation)
+			return
+		}
+	}
+
+	if waitForDb > 0 {
+		if err := waitForRepository(ctx, db, repository, waitForDb); err != nil {
+			_, _ = fmt.Fprintf(outputWriter, "Database wait failed: %s\n", err)
+			processExit(ExitCodeRepository)
+			return
+		}
+	}
+
+	if settings.RunMigrationsExclusively {
+		settings, err = settings.withRepositoryLockTarget(migratedRepository)
+		if err != nil {
+			logger.Warn("the run lock is not scoped to the migrated database", "error", err)
+		}
+	}
+
+	if settings.PermissionsPreflight && !readOnly {
+		if err := execution.CheckPermissions(repository); err != nil {
+			_, _ = fmt.Fprintf(outputWriter, "Permissions preflight failed: %s\n", err)
+			processExit(ExitCode(err))
+			return
+		}
+	}
+
+	// only the handler writes the executions, the commands keep the repository as it is, with
+	// its optional interfaces
+	handlerRepository := repository
+	if settings.WriteInterval > 0 && !readOnly {
+		handlerRepository = execution.NewThrottledRepository(repository, settings.WriteInterval)
+	}
+
+	migrationsHandler, err := newHandler(
+		registry, handlerRepository, settings.executionPlanBuilder(), db,
+	)
+
+	var initErr *execution.InitError
+	if settings.DescribeInitFailures && errors.As(err, &initErr) {
+		describeInitFailure(outputWriter, initErr, settings.InitStatementsFile)
+		processExit(ExitCodeRepository)
+		return
+	}
 
 	if err != nil {
 		panic(
@@ -95,44 +573,286 @@ func Bootstrap(
 		)
 	}
 
-	var up, down, forceUp, forceDown cli.Command
-	up = &MigrateUpCommand{handler: migrationsHandler, ctx: ctx}
-	down = &MigrateDownCommand{handler: migrationsHandler, ctx: ctx}
-	forceUp = &MigrateForceUpCommand{handler: migrationsHandler, ctx: ctx}
-	forceDown = &MigrateForceDownCommand{handler: migrationsHandler, ctx: ctx}
+	// the executions storage is created or upgraded by the handler, so it can be checked now
+	if err = execution.CheckSchemaVersion(repository); err != nil {
+		_, _ = fmt.Fprintf(outputWriter, "Warning: %s\n", err)
+	}
+
+	if settings.AuditSink != nil {
+		migrationsHandler.AddListener(audit.NewListener(settings.AuditSink))
+	}
 
-	if settings != nil && settings.RunMigrationsExclusively {
-		lockName := MigrationsCmdLockName
-		if inputLockName := strings.TrimSpace(settings.MigrationsCmdLockName); inputLockName != "" {
-			lockName = inputLockName
+	if settings.HistoryStore != nil {
+		historyStore := settings.HistoryStore
+		if settings.HistoryBatchSize > 1 && !readOnly {
+			buffered := history.NewBufferedStore(historyStore, settings.HistoryBatchSize)
+			processExit = flushingExit(ctx, buffered, outputWriter, processExit)
+			historyStore = buffered
 		}
+		migrationsHandler.AddListener(history.NewListener(historyStore))
+	}
 
-		up = cli.NewLockableCommandWithLockName(up, settings.RunLockFilesDirPath, lockName)
-		down = cli.NewLockableCommandWithLockName(down, settings.RunLockFilesDirPath, lockName)
-		forceUp = cli.NewLockableCommandWithLockName(
-			forceUp,
-			settings.RunLockFilesDirPath,
-			lockName,
-		)
-		forceDown = cli.NewLockableCommandWithLockName(
-			forceDown,
-			settings.RunLockFilesDirPath,
-			lockName,
-		)
+	if settings.Notifications != nil {
+		migrationsHandler.AddListener(notify.NewListener(settings.Notifications))
 	}
 
-	stats := &MigrateStatsCommand{registry: registry, repository: repository}
-	blank := &GenerateBlankMigrationCommand{migrationsDir: dirPath}
+	color := colorEnabled(outputWriter, noColor || settings.NoColor)
+	if color {
+		outputWriter = &failureColorWriter{outputWriter}
+	}
+	output := func() outputFlags {
+		return newOutputFlags(settings.DefaultFormat, settings.Formatters, color)
+	}
+
+	var up, down, forceUp, forceDown, markExecuted, redo, stats, status, blank cli.Command
+	var generate, version, describe, reset, fresh, baseline, repair cli.Command
+	up = &MigrateUpCommand{
+		handler: migrationsHandler, repository: repository, ctx: ctx, outputFlags: output(),
+		progress: settings.ProgressReporter, lockfile: settings.Lockfile, migrationsDir: dirPath,
+	}
+	down = &MigrateDownCommand{
+		handler: migrationsHandler, ctx: ctx, outputFlags: output(), confirmation: newConfirmation(),
+		progress: settings.ProgressReporter, repository: repository,
+	}
+	forceUp = &MigrateForceUpCommand{
+		handler: migrationsHandler, ctx: ctx, outputFlags: output(),
+	}
+	forceDown = &MigrateForceDownCommand{
+		handler: migrationsHandler, ctx: ctx, outputFlags: output(), confirmation: newConfirmation(),
+	}
+	markExecuted = &MarkExecutedCommand{
+		handler: migrationsHandler, ctx: ctx, outputFlags: output(),
+	}
+	baseline = &BaselineCommand{
+		registry: registry, repository: repository, handler: migrationsHandler, ctx: ctx,
+		outputFlags: output(),
+	}
+	repair = &RepairCommand{
+		handler: migrationsHandler, outputFlags: output(), confirmation: newConfirmation(),
+	}
+	redo = &RedoCommand{
+		handler: migrationsHandler, ctx: ctx, outputFlags: output(), confirmation: newConfirmation(),
+	}
+	reset = &ResetCommand{
+		environments: settings.ResetEnvironments, confirmation: newConfirmation(),
+		repository: repository, handler: migrationsHandler, ctx: ctx, outputFlags: output(),
+	}
+	fresh = &ResetCommand{
+		fresh: true, environments: settings.ResetEnvironments, confirmation: newConfirmation(),
+		repository: repository, handler: migrationsHandler, ctx: ctx, outputFlags: output(),
+	}
+	stats = &MigrateStatsCommand{
+		registry: registry, repository: repository, outputFlags: output(),
+		newExecutionPlan: settings.executionPlanBuilder(),
+	}
+	status = &MigrateStatusCommand{
+		registry: registry, repository: repository, outputFlags: output(),
+	}
+	blank = &GenerateBlankMigrationCommand{
+		migrationsDir: dirPath, template: settings.MigrationTemplate, outputFlags: output(),
+	}
+	generate = &GenerateMigrationCommand{
+		GenerateBlankMigrationCommand{
+			migrationsDir: dirPath, template: settings.MigrationTemplate, outputFlags: output(),
+		},
+	}
+	version = &VersionCommand{registry: registry, repository: repository, outputFlags: output()}
+	describeCmd := &DescribeCommand{
+		registry: registry, repository: repository, outputFlags: output(),
+		settings: newSettingsReport(settings, dirPath, output()),
+	}
+	describe = describeCmd
+
+	withHooks := func(cmd cli.Command) cli.Command {
+		if hooks, ok := settings.CommandHooks[cmd.Id()]; ok {
+			return NewHookedCommand(ctx, cmd, hooks)
+		}
+		return cmd
+	}
+	up, down, forceUp, forceDown = withHooks(up), withHooks(down),
+		withHooks(forceUp), withHooks(forceDown)
+	stats, status, blank, version = withHooks(stats), withHooks(status), withHooks(blank),
+		withHooks(version)
+	generate, describe, markExecuted, redo = withHooks(generate), withHooks(describe),
+		withHooks(markExecuted), withHooks(redo)
+	reset, fresh, baseline, repair = withHooks(reset), withHooks(fresh), withHooks(baseline),
+		withHooks(repair)
+
+	maskingEnvironments := settings.MaskingEnvironments
+	if len(maskingEnvironments) == 0 {
+		maskingEnvironments = DefaultMaskingEnvironments
+	}
+	if len(settings.MaskingRoutines) > 0 {
+		hooks := maskingHooks(db, settings.MaskingRoutines, maskingEnvironments)
+		up, fresh = NewHookedCommand(ctx, up, hooks), NewHookedCommand(ctx, fresh, hooks)
+	}
+
+	// the who command inspects the lock only if the runs take it
+	var whoLocker lock.Locker
+	if settings.RunMigrationsExclusively {
+		whoLocker = settings.locker()
+		up = NewLockableCommand(ctx, up, settings.runLocker())
+		down = NewLockableCommand(ctx, down, settings.runLocker())
+		forceUp = NewLockableCommand(ctx, forceUp, settings.runLocker())
+		forceDown = NewLockableCommand(ctx, forceDown, settings.runLocker())
+		markExecuted = NewLockableCommand(ctx, markExecuted, settings.runLocker())
+		baseline = NewLockableCommand(ctx, baseline, settings.runLocker())
+		repair = NewLockableCommand(ctx, repair, settings.runLocker())
+		redo = NewLockableCommand(ctx, redo, settings.runLocker())
+		reset = NewLockableCommand(ctx, reset, settings.runLocker())
+		fresh = NewLockableCommand(ctx, fresh, settings.runLocker())
+	}
 
 	availableCommands := []cli.Command{
-		up, down, forceUp, forceDown, blank, stats,
+		up, down, forceUp, forceDown, markExecuted, baseline, repair, redo, reset, fresh, blank,
+		generate, stats, status, version, describe,
+		withHooks(&UnlockCommand{locker: settings.locker(), ctx: ctx, outputFlags: output()}),
+		withHooks(
+			&ValidateCommand{registry: registry, migrationsDir: dirPath, outputFlags: output()},
+		),
+		withHooks(
+			&PendingCommand{registry: registry, repository: repository, outputFlags: output()},
+		),
+		withHooks(
+			&HistoryCommand{registry: registry, repository: repository, outputFlags: output()},
+		),
+		withHooks(
+			&CheckCommand{registry: registry, repository: repository, outputFlags: output()},
+		),
+		withHooks(
+			&WhoCommand{
+				locker: whoLocker, repository: repository, ctx: ctx, outputFlags: output(),
+			},
+		),
+		withHooks(&SummaryCommand{registry: registry, outputFlags: output()}),
+		withHooks(&PlanCommand{handler: migrationsHandler, outputFlags: output()}),
+		withHooks(
+			&PlanExportCommand{
+				signingKey: settings.PlanSigningKey, migrationsDir: dirPath, registry: registry,
+				repository: repository, handler: migrationsHandler, outputFlags: output(),
+			},
+		),
+		withHooks(
+			&LockfileCommand{registry: registry, migrationsDir: dirPath, outputFlags: output()},
+		),
+	}
+
+	if settings.HistoryStore != nil {
+		availableCommands = append(
+			availableCommands,
+			withHooks(
+				&HistoryVerifyCommand{
+					store: settings.HistoryStore, repository: repository, ctx: ctx,
+					outputFlags: output(),
+				},
+			),
+		)
+	}
+	if settings.SchemaSource != nil {
+		availableCommands = append(
+			availableCommands,
+			withHooks(
+				&DiffCommand{
+					migrationsDir: dirPath, template: settings.MigrationTemplate,
+					source: settings.SchemaSource, targets: settings.SchemaTargets,
+					ignoredTables: settings.SchemaIgnoredTables, ctx: ctx, outputFlags: output(),
+				},
+			),
+		)
+	}
+	if len(settings.Environments) > 1 {
+		availableCommands = append(
+			availableCommands,
+			withHooks(
+				&DriftCommand{
+					environments: settings.Environments, ctx: ctx, outputFlags: output(),
+					ignoredTables: settings.SchemaIgnoredTables,
+				},
+			),
+		)
+	}
+	if len(settings.MaskingRoutines) > 0 {
+		var mask cli.Command = withHooks(
+			&MaskCommand{
+				db: db, routines: settings.MaskingRoutines, environments: maskingEnvironments,
+				ctx: ctx, outputFlags: output(),
+			},
+		)
+		if settings.RunMigrationsExclusively {
+			mask = NewLockableCommand(ctx, mask, settings.runLocker())
+		}
+		availableCommands = append(availableCommands, mask)
+	}
+	if settings.Repeatables != nil && settings.RepeatablesStore != nil {
+		var repeatableSync cli.Command = &RepeatableSyncCommand{
+			db: db, store: settings.RepeatablesStore, registry: settings.Repeatables, ctx: ctx,
+			outputFlags: output(),
+		}
+		repeatableSync = withHooks(repeatableSync)
+		if settings.RunMigrationsExclusively {
+			repeatableSync = NewLockableCommand(ctx, repeatableSync, settings.runLocker())
+		}
+		availableCommands = append(availableCommands, repeatableSync)
+	}
+	if archiver, ok := repository.(execution.Archiver); ok {
+		var archive cli.Command = withHooks(
+			&ArchiveCommand{
+				archiver: archiver, outputFlags: output(), confirmation: newConfirmation(),
+			},
+		)
+		if settings.RunMigrationsExclusively {
+			archive = NewLockableCommand(ctx, archive, settings.runLocker())
+		}
+		availableCommands = append(availableCommands, archive)
+	}
+	if settings.PlanVerifyingKey != nil {
+		var planApply cli.Command = withHooks(
+			&PlanApplyCommand{
+				verifyingKey: settings.PlanVerifyingKey, progress: settings.ProgressReporter,
+				migrationsDir: dirPath, registry: registry, repository: repository,
+				handler: migrationsHandler, ctx: ctx, outputFlags: output(),
+			},
+		)
+		if settings.RunMigrationsExclusively {
+			planApply = NewLockableCommand(ctx, planApply, settings.runLocker())
+		}
+		availableCommands = append(availableCommands, planApply)
+	}
+	for _, plugin := range settings.Commands {
+		var custom cli.Command = plugin.New(
+			CommandEnv{
+				Ctx: ctx, Db: db, Registry: registry, Repository: repository,
+				Handler: migrationsHandler, MigrationsDir: dirPath, Output: OutputFlags{output()},
+			},
+		)
+		if custom.Id() != plugin.Id {
+			panic(
+				fmt.Errorf(
+					"could not bootstrap cli, the custom command %s has the id %s",
+					plugin.Id, custom.Id(),
+				),
+			)
+		}
+
+		custom = withHooks(custom)
+		if plugin.Exclusive && !plugin.ReadOnly && settings.RunMigrationsExclusively {
+			custom = NewLockableCommand(ctx, custom, settings.runLocker())
+		}
+		availableCommands = append(availableCommands, custom)
 	}
 	help := &HelpCommand{*cli.NewHelpCommand(availableCommands)}
 	availableCommands = append(availableCommands, help)
+	describeCmd.commands = availableCommands
 
+	// the error of the executed command determines the exit code (see ExitCode)
+	var cmdErr error
 	cmdRegistry := cli.NewCommandsRegistry()
+	readOnlyIds := slices.Concat(readOnlyCommandIds, readOnlyPluginIds(settings.Commands))
 	for _, cmd := range availableCommands {
-		err = cmdRegistry.Register(cmd)
+		if readOnlyMode && !slices.Contains(readOnlyIds, cmd.Id()) {
+			cmd = &readOnlyModeCommand{cmd}
+		}
+		err = cmdRegistry.Register(&categorizedCommand{cmd, &cmdErr})
 		if err != nil {
 			panic(
 				fmt.Errorf(
@@ -143,7 +863,9 @@ func Bootstrap(
 		}
 	}
 
-	cli.Bootstrap(args, cmdRegistry, outputWriter, processExit)
+	cli.Bootstrap(
+		versionFlagAlias(args), cmdRegistry, outputWriter, categorizedExit(processExit, &cmdErr),
+	)
 }
 
 // HelpCommand implements the Command interface to display help information about all available commands.
@@ -155,10 +877,26 @@ type HelpCommand struct {
 // MigrateUpCommand implements the Command interface to execute the Up() method
 // of migrations that haven't been executed yet.
 type MigrateUpCommand struct {
-	steps     string
-	numOfRuns handler.NumOfRuns
-	handler   *handler.MigrationsHandler // Handler for executing migrations
-	ctx       context.Context
+	outputFlags
+	steps       string
+	numOfRuns   handler.NumOfRuns
+	target      string
+	targetVer   uint64
+	only        string
+	onlyVer     uint64
+	match       string
+	matcher     *handler.Matcher
+	maxDuration time.Duration
+	verifyRerun bool
+	dryRun      bool
+	sqlOnly     string
+	lockfile    string
+	progress    handler.ProgressReporter
+	// Directory of the migration files, hashed by the file and go-ast hashing strategies
+	migrationsDir migration.MigrationsDirPath
+	repository    execution.Repository
+	handler       *handler.MigrationsHandler // Handler for executing migrations
+	ctx           context.Context
 }
 
 func (c *MigrateUpCommand) Id() string {
@@ -170,6 +908,7 @@ func (c *MigrateUpCommand) Description() string {
 }
 
 func (c *MigrateUpCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
 	flagSet.StringVar(
 		&c.steps,
 		"steps",
@@ -182,40 +921,255 @@ func (c *MigrateUpCommand) DefineFlags(flagSet *flag.FlagSet) {
 		Examples: migrate up, migrate up --steps=all, migrate up --steps=3
 		`,
 	)
+	flagSet.StringVar(
+		&c.target,
+		"target",
+		"",
+		`
+		Execute all the migrations up to and including the target version, which
+		must be registered, to stage a rollout. Takes precedence over --steps.
+		Examples: migrate up --target=20240105120000
+		`,
+	)
+	flagSet.StringVar(
+		&c.only,
+		"only",
+		"",
+		`
+		Execute only the migration of this version, which must not be executed yet,
+		out of order: the earlier migrations not executed yet are skipped, with a
+		warning, and are executed by the next runs. Meant for the hotfix migrations
+		which must ship before earlier, slower ones. Skipping migrations requires the
+		out-of-order executions to be allowed (see BootstrapSettings.OutOfOrder).
+		Takes precedence over --steps.
+		Examples: migrate up --only=20240105120000
+		`,
+	)
+	flagSet.StringVar(
+		&c.match,
+		"match",
+		"",
+		`
+		Only execute the migrations whose version or description matches.
+		A pattern ending with "*" is a prefix, any other pattern is a regular expression.
+		Migrations run in order, so the run stops at the first one which does not match.
+		Examples: migrate up --steps=all --match=2024*, migrate up --match="^2024(01|02)"
+		`,
+	)
+	flagSet.DurationVar(
+		&c.maxDuration,
+		"max-duration",
+		0,
+		`
+		Time budget of the run. Migrations are executed until the budget is nearly
+		exhausted, then the run stops between migrations (never in the middle of one)
+		and reports the remaining ones. Defaults to no limit.
+		Examples: migrate up --steps=all --max-duration=30m
+		`,
+	)
+	flagSet.BoolVar(
+		&c.verifyRerun,
+		"verify-rerun",
+		false,
+		`
+		Safe rerun mode, for non-production verification only. Each migration is
+		executed a second time, which must succeed without affecting any rows.
+		The migrations which are not safe to rerun are reported as an error.
+		Examples: migrate up --steps=all --verify-rerun
+		`,
+	)
+	flagSet.BoolVar(
+		&c.dryRun,
+		"dry-run",
+		false,
+		`
+		Only display which migrations would be executed, in order. No Up() is
+		called and no execution is saved.
+		Examples: migrate up --steps=all --dry-run, migrate up --target=3 --dry-run
+		`,
+	)
+	flagSet.StringVar(
+		&c.sqlOnly,
+		"sql-only",
+		"",
+		`
+		Only write the SQL of the migrations to the given file, in order, along with
+		the statements recording their executions, for a DBA to run it manually.
+		All the migrations must be capture capable. No execution is saved.
+		Examples: migrate up --steps=all --sql-only=out.sql
+		`,
+	)
+	flagSet.StringVar(
+		&c.lockfile,
+		"lockfile",
+		c.lockfile,
+		`
+		Only execute the migrations pinned by the lockfile (see the lockfile command),
+		with their pinned checksums. The run is refused, before executing anything,
+		if it would execute any other migration. Defaults to the lockfile setting.
+		Examples: migrate up --steps=all --lockfile=migrations.lock
+		`,
+	)
 }
 
 func (c *MigrateUpCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
 	num, err := handler.NewNumOfRuns(c.steps)
 	if err != nil {
 		return err
 	}
 	c.numOfRuns = num
+
+	if c.target != "" {
+		if c.targetVer, err = getVersionFrom(c.target); err != nil {
+			return err
+		}
+	}
+
+	if c.only != "" {
+		if c.target != "" || c.match != "" {
+			return errors.New("the only flag can't be combined with the target or match flags")
+		}
+		if c.onlyVer, err = getVersionFrom(c.only); err != nil {
+			return err
+		}
+	}
+
+	if c.match != "" {
+		if c.matcher, err = handler.NewMatcher(c.match); err != nil {
+			return err
+		}
+	}
+
+	if c.maxDuration < 0 {
+		return errors.New("the max duration of the run must not be negative")
+	}
+
+	if c.sqlOnly != "" && (c.dryRun || c.maxDuration > 0 || c.verifyRerun) {
+		return errors.New(
+			"the sql-only flag can't be combined with the dry-run, max-duration or" +
+				" verify-rerun flags",
+		)
+	}
 	return nil
 }
 
 func (c *MigrateUpCommand) Exec(stdWriter io.Writer) error {
-	execs, err := c.handler.MigrateUp(c.ctx, c.numOfRuns)
-	_, _ = fmt.Fprintf(stdWriter, "Executed Up() for %d migrations\n", len(execs))
-
-	for _, execMig := range execs {
-		if execMig.Execution != nil {
-			_, _ = fmt.Fprintf(
-				stdWriter, "Executed Up() for %d migration\n",
-				execMig.Execution.Version,
-			)
+	if c.lockfile != "" {
+		planned, err := c.plan()
+		if err != nil {
+			return err
+		}
+		if err = checkLockfile(c.lockfile, c.migrationsDir, planned); err != nil {
+			return err
+		}
+	}
+
+	var skipped []MigrationReport
+	if c.only != "" {
+		skippedMigrations, err := c.handler.SkippedBy(c.onlyVer)
+		if err != nil {
+			return err
 		}
+		for _, mig := range skippedMigrations {
+			skipped = append(skipped, newMigrationReport(mig, nil))
+		}
+	}
+
+	if c.dryRun {
+		planned, err := c.plan()
+		report := newDryRunReport(c.Id(), "up", planned)
+		if err == nil {
+			report.Skipped = skipped
+		}
+		_ = c.output().FormatRun(stdWriter, report)
+		return err
+	}
+
+	if c.sqlOnly != "" {
+		return c.writeSqlScript(stdWriter)
+	}
+
+	ctx := withProgress(c.ctx, c.progress, c.output(), stdWriter)
+	if c.maxDuration > 0 {
+		ctx = handler.WithTimeBudget(ctx, c.maxDuration)
+	}
+	if c.verifyRerun {
+		ctx = handler.WithRerunCheck(ctx)
+	}
+
+	var execs []handler.ExecutedMigration
+	var err error
+	switch {
+	case c.only != "":
+		execs, err = c.handler.MigrateUpOnly(ctx, c.onlyVer)
+	case c.target != "":
+		execs, err = c.handler.MigrateUpTo(ctx, c.targetVer, c.matcher)
+	default:
+		execs, err = c.handler.MigrateUpMatching(ctx, c.numOfRuns, c.matcher)
+	}
+	report := newRunReport(c.Id(), "up", false, execution.RunIdFrom(c.ctx), execs)
+	if len(report.Migrations) > 0 {
+		report.Skipped = skipped
 	}
 
+	// stopping at the time budget is the expected outcome of a time-boxed run, and stopping
+	// before a migration deferred by the gate the one of a gated run
+	var budgetErr *handler.BudgetExhaustedError
+	var deferredErr *handler.GateDeferredError
+	var remaining []migration.Migration
+	switch {
+	case errors.As(err, &budgetErr):
+		remaining = budgetErr.Remaining
+	case errors.As(err, &deferredErr):
+		remaining = deferredErr.Remaining
+		report.Deferred = true
+	}
+	if remaining != nil {
+		for _, mig := range remaining {
+			report.Remaining = append(report.Remaining, newMigrationReport(mig, nil))
+		}
+
+		// the migrations executed before the budget stop may still not be safe to rerun
+		var rerunErr *handler.RerunUnsafeError
+		if errors.As(err, &rerunErr) {
+			err = rerunErr
+		} else {
+			err = nil
+		}
+	}
+
+	_ = c.output().FormatRun(stdWriter, report)
 	return err
 }
 
+// plan returns the migrations the run would execute, in order
+func (c *MigrateUpCommand) plan() ([]migration.Migration, error) {
+	if c.only != "" {
+		return c.handler.PlanUpOnly(c.onlyVer)
+	}
+	if c.target != "" {
+		return c.handler.PlanUpTo(c.targetVer, c.matcher)
+	}
+	return c.handler.PlanUp(c.numOfRuns, c.matcher)
+}
+
 // MigrateDownCommand implements the Command interface to execute the Down() method
 // of migrations that have been previously executed, effectively rolling them back.
 type MigrateDownCommand struct {
-	steps     string
-	numOfRuns handler.NumOfRuns
-	handler   *handler.MigrationsHandler // Handler for executing migrations
-	ctx       context.Context
+	outputFlags
+	confirmation
+	steps      string
+	numOfRuns  handler.NumOfRuns
+	dryRun     bool
+	sqlOnly    string
+	progress   handler.ProgressReporter
+	repository execution.Repository
+	handler    *handler.MigrationsHandler // Handler for executing migrations
+	ctx        context.Context
 }
 
 func (c *MigrateDownCommand) Id() string {
@@ -227,49 +1181,109 @@ func (c *MigrateDownCommand) Description() string {
 }
 
 func (c *MigrateDownCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
 	flagSet.StringVar(
 		&c.steps,
 		"steps",
 		"1",
-		"Number of steps to execute."+" If the number of migrations to execute is not specified, defaults to 1. Allowed"+
-			" values for the number of migrations to run Down(): \"all\", "+
-			"alias for 99999 and a valid"+
-			" integer greater than 0\n"+
-			"Examples: migrate down, migrate down --steps=all, migrate down --steps=3",
+		`
+		Number of steps to execute. Rolls back the N executed migrations with the
+		highest versions, the highest version first. If not specified, defaults to 1.
+		Allowed values for the number of migrations to run Down(): "all",
+		alias for 99999 and a valid integer greater than 0
+		Examples: migrate down, migrate down --steps=all, migrate down --steps=3
+		`,
+	)
+	flagSet.BoolVar(
+		&c.dryRun,
+		"dry-run",
+		false,
+		`
+		Only display which migrations would be rolled back, in order. No Down() is
+		called and no execution is removed.
+		Examples: migrate down --steps=3 --dry-run
+		`,
 	)
+	flagSet.StringVar(
+		&c.sqlOnly,
+		"sql-only",
+		"",
+		`
+		Only write the SQL of the rollbacks to the given file, in order, along with
+		the statements removing their executions, for a DBA to run it manually.
+		All the migrations must be capture capable. No execution is removed.
+		Examples: migrate down --steps=2 --sql-only=rollback.sql
+		`,
+	)
+	c.confirmation.DefineFlags(flagSet)
 }
 
 func (c *MigrateDownCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
 	num, err := handler.NewNumOfRuns(c.steps)
 	if err != nil {
 		return err
 	}
 	c.numOfRuns = num
+
+	if c.sqlOnly != "" && c.dryRun {
+		return errors.New("the sql-only flag can't be combined with the dry-run flag")
+	}
 	return nil
 }
 
 func (c *MigrateDownCommand) Exec(stdWriter io.Writer) error {
-	execs, err := c.handler.MigrateDown(c.ctx, c.numOfRuns)
-	_, _ = fmt.Fprintf(stdWriter, "Executed Down() for %d migrations\n", len(execs))
-
-	for _, execMig := range execs {
-		if execMig.Execution != nil {
-			_, _ = fmt.Fprintf(
-				stdWriter, "Executed Down() for %d migration\n",
-				execMig.Execution.Version,
+	if c.dryRun {
+		planned, err := c.handler.PlanDown(c.numOfRuns)
+		_ = c.output().FormatRun(stdWriter, newDryRunReport(c.Id(), "down", planned))
+		return err
+	}
+
+	if c.sqlOnly != "" {
+		return c.writeSqlScript(stdWriter)
+	}
+
+	if c.interactive && !c.yes {
+		planned, err := c.handler.PlanDown(c.numOfRuns)
+		if err != nil {
+			return err
+		}
+		if len(planned) > 0 {
+			versions := make([]string, 0, len(planned))
+			for _, mig := range planned {
+				versions = append(versions, strconv.FormatUint(mig.Version(), 10))
+			}
+
+			action := fmt.Sprintf(
+				"roll back %d migrations (%s)", len(planned), strings.Join(versions, ", "),
 			)
+			if err = c.confirm(stdWriter, action, false); err != nil {
+				return err
+			}
 		}
 	}
 
+	execs, err := c.handler.MigrateDown(
+		withProgress(c.ctx, c.progress, c.output(), stdWriter), c.numOfRuns,
+	)
+	_ = c.output().FormatRun(
+		stdWriter, newRunReport(c.Id(), "down", false, execution.RunIdFrom(c.ctx), execs),
+	)
 	return err
 }
 
 // MigrateStatsCommand implements the Command interface to display statistics
 // about registered migrations and their execution status.
 type MigrateStatsCommand struct {
-	cli.CommandWithoutFlags
+	outputFlags
 	registry   migration.MigrationsRegistry // Registry containing all available migrations
 	repository execution.Repository         // Repository for accessing migration execution state
+
+	// newExecutionPlan builds the plan the stats are computed from, handler.NewPlan if nil
+	newExecutionPlan handler.ExecutionPlanBuilder
 }
 
 func (c *MigrateStatsCommand) Id() string {
@@ -283,38 +1297,28 @@ func (c *MigrateStatsCommand) Description() string {
 }
 
 func (c *MigrateStatsCommand) Exec(stdWriter io.Writer) error {
-	plan, err := handler.NewPlan(c.registry, c.repository)
+	newExecutionPlan := c.newExecutionPlan
+	if newExecutionPlan == nil {
+		newExecutionPlan = handler.NewPlan
+	}
+	plan, err := newExecutionPlan(c.registry, c.repository)
 
 	if plan != nil {
-		nextMigFile := "N/A"
-		lastMigFile := "N/A"
-		next := plan.NextToExecute()
-		prev := plan.LastExecuted().Migration
+		report := StatsReport{
+			RegisteredCount: plan.RegisteredMigrationsCount(),
+			ExecutionsCount: plan.FinishedExecutionsCount(),
+		}
 
-		if next != nil {
-			nextMigFile = migration.FileNamePrefix + migration.FileNameSeparator +
-				strconv.Itoa(int(next.Version())) + ".go"
+		if next := plan.NextToExecute(); next != nil {
+			nextReport := newMigrationReport(next, nil)
+			report.NextToExecute = &nextReport
 		}
-		if prev != nil {
-			lastMigFile = migration.FileNamePrefix + migration.FileNameSeparator +
-				strconv.Itoa(int(prev.Version())) + ".go"
+		if prev := plan.LastExecuted().Migration; prev != nil {
+			prevReport := newMigrationReport(prev, nil)
+			report.LastExecuted = &prevReport
 		}
 
-		_, _ = fmt.Fprintln(stdWriter, "")
-		_, _ = fmt.Fprintf(
-			stdWriter,
-			"Registered migrations count: %d\n",
-			plan.RegisteredMigrationsCount(),
-		)
-		_, _ = fmt.Fprintf(
-			stdWriter, "Executions count: %d\n", plan.FinishedExecutionsCount(),
-		)
-		_, _ = fmt.Fprintf(
-			stdWriter, "Next to execute migration file: %s\n", nextMigFile,
-		)
-		_, _ = fmt.Fprintf(
-			stdWriter, "Last executed migration file: %s\n", lastMigFile,
-		)
+		_ = c.output().FormatStats(stdWriter, report)
 	}
 
 	return err
@@ -323,33 +1327,199 @@ func (c *MigrateStatsCommand) Exec(stdWriter io.Writer) error {
 // GenerateBlankMigrationCommand implements the Command interface to create a new
 // blank migration file in the configured migrations' directory.
 type GenerateBlankMigrationCommand struct {
-	cli.CommandWithoutFlags
+	outputFlags
 	migrationsDir migration.MigrationsDirPath // Path to the directory where migration files are stored
+	author        string
+	ticket        string
+	templatePath  string
+	template      string // The template contents, defaults to the bootstrap settings one
+	sqlPath       string
+	upSql         string
+	downStub      bool
+	name          string
 }
 
+// AuthorEnvVar is the environment variable used as the default author of generated migrations
+const AuthorEnvVar = "MIGRATIONS_AUTHOR"
+
+// TicketEnvVar is the environment variable used as the default ticket of generated migrations
+const TicketEnvVar = "MIGRATIONS_TICKET"
+
+// TemplateEnvVar is the environment variable used as the default path of the template of
+// generated migrations
+const TemplateEnvVar = "MIGRATIONS_TEMPLATE"
+
 func (c *GenerateBlankMigrationCommand) Id() string {
 	return "blank"
 }
 
 func (c *GenerateBlankMigrationCommand) Description() string {
 	return "Generates a new, blank migrations file in the configured migrations directory" +
-		"\nExamples: migrate blank"
+		"\nExamples: migrate blank, migrate blank --ticket=JIRA-123"
+}
+
+func (c *GenerateBlankMigrationCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.StringVar(
+		&c.author,
+		"author",
+		"",
+		"Author written in the generated file. Defaults to the "+AuthorEnvVar+
+			" environment variable or, if missing, to the git user (name and email).",
+	)
+	flagSet.StringVar(
+		&c.ticket,
+		"ticket",
+		"",
+		"Ticket/issue ID written in the generated file. Defaults to the "+TicketEnvVar+
+			" environment variable.",
+	)
+	flagSet.StringVar(
+		&c.templatePath,
+		"template",
+		"",
+		"Path of a text/template file used instead of the built-in migration skeleton. "+
+			"Defaults to the "+TemplateEnvVar+" environment variable or, if missing, to the "+
+			"template configured in the bootstrap settings.",
+	)
+	flagSet.StringVar(
+		&c.sqlPath,
+		"sql",
+		"",
+		"Path of a SQL script executed by the generated Up(), statement by statement.\n"+
+			"Examples: migrate generate --sql=add_users.sql --down-stub",
+	)
+	flagSet.BoolVar(
+		&c.downStub,
+		"down-stub",
+		false,
+		"Generate a best-effort reverse of the --sql script (DROP for CREATE, reversed "+
+			"renames) in Down(), marked for review.",
+	)
+	flagSet.StringVar(
+		&c.name,
+		"name",
+		"",
+		"Human-readable name of the migration, added to the file name as a slug.\n"+
+			"Examples: migrate generate --name=\"add users\" (version_<timestamp>_add_users.go)",
+	)
+}
+
+func (c *GenerateBlankMigrationCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(c.author) == "" {
+		c.author = os.Getenv(AuthorEnvVar)
+	}
+	if strings.TrimSpace(c.author) == "" {
+		c.author = gitAuthor()
+	}
+	if strings.TrimSpace(c.ticket) == "" {
+		c.ticket = os.Getenv(TicketEnvVar)
+	}
+	if strings.TrimSpace(c.templatePath) == "" {
+		c.templatePath = os.Getenv(TemplateEnvVar)
+	}
+
+	if templatePath := strings.TrimSpace(c.templatePath); templatePath != "" {
+		contents, err := os.ReadFile(templatePath)
+		if err != nil {
+			return fmt.Errorf("failed to read the migration template with error: %w", err)
+		}
+		c.template = string(contents)
+	}
+
+	if c.name != "" {
+		if err := migration.ValidateName(c.name); err != nil {
+			return fmt.Errorf("invalid --name flag: %w", err)
+		}
+	}
+	if c.downStub && c.sqlPath == "" {
+		return errors.New("the --down-stub flag requires the --sql flag")
+	}
+	if c.sqlPath != "" {
+		contents, err := os.ReadFile(c.sqlPath)
+		if err != nil {
+			return fmt.Errorf("failed to read the SQL script with error: %w", err)
+		}
+		c.upSql = string(contents)
+	}
+	return nil
 }
 
 func (c *GenerateBlankMigrationCommand) Exec(stdWriter io.Writer) error {
-	fileName, err := migration.GenerateBlankMigration(c.migrationsDir)
+	return c.generate(stdWriter, "New blank migration file generated: ")
+}
+
+// generate creates the migration file and reports its name, after the message prefix
+func (c *GenerateBlankMigrationCommand) generate(stdWriter io.Writer, msgPrefix string) error {
+	fileName, err := migration.GenerateMigration(
+		c.migrationsDir,
+		migration.GenerateOptions{
+			Author:   c.author,
+			Ticket:   c.ticket,
+			Template: c.template,
+			UpSql:    c.upSql,
+			DownStub: c.downStub,
+			Name:     c.name,
+		},
+	)
 
 	if err != nil {
 		return err
 	}
 
-	_, _ = fmt.Fprintln(stdWriter, "")
-	_, _ = fmt.Fprintln(stdWriter, "New blank migration file generated: "+fileName)
-	_, _ = fmt.Fprintln(stdWriter, "")
-
+	_ = c.output().FormatMessage(stdWriter, msgPrefix+fileName)
 	return nil
 }
 
+// GenerateMigrationCommand implements the Command interface to scaffold a new migration file
+// (version_<unix timestamp>.go, or version_<unix timestamp>_<name>.go with the --name flag) in
+// the configured migrations' directory, with the migration struct, Version(), Up(), Down() and
+// the migration.Register init call pre-filled. It accepts the same flags as the blank command.
+type GenerateMigrationCommand struct {
+	GenerateBlankMigrationCommand
+}
+
+func (c *GenerateMigrationCommand) Id() string {
+	return "generate"
+}
+
+func (c *GenerateMigrationCommand) Description() string {
+	return "Scaffolds a new migration file, with the version, the struct, Version(), Up(), " +
+		"Down() and the registration pre-filled, in the configured migrations directory" +
+		"\nExamples: migrate generate, migrate generate --ticket=JIRA-123, " +
+		"migrate generate --template=migration.tmpl"
+}
+
+func (c *GenerateMigrationCommand) Exec(stdWriter io.Writer) error {
+	return c.generate(stdWriter, "New migration file generated: ")
+}
+
+// gitAuthor returns the configured git user as "name <email>", or an empty string
+// if git is not available or the user is not configured
+func gitAuthor() string {
+	gitConfig := func(key string) string {
+		out, err := exec.Command("git", "config", "--get", key).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	name := gitConfig("user.name")
+	if email := gitConfig("user.email"); email != "" {
+		if name == "" {
+			return email
+		}
+		return name + " <" + email + ">"
+	}
+
+	return name
+}
+
 func getVersionFrom(rawVersion string) (uint64, error) {
 	migVersion, err := strconv.Atoi(rawVersion)
 
@@ -366,6 +1536,7 @@ func getVersionFrom(rawVersion string) (uint64, error) {
 // of a specific migration, even if it has been executed before.
 // This is useful for re-running migrations that need to be applied again.
 type MigrateForceUpCommand struct {
+	outputFlags
 	rawVersion string
 	migVersion uint64
 	handler    *handler.MigrationsHandler // Handler for executing migrations
@@ -383,6 +1554,7 @@ func (c *MigrateForceUpCommand) Description() string {
 }
 
 func (c *MigrateForceUpCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
 	flagSet.StringVar(
 		&c.rawVersion,
 		"version",
@@ -393,6 +1565,10 @@ func (c *MigrateForceUpCommand) DefineFlags(flagSet *flag.FlagSet) {
 }
 
 func (c *MigrateForceUpCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
 	version, err := getVersionFrom(c.rawVersion)
 	if err != nil {
 		return err
@@ -403,16 +1579,11 @@ func (c *MigrateForceUpCommand) ValidateFlags() error {
 
 func (c *MigrateForceUpCommand) Exec(stdWriter io.Writer) error {
 	exec, err := c.handler.ForceUp(c.ctx, c.migVersion)
-
-	if exec.Execution != nil {
-		_, _ = fmt.Fprintf(
-			stdWriter, "Executed Up() forcefully for %d migration\n",
-			exec.Execution.Version,
-		)
-	} else {
-		_, _ = fmt.Fprintln(stdWriter, "No forced Up() migration executed")
-	}
-
+	_ = c.output().FormatRun(
+		stdWriter, newRunReport(
+			c.Id(), "up", true, execution.RunIdFrom(c.ctx), []handler.ExecutedMigration{exec},
+		),
+	)
 	return err
 }
 
@@ -420,6 +1591,8 @@ func (c *MigrateForceUpCommand) Exec(stdWriter io.Writer) error {
 // of a specific migration, even if it hasn't been executed or has already been rolled back.
 // This is useful for forcing the rollback of specific migrations.
 type MigrateForceDownCommand struct {
+	outputFlags
+	confirmation
 	rawVersion string
 	migVersion uint64
 	handler    *handler.MigrationsHandler // Handler for executing migrations
@@ -437,6 +1610,7 @@ func (c *MigrateForceDownCommand) Description() string {
 }
 
 func (c *MigrateForceDownCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
 	flagSet.StringVar(
 		&c.rawVersion,
 		"version",
@@ -444,9 +1618,14 @@ func (c *MigrateForceDownCommand) DefineFlags(flagSet *flag.FlagSet) {
 		"Version number for force down.\n"+
 			"Examples: migrate force:down --version=1712953077",
 	)
+	c.confirmation.DefineFlags(flagSet)
 }
 
 func (c *MigrateForceDownCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
 	version, err := getVersionFrom(c.rawVersion)
 	if err != nil {
 		return err
@@ -456,16 +1635,198 @@ func (c *MigrateForceDownCommand) ValidateFlags() error {
 }
 
 func (c *MigrateForceDownCommand) Exec(stdWriter io.Writer) error {
+	action := fmt.Sprintf("execute Down() for migration version %d", c.migVersion)
+	if err := c.confirm(stdWriter, action, false); err != nil {
+		return err
+	}
+
 	exec, err := c.handler.ForceDown(c.ctx, c.migVersion)
+	_ = c.output().FormatRun(
+		stdWriter, newRunReport(
+			c.Id(), "down", true, execution.RunIdFrom(c.ctx), []handler.ExecutedMigration{exec},
+		),
+	)
+	return err
+}
+
+// MarkExecutedCommand implements the Command interface to record a migration version as
+// executed without calling its Up() method, for changes which were already applied manually or
+// by another tool (baselining)
+type MarkExecutedCommand struct {
+	outputFlags
+	rawVersion string
+	migVersion uint64
+	rawUpTo    string
+	upTo       uint64
+	force      bool
+	handler    *handler.MigrationsHandler // Handler for executing migrations
+	ctx        context.Context
+}
+
+func (c *MarkExecutedCommand) Id() string {
+	return "mark-executed"
+}
+
+func (c *MarkExecutedCommand) Description() string {
+	return "Records the provided migration version as executed, without executing Up(), " +
+		"for changes already applied manually or by another tool.\n" +
+		"Examples: migrate mark-executed --version=1712953077, " +
+		"migrate mark-executed --up-to=1712953077"
+}
+
+func (c *MarkExecutedCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.StringVar(
+		&c.rawVersion,
+		"version",
+		"",
+		"Version number to mark as executed.\n"+
+			"Examples: migrate mark-executed --version=1712953077",
+	)
+	flagSet.StringVar(
+		&c.rawUpTo,
+		"up-to",
+		"",
+		"Baseline: mark all the registered versions up to this one which are not executed "+
+			"yet, at once.\n"+
+			"Examples: migrate mark-executed --up-to=1712953077",
+	)
+	flagSet.BoolVar(
+		&c.force,
+		"force",
+		false,
+		"Record the version even if it is not registered",
+	)
+}
+
+func (c *MarkExecutedCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
+	if c.rawUpTo != "" {
+		if c.rawVersion != "" || c.force {
+			return errors.New("the up-to flag can't be combined with the version or force flags")
+		}
+
+		upTo, err := getVersionFrom(c.rawUpTo)
+		if err != nil {
+			return err
+		}
+		c.upTo = upTo
+		return nil
+	}
+
+	version, err := getVersionFrom(c.rawVersion)
+	if err != nil {
+		return err
+	}
+	c.migVersion = version
+	return nil
+}
 
-	if exec.Execution != nil {
-		_, _ = fmt.Fprintf(
-			stdWriter, "Executed Down() forcefully for %d migration\n",
-			exec.Execution.Version,
+func (c *MarkExecutedCommand) Exec(stdWriter io.Writer) error {
+	if c.rawUpTo != "" {
+		marked, err := c.handler.MarkExecutedUpTo(c.ctx, c.upTo)
+		if err != nil {
+			return err
+		}
+
+		return c.output().FormatMessage(
+			stdWriter,
+			fmt.Sprintf("%d migration versions up to %d marked as executed", len(marked), c.upTo),
 		)
+	}
+
+	if _, err := c.handler.MarkExecuted(c.ctx, c.migVersion, c.force); err != nil {
+		return err
+	}
+
+	return c.output().FormatMessage(
+		stdWriter, fmt.Sprintf("Migration version %d marked as executed", c.migVersion),
+	)
+}
+
+// RedoCommand implements the Command interface to execute Down() then Up() for the executed
+// migration with the highest version (or the provided version), updating the executions. Useful
+// while iterating on a migration, in development.
+type RedoCommand struct {
+	outputFlags
+	confirmation
+	rawVersion string
+	migVersion uint64
+	handler    *handler.MigrationsHandler // Handler for executing migrations
+	ctx        context.Context
+}
+
+func (c *RedoCommand) Id() string {
+	return "redo"
+}
+
+func (c *RedoCommand) Description() string {
+	return "Executes Down() then Up() for the executed migration with the highest version, or " +
+		"for the provided version. Meant for development, while iterating on a migration.\n" +
+		"Examples: migrate redo, migrate redo --version=1712953077"
+}
+
+func (c *RedoCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.StringVar(
+		&c.rawVersion,
+		"version",
+		"",
+		"Version number to redo. Defaults to the executed migration with the highest version.\n"+
+			"Examples: migrate redo --version=1712953077",
+	)
+	c.confirmation.DefineFlags(flagSet)
+}
+
+func (c *RedoCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
+	if c.rawVersion == "" {
+		return nil
+	}
+
+	version, err := getVersionFrom(c.rawVersion)
+	if err != nil {
+		return err
+	}
+	c.migVersion = version
+	return nil
+}
+
+func (c *RedoCommand) Exec(stdWriter io.Writer) error {
+	action := "execute Down() then Up() for the executed migration with the highest version"
+	if c.rawVersion != "" {
+		action = fmt.Sprintf("execute Down() then Up() for migration version %d", c.migVersion)
+	}
+	if err := c.confirm(stdWriter, action, false); err != nil {
+		return err
+	}
+
+	var down, up handler.ExecutedMigration
+	var err error
+	if c.rawVersion == "" {
+		down, up, err = c.handler.RedoLast(c.ctx)
 	} else {
-		_, _ = fmt.Fprintln(stdWriter, "No forced Down() migration executed")
+		down, up, err = c.handler.Redo(c.ctx, c.migVersion)
 	}
 
+	runId := execution.RunIdFrom(c.ctx)
+	if down.Migration != nil {
+		_ = c.output().FormatRun(
+			stdWriter,
+			newRunReport(c.Id(), "down", true, runId, []handler.ExecutedMigration{down}),
+		)
+	}
+	if up.Migration != nil {
+		_ = c.output().FormatRun(
+			stdWriter,
+			newRunReport(c.Id(), "up", true, runId, []handler.ExecutedMigration{up}),
+		)
+	}
 	return err
 }
@@ -1,17 +1,93 @@
 package cli
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"flag"
+	"fmt"
+	"github.com/golibry/go-cli-command/cli"
+	"github.com/golibry/go-migrations/drift"
 	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/history"
+	"github.com/golibry/go-migrations/lock"
+	"github.com/golibry/go-migrations/masking"
 	"github.com/golibry/go-migrations/migration"
+	"github.com/golibry/go-migrations/notify"
+	"github.com/golibry/go-migrations/repeatable"
+	"github.com/golibry/go-migrations/schemadiff"
 	"github.com/stretchr/testify/suite"
 	"io"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
+// bootstrapRun holds the arguments of the Bootstrap calls of a test. The context defaults to
+// context.Background(), the registry to the one of the migrations directory and the repository
+// to a new empty in-memory one.
+type bootstrapRun struct {
+	ctx      context.Context
+	db       any
+	registry migration.MigrationsRegistry
+	repo     execution.Repository
+	migPath  migration.MigrationsDirPath
+	settings *BootstrapSettings
+
+	// exitCode, if not nil, receives the exit code of each run
+	exitCode *int
+}
+
+// run bootstraps the CLI with the args and returns its output and exit code (-1 if it didn't
+// exit)
+func (b bootstrapRun) run(args ...string) (string, int) {
+	ctx := b.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	registry := b.registry
+	if registry == nil {
+		registry = migration.NewEmptyDirMigrationsRegistry(b.migPath)
+	}
+	repo := b.repo
+	if repo == nil {
+		repo = &execution.InMemoryRepository{}
+	}
+
+	var buf bytes.Buffer
+	exitCode := -1
+	Bootstrap(
+		ctx, b.db, args, registry, repo, b.migPath, nil, &buf,
+		func(code int) { exitCode = code }, b.settings,
+	)
+	if b.exitCode != nil {
+		*b.exitCode = exitCode
+	}
+	return buf.String(), exitCode
+}
+
+// output bootstraps the CLI with the args and returns its output
+func (b bootstrapRun) output(args ...string) string {
+	output, _ := b.run(args...)
+	return output
+}
+
+// answering returns a copy of the run with the settings answering its prompts with the input
+func (b bootstrapRun) answering(input string) bootstrapRun {
+	b.settings = &BootstrapSettings{Input: strings.NewReader(input)}
+	return b
+}
+
 type CliTestSuite struct {
 	suite.Suite
 }
@@ -36,7 +112,7 @@ func (suite *CliTestSuite) TestItFailsToBootstrapCliWhenMigrationsHandlerInitFai
 	var buf bytes.Buffer
 	Bootstrap(
 		context.Background(), nil,
-		[]string{}, registry, repo, migPath, nil,
+		[]string{"up"}, registry, repo, migPath, nil,
 		&buf,
 		func(code int) {},
 		nil,
@@ -103,3 +179,2913 @@ func (suite *CliTestSuite) TestItCanRunTheGivenCommand() {
 		)
 	}
 }
+
+func (suite *CliTestSuite) TestItCanGenerateBlankMigrationWithMetadata() {
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	suite.T().Setenv(TicketEnvVar, "ENV-1")
+	var buf bytes.Buffer
+	Bootstrap(
+		context.Background(), nil,
+		[]string{"blank", "--author=Jane"},
+		migration.NewEmptyDirMigrationsRegistry(migPath),
+		&execution.InMemoryRepository{},
+		migPath,
+		nil,
+		&buf,
+		func(code int) {},
+		nil,
+	)
+
+	suite.Assert().Contains(buf.String(), "New blank migration file generated")
+	entries, _ := os.ReadDir(string(migPath))
+	suite.Require().Len(entries, 1)
+	contents, _ := os.ReadFile(filepath.Join(string(migPath), entries[0].Name()))
+	suite.Assert().Contains(string(contents), "// Author: Jane\n")
+	suite.Assert().Contains(string(contents), "// Ticket: ENV-1\n")
+}
+
+func (suite *CliTestSuite) TestItCanScaffoldANewMigration() {
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	var buf bytes.Buffer
+	Bootstrap(
+		context.Background(), nil,
+		[]string{"generate", "--ticket=JIRA-123"},
+		migration.NewEmptyDirMigrationsRegistry(migPath),
+		&execution.InMemoryRepository{},
+		migPath,
+		nil,
+		&buf,
+		func(code int) {},
+		nil,
+	)
+
+	entries, _ := os.ReadDir(string(migPath))
+	suite.Require().Len(entries, 1)
+	suite.Assert().Contains(buf.String(), "New migration file generated: "+entries[0].Name())
+
+	version := strings.TrimSuffix(strings.TrimPrefix(entries[0].Name(), "version_"), ".go")
+	contents, _ := os.ReadFile(filepath.Join(string(migPath), entries[0].Name()))
+	suite.Assert().Contains(string(contents), "migration.Register(&Migration"+version+"{})")
+	suite.Assert().Contains(string(contents), "return "+version+" // Do not edit this!")
+	suite.Assert().Contains(string(contents), "// Ticket: JIRA-123\n")
+}
+
+// fakeSchemaSource returns a fixed schema
+type fakeSchemaSource struct {
+	schema schemadiff.Schema
+}
+
+func (s *fakeSchemaSource) Schema(context.Context) (schemadiff.Schema, error) {
+	return s.schema, nil
+}
+
+func (suite *CliTestSuite) TestItDraftsAMigrationFromTheSchemaDiff() {
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	dumpPath := filepath.Join(suite.T().TempDir(), "schema.sql")
+	_ = os.WriteFile(dumpPath, []byte("CREATE TABLE users (id int NOT NULL, email text);"), 0644)
+
+	settings := &BootstrapSettings{
+		SchemaSource: &fakeSchemaSource{
+			schemadiff.Schema{
+				Dialect: schemadiff.DialectMysql,
+				Tables: []schemadiff.Table{
+					{Name: "users", Columns: []schemadiff.Column{{Name: "id", Type: "int"}}},
+					{Name: "old", Columns: []schemadiff.Column{{Name: "id", Type: "int"}}},
+					{Name: "executions", Columns: []schemadiff.Column{{Name: "id", Type: "int"}}},
+				},
+			},
+		},
+		SchemaIgnoredTables: []string{"executions"},
+	}
+	bootstrap := bootstrapRun{migPath: migPath, settings: settings}
+
+	output := bootstrap.output("diff", "--target="+dumpPath, "--dry-run")
+	suite.Assert().Contains(output, "ALTER TABLE users ADD COLUMN email text;")
+	suite.Assert().Contains(
+		output,
+		"Skipped destructive statements (use --allow-drop to include them):\nDROP TABLE old;",
+	)
+	suite.Assert().NotContains(output, "executions")
+	entries, _ := os.ReadDir(string(migPath))
+	suite.Assert().Empty(entries)
+
+	output = bootstrap.output("diff", "--target="+dumpPath, "--allow-drop")
+	entries, _ = os.ReadDir(string(migPath))
+	suite.Require().Len(entries, 1)
+	suite.Assert().Contains(output, "New migration file generated: "+entries[0].Name())
+
+	contents, _ := os.ReadFile(filepath.Join(string(migPath), entries[0].Name()))
+	suite.Assert().Contains(string(contents), `"ALTER TABLE users ADD COLUMN email text"`)
+	suite.Assert().Contains(string(contents), `"DROP TABLE old"`)
+	suite.Assert().Contains(string(contents), `ALTER TABLE users DROP COLUMN email`)
+
+	suite.Assert().Contains(
+		bootstrap.output("diff", "--target-db=reference"), `unknown target database "reference"`,
+	)
+}
+
+func (suite *CliTestSuite) TestItCanScaffoldMigrationsFromCustomTemplates() {
+	templatePath := filepath.Join(suite.T().TempDir(), "migration.tmpl")
+	_ = os.WriteFile(templatePath, []byte("// from file {{.Ticket}}\n"), 0644)
+
+	scenarios := map[string]struct {
+		args     []string
+		expected string
+	}{
+		"settings template": {[]string{"generate", "--ticket=T-1"}, "// from settings T-1\n"},
+		"template flag": {
+			[]string{"generate", "--ticket=T-1", "--template=" + templatePath},
+			"// from file T-1\n",
+		},
+	}
+
+	for name, scenario := range scenarios {
+		migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+		var buf bytes.Buffer
+		Bootstrap(
+			context.Background(), nil, scenario.args,
+			migration.NewEmptyDirMigrationsRegistry(migPath), &execution.InMemoryRepository{},
+			migPath, nil, &buf, func(code int) {},
+			&BootstrapSettings{MigrationTemplate: "// from settings {{.Ticket}}\n"},
+		)
+
+		entries, _ := os.ReadDir(string(migPath))
+		suite.Require().Len(entries, 1, "scenario %s", name)
+		contents, _ := os.ReadFile(filepath.Join(string(migPath), entries[0].Name()))
+		suite.Assert().Equal(scenario.expected, string(contents), "scenario %s", name)
+	}
+}
+
+func (suite *CliTestSuite) TestItCanScaffoldSqlMigrationsWithADownStub() {
+	sqlPath := filepath.Join(suite.T().TempDir(), "add_users.sql")
+	_ = os.WriteFile(sqlPath, []byte("CREATE TABLE users (id INT);"), 0644)
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{migPath: migPath}
+
+	suite.Assert().Contains(bootstrap.output("generate", "--down-stub"), "requires the --sql flag")
+	bootstrap.output("generate", "--sql="+sqlPath, "--down-stub")
+
+	entries, _ := os.ReadDir(string(migPath))
+	suite.Require().Len(entries, 1)
+	contents, _ := os.ReadFile(filepath.Join(string(migPath), entries[0].Name()))
+	suite.Assert().Contains(string(contents), `"CREATE TABLE users (id INT)"`)
+	suite.Assert().Contains(string(contents), `"DROP TABLE IF EXISTS users"`)
+}
+
+func (suite *CliTestSuite) TestItCanScaffoldANamedMigration() {
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	bootstrap := bootstrapRun{migPath: migPath}
+
+	suite.Assert().Contains(bootstrap.output("generate", "--name=--"), "has no letters or digits")
+	suite.Assert().Contains(
+		bootstrap.output("generate", "--name=users test"),
+		`ends with "test", which the go build treats`,
+	)
+	suite.Assert().Contains(
+		bootstrap.output("generate", "--name=fix Windows"),
+		`ends with "windows", which the go build treats`,
+	)
+	output := bootstrap.output("blank", "--name=Add users")
+
+	entries, _ := os.ReadDir(string(migPath))
+	suite.Require().Len(entries, 1)
+	suite.Assert().Regexp(`^version_\d+_add_users\.go$`, entries[0].Name())
+	suite.Assert().Contains(output, "New blank migration file generated: "+entries[0].Name())
+}
+
+type describedMigration struct {
+	migration.DummyMigration
+}
+
+func (m *describedMigration) Metadata() migration.Metadata {
+	return migration.Metadata{Description: "add users phone index", Ticket: "JIRA-1"}
+}
+
+// describableMigration only describes itself (see migration.Describable)
+type describableMigration struct {
+	migration.DummyMigration
+}
+
+func (m *describableMigration) Description() string {
+	return "backfill users phone"
+}
+
+func (suite *CliTestSuite) TestItShowsTheDescriptionsOfDescribableMigrations() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(&describableMigration{*migration.NewDummyMigration(1)})
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	suite.Assert().Contains(bootstrap.output("status"), "version_1.go [backfill users phone]")
+	suite.Assert().Contains(bootstrap.output("plan"), "backfill users phone")
+	bootstrap.output("up")
+	suite.Assert().Regexp(`  1 \(backfill users phone\) executed at `, bootstrap.output("history"))
+	suite.Assert().Contains(
+		bootstrap.output("history", "--format=json"), `"description":"backfill users phone"`,
+	)
+	suite.Assert().Contains(bootstrap.output("history", "--format=table"), "backfill users phone")
+}
+
+func (suite *CliTestSuite) TestItShowsMigrationsMetadataInStats() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(&describedMigration{*migration.NewDummyMigration(1)})
+	var buf bytes.Buffer
+
+	err := (&MigrateStatsCommand{
+		registry: registry, repository: &execution.InMemoryRepository{},
+	}).Exec(&buf)
+
+	suite.Assert().NoError(err)
+	suite.Assert().Contains(
+		buf.String(),
+		"Next to execute migration file: version_1.go [add users phone index; ticket: JIRA-1]",
+	)
+}
+
+type recordingCommand struct {
+	cli.CommandWithoutFlags
+	executed bool
+}
+
+func (c *recordingCommand) Id() string {
+	return "recording"
+}
+
+func (c *recordingCommand) Description() string {
+	return "records its execution"
+}
+
+func (c *recordingCommand) Exec(_ io.Writer) error {
+	c.executed = true
+	return nil
+}
+
+func (suite *CliTestSuite) TestItSkipsLockableCommandsWhenTheLockIsHeld() {
+	ctx := context.Background()
+	lockFilePath := LockFilePath(suite.T().TempDir(), MigrationsCmdLockName)
+	holder := lock.NewFileLocker(lockFilePath)
+	suite.Require().NoError(holder.Lock(ctx))
+
+	cmd := &recordingCommand{}
+	lockable := NewLockableCommand(ctx, cmd, lock.NewFileLocker(lockFilePath))
+
+	suite.Assert().ErrorIs(lockable.Exec(io.Discard), cli.CommandLocked)
+	suite.Assert().False(cmd.executed)
+
+	suite.Require().NoError(holder.Unlock(ctx))
+	suite.Assert().NoError(lockable.Exec(io.Discard))
+	suite.Assert().True(cmd.executed)
+
+	// The lock must be released after the execution
+	suite.Assert().NoError(holder.Lock(ctx))
+	suite.Assert().NoError(holder.Unlock(ctx))
+}
+
+func (suite *CliTestSuite) TestItKeepsTheLockFileNameFormat() {
+	suite.Assert().Equal(
+		filepath.Join("locks", "go-cli-command-app-go-migrations-"+
+			"01a8c8ea3f5013e868b0b10e3e469484.lock"),
+		LockFilePath("locks", MigrationsCmdLockName),
+	)
+}
+
+func (suite *CliTestSuite) TestItScopesTheLockNameToTheLockTarget() {
+	scenarios := map[string]struct {
+		settings     BootstrapSettings
+		expectedName string
+	}{
+		"default name": {BootstrapSettings{}, MigrationsCmdLockName},
+		"custom name":  {BootstrapSettings{MigrationsCmdLockName: " my-lock "}, "my-lock"},
+		"default name with target": {
+			BootstrapSettings{LockTarget: "postgres://localhost/db1"},
+			MigrationsCmdLockName + "-588944fbb182",
+		},
+	}
+
+	for name, scenario := range scenarios {
+		suite.Assert().Equal(scenario.expectedName, scenario.settings.LockName(), name)
+	}
+
+	db1 := BootstrapSettings{LockTarget: "postgres://localhost/db1"}
+	db2 := BootstrapSettings{LockTarget: "postgres://localhost/db2"}
+	suite.Assert().NotEqual(db1.LockName(), db2.LockName())
+	suite.Assert().NotContains(db1.LockName(), "localhost")
+}
+
+type fakeLocker struct {
+	lockName string
+	locked   bool
+}
+
+func (l *fakeLocker) Lock(_ context.Context) error {
+	if l.locked {
+		return lock.ErrLockHeld
+	}
+	l.locked = true
+	return nil
+}
+
+func (l *fakeLocker) Unlock(_ context.Context) error {
+	l.locked = false
+	return nil
+}
+
+func (suite *CliTestSuite) TestItUsesTheConfiguredLocker() {
+	var lockers []*fakeLocker
+	settings := &BootstrapSettings{
+		RunMigrationsExclusively: true,
+		LockTarget:               "postgres://localhost/db1",
+		NewLocker: func(lockName string) lock.Locker {
+			locker := &fakeLocker{lockName: lockName}
+			lockers = append(lockers, locker)
+			return locker
+		},
+	}
+
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	var buf bytes.Buffer
+	Bootstrap(
+		context.Background(), nil,
+		[]string{"up"},
+		migration.NewEmptyDirMigrationsRegistry(migPath),
+		&execution.InMemoryRepository{},
+		migPath,
+		nil,
+		&buf,
+		func(code int) {},
+		settings,
+	)
+
+	suite.Assert().Contains(buf.String(), "Executed Up() for 0 migrations")
+	suite.Require().NotEmpty(lockers)
+	for _, locker := range lockers {
+		suite.Assert().Equal(settings.LockName(), locker.lockName)
+		suite.Assert().False(locker.locked)
+	}
+}
+
+// targetedRepository identifies the database it records the executions of
+type targetedRepository struct {
+	execution.InMemoryRepository
+	target string
+}
+
+func (r *targetedRepository) Target() (string, error) {
+	return r.target, nil
+}
+
+func (suite *CliTestSuite) TestItScopesTheLockToTheDatabaseOfTheRepositoryByDefault() {
+	lockNames := func(repo execution.Repository, lockTarget string) []string {
+		var names []string
+		settings := &BootstrapSettings{
+			RunMigrationsExclusively: true,
+			LockTarget:               lockTarget,
+			NewLocker: func(lockName string) lock.Locker {
+				names = append(names, lockName)
+				return &fakeLocker{lockName: lockName}
+			},
+		}
+		migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+		bootstrapRun{repo: repo, migPath: migPath, settings: settings}.output("up")
+
+		suite.Require().NotEmpty(names)
+		return slices.Compact(names)
+	}
+
+	db1 := &targetedRepository{target: "mysql://db:3306/db1/migration_executions"}
+	db2 := &targetedRepository{target: "mysql://db:3306/db2/migration_executions"}
+	suite.Assert().Equal(
+		[]string{(&BootstrapSettings{LockTarget: db1.target}).LockName()}, lockNames(db1, ""),
+	)
+	suite.Assert().NotEqual(lockNames(db1, ""), lockNames(db2, ""))
+	suite.Assert().Equal(lockNames(db1, "tenant-1"), lockNames(db2, "tenant-1"))
+	suite.Assert().Equal(
+		[]string{MigrationsCmdLockName}, lockNames(&execution.InMemoryRepository{}, ""),
+	)
+}
+
+func (suite *CliTestSuite) TestItInspectsAndBreaksTheRunLock() {
+	settings := &BootstrapSettings{RunLockFilesDirPath: suite.T().TempDir()}
+	lockPath := LockFilePath(settings.RunLockFilesDirPath, settings.LockName())
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{migPath: migPath, settings: settings}
+
+	suite.Assert().Contains(bootstrap.output("unlock", "--inspect"), "The lock is not held")
+
+	holder := lock.NewFileLocker(lockPath)
+	suite.Require().NoError(holder.Lock(context.Background()))
+	defer func() {
+		_ = holder.Unlock(context.Background())
+	}()
+
+	suite.Assert().Contains(
+		bootstrap.output("unlock", "--inspect"),
+		"The lock is held by pid "+strconv.Itoa(os.Getpid()),
+	)
+	suite.Assert().Contains(bootstrap.output("unlock"), "use --force to break it")
+	suite.Assert().FileExists(lockPath)
+
+	suite.Assert().Contains(bootstrap.output("unlock", "--force"), "The lock was broken")
+	suite.Assert().NoFileExists(lockPath)
+}
+
+func (suite *CliTestSuite) TestItRunsTheConfiguredCommandHooks() {
+	var calls []string
+	settings := &BootstrapSettings{
+		CommandHooks: map[string]CommandHooks{
+			"up": {
+				Before: func(_ context.Context, cmdId string) error {
+					calls = append(calls, "before "+cmdId)
+					return nil
+				},
+				After: func(_ context.Context, cmdId string, cmdErr error) error {
+					calls = append(calls, "after "+cmdId)
+					suite.Assert().NoError(cmdErr)
+					return errors.New("notification failed")
+				},
+			},
+			"down": {
+				Before: func(_ context.Context, cmdId string) error {
+					return errors.New("not allowed")
+				},
+			},
+		},
+	}
+
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	bootstrap := bootstrapRun{migPath: migPath, settings: settings}
+
+	output := bootstrap.output("up")
+	suite.Assert().Equal([]string{"before up", "after up"}, calls)
+	suite.Assert().Contains(output, "Executed Up() for 0 migrations")
+	suite.Assert().Contains(output, "after hook of command up failed with error: notification failed")
+
+	output = bootstrap.output("down")
+	suite.Assert().NotContains(output, "Executed Down()")
+	suite.Assert().Contains(output, "before hook of command down failed with error: not allowed")
+
+	calls = nil
+	bootstrap.output("stats")
+	suite.Assert().Empty(calls)
+}
+
+func (suite *CliTestSuite) TestItRecordsAndVerifiesTheHistory() {
+	settings := &BootstrapSettings{
+		HistoryStore: history.NewFileStore(filepath.Join(suite.T().TempDir(), "history.jsonl")),
+	}
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath, settings: settings}
+
+	bootstrap.output("up", "--steps=all")
+	suite.Assert().Contains(
+		bootstrap.output("history:verify"), "History verified: 2 records, no tampering detected",
+	)
+
+	repo.PersistedExecutions[0].FinishedAtMs++
+	suite.Assert().Contains(
+		bootstrap.output("history:verify"), "execution 1 does not match its history record",
+	)
+}
+
+func (suite *CliTestSuite) TestItBatchesTheHistoryAndThrottlesTheWrites() {
+	historyPath := filepath.Join(suite.T().TempDir(), "history.jsonl")
+	settings := &BootstrapSettings{
+		HistoryStore:     history.NewFileStore(historyPath),
+		HistoryBatchSize: 10,
+		WriteInterval:    10 * time.Millisecond,
+	}
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var buf bytes.Buffer
+	exitCode := -1
+	start := time.Now()
+	Bootstrap(
+		context.Background(), nil, []string{"up", "--steps=all"}, registry, repo, migPath, nil,
+		&buf, func(code int) {
+			exitCode = code
+			// the records of the run are flushed before the process exits
+			records, err := history.NewFileStore(historyPath).Load(context.Background())
+			suite.Require().NoError(err)
+			suite.Assert().Len(records, 2)
+		}, settings,
+	)
+	suite.Assert().Equal(ExitCodeOk, exitCode)
+	suite.Assert().Contains(buf.String(), "Executed Up() for 2 migrations")
+	// the executions are saved one write interval apart
+	suite.Assert().GreaterOrEqual(time.Since(start), 10*time.Millisecond)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+
+	// a failed flush fails the run
+	settings.HistoryStore = failingHistoryStore{settings.HistoryStore}
+	buf.Reset()
+	Bootstrap(
+		context.Background(), nil, []string{"down", "--steps=1", "--yes"}, registry, repo,
+		migPath, nil, &buf, func(code int) { exitCode = code }, settings,
+	)
+	suite.Assert().Equal(ExitCodeRepository, exitCode)
+	suite.Assert().Contains(
+		buf.String(), "History flush failed: failed to flush 1 history records with error: full",
+	)
+}
+
+// failingHistoryStore is a history store which loads the records, but fails to append them
+type failingHistoryStore struct {
+	history.Store
+}
+
+func (s failingHistoryStore) Append(context.Context, history.Record) error {
+	return errors.New("full")
+}
+
+func (suite *CliTestSuite) TestItCanMigrateUpOnlyMatchingMigrations() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(2024010101))
+	_ = registry.Register(migration.NewDummyMigration(2024020101))
+	_ = registry.Register(migration.NewDummyMigration(2025010101))
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	suite.Assert().Contains(
+		bootstrap.output("up", "--steps=all", "--match=2024*"), "Executed Up() for 2 migrations",
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+	suite.Assert().Contains(
+		bootstrap.output("up", "--match=2024["), "invalid migrations match pattern",
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+}
+
+func (suite *CliTestSuite) TestItCanMigrateUpToATargetVersion() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	suite.Assert().Contains(bootstrap.output("up", "--target=2"), "Executed Up() for 2 migrations")
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+	suite.Assert().Contains(
+		bootstrap.output("up", "--target=v3"), "migration version must be a valid",
+	)
+	suite.Assert().Contains(bootstrap.output("up", "--target=4"), "the version is not registered")
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+}
+
+func (suite *CliTestSuite) TestItCanMigrateUpOnlyOneVersionOutOfOrder() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	settings := &BootstrapSettings{}
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath, settings: settings}
+
+	suite.Assert().Contains(
+		bootstrap.output("up", "--only=3"), "the version 3 can't run out of order",
+	)
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	settings.OutOfOrder = true
+	output := bootstrap.output("up", "--only=3", "--dry-run")
+	suite.Assert().Contains(output, "Warning: executing out of order, 2 earlier migrations are")
+	suite.Assert().Contains(output, "Skipped version_1.go\nSkipped version_2.go\n")
+	suite.Assert().Contains(output, "Would execute Up() for version_3.go")
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	output = bootstrap.output("up", "--only=3", "--format=json")
+	suite.Assert().Contains(output, `"skipped":[{"version":1`)
+	suite.Require().Len(repo.PersistedExecutions, 1)
+	suite.Assert().Equal(uint64(3), repo.PersistedExecutions[0].Version)
+
+	output = bootstrap.output("up", "--only=3")
+	suite.Assert().Contains(output, "the version 3 is already executed")
+	suite.Assert().NotContains(output, "Warning")
+	suite.Assert().Contains(bootstrap.output("up", "--only=2", "--target=3"), "can't be combined")
+
+	suite.Assert().Contains(bootstrap.output("up", "--steps=all"), "Executed Up() for 2 migrations")
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+}
+
+func (suite *CliTestSuite) TestItCanRollBackTheLastMigrations() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3, 4} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	bootstrap.output("up", "--steps=all")
+	suite.Assert().Contains(
+		bootstrap.output("down", "--steps=2", "--format=json"),
+		`"migrations":[{"version":4,`,
+	)
+	suite.Require().Len(repo.PersistedExecutions, 2)
+	suite.Assert().Equal(uint64(1), repo.PersistedExecutions[0].Version)
+	suite.Assert().Equal(uint64(2), repo.PersistedExecutions[1].Version)
+}
+
+func (suite *CliTestSuite) TestItDisplaysTheMigrationsOfADryRun() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	output := bootstrap.output("up", "--steps=all", "--dry-run")
+	suite.Assert().Contains(output, "Dry run, would execute Up() for 3 migrations")
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	suite.Assert().Contains(
+		bootstrap.output("up", "--target=2", "--dry-run", "--format=json"),
+		`"dryRun":true`,
+	)
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	bootstrap.output("up", "--steps=all")
+	output = bootstrap.output("down", "--steps=2", "--dry-run")
+	suite.Assert().Contains(output, "Dry run, would execute Down() for 2 migrations")
+	suite.Assert().Less(
+		strings.Index(output, "_3.go"),
+		strings.Index(output, "_2.go"),
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+}
+
+// transactionalMigration declares whether it runs in a transaction (see
+// migration.Transactional)
+type transactionalMigration struct {
+	migration.DummyMigration
+	transactional bool
+}
+
+func (m *transactionalMigration) Transactional() bool {
+	return m.transactional
+}
+
+func (suite *CliTestSuite) TestItPrintsThePlanOfARun() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(&transactionalMigration{*migration.NewDummyMigration(1), true})
+	_ = registry.Register(&transactionalMigration{*migration.NewDummyMigration(2), false})
+	_ = registry.Register(migration.NewDummyMigration(3))
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	output := bootstrap.output("plan")
+	suite.Assert().Contains(output, "Plan of Up() for 3 migrations")
+	suite.Assert().Contains(output, "1. Up() version_1.go (transaction: yes)")
+	suite.Assert().Contains(output, "2. Up() version_2.go (transaction: no)")
+	suite.Assert().Contains(output, "3. Up() version_3.go (transaction: undeclared)")
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	suite.Assert().Contains(
+		bootstrap.output("plan", "--target=1", "--format=json"),
+		`{"direction":"up","steps":[{"version":1,"file":"version_1.go","direction":"up",`+
+			`"transactional":true}]}`,
+	)
+
+	bootstrap.output("up", "--steps=all")
+	output = bootstrap.output("plan", "--down", "--steps=2", "--format=table")
+	suite.Assert().Regexp(`(?m)^3\s+down\s+undeclared\s*$`, output)
+	suite.Assert().Less(strings.Index(output, "3 "), strings.Index(output, "2 "))
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+	suite.Assert().Contains(
+		bootstrap.output("plan", "--down", "--target=1"), "the target version can only be used",
+	)
+}
+
+// capturedMigration records its statement in capture mode (see migration.WithCapture)
+type capturedMigration struct {
+	migration.DummyMigration
+	statement string
+}
+
+func (m *capturedMigration) Up(ctx context.Context, _ any) error {
+	if capture, ok := migration.CaptureFromContext(ctx); ok {
+		capture.Add(m.statement)
+		return nil
+	}
+	return errors.New("the migration must only be captured")
+}
+
+func (m *capturedMigration) Down(ctx context.Context, _ any) error {
+	if capture, ok := migration.CaptureFromContext(ctx); ok {
+		capture.Add("-- revert: " + m.statement)
+		return nil
+	}
+	return errors.New("the migration must only be captured")
+}
+
+func (m *capturedMigration) CaptureCapable() bool {
+	return true
+}
+
+// scriptedRepository describes the statements which save and remove an execution
+type scriptedRepository struct {
+	execution.InMemoryRepository
+}
+
+func (r *scriptedRepository) SaveStatement(exec execution.MigrationExecution) string {
+	return fmt.Sprintf("INSERT INTO executions VALUES (%d, '%s')", exec.Version, exec.RunId)
+}
+
+func (r *scriptedRepository) RemoveStatement(exec execution.MigrationExecution) string {
+	return fmt.Sprintf("DELETE FROM executions WHERE version = %d", exec.Version)
+}
+
+func (suite *CliTestSuite) TestItWritesTheSqlOfTheMigrationsToAScript() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(
+		&capturedMigration{*migration.NewDummyMigration(1), "CREATE TABLE users (id INT);"},
+	)
+	_ = registry.Register(
+		&capturedMigration{*migration.NewDummyMigration(2), "ALTER TABLE users ADD name TEXT"},
+	)
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	scriptPath := filepath.Join(suite.T().TempDir(), "out.sql")
+
+	repo := &scriptedRepository{}
+	bootstrap := bootstrapRun{
+		ctx: execution.WithRunId(context.Background(), "run-1"), registry: registry, repo: repo,
+		migPath: migPath,
+	}
+	output, exitCode := bootstrap.run("up", "--steps=all", "--sql-only", scriptPath)
+	suite.Assert().Equal(ExitCodeOk, exitCode)
+	suite.Assert().Contains(
+		output, "Wrote the SQL of 2 migrations, with the recording of their executions",
+	)
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	script, err := os.ReadFile(scriptPath)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(
+		"-- go-migrations up script of run run-1\n\n"+
+			"-- migration 1\nCREATE TABLE users (id INT);\n"+
+			"INSERT INTO executions VALUES (1, 'run-1');\n\n"+
+			"-- migration 2\nALTER TABLE users ADD name TEXT;\n"+
+			"INSERT INTO executions VALUES (2, 'run-1');\n",
+		string(script),
+	)
+
+	inMemory := bootstrap
+	inMemory.repo = &execution.InMemoryRepository{}
+	output = inMemory.output("up", "--sql-only="+scriptPath)
+	suite.Assert().Contains(output, "mark them as executed with the mark-executed command")
+
+	_ = registry.Register(migration.NewDummyMigration(3))
+	output, exitCode = bootstrap.run("up", "--steps=all", "--sql-only="+scriptPath)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Contains(output, "the migrations [3] are not capture capable")
+
+	_, exitCode = bootstrap.run("up", "--sql-only="+scriptPath, "--dry-run")
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+}
+
+func (suite *CliTestSuite) TestItWritesTheSqlOfTheRollbacksToAScript() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(
+		&capturedMigration{*migration.NewDummyMigration(1), "CREATE TABLE users (id INT)"},
+	)
+	_ = registry.Register(
+		&capturedMigration{*migration.NewDummyMigration(2), "ALTER TABLE users ADD name TEXT"},
+	)
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	scriptPath := filepath.Join(suite.T().TempDir(), "rollback.sql")
+	repo := &scriptedRepository{}
+	_ = repo.SaveAll(
+		[]execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+			{Version: 2, ExecutedAtMs: 3, FinishedAtMs: 4},
+		},
+	)
+
+	var buf bytes.Buffer
+	exitCode := 0
+	Bootstrap(
+		execution.WithRunId(context.Background(), "run-2"), nil,
+		[]string{"down", "--steps=all", "--sql-only=" + scriptPath}, registry, repo, migPath,
+		nil, &buf, func(code int) { exitCode = code }, nil,
+	)
+	suite.Assert().Equal(ExitCodeOk, exitCode)
+	suite.Assert().Contains(
+		buf.String(), "Wrote the SQL of 2 rollbacks, with the removal of their executions",
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+
+	script, err := os.ReadFile(scriptPath)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(
+		"-- go-migrations down script of run run-2\n\n"+
+			"-- migration 2\n-- revert: ALTER TABLE users ADD name TEXT;\n"+
+			"DELETE FROM executions WHERE version = 2;\n\n"+
+			"-- migration 1\n-- revert: CREATE TABLE users (id INT);\n"+
+			"DELETE FROM executions WHERE version = 1;\n",
+		string(script),
+	)
+}
+
+func (suite *CliTestSuite) TestItMarksAMigrationAsExecuted() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	suite.Assert().Contains(
+		bootstrap.output("mark-executed", "--version=1"), "Migration version 1 marked as executed",
+	)
+	suite.Require().Len(repo.PersistedExecutions, 1)
+	suite.Assert().True(repo.PersistedExecutions[0].Finished())
+
+	suite.Assert().Contains(
+		bootstrap.output("mark-executed", "--version=2"), "the version is not registered",
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+
+	bootstrap.output("mark-executed", "--version=2", "--force")
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+
+	for _, version := range []uint64{3, 4, 5} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	suite.Assert().Contains(
+		bootstrap.output("mark-executed", "--up-to=4"),
+		"2 migration versions up to 4 marked as executed",
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 4)
+	suite.Assert().Contains(
+		bootstrap.output("mark-executed", "--up-to=4", "--force"), "can't be combined",
+	)
+}
+
+func (suite *CliTestSuite) TestItBaselinesAnExistingDatabase() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var exitCode int
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath, exitCode: &exitCode}
+
+	suite.Assert().Contains(bootstrap.output("baseline"), "the baseline version is required")
+	suite.Assert().Contains(
+		bootstrap.output("baseline", "--version=4"), "the version 4 is not registered",
+	)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	suite.Assert().Contains(
+		bootstrap.output("baseline", "--version=2"), "Baselined 2 migration versions up to 2",
+	)
+	suite.Require().Len(repo.PersistedExecutions, 2)
+	suite.Assert().True(repo.PersistedExecutions[1].Finished())
+
+	suite.Assert().Contains(
+		bootstrap.output("baseline", "--version=latest"), "the repository already has 2 executions",
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+
+	repo.PersistedExecutions = nil
+	suite.Assert().Contains(
+		bootstrap.output("baseline", "--version=latest"), "Baselined 3 migration versions up to 3",
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+}
+
+func (suite *CliTestSuite) TestItRedoesTheLastMigration() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	bootstrap.output("up", "--steps=all")
+	output := bootstrap.output("redo")
+	suite.Assert().Contains(output, "Executed Down() forcefully for 2 migration")
+	suite.Assert().Contains(output, "Executed Up() forcefully for 2 migration")
+	suite.Assert().Less(
+		strings.Index(output, "Down()"),
+		strings.Index(output, "Up()"),
+	)
+
+	suite.Assert().Contains(
+		bootstrap.output("redo", "--version=1"), "Executed Up() forcefully for 1 migration",
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+}
+
+func (suite *CliTestSuite) TestItReportsTheDriftBetweenEnvironments() {
+	staging, production := &execution.InMemoryRepository{}, &execution.InMemoryRepository{}
+	for _, version := range []uint64{1, 2} {
+		exec := execution.MigrationExecution{Version: version, ExecutedAtMs: 1, FinishedAtMs: 2}
+		staging.PersistedExecutions = append(staging.PersistedExecutions, exec)
+	}
+	production.PersistedExecutions = staging.PersistedExecutions[:1]
+
+	settings := &BootstrapSettings{
+		Environments: map[string]drift.Environment{
+			"staging":    {Repository: staging},
+			"production": {Repository: production},
+		},
+	}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	bootstrap := bootstrapRun{migPath: migPath, settings: settings}
+
+	output, exitCode := bootstrap.run("drift", "--from=staging", "--to=production")
+	suite.Assert().Contains(output, "Applied only in staging: 1\n  2\n")
+	suite.Assert().Contains(output, "Drift detected")
+	suite.Assert().NotZero(exitCode)
+
+	production.PersistedExecutions = staging.PersistedExecutions
+	output, exitCode = bootstrap.run("drift", "--from=staging", "--to=production", "--format=json")
+	suite.Assert().Contains(output, `"onlyInFrom":[]`)
+	suite.Assert().Zero(exitCode)
+
+	output, _ = bootstrap.run("drift", "--from=staging", "--to=qa")
+	suite.Assert().Contains(
+		output, `unknown environment "qa", the configured ones are: production, staging`,
+	)
+}
+
+func (suite *CliTestSuite) TestItRunsTheReadOnlyCommandsWhileARunIsInProgress() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	// the executions storage can't be initialized while a run alters it
+	repo := &execution.InMemoryRepository{InitErr: errors.New("lock wait timeout exceeded")}
+	settings := &BootstrapSettings{
+		RunMigrationsExclusively: true, RunLockFilesDirPath: suite.T().TempDir(),
+	}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	holder := lock.NewFileLocker(LockFilePath(settings.RunLockFilesDirPath, settings.LockName()))
+	suite.Require().NoError(holder.Lock(context.Background()))
+	defer func() {
+		_ = holder.Unlock(context.Background())
+	}()
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath, settings: settings}
+
+	for _, args := range [][]string{{"status"}, {"stats"}, {"--version"}, {"describe"}} {
+		_, exitCode := bootstrap.run(args...)
+		suite.Assert().Zero(exitCode, args)
+	}
+	output, _ := bootstrap.run("status")
+	suite.Assert().Contains(output, "Pending migrations: 1\n")
+
+	suite.Assert().Panics(func() { bootstrap.run("up") })
+}
+
+func (suite *CliTestSuite) TestItWaitsForTheLockUpToTheLockTimeout() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	settings := &BootstrapSettings{
+		RunMigrationsExclusively: true, RunLockFilesDirPath: suite.T().TempDir(),
+		LockTimeout: time.Minute,
+	}
+
+	holder := lock.NewFileLocker(LockFilePath(settings.RunLockFilesDirPath, settings.LockName()))
+	suite.Require().NoError(holder.Lock(context.Background()))
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath, settings: settings}
+
+	// the flag overrides the settings, 0 failing immediately
+	_, exitCode := bootstrap.run("up", "--lock-timeout=0")
+	suite.Assert().Equal(ExitCodeLocked, exitCode)
+	_, exitCode = bootstrap.run("--lock-timeout=200ms", "up")
+	suite.Assert().Equal(ExitCodeLocked, exitCode)
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	time.AfterFunc(200*time.Millisecond, func() { _ = holder.Unlock(context.Background()) })
+	output, exitCode := bootstrap.run("up")
+	suite.Assert().Zero(exitCode)
+	suite.Assert().Contains(output, "Executed Up() for 1 migrations")
+
+	output, exitCode = bootstrap.run("up", "--lock-timeout=soon")
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Contains(output, `invalid --lock-timeout value "soon"`)
+}
+
+func (suite *CliTestSuite) TestItTellsWhoIsRunningTheMigrations() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	startedAt := time.Now().Add(-time.Minute)
+	repo := &execution.InMemoryRepository{
+		PersistedExecutions: []execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: uint64(startedAt.UnixMilli()), FinishedAtMs: 1},
+			{Version: 2, ExecutedAtMs: uint64(startedAt.UnixMilli()), RunId: "run-1"},
+		},
+	}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	settings := &BootstrapSettings{}
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath, settings: settings}
+
+	// without the lock, the unfinished executions tell a run is in progress
+	output, exitCode := bootstrap.run("who", "--exit-code")
+	suite.Assert().Contains(output, "A run appears to be in progress\n")
+	suite.Assert().Contains(output, "lock: not inspected")
+	suite.Assert().Contains(output, "version 2: unfinished, started at")
+	suite.Assert().Contains(output, "(1m0s ago), run run-1\n")
+	suite.Assert().NotContains(output, "version 1:")
+	suite.Assert().Equal(ExitCodeFailure, exitCode)
+
+	settings.RunMigrationsExclusively = true
+	settings.RunLockFilesDirPath = suite.T().TempDir()
+	output, exitCode = bootstrap.run("who", "--exit-code")
+	suite.Assert().Contains(
+		output, "No run appears to be in progress, the unfinished executions were interrupted\n",
+	)
+	suite.Assert().Contains(output, "lock: not held\n")
+	suite.Assert().Zero(exitCode)
+
+	holder := lock.NewFileLocker(LockFilePath(settings.RunLockFilesDirPath, settings.LockName()))
+	suite.Require().NoError(holder.Lock(execution.WithRunId(context.Background(), "run-1")))
+	defer func() {
+		_ = holder.Unlock(context.Background())
+	}()
+
+	hostname, _ := os.Hostname()
+	output, _ = bootstrap.run("who")
+	suite.Assert().Contains(output, "A run appears to be in progress\n")
+	suite.Assert().Contains(
+		output, fmt.Sprintf("lock: held by pid %d on %s since", os.Getpid(), hostname),
+	)
+	suite.Assert().Contains(output, ", run run-1\n")
+
+	output, exitCode = bootstrap.run("who", "--format=json")
+	suite.Assert().Contains(output, `"active":true`)
+	suite.Assert().Contains(output, `"runId":"run-1"`)
+	suite.Assert().Contains(output, `"unfinished":[{"version":2`)
+	suite.Assert().Zero(exitCode)
+}
+
+func (suite *CliTestSuite) TestItValidatesTheRegisteredMigrations() {
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	for _, version := range []string{"1", "2"} {
+		_ = os.WriteFile(filepath.Join(string(migPath), "version_"+version+".go"), nil, 0644)
+	}
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(3))
+
+	bootstrap := bootstrapRun{registry: registry, migPath: migPath}
+
+	output, exitCode := bootstrap.run("validate")
+	suite.Assert().Contains(output, "Migration files not registered: 1\n  version_2.go\n")
+	suite.Assert().Contains(output, "Registered migrations without a file: 1\n  version_3.go")
+	suite.Assert().Contains(output, "the migration files and the registered migrations diverge")
+	suite.Assert().NotZero(exitCode)
+
+	_ = registry.Register(migration.NewDummyMigration(2))
+	_ = os.WriteFile(filepath.Join(string(migPath), "version_3.go"), nil, 0644)
+	output, exitCode = bootstrap.run("validate")
+	suite.Assert().Contains(output, "All the 3 migrations are registered")
+	suite.Assert().NotContains(output, "Hotfix")
+	suite.Assert().Zero(exitCode)
+
+	noop := func(context.Context, any) error { return nil }
+	_ = registry.Register(migration.NewHotfix(4, 2, noop))
+	_ = registry.Register(migration.NewHotfix(5, 6, noop))
+	for _, version := range []string{"4", "5"} {
+		_ = os.WriteFile(filepath.Join(string(migPath), "version_"+version+".go"), nil, 0644)
+	}
+	output, exitCode = bootstrap.run("validate")
+	suite.Assert().Contains(
+		output,
+		"Invalid hotfix migrations: 1\n"+
+			"  the hotfix migration 5 must fix an earlier registered version, not 6\n"+
+			"Hotfix migrations, which can't be rolled back: 1\n  4 fixes 2",
+	)
+	suite.Assert().NotZero(exitCode)
+}
+
+func (suite *CliTestSuite) TestItListsThePendingVersions() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	repo.SaveAll([]execution.MigrationExecution{{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2}})
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	output, exitCode := bootstrap.run("pending")
+	suite.Assert().Equal("2\n3\n", output)
+	suite.Assert().Zero(exitCode)
+
+	output, _ = bootstrap.run("pending", "--format=json")
+	suite.Assert().Equal("[2,3]\n", output)
+
+	_, exitCode = bootstrap.run("pending", "--exit-code")
+	suite.Assert().NotZero(exitCode)
+
+	repo.SaveAll(
+		[]execution.MigrationExecution{
+			{Version: 2, ExecutedAtMs: 1, FinishedAtMs: 2},
+			{Version: 3, ExecutedAtMs: 1, FinishedAtMs: 2},
+		},
+	)
+	output, exitCode = bootstrap.run("pending", "--exit-code", "--format=json")
+	suite.Assert().Equal("[]\n", output)
+	suite.Assert().Zero(exitCode)
+}
+
+func (suite *CliTestSuite) TestItChecksWhetherTheDatabaseIsFullyMigrated() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	repo.SaveAll([]execution.MigrationExecution{{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2}})
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	output, exitCode := bootstrap.run("check")
+	suite.Assert().Contains(output, "Pending migrations: 2\n")
+	suite.Assert().Equal(ExitCodeCheckPending, exitCode)
+
+	// the failures of check exit with the codes of their categories
+	_, exitCode = bootstrap.run("check", "--format=unknown")
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+
+	repo.SaveAll(
+		[]execution.MigrationExecution{
+			{Version: 2, ExecutedAtMs: 1, FinishedAtMs: 2},
+			{Version: 5, ExecutedAtMs: 1, FinishedAtMs: 2},
+		},
+	)
+	output, exitCode = bootstrap.run("check", "--format=json")
+	suite.Assert().Contains(output, `{"state":"unknown","pending":[],"unknown":[5]}`)
+	suite.Assert().Equal(ExitCodeCheckUnknown, exitCode)
+
+	_ = repo.Remove(execution.MigrationExecution{Version: 5})
+	output, exitCode = bootstrap.run("check")
+	suite.Assert().Equal("The database is fully migrated\n", output)
+	suite.Assert().Equal(ExitCodeOk, exitCode)
+
+	output, exitCode = bootstrap.run("check", "--quiet")
+	suite.Assert().Empty(output)
+	suite.Assert().Equal(ExitCodeOk, exitCode)
+
+	repo.LoadErr = errors.New("connection refused")
+	_, exitCode = bootstrap.run("check")
+	suite.Assert().Equal(ExitCodeRepository, exitCode)
+}
+
+func (suite *CliTestSuite) TestItOnlyRunsTheReadOnlyCommandsInTheReadOnlyMode() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &execution.InMemoryRepository{
+		InitErr: errors.New("cannot execute CREATE TABLE in a read-only transaction"),
+		PersistedExecutions: []execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+		},
+	}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	output, exitCode := bootstrap.run("--read-only", "pending")
+	suite.Assert().Equal("2\n", output)
+	suite.Assert().Equal(ExitCodeOk, exitCode)
+
+	output, exitCode = bootstrap.run("up", "--read-only")
+	suite.Assert().Contains(
+		output,
+		"the up command is not available in the read-only mode: "+
+			"the executions repository is read-only",
+	)
+	suite.Assert().Equal(ExitCodeRepository, exitCode)
+
+	exclusive := bootstrap
+	exclusive.settings = &BootstrapSettings{ReadOnly: true, RunMigrationsExclusively: true}
+	_, exitCode = exclusive.run("mark-executed", "--version=2")
+	suite.Assert().Equal(ExitCodeRepository, exitCode)
+
+	// the mode is enabled when the storage is read-only
+	replica := bootstrap
+	replica.repo = &readOnlyReplica{repo}
+	output, exitCode = replica.run("up")
+	suite.Assert().Contains(output, "the up command is not available in the read-only mode")
+	suite.Assert().Equal(ExitCodeRepository, exitCode)
+	output, _ = replica.run("status")
+	suite.Assert().Contains(output, "Pending migrations: 1")
+
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+}
+
+func (suite *CliTestSuite) TestItSummarizesTheRegistry() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1704067200))
+	_ = registry.Register(&contractMigration{*migration.NewDummyMigration(1706745600)})
+	_ = registry.Register(migration.NewDummyMigration(1706832000))
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, migPath: migPath}
+
+	output, exitCode := bootstrap.run("summary", "--gaps=1")
+	suite.Assert().Zero(exitCode)
+	suite.Assert().Contains(output, "Registered migrations: 3")
+	suite.Assert().Contains(output, "2024-02: 2")
+	suite.Assert().Contains(output, "1704067200 -> 1706745600: 31.0 days")
+	suite.Assert().NotContains(output, "1706745600 -> 1706832000")
+	suite.Assert().Contains(output, "contract: 1")
+	suite.Assert().Contains(output, "Untagged: 2")
+
+	output, _ = bootstrap.run("summary", "--format=json")
+	var summary migration.RegistrySummary
+	suite.Require().NoError(json.Unmarshal([]byte(output), &summary))
+	suite.Assert().Equal([]migration.PeriodCount{{Period: "2024", Count: 3}}, summary.PerYear)
+	suite.Assert().Len(summary.LargestGaps, 2)
+
+	_, exitCode = bootstrap.run("summary", "--gaps=-1")
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+}
+
+func (suite *CliTestSuite) TestItReportsTheProgressOfTheRuns() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	output := bootstrap.output("up", "--steps=all")
+	suite.Assert().Contains(output, "[1/2] 1 up started\n")
+	suite.Assert().Regexp(`\[2/2\] 2 up done in \S+ \(2/2 applied, 00:00 elapsed\)`, output)
+
+	output = bootstrap.output("down", "--steps=all", "--format=json")
+	suite.Assert().NotContains(output, "[1/2]")
+
+	suite.Assert().Equal("00:42", elapsedClock(42*time.Second))
+	suite.Assert().Equal("1:02:03", elapsedClock(time.Hour+2*time.Minute+3*time.Second))
+}
+
+func (suite *CliTestSuite) TestItExecutesExactlyTheSignedPlanArtifacts() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	repo.SaveAll([]execution.MigrationExecution{{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2}})
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	suite.Require().NoError(err)
+	planFile := filepath.Join(suite.T().TempDir(), "plan.json")
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+	for _, version := range []uint64{1, 2, 3} {
+		path := filepath.Join(string(migPath), migration.FileName(version, ""))
+		suite.Require().NoError(os.WriteFile(path, []byte("package migrations\n"), 0o644))
+	}
+	approver, production := bootstrap, bootstrap
+	approver.settings = &BootstrapSettings{PlanSigningKey: privateKey}
+	production.settings = &BootstrapSettings{PlanVerifyingKey: publicKey}
+
+	output, exitCode := approver.run("plan:export", "--steps=all", "--file="+planFile)
+	suite.Assert().Zero(exitCode)
+	suite.Assert().Contains(output, "Exported the up plan of 2 migrations to "+planFile)
+
+	// the code of a planned migration changed since the export
+	migFile := filepath.Join(string(migPath), migration.FileName(3, ""))
+	suite.Require().NoError(os.WriteFile(migFile, []byte("package migrations // v2\n"), 0o644))
+	output, exitCode = production.run("plan:apply", "--file="+planFile)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Contains(output, "the registered migrations changed")
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+
+	suite.Require().NoError(os.WriteFile(migFile, []byte("package migrations\n"), 0o644))
+	output, exitCode = production.run("plan:apply", "--file="+planFile)
+	suite.Assert().Zero(exitCode, output)
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+
+	// the state changed since the export
+	output, exitCode = production.run("plan:apply", "--file="+planFile)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Contains(output, "the applied migrations changed")
+
+	_, _ = bootstrap.run("plan:export", "--down", "--file="+planFile)
+	output, exitCode = production.run("plan:apply", "--file="+planFile)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Contains(output, "signature is invalid")
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+
+	_, exitCode = bootstrap.run("plan:apply", "--file="+planFile)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+}
+
+// startingDb is a db whose ping fails until it is ready
+type startingDb struct {
+	pings      int
+	readyAfter int
+}
+
+func (db *startingDb) PingContext(context.Context) error {
+	db.pings++
+	if db.pings <= db.readyAfter {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (suite *CliTestSuite) TestItWaitsForTheDatabaseToBeReady() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, migPath: migPath}
+
+	db := &startingDb{readyAfter: 2}
+	bootstrap.db = db
+	output, exitCode := bootstrap.run("--wait-for-db=5s", "up")
+	suite.Assert().Zero(exitCode, output)
+	suite.Assert().Equal(3, db.pings)
+
+	db = &startingDb{readyAfter: 1}
+	bootstrap.db, bootstrap.settings = db, &BootstrapSettings{WaitForDb: 5 * time.Second}
+	_, exitCode = bootstrap.run("status")
+	suite.Assert().Zero(exitCode)
+	suite.Assert().Equal(2, db.pings)
+
+	bootstrap.db, bootstrap.settings = &startingDb{readyAfter: 100}, nil
+	output, exitCode = bootstrap.run("up", "--wait-for-db", "150ms")
+	suite.Assert().Equal(ExitCodeRepository, exitCode)
+	suite.Assert().Contains(output, "Database wait failed: the database was not ready within 150ms")
+	suite.Assert().Contains(output, "connection refused")
+
+	bootstrap.db = nil
+	_, exitCode = bootstrap.run("up", "--wait-for-db=soon")
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+}
+
+// stuckMigration blocks each Up() call until its context is cancelled
+type stuckMigration struct {
+	migration.DummyMigration
+}
+
+func (m *stuckMigration) Up(ctx context.Context, _ any) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// contextLocker fails to release the lock with a cancelled context
+type contextLocker struct {
+	fakeLocker
+}
+
+func (l *contextLocker) Unlock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return l.fakeLocker.Unlock(ctx)
+}
+
+func (suite *CliTestSuite) TestItCancelsTheRunsWhichExceedTheTimeout() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(&stuckMigration{*migration.NewDummyMigration(1)})
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	locker := &contextLocker{}
+	settings := &BootstrapSettings{
+		RunMigrationsExclusively: true,
+		NewLocker:                func(lockName string) lock.Locker { return locker },
+	}
+
+	bootstrap := bootstrapRun{registry: registry, migPath: migPath, settings: settings}
+
+	output, exitCode := bootstrap.run("--timeout=50ms", "up")
+	suite.Assert().Equal(ExitCodeMigration, exitCode)
+	suite.Assert().Contains(output, "context deadline exceeded")
+	suite.Assert().False(locker.locked)
+
+	settings.Timeout = 50 * time.Millisecond
+	_, exitCode = bootstrap.run("up")
+	suite.Assert().Equal(ExitCodeMigration, exitCode)
+	suite.Assert().False(locker.locked)
+
+	_, exitCode = bootstrap.run("up", "--timeout", "later")
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+}
+
+// interruptingMigration interrupts the process on its Up() call, then blocks until its context
+// is cancelled
+type interruptingMigration struct {
+	migration.DummyMigration
+}
+
+func (m *interruptingMigration) Up(ctx context.Context, _ any) error {
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+	if err = process.Signal(os.Interrupt); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (suite *CliTestSuite) TestItShutsDownGracefullyWhenInterrupted() {
+	if runtime.GOOS == "windows" {
+		suite.T().Skip("the interrupt signal can't be sent on windows")
+	}
+
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(&interruptingMigration{*migration.NewDummyMigration(1)})
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	settings := &BootstrapSettings{
+		RunMigrationsExclusively: true, RunLockFilesDirPath: suite.T().TempDir(),
+	}
+	var buf, logs bytes.Buffer
+	settings.LogWriter = &logs
+
+	exitCode := 0
+	Bootstrap(
+		context.Background(), nil, []string{"up", "--steps=all"}, registry, repo, migPath, nil,
+		&buf, func(code int) { exitCode = code }, settings,
+	)
+
+	suite.Assert().Equal(ExitCodeMigration, exitCode)
+	suite.Assert().Contains(logs.String(), "interrupted, stopping the run")
+	suite.Require().Len(repo.PersistedExecutions, 1)
+	suite.Assert().False(repo.PersistedExecutions[0].Finished())
+
+	info, err := settings.locker().(lock.Breaker).Inspect(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().False(info.Held)
+}
+
+func (suite *CliTestSuite) TestItResetsAndRebuildsDevelopmentDatabases() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	bootstrap.answering("").output("up", "--steps=all")
+	suite.Assert().Contains(
+		bootstrap.answering("").output("reset", "--yes"),
+		"requires the MIGRATIONS_ENV environment variable",
+	)
+
+	suite.T().Setenv(EnvironmentEnvVar, "production")
+	suite.Assert().Contains(
+		bootstrap.answering("").output("reset", "--yes"),
+		`not allowed in the "production" environment`,
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+
+	suite.T().Setenv(EnvironmentEnvVar, "development")
+	output := bootstrap.answering("no\n").output("fresh")
+	suite.Assert().Contains(output, "roll back all the 3 executed migrations and re-apply")
+	suite.Assert().Contains(output, "aborted, the confirmation was not given")
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+
+	output = bootstrap.answering("yes\n").output("fresh")
+	suite.Assert().Contains(output, "Executed Down() for 3 migrations")
+	suite.Assert().Contains(output, "Executed Up() for 3 migrations")
+	suite.Assert().Less(strings.Index(output, "Down()"), strings.Index(output, "Up()"))
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+
+	suite.Assert().Contains(
+		bootstrap.answering("").output("reset", "--non-interactive"),
+		"Executed Down() for 3 migrations",
+	)
+	suite.Assert().Empty(repo.PersistedExecutions)
+}
+
+// rowsMigration affects 2 rows on each Up() call
+type rowsMigration struct {
+	migration.DummyMigration
+}
+
+func (m *rowsMigration) Up(ctx context.Context, _ any) error {
+	migration.RecordRowsAffected(ctx, 2)
+	return nil
+}
+
+func (suite *CliTestSuite) TestItReportsMigrationsWhichAreNotSafeToRerun() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(&rowsMigration{*migration.NewDummyMigration(1)})
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var buf bytes.Buffer
+	exitCode := 0
+	Bootstrap(
+		context.Background(), nil, []string{"up", "--verify-rerun"}, registry,
+		&execution.InMemoryRepository{}, migPath, nil, &buf,
+		func(code int) { exitCode = code }, nil,
+	)
+
+	suite.Assert().Contains(buf.String(), "Executed Up() for 1 migrations")
+	suite.Assert().Contains(
+		buf.String(), "migrations not safe to rerun: 1 (second Up() affected 2 rows)",
+	)
+	suite.Assert().NotEqual(0, exitCode)
+}
+
+// restrictedRepository reports a missing privilege in the permissions preflight
+type restrictedRepository struct {
+	execution.InMemoryRepository
+}
+
+func (r *restrictedRepository) CheckPermissions() ([]execution.MissingPermission, error) {
+	return []execution.MissingPermission{
+		{Privilege: "CREATE", Target: "schema public", Reason: "creating tables"},
+	}, nil
+}
+
+func (suite *CliTestSuite) TestItFailsThePermissionsPreflightBeforeRunning() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	repo := &restrictedRepository{}
+	repo.InitErr = errors.New("init must not be called")
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var buf bytes.Buffer
+	exitCode := 0
+	Bootstrap(
+		context.Background(), nil, []string{"up"}, registry, repo, migPath, nil, &buf,
+		func(code int) { exitCode = code }, &BootstrapSettings{PermissionsPreflight: true},
+	)
+
+	suite.Assert().Equal(ExitCodeRepository, exitCode)
+	suite.Assert().Equal(
+		"Permissions preflight failed: the connected role is missing the privileges needed"+
+			" by the run:\n  - CREATE on schema public (creating tables)\n",
+		buf.String(),
+	)
+	suite.Assert().Empty(repo.PersistedExecutions)
+}
+
+// describedRepository describes the statements which create its storage
+type describedRepository struct {
+	execution.InMemoryRepository
+}
+
+func (r *describedRepository) InitStatements() []string {
+	return []string{"CREATE TABLE executions (version BIGINT)", "COMMENT ON TABLE executions"}
+}
+
+func (suite *CliTestSuite) TestItDescribesTheStatementsOfFailedInitializations() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	repo := &describedRepository{}
+	repo.InitErr = errors.New("permission denied for schema public")
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	statements := "CREATE TABLE executions (version BIGINT);\n\nCOMMENT ON TABLE executions;\n"
+
+	var buf bytes.Buffer
+	exitCode := 0
+	settings := &BootstrapSettings{DescribeInitFailures: true}
+	Bootstrap(
+		context.Background(), nil, []string{"up"}, registry, repo, migPath, nil, &buf,
+		func(code int) { exitCode = code }, settings,
+	)
+
+	suite.Assert().Equal(ExitCodeRepository, exitCode)
+	suite.Assert().Equal(
+		"Executions storage initialization failed: permission denied for schema public\n"+
+			"Run these statements with a privileged role, then run the command again:\n\n"+
+			statements,
+		buf.String(),
+	)
+
+	buf.Reset()
+	settings.InitStatementsFile = filepath.Join(suite.T().TempDir(), "executions.sql")
+	Bootstrap(
+		context.Background(), nil, []string{"up"}, registry, repo, migPath, nil, &buf,
+		func(code int) { exitCode = code }, settings,
+	)
+
+	suite.Assert().Contains(
+		buf.String(), "Run the statements written to "+settings.InitStatementsFile,
+	)
+	written, err := os.ReadFile(settings.InitStatementsFile)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(statements, string(written))
+
+	// the run proceeds once a privileged role created the storage
+	buf.Reset()
+	repo.InitErr = nil
+	Bootstrap(
+		context.Background(), nil, []string{"up"}, registry, repo, migPath, nil, &buf,
+		func(code int) { exitCode = code }, settings,
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+}
+
+// versionedRepository records the given executions schema version
+type versionedRepository struct {
+	execution.InMemoryRepository
+	stored int
+}
+
+func (r *versionedRepository) StoredSchemaVersion() (int, error) {
+	return r.stored, nil
+}
+
+func (suite *CliTestSuite) TestItReportsTheVersionAndTheRegistryStatistics() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &versionedRepository{stored: execution.SchemaVersion}
+	repo.SaveAll(
+		[]execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+			{Version: 9, ExecutedAtMs: 1, FinishedAtMs: 2},
+		},
+	)
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	schemaVersion := strconv.Itoa(execution.SchemaVersion)
+
+	for _, args := range [][]string{{"version"}, {"--version"}, {"--", "--version"}} {
+		var buf bytes.Buffer
+		Bootstrap(
+			context.Background(), nil, args, registry, repo, migPath, nil, &buf,
+			func(code int) {}, nil,
+		)
+
+		suite.Assert().Equal(
+			"Library version: "+libraryVersion()+"\n"+
+				"Supported executions schema version: "+schemaVersion+"\n"+
+				"Stored executions schema version: "+schemaVersion+"\n"+
+				"Registered migrations: 2\n"+
+				"Applied migrations: 1\n"+
+				"Pending migrations: 1\n"+
+				"Unknown executed versions: 1\n",
+			buf.String(),
+			"args %v", args,
+		)
+	}
+}
+
+func (suite *CliTestSuite) TestItDescribesTheSetupAsJson() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &versionedRepository{stored: execution.SchemaVersion}
+	repo.SaveAll([]execution.MigrationExecution{{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2}})
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var buf bytes.Buffer
+	Bootstrap(
+		context.Background(), nil, []string{"describe", "--json"}, registry, repo, migPath, nil,
+		&buf, func(code int) {},
+		&BootstrapSettings{
+			RunMigrationsExclusively: true,
+			MigrationsCmdLockName:    "app",
+			CommandHooks:             map[string]CommandHooks{"up": {}},
+		},
+	)
+
+	var report DescribeReport
+	suite.Require().NoError(json.Unmarshal(buf.Bytes(), &report), buf.String())
+	suite.Assert().Equal(execution.SchemaVersion, report.StoredSchemaVersion)
+	suite.Assert().Len(report.Migrations, 2)
+	suite.Assert().Equal(string(migPath), report.Settings.MigrationsDir)
+	suite.Assert().Equal(FormatText, report.Settings.DefaultFormat)
+	suite.Assert().Equal("app", report.Settings.LockName)
+	suite.Assert().Equal([]string{"up"}, report.Settings.HookedCommands)
+	suite.Assert().Len(report.State.Applied, 1)
+	suite.Assert().Len(report.State.Pending, 1)
+
+	idx := slices.IndexFunc(
+		report.Commands, func(cmd CommandReport) bool { return cmd.Id == "up" },
+	)
+	suite.Require().NotEqual(-1, idx)
+	suite.Assert().True(
+		slices.ContainsFunc(
+			report.Commands[idx].Flags, func(f FlagReport) bool { return f.Name == "dry-run" },
+		),
+	)
+	suite.Assert().True(
+		slices.ContainsFunc(
+			report.Commands, func(cmd CommandReport) bool { return cmd.Id == "describe" },
+		),
+	)
+}
+
+func (suite *CliTestSuite) TestItWarnsWhenTheSchemaIsNewerThanSupported() {
+	registry := migration.NewGenericRegistry()
+	repo := &versionedRepository{stored: execution.SchemaVersion + 1}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var buf bytes.Buffer
+	Bootstrap(
+		context.Background(), nil, []string{"up"}, registry, repo, migPath, nil, &buf,
+		func(code int) {}, nil,
+	)
+
+	tooNewErr := &execution.SchemaTooNewError{
+		Stored: repo.stored, Supported: execution.SchemaVersion,
+	}
+	suite.Assert().Contains(buf.String(), "Warning: "+tooNewErr.Error()+"\n")
+	suite.Assert().Contains(buf.String(), "Executed Up() for 0 migrations")
+}
+
+func (suite *CliTestSuite) TestTheDockerEntrypointMigratesThenStartsTheApplication() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &execution.InMemoryRepository{}
+	app, _ := filepath.Abs(os.Args[0])
+
+	pings := 0
+	var started []string
+	var buf bytes.Buffer
+	settings := &EntrypointSettings{
+		Ping: func(ctx context.Context) error {
+			pings++
+			if pings == 1 {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+		PollInterval: time.Millisecond,
+		Output:       &buf,
+		Exec: func(path string, args []string, env []string) error {
+			suite.Assert().Len(repo.PersistedExecutions, 2)
+			started = append([]string{path}, args...)
+			return nil
+		},
+	}
+
+	err := DockerEntrypoint(
+		context.Background(), nil, []string{"--", app, "--port=8080"}, registry, repo, settings,
+	)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(2, pings)
+	suite.Assert().Contains(buf.String(), "Executed Up() for 2 migrations")
+	suite.Assert().Equal([]string{app, app, "--port=8080"}, started)
+
+	suite.T().Setenv(SkipMigrationsEnvVar, "true")
+	_ = registry.Register(migration.NewDummyMigration(3))
+	suite.Require().NoError(
+		DockerEntrypoint(context.Background(), nil, []string{app}, registry, repo, settings),
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+}
+
+func (suite *CliTestSuite) TestTheDockerEntrypointWaitsForTheRunOfAnotherContainer() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	repo := &execution.InMemoryRepository{}
+	settings := &EntrypointSettings{
+		BootstrapSettings: BootstrapSettings{
+			RunMigrationsExclusively: true,
+			NewLocker: func(lockName string) lock.Locker {
+				return &fakeLocker{lockName: lockName, locked: true}
+			},
+		},
+		PollInterval: time.Millisecond,
+		Output:       io.Discard,
+	}
+
+	suite.T().Setenv(RunWaitTimeoutEnvVar, "20ms")
+	err := DockerEntrypoint(context.Background(), nil, nil, registry, repo, settings)
+	suite.Assert().ErrorContains(err, "1 migrations are not executed yet")
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	repo.SaveAll([]execution.MigrationExecution{{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2}})
+	suite.Assert().NoError(
+		DockerEntrypoint(context.Background(), nil, nil, registry, repo, settings),
+	)
+
+	suite.T().Setenv(DbWaitTimeoutEnvVar, "soon")
+	err = DockerEntrypoint(context.Background(), nil, nil, registry, repo, settings)
+	suite.Assert().ErrorContains(err, "invalid MIGRATIONS_DB_WAIT_TIMEOUT value")
+}
+
+// cancellingMigration cancels the run on its Up() call, like a container stopped during the run
+type cancellingMigration struct {
+	migration.DummyMigration
+	cancel context.CancelFunc
+}
+
+func (m *cancellingMigration) Up(ctx context.Context, _ any) error {
+	m.cancel()
+	return ctx.Err()
+}
+
+// failingUnlocker fails to release the lock
+type failingUnlocker struct {
+	fakeLocker
+}
+
+func (l *failingUnlocker) Unlock(context.Context) error {
+	return errors.New("connection reset")
+}
+
+func (suite *CliTestSuite) TestTheDockerEntrypointReleasesTheLockOfTheCancelledRuns() {
+	ctx, cancel := context.WithCancel(context.Background())
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(&cancellingMigration{*migration.NewDummyMigration(1), cancel})
+	locker := &contextLocker{}
+	var buf bytes.Buffer
+	settings := &EntrypointSettings{
+		BootstrapSettings: BootstrapSettings{
+			RunMigrationsExclusively: true,
+			NewLocker:                func(string) lock.Locker { return locker },
+		},
+		PollInterval: time.Millisecond,
+		Output:       &buf,
+	}
+
+	err := DockerEntrypoint(ctx, nil, nil, registry, &execution.InMemoryRepository{}, settings)
+	suite.Assert().ErrorIs(err, context.Canceled)
+	suite.Assert().False(locker.locked)
+	suite.Assert().NotContains(buf.String(), "failed to release the migrations lock")
+
+	registry = migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	settings.NewLocker = func(string) lock.Locker { return &failingUnlocker{} }
+	suite.Require().NoError(
+		DockerEntrypoint(
+			context.Background(), nil, nil, registry, &execution.InMemoryRepository{}, settings,
+		),
+	)
+	suite.Assert().Contains(
+		buf.String(), "Warning: failed to release the migrations lock: connection reset\n",
+	)
+}
+
+// contractMigration is tagged as a contract migration
+type contractMigration struct {
+	migration.DummyMigration
+}
+
+func (m *contractMigration) Metadata() migration.Metadata {
+	return migration.Metadata{Tags: []string{migration.TagContract}}
+}
+
+// fixedFleetVersion is a FleetVersionSource which reports a fixed version
+type fixedFleetVersion uint64
+
+func (f fixedFleetVersion) MinCompatibleVersion(context.Context) (uint64, error) {
+	return uint64(f), nil
+}
+
+func (suite *CliTestSuite) TestItRefusesContractMigrationsNotSupportedByTheFleet() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(&contractMigration{*migration.NewDummyMigration(2)})
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var buf bytes.Buffer
+	exitCode := 0
+	Bootstrap(
+		context.Background(), nil, []string{"up", "--steps=all"}, registry, repo, migPath, nil,
+		&buf, func(code int) { exitCode = code },
+		&BootstrapSettings{FleetVersionSource: fixedFleetVersion(1)},
+	)
+
+	suite.Assert().Contains(
+		buf.String(),
+		"contract migration 2 can't be executed while the running app fleet is compatible "+
+			"only up to version 1",
+	)
+	suite.Assert().NotEqual(0, exitCode)
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+}
+
+func (suite *CliTestSuite) TestItPromptsForTheMigrationToKeepOnVersionCollisions() {
+	registry := migration.NewGenericRegistryWithCollisionPolicy(migration.CollisionInteractive)
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.RegisterFromFile(migration.NewDummyMigration(2), "/migrations/version_2.go")
+	_ = registry.RegisterFromFile(migration.NewDummyMigration(2), "/migrations/version_3.go")
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	// the read-only commands don't prompt
+	output, _ := bootstrap.answering("").run("status")
+	suite.Assert().NotContains(output, "Choose the one to keep")
+	output, _ = bootstrap.answering("").run("validate")
+	suite.Assert().Contains(
+		output,
+		"Migration versions declared by several migrations: 1\n"+
+			"  2 (declared by version_2.go, version_3.go)",
+	)
+
+	// a quarantined version stops the run
+	output, exitCode := bootstrap.answering("\n").run("up", "--steps=all")
+	suite.Assert().Contains(output, "Migration version 2 is declared by 2 migrations:")
+	suite.Assert().Contains(output, "  1) version_2.go\n  2) version_3.go\n")
+	suite.Assert().Contains(output, "Version 2 stays quarantined")
+	suite.Assert().Contains(output, "declared by several migrations")
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+
+	output, exitCode = bootstrap.answering("3\n").run("up", "--steps=all")
+	suite.Assert().Contains(output, `invalid choice "3" for migration version 2`)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+
+	_, exitCode = bootstrap.answering("2\n").run("up", "--steps=all")
+	suite.Assert().Zero(exitCode)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+}
+
+func (suite *CliTestSuite) TestItReadsTheConfigurationFromTheEnvironment() {
+	dir := suite.T().TempDir()
+	suite.T().Setenv(DirEnvVar, dir)
+	suite.T().Setenv(MigrationTimeoutEnvVar, "")
+	suite.T().Setenv(CollisionPolicyEnvVar, "")
+
+	config, err := ConfigFromEnv()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(migration.MigrationsDirPath(dir), config.Dir)
+	suite.Assert().Equal(DefaultMigrationsTable, config.Table)
+	suite.Assert().Equal(migration.CollisionFail, config.CollisionPolicy)
+	suite.Assert().False(config.Settings().RunMigrationsExclusively)
+
+	suite.T().Setenv(TableEnvVar, "tenant_executions")
+	suite.T().Setenv(LockDirEnvVar, dir)
+	suite.T().Setenv(LockNameEnvVar, "app-lock")
+	suite.T().Setenv(FormatEnvVar, FormatJson)
+	suite.T().Setenv(MigrationTimeoutEnvVar, "90s")
+	suite.T().Setenv(CollisionPolicyEnvVar, string(migration.CollisionQuarantine))
+	suite.T().Setenv(LockfileEnvVar, "release/migrations.lock")
+
+	config, err = ConfigFromEnv()
+	suite.Require().NoError(err)
+	suite.Assert().Equal("tenant_executions", config.Table)
+	suite.Assert().Equal(migration.CollisionQuarantine, config.CollisionPolicy)
+	suite.Assert().Equal(
+		&BootstrapSettings{
+			RunMigrationsExclusively: true,
+			RunLockFilesDirPath:      dir,
+			MigrationsCmdLockName:    "app-lock",
+			DefaultFormat:            FormatJson,
+			MigrationTimeout:         90 * time.Second,
+			Lockfile:                 "release/migrations.lock",
+		},
+		config.Settings(),
+	)
+
+	for envVar, value := range map[string]string{
+		DirEnvVar:              filepath.Join(dir, "missing"),
+		MigrationTimeoutEnvVar: "soon",
+		CollisionPolicyEnvVar:  "newest",
+	} {
+		suite.T().Setenv(envVar, value)
+		_, err = ConfigFromEnv()
+		suite.Assert().ErrorContains(err, "invalid "+envVar)
+		suite.T().Setenv(envVar, map[string]string{DirEnvVar: dir}[envVar])
+	}
+}
+
+func (suite *CliTestSuite) TestItLoadsTheDotEnvFile() {
+	dir := suite.T().TempDir()
+	envFile := filepath.Join(dir, ".env")
+	suite.Require().NoError(
+		os.WriteFile(
+			envFile, []byte(
+				"# defaults for the host machine\n\n"+
+					"export "+DirEnvVar+"="+dir+"\n"+
+					TableEnvVar+"=tenant_executions # inline comment\n"+
+					"APP_DSN=\"root:pass@tcp(localhost:3306)/app\\n\"\n"+
+					"APP_QUOTED='#not a comment'\n"+
+					FormatEnvVar+"="+FormatJson+"\n",
+			), 0600,
+		),
+	)
+	suite.T().Setenv(EnvFileEnvVar, envFile)
+	suite.T().Setenv(FormatEnvVar, FormatTable)
+	for _, envVar := range []string{DirEnvVar, TableEnvVar, "APP_DSN", "APP_QUOTED"} {
+		suite.T().Setenv(envVar, "")
+		suite.Require().NoError(os.Unsetenv(envVar))
+	}
+
+	config, err := ConfigFromEnv()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(migration.MigrationsDirPath(dir), config.Dir)
+	suite.Assert().Equal("tenant_executions", config.Table)
+	suite.Assert().Equal(FormatTable, config.Format)
+	suite.Assert().Equal("root:pass@tcp(localhost:3306)/app\n", os.Getenv("APP_DSN"))
+	suite.Assert().Equal("#not a comment", EnvOrDefault("APP_QUOTED", "default"))
+	suite.Assert().Equal("default", EnvOrDefault("APP_MISSING", "default"))
+
+	suite.T().Setenv(EnvFileEnvVar, filepath.Join(dir, "missing.env"))
+	_, err = ConfigFromEnv()
+	suite.Assert().ErrorIs(err, os.ErrNotExist)
+
+	for content, expectedErr := range map[string]string{
+		"APP_DSN\n":              "line 1 is not a KEY=VALUE assignment",
+		"\nAPP_DSN=\"open\n":     "invalid value of APP_DSN on line 2: unterminated quoted value",
+		"APP_DSN='value' rest\n": "unexpected characters after the quoted value",
+	} {
+		suite.Require().NoError(os.WriteFile(envFile, []byte(content), 0600))
+		suite.Assert().ErrorContains(LoadDotEnv(envFile), expectedErr)
+	}
+}
+
+func (suite *CliTestSuite) TestItBootstrapsFromTheEnvironment() {
+	suite.T().Setenv(DirEnvVar, suite.T().TempDir())
+	suite.T().Setenv(LockDirEnvVar, suite.T().TempDir())
+	suite.T().Setenv(FormatEnvVar, FormatJson)
+	suite.T().Setenv(MigrationTimeoutEnvVar, "")
+	suite.T().Setenv(CollisionPolicyEnvVar, "")
+
+	var buf bytes.Buffer
+	exitCode := 0
+	bootstrapFromEnv(
+		context.Background(), nil, []string{"up"}, &execution.InMemoryRepository{}, &buf,
+		func(code int) { exitCode = code },
+	)
+	suite.Assert().Zero(exitCode)
+	suite.Assert().True(json.Valid(buf.Bytes()), buf.String())
+
+	suite.T().Setenv(MigrationTimeoutEnvVar, "soon")
+	buf.Reset()
+	bootstrapFromEnv(
+		context.Background(), nil, []string{"up"}, &execution.InMemoryRepository{}, &buf,
+		func(code int) { exitCode = code },
+	)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Contains(buf.String(), "Invalid configuration: invalid MIGRATIONS_TIMEOUT")
+}
+
+func (suite *CliTestSuite) TestItRunsTheAppBuiltFromTheOptions() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var buf bytes.Buffer
+	exitCode := 0
+	New(
+		WithArgs([]string{"pending"}),
+		WithRegistry(registry),
+		WithRepository(repo),
+		WithMigrationsDir(migPath),
+		WithOutput(&buf),
+		WithProcessExit(func(code int) { exitCode = code }),
+		WithSettings(&BootstrapSettings{DefaultFormat: FormatJson}),
+	).Run(context.Background())
+	suite.Assert().Equal("[1,2]\n", buf.String())
+	suite.Assert().Zero(exitCode)
+
+	buf.Reset()
+	New(
+		WithArgs([]string{"pending"}),
+		WithMigrationsDir(migPath),
+		WithOutput(&buf),
+		WithProcessExit(func(code int) { exitCode = code }),
+	).Run(context.Background())
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Contains(buf.String(), "Invalid configuration: no executions repository")
+}
+
+func (suite *CliTestSuite) TestItRoutesTheCommandsToTheModules() {
+	newModule := func(name string, versions ...uint64) (Module, *execution.InMemoryRepository) {
+		registry := migration.NewGenericRegistry()
+		for _, version := range versions {
+			_ = registry.Register(migration.NewDummyMigration(version))
+		}
+		repo := &execution.InMemoryRepository{}
+		migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+		module := Module{Name: name, Registry: registry, Repository: repo, MigrationsDir: migPath}
+		return module, repo
+	}
+	billing, billingRepo := newModule("billing", 1, 2)
+	auth, authRepo := newModule("auth", 3)
+	lockDir := suite.T().TempDir()
+
+	run := func(modules []Module, args ...string) (string, int) {
+		var buf bytes.Buffer
+		exitCode := -1
+		New(
+			WithArgs(args),
+			WithModules(modules...),
+			WithOutput(&buf),
+			WithProcessExit(func(code int) { exitCode = code }),
+			WithSettings(
+				&BootstrapSettings{RunMigrationsExclusively: true, RunLockFilesDirPath: lockDir},
+			),
+		).Run(context.Background())
+		return buf.String(), exitCode
+	}
+	modules := []Module{billing, auth}
+
+	output, exitCode := run(modules, "up", "billing", "--steps=1")
+	suite.Assert().Contains(output, "Executed Up() for 1 migrations")
+	suite.Assert().Equal(ExitCodeOk, exitCode)
+	suite.Assert().Len(billingRepo.PersistedExecutions, 1)
+	suite.Assert().Empty(authRepo.PersistedExecutions)
+
+	output, exitCode = run(modules, "pending", "--all")
+	suite.Assert().Equal("Module billing:\n2\nModule auth:\n3\n", output)
+	suite.Assert().Equal(ExitCodeOk, exitCode)
+
+	output, _ = run(modules, "pending", "--all", "--format=json")
+	suite.Assert().Equal("[2]\n[3]\n", output)
+
+	// the runs of --all stop at the first failed module
+	billingRepo.LoadErr = errors.New("connection refused")
+	output, exitCode = run(modules, "check", "--all")
+	suite.Assert().NotContains(output, "Module auth:")
+	suite.Assert().Equal(ExitCodeRepository, exitCode)
+	billingRepo.LoadErr = nil
+
+	output, exitCode = run(modules, "status")
+	suite.Assert().Contains(
+		output, "Invalid arguments: the command needs a module (billing, auth) or --all",
+	)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+
+	output, exitCode = run(modules, "status", "auth", "--all")
+	suite.Assert().Contains(output, "Invalid arguments: give the module auth or --all, not both")
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+
+	output, exitCode = run(modules, "help")
+	suite.Assert().Contains(output, "Lists all available commands")
+	suite.Assert().Equal(ExitCodeOk, exitCode)
+
+	// a single module can be omitted
+	_, exitCode = run([]Module{auth}, "up")
+	suite.Assert().Equal(ExitCodeOk, exitCode)
+	suite.Assert().Len(authRepo.PersistedExecutions, 1)
+
+	output, exitCode = run([]Module{billing, {Name: "billing"}, {Name: "--all"}})
+	suite.Assert().Contains(
+		output, "Invalid configuration: the module billing is given several times",
+	)
+	suite.Assert().Contains(output, `invalid module name "--all"`)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+}
+
+func (suite *CliTestSuite) TestItConfirmsTheRollbacksOnTerminals() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	repo.SaveAll(
+		[]execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+			{Version: 2, ExecutedAtMs: 1, FinishedAtMs: 2},
+		},
+	)
+	migrationsHandler, _ := handler.NewHandler(registry, repo, nil)
+
+	run := func(input string, interactive bool, args ...string) (string, error) {
+		cmd := &MigrateDownCommand{
+			handler: migrationsHandler, ctx: context.Background(),
+			outputFlags: newOutputFlags("", nil, false),
+			confirmation: confirmation{
+				input: bufio.NewReader(strings.NewReader(input)), interactive: interactive,
+			},
+		}
+		flagSet := flag.NewFlagSet(cmd.Id(), flag.ContinueOnError)
+		cmd.DefineFlags(flagSet)
+		suite.Require().NoError(flagSet.Parse(args))
+		suite.Require().NoError(cmd.ValidateFlags())
+
+		var buf bytes.Buffer
+		err := cmd.Exec(&buf)
+		return buf.String(), err
+	}
+
+	output, err := run("no\n", true, "--steps=all")
+	suite.Assert().ErrorIs(err, errNotConfirmed)
+	suite.Assert().Contains(output, "This will roll back 2 migrations (2, 1)")
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+
+	output, err = run("yes\n", true)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(output, "This will roll back 1 migrations (2)")
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+
+	// the automated runs are not prompted, and --non-interactive skips the prompt
+	output, err = run("", true, "--non-interactive")
+	suite.Require().NoError(err)
+	suite.Assert().NotContains(output, "This will")
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	_ = repo.SaveAll([]execution.MigrationExecution{{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2}})
+	output, err = run("", false)
+	suite.Require().NoError(err)
+	suite.Assert().NotContains(output, "This will")
+	suite.Assert().Empty(repo.PersistedExecutions)
+}
+
+func (suite *CliTestSuite) TestItConfirmsTheRedoAndTheArchiveOnTerminals() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	hot := &execution.InMemoryRepository{
+		PersistedExecutions: []execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: 1712953077000, FinishedAtMs: 1712953078000},
+		},
+	}
+	archive := &execution.InMemoryRepository{}
+	repo := execution.NewArchivedRepository(hot, archive)
+	migrationsHandler, _ := handler.NewHandler(registry, repo, nil)
+
+	run := func(cmd interface {
+		DefineFlags(*flag.FlagSet)
+		ValidateFlags() error
+		Exec(io.Writer) error
+		Id() string
+	}, args ...string) (string, error) {
+		flagSet := flag.NewFlagSet(cmd.Id(), flag.ContinueOnError)
+		cmd.DefineFlags(flagSet)
+		suite.Require().NoError(flagSet.Parse(args))
+		suite.Require().NoError(cmd.ValidateFlags())
+
+		var buf bytes.Buffer
+		err := cmd.Exec(&buf)
+		return buf.String(), err
+	}
+	terminal := func(input string) confirmation {
+		return confirmation{input: bufio.NewReader(strings.NewReader(input)), interactive: true}
+	}
+
+	output, err := run(
+		&RedoCommand{
+			handler: migrationsHandler, ctx: context.Background(),
+			outputFlags: newOutputFlags("", nil, false), confirmation: terminal("no\n"),
+		},
+		"--version=1",
+	)
+	suite.Assert().ErrorIs(err, errNotConfirmed)
+	suite.Assert().Contains(output, "This will execute Down() then Up() for migration version 1")
+	suite.Assert().NotContains(output, "Executed Down()")
+
+	output, err = run(
+		&RedoCommand{
+			handler: migrationsHandler, ctx: context.Background(),
+			outputFlags: newOutputFlags("", nil, false), confirmation: terminal(""),
+		},
+		"--yes",
+	)
+	suite.Require().NoError(err)
+	suite.Assert().NotContains(output, "This will")
+
+	output, err = run(
+		&ArchiveCommand{
+			archiver: repo, outputFlags: newOutputFlags("", nil, false),
+			confirmation: terminal("no\n"),
+		},
+		"--before=2100-01-01",
+	)
+	suite.Assert().ErrorIs(err, errNotConfirmed)
+	suite.Assert().Contains(
+		output, "This will move the finished executions started before 2100-01-01T00:00:00Z to",
+	)
+	suite.Assert().Empty(archive.PersistedExecutions)
+
+	_, err = run(
+		&ArchiveCommand{
+			archiver: repo, outputFlags: newOutputFlags("", nil, false),
+			confirmation: terminal("yes\n"),
+		},
+		"--before=2100-01-01",
+	)
+	suite.Require().NoError(err)
+	suite.Assert().Len(archive.PersistedExecutions, 1)
+}
+
+func (suite *CliTestSuite) TestItLogsTheRunsAtTheSelectedLevel() {
+	logsOf := func(args ...string) string {
+		registry := migration.NewGenericRegistry()
+		_ = registry.Register(migration.NewDummyMigration(1))
+		migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+		var logs bytes.Buffer
+		output := bootstrapRun{
+			registry: registry, migPath: migPath, settings: &BootstrapSettings{LogWriter: &logs},
+		}.output(args...)
+		if slices.Contains(args, "--quiet") {
+			suite.Assert().Empty(output)
+		} else {
+			suite.Assert().Contains(output, "Executed Up() for 1 migrations")
+		}
+		return logs.String()
+	}
+
+	suite.Assert().Empty(logsOf("up"))
+
+	logs := logsOf("up", "--verbose")
+	suite.Assert().Contains(logs, "msg=\"migration started\"")
+	suite.Assert().Contains(logs, "msg=\"migration finished\"")
+	suite.Assert().Contains(logs, "version=1")
+	suite.Assert().Contains(logs, "run_id=")
+
+	suite.Assert().Empty(logsOf("-q", "up", "--quiet"))
+}
+
+func (suite *CliTestSuite) TestItLogsOneJsonEventPerLifecycleStep() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var buf, logs bytes.Buffer
+	exitCode := 0
+	Bootstrap(
+		context.Background(), nil, []string{"--log-format", "json", "up", "--steps=1"}, registry,
+		&execution.InMemoryRepository{}, migPath, nil, &buf, func(code int) { exitCode = code },
+		&BootstrapSettings{LogWriter: &logs},
+	)
+	suite.Require().Zero(exitCode)
+	suite.Assert().Contains(buf.String(), "Executed Up() for 1 migrations")
+
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var event map[string]any
+		suite.Require().NoError(json.Unmarshal([]byte(line), &event), line)
+		events = append(events, event)
+	}
+
+	suite.Require().Len(events, 3)
+	suite.Assert().Equal("plan computed", events[0]["msg"])
+	suite.Assert().Equal([]any{float64(1)}, events[0]["versions"])
+	suite.Assert().Equal("migration started", events[1]["msg"])
+	suite.Assert().Equal("migration finished", events[2]["msg"])
+	for _, event := range events {
+		suite.Assert().Equal("INFO", event["level"])
+		suite.Assert().NotEmpty(event["run_id"])
+	}
+	suite.Assert().Equal(float64(1), events[2]["version"])
+	suite.Assert().Equal("up", events[2]["direction"])
+
+	buf.Reset()
+	Bootstrap(
+		context.Background(), nil, []string{"up", "--log-format=xml"}, registry,
+		&execution.InMemoryRepository{}, migPath, nil, &buf, func(code int) { exitCode = code },
+		&BootstrapSettings{LogWriter: &logs},
+	)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Contains(buf.String(), "Invalid log settings: unsupported log format \"xml\"")
+}
+
+// statementsDb records the executed statements
+type statementsDb struct {
+	statements []string
+}
+
+func (db *statementsDb) ExecContext(_ context.Context, query string, _ ...any) (sql.Result, error) {
+	db.statements = append(db.statements, query)
+	return nil, nil
+}
+
+func (suite *CliTestSuite) TestItSyncsTheRepeatableObjects() {
+	registry := repeatable.NewRegistry()
+	_ = registry.Register(
+		repeatable.Object{
+			Kind: repeatable.KindView, Name: "active_users",
+			Create: "CREATE OR REPLACE VIEW active_users AS SELECT 1",
+		},
+	)
+	settings := &BootstrapSettings{
+		Repeatables: registry, RepeatablesStore: &repeatable.InMemoryStore{},
+	}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	db := &statementsDb{}
+
+	bootstrap := bootstrapRun{db: db, migPath: migPath, settings: settings}
+
+	suite.Assert().Contains(
+		bootstrap.output("repeatable:sync", "--dry-run"),
+		"1 repeatable objects changed: view:active_users",
+	)
+	suite.Assert().Empty(db.statements)
+
+	suite.Assert().Contains(
+		bootstrap.output("repeatable:sync"), "1 repeatable objects applied: view:active_users",
+	)
+	suite.Assert().Equal([]string{"CREATE OR REPLACE VIEW active_users AS SELECT 1"}, db.statements)
+	suite.Assert().Contains(bootstrap.output("repeatable:sync"), "0 repeatable objects applied")
+}
+
+// failingMigration fails on each Up() call
+type failingMigration struct {
+	migration.DummyMigration
+}
+
+func (m *failingMigration) Up(_ context.Context, _ any) error {
+	return errors.New("duplicate column")
+}
+
+func (suite *CliTestSuite) TestItExitsWithTheCodeOfTheFailureCategory() {
+	scenarios := map[string]struct {
+		args         []string
+		migration    migration.Migration
+		repository   *execution.InMemoryRepository
+		locked       bool
+		expectedCode int
+	}{
+		"success":         {[]string{"up"}, nil, nil, false, ExitCodeOk},
+		"unknown command": {[]string{"upp"}, nil, nil, false, ExitCodeValidation},
+		"invalid flags":   {[]string{"up", "--steps=abc"}, nil, nil, false, ExitCodeValidation},
+		"lock contention": {[]string{"up"}, nil, nil, true, ExitCodeLocked},
+		"failed migration": {
+			[]string{"up", "--steps=all"}, &failingMigration{*migration.NewDummyMigration(2)},
+			nil, false,
+			ExitCodeMigration,
+		},
+		"failed repository": {
+			[]string{"up"}, nil,
+			&execution.InMemoryRepository{SaveErr: errors.New("connection reset")}, false,
+			ExitCodeRepository,
+		},
+	}
+
+	for name, scenario := range scenarios {
+		registry := migration.NewGenericRegistry()
+		_ = registry.Register(migration.NewDummyMigration(1))
+		if scenario.migration != nil {
+			_ = registry.Register(scenario.migration)
+		}
+		repo := scenario.repository
+		if repo == nil {
+			repo = &execution.InMemoryRepository{}
+		}
+		migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+		settings := &BootstrapSettings{
+			RunMigrationsExclusively: true,
+			NewLocker: func(lockName string) lock.Locker {
+				return &fakeLocker{lockName: lockName, locked: scenario.locked}
+			},
+		}
+
+		exitCode := -1
+		Bootstrap(
+			context.Background(), nil, scenario.args, registry, repo, migPath, nil,
+			io.Discard, func(code int) { exitCode = code }, settings,
+		)
+		suite.Assert().Equal(scenario.expectedCode, exitCode, name)
+	}
+
+	suite.Assert().Equal(
+		ExitCodeMigration,
+		ExitCode(
+			errors.Join(
+				&handler.MigrationError{Version: 1, Err: errors.New("failed")},
+				&handler.RepositoryError{Err: errors.New("failed")},
+			),
+		),
+	)
+	suite.Assert().Equal(ExitCodeFailure, ExitCode(errors.New("failed")))
+}
+
+// recordingNotifier is a notify.Notifier which records the notifications
+type recordingNotifier struct {
+	notifications []notify.Notification
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, notification notify.Notification) error {
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func (suite *CliTestSuite) TestItRoutesTheFailuresToTheOwnersOfTheMigrations() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(&failingMigration{*migration.NewDummyMigration(1)})
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	fallback, payments := &recordingNotifier{}, &recordingNotifier{}
+	router := notify.NewRouter(fallback)
+	router.Route("payments", payments)
+	suite.Require().NoError(router.Assign("1*", "payments"))
+
+	var buf bytes.Buffer
+	exitCode := 0
+	Bootstrap(
+		context.Background(), nil, []string{"up"}, registry, &execution.InMemoryRepository{},
+		migPath, nil, &buf, func(code int) { exitCode = code },
+		&BootstrapSettings{Notifications: router},
+	)
+
+	suite.Assert().Equal(ExitCodeMigration, exitCode)
+	suite.Assert().Empty(fallback.notifications)
+	suite.Require().Len(payments.notifications, 1)
+	suite.Assert().Equal("duplicate column", payments.notifications[0].Error)
+}
+
+func (suite *CliTestSuite) TestItMasksTheDataInTheNonProductionEnvironments() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	db := &statementsDb{}
+	settings := &BootstrapSettings{
+		MaskingRoutines: []masking.Routine{
+			masking.SqlRoutine("users", "UPDATE users SET email = NULL"),
+		},
+	}
+
+	bootstrap := bootstrapRun{db: db, registry: registry, migPath: migPath, settings: settings}
+
+	suite.T().Setenv(EnvironmentEnvVar, "production")
+	_, exitCode := bootstrap.run("up")
+	suite.Assert().Zero(exitCode)
+	suite.Assert().Empty(db.statements)
+
+	output, exitCode := bootstrap.run("mask")
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Contains(output, "the mask command is not allowed in the \"production\"")
+
+	suite.T().Setenv(EnvironmentEnvVar, "staging")
+	_, exitCode = bootstrap.run("up")
+	suite.Assert().Zero(exitCode)
+	suite.Assert().Equal([]string{"UPDATE users SET email = NULL"}, db.statements)
+
+	output, exitCode = bootstrap.run("mask")
+	suite.Assert().Zero(exitCode)
+	suite.Assert().Contains(output, "Masked the data with 1 routines: users")
+	suite.Assert().Len(db.statements, 2)
+}
+
+func (suite *CliTestSuite) TestItRepairsTheExecutions() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{
+		PersistedExecutions: []execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+			{Version: 1, ExecutedAtMs: 3},
+			{Version: 2, ExecutedAtMs: 4},
+			{Version: 5, ExecutedAtMs: 5, FinishedAtMs: 6},
+		},
+	}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var exitCode int
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath, exitCode: &exitCode}
+
+	output := bootstrap.output("repair")
+	suite.Assert().Contains(output, "Execution issues: 3")
+	suite.Assert().Contains(
+		output, "version 1 is recorded 2 times (fix: keep one execution of version 1)",
+	)
+	suite.Assert().Contains(output, "version 2 is not finished (fix: mark version 2 as finished)")
+	suite.Assert().Contains(
+		output, "version 5 is executed but not registered (fix: delete the execution of version 5)",
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 4)
+
+	suite.Assert().Contains(
+		bootstrap.output("repair", "--fix=missing"), `unknown issue kind "missing"`,
+	)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+
+	output = bootstrap.output("repair", "--fix=duplicate,orphan")
+	suite.Assert().Contains(output, "Applied fixes: 2")
+	suite.Assert().Equal(
+		[]execution.MigrationExecution{
+			{Version: 2, ExecutedAtMs: 4}, {Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+		},
+		repo.PersistedExecutions,
+	)
+
+	output = bootstrap.output("repair", "--fix=all", "--yes")
+	suite.Assert().Contains(output, "mark version 2 as finished")
+	suite.Assert().True(repo.PersistedExecutions[0].Finished())
+	suite.Assert().Contains(bootstrap.output("repair"), "No execution issue found")
+
+	suite.Assert().Contains(bootstrap.output("up"), "Executed Up() for 1 migrations")
+	suite.Assert().Zero(exitCode)
+}
+
+func (suite *CliTestSuite) TestItOnlyRunsThePinnedMigrations() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	lockfilePath := filepath.Join(suite.T().TempDir(), "migrations.lock")
+
+	var exitCode int
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath, exitCode: &exitCode}
+
+	suite.Assert().Contains(
+		bootstrap.output("lockfile", "--file="+lockfilePath),
+		"Pinned 2 migrations in "+lockfilePath,
+	)
+	suite.Assert().Zero(exitCode)
+
+	// a migration merged after the lockfile was generated is refused
+	_ = registry.Register(migration.NewDummyMigration(3))
+	pinned := bootstrap
+	pinned.settings = &BootstrapSettings{Lockfile: lockfilePath}
+	output := pinned.output("up", "--steps=all")
+	suite.Assert().Contains(output, "the run is not pinned by the lockfile: the version 3")
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	output = bootstrap.output("up", "--steps=2", "--lockfile="+lockfilePath)
+	suite.Assert().Contains(output, "Executed Up() for 2 migrations")
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+
+	output = bootstrap.output("up", "--lockfile="+lockfilePath+".missing")
+	suite.Assert().Contains(output, "failed to open the lockfile")
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+
+	output = bootstrap.output("lockfile", "--file="+lockfilePath, "--hashing=md5")
+	suite.Assert().Contains(output, `unknown hashing strategy "md5"`)
+}
+
+func (suite *CliTestSuite) TestItPrintsTheCurrentVersion() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var exitCode int
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath, exitCode: &exitCode}
+
+	suite.Assert().Equal("0\n", bootstrap.output("version", "--current", "--timestamp"))
+
+	repo.PersistedExecutions = []execution.MigrationExecution{
+		{Version: 1, ExecutedAtMs: 1712953077000, FinishedAtMs: 1712953078000},
+		{Version: 2, ExecutedAtMs: 1712953079000},
+	}
+	suite.Assert().Equal("1\n", bootstrap.output("version", "--current"))
+	suite.Assert().Equal(
+		"1 2024-04-12T20:17:58Z\n", bootstrap.output("version", "--current", "--timestamp"),
+	)
+	suite.Assert().JSONEq(
+		`{"version": 1, "appliedAt": "2024-04-12T20:17:58Z"}`,
+		bootstrap.output("version", "--current", "--timestamp", "--format=json"),
+	)
+
+	suite.Assert().Contains(
+		bootstrap.output("version", "--timestamp"),
+		"the timestamp flag can only be used with the current flag",
+	)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+}
+
+func (suite *CliTestSuite) TestItListsTheExecutionsHistory() {
+	repo := &execution.InMemoryRepository{
+		PersistedExecutions: []execution.MigrationExecution{
+			{Version: 3, ExecutedAtMs: 1712953079000},
+			{Version: 1, ExecutedAtMs: 1712953077000, FinishedAtMs: 1712953078500, RunId: "r1"},
+			{Version: 2, ExecutedAtMs: 1712970000000, FinishedAtMs: 1712970000200, RunId: "r2"},
+		},
+	}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var exitCode int
+	bootstrap := bootstrapRun{
+		registry: migration.NewGenericRegistry(),
+		repo:     repo,
+		migPath:  migPath,
+		exitCode: &exitCode,
+	}
+
+	suite.Assert().Equal(
+		"Executions: 3\n"+
+			"  1 executed at 2024-04-12T20:17:57Z, finished at 2024-04-12T20:17:58Z (1.5s)\n"+
+			"  2 executed at 2024-04-13T01:00:00Z, finished at 2024-04-13T01:00:00Z (200ms)\n"+
+			"  3 executed at 2024-04-12T20:17:59Z, not finished\n",
+		bootstrap.output("history"),
+	)
+
+	// the unfinished executions are sorted after the finished ones, so they are first reversed
+	suite.Assert().JSONEq(
+		`[
+			{"version": 3, "executedAt": "2024-04-12T20:17:59Z"},
+			{"version": 1, "executedAt": "2024-04-12T20:17:57Z",
+				"finishedAt": "2024-04-12T20:17:58.5Z", "durationMs": 1500, "runId": "r1"},
+			{"version": 2, "executedAt": "2024-04-13T01:00:00Z",
+				"finishedAt": "2024-04-13T01:00:00.2Z", "durationMs": 200, "runId": "r2"}
+		]`,
+		bootstrap.output(
+			"history", "--since=2024-04-12", "--until=2024-04-13T02:00:00Z", "--sort=duration",
+			"--reverse", "--format=json",
+		),
+	)
+
+	output := bootstrap.output("history", "--since=2024-04-13", "--format=table")
+	suite.Assert().Contains(output, "VERSION  EXECUTED AT")
+	suite.Assert().Contains(
+		output, "2        2024-04-13T01:00:00Z  2024-04-13T01:00:00Z  200ms     r2",
+	)
+	suite.Assert().NotContains(output, "2024-04-12")
+
+	suite.Assert().Contains(bootstrap.output("history", "--sort=name"), `unknown order "name"`)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+	suite.Assert().Contains(
+		bootstrap.output("history", "--since=yesterday"), `invalid --since value`,
+	)
+	suite.Assert().Contains(
+		bootstrap.output("history", "--since=2024-04-13", "--until=2024-04-12"),
+		"the --since time must be before the --until time",
+	)
+}
+
+func (suite *CliTestSuite) TestItArchivesTheOldExecutions() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	hot := &execution.InMemoryRepository{
+		PersistedExecutions: []execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: 1712953077000, FinishedAtMs: 1712953078000},
+			{Version: 2, ExecutedAtMs: 1712970000000, FinishedAtMs: 1712970001000},
+		},
+	}
+	archive := &execution.InMemoryRepository{}
+	repo := execution.NewArchivedRepository(hot, archive)
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	var exitCode int
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath, exitCode: &exitCode}
+
+	suite.Assert().Contains(
+		bootstrap.output("archive", "--before=2024-04-13"),
+		"Archived 1 executions started before 2024-04-13T00:00:00Z",
+	)
+	suite.Assert().Len(hot.PersistedExecutions, 1)
+	suite.Assert().Len(archive.PersistedExecutions, 1)
+
+	// the archived versions stay applied, and are only listed when asked
+	suite.Assert().Equal(
+		"Applied migrations: 1\n  version_2.go\nArchived applied migrations: 1 (listed "+
+			"with --archived)\nPending migrations: 1\n  version_3.go\n"+
+			"Unknown executed versions: 0\n",
+		bootstrap.output("status"),
+	)
+	suite.Assert().Contains(bootstrap.output("status", "--archived"), "Applied migrations: 2\n")
+	suite.Assert().Contains(bootstrap.output("history"), "Executions: 1\n")
+	suite.Assert().Contains(
+		bootstrap.output("history", "--archived"),
+		"  1 executed at 2024-04-12T20:17:57Z, finished at 2024-04-12T20:17:58Z (1s, archived)\n",
+	)
+
+	output := bootstrap.output("up", "--steps=all")
+	suite.Assert().Contains(output, "Executed Up() for 1 migrations")
+	suite.Assert().Len(hot.PersistedExecutions, 2)
+
+	suite.Assert().Contains(
+		bootstrap.output("archive", "--before=2024-04-13", "--older-than=24h"),
+		"either the before or the older-than flag is required",
+	)
+	suite.Assert().Equal(ExitCodeValidation, exitCode)
+}
+
+// seedCommand is a custom command, which reuses the wiring of the invocation
+type seedCommand struct {
+	CommandEnv
+	rows int
+}
+
+func (c *seedCommand) Id() string {
+	return "seed"
+}
+
+func (c *seedCommand) Description() string {
+	return "Seeds the database"
+}
+
+func (c *seedCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.Output.DefineFlags(flagSet)
+	flagSet.IntVar(&c.rows, "rows", 1, "Number of rows")
+}
+
+func (c *seedCommand) ValidateFlags() error {
+	return c.Output.ValidateFlags()
+}
+
+func (c *seedCommand) Exec(stdWriter io.Writer) error {
+	executions, err := c.Repository.LoadExecutions()
+	if err != nil {
+		return err
+	}
+	return c.Output.Formatter().FormatMessage(
+		stdWriter,
+		fmt.Sprintf(
+			"Seeded %d rows for %d migrations, %d executed",
+			c.rows, len(c.Registry.OrderedMigrations()), len(executions),
+		),
+	)
+}
+
+func (suite *CliTestSuite) TestItRunsTheCustomCommands() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	repo := &execution.InMemoryRepository{
+		PersistedExecutions: []execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+		},
+	}
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	seed := CommandPlugin{
+		Id:  "seed",
+		New: func(env CommandEnv) cli.Command { return &seedCommand{CommandEnv: env} },
+	}
+
+	var buf bytes.Buffer
+	var hooked []string
+	exitCode := 0
+	run := func(plugin CommandPlugin, args ...string) {
+		buf.Reset()
+		New(
+			WithArgs(args),
+			WithRegistry(registry),
+			WithRepository(repo),
+			WithMigrationsDir(migPath),
+			WithOutput(&buf),
+			WithProcessExit(func(code int) { exitCode = code }),
+			WithSettings(
+				&BootstrapSettings{
+					CommandHooks: map[string]CommandHooks{
+						"seed": {
+							Before: func(context.Context, string) error {
+								hooked = append(hooked, "before")
+								return nil
+							},
+						},
+					},
+				},
+			),
+			WithCommands(plugin),
+		).Run(context.Background())
+	}
+
+	run(seed, "seed", "--rows=10", "--format=json")
+	suite.Assert().JSONEq(
+		`{"message": "Seeded 10 rows for 1 migrations, 1 executed"}`, buf.String(),
+	)
+	suite.Assert().Equal([]string{"before"}, hooked)
+	suite.Assert().Zero(exitCode)
+
+	run(seed, "help")
+	suite.Assert().Contains(buf.String(), "Seeds the database")
+
+	// the read-only custom commands don't initialize the executions storage
+	repo.InitErr = errors.New("init must not be called")
+	seed.ReadOnly = true
+	run(seed, "seed")
+	suite.Assert().Contains(buf.String(), "Seeded 1 rows")
+
+	seed.Id = "anonymize"
+	suite.Assert().PanicsWithError(
+		"could not bootstrap cli, the custom command anonymize has the id seed",
+		func() { run(seed, "status") },
+	)
+}
+
+// readOnlyReplica is a repository whose storage is read-only
+type readOnlyReplica struct {
+	*execution.InMemoryRepository
+}
+
+func (r *readOnlyReplica) ReadOnly() (bool, error) {
+	return true, nil
+}
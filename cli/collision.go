@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// resolveCollisions prompts for the migration to keep for each version declared by several
+// migrations, if the registry resolves the collisions interactively (see
+// migration.CollisionInteractive). The versions left unresolved stay quarantined, so the runs
+// stop before them.
+func resolveCollisions(
+	registry migration.MigrationsRegistry,
+	input io.Reader,
+	outputWriter io.Writer,
+) error {
+	resolver, ok := registry.(migration.CollisionResolver)
+	if !ok || resolver.CollisionPolicy() != migration.CollisionInteractive {
+		return nil
+	}
+
+	reader := bufio.NewReader(input)
+	for _, quarantined := range resolver.Collisions() {
+		_, _ = fmt.Fprintf(
+			outputWriter, "Migration version %d is declared by %d migrations:\n",
+			quarantined.Version(), len(quarantined.Candidates),
+		)
+		for i, candidate := range quarantined.Candidates {
+			_, _ = fmt.Fprintf(
+				outputWriter, "  %d) %s\n",
+				i+1, migration.CandidateLabel(candidate, quarantined.Files[i]),
+			)
+		}
+		_, _ = fmt.Fprintf(
+			outputWriter,
+			"Choose the one to keep (1-%d), or leave empty to quarantine the version: ",
+			len(quarantined.Candidates),
+		)
+
+		answer, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read the choice with error: %w", err)
+		}
+
+		version := quarantined.Version()
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			_, _ = fmt.Fprintf(outputWriter, "Version %d stays quarantined\n", version)
+			continue
+		}
+
+		choice, err := strconv.Atoi(answer)
+		if err != nil || choice < 1 || choice > len(quarantined.Candidates) {
+			return fmt.Errorf("invalid choice %q for migration version %d", answer, version)
+		}
+
+		if err = resolver.ResolveCollision(version, choice-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
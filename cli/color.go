@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// NoColorEnvVar is the environment variable which disables the colors of the text output when
+// it is set to any non-empty value (see https://no-color.org)
+const NoColorEnvVar = "NO_COLOR"
+
+// colorEnabled checks if the text output written to the writer is colored: only on a terminal,
+// unless the colors are disabled by the settings, the --no-color flag or NoColorEnvVar
+func colorEnabled(writer io.Writer, noColor bool) bool {
+	if noColor || os.Getenv(NoColorEnvVar) != "" {
+		return false
+	}
+	file, ok := writer.(*os.File)
+	return ok && isTerminal(file)
+}
+
+// failurePrefix starts the message the command runner writes when a command fails
+var failurePrefix = []byte("Failed to execute command ")
+
+// failureColorWriter colors the failure messages of the command runner in red
+type failureColorWriter struct {
+	io.Writer
+}
+
+func (w *failureColorWriter) Write(p []byte) (int, error) {
+	if !bytes.HasPrefix(p, failurePrefix) {
+		return w.Writer.Write(p)
+	}
+
+	message := bytes.TrimSuffix(p, []byte("\n"))
+	colored := append([]byte(colorRed), message...)
+	colored = append(colored, colorReset...)
+	colored = append(colored, p[len(message):]...)
+	if _, err := w.Writer.Write(colored); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
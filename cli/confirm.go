@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// errNotConfirmed is returned by the destructive commands when the confirmation is not given
+var errNotConfirmed = errors.New("aborted, the confirmation was not given")
+
+// confirmation asks for a typed "yes" before a destructive command (down, force:down, redo,
+// reset, fresh, repair, archive) runs, unless the --yes (or --non-interactive) flag is given
+type confirmation struct {
+	input *bufio.Reader
+
+	// interactive is true if the input is a terminal
+	interactive bool
+
+	yes bool
+}
+
+func (c *confirmation) DefineFlags(flagSet *flag.FlagSet) {
+	flagSet.BoolVar(&c.yes, "yes", false, "Skip the confirmation prompt")
+	flagSet.BoolVar(
+		&c.yes, "non-interactive", false, "Skip the confirmation prompt (alias of --yes)",
+	)
+}
+
+// confirm prompts for the confirmation of the action and fails with errNotConfirmed unless
+// "yes" is answered. Unless required, the prompt is shown only when the input is a terminal,
+// so the automated runs are not blocked.
+func (c *confirmation) confirm(stdWriter io.Writer, action string, required bool) error {
+	if c.yes || (!c.interactive && !required) {
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(stdWriter, "This will %s. Type \"yes\" to continue: ", action)
+
+	answer, err := c.input.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read the confirmation with error: %w", err)
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(answer), "yes") {
+		return errNotConfirmed
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/golibry/go-migrations/execution"
+)
+
+// describeInitFailure writes the failed initialization of the executions storage to the
+// output, along with the statements which create it, for a privileged role to run them. The
+// statements are written to the file instead, if one is given and it can be written.
+func describeInitFailure(w io.Writer, initErr *execution.InitError, file string) {
+	_, _ = fmt.Fprintf(w, "Executions storage initialization failed: %s\n", initErr)
+
+	if file != "" {
+		err := os.WriteFile(file, []byte(initErr.Script()), 0644)
+		if err == nil {
+			_, _ = fmt.Fprintf(
+				w,
+				"Run the statements written to %s with a privileged role,"+
+					" then run the command again\n",
+				file,
+			)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "Failed to write the statements to %s: %s\n", file, err)
+	}
+
+	_, _ = fmt.Fprintf(
+		w,
+		"Run these statements with a privileged role, then run the command again:\n\n%s",
+		initErr.Script(),
+	)
+}
@@ -0,0 +1,254 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/golibry/go-cli-command/cli"
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// DescribeReport is the result of the describe command: a machine readable description of the
+// migrations setup, for IDE plugins and dashboards
+type DescribeReport struct {
+	// LibraryVersion is the version of this library the binary was built with
+	LibraryVersion string `json:"libraryVersion"`
+
+	// SchemaVersion is the executions schema version supported by the library
+	SchemaVersion int `json:"schemaVersion"`
+
+	// StoredSchemaVersion is the schema version recorded by the repository, 0 if none is
+	// recorded or the repository does not record it (see execution.SchemaVersioner)
+	StoredSchemaVersion int `json:"storedSchemaVersion"`
+
+	// Commands holds the available commands, in id order
+	Commands []CommandReport `json:"commands"`
+
+	// Migrations holds the registered migrations, in version order
+	Migrations []MigrationReport `json:"migrations"`
+
+	Settings SettingsReport `json:"settings"`
+
+	// State holds the applied, pending and unknown migrations, as in the status command
+	State StatusReport `json:"state"`
+}
+
+// CommandReport describes a command and its flags
+type CommandReport struct {
+	Id          string       `json:"id"`
+	Description string       `json:"description"`
+	Flags       []FlagReport `json:"flags"`
+}
+
+// FlagReport describes a command flag
+type FlagReport struct {
+	Name    string `json:"name"`
+	Default string `json:"default"`
+	Usage   string `json:"usage"`
+}
+
+// SettingsReport describes the bootstrap settings (see BootstrapSettings)
+type SettingsReport struct {
+	MigrationsDir string `json:"migrationsDir"`
+
+	// Formats holds the names accepted by the --format flag, in name order
+	Formats       []string `json:"formats"`
+	DefaultFormat string   `json:"defaultFormat"`
+
+	RunMigrationsExclusively bool `json:"runMigrationsExclusively"`
+
+	// LockName is the identity of the lock used for exclusive runs, empty if the runs are not
+	// exclusive
+	LockName string `json:"lockName,omitempty"`
+
+	PermissionsPreflight bool `json:"permissionsPreflight"`
+	Audit                bool `json:"audit"`
+	History              bool `json:"history"`
+	Notifications        bool `json:"notifications"`
+	DeploymentGuard      bool `json:"deploymentGuard"`
+	SchemaDiff           bool `json:"schemaDiff"`
+
+	// Environments holds the names of the environments the drift command compares, in name
+	// order
+	Environments []string `json:"environments"`
+
+	// HookedCommands holds the ids of the commands with hooks, in id order
+	HookedCommands []string `json:"hookedCommands"`
+}
+
+// DescribeFormatter is an optional interface for the formatters which render the result of
+// the describe command. The output of the formatters which don't implement it is rendered by
+// the TextFormatter.
+type DescribeFormatter interface {
+	FormatDescribe(w io.Writer, report DescribeReport) error
+}
+
+// newSettingsReport describes the bootstrap settings, with the available output formats
+func newSettingsReport(
+	settings *BootstrapSettings,
+	dirPath migration.MigrationsDirPath,
+	output outputFlags,
+) SettingsReport {
+	report := SettingsReport{
+		MigrationsDir:            string(dirPath),
+		Formats:                  []string{},
+		DefaultFormat:            output.format,
+		RunMigrationsExclusively: settings.RunMigrationsExclusively,
+		PermissionsPreflight:     settings.PermissionsPreflight,
+		Audit:                    settings.AuditSink != nil,
+		History:                  settings.HistoryStore != nil,
+		Notifications:            settings.Notifications != nil,
+		DeploymentGuard:          settings.FleetVersionSource != nil,
+		SchemaDiff:               settings.SchemaSource != nil,
+		HookedCommands:           []string{},
+		Environments:             []string{},
+	}
+
+	for name := range output.formatters {
+		report.Formats = append(report.Formats, name)
+	}
+	slices.Sort(report.Formats)
+
+	if settings.RunMigrationsExclusively {
+		report.LockName = settings.LockName()
+	}
+
+	for name := range settings.Environments {
+		report.Environments = append(report.Environments, name)
+	}
+	slices.Sort(report.Environments)
+
+	for cmdId := range settings.CommandHooks {
+		report.HookedCommands = append(report.HookedCommands, cmdId)
+	}
+	slices.Sort(report.HookedCommands)
+
+	return report
+}
+
+// newCommandReport describes the command and the flags it defines
+func newCommandReport(cmd cli.Command) CommandReport {
+	report := CommandReport{Id: cmd.Id(), Description: cmd.Description(), Flags: []FlagReport{}}
+
+	flagSet := flag.NewFlagSet(cmd.Id(), flag.ContinueOnError)
+	cmd.DefineFlags(flagSet)
+	flagSet.VisitAll(
+		func(f *flag.Flag) {
+			report.Flags = append(
+				report.Flags,
+				FlagReport{Name: f.Name, Default: f.DefValue, Usage: trimUsage(f.Usage)},
+			)
+		},
+	)
+
+	return report
+}
+
+// trimUsage removes the indentation and the blank lines of a multiline flag usage
+func trimUsage(usage string) string {
+	lines := []string{}
+	for _, line := range strings.Split(usage, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DescribeCommand implements the Command interface to describe the migrations setup: the
+// commands, the registered migrations, the settings and the state. With --json, the output is
+// meant to be consumed by IDE plugins and dashboards.
+type DescribeCommand struct {
+	outputFlags
+	jsonOutput bool
+	registry   migration.MigrationsRegistry
+	repository execution.Repository
+	settings   SettingsReport
+	commands   []cli.Command
+}
+
+func (c *DescribeCommand) Id() string {
+	return "describe"
+}
+
+func (c *DescribeCommand) Description() string {
+	return "Describes the commands, the registered migrations, the settings and the state.\n" +
+		"Examples: migrate describe, migrate describe --json"
+}
+
+func (c *DescribeCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.BoolVar(&c.jsonOutput, "json", false, "Alias of --format=json, for IDEs and tooling")
+}
+
+func (c *DescribeCommand) ValidateFlags() error {
+	if c.jsonOutput {
+		c.format = FormatJson
+	}
+	return c.outputFlags.ValidateFlags()
+}
+
+func (c *DescribeCommand) Exec(stdWriter io.Writer) error {
+	stored, err := storedSchemaVersion(c.repository)
+	if err != nil {
+		return err
+	}
+
+	executions, err := c.repository.LoadExecutions()
+	if err != nil {
+		return fmt.Errorf("failed to load executions with error: %w", err)
+	}
+
+	migrations := c.registry.OrderedMigrations()
+	report := DescribeReport{
+		LibraryVersion:      libraryVersion(),
+		SchemaVersion:       execution.SchemaVersion,
+		StoredSchemaVersion: stored,
+		Commands:            []CommandReport{},
+		Migrations:          []MigrationReport{},
+		Settings:            c.settings,
+		State:               newStatusReport(migrations, executions),
+	}
+
+	for _, cmd := range c.commands {
+		report.Commands = append(report.Commands, newCommandReport(cmd))
+	}
+	slices.SortFunc(
+		report.Commands,
+		func(a, b CommandReport) int { return strings.Compare(a.Id, b.Id) },
+	)
+
+	for _, mig := range migrations {
+		report.Migrations = append(report.Migrations, newMigrationReport(mig, nil))
+	}
+
+	if formatter, ok := c.output().(DescribeFormatter); ok {
+		return formatter.FormatDescribe(stdWriter, report)
+	}
+	return (&TextFormatter{}).FormatDescribe(stdWriter, report)
+}
+
+func (f *TextFormatter) FormatDescribe(w io.Writer, report DescribeReport) error {
+	_, _ = fmt.Fprintf(w, "Library version: %s\n", report.LibraryVersion)
+	_, _ = fmt.Fprintf(w, "Migrations directory: %s\n", report.Settings.MigrationsDir)
+
+	cmdIds := make([]string, 0, len(report.Commands))
+	for _, cmd := range report.Commands {
+		cmdIds = append(cmdIds, cmd.Id)
+	}
+	_, _ = fmt.Fprintf(w, "Commands: %s\n", strings.Join(cmdIds, ", "))
+
+	_, _ = fmt.Fprintf(w, "Registered migrations: %d\n", len(report.Migrations))
+	return f.FormatStatus(w, report.State)
+}
+
+func (f *JsonFormatter) FormatDescribe(w io.Writer, report DescribeReport) error {
+	return json.NewEncoder(w).Encode(report)
+}
+
+func (f *QuietFormatter) FormatDescribe(io.Writer, DescribeReport) error { return nil }
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/golibry/go-migrations/migration"
+	"github.com/golibry/go-migrations/schemadiff"
+)
+
+// DiffCommand implements the Command interface to compare the live database schema against a
+// declared target (a schema dump file or another database) and draft a migration file with
+// the required DDL, for teams moving toward declarative schema management
+type DiffCommand struct {
+	outputFlags
+	migrationsDir migration.MigrationsDirPath
+	template      string
+	source        schemadiff.Source
+	targets       map[string]schemadiff.Source
+	ignoredTables []string
+	ctx           context.Context
+	targetPath    string
+	targetDb      string
+	allowDrop     bool
+	dryRun        bool
+	author        string
+	ticket        string
+}
+
+func (c *DiffCommand) Id() string {
+	return "diff"
+}
+
+func (c *DiffCommand) Description() string {
+	return "Compares the live database schema against a declared target (a schema dump file " +
+		"or another database) and drafts a migration file with the required DDL.\n" +
+		"Examples: migrate diff --target=schema.sql, migrate diff --target-db=reference --dry-run"
+}
+
+func (c *DiffCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.StringVar(
+		&c.targetPath,
+		"target",
+		"",
+		"Path of the schema dump file declaring the target schema (for example, the output "+
+			"of mysqldump --no-data or pg_dump --schema-only)",
+	)
+	flagSet.StringVar(
+		&c.targetDb,
+		"target-db",
+		"",
+		"Name of the target schema database, as configured in the bootstrap settings",
+	)
+	flagSet.BoolVar(
+		&c.allowDrop,
+		"allow-drop",
+		false,
+		"Include the destructive statements (dropped tables and columns, changed column "+
+			"types) in the drafted migration",
+	)
+	flagSet.BoolVar(
+		&c.dryRun,
+		"dry-run",
+		false,
+		"Only display the drafted DDL, without generating the migration file",
+	)
+	flagSet.StringVar(
+		&c.author,
+		"author",
+		"",
+		"Author written in the generated file. Defaults to the "+AuthorEnvVar+
+			" environment variable or, if missing, to the git user (name and email).",
+	)
+	flagSet.StringVar(
+		&c.ticket,
+		"ticket",
+		"",
+		"Ticket/issue ID written in the generated file. Defaults to the "+TicketEnvVar+
+			" environment variable.",
+	)
+}
+
+func (c *DiffCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
+	if (c.targetPath == "") == (c.targetDb == "") {
+		return errors.New("exactly one of the --target and --target-db flags is required")
+	}
+
+	if _, ok := c.targets[c.targetDb]; c.targetDb != "" && !ok {
+		names := make([]string, 0, len(c.targets))
+		for name := range c.targets {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		return fmt.Errorf(
+			"unknown target database %q, the configured ones are: %s",
+			c.targetDb, strings.Join(names, ", "),
+		)
+	}
+
+	if strings.TrimSpace(c.author) == "" {
+		c.author = os.Getenv(AuthorEnvVar)
+	}
+	if strings.TrimSpace(c.author) == "" {
+		c.author = gitAuthor()
+	}
+	if strings.TrimSpace(c.ticket) == "" {
+		c.ticket = os.Getenv(TicketEnvVar)
+	}
+	return nil
+}
+
+func (c *DiffCommand) Exec(stdWriter io.Writer) error {
+	current, err := c.source.Schema(c.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the live schema with error: %w", err)
+	}
+
+	target := c.targets[c.targetDb]
+	if c.targetPath != "" {
+		target = schemadiff.NewDumpSource(c.targetPath, current.Dialect)
+	}
+
+	targetSchema, err := target.Schema(c.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the target schema with error: %w", err)
+	}
+
+	var statements, skipped []string
+	for _, change := range schemadiff.Diff(current, targetSchema, c.ignoredTables...) {
+		if change.Destructive && !c.allowDrop {
+			skipped = append(skipped, change.Sql+";")
+			continue
+		}
+		statements = append(statements, change.Sql+";")
+	}
+
+	if len(statements) == 0 && len(skipped) == 0 {
+		return c.output().FormatMessage(stdWriter, "No schema differences found")
+	}
+
+	var msg strings.Builder
+	if len(statements) > 0 {
+		msg.WriteString("Drafted DDL:\n" + strings.Join(statements, "\n") + "\n")
+	}
+	if len(skipped) > 0 {
+		msg.WriteString(
+			"Skipped destructive statements (use --allow-drop to include them):\n" +
+				strings.Join(skipped, "\n") + "\n",
+		)
+	}
+
+	if c.dryRun || len(statements) == 0 {
+		return c.output().FormatMessage(stdWriter, strings.TrimSuffix(msg.String(), "\n"))
+	}
+
+	fileName, err := migration.GenerateMigration(
+		c.migrationsDir,
+		migration.GenerateOptions{
+			Author:   c.author,
+			Ticket:   c.ticket,
+			Template: c.template,
+			UpSql:    strings.Join(statements, "\n"),
+			DownStub: true,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	msg.WriteString("New migration file generated: " + fileName)
+	return c.output().FormatMessage(stdWriter, msg.String())
+}
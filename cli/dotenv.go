@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvFileEnvVar is the environment variable with the path of the dotenv file ConfigFromEnv
+// loads before reading the configuration (see LoadDotEnv)
+const EnvFileEnvVar = "MIGRATIONS_ENV_FILE"
+
+// LoadDotEnv sets the environment variables from the dotenv file at the path, so the
+// configuration (see ConfigFromEnv) and the DSN of the database can be resolved from it, for
+// example when the binary runs from the host machine instead of the containers. The variables
+// already set in the environment are kept, so the file only provides the defaults.
+//
+// The file has a KEY=VALUE assignment per line, optionally prefixed by export. The values can
+// be double-quoted (with the escape sequences of Go, such as \n) or single-quoted (taken as
+// they are). The empty lines and the ones starting with # are ignored, as well as the comments
+// following an unquoted value after a space.
+func LoadDotEnv(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the dotenv file %s with error: %w", path, err)
+	}
+
+	values, err := parseDotEnv(string(content))
+	if err != nil {
+		return fmt.Errorf("invalid dotenv file %s: %w", path, err)
+	}
+
+	for key, value := range values {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to set %s from the dotenv file with error: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// EnvOrDefault returns the trimmed value of the environment variable, or defaultValue if it is
+// empty. It resolves the settings of the main packages (for example, the DSN of the database)
+// the same way ConfigFromEnv resolves the configuration.
+func EnvOrDefault(envVar string, defaultValue string) string {
+	if value := strings.TrimSpace(os.Getenv(envVar)); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// parseDotEnv returns the variables assigned by the content of a dotenv file. The last
+// assignment of a variable wins.
+func parseDotEnv(content string) (map[string]string, error) {
+	values := make(map[string]string)
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t\"'") {
+			return nil, fmt.Errorf("line %d is not a KEY=VALUE assignment", i+1)
+		}
+
+		value, err := dotEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s on line %d: %w", key, i+1, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// dotEnvValue unquotes the value and removes its trailing comment
+func dotEnvValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	quote := value[0]
+	if quote != '"' && quote != '\'' {
+		if comment := strings.Index(value, " #"); comment >= 0 {
+			value = value[:comment]
+		}
+		return strings.TrimSpace(value), nil
+	}
+
+	end := 1
+	for ; end < len(value) && value[end] != quote; end++ {
+		if quote == '"' && value[end] == '\\' {
+			end++
+		}
+	}
+	if end >= len(value) {
+		return "", errors.New("unterminated quoted value")
+	}
+	if rest := strings.TrimSpace(value[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", errors.New("unexpected characters after the quoted value")
+	}
+
+	if quote == '\'' {
+		return value[1:end], nil
+	}
+	return strconv.Unquote(value[:end+1])
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/golibry/go-migrations/drift"
+)
+
+// DriftFormatter is an optional interface for the formatters which render the result of the
+// drift command. The output of the formatters which don't implement it is rendered by the
+// TextFormatter.
+type DriftFormatter interface {
+	FormatDrift(w io.Writer, report drift.Report) error
+}
+
+// DriftCommand implements the Command interface to compare the applied versions (and, if
+// configured, the schemas) of two environments, like staging and production. It fails when
+// the environments diverge, so it can gate releases.
+type DriftCommand struct {
+	outputFlags
+	environments  map[string]drift.Environment
+	ignoredTables []string
+	ctx           context.Context
+	from          string
+	to            string
+	noSchema      bool
+}
+
+func (c *DriftCommand) Id() string {
+	return "drift"
+}
+
+func (c *DriftCommand) Description() string {
+	return "Compares the applied versions (and the schemas, if configured) of two environments " +
+		"and reports the divergences.\n" +
+		"Examples: migrate drift --from=staging --to=production"
+}
+
+func (c *DriftCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.StringVar(
+		&c.from, "from", "", "Name of the first environment, as configured in the settings",
+	)
+	flagSet.StringVar(
+		&c.to, "to", "", "Name of the second environment, as configured in the settings",
+	)
+	flagSet.BoolVar(&c.noSchema, "no-schema", false, "Compare only the applied versions")
+}
+
+func (c *DriftCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
+	if c.from == "" || c.to == "" {
+		return errors.New("both the --from and the --to flags are required")
+	}
+	if c.from == c.to {
+		return errors.New("the --from and the --to environments must differ")
+	}
+
+	for _, name := range []string{c.from, c.to} {
+		if _, ok := c.environments[name]; !ok {
+			names := make([]string, 0, len(c.environments))
+			for envName := range c.environments {
+				names = append(names, envName)
+			}
+			slices.Sort(names)
+
+			return fmt.Errorf(
+				"unknown environment %q, the configured ones are: %s",
+				name, strings.Join(names, ", "),
+			)
+		}
+	}
+	return nil
+}
+
+func (c *DriftCommand) Exec(stdWriter io.Writer) error {
+	from, to := c.environments[c.from], c.environments[c.to]
+	if c.noSchema {
+		from.Schema, to.Schema = nil, nil
+	}
+
+	report, err := drift.Compare(c.ctx, c.from, from, c.to, to, c.ignoredTables...)
+	if err != nil {
+		return err
+	}
+
+	if formatter, ok := c.output().(DriftFormatter); ok {
+		err = formatter.FormatDrift(stdWriter, report)
+	} else {
+		err = (&TextFormatter{}).FormatDrift(stdWriter, report)
+	}
+	if err != nil {
+		return err
+	}
+
+	if report.Drifted() {
+		return fmt.Errorf("environments %s and %s diverge", c.from, c.to)
+	}
+	return nil
+}
+
+func (f *TextFormatter) FormatDrift(w io.Writer, report drift.Report) error {
+	_, _ = fmt.Fprintf(w, "Comparing %s with %s\n", report.From, report.To)
+
+	for _, versions := range []struct {
+		label    string
+		versions []uint64
+	}{
+		{"Applied only in " + report.From, report.OnlyInFrom},
+		{"Applied only in " + report.To, report.OnlyInTo},
+		{"Unfinished in " + report.From, report.UnfinishedFrom},
+		{"Unfinished in " + report.To, report.UnfinishedTo},
+	} {
+		_, _ = fmt.Fprintf(w, "%s: %d\n", versions.label, len(versions.versions))
+		for _, version := range versions.versions {
+			_, _ = fmt.Fprintf(w, "  %d\n", version)
+		}
+	}
+
+	switch {
+	case !report.SchemaCompared:
+		_, _ = fmt.Fprintln(w, "Schemas: not compared")
+	case report.SchemaDrifted():
+		_, _ = fmt.Fprintf(
+			w, "Schemas: different (%s: %s, %s: %s)\n",
+			report.From, report.FromSchemaHash, report.To, report.ToSchemaHash,
+		)
+	default:
+		_, _ = fmt.Fprintln(w, "Schemas: identical")
+	}
+
+	if report.Drifted() {
+		_, err := fmt.Fprintln(w, "Drift detected")
+		return err
+	}
+	_, err := fmt.Fprintln(w, "No drift detected")
+	return err
+}
+
+func (f *JsonFormatter) FormatDrift(w io.Writer, report drift.Report) error {
+	return json.NewEncoder(w).Encode(report)
+}
+
+func (f *QuietFormatter) FormatDrift(io.Writer, drift.Report) error { return nil }
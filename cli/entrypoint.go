@@ -0,0 +1,330 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	migrations "github.com/golibry/go-migrations"
+	"github.com/golibry/go-migrations/audit"
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/history"
+	"github.com/golibry/go-migrations/lock"
+	"github.com/golibry/go-migrations/migration"
+)
+
+const (
+	// SkipMigrationsEnvVar disables the migrations run of DockerEntrypoint when set to a true
+	// value (see strconv.ParseBool), so the application command is started right away
+	SkipMigrationsEnvVar = "MIGRATIONS_SKIP"
+
+	// DbWaitTimeoutEnvVar is the environment variable with the maximum duration (see
+	// time.ParseDuration) DockerEntrypoint waits for the database to be reachable
+	DbWaitTimeoutEnvVar = "MIGRATIONS_DB_WAIT_TIMEOUT"
+
+	// RunWaitTimeoutEnvVar is the environment variable with the maximum duration (see
+	// time.ParseDuration) DockerEntrypoint waits for the migrations run of another container,
+	// which holds the lock, to finish
+	RunWaitTimeoutEnvVar = "MIGRATIONS_RUN_WAIT_TIMEOUT"
+)
+
+const (
+	// DefaultDbWaitTimeout is used when DbWaitTimeoutEnvVar is not set
+	DefaultDbWaitTimeout = 30 * time.Second
+
+	// DefaultRunWaitTimeout is used when RunWaitTimeoutEnvVar is not set
+	DefaultRunWaitTimeout = 5 * time.Minute
+)
+
+// EntrypointSettings configures DockerEntrypoint. Of the embedded BootstrapSettings, the lock
+// settings (RunMigrationsExclusively, RunLockFilesDirPath, MigrationsCmdLockName, LockTarget
+// and NewLocker), AuditSink, HistoryStore, PermissionsPreflight, FleetVersionSource,
+// DefaultFormat and Formatters are used, as in Bootstrap.
+type EntrypointSettings struct {
+	BootstrapSettings
+
+	// Optional function which checks that the database is reachable. Defaults to the
+	// PingContext method of the db (for example, *sql.DB), if it has one. Until the
+	// DbWaitTimeoutEnvVar timeout, both the ping and the repository initialization are
+	// retried.
+	Ping func(ctx context.Context) error
+
+	// The interval between the retries of the database wait and the polls of the migrations
+	// run of another container. Defaults to migrations.DefaultPollInterval.
+	PollInterval time.Duration
+
+	// The writer of the progress and of the run report. Defaults to os.Stdout.
+	Output io.Writer
+
+	// Optional function which replaces the current process with the application command.
+	// Defaults to syscall.Exec on Unix and to running the command as a child process (then
+	// exiting with its exit code) on the other systems.
+	Exec func(path string, args []string, env []string) error
+}
+
+// pingContexter is implemented by the database handles which can check the connection, like
+// *sql.DB
+type pingContexter interface {
+	PingContext(ctx context.Context) error
+}
+
+// entrypointEnv holds the configuration of DockerEntrypoint read from the environment
+type entrypointEnv struct {
+	skip           bool
+	dbWaitTimeout  time.Duration
+	runWaitTimeout time.Duration
+}
+
+// newEntrypointEnv reads the configuration of DockerEntrypoint from the environment variables
+func newEntrypointEnv() (entrypointEnv, error) {
+	env := entrypointEnv{dbWaitTimeout: DefaultDbWaitTimeout, runWaitTimeout: DefaultRunWaitTimeout}
+
+	if value := os.Getenv(SkipMigrationsEnvVar); value != "" {
+		skip, err := strconv.ParseBool(value)
+		if err != nil {
+			return env, fmt.Errorf("invalid %s value %q: %w", SkipMigrationsEnvVar, value, err)
+		}
+		env.skip = skip
+	}
+
+	for envVar, timeout := range map[string]*time.Duration{
+		DbWaitTimeoutEnvVar:  &env.dbWaitTimeout,
+		RunWaitTimeoutEnvVar: &env.runWaitTimeout,
+	} {
+		value := os.Getenv(envVar)
+		if value == "" {
+			continue
+		}
+
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			return env, fmt.Errorf(
+				"invalid %s value %q, expected a positive duration", envVar, value,
+			)
+		}
+		*timeout = duration
+	}
+
+	return env, nil
+}
+
+// DockerEntrypoint implements the "migrate then start" container entrypoint: it waits for the
+// database, runs all the pending migrations (exclusively, if RunMigrationsExclusively is set)
+// and replaces the process with the application command, given by args (for example,
+// os.Args[1:], optionally starting with "--"). Without an application command, it returns
+// after the migrations run.
+//
+// When another container holds the lock, it waits for that run to execute all the migrations
+// instead. The behavior can be tuned per deployment with SkipMigrationsEnvVar,
+// DbWaitTimeoutEnvVar and RunWaitTimeoutEnvVar. An error is returned if the application
+// command is not started.
+//
+// Example (the image entrypoint is `/app/migrate-and-run /app/server --port=8080`):
+//
+//	err := cli.DockerEntrypoint(
+//		ctx, db, os.Args[1:], migration.NewAutoDirMigrationsRegistry(dirPath), repository,
+//		&cli.EntrypointSettings{
+//			BootstrapSettings: cli.BootstrapSettings{
+//				RunMigrationsExclusively: true,
+//				NewLocker: func(lockName string) lock.Locker {
+//					return lock.NewRedisLocker(redisClient, lockName, time.Minute)
+//				},
+//			},
+//		},
+//	)
+func DockerEntrypoint(
+	ctx context.Context,
+	db any,
+	args []string,
+	registry migration.MigrationsRegistry,
+	repository execution.Repository,
+	settings *EntrypointSettings,
+) error {
+	if settings == nil {
+		settings = &EntrypointSettings{}
+	}
+
+	output := settings.Output
+	if output == nil {
+		output = os.Stdout
+	}
+
+	env, err := newEntrypointEnv()
+	if err != nil {
+		return fmt.Errorf("failed to configure the entrypoint: %w", err)
+	}
+
+	if !env.skip {
+		err = migrateForEntrypoint(ctx, db, registry, repository, settings, env, output)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to find the application command %s: %w", args[0], err)
+	}
+
+	execCmd := settings.Exec
+	if execCmd == nil {
+		execCmd = execApplication
+	}
+
+	if err = execCmd(path, args, os.Environ()); err != nil {
+		return fmt.Errorf("failed to start the application command %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// migrateForEntrypoint waits for the database and runs all the pending migrations, or waits
+// for the run of the container which holds the lock
+func migrateForEntrypoint(
+	ctx context.Context,
+	db any,
+	registry migration.MigrationsRegistry,
+	repository execution.Repository,
+	settings *EntrypointSettings,
+	env entrypointEnv,
+	output io.Writer,
+) error {
+	ctx, _ = execution.EnsureRunId(ctx)
+	if settings.FleetVersionSource != nil {
+		ctx = handler.WithDeploymentGuard(ctx, settings.FleetVersionSource)
+	}
+
+	pollInterval := settings.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = migrations.DefaultPollInterval
+	}
+
+	migrationsHandler, err := waitForDb(ctx, db, registry, repository, settings, env, pollInterval)
+	if err != nil {
+		return err
+	}
+
+	if err = execution.CheckSchemaVersion(repository); err != nil {
+		_, _ = fmt.Fprintf(output, "Warning: %s\n", err)
+	}
+
+	if settings.AuditSink != nil {
+		migrationsHandler.AddListener(audit.NewListener(settings.AuditSink))
+	}
+	if settings.HistoryStore != nil {
+		migrationsHandler.AddListener(history.NewListener(settings.HistoryStore))
+	}
+
+	if settings.RunMigrationsExclusively {
+		lockSettings, targetErr := settings.withRepositoryLockTarget(repository)
+		if targetErr != nil {
+			_, _ = fmt.Fprintf(output, "Warning: %s\n", targetErr)
+		}
+
+		locker := lockSettings.locker()
+		err = locker.Lock(ctx)
+		if errors.Is(err, lock.ErrLockHeld) {
+			_, _ = fmt.Fprintln(output, "Migrations are run by another container, waiting for them")
+			waitCtx, cancel := context.WithTimeout(ctx, env.runWaitTimeout)
+			defer cancel()
+
+			return migrations.WaitUntilCurrent(waitCtx, registry, repository, pollInterval)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to acquire the migrations lock: %w", err)
+		}
+
+		defer func() {
+			// the lock is released even when the container is stopped during the run, so the
+			// next containers don't wait for it until it expires
+			if unlockErr := locker.Unlock(context.WithoutCancel(ctx)); unlockErr != nil {
+				_, _ = fmt.Fprintf(
+					output, "Warning: failed to release the migrations lock: %s\n", unlockErr,
+				)
+			}
+		}()
+	}
+
+	execs, err := migrationsHandler.MigrateUp(ctx, handler.NumOfRuns(registry.Count()))
+
+	outputFlags := newOutputFlags(settings.DefaultFormat, settings.Formatters, false)
+	if validateErr := outputFlags.ValidateFlags(); validateErr != nil {
+		return errors.Join(err, validateErr)
+	}
+	_ = outputFlags.output().FormatRun(
+		output, newRunReport("up", "up", false, execution.RunIdFrom(ctx), execs),
+	)
+
+	return err
+}
+
+// waitForDb creates the migrations handler (which initializes the repository) once the
+// database is reachable, retrying until the database wait timeout. The permissions preflight,
+// if enabled, runs before the repository initialization, and missing privileges are not
+// retried.
+func waitForDb(
+	ctx context.Context,
+	db any,
+	registry migration.MigrationsRegistry,
+	repository execution.Repository,
+	settings *EntrypointSettings,
+	env entrypointEnv,
+	pollInterval time.Duration,
+) (*handler.MigrationsHandler, error) {
+	ping := settings.Ping
+	if pinger, ok := db.(pingContexter); ping == nil && ok {
+		ping = pinger.PingContext
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, env.dbWaitTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		if ping != nil {
+			err = ping(waitCtx)
+		}
+
+		if err == nil && settings.PermissionsPreflight {
+			err = execution.CheckPermissions(repository)
+
+			var permissionsErr *execution.PermissionsError
+			if errors.As(err, &permissionsErr) {
+				return nil, fmt.Errorf("permissions preflight failed: %w", err)
+			}
+		}
+
+		if err == nil {
+			var migrationsHandler *handler.MigrationsHandler
+			migrationsHandler, err = handler.NewHandlerWithDB(
+				registry, repository, settings.executionPlanBuilder(), db,
+			)
+			if err == nil {
+				return migrationsHandler, nil
+			}
+		}
+
+		select {
+		case <-waitCtx.Done():
+			return nil, fmt.Errorf(
+				"failed to wait for the database: %w", errors.Join(waitCtx.Err(), err),
+			)
+		case <-ticker.C:
+		}
+	}
+}
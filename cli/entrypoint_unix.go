@@ -0,0 +1,11 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package cli
+
+import "syscall"
+
+// execApplication replaces the current process with the application command, so it receives
+// the container signals directly
+func execApplication(path string, args []string, env []string) error {
+	return syscall.Exec(path, args, env)
+}
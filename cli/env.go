@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+)
+
+const (
+	// DirEnvVar is the environment variable with the path of the migrations directory
+	DirEnvVar = "MIGRATIONS_DIR"
+
+	// TableEnvVar is the environment variable with the name of the executions table (or
+	// collection) the repository should use
+	TableEnvVar = "MIGRATIONS_TABLE"
+
+	// LockDirEnvVar is the environment variable with the directory of the lock files. When
+	// set, the migration commands run exclusively.
+	LockDirEnvVar = "MIGRATIONS_LOCK_DIR"
+
+	// LockNameEnvVar is the environment variable with the name of the lock (see
+	// BootstrapSettings.MigrationsCmdLockName)
+	LockNameEnvVar = "MIGRATIONS_LOCK_NAME"
+
+	// FormatEnvVar is the environment variable with the output format used when the --format
+	// flag is not provided
+	FormatEnvVar = "MIGRATIONS_FORMAT"
+
+	// MigrationTimeoutEnvVar is the environment variable with the timeout (see
+	// time.ParseDuration) of each Up() or Down() call
+	MigrationTimeoutEnvVar = "MIGRATIONS_TIMEOUT"
+
+	// CollisionPolicyEnvVar is the environment variable with the policy applied to the
+	// migrations declaring the same version (see migration.CollisionPolicy)
+	CollisionPolicyEnvVar = "MIGRATIONS_COLLISION_POLICY"
+
+	// LockfileEnvVar is the environment variable with the path of the lockfile the up runs are
+	// restricted to (see BootstrapSettings.Lockfile)
+	LockfileEnvVar = "MIGRATIONS_LOCKFILE"
+)
+
+const (
+	// DefaultMigrationsDir is used when DirEnvVar is not set, relative to the working directory
+	DefaultMigrationsDir = "migrations"
+
+	// DefaultMigrationsTable is used when TableEnvVar is not set
+	DefaultMigrationsTable = "migration_executions"
+)
+
+// EnvConfig is the configuration read from the environment variables by ConfigFromEnv
+type EnvConfig struct {
+	// The migrations directory (DirEnvVar). Defaults to DefaultMigrationsDir.
+	Dir migration.MigrationsDirPath
+
+	// The executions table, or collection, to build the repository with (TableEnvVar).
+	// Defaults to DefaultMigrationsTable.
+	Table string
+
+	// The directory of the lock files (LockDirEnvVar). The migration commands run exclusively
+	// if it is not empty.
+	LockDir string
+
+	// The name of the lock (LockNameEnvVar). Defaults to MigrationsCmdLockName.
+	LockName string
+
+	// The default output format (FormatEnvVar). Defaults to FormatText.
+	Format string
+
+	// The timeout of each Up() or Down() call (MigrationTimeoutEnvVar). Zero disables it.
+	MigrationTimeout time.Duration
+
+	// The policy applied to the migrations declaring the same version
+	// (CollisionPolicyEnvVar). Defaults to migration.CollisionFail.
+	CollisionPolicy migration.CollisionPolicy
+
+	// The path of the lockfile the up runs are restricted to (LockfileEnvVar). Empty if the
+	// runs are not restricted.
+	Lockfile string
+}
+
+// ConfigFromEnv reads the configuration from the environment variables (see DirEnvVar,
+// TableEnvVar, LockDirEnvVar, LockNameEnvVar, FormatEnvVar, MigrationTimeoutEnvVar,
+// CollisionPolicyEnvVar and LockfileEnvVar). The unset ones get their defaults. Fails if a
+// value is invalid, for example, if the migrations directory doesn't exist.
+//
+// If EnvFileEnvVar is set, the dotenv file it names is loaded first (see LoadDotEnv), so the
+// variables read afterward by the main package (for example, the DSN of the database) can come
+// from it too.
+func ConfigFromEnv() (EnvConfig, error) {
+	if path := strings.TrimSpace(os.Getenv(EnvFileEnvVar)); path != "" {
+		if err := LoadDotEnv(path); err != nil {
+			return EnvConfig{}, fmt.Errorf("invalid %s value: %w", EnvFileEnvVar, err)
+		}
+	}
+
+	config := EnvConfig{
+		Table:           EnvOrDefault(TableEnvVar, DefaultMigrationsTable),
+		LockDir:         strings.TrimSpace(os.Getenv(LockDirEnvVar)),
+		LockName:        strings.TrimSpace(os.Getenv(LockNameEnvVar)),
+		Format:          strings.TrimSpace(os.Getenv(FormatEnvVar)),
+		CollisionPolicy: migration.CollisionPolicy(EnvOrDefault(CollisionPolicyEnvVar, "")),
+		Lockfile:        strings.TrimSpace(os.Getenv(LockfileEnvVar)),
+	}
+
+	dir, err := migration.NewMigrationsDirPath(EnvOrDefault(DirEnvVar, DefaultMigrationsDir))
+	if err != nil {
+		return config, fmt.Errorf("invalid %s value: %w", DirEnvVar, err)
+	}
+	config.Dir = dir
+
+	if value := os.Getenv(MigrationTimeoutEnvVar); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return config, fmt.Errorf(
+				"invalid %s value %q, expected a duration", MigrationTimeoutEnvVar, value,
+			)
+		}
+		config.MigrationTimeout = timeout
+	}
+
+	switch config.CollisionPolicy {
+	case "":
+		config.CollisionPolicy = migration.CollisionFail
+	case migration.CollisionFail, migration.CollisionPreferNewestFile,
+		migration.CollisionQuarantine, migration.CollisionInteractive:
+	default:
+		return config, fmt.Errorf(
+			"invalid %s value %q", CollisionPolicyEnvVar, config.CollisionPolicy,
+		)
+	}
+
+	return config, nil
+}
+
+// Settings returns the bootstrap settings of the configuration
+func (c EnvConfig) Settings() *BootstrapSettings {
+	return &BootstrapSettings{
+		RunMigrationsExclusively: c.LockDir != "",
+		RunLockFilesDirPath:      c.LockDir,
+		MigrationsCmdLockName:    c.LockName,
+		DefaultFormat:            c.Format,
+		MigrationTimeout:         c.MigrationTimeout,
+		Lockfile:                 c.Lockfile,
+	}
+}
+
+// BootstrapFromEnv bootstraps the cli (see Bootstrap) from the configuration read from the
+// environment variables (see ConfigFromEnv), with the command line arguments of the process,
+// the migrations registered to migration.DefaultRegistry and the repository. Build the
+// repository with the EnvConfig.Table name, so it is configured from the environment too.
+// Use New for the settings which can't be read from the environment.
+//
+// Example:
+//
+//	config, err := cli.ConfigFromEnv()
+//	...
+//	repo, err := repository.NewMysqlHandler("", config.Table, ctx, db)
+//	...
+//	cli.BootstrapFromEnv(ctx, db, repo)
+func BootstrapFromEnv(ctx context.Context, db any, repository execution.Repository) {
+	bootstrapFromEnv(ctx, db, os.Args[1:], repository, os.Stdout, os.Exit)
+}
+
+func bootstrapFromEnv(
+	ctx context.Context,
+	db any,
+	args []string,
+	repository execution.Repository,
+	outputWriter io.Writer,
+	processExit func(code int),
+) {
+	config, err := ConfigFromEnv()
+	if err != nil {
+		_, _ = fmt.Fprintf(outputWriter, "Invalid configuration: %s\n", err)
+		processExit(ExitCodeValidation)
+		return
+	}
+
+	New(
+		WithDB(db),
+		WithArgs(args),
+		WithRegistry(
+			migration.NewAutoDirMigrationsRegistryWithPolicy(config.Dir, config.CollisionPolicy),
+		),
+		WithRepository(repository),
+		WithMigrationsDir(config.Dir),
+		WithOutput(outputWriter),
+		WithProcessExit(processExit),
+		WithSettings(config.Settings()),
+	).Run(ctx)
+}
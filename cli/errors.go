@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"errors"
+	"io"
+
+	"github.com/golibry/go-cli-command/cli"
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/lock"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// Exit codes of the commands, per category of failure (see ExitCode). They are stable, so
+// orchestration tooling can branch on them.
+const (
+	// ExitCodeOk is returned when the command succeeds
+	ExitCodeOk = cli.StatusOk
+
+	// ExitCodeFailure is returned for the failures which have no category
+	ExitCodeFailure = cli.StatusErr
+
+	// ExitCodeValidation is returned for invalid arguments or settings, unknown commands and
+	// migrations which fail validation (see ValidationError)
+	ExitCodeValidation = 2
+
+	// ExitCodeLocked is returned when the lock of the exclusive runs can't be acquired (see
+	// LockError)
+	ExitCodeLocked = 3
+
+	// ExitCodeMigration is returned when a migration Up()/Down() call fails (see
+	// handler.MigrationError)
+	ExitCodeMigration = 4
+
+	// ExitCodeRepository is returned when the executions repository fails (see
+	// handler.RepositoryError)
+	ExitCodeRepository = 5
+)
+
+// LockError is returned when the lock of the exclusive runs is held by another run, or can't be
+// acquired. Its message is the one of the wrapped error.
+type LockError struct {
+	Err error
+}
+
+func (e *LockError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LockError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned for invalid arguments or settings, and for the migrations which
+// fail validation. Its message is the one of the wrapped error.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the category of err: ExitCodeOk if it is nil, the code of
+// the *CheckError of the check command, then, in this order of precedence, ExitCodeLocked,
+// ExitCodeValidation, ExitCodeMigration and ExitCodeRepository, or ExitCodeFailure if it has no
+// category. The errors of the lock, migration and execution packages are categorized too (for
+// example, lock.ErrLockHeld, *migration.CollisionError or *execution.PermissionsError).
+func ExitCode(err error) int {
+	var (
+		lockErr        *LockError
+		validationErr  *ValidationError
+		collisionErr   *migration.CollisionError
+		registryErr    *migration.RegistryError
+		migrationErr   *handler.MigrationError
+		repositoryErr  *handler.RepositoryError
+		permissionsErr *execution.PermissionsError
+		schemaErr      *execution.SchemaTooNewError
+		checkErr       *CheckError
+	)
+
+	switch {
+	case err == nil:
+		return ExitCodeOk
+	case errors.As(err, &checkErr):
+		return checkErr.ExitCode()
+	case errors.As(err, &lockErr), errors.Is(err, lock.ErrLockHeld),
+		errors.Is(err, cli.CommandLocked):
+		return ExitCodeLocked
+	case errors.As(err, &validationErr), errors.As(err, &collisionErr),
+		errors.As(err, &registryErr):
+		return ExitCodeValidation
+	case errors.As(err, &migrationErr):
+		return ExitCodeMigration
+	case errors.As(err, &repositoryErr), errors.As(err, &permissionsErr),
+		errors.As(err, &schemaErr), errors.Is(err, execution.ErrReadOnlyRepository):
+		return ExitCodeRepository
+	default:
+		return ExitCodeFailure
+	}
+}
+
+// categorizedCommand wraps a command to record its error, so the process exits with the code
+// of its category (see ExitCode). The errors of the flags validation are ValidationErrors.
+type categorizedCommand struct {
+	cli.Command
+	err *error
+}
+
+func (c *categorizedCommand) ValidateFlags() error {
+	if err := c.Command.ValidateFlags(); err != nil {
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			err = &ValidationError{Err: err}
+		}
+		*c.err = err
+		return err
+	}
+	return nil
+}
+
+func (c *categorizedCommand) Exec(stdWriter io.Writer) error {
+	*c.err = c.Command.Exec(stdWriter)
+	return *c.err
+}
+
+// categorizedExit returns the process exit function which replaces the generic error code of
+// the command runner with the one of the category of the recorded command error. A failure
+// without a recorded error happened before the command could run (an unknown command or
+// invalid flags), so it is a validation failure.
+func categorizedExit(processExit func(code int), cmdErr *error) func(code int) {
+	return func(code int) {
+		if code == cli.StatusOk {
+			processExit(code)
+			return
+		}
+
+		if *cmdErr == nil {
+			processExit(ExitCodeValidation)
+			return
+		}
+		processExit(ExitCode(*cmdErr))
+	}
+}
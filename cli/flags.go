@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The flags of an invocation, which can be given before or after the command
+const (
+	// waitForDbFlag sets the maximum duration the bootstrap waits for the database (see
+	// BootstrapSettings.WaitForDb)
+	waitForDbFlag = "--wait-for-db"
+
+	// timeoutFlag sets the deadline of the whole invocation (see BootstrapSettings.Timeout)
+	timeoutFlag = "--timeout"
+
+	// lockTimeoutFlag sets the maximum duration the exclusive runs wait for the lock (see
+	// BootstrapSettings.LockTimeout)
+	lockTimeoutFlag = "--lock-timeout"
+
+	// noColorFlag disables the colors of the text output (see BootstrapSettings.NoColor)
+	noColorFlag = "--no-color"
+
+	// readOnlyFlag enables the read-only mode (see BootstrapSettings.ReadOnly)
+	readOnlyFlag = "--read-only"
+)
+
+// boolFlag removes the boolean flag with the given name from the arguments, and returns true if
+// it is given. It can be given before or after the command. The arguments after "--" are kept
+// as they are.
+func boolFlag(args []string, name string) ([]string, bool) {
+	given := false
+	remaining := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" && i > 0 {
+			remaining = append(remaining, args[i:]...)
+			break
+		}
+		if arg == name {
+			given = true
+			continue
+		}
+		remaining = append(remaining, arg)
+	}
+	return remaining, given
+}
+
+// durationFlag removes the duration flag with the given name from the arguments, and returns
+// the duration it sets (-1 if it is not given). It can be given before or after the command.
+// The arguments after "--" are kept as they are.
+func durationFlag(args []string, name string) ([]string, time.Duration, error) {
+	duration := time.Duration(-1)
+	remaining := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" && i > 0 {
+			remaining = append(remaining, args[i:]...)
+			break
+		}
+
+		value, ok := strings.CutPrefix(arg, name+"=")
+		if !ok && arg == name && i+1 < len(args) {
+			i++
+			value, ok = args[i], true
+		}
+		if !ok {
+			remaining = append(remaining, arg)
+			continue
+		}
+
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, 0, fmt.Errorf(
+				"invalid %s value %q, expected a duration (for example, 30s)", name, value,
+			)
+		}
+		duration = parsed
+	}
+	return remaining, duration, nil
+}
@@ -0,0 +1,486 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// Names of the built-in output formats
+const (
+	FormatText  = "text"
+	FormatJson  = "json"
+	FormatTable = "table"
+	FormatQuiet = "quiet"
+)
+
+// MigrationReport describes a migration handled (or inspected) by a command
+type MigrationReport struct {
+	Version      uint64              `json:"version"`
+	File         string              `json:"file"`
+	Metadata     *migration.Metadata `json:"metadata,omitempty"`
+	RowsAffected *int64              `json:"rowsAffected,omitempty"`
+
+	// Fixes is the version reversed by the migration, if it is a hotfix (see migration.Hotfix)
+	Fixes *uint64 `json:"fixes,omitempty"`
+}
+
+// RunReport is the result of a command which runs migrations (up, down, force:up, force:down)
+type RunReport struct {
+	// Command is the id of the command which produced the report
+	Command string `json:"command"`
+
+	// Direction is "up" or "down"
+	Direction string `json:"direction"`
+
+	// Forced is true for force:up and force:down
+	Forced bool `json:"forced"`
+
+	// DryRun is true if nothing was executed: Migrations holds the migrations which would be
+	// executed, in order (see the --dry-run flag of the up and down commands)
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// RunId is the ID of the run (see execution.NewRunId), to cross-reference it with the
+	// saved executions and the audit records
+	RunId string `json:"runId,omitempty"`
+
+	// Migrations holds the handled migrations which have an execution (for Up(), the last
+	// one may be unfinished if it failed)
+	Migrations []MigrationReport `json:"migrations"`
+
+	// TotalRowsAffected is nil if none of the migrations recorded affected rows
+	TotalRowsAffected *int64 `json:"totalRowsAffected,omitempty"`
+
+	// Remaining holds the migrations not executed because the run stopped at its time
+	// budget (see the --max-duration flag of the up command), or before a migration deferred
+	// by the gate (see BootstrapSettings.Gate)
+	Remaining []MigrationReport `json:"remaining,omitempty"`
+
+	// Deferred is true if the run stopped before the first of the Remaining migrations, because
+	// the gate deferred it
+	Deferred bool `json:"deferred,omitempty"`
+
+	// Skipped holds the earlier migrations, not executed yet, skipped by a run executing a
+	// migration out of order (see the --only flag of the up command)
+	Skipped []MigrationReport `json:"skipped,omitempty"`
+}
+
+// StatsReport is the result of the stats command
+type StatsReport struct {
+	RegisteredCount int              `json:"registeredCount"`
+	ExecutionsCount int              `json:"executionsCount"`
+	NextToExecute   *MigrationReport `json:"nextToExecute"`
+	LastExecuted    *MigrationReport `json:"lastExecuted"`
+}
+
+// Formatter renders the results of the CLI commands. Implement it to add new output modes
+// (for example, TAP for CI) and register it in BootstrapSettings.Formatters.
+type Formatter interface {
+	// FormatRun renders the result of a command which runs migrations
+	FormatRun(w io.Writer, report RunReport) error
+
+	// FormatStats renders the result of the stats command
+	FormatStats(w io.Writer, report StatsReport) error
+
+	// FormatMessage renders a simple informative message (for example, the name of
+	// a generated file)
+	FormatMessage(w io.Writer, message string) error
+}
+
+// BuiltinFormatters returns the formatters available out of the box, indexed by name
+func BuiltinFormatters() map[string]Formatter {
+	return map[string]Formatter{
+		FormatText:  &TextFormatter{},
+		FormatJson:  &JsonFormatter{},
+		FormatTable: &TableFormatter{},
+		FormatQuiet: &QuietFormatter{},
+	}
+}
+
+// newMigrationReport builds the report of a migration, with the affected rows recorded
+// during its execution, if any
+func newMigrationReport(mig migration.Migration, rowsAffected *int64) MigrationReport {
+	report := MigrationReport{
+		Version:      mig.Version(),
+		File:         migration.FileNameOf(mig),
+		RowsAffected: rowsAffected,
+	}
+
+	if metadata, ok := migration.MetadataOf(mig); ok && !metadata.IsEmpty() {
+		report.Metadata = &metadata
+	}
+
+	if fixed, ok := migration.FixedVersionOf(mig); ok {
+		report.Fixes = &fixed
+	}
+
+	return report
+}
+
+// newRunReport builds the report of the migrations handled by a run command. Only the
+// migrations which have an execution are included.
+func newRunReport(
+	cmdId string,
+	direction string,
+	forced bool,
+	runId string,
+	execs []handler.ExecutedMigration,
+) RunReport {
+	report := RunReport{
+		Command:    cmdId,
+		Direction:  direction,
+		Forced:     forced,
+		RunId:      runId,
+		Migrations: []MigrationReport{},
+	}
+
+	for _, execMig := range execs {
+		if execMig.Execution != nil {
+			report.Migrations = append(
+				report.Migrations, newMigrationReport(execMig.Migration, execMig.RowsAffected),
+			)
+		}
+	}
+
+	if total, recorded := handler.TotalRowsAffected(execs); recorded {
+		report.TotalRowsAffected = &total
+	}
+
+	return report
+}
+
+// newDryRunReport builds the report of the migrations a run command would handle, in order
+func newDryRunReport(cmdId string, direction string, planned []migration.Migration) RunReport {
+	report := RunReport{
+		Command:    cmdId,
+		Direction:  direction,
+		DryRun:     true,
+		Migrations: []MigrationReport{},
+	}
+
+	for _, mig := range planned {
+		report.Migrations = append(report.Migrations, newMigrationReport(mig, nil))
+	}
+
+	return report
+}
+
+// outputFlags handles the --format flag, shared by all the commands which produce output
+type outputFlags struct {
+	format     string
+	formatters map[string]Formatter
+	formatter  Formatter
+}
+
+// newOutputFlags builds the output flags with the built-in formatters and the extra ones,
+// with the colored TextFormatter if color is true. Extra formatters can override the built-in
+// ones.
+func newOutputFlags(defaultFormat string, extra map[string]Formatter, color bool) outputFlags {
+	formatters := BuiltinFormatters()
+	if color {
+		formatters[FormatText] = &TextFormatter{Color: true}
+	}
+	for name, formatter := range extra {
+		formatters[name] = formatter
+	}
+
+	if defaultFormat == "" {
+		defaultFormat = FormatText
+	}
+
+	return outputFlags{format: defaultFormat, formatters: formatters}
+}
+
+func (o *outputFlags) DefineFlags(flagSet *flag.FlagSet) {
+	if o.format == "" {
+		o.format = FormatText
+	}
+
+	names := make([]string, 0, len(o.formatters))
+	for name := range o.formatters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	flagSet.StringVar(
+		&o.format,
+		"format",
+		o.format,
+		"Output format. Available formats: "+strings.Join(names, ", "),
+	)
+}
+
+func (o *outputFlags) ValidateFlags() error {
+	formatters := o.formatters
+	if formatters == nil {
+		formatters = BuiltinFormatters()
+	}
+
+	formatter, ok := formatters[o.format]
+	if !ok {
+		return fmt.Errorf("unknown output format %s", o.format)
+	}
+
+	o.formatter = formatter
+	return nil
+}
+
+// output returns the selected formatter, defaulting to TextFormatter
+func (o *outputFlags) output() Formatter {
+	if o.formatter == nil {
+		return &TextFormatter{}
+	}
+	return o.formatter
+}
+
+// The ANSI escape sequences of the colors of the TextFormatter
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+)
+
+// TextFormatter renders human-readable, line based output. It is the default formatter.
+type TextFormatter struct {
+	// Color enables the colors of the output: green for the applied migrations, yellow for the
+	// pending ones and red for the failures. Bootstrap enables it when the output is a
+	// terminal, unless the colors are disabled (see BootstrapSettings.NoColor).
+	Color bool
+}
+
+// paint colors the text, if the colors are enabled
+func (f *TextFormatter) paint(color string, text string) string {
+	if !f.Color || text == "" {
+		return text
+	}
+	return color + text + colorReset
+}
+
+func (f *TextFormatter) FormatRun(w io.Writer, report RunReport) error {
+	action := "Up()"
+	if report.Direction == "down" {
+		action = "Down()"
+	}
+
+	if len(report.Skipped) > 0 {
+		_, _ = fmt.Fprintf(
+			w, "%s, %d earlier migrations are skipped:\n",
+			f.paint(colorYellow, "Warning: executing out of order"), len(report.Skipped),
+		)
+		for _, mig := range report.Skipped {
+			_, _ = fmt.Fprintf(w, "%s\n", f.paint(colorYellow, "Skipped "+migrationLabel(&mig)))
+		}
+	}
+
+	if report.DryRun {
+		_, err := fmt.Fprintf(
+			w, "Dry run, would execute %s for %d migrations\n", action, len(report.Migrations),
+		)
+		for _, mig := range report.Migrations {
+			label := "Would execute " + action + " for " + migrationLabel(&mig)
+			_, err = fmt.Fprintf(w, "%s\n", f.paint(colorYellow, label))
+		}
+		return err
+	}
+
+	if report.Forced {
+		if len(report.Migrations) == 0 {
+			_, err := fmt.Fprintf(w, "No forced %s migration executed\n", action)
+			return err
+		}
+
+		for _, mig := range report.Migrations {
+			_, _ = fmt.Fprintf(
+				w, "%s%s\n",
+				f.paint(
+					colorGreen,
+					fmt.Sprintf("Executed %s forcefully for %d migration", action, mig.Version),
+				),
+				rowsAffectedSuffix(mig.RowsAffected),
+			)
+		}
+		return printRunId(w, report.RunId)
+	}
+
+	_, _ = fmt.Fprintf(w, "Executed %s for %d migrations\n", action, len(report.Migrations))
+	for _, mig := range report.Migrations {
+		_, _ = fmt.Fprintf(
+			w, "%s%s\n",
+			f.paint(colorGreen, fmt.Sprintf("Executed %s for %d migration", action, mig.Version)),
+			rowsAffectedSuffix(mig.RowsAffected),
+		)
+	}
+
+	if report.TotalRowsAffected != nil {
+		_, _ = fmt.Fprintf(w, "Total rows affected: %d\n", *report.TotalRowsAffected)
+	}
+
+	if len(report.Remaining) > 0 {
+		stop := "Stopped at the time budget"
+		if report.Deferred {
+			stop = fmt.Sprintf("Migration %d deferred by the gate", report.Remaining[0].Version)
+		}
+		_, _ = fmt.Fprintf(w, "%s, %d migrations remaining:\n", stop, len(report.Remaining))
+		for _, mig := range report.Remaining {
+			_, _ = fmt.Fprintf(w, "%s\n", f.paint(colorYellow, "Remaining "+migrationLabel(&mig)))
+		}
+	}
+
+	return printRunId(w, report.RunId)
+}
+
+func (f *TextFormatter) FormatStats(w io.Writer, report StatsReport) error {
+	_, _ = fmt.Fprintln(w, "")
+	_, _ = fmt.Fprintf(w, "Registered migrations count: %d\n", report.RegisteredCount)
+	_, _ = fmt.Fprintf(w, "Executions count: %d\n", report.ExecutionsCount)
+	_, _ = fmt.Fprintf(
+		w, "Next to execute migration file: %s\n", migrationLabel(report.NextToExecute),
+	)
+	_, err := fmt.Fprintf(
+		w, "Last executed migration file: %s\n", migrationLabel(report.LastExecuted),
+	)
+	return err
+}
+
+func (f *TextFormatter) FormatMessage(w io.Writer, message string) error {
+	_, err := fmt.Fprintf(w, "\n%s\n\n", message)
+	return err
+}
+
+// JsonFormatter renders each result as a JSON document, for machine consumption
+type JsonFormatter struct{}
+
+func (f *JsonFormatter) FormatRun(w io.Writer, report RunReport) error {
+	return json.NewEncoder(w).Encode(report)
+}
+
+func (f *JsonFormatter) FormatStats(w io.Writer, report StatsReport) error {
+	return json.NewEncoder(w).Encode(report)
+}
+
+func (f *JsonFormatter) FormatMessage(w io.Writer, message string) error {
+	return json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// TableFormatter renders results as aligned columns
+type TableFormatter struct{}
+
+func (f *TableFormatter) FormatRun(w io.Writer, report RunReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "VERSION\tFILE\tDIRECTION\tROWS AFFECTED\tDESCRIPTION")
+	direction := report.Direction
+	if report.DryRun {
+		direction += " (dry run)"
+	}
+	for _, mig := range report.Migrations {
+		_, _ = fmt.Fprintf(
+			tw, "%d\t%s\t%s\t%s\t%s\n",
+			mig.Version, mig.File, direction, rowsAffectedCell(mig.RowsAffected),
+			metadataCell(mig.Metadata),
+		)
+	}
+	if report.TotalRowsAffected != nil {
+		_, _ = fmt.Fprintf(tw, "TOTAL\t\t\t%d\t\n", *report.TotalRowsAffected)
+	}
+	for _, mig := range report.Skipped {
+		_, _ = fmt.Fprintf(
+			tw, "%d\t%s\tskipped\t\t%s\n", mig.Version, mig.File, metadataCell(mig.Metadata),
+		)
+	}
+	for i, mig := range report.Remaining {
+		status := "remaining"
+		if i == 0 && report.Deferred {
+			status = "deferred"
+		}
+		_, _ = fmt.Fprintf(
+			tw, "%d\t%s\t%s\t\t%s\n", mig.Version, mig.File, status, metadataCell(mig.Metadata),
+		)
+	}
+	if report.RunId != "" {
+		_, _ = fmt.Fprintf(tw, "RUN ID\t%s\t\t\t\n", report.RunId)
+	}
+	return tw.Flush()
+}
+
+func (f *TableFormatter) FormatStats(w io.Writer, report StatsReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Registered migrations\t%d\n", report.RegisteredCount)
+	_, _ = fmt.Fprintf(tw, "Executions\t%d\n", report.ExecutionsCount)
+	_, _ = fmt.Fprintf(tw, "Next to execute\t%s\n", migrationLabel(report.NextToExecute))
+	_, _ = fmt.Fprintf(tw, "Last executed\t%s\n", migrationLabel(report.LastExecuted))
+	return tw.Flush()
+}
+
+func (f *TableFormatter) FormatMessage(w io.Writer, message string) error {
+	_, err := fmt.Fprintln(w, message)
+	return err
+}
+
+// QuietFormatter renders nothing. Errors are still reported by the CLI.
+type QuietFormatter struct{}
+
+func (f *QuietFormatter) FormatRun(io.Writer, RunReport) error { return nil }
+
+func (f *QuietFormatter) FormatStats(io.Writer, StatsReport) error { return nil }
+
+func (f *QuietFormatter) FormatMessage(io.Writer, string) error { return nil }
+
+// migrationLabel builds the file name of the migration, followed by its metadata summary
+func migrationLabel(mig *MigrationReport) string {
+	if mig == nil {
+		return "N/A"
+	}
+
+	label := mig.File
+	if mig.Fixes != nil {
+		label += fmt.Sprintf(" (hotfix of %d)", *mig.Fixes)
+	}
+
+	if mig.Metadata == nil {
+		return label
+	}
+
+	return label + " [" + mig.Metadata.String() + "]"
+}
+
+// printRunId prints the run ID line of the text output, if the run ID is known
+func printRunId(w io.Writer, runId string) error {
+	if runId == "" {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "Run ID: %s\n", runId)
+	return err
+}
+
+// rowsAffectedSuffix builds the affected rows details shown after an executed migration.
+// It is empty if the migration did not record affected rows.
+func rowsAffectedSuffix(rowsAffected *int64) string {
+	if rowsAffected == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (%d rows affected)", *rowsAffected)
+}
+
+func rowsAffectedCell(rowsAffected *int64) string {
+	if rowsAffected == nil {
+		return "-"
+	}
+	return strconv.FormatInt(*rowsAffected, 10)
+}
+
+func metadataCell(metadata *migration.Metadata) string {
+	if metadata == nil {
+		return ""
+	}
+	return metadata.String()
+}
@@ -0,0 +1,304 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type FormatTestSuite struct {
+	suite.Suite
+}
+
+func TestFormatTestSuite(t *testing.T) {
+	suite.Run(t, new(FormatTestSuite))
+}
+
+func (suite *FormatTestSuite) bootstrap(settings *BootstrapSettings, args ...string) string {
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(&describedMigration{*migration.NewDummyMigration(1)})
+	_ = registry.Register(migration.NewDummyMigration(2))
+
+	var buf bytes.Buffer
+	Bootstrap(
+		context.Background(), nil,
+		args,
+		registry,
+		&execution.InMemoryRepository{},
+		migPath,
+		nil,
+		&buf,
+		func(code int) {},
+		settings,
+	)
+	return buf.String()
+}
+
+func (suite *FormatTestSuite) TestItRendersRunsAsJson() {
+	output := suite.bootstrap(nil, "up", "--steps=all", "--format=json")
+
+	var report RunReport
+	suite.Require().NoError(json.Unmarshal([]byte(output), &report))
+	suite.Assert().Equal("up", report.Command)
+	suite.Assert().Equal("up", report.Direction)
+	suite.Assert().False(report.Forced)
+	suite.Assert().Len(report.RunId, 26)
+	suite.Require().Len(report.Migrations, 2)
+	suite.Assert().Equal("version_1.go", report.Migrations[0].File)
+	suite.Assert().Equal("JIRA-1", report.Migrations[0].Metadata.Ticket)
+	suite.Assert().Nil(report.Migrations[1].Metadata)
+}
+
+func (suite *FormatTestSuite) TestItRendersStatsAsJson() {
+	output := suite.bootstrap(nil, "stats", "--format=json")
+
+	var report StatsReport
+	suite.Require().NoError(json.Unmarshal([]byte(output), &report))
+	suite.Assert().Equal(2, report.RegisteredCount)
+	suite.Assert().Equal(0, report.ExecutionsCount)
+	suite.Assert().Equal(uint64(1), report.NextToExecute.Version)
+	suite.Assert().Nil(report.LastExecuted)
+}
+
+func (suite *FormatTestSuite) TestItRendersRunsAsTable() {
+	output := suite.bootstrap(nil, "up", "--steps=all", "--format=table")
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+
+	suite.Require().Len(lines, 4)
+	suite.Assert().Regexp(`^VERSION\s+FILE\s+DIRECTION\s+ROWS AFFECTED\s+DESCRIPTION$`, lines[0])
+	suite.Assert().Regexp(
+		`^1\s+version_1.go\s+up\s+-\s+add users phone index; ticket: JIRA-1$`, lines[1],
+	)
+	suite.Assert().Regexp(`^2\s+version_2.go\s+up\s+-$`, strings.TrimSpace(lines[2]))
+	suite.Assert().Regexp(`^RUN ID\s+\w{26}$`, strings.TrimSpace(lines[3]))
+}
+
+func (suite *FormatTestSuite) TestItRendersNothingInQuietMode() {
+	suite.Assert().Empty(suite.bootstrap(nil, "up", "--format=quiet"))
+}
+
+func (suite *FormatTestSuite) TestItUsesTheConfiguredDefaultAndExtraFormatters() {
+	settings := &BootstrapSettings{
+		DefaultFormat: "tap",
+		Formatters:    map[string]Formatter{"tap": &tapFormatter{}},
+	}
+
+	suite.Assert().Equal(
+		"ok 1 - version_1.go\nok 2 - version_2.go\n", suite.bootstrap(settings, "up", "--steps=all"),
+	)
+	suite.Assert().Contains(
+		suite.bootstrap(settings, "down", "--format=text"), "Executed Down() for 0 migrations",
+	)
+}
+
+func (suite *FormatTestSuite) TestItFailsOnUnknownFormat() {
+	suite.Assert().Contains(
+		suite.bootstrap(nil, "stats", "--format=xml"), "unknown output format xml",
+	)
+}
+
+type tapFormatter struct {
+	TextFormatter
+}
+
+func (f *tapFormatter) FormatRun(w io.Writer, report RunReport) error {
+	for i, mig := range report.Migrations {
+		_, _ = io.WriteString(w, "ok "+string(rune('1'+i))+" - "+mig.File+"\n")
+	}
+	return nil
+}
+
+func (suite *FormatTestSuite) TestItRendersTheRemainingMigrationsOfTimeBoxedRuns() {
+	output := suite.bootstrap(nil, "up", "--steps=all", "--max-duration=1ns")
+	suite.Assert().Contains(output, "Executed Up() for 0 migrations\n")
+	suite.Assert().Contains(
+		output, "Stopped at the time budget, 2 migrations remaining:\n"+
+			"Remaining version_1.go [add users phone index; ticket: JIRA-1]\n"+
+			"Remaining version_2.go\n",
+	)
+
+	output = suite.bootstrap(nil, "up", "--steps=all", "--max-duration=1ns", "--format=json")
+	var report RunReport
+	suite.Require().NoError(json.Unmarshal([]byte(output), &report))
+	suite.Assert().Empty(report.Migrations)
+	suite.Require().Len(report.Remaining, 2)
+	suite.Assert().Equal(uint64(2), report.Remaining[1].Version)
+
+	output = suite.bootstrap(nil, "up", "--steps=all", "--max-duration=1h")
+	suite.Assert().Contains(output, "Executed Up() for 2 migrations\n")
+	suite.Assert().NotContains(output, "Remaining")
+
+	suite.Assert().Contains(
+		suite.bootstrap(nil, "up", "--max-duration=-1m"),
+		"the max duration of the run must not be negative",
+	)
+}
+
+func (suite *FormatTestSuite) TestItRendersTheMigrationsDeferredByTheGate() {
+	decision := handler.GateDefer
+	settings := &BootstrapSettings{
+		Gate: func(_ context.Context, version uint64, _ migration.Metadata) (
+			handler.GateDecision,
+			error,
+		) {
+			if version == 2 {
+				return decision, nil
+			}
+			return handler.GateAllow, nil
+		},
+	}
+
+	output := suite.bootstrap(settings, "up", "--steps=all")
+	suite.Assert().Contains(output, "Executed Up() for 1 migrations\n")
+	suite.Assert().Contains(
+		output, "Migration 2 deferred by the gate, 1 migrations remaining:\n"+
+			"Remaining version_2.go\n",
+	)
+	suite.Assert().NotContains(output, "Failed to execute command")
+
+	var report RunReport
+	output = suite.bootstrap(settings, "up", "--steps=all", "--format=json")
+	suite.Require().NoError(json.Unmarshal([]byte(output), &report))
+	suite.Assert().True(report.Deferred)
+	suite.Require().Len(report.Remaining, 1)
+
+	decision = handler.GateDeny
+	suite.Assert().Contains(
+		suite.bootstrap(settings, "up", "--steps=all"),
+		"Failed to execute command up with error: migration 2 was denied by the gate",
+	)
+}
+
+func (suite *FormatTestSuite) TestItRendersTheVersionsFixedByTheHotfixes() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(
+		migration.NewHotfix(2, 1, func(context.Context, any) error { return nil }),
+	)
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+
+	bootstrap := bootstrapRun{registry: registry, migPath: migPath}
+
+	suite.Assert().Contains(bootstrap.output("status"), "  version_2.go (hotfix of 1)\n")
+	suite.Assert().Contains(
+		bootstrap.output("up", "--steps=all", "--format=json"),
+		`{"version":2,"file":"version_2.go","fixes":1}`,
+	)
+}
+
+// plainFormatter only has the methods of the Formatter interface
+type plainFormatter struct {
+	Formatter
+}
+
+func (suite *FormatTestSuite) TestItRendersTheStatusOfTheMigrations() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(&describedMigration{*migration.NewDummyMigration(1)})
+	_ = registry.Register(migration.NewDummyMigration(2))
+	_ = registry.Register(migration.NewDummyMigration(3))
+	repo := &execution.InMemoryRepository{}
+	repo.SaveAll(
+		[]execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+			{Version: 2, ExecutedAtMs: 3},
+			{Version: 9, ExecutedAtMs: 4, FinishedAtMs: 5},
+		},
+	)
+	migPath, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	bootstrap := bootstrapRun{registry: registry, repo: repo, migPath: migPath}
+
+	suite.Assert().Equal(
+		"Applied migrations: 1\n"+
+			"  version_1.go [add users phone index; ticket: JIRA-1]\n"+
+			"Pending migrations: 2\n"+
+			"  version_2.go\n"+
+			"  version_3.go\n"+
+			"Unknown executed versions: 1\n"+
+			"  9\n",
+		bootstrap.output("status"),
+	)
+
+	var report StatusReport
+	suite.Require().NoError(json.Unmarshal([]byte(bootstrap.output("status", "--json")), &report))
+	suite.Require().Len(report.Applied, 1)
+	suite.Assert().Equal("JIRA-1", report.Applied[0].Metadata.Ticket)
+	suite.Require().Len(report.Pending, 2)
+	suite.Assert().Equal(uint64(2), report.Pending[0].Version)
+	suite.Assert().Equal([]uint64{2}, report.Failed)
+	suite.Assert().Equal([]uint64{9}, report.Unknown)
+
+	lines := strings.Split(strings.TrimSpace(bootstrap.output("status", "--format=table")), "\n")
+	suite.Require().Len(lines, 5)
+	suite.Assert().Regexp(`^3\s+version_3.go\s+pending$`, strings.TrimSpace(lines[3]))
+	suite.Assert().Regexp(`^9\s+unknown$`, strings.TrimSpace(lines[4]))
+
+	// formatters without status support fall back to the text output
+	settings := &BootstrapSettings{
+		Formatters: map[string]Formatter{"plain": &plainFormatter{&JsonFormatter{}}},
+	}
+	var buf bytes.Buffer
+	Bootstrap(
+		context.Background(), nil, []string{"status", "--format=plain"}, registry, repo,
+		migPath, nil, &buf, func(code int) {}, settings,
+	)
+	suite.Assert().Contains(buf.String(), "Unknown executed versions: 1\n")
+}
+
+func (suite *FormatTestSuite) TestItColorsTheTextOutput() {
+	report := StatusReport{
+		Applied: []MigrationReport{{Version: 1, File: "version_1.go"}},
+		Pending: []MigrationReport{
+			{Version: 2, File: "version_2.go"}, {Version: 3, File: "version_3.go"},
+		},
+		Failed:  []uint64{2},
+		Unknown: []uint64{9},
+	}
+	var buf bytes.Buffer
+	suite.Require().NoError((&TextFormatter{Color: true}).FormatStatus(&buf, report))
+	suite.Assert().Equal(
+		"Applied migrations: 1\n"+
+			"  \x1b[32mversion_1.go\x1b[0m\n"+
+			"Pending migrations: 2\n"+
+			"  \x1b[31mversion_2.go\x1b[0m\n"+
+			"  \x1b[33mversion_3.go\x1b[0m\n"+
+			"Unknown executed versions: 1\n"+
+			"  \x1b[31m9\x1b[0m\n",
+		buf.String(),
+	)
+
+	buf.Reset()
+	rows := int64(3)
+	run := RunReport{
+		Direction: "up", Migrations: []MigrationReport{{Version: 1, RowsAffected: &rows}},
+	}
+	suite.Require().NoError((&TextFormatter{Color: true}).FormatRun(&buf, run))
+	suite.Assert().Contains(buf.String(), "\x1b[32mExecuted Up() for 1 migration\x1b[0m (3 rows")
+
+	buf.Reset()
+	writer := &failureColorWriter{&buf}
+	_, _ = writer.Write([]byte("Executed Up() for 0 migrations\n"))
+	_, _ = writer.Write([]byte("Failed to execute command up with error: boom\n"))
+	suite.Assert().Equal(
+		"Executed Up() for 0 migrations\n"+
+			"\x1b[31mFailed to execute command up with error: boom\x1b[0m\n",
+		buf.String(),
+	)
+
+	// the colors are only enabled on a terminal, and never with NO_COLOR or --no-color
+	suite.Assert().False(colorEnabled(&buf, false))
+	suite.T().Setenv(NoColorEnvVar, "1")
+	suite.Assert().False(colorEnabled(os.Stdout, false))
+	output := suite.bootstrap(nil, "status", "--no-color")
+	suite.Assert().Contains(output, "Pending migrations: 2\n  version_1.go [")
+	suite.Assert().NotContains(output, "\x1b[")
+}
@@ -0,0 +1,357 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/history"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// HistoryVerifyCommand implements the Command interface to check that the hash-chained
+// history was not tampered with and that the executions state matches it
+type HistoryVerifyCommand struct {
+	outputFlags
+	store      history.Store
+	repository execution.Repository
+	ctx        context.Context
+}
+
+func (c *HistoryVerifyCommand) Id() string {
+	return "history:verify"
+}
+
+func (c *HistoryVerifyCommand) Description() string {
+	return "Verifies the history chain and that the executions match it, " +
+		"detecting changes made outside the migrations runs.\nExamples: migrate history:verify"
+}
+
+func (c *HistoryVerifyCommand) Exec(stdWriter io.Writer) error {
+	records, err := c.store.Load(c.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load history with error: %w", err)
+	}
+
+	if err = history.Verify(records); err != nil {
+		return err
+	}
+
+	executions, err := c.repository.LoadExecutions()
+	if err != nil {
+		return fmt.Errorf("failed to load executions with error: %w", err)
+	}
+
+	if err = history.VerifyExecutions(records, executions); err != nil {
+		return err
+	}
+
+	return c.output().FormatMessage(
+		stdWriter, fmt.Sprintf("History verified: %d records, no tampering detected", len(records)),
+	)
+}
+
+// flushingExit returns the process exit function which flushes the buffered history records
+// first. If the flush fails, the error is written to the output and a successful exit becomes
+// a repository failure, since the history misses the records of the run.
+func flushingExit(
+	ctx context.Context,
+	store *history.BufferedStore,
+	w io.Writer,
+	processExit func(code int),
+) func(code int) {
+	return func(code int) {
+		// the records are flushed even if the run was interrupted
+		if err := store.Flush(context.WithoutCancel(ctx)); err != nil {
+			_, _ = fmt.Fprintf(w, "History flush failed: %s\n", err)
+			if code == ExitCodeOk {
+				code = ExitCodeRepository
+			}
+		}
+		processExit(code)
+	}
+}
+
+// The orders of the history command (see the --sort flag)
+const (
+	historySortVersion  = "version"
+	historySortExecuted = "executed"
+	historySortFinished = "finished"
+	historySortDuration = "duration"
+)
+
+var historySorts = []string{
+	historySortVersion, historySortExecuted, historySortFinished, historySortDuration,
+}
+
+// ExecutionReport describes a recorded execution, listed by the history command
+type ExecutionReport struct {
+	Version    uint64     `json:"version"`
+	ExecutedAt time.Time  `json:"executedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+
+	// DurationMs is nil if the execution is not finished
+	DurationMs *int64 `json:"durationMs,omitempty"`
+	RunId      string `json:"runId,omitempty"`
+
+	// Archived is true if the execution was moved to the executions archive (see the archive
+	// command)
+	Archived bool `json:"archived,omitempty"`
+
+	// Description is the description of the registered migration (see
+	// migration.DescriptionOf), empty if it has none or if it is not registered
+	Description string `json:"description,omitempty"`
+}
+
+// Duration returns the duration of the execution, zero if it is not finished
+func (r ExecutionReport) Duration() time.Duration {
+	if r.DurationMs == nil {
+		return 0
+	}
+	return time.Duration(*r.DurationMs) * time.Millisecond
+}
+
+// HistoryFormatter is an optional interface for the formatters which render the result of the
+// history command. The output of the formatters which don't implement it is rendered by the
+// TextFormatter.
+type HistoryFormatter interface {
+	FormatHistory(w io.Writer, executions []ExecutionReport) error
+}
+
+// newExecutionReport builds the report of a recorded execution
+func newExecutionReport(exec execution.MigrationExecution) ExecutionReport {
+	report := ExecutionReport{
+		Version:    exec.Version,
+		ExecutedAt: time.UnixMilli(int64(exec.ExecutedAtMs)).UTC(),
+		RunId:      exec.RunId,
+	}
+	if exec.Finished() {
+		finishedAt := time.UnixMilli(int64(exec.FinishedAtMs)).UTC()
+		duration := int64(exec.FinishedAtMs) - int64(exec.ExecutedAtMs)
+		report.FinishedAt, report.DurationMs = &finishedAt, &duration
+	}
+	return report
+}
+
+// HistoryCommand implements the Command interface to list the recorded executions: each
+// executed version with its start and finish times and its duration, filtered by the time
+// they were executed at and sorted by version, start or finish time, or duration. The archived
+// executions are only listed with the --archived flag.
+type HistoryCommand struct {
+	outputFlags
+	sort       string
+	reverse    bool
+	archived   bool
+	rawSince   string
+	rawUntil   string
+	since      time.Time
+	until      time.Time
+	registry   migration.MigrationsRegistry
+	repository execution.Repository
+}
+
+func (c *HistoryCommand) Id() string {
+	return "history"
+}
+
+func (c *HistoryCommand) Description() string {
+	return "Lists the recorded executions, with their start and finish times and durations.\n" +
+		"Examples: migrate history, migrate history --since=2024-01-01 --format=table, " +
+		"migrate history --sort=duration --reverse --format=json"
+}
+
+func (c *HistoryCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.StringVar(
+		&c.sort,
+		"sort",
+		historySortVersion,
+		`Order of the executions (`+strings.Join(historySorts, ", ")+`). The unfinished
+		executions are listed last when sorted by finish time or duration.
+		Examples: migrate history --sort=executed`,
+	)
+	flagSet.BoolVar(&c.reverse, "reverse", false, "Reverse the order of the executions")
+	flagSet.BoolVar(
+		&c.archived, "archived", false, "Also list the executions moved to the executions archive",
+	)
+	flagSet.StringVar(
+		&c.rawSince,
+		"since",
+		"",
+		`Only list the executions started at or after the given time (RFC 3339 or a date).
+		Examples: migrate history --since=2024-01-01, migrate history --since=2024-01-01T12:00:00Z`,
+	)
+	flagSet.StringVar(
+		&c.rawUntil,
+		"until",
+		"",
+		`Only list the executions started before the given time (RFC 3339 or a date).
+		Examples: migrate history --until=2024-02-01`,
+	)
+}
+
+func (c *HistoryCommand) ValidateFlags() error {
+	if !slices.Contains(historySorts, c.sort) {
+		return fmt.Errorf(
+			"unknown order %q, expected one of: %s", c.sort, strings.Join(historySorts, ", "),
+		)
+	}
+
+	var err error
+	if c.since, err = parseTimeFlag("since", c.rawSince); err != nil {
+		return err
+	}
+	if c.until, err = parseTimeFlag("until", c.rawUntil); err != nil {
+		return err
+	}
+	if !c.since.IsZero() && !c.until.IsZero() && !c.since.Before(c.until) {
+		return errors.New("the --since time must be before the --until time")
+	}
+	return c.outputFlags.ValidateFlags()
+}
+
+// parseTimeFlag parses the value of a time flag, an RFC 3339 time or a date (midnight UTC).
+// Returns the zero time for an empty value.
+func parseTimeFlag(name string, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf(
+		"invalid --%s value %q, expected an RFC 3339 time or a date (for example, 2024-01-01)",
+		name, value,
+	)
+}
+
+func (c *HistoryCommand) Exec(stdWriter io.Writer) error {
+	executions, archived, err := loadExecutions(c.repository)
+	if err != nil {
+		return fmt.Errorf("failed to load executions with error: %w", err)
+	}
+
+	reports := []ExecutionReport{}
+	for _, exec := range executions {
+		report := newExecutionReport(exec)
+		report.Archived = archived[exec.Version]
+		if mig := c.registry.Get(exec.Version); mig != nil {
+			report.Description = migration.DescriptionOf(mig)
+		}
+		if (report.Archived && !c.archived) || (!c.since.IsZero() && report.ExecutedAt.Before(c.since)) ||
+			(!c.until.IsZero() && !report.ExecutedAt.Before(c.until)) {
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	slices.SortStableFunc(reports, c.compare)
+	if c.reverse {
+		slices.Reverse(reports)
+	}
+
+	if formatter, ok := c.output().(HistoryFormatter); ok {
+		return formatter.FormatHistory(stdWriter, reports)
+	}
+	return (&TextFormatter{}).FormatHistory(stdWriter, reports)
+}
+
+// compare orders the executions by the --sort flag, then by version
+func (c *HistoryCommand) compare(a ExecutionReport, b ExecutionReport) int {
+	var order int
+	switch c.sort {
+	case historySortExecuted:
+		order = a.ExecutedAt.Compare(b.ExecutedAt)
+	case historySortFinished:
+		order = compareUnfinishedLast(a.FinishedAt, b.FinishedAt, func(a, b *time.Time) int {
+			return a.Compare(*b)
+		})
+	case historySortDuration:
+		order = compareUnfinishedLast(a.DurationMs, b.DurationMs, func(a, b *int64) int {
+			return cmp.Compare(*a, *b)
+		})
+	}
+	return cmp.Or(order, cmp.Compare(a.Version, b.Version))
+}
+
+// compareUnfinishedLast compares the values of two executions, the missing values (of the
+// unfinished executions) last
+func compareUnfinishedLast[T any](a *T, b *T, compare func(a *T, b *T) int) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return compare(a, b)
+}
+
+func (f *TextFormatter) FormatHistory(w io.Writer, executions []ExecutionReport) error {
+	_, err := fmt.Fprintf(w, "Executions: %d\n", len(executions))
+	for _, exec := range executions {
+		archived := ""
+		if exec.Archived {
+			archived = ", archived"
+		}
+		described := ""
+		if exec.Description != "" {
+			described = " (" + exec.Description + ")"
+		}
+
+		if exec.FinishedAt == nil {
+			_, err = fmt.Fprintf(
+				w, "  %s\n",
+				f.paint(
+					colorRed,
+					fmt.Sprintf(
+						"%d%s executed at %s, not finished",
+						exec.Version, described, exec.ExecutedAt.Format(time.RFC3339),
+					),
+				),
+			)
+			continue
+		}
+		_, err = fmt.Fprintf(
+			w, "  %d%s executed at %s, finished at %s (%s%s)\n",
+			exec.Version, described, exec.ExecutedAt.Format(time.RFC3339),
+			exec.FinishedAt.Format(time.RFC3339), exec.Duration(), archived,
+		)
+	}
+	return err
+}
+
+func (f *JsonFormatter) FormatHistory(w io.Writer, executions []ExecutionReport) error {
+	return json.NewEncoder(w).Encode(executions)
+}
+
+func (f *TableFormatter) FormatHistory(w io.Writer, executions []ExecutionReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "VERSION\tEXECUTED AT\tFINISHED AT\tDURATION\tRUN\tDESCRIPTION")
+	for _, exec := range executions {
+		finishedAt, duration := "", ""
+		if exec.FinishedAt != nil {
+			finishedAt, duration = exec.FinishedAt.Format(time.RFC3339), exec.Duration().String()
+		}
+		_, _ = fmt.Fprintf(
+			tw, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			exec.Version, exec.ExecutedAt.Format(time.RFC3339), finishedAt, duration, exec.RunId,
+			exec.Description,
+		)
+	}
+	return tw.Flush()
+}
+
+func (f *QuietFormatter) FormatHistory(io.Writer, []ExecutionReport) error { return nil }
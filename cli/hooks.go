@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/golibry/go-cli-command/cli"
+)
+
+// CommandHooks holds the functions which run before and after a CLI command. Both are optional.
+type CommandHooks struct {
+	// Before runs before the command is executed (after its flags are validated). If it fails,
+	// the command is not executed.
+	Before func(ctx context.Context, cmdId string) error
+
+	// After runs after the command is executed, even if it failed. It receives the command
+	// error (nil on success). Its error is reported along with the command error.
+	After func(ctx context.Context, cmdId string, cmdErr error) error
+}
+
+// HookedCommand wraps a command so that the configured hooks run around its execution
+type HookedCommand struct {
+	Command cli.Command
+	hooks   CommandHooks
+	ctx     context.Context
+}
+
+// NewHookedCommand Builds a new HookedCommand which runs the given hooks around cmd
+func NewHookedCommand(ctx context.Context, cmd cli.Command, hooks CommandHooks) *HookedCommand {
+	return &HookedCommand{Command: cmd, hooks: hooks, ctx: ctx}
+}
+
+func (c *HookedCommand) Id() string {
+	return c.Command.Id()
+}
+
+func (c *HookedCommand) Description() string {
+	return c.Command.Description()
+}
+
+func (c *HookedCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.Command.DefineFlags(flagSet)
+}
+
+func (c *HookedCommand) ValidateFlags() error {
+	return c.Command.ValidateFlags()
+}
+
+func (c *HookedCommand) Exec(stdWriter io.Writer) error {
+	if c.hooks.Before != nil {
+		if err := c.hooks.Before(c.ctx, c.Id()); err != nil {
+			return fmt.Errorf("before hook of command %s failed with error: %w", c.Id(), err)
+		}
+	}
+
+	err := c.Command.Exec(stdWriter)
+
+	if c.hooks.After != nil {
+		if hookErr := c.hooks.After(c.ctx, c.Id(), err); hookErr != nil {
+			err = errors.Join(
+				err,
+				fmt.Errorf("after hook of command %s failed with error: %w", c.Id(), hookErr),
+			)
+		}
+	}
+
+	return err
+}
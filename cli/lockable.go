@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/golibry/go-cli-command/cli"
+	"github.com/golibry/go-migrations/lock"
+	"github.com/golibry/go-migrations/migration"
+)
+
+var nonAlphanumericRegex = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// LockFilePath builds the path of the lock file used for the given lock name. The file name
+// format is the one used by go-cli-command lockable commands, so binaries built with older
+// versions of this package still exclude each other's runs.
+func LockFilePath(lockFilesDirPath string, lockName string) string {
+	nameHash := md5.Sum([]byte(lockName))
+	return filepath.Join(
+		lockFilesDirPath,
+		fmt.Sprintf(
+			"go-cli-command-%s-%s.lock",
+			nonAlphanumericRegex.ReplaceAllString(lockName, "-"),
+			hex.EncodeToString(nameHash[:]),
+		),
+	)
+}
+
+// LockableCommand wraps a command so that it runs exclusively. If the lock is held by another
+// process, the command is not executed and a *LockError wrapping cli.CommandLocked is returned.
+type LockableCommand struct {
+	Command cli.Command
+	locker  lock.Locker
+	ctx     context.Context
+}
+
+// NewLockableCommand Builds a new LockableCommand which guards the execution of cmd
+// with the provided locker
+func NewLockableCommand(
+	ctx context.Context,
+	cmd cli.Command,
+	locker lock.Locker,
+) *LockableCommand {
+	return &LockableCommand{Command: cmd, locker: locker, ctx: ctx}
+}
+
+func (c *LockableCommand) Id() string {
+	return c.Command.Id()
+}
+
+func (c *LockableCommand) Description() string {
+	return c.Command.Description()
+}
+
+func (c *LockableCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.Command.DefineFlags(flagSet)
+}
+
+func (c *LockableCommand) ValidateFlags() error {
+	return c.Command.ValidateFlags()
+}
+
+func (c *LockableCommand) Exec(stdWriter io.Writer) error {
+	logger := migration.LoggerFrom(c.ctx).With("command", c.Id())
+	if err := c.locker.Lock(c.ctx); err != nil {
+		if errors.Is(err, lock.ErrLockHeld) {
+			logger.WarnContext(c.ctx, "the lock is held by another run", "error", err)
+			return &LockError{Err: cli.CommandLocked}
+		}
+
+		return &LockError{
+			Err: fmt.Errorf("failed to acquire lock for command %s: %w", c.Id(), err),
+		}
+	}
+	logger.DebugContext(c.ctx, "lock acquired")
+
+	defer func() {
+		// the lock is released even when the run was cancelled or exceeded its deadline
+		if err := c.locker.Unlock(context.WithoutCancel(c.ctx)); err != nil {
+			logger.WarnContext(c.ctx, "failed to release the lock", "error", err)
+			return
+		}
+		logger.DebugContext(c.ctx, "lock released")
+	}()
+
+	return c.Command.Exec(stdWriter)
+}
+
+// UnlockCommand implements the Command interface to inspect the lock of the exclusive runs and
+// to break it, when it is held by a process which can't release it (see lock.Breaker)
+type UnlockCommand struct {
+	outputFlags
+	locker  lock.Locker
+	ctx     context.Context
+	inspect bool
+	force   bool
+}
+
+func (c *UnlockCommand) Id() string {
+	return "unlock"
+}
+
+func (c *UnlockCommand) Description() string {
+	return "Inspects the lock of the exclusive runs and breaks it, if its holder process is " +
+		"not running anymore (or, with --force, if it is hung).\n" +
+		"Examples: migrate unlock --inspect, migrate unlock, migrate unlock --force"
+}
+
+func (c *UnlockCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.BoolVar(&c.inspect, "inspect", false, "Only display the state of the lock")
+	flagSet.BoolVar(
+		&c.force,
+		"force",
+		false,
+		"Break the lock even if its holder process is running. Make sure the holder is hung, "+
+			"otherwise two runs may execute at the same time.",
+	)
+}
+
+func (c *UnlockCommand) Exec(stdWriter io.Writer) error {
+	breaker, ok := c.locker.(lock.Breaker)
+	if !ok {
+		return errors.New(
+			"the configured locker can't be inspected or broken (see lock.Breaker)",
+		)
+	}
+
+	info, err := breaker.Inspect(c.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to inspect the lock with error: %w", err)
+	}
+
+	msg := describeLock(info)
+	if c.inspect || !info.Held {
+		return c.output().FormatMessage(stdWriter, msg)
+	}
+
+	if err = breaker.Break(c.ctx, c.force); err != nil {
+		if errors.Is(err, lock.ErrLockHeld) {
+			return fmt.Errorf("%w (use --force to break it, if the holder is hung)", err)
+		}
+		return fmt.Errorf("failed to break the lock with error: %w", err)
+	}
+
+	return c.output().FormatMessage(stdWriter, msg+"\nThe lock was broken")
+}
+
+// describeLock describes the state of the lock
+func describeLock(info lock.Info) string {
+	if !info.Held {
+		return "The lock is not held"
+	}
+	if info.Holder == nil {
+		return "The lock is held by an unknown process"
+	}
+
+	msg := fmt.Sprintf(
+		"The lock is held by pid %d on %s since %s",
+		info.Holder.Pid, info.Holder.Hostname, info.Holder.AcquiredAt.Format(time.RFC3339),
+	)
+	if info.Stale {
+		msg += " (stale, the process is not running anymore)"
+	}
+	return msg
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/golibry/go-migrations/lockfile"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// LockfileCommand implements the Command interface to write the lockfile pinning the registered
+// migrations (see the lockfile package), usually in CI, when a release is cut. The up command
+// given the lockfile (see BootstrapSettings.Lockfile) only executes the pinned migrations.
+type LockfileCommand struct {
+	outputFlags
+	file          string
+	hashing       string
+	hasher        lockfile.Hasher
+	migrationsDir migration.MigrationsDirPath
+	registry      migration.MigrationsRegistry
+}
+
+func (c *LockfileCommand) Id() string {
+	return "lockfile"
+}
+
+func (c *LockfileCommand) Description() string {
+	return "Writes the lockfile pinning the registered migrations and their checksums, so the " +
+		"production runs execute only them (see up --lockfile).\n" +
+		"Examples: migrate lockfile, migrate lockfile --file=release/migrations.lock, " +
+		"migrate lockfile --hashing=go-ast"
+}
+
+func (c *LockfileCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.StringVar(
+		&c.file, "file", lockfile.DefaultFile, "File the lockfile is written to",
+	)
+	flagSet.StringVar(
+		&c.hashing,
+		"hashing",
+		lockfile.HashingDeclared,
+		`Hashing strategy of the checksums (`+strings.Join(lockfile.Hashings, ", ")+`).
+		declared: the checksums the migrations declare, file: the bytes of the migration files,
+		go-ast: the code of the migration files, ignoring the comments and the formatting,
+		sql: the SQL the migrations declare, ignoring the comments and the whitespace.
+		The up runs check the lockfile with the same strategy.
+		Examples: migrate lockfile --hashing=go-ast`,
+	)
+}
+
+func (c *LockfileCommand) ValidateFlags() error {
+	if c.file == "" {
+		return errors.New("the lockfile path is required")
+	}
+
+	hasher, err := lockfile.NewHasher(c.hashing, c.migrationsDir)
+	if err != nil {
+		return err
+	}
+	c.hasher = hasher
+	return c.outputFlags.ValidateFlags()
+}
+
+func (c *LockfileCommand) Exec(stdWriter io.Writer) error {
+	pinned, err := lockfile.New(c.registry, c.hasher)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(c.file)
+	if err != nil {
+		return fmt.Errorf("failed to create the lockfile with error: %w", err)
+	}
+	if err = errors.Join(lockfile.Write(file, pinned), file.Close()); err != nil {
+		return err
+	}
+
+	return c.output().FormatMessage(
+		stdWriter, fmt.Sprintf("Pinned %d migrations in %s", len(pinned.Migrations), c.file),
+	)
+}
+
+// checkLockfile verifies that the planned migrations are all pinned by the lockfile at the
+// given path, with their pinned checksums, computed with the hashing strategy of the lockfile
+func checkLockfile(
+	path string,
+	migrationsDir migration.MigrationsDirPath,
+	planned []migration.Migration,
+) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open the lockfile with error: %w", err)
+	}
+
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	pinned, err := lockfile.Read(file)
+	if err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	hasher, err := lockfile.NewHasher(pinned.Hashing, migrationsDir)
+	if err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	if err = pinned.Check(planned, hasher); err != nil {
+		return &ValidationError{Err: err}
+	}
+	return nil
+}
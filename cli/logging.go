@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Flags which set the level and the format of the run logs. They can be given before or after
+// the command.
+var (
+	verboseFlags = []string{"--verbose", "-v"}
+	quietFlags   = []string{"--quiet", "-q"}
+)
+
+const logFormatFlag = "--log-format"
+
+// Formats of the run logs, selected with the --log-format flag
+const (
+	// LogFormatText logs key=value pairs (the default)
+	LogFormatText = "text"
+
+	// LogFormatJson logs one JSON object per line, for log shippers (Loki, Datadog, etc.)
+	LogFormatJson = "json"
+)
+
+// logFlags removes the --verbose, --quiet and --log-format flags from the arguments, and
+// returns the log level (nil if none is given) and the log format (empty if none is given)
+// they select. The arguments after "--" are kept as they are.
+func logFlags(args []string) ([]string, slog.Leveler, string) {
+	var level slog.Leveler
+	var format string
+	remaining := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" && i > 0 {
+			remaining = append(remaining, args[i:]...)
+			break
+		}
+
+		switch {
+		case slices.Contains(verboseFlags, arg):
+			level = slog.LevelDebug
+		case slices.Contains(quietFlags, arg):
+			level = slog.LevelError
+		case strings.HasPrefix(arg, logFormatFlag+"="):
+			format = strings.TrimPrefix(arg, logFormatFlag+"=")
+		case arg == logFormatFlag && i+1 < len(args):
+			i++
+			format = args[i]
+		default:
+			remaining = append(remaining, arg)
+		}
+	}
+	return remaining, level, format
+}
+
+// newLogger builds the logger of the runs, which writes to writer (os.Stderr if nil) the
+// records at or above the level, in the format (LogFormatText if empty). If level is nil,
+// it defaults to slog.LevelInfo for the JSON logs, which are meant to be ingested, and to
+// slog.LevelWarn otherwise.
+func newLogger(writer io.Writer, level slog.Leveler, format string) (*slog.Logger, error) {
+	if writer == nil {
+		writer = os.Stderr
+	}
+
+	if level == nil {
+		level = slog.LevelWarn
+		if format == LogFormatJson {
+			level = slog.LevelInfo
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "", LogFormatText:
+		return slog.New(slog.NewTextHandler(writer, options)), nil
+	case LogFormatJson:
+		return slog.New(slog.NewJSONHandler(writer, options)), nil
+	default:
+		return nil, fmt.Errorf(
+			"unsupported log format %q, use %s or %s", format, LogFormatText, LogFormatJson,
+		)
+	}
+}
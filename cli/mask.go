@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golibry/go-migrations/masking"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// DefaultMaskingEnvironments are the non-production environments the data is masked in, when
+// BootstrapSettings.MaskingEnvironments is empty
+var DefaultMaskingEnvironments = []string{
+	"local", "dev", "development", "test", "testing", "qa", "staging", "uat",
+}
+
+// MaskCommand implements the Command interface to run the data masking routines (see the
+// masking package). It runs only in the non-production environments (see EnvironmentEnvVar).
+type MaskCommand struct {
+	outputFlags
+	db           any
+	routines     []masking.Routine
+	environments []string
+	ctx          context.Context
+}
+
+func (c *MaskCommand) Id() string {
+	return "mask"
+}
+
+func (c *MaskCommand) Description() string {
+	return "Runs the data masking routines, for the databases of the non-production " +
+		"environments (for example, refreshed from production).\n" +
+		"Examples: MIGRATIONS_ENV=staging migrate mask"
+}
+
+func (c *MaskCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+	return checkEnvironment(c.Id(), c.environments)
+}
+
+func (c *MaskCommand) Exec(stdWriter io.Writer) error {
+	masked, err := masking.Run(c.ctx, c.db, c.routines)
+	formatErr := c.output().FormatMessage(stdWriter, maskedMessage(masked))
+	return errors.Join(err, formatErr)
+}
+
+// maskingHooks builds the hooks which run the data masking routines after each successful run
+// of a command, in the allowed environments only. In the other ones, nothing is masked.
+func maskingHooks(db any, routines []masking.Routine, environments []string) CommandHooks {
+	return CommandHooks{
+		After: func(ctx context.Context, cmdId string, cmdErr error) error {
+			logger := migration.LoggerFrom(ctx).With("command", cmdId)
+			if cmdErr != nil {
+				return nil
+			}
+
+			if err := checkEnvironment("mask", environments); err != nil {
+				logger.DebugContext(ctx, "data not masked", "reason", err)
+				return nil
+			}
+
+			masked, err := masking.Run(ctx, db, routines)
+			if err != nil {
+				return err
+			}
+			logger.InfoContext(ctx, "data masked", "routines", masked)
+			return nil
+		},
+	}
+}
+
+func maskedMessage(masked []string) string {
+	if len(masked) == 0 {
+		return "No masking routine executed"
+	}
+	return fmt.Sprintf(
+		"Masked the data with %d routines: %s", len(masked), strings.Join(masked, ", "),
+	)
+}
@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// allModulesFlag runs the command for all the modules (see BootstrapModules)
+const allModulesFlag = "--all"
+
+// Module is a named set of migrations of a monorepo (for example, the ones of a service), with
+// its own registry, executions repository and migrations directory (see BootstrapModules)
+type Module struct {
+	// Name selects the module on the command line, for example: migrate up billing
+	Name string
+
+	// Db is the database handle (or any other dependency) passed to the migrations of the
+	// module
+	Db any
+
+	Registry      migration.MigrationsRegistry
+	Repository    execution.Repository
+	MigrationsDir migration.MigrationsDirPath
+}
+
+// BootstrapModules works like Bootstrap, for several modules (see Module) driven from one
+// binary. The commands run for the module named in the arguments (for example, migrate up
+// billing or migrate status --steps=all auth), or for all the modules, in the given order, with
+// the --all flag (for example, migrate status --all). The runs of --all stop at the first
+// failed module, and the process exits with its code. The module can be omitted if there is
+// only one, and for the help command.
+//
+// The modules share the settings, except for the lock of the exclusive runs: each module has
+// its own, scoped to the module in addition to the lock target (see
+// BootstrapSettings.LockTarget), so the runs of different modules don't wait for each other.
+func BootstrapModules(
+	ctx context.Context,
+	args []string,
+	modules []Module,
+	newHandler HandlerFactory,
+	outputWriter io.Writer,
+	processExit func(code int),
+	settings *BootstrapSettings,
+) {
+	if err := validateModules(modules); err != nil {
+		_, _ = fmt.Fprintf(outputWriter, "Invalid configuration: %s\n", err)
+		processExit(ExitCodeValidation)
+		return
+	}
+
+	// all the modules run by this invocation share the same run ID
+	ctx, _ = execution.EnsureRunId(ctx)
+
+	args, all := boolFlag(args, allModulesFlag)
+	args, selected := moduleArg(args, modules)
+	if selected == nil && !all && (len(modules) == 1 || isHelpRun(args)) {
+		selected = &modules[0]
+	}
+
+	switch {
+	case selected != nil && all:
+		_, _ = fmt.Fprintf(
+			outputWriter, "Invalid arguments: give the module %s or %s, not both\n",
+			selected.Name, allModulesFlag,
+		)
+		processExit(ExitCodeValidation)
+	case selected != nil:
+		bootstrapModule(ctx, args, *selected, newHandler, outputWriter, processExit, settings)
+	case all:
+		headers := moduleHeaders(args, settings)
+		for _, module := range modules {
+			if headers {
+				_, _ = fmt.Fprintf(outputWriter, "Module %s:\n", module.Name)
+			}
+
+			exitCode := ExitCodeOk
+			bootstrapModule(
+				ctx, args, module, newHandler, outputWriter,
+				func(code int) { exitCode = code }, settings,
+			)
+			if exitCode != ExitCodeOk {
+				processExit(exitCode)
+				return
+			}
+		}
+		processExit(ExitCodeOk)
+	default:
+		_, _ = fmt.Fprintf(
+			outputWriter, "Invalid arguments: the command needs a module (%s) or %s\n",
+			strings.Join(moduleNames(modules), ", "), allModulesFlag,
+		)
+		processExit(ExitCodeValidation)
+	}
+}
+
+// bootstrapModule runs Bootstrap for the module, with the lock of the exclusive runs of the
+// module
+func bootstrapModule(
+	ctx context.Context,
+	args []string,
+	module Module,
+	newHandler HandlerFactory,
+	outputWriter io.Writer,
+	processExit func(code int),
+	settings *BootstrapSettings,
+) {
+	moduleSettings := BootstrapSettings{}
+	if settings != nil {
+		moduleSettings = *settings
+	}
+	moduleSettings.lockScope = module.Name
+
+	Bootstrap(
+		ctx, module.Db, args, module.Registry, module.Repository, module.MigrationsDir,
+		newHandler, outputWriter, processExit, &moduleSettings,
+	)
+}
+
+// validateModules checks that the modules have distinct names, which can't be confused with
+// flags, and the registry, repository and migrations directory they require
+func validateModules(modules []Module) error {
+	if len(modules) == 0 {
+		return errors.New("no modules")
+	}
+
+	var errs []error
+	for i, module := range modules {
+		switch {
+		case module.Name == "" || strings.HasPrefix(module.Name, "-"):
+			errs = append(errs, fmt.Errorf("invalid module name %q", module.Name))
+		case slices.ContainsFunc(
+			modules[:i], func(other Module) bool { return other.Name == module.Name },
+		):
+			errs = append(errs, fmt.Errorf("the module %s is given several times", module.Name))
+		case module.Registry == nil:
+			errs = append(errs, fmt.Errorf("no registry for the module %s", module.Name))
+		case module.Repository == nil:
+			errs = append(
+				errs, fmt.Errorf("no executions repository for the module %s", module.Name),
+			)
+		case module.MigrationsDir == "":
+			errs = append(
+				errs, fmt.Errorf("no migrations directory for the module %s", module.Name),
+			)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// moduleArg removes the first argument which names a module, and returns the module (nil if
+// none is named). The arguments after "--" are kept as they are.
+func moduleArg(args []string, modules []Module) ([]string, *Module) {
+	for i, arg := range args {
+		if arg == "--" && i > 0 {
+			break
+		}
+		for m := range modules {
+			if modules[m].Name == arg {
+				return slices.Delete(slices.Clone(args), i, i+1), &modules[m]
+			}
+		}
+	}
+	return args, nil
+}
+
+// isHelpRun checks if the arguments invoke the help command
+func isHelpRun(args []string) bool {
+	args = versionFlagAlias(args)
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	return len(args) == 0 || strings.TrimSpace(args[0]) == "help"
+}
+
+// moduleHeaders checks if the output of the --all runs is human-readable (text or table), so
+// the output of each module can be headed by its name without breaking a JSON output
+func moduleHeaders(args []string, settings *BootstrapSettings) bool {
+	format := FormatText
+	if settings != nil && settings.DefaultFormat != "" {
+		format = settings.DefaultFormat
+	}
+	for _, arg := range args {
+		if slices.Contains(quietFlags, arg) {
+			format = FormatQuiet
+		} else if value, ok := strings.CutPrefix(strings.TrimLeft(arg, "-"), "format="); ok {
+			format = value
+		}
+	}
+	return format == FormatText || format == FormatTable
+}
+
+func moduleNames(modules []Module) []string {
+	names := make([]string, 0, len(modules))
+	for _, module := range modules {
+		names = append(names, module.Name)
+	}
+	return names
+}
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+)
+
+// writeSqlScript implements the --sql-only mode of the up command: the statements of the
+// planned migrations, captured (see migration.CaptureCapable), are written to a SQL script
+// along with the statements recording their executions, if the repository can describe them
+// (see execution.SaveDescriber), instead of being executed. Nothing is written if a planned
+// migration is not capture capable.
+func (c *MigrateUpCommand) writeSqlScript(stdWriter io.Writer) error {
+	planned, err := c.plan()
+	if err != nil {
+		return err
+	}
+
+	captured, err := c.handler.CaptureMigrationsUp(c.ctx, planned)
+	if err != nil {
+		return err
+	}
+
+	var bookkeeping func(exec execution.MigrationExecution) string
+	if describer, ok := execution.SaveDescriberOf(c.repository); ok {
+		bookkeeping = describer.SaveStatement
+	}
+	if err = writeSqlScript(c.ctx, c.sqlOnly, captured, "up", bookkeeping); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf(
+		"Wrote the SQL of %d migrations, with the recording of their executions, to %s",
+		len(captured), c.sqlOnly,
+	)
+	if bookkeeping == nil {
+		message = fmt.Sprintf(
+			"Wrote the SQL of %d migrations to %s, mark them as executed with the "+
+				"mark-executed command once it is run",
+			len(captured), c.sqlOnly,
+		)
+	}
+	return c.output().FormatMessage(stdWriter, message)
+}
+
+// writeSqlScript implements the --sql-only mode of the down command: the statements of the
+// rollbacks of the planned migrations, captured (see migration.CaptureCapable), are written to
+// a SQL script along with the statements removing their executions, if the repository can
+// describe them (see execution.RemoveDescriber), instead of being executed. Nothing is written
+// if a planned migration is not capture capable.
+func (c *MigrateDownCommand) writeSqlScript(stdWriter io.Writer) error {
+	planned, err := c.handler.PlanDown(c.numOfRuns)
+	if err != nil {
+		return err
+	}
+
+	captured, err := c.handler.CaptureMigrationsDown(c.ctx, planned)
+	if err != nil {
+		return err
+	}
+
+	var bookkeeping func(exec execution.MigrationExecution) string
+	if describer, ok := execution.RemoveDescriberOf(c.repository); ok {
+		bookkeeping = describer.RemoveStatement
+	}
+	if err = writeSqlScript(c.ctx, c.sqlOnly, captured, "down", bookkeeping); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf(
+		"Wrote the SQL of %d rollbacks, with the removal of their executions, to %s",
+		len(captured), c.sqlOnly,
+	)
+	if bookkeeping == nil {
+		message = fmt.Sprintf(
+			"Wrote the SQL of %d rollbacks to %s, remove their executions once it is run",
+			len(captured), c.sqlOnly,
+		)
+	}
+	return c.output().FormatMessage(stdWriter, message)
+}
+
+// writeSqlScript writes the SQL script of the migrations captured in the direction (see
+// sqlScript) to the file at path
+func writeSqlScript(
+	ctx context.Context,
+	path string,
+	captured []handler.CapturedMigration,
+	direction string,
+	bookkeeping func(exec execution.MigrationExecution) string,
+) error {
+	script, err := sqlScript(captured, direction, bookkeeping, execution.RunIdFrom(ctx))
+	if err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	if err = os.WriteFile(path, []byte(script), 0644); err != nil {
+		return fmt.Errorf("failed to write the SQL script with error: %w", err)
+	}
+	return nil
+}
+
+// sqlScript builds the SQL script of the migrations captured in the direction ("up" or
+// "down"), each one followed by its bookkeeping statement (recording or removing its
+// execution), if bookkeeping is not nil. Fails if a migration is not capture capable, or has a
+// statement with arguments, which can't be inlined portably.
+func sqlScript(
+	captured []handler.CapturedMigration,
+	direction string,
+	bookkeeping func(exec execution.MigrationExecution) string,
+	runId string,
+) (string, error) {
+	var notCapturable []uint64
+	for _, mig := range captured {
+		if !mig.Capturable {
+			notCapturable = append(notCapturable, mig.Migration.Version())
+		}
+	}
+	if len(notCapturable) > 0 {
+		return "", fmt.Errorf(
+			"the migrations %v are not capture capable, their SQL can't be written", notCapturable,
+		)
+	}
+
+	var script strings.Builder
+	_, _ = fmt.Fprintf(&script, "-- go-migrations %s script of run %s\n", direction, runId)
+	if bookkeeping == nil && direction == "up" {
+		script.WriteString("-- mark the migrations as executed with mark-executed once run\n")
+	} else if bookkeeping == nil {
+		script.WriteString("-- remove the executions of the migrations once run\n")
+	}
+
+	for _, mig := range captured {
+		version := mig.Migration.Version()
+		_, _ = fmt.Fprintf(&script, "\n-- migration %d\n", version)
+
+		for _, statement := range mig.Statements {
+			if len(statement.Args) > 0 {
+				return "", fmt.Errorf(
+					"the statement %q of the migration %d has arguments, its SQL can't be"+
+						" written",
+					statement.Query, version,
+				)
+			}
+			script.WriteString(terminated(statement.Query))
+		}
+
+		if bookkeeping != nil {
+			exec := execution.StartExecution(mig.Migration)
+			exec.RunId = runId
+			exec.FinishExecution()
+			script.WriteString(terminated(bookkeeping(*exec)))
+		}
+	}
+
+	return script.String(), nil
+}
+
+// terminated returns the statement on its own line, terminated by a semicolon
+func terminated(statement string) string {
+	statement = strings.TrimSpace(statement)
+	if !strings.HasSuffix(statement, ";") {
+		statement += ";"
+	}
+	return statement + "\n"
+}
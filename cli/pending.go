@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// PendingFormatter is an optional interface for the formatters which render the result of the
+// pending command. The output of the formatters which don't implement it is rendered by the
+// TextFormatter.
+type PendingFormatter interface {
+	FormatPending(w io.Writer, versions []uint64) error
+}
+
+// PendingCommand implements the Command interface to print only the versions of the pending
+// migrations, in the order they will be executed, so shell scripts can gate deploys on them
+// without parsing the status output
+type PendingCommand struct {
+	outputFlags
+	exitCode   bool
+	registry   migration.MigrationsRegistry
+	repository execution.Repository
+}
+
+func (c *PendingCommand) Id() string {
+	return "pending"
+}
+
+func (c *PendingCommand) Description() string {
+	return "Prints the versions of the pending migrations, one per line (or a JSON array).\n" +
+		"Examples: migrate pending, migrate pending --format=json, migrate pending --exit-code"
+}
+
+func (c *PendingCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.BoolVar(
+		&c.exitCode,
+		"exit-code",
+		false,
+		"Exit with a non-zero code if there are pending migrations",
+	)
+}
+
+func (c *PendingCommand) Exec(stdWriter io.Writer) error {
+	executions, err := c.repository.LoadExecutions()
+	if err != nil {
+		return fmt.Errorf("failed to load executions with error: %w", err)
+	}
+
+	report := newStatusReport(c.registry.OrderedMigrations(), executions)
+	versions := make([]uint64, 0, len(report.Pending))
+	for _, mig := range report.Pending {
+		versions = append(versions, mig.Version)
+	}
+
+	if formatter, ok := c.output().(PendingFormatter); ok {
+		err = formatter.FormatPending(stdWriter, versions)
+	} else {
+		err = (&TextFormatter{}).FormatPending(stdWriter, versions)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.exitCode && len(versions) > 0 {
+		return errors.New("there are pending migrations")
+	}
+	return nil
+}
+
+func (f *TextFormatter) FormatPending(w io.Writer, versions []uint64) error {
+	for _, version := range versions {
+		if _, err := fmt.Fprintln(w, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *JsonFormatter) FormatPending(w io.Writer, versions []uint64) error {
+	return json.NewEncoder(w).Encode(versions)
+}
+
+func (f *QuietFormatter) FormatPending(io.Writer, []uint64) error { return nil }
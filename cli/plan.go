@@ -0,0 +1,421 @@
+package cli
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/lockfile"
+	"github.com/golibry/go-migrations/migration"
+	"github.com/golibry/go-migrations/plan"
+)
+
+// PlanExportCommand implements the Command interface to export the plan of an up or down run
+// as an artifact (see the plan package), signed when a signing key is configured, to be
+// reviewed, approved and then executed exactly by the plan:apply command
+type PlanExportCommand struct {
+	outputFlags
+	steps         string
+	numOfRuns     handler.NumOfRuns
+	target        string
+	targetVer     uint64
+	down          bool
+	file          string
+	hashing       string
+	hasher        lockfile.Hasher
+	signingKey    ed25519.PrivateKey
+	migrationsDir migration.MigrationsDirPath
+	registry      migration.MigrationsRegistry
+	repository    execution.Repository
+	handler       *handler.MigrationsHandler
+}
+
+func (c *PlanExportCommand) Id() string {
+	return "plan:export"
+}
+
+func (c *PlanExportCommand) Description() string {
+	return "Exports the plan of a run as a (signed) artifact, to be executed by plan:apply.\n" +
+		"Examples: migrate plan:export --steps=all --file=plan.json, " +
+		"migrate plan:export --down --steps=2 --file=plan.json"
+}
+
+func (c *PlanExportCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.StringVar(
+		&c.steps, "steps", "1", `Number of migrations of the run: "all" or an integer`,
+	)
+	flagSet.StringVar(
+		&c.target, "target", "", "Plan the up run up to and including the target version",
+	)
+	flagSet.BoolVar(&c.down, "down", false, "Plan a down run instead of an up run")
+	flagSet.StringVar(&c.file, "file", "", "File the artifact is written to. Defaults to stdout.")
+	flagSet.StringVar(
+		&c.hashing,
+		"hashing",
+		lockfile.HashingFile,
+		`Hashing strategy of the migrations content covered by the artifact (`+
+			strings.Join(lockfile.Hashings, ", ")+`), like the lockfile command.
+		The file and go-ast strategies detect a change of the migrations code before plan:apply,
+		which needs the migration files. Use declared where they are not deployed.`,
+	)
+}
+
+func (c *PlanExportCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
+	num, err := handler.NewNumOfRuns(c.steps)
+	if err != nil {
+		return err
+	}
+	c.numOfRuns = num
+
+	if c.hasher, err = lockfile.NewHasher(c.hashing, c.migrationsDir); err != nil {
+		return err
+	}
+
+	if c.target != "" {
+		if c.down {
+			return errors.New("the target version can only be used to plan an up run")
+		}
+		if c.targetVer, err = getVersionFrom(c.target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *PlanExportCommand) Exec(stdWriter io.Writer) error {
+	executions, err := c.repository.LoadExecutions()
+	if err != nil {
+		return fmt.Errorf("failed to load executions with error: %w", err)
+	}
+
+	direction := handler.DirectionUp
+	var planned []migration.Migration
+	switch {
+	case c.down:
+		direction = handler.DirectionDown
+		planned, err = c.handler.PlanDown(c.numOfRuns)
+	case c.target != "":
+		planned, err = c.handler.PlanUpTo(c.targetVer, nil)
+	default:
+		planned, err = c.handler.PlanUp(c.numOfRuns, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	artifact, err := plan.New(direction, planned, c.registry, executions, c.hasher)
+	if err != nil {
+		return err
+	}
+
+	if c.signingKey != nil {
+		artifact.Sign(c.signingKey)
+	}
+
+	if c.file == "" {
+		return plan.Write(stdWriter, artifact)
+	}
+
+	file, err := os.Create(c.file)
+	if err != nil {
+		return fmt.Errorf("failed to create the plan artifact file with error: %w", err)
+	}
+	if err = errors.Join(plan.Write(file, artifact), file.Close()); err != nil {
+		return err
+	}
+
+	return c.output().FormatMessage(
+		stdWriter,
+		fmt.Sprintf(
+			"Exported the %s plan of %d migrations to %s", direction, len(planned), c.file,
+		),
+	)
+}
+
+// PlanApplyCommand implements the Command interface to execute a signed plan artifact
+// exported by the plan:export command, exactly: the run fails before executing any migration
+// if the signature is invalid, or if the artifact no longer matches the registry, the
+// executions state or the current plan
+type PlanApplyCommand struct {
+	outputFlags
+	file          string
+	verifyingKey  ed25519.PublicKey
+	progress      handler.ProgressReporter
+	migrationsDir migration.MigrationsDirPath
+	registry      migration.MigrationsRegistry
+	repository    execution.Repository
+	handler       *handler.MigrationsHandler
+	ctx           context.Context
+}
+
+func (c *PlanApplyCommand) Id() string {
+	return "plan:apply"
+}
+
+func (c *PlanApplyCommand) Description() string {
+	return "Executes exactly the migrations of a signed plan artifact, after verifying it " +
+		"matches the registry and the executions.\nExamples: migrate plan:apply --file=plan.json"
+}
+
+func (c *PlanApplyCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.StringVar(&c.file, "file", "", "File of the plan artifact to execute (required)")
+}
+
+func (c *PlanApplyCommand) ValidateFlags() error {
+	if c.file == "" {
+		return errors.New("the plan artifact file is required")
+	}
+	return c.outputFlags.ValidateFlags()
+}
+
+func (c *PlanApplyCommand) Exec(stdWriter io.Writer) error {
+	artifact, err := c.readArtifact()
+	if err != nil {
+		return err
+	}
+
+	if err = artifact.Verify(c.verifyingKey); err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	executions, err := c.repository.LoadExecutions()
+	if err != nil {
+		return fmt.Errorf("failed to load executions with error: %w", err)
+	}
+
+	numOfRuns := handler.NumOfRuns(len(artifact.Versions))
+	var planned []migration.Migration
+	if artifact.Direction == handler.DirectionDown {
+		planned, err = c.handler.PlanDown(numOfRuns)
+	} else {
+		planned, err = c.handler.PlanUp(numOfRuns, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	hasher, err := lockfile.NewHasher(artifact.Hashing, c.migrationsDir)
+	if err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	if err = artifact.Check(c.registry, executions, planned, hasher); err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	var execs []handler.ExecutedMigration
+	if len(planned) > 0 {
+		ctx := withProgress(c.ctx, c.progress, c.output(), stdWriter)
+		if artifact.Direction == handler.DirectionDown {
+			execs, err = c.handler.MigrateDown(ctx, numOfRuns)
+		} else {
+			execs, err = c.handler.MigrateUp(ctx, numOfRuns)
+		}
+	}
+
+	_ = c.output().FormatRun(
+		stdWriter,
+		newRunReport(c.Id(), artifact.Direction, false, execution.RunIdFrom(c.ctx), execs),
+	)
+	return err
+}
+
+func (c *PlanApplyCommand) readArtifact() (plan.Artifact, error) {
+	file, err := os.Open(c.file)
+	if err != nil {
+		return plan.Artifact{}, fmt.Errorf(
+			"failed to open the plan artifact file with error: %w", err,
+		)
+	}
+
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	artifact, err := plan.Read(file)
+	if err != nil {
+		return plan.Artifact{}, &ValidationError{Err: err}
+	}
+	return artifact, nil
+}
+
+// PlanStep describes a migration of the plan of a run
+type PlanStep struct {
+	MigrationReport
+
+	// Direction is "up" or "down"
+	Direction string `json:"direction"`
+
+	// Transactional is nil if the migration doesn't declare whether it runs in a transaction
+	// (see migration.Transactional)
+	Transactional *bool `json:"transactional,omitempty"`
+}
+
+// PlanReport is the result of the plan command
+type PlanReport struct {
+	// Direction is "up" or "down"
+	Direction string `json:"direction"`
+
+	// Steps holds the migrations the run would execute, in order
+	Steps []PlanStep `json:"steps"`
+}
+
+// PlanFormatter is an optional interface for the formatters which render the result of the
+// plan command. The output of the formatters which don't implement it is rendered by the
+// TextFormatter.
+type PlanFormatter interface {
+	FormatPlan(w io.Writer, report PlanReport) error
+}
+
+// newPlanReport builds the plan report of the migrations a run would execute, in order
+func newPlanReport(direction string, planned []migration.Migration) PlanReport {
+	report := PlanReport{Direction: direction, Steps: []PlanStep{}}
+	for _, mig := range planned {
+		step := PlanStep{MigrationReport: newMigrationReport(mig, nil), Direction: direction}
+		if transactional, declared := migration.TransactionalOf(mig); declared {
+			step.Transactional = &transactional
+		}
+		report.Steps = append(report.Steps, step)
+	}
+	return report
+}
+
+// PlanCommand implements the Command interface to print the ordered plan of an up or down run,
+// resolved from the registry and the executions, without executing anything: the version,
+// description and direction of each migration, and whether it runs in a transaction
+type PlanCommand struct {
+	outputFlags
+	steps     string
+	numOfRuns handler.NumOfRuns
+	target    string
+	targetVer uint64
+	down      bool
+	handler   *handler.MigrationsHandler
+}
+
+func (c *PlanCommand) Id() string {
+	return "plan"
+}
+
+func (c *PlanCommand) Description() string {
+	return "Prints the ordered plan of a run, without executing anything.\n" +
+		"Examples: migrate plan, migrate plan --target=3, migrate plan --down --steps=2"
+}
+
+func (c *PlanCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.StringVar(
+		&c.steps, "steps", "all", `Number of migrations of the run: "all" or an integer`,
+	)
+	flagSet.StringVar(
+		&c.target, "target", "", "Plan the up run up to and including the target version",
+	)
+	flagSet.BoolVar(&c.down, "down", false, "Plan a down run instead of an up run")
+}
+
+func (c *PlanCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
+	num, err := handler.NewNumOfRuns(c.steps)
+	if err != nil {
+		return err
+	}
+	c.numOfRuns = num
+
+	if c.target != "" {
+		if c.down {
+			return errors.New("the target version can only be used to plan an up run")
+		}
+		if c.targetVer, err = getVersionFrom(c.target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *PlanCommand) Exec(stdWriter io.Writer) error {
+	direction := handler.DirectionUp
+	var planned []migration.Migration
+	var err error
+	switch {
+	case c.down:
+		direction = handler.DirectionDown
+		planned, err = c.handler.PlanDown(c.numOfRuns)
+	case c.target != "":
+		planned, err = c.handler.PlanUpTo(c.targetVer, nil)
+	default:
+		planned, err = c.handler.PlanUp(c.numOfRuns, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	report := newPlanReport(direction, planned)
+	if formatter, ok := c.output().(PlanFormatter); ok {
+		return formatter.FormatPlan(stdWriter, report)
+	}
+	return (&TextFormatter{}).FormatPlan(stdWriter, report)
+}
+
+// transactionCell describes whether the step runs in a transaction
+func transactionCell(step PlanStep) string {
+	switch {
+	case step.Transactional == nil:
+		return "undeclared"
+	case *step.Transactional:
+		return "yes"
+	default:
+		return "no"
+	}
+}
+
+func (f *TextFormatter) FormatPlan(w io.Writer, report PlanReport) error {
+	action := "Up()"
+	if report.Direction == handler.DirectionDown {
+		action = "Down()"
+	}
+
+	_, err := fmt.Fprintf(w, "Plan of %s for %d migrations\n", action, len(report.Steps))
+	for i, step := range report.Steps {
+		_, err = fmt.Fprintf(
+			w, "%d. %s %s (transaction: %s)\n",
+			i+1, action, migrationLabel(&step.MigrationReport), transactionCell(step),
+		)
+	}
+	return err
+}
+
+func (f *JsonFormatter) FormatPlan(w io.Writer, report PlanReport) error {
+	return json.NewEncoder(w).Encode(report)
+}
+
+func (f *TableFormatter) FormatPlan(w io.Writer, report PlanReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "VERSION\tDIRECTION\tTRANSACTION\tDESCRIPTION")
+	for _, step := range report.Steps {
+		_, _ = fmt.Fprintf(
+			tw, "%d\t%s\t%s\t%s\n",
+			step.Version, step.Direction, transactionCell(step), metadataCell(step.Metadata),
+		)
+	}
+	return tw.Flush()
+}
+
+func (f *QuietFormatter) FormatPlan(io.Writer, PlanReport) error { return nil }
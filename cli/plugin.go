@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/golibry/go-cli-command/cli"
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// CommandPlugin registers a custom command (for example, seed or anonymize) along with the
+// built-in ones (see BootstrapSettings.Commands). The command is wired like them: it gets the
+// hooks of its id (see BootstrapSettings.CommandHooks), takes the run lock if it is exclusive
+// and is listed by the help and describe commands.
+type CommandPlugin struct {
+	// Id is the id of the command New builds, which must not be the id of another command
+	Id string
+
+	// New builds the command, from the wiring of the invocation
+	New func(env CommandEnv) cli.Command
+
+	// If the command only reads the executions: like the built-in read-only commands, it
+	// runs with a read-only repository (see execution.NewReadOnlyRepository), which doesn't
+	// create or upgrade the executions storage, and never takes the run lock
+	ReadOnly bool
+
+	// If the command takes the run lock, when the runs are exclusive (see
+	// BootstrapSettings.RunMigrationsExclusively)
+	Exclusive bool
+}
+
+// CommandEnv is the wiring of an invocation, shared by the built-in commands, which the
+// custom commands (see CommandPlugin) reuse
+type CommandEnv struct {
+	// Ctx is the context of the run, cancelled on interrupt and at the invocation deadline
+	Ctx context.Context
+
+	// Db is the database handle (or any other dependency) given to Bootstrap
+	Db any
+
+	Registry      migration.MigrationsRegistry
+	Repository    execution.Repository
+	Handler       *handler.MigrationsHandler
+	MigrationsDir migration.MigrationsDirPath
+
+	// Output handles the --format flag of the command, with the configured formatters
+	Output OutputFlags
+}
+
+// OutputFlags handles the --format flag of a custom command, like for the built-in commands:
+// call its DefineFlags and ValidateFlags methods from the ones of the command, then render
+// the output with the Formatter
+type OutputFlags struct {
+	outputFlags
+}
+
+// Formatter returns the formatter selected with the --format flag
+func (o *OutputFlags) Formatter() Formatter {
+	return o.output()
+}
+
+// readOnlyPluginIds returns the ids of the read-only custom commands
+func readOnlyPluginIds(plugins []CommandPlugin) []string {
+	var ids []string
+	for _, plugin := range plugins {
+		if plugin.ReadOnly {
+			ids = append(ids, plugin.Id)
+		}
+	}
+	return ids
+}
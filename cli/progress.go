@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/golibry/go-migrations/handler"
+)
+
+// TextProgressReporter implements the handler.ProgressReporter interface by writing a line
+// when each migration of a batch run starts and finishes, with a running counter and the
+// elapsed time of the run, for example: [3/17] 1712953077 up done in 1.2s (3/17 applied,
+// 00:42 elapsed).
+type TextProgressReporter struct {
+	writer io.Writer
+}
+
+// NewTextProgressReporter builds a new TextProgressReporter, writing to w
+func NewTextProgressReporter(w io.Writer) *TextProgressReporter {
+	return &TextProgressReporter{writer: w}
+}
+
+// Started implements the handler.ProgressReporter.Started method
+func (r *TextProgressReporter) Started(_ context.Context, progress handler.Progress) {
+	_, _ = fmt.Fprintf(
+		r.writer, "[%d/%d] %d %s started\n",
+		progress.Position, progress.Total, progress.Version, progress.Direction,
+	)
+}
+
+// Finished implements the handler.ProgressReporter.Finished method
+func (r *TextProgressReporter) Finished(_ context.Context, progress handler.Progress) {
+	outcome := "done in"
+	if progress.Err != nil {
+		outcome = "failed after"
+	}
+
+	counter := "applied"
+	if progress.Direction == handler.DirectionDown {
+		counter = "rolled back"
+	}
+
+	_, _ = fmt.Fprintf(
+		r.writer, "[%d/%d] %d %s %s %s (%d/%d %s, %s elapsed)\n",
+		progress.Position, progress.Total, progress.Version, progress.Direction, outcome,
+		progress.Duration.Round(time.Millisecond), progress.Done, progress.Total, counter,
+		elapsedClock(progress.Elapsed),
+	)
+}
+
+// elapsedClock formats the duration as minutes and seconds (00:42), prefixed by the hours when
+// there are any (1:02:03)
+func elapsedClock(elapsed time.Duration) string {
+	seconds := int(elapsed.Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// withProgress returns a copy of ctx which carries the progress reporter of a batch run: the
+// given one or, when the output is rendered as text, a TextProgressReporter writing to w. The
+// other outputs (json, table, quiet) are not interleaved with progress lines.
+func withProgress(
+	ctx context.Context,
+	reporter handler.ProgressReporter,
+	output Formatter,
+	w io.Writer,
+) context.Context {
+	if reporter == nil {
+		if _, isText := output.(*TextFormatter); !isText {
+			return ctx
+		}
+		reporter = NewTextProgressReporter(w)
+	}
+	return handler.WithProgressReporter(ctx, reporter)
+}
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/golibry/go-cli-command/cli"
+	"github.com/golibry/go-migrations/execution"
+)
+
+// readOnlyModeCommand wraps the commands which write the executions in the read-only mode (see
+// BootstrapSettings.ReadOnly), so they fail before doing anything: before taking the lock of
+// the exclusive runs or executing any migration
+type readOnlyModeCommand struct {
+	cli.Command
+}
+
+func (c *readOnlyModeCommand) Exec(io.Writer) error {
+	return fmt.Errorf(
+		"the %s command is not available in the read-only mode: %w",
+		c.Id(), execution.ErrReadOnlyRepository,
+	)
+}
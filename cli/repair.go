@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/golibry/go-migrations/handler"
+)
+
+// repairAll selects the fixes of all the issue kinds
+const repairAll = "all"
+
+// RepairCommand implements the Command interface to find the inconsistencies of the recorded
+// executions (see handler.ExecutionIssue) and fix them, instead of editing the executions
+// table manually. Without the --fix flag, the issues and their fixes are only listed.
+type RepairCommand struct {
+	outputFlags
+	confirmation
+	rawKinds string
+	kinds    []string
+	handler  *handler.MigrationsHandler
+}
+
+func (c *RepairCommand) Id() string {
+	return "repair"
+}
+
+func (c *RepairCommand) Description() string {
+	return "Lists the inconsistencies of the recorded executions (unfinished executions, " +
+		"executions of unregistered versions, versions recorded several times) and their " +
+		"fixes, applied with the --fix flag.\n" +
+		"Examples: migrate repair, migrate repair --fix=all, migrate repair --fix=orphan --yes"
+}
+
+func (c *RepairCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	c.confirmation.DefineFlags(flagSet)
+	flagSet.StringVar(
+		&c.rawKinds,
+		"fix",
+		"",
+		`Comma separated kinds of the issues to fix (`+strings.Join(handler.IssueKinds, ", ")+
+			`), or "all".`+"\n"+
+			"Examples: migrate repair --fix=all, migrate repair --fix=duplicate,orphan",
+	)
+}
+
+func (c *RepairCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
+	c.kinds = nil
+	if c.rawKinds == repairAll {
+		c.kinds = handler.IssueKinds
+		return nil
+	}
+
+	for _, kind := range strings.Split(c.rawKinds, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		if !slices.Contains(handler.IssueKinds, kind) {
+			return fmt.Errorf(
+				"unknown issue kind %q, expected one of: %s, %s",
+				kind, strings.Join(handler.IssueKinds, ", "), repairAll,
+			)
+		}
+		c.kinds = append(c.kinds, kind)
+	}
+	return nil
+}
+
+func (c *RepairCommand) Exec(stdWriter io.Writer) error {
+	issues, err := c.handler.ExecutionIssues()
+	if err != nil {
+		return err
+	}
+
+	if len(issues) == 0 {
+		return c.output().FormatMessage(stdWriter, "No execution issue found")
+	}
+
+	var selected []handler.ExecutionIssue
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("Execution issues: %d\n", len(issues)))
+	for _, issue := range issues {
+		msg.WriteString(fmt.Sprintf("  %s (fix: %s)\n", issue, issue.Fix()))
+		if slices.Contains(c.kinds, issue.Kind) {
+			selected = append(selected, issue)
+		}
+	}
+	_ = c.output().FormatMessage(stdWriter, strings.TrimSuffix(msg.String(), "\n"))
+
+	if len(selected) == 0 {
+		return nil
+	}
+
+	action := fmt.Sprintf("apply %d fixes to the recorded executions", len(selected))
+	if err = c.confirm(stdWriter, action, false); err != nil {
+		return err
+	}
+
+	fixed, err := c.handler.Repair(selected)
+	msg.Reset()
+	msg.WriteString(fmt.Sprintf("Applied fixes: %d", len(fixed)))
+	for _, issue := range fixed {
+		msg.WriteString("\n  " + issue.Fix())
+	}
+	_ = c.output().FormatMessage(stdWriter, msg.String())
+	return err
+}
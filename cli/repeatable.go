@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golibry/go-migrations/repeatable"
+	"github.com/golibry/go-migrations/sqlhelper"
+)
+
+// RepeatableSyncCommand implements the Command interface to (re)create the repeatable objects
+// (views, functions, procedures, triggers) whose definition changed since their last apply
+// (see the repeatable package)
+type RepeatableSyncCommand struct {
+	outputFlags
+	dryRun   bool
+	db       any
+	store    repeatable.Store
+	registry *repeatable.Registry
+	ctx      context.Context
+}
+
+func (c *RepeatableSyncCommand) Id() string {
+	return "repeatable:sync"
+}
+
+func (c *RepeatableSyncCommand) Description() string {
+	return "Drops and creates again the repeatable objects (views, functions, procedures, " +
+		"triggers) whose definition changed.\n" +
+		"Examples: migrate repeatable:sync, migrate repeatable:sync --dry-run"
+}
+
+func (c *RepeatableSyncCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.BoolVar(
+		&c.dryRun,
+		"dry-run",
+		false,
+		"Only display which repeatable objects changed, in the order they would be applied",
+	)
+}
+
+func (c *RepeatableSyncCommand) Exec(stdWriter io.Writer) error {
+	if c.dryRun {
+		changed, err := repeatable.Changed(c.ctx, c.store, c.registry)
+		if err != nil {
+			return err
+		}
+		return c.output().FormatMessage(
+			stdWriter,
+			fmt.Sprintf("%d repeatable objects changed%s", len(changed), objectKeys(changed)),
+		)
+	}
+
+	db, ok := c.db.(sqlhelper.Execer)
+	if !ok {
+		return errors.New("the repeatable objects need a db which can execute statements")
+	}
+
+	applied, err := repeatable.Sync(c.ctx, db, c.store, c.registry)
+	formatErr := c.output().FormatMessage(
+		stdWriter,
+		fmt.Sprintf("%d repeatable objects applied%s", len(applied), objectKeys(applied)),
+	)
+	return errors.Join(err, formatErr)
+}
+
+// objectKeys lists the keys of the objects, after a colon, or returns an empty string if
+// there are none
+func objectKeys(objects []repeatable.Object) string {
+	if len(objects) == 0 {
+		return ""
+	}
+
+	keys := make([]string, len(objects))
+	for i, object := range objects {
+		keys[i] = object.Key()
+	}
+	return ": " + strings.Join(keys, ", ")
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+)
+
+// EnvironmentEnvVar is the environment variable with the name of the environment the migrations
+// run in (for example, "development" or "production"), checked by the reset and fresh commands
+const EnvironmentEnvVar = "MIGRATIONS_ENV"
+
+// DefaultResetEnvironments are the environments the reset and fresh commands are allowed in,
+// when BootstrapSettings.ResetEnvironments is empty
+var DefaultResetEnvironments = []string{"local", "dev", "development", "test", "testing"}
+
+// ResetCommand implements the Command interface to execute Down() for all the executed
+// migrations, in reverse order, and (for the fresh command) Up() for all of them afterward, to
+// rebuild development databases. It runs only in the allowed environments (see
+// EnvironmentEnvVar), after a confirmation.
+type ResetCommand struct {
+	outputFlags
+	confirmation
+	fresh        bool
+	environments []string
+	repository   execution.Repository
+	handler      *handler.MigrationsHandler // Handler for executing migrations
+	ctx          context.Context
+}
+
+func (c *ResetCommand) Id() string {
+	if c.fresh {
+		return "fresh"
+	}
+	return "reset"
+}
+
+func (c *ResetCommand) Description() string {
+	if c.fresh {
+		return "Executes Down() for all the executed migrations, in reverse order, then Up() " +
+			"for all the migrations. Meant for rebuilding development databases.\n" +
+			"Examples: MIGRATIONS_ENV=development migrate fresh --yes"
+	}
+	return "Executes Down() for all the executed migrations, in reverse order. Meant for " +
+		"development databases.\n" +
+		"Examples: MIGRATIONS_ENV=development migrate reset"
+}
+
+func (c *ResetCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	c.confirmation.DefineFlags(flagSet)
+}
+
+func (c *ResetCommand) ValidateFlags() error {
+	if err := c.outputFlags.ValidateFlags(); err != nil {
+		return err
+	}
+
+	allowed := c.environments
+	if len(allowed) == 0 {
+		allowed = DefaultResetEnvironments
+	}
+	return checkEnvironment(c.Id(), allowed)
+}
+
+// checkEnvironment fails unless the environment (see EnvironmentEnvVar) is one of the allowed
+// ones, which the command with the given id requires
+func checkEnvironment(cmdId string, allowed []string) error {
+	env := strings.TrimSpace(os.Getenv(EnvironmentEnvVar))
+	if env != "" && slices.ContainsFunc(
+		allowed, func(name string) bool { return strings.EqualFold(name, env) },
+	) {
+		return nil
+	}
+
+	if env == "" {
+		return fmt.Errorf(
+			"the %s command requires the %s environment variable, set to one of: %s",
+			cmdId, EnvironmentEnvVar, strings.Join(allowed, ", "),
+		)
+	}
+	return fmt.Errorf(
+		"the %s command is not allowed in the %q environment, only in: %s",
+		cmdId, env, strings.Join(allowed, ", "),
+	)
+}
+
+func (c *ResetCommand) Exec(stdWriter io.Writer) error {
+	// the irreversible migrations (see migration.Irreversible) refuse the run before the
+	// confirmation
+	if _, err := c.handler.PlanDown(handler.NumOfRuns(math.MaxInt)); err != nil {
+		return err
+	}
+
+	if !c.yes {
+		executions, err := c.repository.LoadExecutions()
+		if err != nil {
+			return fmt.Errorf("failed to load the executions with error: %w", err)
+		}
+
+		action := fmt.Sprintf("roll back all the %d executed migrations", len(executions))
+		if c.fresh {
+			action += " and re-apply all the migrations"
+		}
+		if err = c.confirm(stdWriter, action, true); err != nil {
+			return err
+		}
+	}
+
+	runId := execution.RunIdFrom(c.ctx)
+	executed, err := c.handler.MigrateDown(c.ctx, handler.NumOfRuns(math.MaxInt))
+	_ = c.output().FormatRun(stdWriter, newRunReport(c.Id(), "down", false, runId, executed))
+	if err != nil || !c.fresh {
+		return err
+	}
+
+	executed, err = c.handler.MigrateUp(c.ctx, handler.NumOfRuns(math.MaxInt))
+	_ = c.output().FormatRun(stdWriter, newRunReport(c.Id(), "up", false, runId, executed))
+	return err
+}
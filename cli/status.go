@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// StatusReport is the result of the status command
+type StatusReport struct {
+	// Applied holds the registered migrations with a finished execution, in version order
+	Applied []MigrationReport `json:"applied"`
+
+	// Pending holds the registered migrations without a finished execution, in the order
+	// they will be executed
+	Pending []MigrationReport `json:"pending"`
+
+	// Failed holds the versions of the pending migrations with an unfinished execution: their
+	// migration failed or was interrupted, in the order of Pending
+	Failed []uint64 `json:"failed,omitempty"`
+
+	// Unknown holds the versions of the executions without a registered migration (for
+	// example, migrations executed from another branch or removed from the registry)
+	Unknown []uint64 `json:"unknown"`
+
+	// Archived is the number of the applied migrations whose executions were moved to the
+	// executions archive (see the archive command). They are only listed in Applied with the
+	// --archived flag.
+	Archived int `json:"archived,omitempty"`
+}
+
+// StatusFormatter is an optional interface for the formatters which render the result of
+// the status command. The output of the formatters which don't implement it is rendered by
+// the TextFormatter.
+type StatusFormatter interface {
+	FormatStatus(w io.Writer, report StatusReport) error
+}
+
+// newStatusReport cross-references the registered migrations with the executions
+func newStatusReport(
+	migrations []migration.Migration,
+	executions []execution.MigrationExecution,
+) StatusReport {
+	report := StatusReport{
+		Applied: []MigrationReport{},
+		Pending: []MigrationReport{},
+		Unknown: []uint64{},
+	}
+
+	finished := make(map[uint64]bool, len(executions))
+	executed := make(map[uint64]bool, len(executions))
+	for _, exec := range executions {
+		finished[exec.Version] = exec.Finished()
+		executed[exec.Version] = true
+	}
+
+	registered := make(map[uint64]bool, len(migrations))
+	for _, mig := range migrations {
+		registered[mig.Version()] = true
+		if finished[mig.Version()] {
+			report.Applied = append(report.Applied, newMigrationReport(mig, nil))
+		} else {
+			report.Pending = append(report.Pending, newMigrationReport(mig, nil))
+			if executed[mig.Version()] {
+				report.Failed = append(report.Failed, mig.Version())
+			}
+		}
+	}
+
+	for _, exec := range executions {
+		if !registered[exec.Version] {
+			report.Unknown = append(report.Unknown, exec.Version)
+		}
+	}
+	slices.Sort(report.Unknown)
+
+	return report
+}
+
+// MigrateStatusCommand implements the Command interface to display the applied, pending and
+// unknown migration versions. Unlike the stats command, it does not fail on an inconsistent
+// state, so it can be used to inspect it.
+type MigrateStatusCommand struct {
+	outputFlags
+	jsonOutput bool
+	archived   bool
+	registry   migration.MigrationsRegistry
+	repository execution.Repository
+}
+
+func (c *MigrateStatusCommand) Id() string {
+	return "status"
+}
+
+func (c *MigrateStatusCommand) Description() string {
+	return "Displays the applied, pending and unknown (executed, but not registered) " +
+		"migration versions.\nExamples: migrate status, migrate status --json"
+}
+
+func (c *MigrateStatusCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.BoolVar(&c.jsonOutput, "json", false, "Alias of --format=json, for deploy pipelines")
+	flagSet.BoolVar(
+		&c.archived,
+		"archived",
+		false,
+		"Also list the applied migrations whose executions are in the executions archive",
+	)
+}
+
+func (c *MigrateStatusCommand) ValidateFlags() error {
+	if c.jsonOutput {
+		c.format = FormatJson
+	}
+	return c.outputFlags.ValidateFlags()
+}
+
+func (c *MigrateStatusCommand) Exec(stdWriter io.Writer) error {
+	executions, archived, err := loadExecutions(c.repository)
+	if err != nil {
+		return fmt.Errorf("failed to load executions with error: %w", err)
+	}
+
+	report := newStatusReport(c.registry.OrderedMigrations(), executions)
+	if !c.archived && len(archived) > 0 {
+		applied := report.Applied
+		report.Applied = slices.DeleteFunc(slices.Clone(applied), func(mig MigrationReport) bool {
+			return archived[mig.Version]
+		})
+		report.Archived = len(applied) - len(report.Applied)
+	}
+
+	if formatter, ok := c.output().(StatusFormatter); ok {
+		return formatter.FormatStatus(stdWriter, report)
+	}
+	return (&TextFormatter{}).FormatStatus(stdWriter, report)
+}
+
+func (f *TextFormatter) FormatStatus(w io.Writer, report StatusReport) error {
+	_, _ = fmt.Fprintf(w, "Applied migrations: %d\n", len(report.Applied))
+	for _, mig := range report.Applied {
+		_, _ = fmt.Fprintf(w, "  %s\n", f.paint(colorGreen, migrationLabel(&mig)))
+	}
+	if report.Archived > 0 {
+		_, _ = fmt.Fprintf(
+			w, "Archived applied migrations: %d (listed with --archived)\n", report.Archived,
+		)
+	}
+
+	_, _ = fmt.Fprintf(w, "Pending migrations: %d\n", len(report.Pending))
+	for _, mig := range report.Pending {
+		if slices.Contains(report.Failed, mig.Version) {
+			_, _ = fmt.Fprintf(w, "  %s\n", f.paint(colorRed, migrationLabel(&mig)))
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "  %s\n", f.paint(colorYellow, migrationLabel(&mig)))
+	}
+
+	_, err := fmt.Fprintf(w, "Unknown executed versions: %d\n", len(report.Unknown))
+	for _, version := range report.Unknown {
+		_, err = fmt.Fprintf(w, "  %s\n", f.paint(colorRed, strconv.FormatUint(version, 10)))
+	}
+	return err
+}
+
+func (f *JsonFormatter) FormatStatus(w io.Writer, report StatusReport) error {
+	return json.NewEncoder(w).Encode(report)
+}
+
+func (f *TableFormatter) FormatStatus(w io.Writer, report StatusReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "VERSION\tFILE\tSTATUS\tDESCRIPTION")
+	for _, mig := range report.Applied {
+		_, _ = fmt.Fprintf(
+			tw, "%d\t%s\tapplied\t%s\n", mig.Version, mig.File, metadataCell(mig.Metadata),
+		)
+	}
+	for _, mig := range report.Pending {
+		_, _ = fmt.Fprintf(
+			tw, "%d\t%s\tpending\t%s\n", mig.Version, mig.File, metadataCell(mig.Metadata),
+		)
+	}
+	for _, version := range report.Unknown {
+		_, _ = fmt.Fprintf(tw, "%d\t\tunknown\t\n", version)
+	}
+	return tw.Flush()
+}
+
+func (f *QuietFormatter) FormatStatus(io.Writer, StatusReport) error { return nil }
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// SummaryFormatter is an optional interface for the formatters which render the result of the
+// summary command. The output of the formatters which don't implement it is rendered by the
+// TextFormatter.
+type SummaryFormatter interface {
+	FormatSummary(w io.Writer, summary migration.RegistrySummary) error
+}
+
+// SummaryCommand implements the Command interface to report the composition of the registry
+// (see migration.Summarize): the migrations per year and month, the largest gaps between the
+// versions and the migrations per tag, to plan squashes and audits. It doesn't read the
+// executions.
+type SummaryCommand struct {
+	outputFlags
+	gaps     int
+	registry migration.MigrationsRegistry
+}
+
+func (c *SummaryCommand) Id() string {
+	return "summary"
+}
+
+func (c *SummaryCommand) Description() string {
+	return "Reports the registered migrations per year, month and tag, and the largest gaps " +
+		"between their versions.\nExamples: migrate summary, migrate summary --gaps=10 " +
+		"--format=json"
+}
+
+func (c *SummaryCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.IntVar(&c.gaps, "gaps", 5, "Number of the largest gaps between versions to report")
+}
+
+func (c *SummaryCommand) ValidateFlags() error {
+	if c.gaps < 0 {
+		return errors.New("the number of gaps must not be negative")
+	}
+	return c.outputFlags.ValidateFlags()
+}
+
+func (c *SummaryCommand) Exec(stdWriter io.Writer) error {
+	summary := migration.Summarize(c.registry, c.gaps)
+	if formatter, ok := c.output().(SummaryFormatter); ok {
+		return formatter.FormatSummary(stdWriter, summary)
+	}
+	return (&TextFormatter{}).FormatSummary(stdWriter, summary)
+}
+
+func (f *TextFormatter) FormatSummary(w io.Writer, summary migration.RegistrySummary) error {
+	_, _ = fmt.Fprintf(w, "Registered migrations: %d\n", summary.Count)
+	if summary.Count == 0 {
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(w, "Versions: %d - %d\n", summary.FirstVersion, summary.LastVersion)
+	_, _ = fmt.Fprintln(w, "Per year:")
+	for _, count := range summary.PerYear {
+		_, _ = fmt.Fprintf(w, "  %s: %d\n", count.Period, count.Count)
+	}
+	_, _ = fmt.Fprintln(w, "Per month:")
+	for _, count := range summary.PerMonth {
+		_, _ = fmt.Fprintf(w, "  %s: %d\n", count.Period, count.Count)
+	}
+	if summary.Undated > 0 {
+		_, _ = fmt.Fprintf(w, "Undated (non timestamp) versions: %d\n", summary.Undated)
+	}
+
+	_, _ = fmt.Fprintln(w, "Largest gaps:")
+	for _, gap := range summary.LargestGaps {
+		_, _ = fmt.Fprintf(w, "  %d -> %d: %s\n", gap.From, gap.To, gapSize(gap))
+	}
+
+	_, _ = fmt.Fprintln(w, "Per tag:")
+	for _, tag := range slices.Sorted(maps.Keys(summary.PerTag)) {
+		_, _ = fmt.Fprintf(w, "  %s: %d\n", tag, summary.PerTag[tag])
+	}
+	_, err := fmt.Fprintf(w, "Untagged: %d\n", summary.Untagged)
+	return err
+}
+
+func (f *JsonFormatter) FormatSummary(w io.Writer, summary migration.RegistrySummary) error {
+	return json.NewEncoder(w).Encode(summary)
+}
+
+func (f *TableFormatter) FormatSummary(w io.Writer, summary migration.RegistrySummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "GROUP\tKEY\tVALUE")
+	_, _ = fmt.Fprintf(tw, "total\t\t%d\n", summary.Count)
+	for _, count := range summary.PerYear {
+		_, _ = fmt.Fprintf(tw, "year\t%s\t%d\n", count.Period, count.Count)
+	}
+	for _, count := range summary.PerMonth {
+		_, _ = fmt.Fprintf(tw, "month\t%s\t%d\n", count.Period, count.Count)
+	}
+	if summary.Undated > 0 {
+		_, _ = fmt.Fprintf(tw, "undated\t\t%d\n", summary.Undated)
+	}
+	for _, gap := range summary.LargestGaps {
+		_, _ = fmt.Fprintf(tw, "gap\t%d -> %d\t%s\n", gap.From, gap.To, gapSize(gap))
+	}
+	for _, tag := range slices.Sorted(maps.Keys(summary.PerTag)) {
+		_, _ = fmt.Fprintf(tw, "tag\t%s\t%d\n", tag, summary.PerTag[tag])
+	}
+	_, _ = fmt.Fprintf(tw, "untagged\t\t%d\n", summary.Untagged)
+	return tw.Flush()
+}
+
+func (f *QuietFormatter) FormatSummary(io.Writer, migration.RegistrySummary) error { return nil }
+
+// gapSize renders the size of the gap in days when both versions are timestamps
+func gapSize(gap migration.VersionGap) string {
+	if !migration.IsDatedVersion(gap.From) {
+		return strconv.FormatUint(gap.Size, 10)
+	}
+	return fmt.Sprintf("%.1f days", float64(gap.Size)/(24*time.Hour).Seconds())
+}
@@ -0,0 +1,11 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package cli
+
+import "os"
+
+// isTerminal reports no terminal on the platforms where it can't be detected, so the
+// prompts which require one are skipped
+func isTerminal(*os.File) bool {
+	return false
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// ValidateCommand implements the Command interface to check if the migration files from the
+// migrations directory and the registered migrations match, without versions declared by
+// several migrations nor invalid hotfixes, and lists the hotfixes (see migration.Hotfix).
+// Unlike migration.DirMigrationsRegistry.AssertValidRegistry, it reports the divergences and
+// fails with a non-zero exit code, so it is suitable for CI pipelines.
+type ValidateCommand struct {
+	outputFlags
+	registry      migration.MigrationsRegistry
+	migrationsDir migration.MigrationsDirPath
+}
+
+func (c *ValidateCommand) Id() string {
+	return "validate"
+}
+
+func (c *ValidateCommand) Description() string {
+	return "Checks if the migration files and the registered migrations match, failing if " +
+		"they diverge. Build the registry with migration.NewUncheckedAutoDirMigrationsRegistry " +
+		"so the divergences are reported instead of panicking.\n" +
+		"Examples: migrate validate"
+}
+
+func (c *ValidateCommand) Exec(stdWriter io.Writer) error {
+	// the registered migrations are checked against the migrations directory of the cli,
+	// whatever the registry implementation
+	dirRegistry := migration.NewEmptyDirMigrationsRegistry(c.migrationsDir)
+	for _, mig := range c.registry.OrderedMigrations() {
+		if err := dirRegistry.Register(mig); err != nil {
+			return fmt.Errorf("failed to check migration %d with error: %w", mig.Version(), err)
+		}
+	}
+
+	err := dirRegistry.Validate()
+	registryErr := &migration.RegistryError{}
+	if err != nil && !errors.As(err, &registryErr) {
+		return err
+	}
+
+	// the hotfixes are reported too, since they can't be rolled back (see
+	// migration.Irreversible), and the ones fixing no earlier registered version fail the
+	// validation
+	var hotfixes, invalidHotfixes []string
+	for _, mig := range c.registry.OrderedMigrations() {
+		fixed, ok := migration.FixedVersionOf(mig)
+		if !ok {
+			continue
+		}
+		if hotfixErr := migration.ValidateHotfix(c.registry, mig); hotfixErr != nil {
+			invalidHotfixes = append(invalidHotfixes, hotfixErr.Error())
+			continue
+		}
+		hotfixes = append(hotfixes, fmt.Sprintf("%d fixes %d", mig.Version(), fixed))
+	}
+
+	var hotfixesMsg string
+	if len(hotfixes) > 0 {
+		hotfixesMsg = fmt.Sprintf(
+			"\nHotfix migrations, which can't be rolled back: %d\n  %s",
+			len(hotfixes), strings.Join(hotfixes, "\n  "),
+		)
+	}
+
+	// the quarantined versions (see migration.CollisionQuarantine) are reported too
+	collisions := dirRegistry.Collisions()
+	if err == nil && len(collisions) == 0 && len(invalidHotfixes) == 0 {
+		return c.output().FormatMessage(
+			stdWriter,
+			fmt.Sprintf("All the %d migrations are registered", dirRegistry.Count())+hotfixesMsg,
+		)
+	}
+
+	quarantined := make([]string, 0, len(collisions))
+	for _, collision := range collisions {
+		quarantined = append(quarantined, collision.String())
+	}
+
+	var msg strings.Builder
+	for _, files := range []struct {
+		label string
+		names []string
+	}{
+		{"Migration files not registered", registryErr.NotRegistered},
+		{"Registered migrations without a file", registryErr.Extra},
+		{"Migration versions declared by several migrations", quarantined},
+		{"Invalid hotfix migrations", invalidHotfixes},
+	} {
+		msg.WriteString(fmt.Sprintf("%s: %d\n", files.label, len(files.names)))
+		for _, name := range files.names {
+			msg.WriteString("  " + name + "\n")
+		}
+	}
+	_ = c.output().FormatMessage(stdWriter, strings.TrimSuffix(msg.String(), "\n")+hotfixesMsg)
+
+	return &ValidationError{
+		Err: errors.New("the migration files and the registered migrations diverge"),
+	}
+}
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// modulePath is the path of this library module, used to find its version in the build info
+const modulePath = "github.com/golibry/go-migrations"
+
+// unknownVersion is reported when the version of the library can't be determined
+const unknownVersion = "(devel)"
+
+// libraryVersion returns the version of this library the binary was built with, or
+// unknownVersion (for example, when built from a local checkout)
+func libraryVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return unknownVersion
+	}
+
+	modules := append([]*debug.Module{&info.Main}, info.Deps...)
+	for _, module := range modules {
+		if module.Path != modulePath {
+			continue
+		}
+
+		if module.Replace != nil && module.Replace.Version != "" {
+			return module.Replace.Version
+		}
+		if module.Version != "" {
+			return module.Version
+		}
+	}
+
+	return unknownVersion
+}
+
+// versionFlagAlias maps the --version flag, given instead of a command, to the version command
+func versionFlagAlias(args []string) []string {
+	cmdArgs := args
+	if len(cmdArgs) > 0 && cmdArgs[0] == "--" {
+		cmdArgs = cmdArgs[1:]
+	}
+
+	if len(cmdArgs) > 0 && cmdArgs[0] == "--version" {
+		return append([]string{(&VersionCommand{}).Id()}, cmdArgs[1:]...)
+	}
+	return args
+}
+
+// storedSchemaVersion returns the schema version recorded by the repository, 0 if none is
+// recorded or the repository does not implement execution.SchemaVersioner
+func storedSchemaVersion(repository execution.Repository) (int, error) {
+	versioner, ok := repository.(execution.SchemaVersioner)
+	if !ok {
+		return 0, nil
+	}
+
+	stored, err := versioner.StoredSchemaVersion()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load the stored schema version with error: %w", err)
+	}
+	return stored, nil
+}
+
+// CurrentVersion is the result of the version command with the --current flag
+type CurrentVersion struct {
+	// Version is the highest applied version, 0 if none is applied
+	Version uint64 `json:"version"`
+
+	// AppliedAt is the time the version was applied, set only with the --timestamp flag
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// CurrentVersionFormatter is an optional interface for the formatters which render the result
+// of the version command with the --current flag. The output of the formatters which don't
+// implement it is rendered by the TextFormatter.
+type CurrentVersionFormatter interface {
+	FormatCurrentVersion(w io.Writer, current CurrentVersion) error
+}
+
+// VersionCommand implements the Command interface to display the library version, the
+// executions schema version (supported and stored) and the registry statistics, to check the
+// compatibility between the running binary and the executions storage. With the --current
+// flag, it only prints the highest applied version, for the health dashboards and the deploy
+// scripts checking that the database matches the release.
+type VersionCommand struct {
+	outputFlags
+	current    bool
+	timestamp  bool
+	registry   migration.MigrationsRegistry
+	repository execution.Repository
+}
+
+func (c *VersionCommand) Id() string {
+	return "version"
+}
+
+func (c *VersionCommand) Description() string {
+	return "Displays the library version, the executions schema version and the registry " +
+		"statistics, or only the highest applied version.\n" +
+		"Examples: migrate version, migrate --version, migrate version --current --timestamp"
+}
+
+func (c *VersionCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.BoolVar(
+		&c.current,
+		"current",
+		false,
+		"Only print the highest applied version (0 if none is applied)",
+	)
+	flagSet.BoolVar(
+		&c.timestamp,
+		"timestamp",
+		false,
+		"Print the time the highest applied version was applied too (with --current)",
+	)
+}
+
+func (c *VersionCommand) ValidateFlags() error {
+	if c.timestamp && !c.current {
+		return errors.New("the timestamp flag can only be used with the current flag")
+	}
+	return c.outputFlags.ValidateFlags()
+}
+
+func (c *VersionCommand) Exec(stdWriter io.Writer) error {
+	if c.current {
+		return c.printCurrent(stdWriter)
+	}
+
+	_, _ = fmt.Fprintf(stdWriter, "Library version: %s\n", libraryVersion())
+	_, _ = fmt.Fprintf(
+		stdWriter, "Supported executions schema version: %d\n", execution.SchemaVersion,
+	)
+
+	if _, ok := c.repository.(execution.SchemaVersioner); ok {
+		stored, err := storedSchemaVersion(c.repository)
+		if err != nil {
+			return err
+		}
+
+		if stored == 0 {
+			_, _ = fmt.Fprintln(stdWriter, "Stored executions schema version: not recorded")
+		} else {
+			_, _ = fmt.Fprintf(stdWriter, "Stored executions schema version: %d\n", stored)
+		}
+	}
+
+	executions, err := c.repository.LoadExecutions()
+	if err != nil {
+		return fmt.Errorf("failed to load executions with error: %w", err)
+	}
+
+	report := newStatusReport(c.registry.OrderedMigrations(), executions)
+	_, _ = fmt.Fprintf(stdWriter, "Registered migrations: %d\n", c.registry.Count())
+	_, _ = fmt.Fprintf(stdWriter, "Applied migrations: %d\n", len(report.Applied))
+	_, _ = fmt.Fprintf(stdWriter, "Pending migrations: %d\n", len(report.Pending))
+	_, err = fmt.Fprintf(stdWriter, "Unknown executed versions: %d\n", len(report.Unknown))
+	return err
+}
+
+// printCurrent prints the highest applied version, with the time it was applied if requested
+func (c *VersionCommand) printCurrent(stdWriter io.Writer) error {
+	executions, err := c.repository.LoadExecutions()
+	if err != nil {
+		return fmt.Errorf("failed to load executions with error: %w", err)
+	}
+
+	var current CurrentVersion
+	var appliedAtMs uint64
+	for _, exec := range executions {
+		if exec.Finished() && exec.Version >= current.Version {
+			current.Version, appliedAtMs = exec.Version, exec.FinishedAtMs
+		}
+	}
+	if c.timestamp && current.Version != 0 {
+		appliedAt := time.UnixMilli(int64(appliedAtMs)).UTC()
+		current.AppliedAt = &appliedAt
+	}
+
+	if formatter, ok := c.output().(CurrentVersionFormatter); ok {
+		return formatter.FormatCurrentVersion(stdWriter, current)
+	}
+	return (&TextFormatter{}).FormatCurrentVersion(stdWriter, current)
+}
+
+func (f *TextFormatter) FormatCurrentVersion(w io.Writer, current CurrentVersion) error {
+	if current.AppliedAt == nil {
+		_, err := fmt.Fprintln(w, current.Version)
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "%d %s\n", current.Version, current.AppliedAt.Format(time.RFC3339))
+	return err
+}
+
+func (f *JsonFormatter) FormatCurrentVersion(w io.Writer, current CurrentVersion) error {
+	return json.NewEncoder(w).Encode(current)
+}
+
+func (f *QuietFormatter) FormatCurrentVersion(io.Writer, CurrentVersion) error { return nil }
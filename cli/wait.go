@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// The bounds of the delay between the attempts of waitForRepository, which doubles after each
+// failed attempt
+const (
+	minWaitBackoff = 100 * time.Millisecond
+	maxWaitBackoff = 5 * time.Second
+)
+
+// waitForRepository waits until the database is reachable (if db has a PingContext method,
+// like *sql.DB) and the repository initializes, retrying with an exponential backoff for at
+// most the timeout. The read-only repositories don't initialize anything, so only the ping is
+// retried for them. Each failed attempt is logged at the warn level.
+func waitForRepository(
+	ctx context.Context,
+	db any,
+	repository execution.Repository,
+	timeout time.Duration,
+) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := minWaitBackoff
+	for attempt := 1; ; attempt++ {
+		var err error
+		if pinger, ok := db.(pingContexter); ok {
+			err = pinger.PingContext(waitCtx)
+		}
+		if err == nil {
+			if err = repository.Init(); err == nil {
+				return nil
+			}
+		}
+
+		migration.LoggerFrom(ctx).WarnContext(
+			ctx, "database not ready", "attempt", attempt, "retry_in", backoff, "error", err,
+		)
+
+		select {
+		case <-waitCtx.Done():
+			return fmt.Errorf(
+				"the database was not ready within %s: %w",
+				timeout, errors.Join(waitCtx.Err(), err),
+			)
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxWaitBackoff)
+	}
+}
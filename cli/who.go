@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/lock"
+)
+
+// WhoReport is the result of the who command
+type WhoReport struct {
+	// Active is true if a run appears to be in progress: the lock of the exclusive runs is
+	// held by a live process or, when the lock can't be inspected, an execution is unfinished
+	Active bool `json:"active"`
+
+	// Lock is the state of the lock of the exclusive runs, nil if the runs are not exclusive
+	// or the locker can't be inspected (see lock.Breaker)
+	Lock *LockReport `json:"lock,omitempty"`
+
+	// Unfinished holds the unfinished executions: the ones of the run in progress, or of the
+	// runs which failed or were interrupted
+	Unfinished []UnfinishedReport `json:"unfinished"`
+}
+
+// LockReport describes the lock of the exclusive runs
+type LockReport struct {
+	Held bool `json:"held"`
+
+	// Stale is true if the lock is held by a process which is not running anymore
+	Stale bool `json:"stale,omitempty"`
+
+	// Holder is the process which holds (or last held) the lock, nil if unknown
+	Holder *lock.Holder `json:"holder,omitempty"`
+
+	// HeldForMs is the time elapsed since the lock was acquired, if it is held by a known
+	// holder
+	HeldForMs int64 `json:"heldForMs,omitempty"`
+}
+
+// UnfinishedReport describes an unfinished execution
+type UnfinishedReport struct {
+	Version   uint64    `json:"version"`
+	StartedAt time.Time `json:"startedAt"`
+	RunId     string    `json:"runId,omitempty"`
+
+	// RunningForMs is the time elapsed since the execution started
+	RunningForMs int64 `json:"runningForMs"`
+}
+
+// WhoFormatter is an optional interface for the formatters which render the result of the who
+// command. The output of the formatters which don't implement it is rendered by the
+// TextFormatter.
+type WhoFormatter interface {
+	FormatWho(w io.Writer, report WhoReport) error
+}
+
+// WhoCommand implements the Command interface to tell whether a run appears to be in progress,
+// where (host, pid and run ID) and for how long, from the holder of the lock of the exclusive
+// runs and the unfinished executions
+type WhoCommand struct {
+	outputFlags
+	exitCode   bool
+	locker     lock.Locker
+	repository execution.Repository
+	ctx        context.Context
+}
+
+func (c *WhoCommand) Id() string {
+	return "who"
+}
+
+func (c *WhoCommand) Description() string {
+	return "Tells whether a run appears to be in progress, where and for how long, from the " +
+		"lock holder and the unfinished executions.\n" +
+		"Examples: migrate who, migrate who --format=json, migrate who --exit-code"
+}
+
+func (c *WhoCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.outputFlags.DefineFlags(flagSet)
+	flagSet.BoolVar(
+		&c.exitCode,
+		"exit-code",
+		false,
+		"Exit with a non-zero code if a run appears to be in progress",
+	)
+}
+
+func (c *WhoCommand) Exec(stdWriter io.Writer) error {
+	now := time.Now()
+	report := WhoReport{Unfinished: []UnfinishedReport{}}
+
+	if breaker, ok := c.locker.(lock.Breaker); ok {
+		info, err := breaker.Inspect(c.ctx)
+		if err != nil {
+			return fmt.Errorf("failed to inspect the lock with error: %w", err)
+		}
+
+		report.Lock = &LockReport{Held: info.Held, Stale: info.Stale, Holder: info.Holder}
+		if info.Held && info.Holder != nil {
+			report.Lock.HeldForMs = now.Sub(info.Holder.AcquiredAt).Milliseconds()
+		}
+	}
+
+	executions, err := c.repository.LoadExecutions()
+	if err != nil {
+		return fmt.Errorf("failed to load executions with error: %w", err)
+	}
+	for _, exec := range executions {
+		if exec.Finished() {
+			continue
+		}
+		startedAt := time.UnixMilli(int64(exec.ExecutedAtMs)).UTC()
+		report.Unfinished = append(
+			report.Unfinished,
+			UnfinishedReport{
+				Version: exec.Version, StartedAt: startedAt, RunId: exec.RunId,
+				RunningForMs: now.Sub(startedAt).Milliseconds(),
+			},
+		)
+	}
+	slices.SortFunc(report.Unfinished, func(a, b UnfinishedReport) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+
+	if report.Lock != nil {
+		report.Active = report.Lock.Held && !report.Lock.Stale
+	} else {
+		report.Active = len(report.Unfinished) > 0
+	}
+
+	if formatter, ok := c.output().(WhoFormatter); ok {
+		err = formatter.FormatWho(stdWriter, report)
+	} else {
+		err = (&TextFormatter{}).FormatWho(stdWriter, report)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.exitCode && report.Active {
+		return errors.New("a run appears to be in progress")
+	}
+	return nil
+}
+
+func (f *TextFormatter) FormatWho(w io.Writer, report WhoReport) error {
+	var msg strings.Builder
+	switch {
+	case report.Active:
+		msg.WriteString("A run appears to be in progress\n")
+	case len(report.Unfinished) > 0:
+		msg.WriteString(
+			"No run appears to be in progress, the unfinished executions were interrupted\n",
+		)
+	default:
+		msg.WriteString("No run appears to be in progress\n")
+	}
+
+	switch {
+	case report.Lock == nil:
+		msg.WriteString("  lock: not inspected (the runs are not exclusive, or the locker " +
+			"can't be inspected)\n")
+	case report.Lock.Held && report.Lock.Holder != nil:
+		holder := report.Lock.Holder
+		_, _ = fmt.Fprintf(
+			&msg, "  lock: held by pid %d on %s since %s (for %s)%s\n",
+			holder.Pid, holder.Hostname, holder.AcquiredAt.Format(time.RFC3339),
+			roundedMs(report.Lock.HeldForMs), runLabel(holder.RunId),
+		)
+		if report.Lock.Stale {
+			msg.WriteString("  the lock is stale, its holder process is not running anymore\n")
+		}
+	case report.Lock.Held:
+		msg.WriteString("  lock: held by an unknown process\n")
+	default:
+		msg.WriteString("  lock: not held\n")
+	}
+
+	for _, unfinished := range report.Unfinished {
+		_, _ = fmt.Fprintf(
+			&msg, "  version %d: unfinished, started at %s (%s ago)%s\n",
+			unfinished.Version, unfinished.StartedAt.Format(time.RFC3339),
+			roundedMs(unfinished.RunningForMs), runLabel(unfinished.RunId),
+		)
+	}
+
+	_, err := io.WriteString(w, msg.String())
+	return err
+}
+
+func (f *JsonFormatter) FormatWho(w io.Writer, report WhoReport) error {
+	return json.NewEncoder(w).Encode(report)
+}
+
+func (f *QuietFormatter) FormatWho(io.Writer, WhoReport) error { return nil }
+
+// roundedMs returns the duration of the milliseconds, rounded to the second
+func roundedMs(ms int64) time.Duration {
+	return (time.Duration(ms) * time.Millisecond).Round(time.Second)
+}
+
+// runLabel describes the run with the ID, if known
+func runLabel(runId string) string {
+	if runId == "" {
+		return ""
+	}
+	return ", run " + runId
+}
@@ -0,0 +1,241 @@
+// Package composite runs the migrations of several heterogeneous stores (for example, a
+// PostgreSQL schema change, a MongoDB backfill and an Elasticsearch reindex of the same
+// feature) as a single logical run. Each store has its own registry, repository and handler,
+// while the migrations of all the stores are executed in a unified version order, one at a
+// time, and reported in a combined summary.
+//
+// The versions must be unique across the stores, so the order of the run is unambiguous. The
+// run stops at the first failure, since the later migrations, from any store, may depend on
+// the failed one. The stores are not updated atomically: the migrations executed before the
+// failure stay executed, and running again resumes from the failed migration.
+package composite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/lock"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// Store is a migrated backend with its own registry and executions state
+type Store struct {
+	Name    string
+	Handler *handler.MigrationsHandler
+}
+
+// Step is a migration of a store, planned by a composite run
+type Step struct {
+	Store     string
+	Migration migration.Migration
+}
+
+// ExecutedStep is a migration of a store handled by a composite run
+type ExecutedStep struct {
+	Store string
+	handler.ExecutedMigration
+}
+
+// Summary is the combined result of a composite run
+type Summary struct {
+	// Direction is handler.DirectionUp or handler.DirectionDown
+	Direction string
+
+	// Executed holds the handled migrations of all the stores, in the order they were
+	// executed. The last one failed if the run failed.
+	Executed []ExecutedStep
+
+	// Remaining holds the planned migrations not handled because the run failed
+	Remaining []Step
+
+	Duration time.Duration
+}
+
+// CountByStore returns the number of handled migrations of each store
+func (s Summary) CountByStore() map[string]int {
+	counts := make(map[string]int)
+	for _, step := range s.Executed {
+		counts[step.Store]++
+	}
+	return counts
+}
+
+// Runner executes the migrations of the stores in a unified version order
+type Runner struct {
+	stores []Store
+	locker lock.Locker
+}
+
+// NewRunner builds a new Runner. The stores must have unique, non-empty names. locker guards
+// the whole run (usually with a lock name shared by the processes running the same stores);
+// it can be nil when the runs must not be locked.
+func NewRunner(stores []Store, locker lock.Locker) (*Runner, error) {
+	names := make(map[string]bool, len(stores))
+	for _, store := range stores {
+		switch {
+		case store.Name == "":
+			return nil, errors.New("failed to build composite runner, empty store name")
+		case store.Handler == nil:
+			return nil, fmt.Errorf(
+				"failed to build composite runner, the store %s has no handler", store.Name,
+			)
+		case names[store.Name]:
+			return nil, fmt.Errorf(
+				"failed to build composite runner, duplicated store %s", store.Name,
+			)
+		}
+		names[store.Name] = true
+	}
+
+	return &Runner{stores: stores, locker: locker}, nil
+}
+
+// PlanUp returns the next numOfRuns migrations Up would execute, across all the stores, in
+// version order, without executing anything
+func (r *Runner) PlanUp(numOfRuns handler.NumOfRuns) ([]Step, error) {
+	return r.plan(handler.DirectionUp, numOfRuns)
+}
+
+// PlanDown returns the last numOfRuns executed migrations Down would roll back, across all the
+// stores, in reverse version order, without executing anything
+func (r *Runner) PlanDown(numOfRuns handler.NumOfRuns) ([]Step, error) {
+	return r.plan(handler.DirectionDown, numOfRuns)
+}
+
+// Up executes Up() for the next numOfRuns migrations, across all the stores, in version order
+func (r *Runner) Up(ctx context.Context, numOfRuns handler.NumOfRuns) (Summary, error) {
+	return r.run(ctx, handler.DirectionUp, numOfRuns)
+}
+
+// Down executes Down() for the last numOfRuns executed migrations, across all the stores, in
+// reverse version order
+func (r *Runner) Down(ctx context.Context, numOfRuns handler.NumOfRuns) (Summary, error) {
+	return r.run(ctx, handler.DirectionDown, numOfRuns)
+}
+
+// plan merges the plans of the stores in version order (reverse version order, for down),
+// keeping the order of each store's own plan
+func (r *Runner) plan(direction string, numOfRuns handler.NumOfRuns) ([]Step, error) {
+	all := handler.NumOfRuns(math.MaxInt)
+	plans := make([][]migration.Migration, len(r.stores))
+	storeOf := make(map[uint64]string)
+	for i, store := range r.stores {
+		var err error
+		if direction == handler.DirectionDown {
+			plans[i], err = store.Handler.PlanDown(all)
+		} else {
+			plans[i], err = store.Handler.PlanUp(all, nil)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("store %s: %w", store.Name, err)
+		}
+
+		for _, mig := range plans[i] {
+			if other, ok := storeOf[mig.Version()]; ok {
+				return nil, fmt.Errorf(
+					"failed to plan the composite run, the version %d is registered in the "+
+						"stores %s and %s", mig.Version(), other, store.Name,
+				)
+			}
+			storeOf[mig.Version()] = store.Name
+		}
+	}
+
+	steps := []Step{}
+	for len(steps) < int(numOfRuns) {
+		next := -1
+		for i, planned := range plans {
+			if len(planned) == 0 {
+				continue
+			}
+			if next == -1 || precedes(direction, planned[0], plans[next][0]) {
+				next = i
+			}
+		}
+		if next == -1 {
+			break
+		}
+
+		steps = append(steps, Step{Store: r.stores[next].Name, Migration: plans[next][0]})
+		plans[next] = plans[next][1:]
+	}
+	return steps, nil
+}
+
+// precedes checks if the migration runs before the other one, in the given direction
+func precedes(direction string, mig migration.Migration, other migration.Migration) bool {
+	if direction == handler.DirectionDown {
+		return mig.Version() > other.Version()
+	}
+	return mig.Version() < other.Version()
+}
+
+// run executes the planned steps one at a time, with the handler of their store, and stops at
+// the first failure. All the stores share the run ID of the context (a new one is generated
+// if it has none).
+func (r *Runner) run(
+	ctx context.Context,
+	direction string,
+	numOfRuns handler.NumOfRuns,
+) (summary Summary, err error) {
+	ctx, _ = execution.EnsureRunId(ctx)
+	summary = Summary{Direction: direction, Executed: []ExecutedStep{}}
+	start := time.Now()
+	defer func() {
+		summary.Duration = time.Since(start)
+	}()
+
+	if r.locker != nil {
+		if err = r.locker.Lock(ctx); err != nil {
+			return summary, fmt.Errorf("failed to acquire composite run lock with error: %w", err)
+		}
+
+		defer func() {
+			err = errors.Join(err, r.locker.Unlock(context.WithoutCancel(ctx)))
+		}()
+	}
+
+	steps, err := r.plan(direction, numOfRuns)
+	if err != nil {
+		return summary, err
+	}
+
+	handlers := make(map[string]*handler.MigrationsHandler, len(r.stores))
+	for _, store := range r.stores {
+		handlers[store.Name] = store.Handler
+	}
+
+	for i, step := range steps {
+		var execs []handler.ExecutedMigration
+		if direction == handler.DirectionDown {
+			execs, err = handlers[step.Store].MigrateDown(ctx, 1)
+		} else {
+			execs, err = handlers[step.Store].MigrateUp(ctx, 1)
+		}
+
+		for _, executed := range execs {
+			summary.Executed = append(summary.Executed, ExecutedStep{step.Store, executed})
+		}
+
+		if err != nil {
+			summary.Remaining = steps[i+1:]
+			return summary, fmt.Errorf("store %s: %w", step.Store, err)
+		}
+
+		// the executions of the store were changed by another process since the plan
+		if len(execs) != 1 || execs[0].Migration.Version() != step.Migration.Version() {
+			summary.Remaining = steps[i:]
+			return summary, fmt.Errorf(
+				"store %s: the executions changed during the run, version %d was expected",
+				step.Store, step.Migration.Version(),
+			)
+		}
+	}
+
+	return summary, nil
+}
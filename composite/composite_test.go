@@ -0,0 +1,145 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type CompositeTestSuite struct {
+	suite.Suite
+}
+
+func TestCompositeTestSuite(t *testing.T) {
+	suite.Run(t, new(CompositeTestSuite))
+}
+
+// journaledMigration records the order of its calls, across the stores
+type journaledMigration struct {
+	migration.DummyMigration
+	journal *[]string
+	runIds  map[string]bool
+	err     error
+}
+
+func (m *journaledMigration) Up(ctx context.Context, _ any) error {
+	*m.journal = append(*m.journal, "up "+versionOf(m))
+	m.runIds[execution.RunIdFrom(ctx)] = true
+	return m.err
+}
+
+func (m *journaledMigration) Down(context.Context, any) error {
+	*m.journal = append(*m.journal, "down "+versionOf(m))
+	return m.err
+}
+
+func versionOf(mig migration.Migration) string {
+	return strconv.FormatUint(mig.Version(), 10)
+}
+
+// newStore builds a store with the given versions, recording their calls in the journal
+func (suite *CompositeTestSuite) newStore(
+	name string,
+	journal *[]string,
+	runIds map[string]bool,
+	versions ...uint64,
+) (Store, map[uint64]*journaledMigration) {
+	registry := migration.NewGenericRegistry()
+	migrations := make(map[uint64]*journaledMigration, len(versions))
+	for _, version := range versions {
+		mig := &journaledMigration{
+			DummyMigration: *migration.NewDummyMigration(version), journal: journal, runIds: runIds,
+		}
+		suite.Require().NoError(registry.Register(mig))
+		migrations[version] = mig
+	}
+
+	migrationsHandler, err := handler.NewHandler(registry, &execution.InMemoryRepository{}, nil)
+	suite.Require().NoError(err)
+	return Store{Name: name, Handler: migrationsHandler}, migrations
+}
+
+func (suite *CompositeTestSuite) TestItRunsTheMigrationsOfAllTheStoresInVersionOrder() {
+	var journal []string
+	runIds := make(map[string]bool)
+	sql, sqlMigrations := suite.newStore("postgres", &journal, runIds, 1, 4)
+	docs, docsMigrations := suite.newStore("mongo", &journal, runIds, 2, 3, 6)
+	search, _ := suite.newStore("elasticsearch", &journal, runIds, 5)
+	runner, err := NewRunner([]Store{sql, docs, search}, nil)
+	suite.Require().NoError(err)
+
+	planned, err := runner.PlanUp(handler.NumOfRuns(4))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(
+		[]Step{
+			{"postgres", sqlMigrations[1]}, {"mongo", docsMigrations[2]},
+			{"mongo", docsMigrations[3]}, {"postgres", sqlMigrations[4]},
+		},
+		planned,
+	)
+	suite.Assert().Empty(journal)
+
+	summary, err := runner.Up(context.Background(), handler.NumOfRuns(99999))
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{"up 1", "up 2", "up 3", "up 4", "up 5", "up 6"}, journal)
+	suite.Assert().Equal(handler.DirectionUp, summary.Direction)
+	suite.Assert().Len(summary.Executed, 6)
+	suite.Assert().Empty(summary.Remaining)
+	suite.Assert().Equal(
+		map[string]int{"postgres": 2, "mongo": 3, "elasticsearch": 1}, summary.CountByStore(),
+	)
+	suite.Assert().Len(runIds, 1)
+
+	journal = nil
+	summary, err = runner.Down(context.Background(), handler.NumOfRuns(3))
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{"down 6", "down 5", "down 4"}, journal)
+	suite.Assert().Equal(handler.DirectionDown, summary.Direction)
+	suite.Assert().Equal("mongo", summary.Executed[0].Store)
+}
+
+func (suite *CompositeTestSuite) TestItStopsTheRunAtTheFirstFailure() {
+	var journal []string
+	runIds := make(map[string]bool)
+	sql, _ := suite.newStore("postgres", &journal, runIds, 1, 3)
+	docs, docsMigrations := suite.newStore("mongo", &journal, runIds, 2)
+	migrationErr := errors.New("backfill failed")
+	docsMigrations[2].err = migrationErr
+	runner, err := NewRunner([]Store{sql, docs}, nil)
+	suite.Require().NoError(err)
+
+	summary, err := runner.Up(context.Background(), handler.NumOfRuns(99999))
+	suite.Assert().ErrorIs(err, migrationErr)
+	suite.Assert().ErrorContains(err, "store mongo:")
+	suite.Assert().Equal([]string{"up 1", "up 2"}, journal)
+	suite.Require().Len(summary.Executed, 2)
+	suite.Assert().False(summary.Executed[1].Execution.Finished())
+	suite.Require().Len(summary.Remaining, 1)
+	suite.Assert().Equal(uint64(3), summary.Remaining[0].Migration.Version())
+}
+
+func (suite *CompositeTestSuite) TestItRejectsAmbiguousStores() {
+	var journal []string
+	runIds := make(map[string]bool)
+	sql, _ := suite.newStore("postgres", &journal, runIds, 1, 2)
+	docs, _ := suite.newStore("mongo", &journal, runIds, 2)
+
+	_, err := NewRunner([]Store{sql, sql}, nil)
+	suite.Assert().ErrorContains(err, "duplicated store postgres")
+	_, err = NewRunner([]Store{{Name: "", Handler: sql.Handler}}, nil)
+	suite.Assert().ErrorContains(err, "empty store name")
+
+	runner, err := NewRunner([]Store{sql, docs}, nil)
+	suite.Require().NoError(err)
+	_, err = runner.Up(context.Background(), handler.NumOfRuns(1))
+	suite.Assert().ErrorContains(
+		err, "the version 2 is registered in the stores postgres and mongo",
+	)
+	suite.Assert().Empty(journal)
+}
@@ -0,0 +1,150 @@
+// Package drift compares the migrations state of two environments (for example, staging and
+// production): the applied versions and, optionally, the schema fingerprints, so the skew
+// between environments is caught before the release day.
+package drift
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/schemadiff"
+)
+
+// Environment is a migrated target whose state can be compared
+type Environment struct {
+	// Repository holds the executions of the environment
+	Repository execution.Repository
+
+	// Schema is the optional source of the environment schema. The schemas are compared only
+	// when both environments have one.
+	Schema schemadiff.Source
+}
+
+// Report describes the divergences between two environments
+type Report struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// OnlyInFrom holds the versions applied (finished) in From but not in To, in version order
+	OnlyInFrom []uint64 `json:"onlyInFrom"`
+
+	// OnlyInTo holds the versions applied (finished) in To but not in From, in version order
+	OnlyInTo []uint64 `json:"onlyInTo"`
+
+	// UnfinishedFrom holds the versions with an unfinished execution in From
+	UnfinishedFrom []uint64 `json:"unfinishedFrom"`
+
+	// UnfinishedTo holds the versions with an unfinished execution in To
+	UnfinishedTo []uint64 `json:"unfinishedTo"`
+
+	// SchemaCompared is true if the schemas of both environments were compared
+	SchemaCompared bool `json:"schemaCompared"`
+
+	// FromSchemaHash and ToSchemaHash are the schema fingerprints (see schemadiff.Hash), empty
+	// if the schemas were not compared
+	FromSchemaHash string `json:"fromSchemaHash,omitempty"`
+	ToSchemaHash   string `json:"toSchemaHash,omitempty"`
+}
+
+// Drifted checks if the environments diverge
+func (r Report) Drifted() bool {
+	return len(r.OnlyInFrom) > 0 || len(r.OnlyInTo) > 0 || len(r.UnfinishedFrom) > 0 ||
+		len(r.UnfinishedTo) > 0 || r.FromSchemaHash != r.ToSchemaHash
+}
+
+// SchemaDrifted checks if the schemas were compared and differ, even if the same versions
+// are applied (for example, after manual changes)
+func (r Report) SchemaDrifted() bool {
+	return r.SchemaCompared && r.FromSchemaHash != r.ToSchemaHash
+}
+
+// Compare compares the environments with the given names. The schemas are compared when both
+// environments have a schema source, without the tables named in ignoredTables (for example,
+// the migrations executions table).
+func Compare(
+	ctx context.Context,
+	fromName string,
+	from Environment,
+	toName string,
+	to Environment,
+	ignoredTables ...string,
+) (Report, error) {
+	report := Report{From: fromName, To: toName}
+
+	fromApplied, fromUnfinished, err := loadVersions(fromName, from)
+	if err != nil {
+		return report, err
+	}
+	toApplied, toUnfinished, err := loadVersions(toName, to)
+	if err != nil {
+		return report, err
+	}
+
+	report.OnlyInFrom = difference(fromApplied, toApplied)
+	report.OnlyInTo = difference(toApplied, fromApplied)
+	report.UnfinishedFrom, report.UnfinishedTo = fromUnfinished, toUnfinished
+
+	if from.Schema == nil || to.Schema == nil {
+		return report, nil
+	}
+
+	if report.FromSchemaHash, err = schemaHash(ctx, fromName, from, ignoredTables); err != nil {
+		return report, err
+	}
+	if report.ToSchemaHash, err = schemaHash(ctx, toName, to, ignoredTables); err != nil {
+		return report, err
+	}
+	report.SchemaCompared = true
+
+	return report, nil
+}
+
+// loadVersions returns the applied and the unfinished versions of the environment, in version
+// order
+func loadVersions(name string, env Environment) (applied, unfinished []uint64, err error) {
+	executions, err := env.Repository.LoadExecutions()
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"failed to load the executions of %s with error: %w", name, err,
+		)
+	}
+
+	applied, unfinished = []uint64{}, []uint64{}
+	for _, exec := range executions {
+		if exec.Finished() {
+			applied = append(applied, exec.Version)
+		} else {
+			unfinished = append(unfinished, exec.Version)
+		}
+	}
+
+	slices.Sort(applied)
+	slices.Sort(unfinished)
+	return applied, unfinished, nil
+}
+
+func schemaHash(
+	ctx context.Context,
+	name string,
+	env Environment,
+	ignoredTables []string,
+) (string, error) {
+	schema, err := env.Schema.Schema(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the schema of %s with error: %w", name, err)
+	}
+	return schemadiff.Hash(schema, ignoredTables...), nil
+}
+
+// difference returns the versions of a which are not in b
+func difference(a, b []uint64) []uint64 {
+	diff := []uint64{}
+	for _, version := range a {
+		if _, found := slices.BinarySearch(b, version); !found {
+			diff = append(diff, version)
+		}
+	}
+	return diff
+}
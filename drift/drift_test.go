@@ -0,0 +1,131 @@
+package drift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/schemadiff"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeSchemaSource returns a fixed schema, or an error
+type fakeSchemaSource struct {
+	schema schemadiff.Schema
+	err    error
+}
+
+func (s *fakeSchemaSource) Schema(context.Context) (schemadiff.Schema, error) {
+	return s.schema, s.err
+}
+
+type DriftTestSuite struct {
+	suite.Suite
+}
+
+func TestDriftTestSuite(t *testing.T) {
+	suite.Run(t, new(DriftTestSuite))
+}
+
+func newRepository(finished []uint64, unfinished ...uint64) *execution.InMemoryRepository {
+	repo := &execution.InMemoryRepository{}
+	for _, version := range finished {
+		repo.PersistedExecutions = append(
+			repo.PersistedExecutions,
+			execution.MigrationExecution{Version: version, ExecutedAtMs: 1, FinishedAtMs: 2},
+		)
+	}
+	for _, version := range unfinished {
+		repo.PersistedExecutions = append(
+			repo.PersistedExecutions,
+			execution.MigrationExecution{Version: version, ExecutedAtMs: 1},
+		)
+	}
+	return repo
+}
+
+func (suite *DriftTestSuite) TestItReportsTheVersionsDivergences() {
+	report, err := Compare(
+		context.Background(),
+		"staging", Environment{Repository: newRepository([]uint64{3, 1, 2}, 4)},
+		"production", Environment{Repository: newRepository([]uint64{1, 5})},
+	)
+
+	suite.Require().NoError(err)
+	suite.Assert().True(report.Drifted())
+	suite.Assert().Equal([]uint64{2, 3}, report.OnlyInFrom)
+	suite.Assert().Equal([]uint64{5}, report.OnlyInTo)
+	suite.Assert().Equal([]uint64{4}, report.UnfinishedFrom)
+	suite.Assert().Empty(report.UnfinishedTo)
+	suite.Assert().False(report.SchemaCompared)
+
+	report, err = Compare(
+		context.Background(),
+		"staging", Environment{Repository: newRepository([]uint64{1, 2})},
+		"production", Environment{Repository: newRepository([]uint64{2, 1})},
+	)
+	suite.Require().NoError(err)
+	suite.Assert().False(report.Drifted())
+}
+
+func (suite *DriftTestSuite) TestItComparesTheSchemas() {
+	schema := func(columnType string) *fakeSchemaSource {
+		return &fakeSchemaSource{
+			schema: schemadiff.Schema{
+				Dialect: schemadiff.DialectPostgres,
+				Tables: []schemadiff.Table{
+					{Name: "users", Columns: []schemadiff.Column{{Name: "id", Type: columnType}}},
+					{
+						Name:    "executions",
+						Columns: []schemadiff.Column{{Name: "id", Type: columnType}},
+					},
+				},
+			},
+		}
+	}
+
+	repo := newRepository([]uint64{1})
+	report, err := Compare(
+		context.Background(),
+		"staging", Environment{Repository: repo, Schema: schema("int4")},
+		"production", Environment{Repository: repo, Schema: schema("integer")},
+	)
+	suite.Require().NoError(err)
+	suite.Assert().True(report.SchemaCompared)
+	suite.Assert().NotEmpty(report.FromSchemaHash)
+	suite.Assert().False(report.Drifted())
+
+	report, err = Compare(
+		context.Background(),
+		"staging", Environment{Repository: repo, Schema: schema("integer")},
+		"production", Environment{Repository: repo, Schema: schema("bigint")},
+	)
+	suite.Require().NoError(err)
+	suite.Assert().True(report.SchemaDrifted())
+	suite.Assert().True(report.Drifted())
+
+	_, err = Compare(
+		context.Background(),
+		"staging", Environment{Repository: repo, Schema: schema("integer")},
+		"production", Environment{
+			Repository: repo, Schema: &fakeSchemaSource{err: errors.New("unreachable")},
+		},
+	)
+	suite.Assert().EqualError(
+		err, "failed to read the schema of production with error: unreachable",
+	)
+}
+
+func (suite *DriftTestSuite) TestItFailsWhenTheExecutionsCanNotBeLoaded() {
+	_, err := Compare(
+		context.Background(),
+		"staging", Environment{Repository: newRepository(nil)},
+		"production", Environment{
+			Repository: &execution.InMemoryRepository{LoadErr: errors.New("timeout")},
+		},
+	)
+	suite.Assert().EqualError(
+		err, "failed to load the executions of production with error: timeout",
+	)
+}
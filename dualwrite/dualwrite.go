@@ -0,0 +1,262 @@
+// Package dualwrite provides the dual-write window of the expand/contract data migrations: while
+// the old and the new app versions serve side by side, the writes of the old schema (for
+// example, a column being renamed) are copied to the new one.
+//
+// An expand migration opens the window: after its Up() (for example, adding and backfilling
+// the new column), it installs the Writer which copies the writes (SQL triggers, see
+// TriggerWriter, or a Mongo change-stream processor registered by a custom Writer). A follow-up
+// contract migration closes the window: it checks the window is still open (and, if the writer
+// is a Verifier, that no write was missed), removes the writer and runs its Up() (for example,
+// dropping the old column).
+//
+// Example (the window is usually a variable of the migrations package, shared by both files):
+//
+//	var emailWindow = dualwrite.NewWindow(
+//		"users_email",
+//		dualwrite.NewTriggerWriter(
+//			dualwrite.DialectMysql, "users_email_dw", "users",
+//			dualwrite.ColumnCopy{From: "mail", To: "email"},
+//		),
+//	)
+//
+//	// version_1712953077.go
+//	migration.Register(emailWindow.Open(&Migration1712953077{}))
+//
+//	// version_1712967000.go
+//	migration.Register(emailWindow.Close(&Migration1712967000{}))
+package dualwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// Writer copies the writes of the old schema to the new one while the dual-write window is
+// open. Its methods get the db passed to the migrations.
+type Writer interface {
+	// Install must start copying the writes. It must be idempotent: installing an installed
+	// writer must not fail, so a failed migration can be retried.
+	Install(ctx context.Context, db any) error
+
+	// Remove must stop copying the writes. Removing a writer which is not installed must not
+	// fail.
+	Remove(ctx context.Context, db any) error
+
+	// Installed must check if the writer is installed
+	Installed(ctx context.Context, db any) (bool, error)
+}
+
+// Verifier is an optional interface writers can implement to check that the new schema holds
+// all the writes of the old one, before the window is closed
+type Verifier interface {
+	// Verify must return an error if some writes of the old schema were not copied
+	Verify(ctx context.Context, db any) error
+}
+
+// ErrWindowNotOpen is returned by the closing migration Up() when the window was not opened
+// by an earlier migration, or its writer is no longer installed
+var ErrWindowNotOpen = errors.New("the dual-write window is not open")
+
+// Window is the dual-write window of an expand/contract migration pair. It is opened by one
+// migration (see Open) and closed by a later one (see Close).
+type Window struct {
+	name   string
+	writer Writer
+	opener migration.Migration
+}
+
+// NewWindow builds a new Window, whose writes are copied by the writer. The name identifies
+// the window in the errors.
+func NewWindow(name string, writer Writer) *Window {
+	return &Window{name: name, writer: writer}
+}
+
+// Name returns the name of the window
+func (w *Window) Name() string {
+	return w.name
+}
+
+// Open wraps the expand migration which opens the window. It must be called once per window.
+func (w *Window) Open(mig migration.Migration) *OpeningMigration {
+	w.opener = mig
+	return &OpeningMigration{mig, w}
+}
+
+// Close wraps the contract migration which closes the window. Its version must be greater
+// than the one of the opening migration. The migration is tagged with migration.TagContract,
+// so the blue/green deployment guard (see handler.WithDeploymentGuard) holds it back while
+// the old app versions still write to the old schema.
+func (w *Window) Close(mig migration.Migration) *ClosingMigration {
+	return &ClosingMigration{mig, w}
+}
+
+// OpeningMigration wraps a migration and opens the dual-write window after its Up() succeeds.
+//
+// It is a migration.Wrapper, so the wrapped migration keeps its optional interfaces (for
+// example, an Irreversible migration stays one), except CaptureCapable: the writer of the
+// window can't be captured.
+type OpeningMigration struct {
+	migration.Migration
+	window *Window
+}
+
+// Up runs the wrapped migration Up() and then installs the writer of the window. If the
+// install fails, the error is returned, so the execution is not marked as finished and can be
+// retried.
+func (m *OpeningMigration) Up(ctx context.Context, db any) error {
+	if err := m.Migration.Up(ctx, db); err != nil {
+		return err
+	}
+
+	if err := m.window.writer.Install(ctx, db); err != nil {
+		return fmt.Errorf(
+			"migration %d up() succeeded, but opening the dual-write window %s failed with "+
+				"error: %w", m.Version(), m.window.name, err,
+		)
+	}
+	return nil
+}
+
+// Down removes the writer of the window and then runs the wrapped migration Down()
+func (m *OpeningMigration) Down(ctx context.Context, db any) error {
+	if err := m.window.writer.Remove(ctx, db); err != nil {
+		return fmt.Errorf(
+			"failed to close the dual-write window %s before running migration %d down() "+
+				"with error: %w", m.window.name, m.Version(), err,
+		)
+	}
+	return m.Migration.Down(ctx, db)
+}
+
+// Unwrap implements the migration.Wrapper interface
+func (m *OpeningMigration) Unwrap() migration.Migration {
+	return m.Migration
+}
+
+// CaptureCapable implements the migration.CaptureCapable interface, always false, since the
+// writer would be installed while the statements are captured
+func (m *OpeningMigration) CaptureCapable() bool {
+	return false
+}
+
+// Metadata implements the migration.MetadataProvider interface, with the wrapped migration
+// metadata
+func (m *OpeningMigration) Metadata() migration.Metadata {
+	metadata, _ := migration.MetadataOf(m.Migration)
+	return metadata
+}
+
+// ClosingMigration wraps a migration and closes the dual-write window before its Up() runs.
+//
+// It is a migration.Wrapper, so the wrapped migration keeps its optional interfaces (for
+// example, an Irreversible migration stays one), except CaptureCapable: the writer of the
+// window can't be captured.
+type ClosingMigration struct {
+	migration.Migration
+	window *Window
+}
+
+// Up checks the window is open, verifies its writes (if the writer is a Verifier), removes
+// the writer and then runs the wrapped migration Up(). If Up() fails, the writer is installed
+// again, so the old app versions keep writing to both schemas.
+func (m *ClosingMigration) Up(ctx context.Context, db any) error {
+	if err := m.checkOpen(ctx, db); err != nil {
+		return err
+	}
+
+	if verifier, ok := m.window.writer.(Verifier); ok {
+		if err := verifier.Verify(ctx, db); err != nil {
+			return fmt.Errorf(
+				"the writes of the dual-write window %s failed the verification with "+
+					"error: %w", m.window.name, err,
+			)
+		}
+	}
+
+	if err := m.window.writer.Remove(ctx, db); err != nil {
+		return fmt.Errorf(
+			"failed to close the dual-write window %s before running migration %d up() "+
+				"with error: %w", m.window.name, m.Version(), err,
+		)
+	}
+
+	if err := m.Migration.Up(ctx, db); err != nil {
+		if reopenErr := m.window.writer.Install(ctx, db); reopenErr != nil {
+			return errors.Join(
+				err,
+				fmt.Errorf(
+					"failed to reopen the dual-write window %s with error: %w",
+					m.window.name, reopenErr,
+				),
+			)
+		}
+		return err
+	}
+	return nil
+}
+
+// Down runs the wrapped migration Down() and then opens the window again. The writes made
+// while the window was closed are not copied: backfill them in the wrapped Down() if needed.
+func (m *ClosingMigration) Down(ctx context.Context, db any) error {
+	if err := m.Migration.Down(ctx, db); err != nil {
+		return err
+	}
+
+	if err := m.window.writer.Install(ctx, db); err != nil {
+		return fmt.Errorf(
+			"migration %d down() succeeded, but reopening the dual-write window %s failed "+
+				"with error: %w", m.Version(), m.window.name, err,
+		)
+	}
+	return nil
+}
+
+// Metadata implements the migration.MetadataProvider interface, with the wrapped migration
+// metadata, tagged with migration.TagContract
+func (m *ClosingMigration) Metadata() migration.Metadata {
+	metadata, _ := migration.MetadataOf(m.Migration)
+	if !slices.Contains(metadata.Tags, migration.TagContract) {
+		metadata.Tags = append(slices.Clone(metadata.Tags), migration.TagContract)
+	}
+	return metadata
+}
+
+// Unwrap implements the migration.Wrapper interface
+func (m *ClosingMigration) Unwrap() migration.Migration {
+	return m.Migration
+}
+
+// CaptureCapable implements the migration.CaptureCapable interface, always false, since the
+// writer would be removed while the statements are captured
+func (m *ClosingMigration) CaptureCapable() bool {
+	return false
+}
+
+// checkOpen returns ErrWindowNotOpen if the window was not opened by an earlier migration or
+// its writer is not installed
+func (m *ClosingMigration) checkOpen(ctx context.Context, db any) error {
+	if m.window.opener == nil || m.window.opener.Version() >= m.Version() {
+		return fmt.Errorf(
+			"%w: %s must be opened by a migration older than %d",
+			ErrWindowNotOpen, m.window.name, m.Version(),
+		)
+	}
+
+	installed, err := m.window.writer.Installed(ctx, db)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to check the dual-write window %s with error: %w", m.window.name, err,
+		)
+	}
+	if !installed {
+		return fmt.Errorf(
+			"%w: the writer of %s (opened by migration %d) is not installed",
+			ErrWindowNotOpen, m.window.name, m.window.opener.Version(),
+		)
+	}
+	return nil
+}
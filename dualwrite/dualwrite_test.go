@@ -0,0 +1,206 @@
+package dualwrite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type DualWriteTestSuite struct {
+	suite.Suite
+}
+
+func TestDualWriteTestSuite(t *testing.T) {
+	suite.Run(t, new(DualWriteTestSuite))
+}
+
+type fakeMigration struct {
+	migration.DummyMigration
+	upErr error
+}
+
+func (f *fakeMigration) Up(_ context.Context, _ any) error {
+	return f.upErr
+}
+
+func (f *fakeMigration) Metadata() migration.Metadata {
+	return migration.Metadata{Ticket: "DB-1", Tags: []string{"users"}}
+}
+
+// fakeWriter records whether it is installed
+type fakeWriter struct {
+	installed  bool
+	installErr error
+	verifyErr  error
+}
+
+func (w *fakeWriter) Install(_ context.Context, _ any) error {
+	if w.installErr != nil {
+		return w.installErr
+	}
+	w.installed = true
+	return nil
+}
+
+func (w *fakeWriter) Remove(_ context.Context, _ any) error {
+	w.installed = false
+	return nil
+}
+
+func (w *fakeWriter) Installed(_ context.Context, _ any) (bool, error) {
+	return w.installed, nil
+}
+
+func (w *fakeWriter) Verify(_ context.Context, _ any) error {
+	return w.verifyErr
+}
+
+func (suite *DualWriteTestSuite) TestItOpensAndClosesTheWindow() {
+	ctx := context.Background()
+	writer := &fakeWriter{}
+	window := NewWindow("users_email", writer)
+	opening := window.Open(&fakeMigration{DummyMigration: *migration.NewDummyMigration(1)})
+	closingMig := &fakeMigration{DummyMigration: *migration.NewDummyMigration(2)}
+	closing := window.Close(closingMig)
+
+	suite.Assert().ErrorIs(closing.Up(ctx, nil), ErrWindowNotOpen)
+
+	suite.Require().NoError(opening.Up(ctx, nil))
+	suite.Assert().True(writer.installed)
+
+	writer.verifyErr = errors.New("1 rows differ")
+	suite.Assert().ErrorIs(closing.Up(ctx, nil), writer.verifyErr)
+	suite.Assert().True(writer.installed)
+
+	writer.verifyErr = nil
+	closingMig.upErr = errors.New("drop failed")
+	suite.Assert().ErrorIs(closing.Up(ctx, nil), closingMig.upErr)
+	suite.Assert().True(writer.installed, "the window must be reopened when Up() fails")
+
+	closingMig.upErr = nil
+	suite.Require().NoError(closing.Up(ctx, nil))
+	suite.Assert().False(writer.installed)
+
+	suite.Require().NoError(closing.Down(ctx, nil))
+	suite.Assert().True(writer.installed)
+
+	suite.Require().NoError(opening.Down(ctx, nil))
+	suite.Assert().False(writer.installed)
+}
+
+func (suite *DualWriteTestSuite) TestItRefusesToCloseTheWindowBeforeItIsOpened() {
+	writer := &fakeWriter{installed: true}
+	window := NewWindow("users_email", writer)
+	window.Open(&fakeMigration{DummyMigration: *migration.NewDummyMigration(3)})
+	closing := window.Close(&fakeMigration{DummyMigration: *migration.NewDummyMigration(2)})
+
+	suite.Assert().ErrorIs(closing.Up(context.Background(), nil), ErrWindowNotOpen)
+	suite.Assert().True(writer.installed)
+}
+
+func (suite *DualWriteTestSuite) TestItFailsTheOpeningWhenTheWriterCantBeInstalled() {
+	writer := &fakeWriter{installErr: errors.New("no privilege")}
+	opening := NewWindow("users_email", writer).Open(
+		&fakeMigration{DummyMigration: *migration.NewDummyMigration(1)},
+	)
+
+	suite.Assert().ErrorIs(opening.Up(context.Background(), nil), writer.installErr)
+}
+
+func (suite *DualWriteTestSuite) TestItTagsTheClosingMigrationAsContract() {
+	window := NewWindow("users_email", &fakeWriter{})
+	opening := window.Open(&fakeMigration{DummyMigration: *migration.NewDummyMigration(1)})
+	closing := window.Close(&fakeMigration{DummyMigration: *migration.NewDummyMigration(2)})
+
+	suite.Assert().False(migration.IsContract(opening))
+	suite.Assert().Equal("DB-1", opening.Metadata().Ticket)
+	suite.Assert().True(migration.IsContract(closing))
+	suite.Assert().Equal([]string{"users", migration.TagContract}, closing.Metadata().Tags)
+}
+
+// contractMigration drops the old column, so it can't be rolled back
+type contractMigration struct {
+	fakeMigration
+}
+
+func (m *contractMigration) Irreversible() error {
+	return errors.New("the mail column is dropped")
+}
+
+func (m *contractMigration) Transactional() bool {
+	return false
+}
+
+func (m *contractMigration) CaptureCapable() bool {
+	return true
+}
+
+func (suite *DualWriteTestSuite) TestItKeepsTheOptionalInterfacesOfTheWrappedMigrations() {
+	window := NewWindow("users_email", &fakeWriter{})
+	opening := window.Open(&contractMigration{
+		fakeMigration{DummyMigration: *migration.NewDummyMigration(1)},
+	})
+	closing := window.Close(&contractMigration{
+		fakeMigration{DummyMigration: *migration.NewDummyMigration(2)},
+	})
+
+	for _, mig := range []migration.Migration{opening, closing} {
+		suite.Assert().ErrorContains(
+			migration.ValidateReversible([]migration.Migration{mig}), "the mail column is dropped",
+		)
+
+		transactional, declared := migration.TransactionalOf(mig)
+		suite.Assert().True(declared)
+		suite.Assert().False(transactional)
+
+		// the writer would be installed or removed while capturing
+		suite.Assert().False(migration.IsCaptureCapable(mig))
+	}
+}
+
+func (suite *DualWriteTestSuite) TestItBuildsTheTriggersOfEachDialect() {
+	columns := []ColumnCopy{{From: "mail", To: "email"}, {From: "nick", To: "nickname"}}
+	scenarios := map[Dialect][]string{
+		DialectMysql: {
+			"CREATE TRIGGER users_dw_insert BEFORE INSERT ON users FOR EACH ROW " +
+				"SET NEW.email = NEW.mail, NEW.nickname = NEW.nick",
+			"CREATE TRIGGER users_dw_update BEFORE UPDATE ON users FOR EACH ROW " +
+				"SET NEW.email = NEW.mail, NEW.nickname = NEW.nick",
+		},
+		DialectPostgres: {
+			"CREATE OR REPLACE FUNCTION users_dw_fn() RETURNS trigger AS $$ BEGIN " +
+				"NEW.email := NEW.mail; NEW.nickname := NEW.nick; RETURN NEW; END; $$ " +
+				"LANGUAGE plpgsql",
+			"CREATE TRIGGER users_dw BEFORE INSERT OR UPDATE ON users FOR EACH ROW " +
+				"EXECUTE FUNCTION users_dw_fn()",
+		},
+		DialectSqlite: {
+			"CREATE TRIGGER users_dw_insert AFTER INSERT ON users FOR EACH ROW BEGIN " +
+				"UPDATE users SET email = NEW.mail, nickname = NEW.nick " +
+				"WHERE rowid = NEW.rowid; END",
+			"CREATE TRIGGER users_dw_update AFTER UPDATE OF mail, nick ON users FOR EACH ROW " +
+				"BEGIN UPDATE users SET email = NEW.mail, nickname = NEW.nick " +
+				"WHERE rowid = NEW.rowid; END",
+		},
+	}
+
+	for dialect, expected := range scenarios {
+		statements, err := NewTriggerWriter(dialect, "users_dw", "users", columns...).
+			installStatements()
+		suite.Require().NoError(err, dialect)
+		suite.Assert().Equal(expected, statements, dialect)
+	}
+
+	_, err := NewTriggerWriter("oracle", "users_dw", "users", columns...).installStatements()
+	suite.Assert().ErrorContains(err, "unsupported trigger dialect")
+	_, err = NewTriggerWriter(DialectMysql, "users_dw", "users").installStatements()
+	suite.Assert().ErrorContains(err, "copy no columns")
+	suite.Assert().ErrorContains(
+		NewTriggerWriter(DialectMysql, "users_dw", "users", columns...).
+			Install(context.Background(), "not a db"),
+		"need a Queryer db",
+	)
+}
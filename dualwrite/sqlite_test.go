@@ -0,0 +1,53 @@
+//go:build sqlite
+
+package dualwrite
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func (suite *DualWriteTestSuite) TestItCopiesTheWritesWithSqliteTriggers() {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	suite.Require().NoError(err)
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, mail TEXT, email TEXT)")
+	suite.Require().NoError(err)
+
+	writer := NewTriggerWriter(
+		DialectSqlite, "users_dw", "users", ColumnCopy{From: "mail", To: "email"},
+	)
+	installed, err := writer.Installed(ctx, db)
+	suite.Require().NoError(err)
+	suite.Assert().False(installed)
+
+	suite.Require().NoError(writer.Install(ctx, db))
+	suite.Require().NoError(writer.Install(ctx, db), "the install must be idempotent")
+	installed, err = writer.Installed(ctx, db)
+	suite.Require().NoError(err)
+	suite.Assert().True(installed)
+
+	_, err = db.Exec("INSERT INTO users (id, mail) VALUES (1, 'a@x.com'), (2, 'b@x.com')")
+	suite.Require().NoError(err)
+	_, err = db.Exec("UPDATE users SET mail = 'c@x.com' WHERE id = 2")
+	suite.Require().NoError(err)
+
+	var email string
+	suite.Require().NoError(db.QueryRow("SELECT email FROM users WHERE id = 2").Scan(&email))
+	suite.Assert().Equal("c@x.com", email)
+	suite.Assert().NoError(writer.Verify(ctx, db))
+
+	suite.Require().NoError(writer.Remove(ctx, db))
+	installed, err = writer.Installed(ctx, db)
+	suite.Require().NoError(err)
+	suite.Assert().False(installed)
+
+	_, err = db.Exec("UPDATE users SET mail = 'd@x.com' WHERE id = 1")
+	suite.Require().NoError(err)
+	suite.Assert().ErrorContains(writer.Verify(ctx, db), "1 rows of users")
+}
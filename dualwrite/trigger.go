@@ -0,0 +1,255 @@
+package dualwrite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/golibry/go-migrations/sqlhelper"
+)
+
+// Dialect is the SQL dialect of the triggers of a TriggerWriter
+type Dialect string
+
+const (
+	DialectMysql    Dialect = "mysql"
+	DialectPostgres Dialect = "postgres"
+	DialectSqlite   Dialect = "sqlite"
+)
+
+// Queryer is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type Queryer interface {
+	sqlhelper.Execer
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ColumnCopy is a column whose writes are copied to another column of the same table
+type ColumnCopy struct {
+	From string
+	To   string
+}
+
+// TriggerWriter is a Writer which copies the writes of columns to other columns of the same
+// table (for example, while a column is renamed or its type changed), with triggers on the
+// inserts and updates of the table. It is also a Verifier, which checks that the copied
+// columns hold the same values in all the rows. The db passed to the migrations must be a
+// Queryer (for example, *sql.DB).
+//
+// The statements are executed through the sqlhelper package, so they honor the capture mode.
+type TriggerWriter struct {
+	dialect Dialect
+	name    string
+	table   string
+	columns []ColumnCopy
+}
+
+// NewTriggerWriter builds a new TriggerWriter. The name prefixes the names of the triggers
+// (and of the trigger function, on Postgres), so it must be unique in the schema. The names
+// are used as given, quote them if needed.
+func NewTriggerWriter(
+	dialect Dialect,
+	name string,
+	table string,
+	columns ...ColumnCopy,
+) *TriggerWriter {
+	return &TriggerWriter{dialect: dialect, name: name, table: table, columns: columns}
+}
+
+// Install implements the Writer.Install method. The triggers are replaced if they exist.
+func (w *TriggerWriter) Install(ctx context.Context, db any) error {
+	queryer, err := w.queryer(db)
+	if err != nil {
+		return err
+	}
+
+	statements, err := w.installStatements()
+	if err != nil {
+		return err
+	}
+
+	return w.exec(ctx, queryer, append(w.removeStatements(), statements...))
+}
+
+// Remove implements the Writer.Remove method
+func (w *TriggerWriter) Remove(ctx context.Context, db any) error {
+	queryer, err := w.queryer(db)
+	if err != nil {
+		return err
+	}
+
+	return w.exec(ctx, queryer, w.removeStatements())
+}
+
+// Installed implements the Writer.Installed method. All the triggers must exist.
+func (w *TriggerWriter) Installed(ctx context.Context, db any) (bool, error) {
+	queryer, err := w.queryer(db)
+	if err != nil {
+		return false, err
+	}
+
+	var query string
+	var args []any
+	expected := 2
+	switch w.dialect {
+	case DialectMysql:
+		query = "SELECT COUNT(*) FROM information_schema.TRIGGERS " +
+			"WHERE TRIGGER_SCHEMA = DATABASE() AND TRIGGER_NAME IN (?, ?)"
+		args = []any{w.name + "_insert", w.name + "_update"}
+	case DialectPostgres:
+		query = "SELECT COUNT(*) FROM pg_trigger WHERE tgname = $1 AND tgrelid = to_regclass($2)"
+		args = []any{w.name, w.table}
+		expected = 1
+	case DialectSqlite:
+		query = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN (?, ?)"
+		args = []any{w.name + "_insert", w.name + "_update"}
+	default:
+		return false, w.unsupportedDialectErr()
+	}
+
+	var count int
+	if err := queryer.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to look up the triggers %s with error: %w", w.name, err)
+	}
+	return count == expected, nil
+}
+
+// Verify implements the Verifier.Verify method. It fails if the copied columns differ in any
+// row.
+func (w *TriggerWriter) Verify(ctx context.Context, db any) error {
+	queryer, err := w.queryer(db)
+	if err != nil {
+		return err
+	}
+
+	conditions := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		switch w.dialect {
+		case DialectMysql:
+			conditions[i] = fmt.Sprintf("NOT (%s <=> %s)", column.From, column.To)
+		case DialectSqlite:
+			conditions[i] = fmt.Sprintf("%s IS NOT %s", column.From, column.To)
+		default:
+			conditions[i] = fmt.Sprintf("%s IS DISTINCT FROM %s", column.From, column.To)
+		}
+	}
+
+	var count int64
+	query := fmt.Sprintf(
+		"SELECT COUNT(*) FROM %s WHERE %s", w.table, strings.Join(conditions, " OR "),
+	)
+	if err := queryer.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return fmt.Errorf("failed to compare the copied columns with error: %w", err)
+	}
+
+	if count > 0 {
+		return fmt.Errorf("%d rows of %s have copied columns which differ", count, w.table)
+	}
+	return nil
+}
+
+// installStatements builds the statements which create the triggers
+func (w *TriggerWriter) installStatements() ([]string, error) {
+	if len(w.columns) == 0 {
+		return nil, fmt.Errorf("the triggers %s copy no columns", w.name)
+	}
+
+	assignments := make([]string, len(w.columns))
+	sources := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		sources[i] = column.From
+		switch w.dialect {
+		case DialectMysql:
+			assignments[i] = fmt.Sprintf("NEW.%s = NEW.%s", column.To, column.From)
+		case DialectPostgres:
+			assignments[i] = fmt.Sprintf("NEW.%s := NEW.%s;", column.To, column.From)
+		default:
+			assignments[i] = fmt.Sprintf("%s = NEW.%s", column.To, column.From)
+		}
+	}
+
+	switch w.dialect {
+	case DialectMysql:
+		set := strings.Join(assignments, ", ")
+		return []string{
+			fmt.Sprintf(
+				"CREATE TRIGGER %s_insert BEFORE INSERT ON %s FOR EACH ROW SET %s",
+				w.name, w.table, set,
+			),
+			fmt.Sprintf(
+				"CREATE TRIGGER %s_update BEFORE UPDATE ON %s FOR EACH ROW SET %s",
+				w.name, w.table, set,
+			),
+		}, nil
+	case DialectPostgres:
+		return []string{
+			fmt.Sprintf(
+				"CREATE OR REPLACE FUNCTION %s_fn() RETURNS trigger AS $$ BEGIN %s RETURN NEW; "+
+					"END; $$ LANGUAGE plpgsql",
+				w.name, strings.Join(assignments, " "),
+			),
+			fmt.Sprintf(
+				"CREATE TRIGGER %s BEFORE INSERT OR UPDATE ON %s FOR EACH ROW "+
+					"EXECUTE FUNCTION %s_fn()",
+				w.name, w.table, w.name,
+			),
+		}, nil
+	case DialectSqlite:
+		// SQLite triggers can't change the NEW row, so the copy is made by an update of the
+		// written row. The update trigger fires only on writes of the copied columns, so it
+		// doesn't fire again on its own update.
+		update := fmt.Sprintf(
+			"UPDATE %s SET %s WHERE rowid = NEW.rowid", w.table, strings.Join(assignments, ", "),
+		)
+		return []string{
+			fmt.Sprintf(
+				"CREATE TRIGGER %s_insert AFTER INSERT ON %s FOR EACH ROW BEGIN %s; END",
+				w.name, w.table, update,
+			),
+			fmt.Sprintf(
+				"CREATE TRIGGER %s_update AFTER UPDATE OF %s ON %s FOR EACH ROW BEGIN %s; END",
+				w.name, strings.Join(sources, ", "), w.table, update,
+			),
+		}, nil
+	default:
+		return nil, w.unsupportedDialectErr()
+	}
+}
+
+// removeStatements builds the statements which drop the triggers, if they exist
+func (w *TriggerWriter) removeStatements() []string {
+	if w.dialect == DialectPostgres {
+		return []string{
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", w.name, w.table),
+			fmt.Sprintf("DROP FUNCTION IF EXISTS %s_fn()", w.name),
+		}
+	}
+
+	return []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_insert", w.name),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_update", w.name),
+	}
+}
+
+func (w *TriggerWriter) exec(ctx context.Context, queryer Queryer, statements []string) error {
+	for _, statement := range statements {
+		if _, err := sqlhelper.Exec(ctx, queryer, statement); err != nil {
+			return fmt.Errorf(
+				"failed to execute the statement %q with error: %w", statement, err,
+			)
+		}
+	}
+	return nil
+}
+
+func (w *TriggerWriter) queryer(db any) (Queryer, error) {
+	queryer, ok := db.(Queryer)
+	if !ok {
+		return nil, fmt.Errorf("the triggers %s need a Queryer db, got %T", w.name, db)
+	}
+	return queryer, nil
+}
+
+func (w *TriggerWriter) unsupportedDialectErr() error {
+	return fmt.Errorf("unsupported trigger dialect %q", w.dialect)
+}
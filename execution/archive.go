@@ -0,0 +1,313 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Archiver is an optional interface for repositories which can move their old executions to
+// an archive storage (see ArchivedRepository), to keep the executions storage small on
+// long-lived systems
+type Archiver interface {
+	// Archive moves the finished executions started before the cutoff to the archive storage
+	// and returns them
+	Archive(cutoff time.Time) ([]MigrationExecution, error)
+
+	// LoadHotExecutions is the Repository.LoadExecutions method, without the archived
+	// executions
+	LoadHotExecutions() ([]MigrationExecution, error)
+
+	// LoadArchivedExecutions loads only the archived executions
+	LoadArchivedExecutions() ([]MigrationExecution, error)
+}
+
+// ArchivedRepository combines the executions repository (the hot one) with an archive
+// repository (for example, a handler of another table or collection), which holds the
+// executions moved there by Archive. The archived executions stay applied: LoadExecutions and
+// FindOne include them, so the runs don't execute the archived versions again, and Remove
+// removes the executions from both. The executions are saved to the hot repository.
+//
+// LoadExecutions doesn't read the archive each time: the archived executions are kept from
+// the previous read, until executions leave the hot repository (archived or removed, maybe by
+// another process). The full history is read by LoadArchivedExecutions.
+//
+// Besides Archiver, it implements ContextRepository, BulkRepository, SchemaVersioner,
+// ReadOnlyChecker, InitDescriber, SaveDescriber and Targeter (with the hot repository),
+// RemoveDescriber, PermissionsChecker and Pinger (with both repositories), falling back to the
+// Repository methods, or to no descriptions, missing privileges or target, for the wrapped
+// repositories which don't implement them.
+type ArchivedRepository struct {
+	hot     Repository
+	archive Repository
+
+	// mu guards the archived executions kept by LoadExecutions, and the versions of the hot
+	// executions loaded along with them
+	mu          sync.Mutex
+	archived    []MigrationExecution
+	hotVersions map[uint64]bool
+}
+
+// NewArchivedRepository builds the repository of the executions of hot and archive
+func NewArchivedRepository(hot Repository, archive Repository) *ArchivedRepository {
+	return &ArchivedRepository{hot: hot, archive: archive}
+}
+
+// Init implements the Repository.Init method, initializing both repositories
+func (r *ArchivedRepository) Init() error {
+	if err := r.hot.Init(); err != nil {
+		return err
+	}
+	if err := r.archive.Init(); err != nil {
+		return fmt.Errorf("failed to init the executions archive with error: %w", err)
+	}
+	return nil
+}
+
+// LoadExecutions implements the Repository.LoadExecutions method, loading the archived
+// executions, then the hot ones. The archive is read by the first load, then only when the hot
+// repository lost executions since the previous load, or has no finished one, since the
+// executions archived elsewhere can only have left it. A version found in both (an
+// interrupted Archive) is only loaded from the hot repository.
+func (r *ArchivedRepository) LoadExecutions() ([]MigrationExecution, error) {
+	hot, err := r.hot.LoadExecutions()
+	if err != nil {
+		return nil, err
+	}
+
+	hotVersions := make(map[uint64]bool, len(hot))
+	hasFinished := false
+	for _, exec := range hot {
+		hotVersions[exec.Version] = true
+		hasFinished = hasFinished || exec.Finished()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.hotVersions == nil || !hasFinished || r.lostHotExecutions(hotVersions) {
+		if r.archived, err = r.loadArchive(); err != nil {
+			r.hotVersions = nil
+			return nil, err
+		}
+	}
+	r.hotVersions = hotVersions
+
+	executions := make([]MigrationExecution, 0, len(r.archived)+len(hot))
+	for _, exec := range r.archived {
+		if !hotVersions[exec.Version] {
+			executions = append(executions, exec)
+		}
+	}
+	return append(executions, hot...), nil
+}
+
+// lostHotExecutions checks if executions loaded from the hot repository by the previous
+// LoadExecutions are not among the given versions anymore
+func (r *ArchivedRepository) lostHotExecutions(hotVersions map[uint64]bool) bool {
+	for version := range r.hotVersions {
+		if !hotVersions[version] {
+			return true
+		}
+	}
+	return false
+}
+
+// forgetArchive makes the next LoadExecutions read the archive again
+func (r *ArchivedRepository) forgetArchive() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.archived, r.hotVersions = nil, nil
+}
+
+func (r *ArchivedRepository) LoadHotExecutions() ([]MigrationExecution, error) {
+	return r.hot.LoadExecutions()
+}
+
+// LoadArchivedExecutions implements the Archiver.LoadArchivedExecutions method, always reading
+// the archive
+func (r *ArchivedRepository) LoadArchivedExecutions() ([]MigrationExecution, error) {
+	return r.loadArchive()
+}
+
+func (r *ArchivedRepository) loadArchive() ([]MigrationExecution, error) {
+	executions, err := r.archive.LoadExecutions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the archived executions with error: %w", err)
+	}
+	return executions, nil
+}
+
+func (r *ArchivedRepository) Save(execution MigrationExecution) error {
+	return r.hot.Save(execution)
+}
+
+// SaveContext implements the ContextRepository.SaveContext method
+func (r *ArchivedRepository) SaveContext(ctx context.Context, execution MigrationExecution) error {
+	return SaveContext(ctx, r.hot, execution)
+}
+
+// SaveAll implements the BulkRepository.SaveAll method
+func (r *ArchivedRepository) SaveAll(executions []MigrationExecution) error {
+	return SaveAll(r.hot, executions)
+}
+
+// Remove implements the Repository.Remove method, removing the execution from both
+// repositories
+func (r *ArchivedRepository) Remove(execution MigrationExecution) error {
+	return r.RemoveContext(context.Background(), execution)
+}
+
+// RemoveContext implements the ContextRepository.RemoveContext method, removing the execution
+// from both repositories
+func (r *ArchivedRepository) RemoveContext(
+	ctx context.Context,
+	execution MigrationExecution,
+) error {
+	if err := RemoveContext(ctx, r.hot, execution); err != nil {
+		return err
+	}
+	defer r.forgetArchive()
+	return RemoveContext(ctx, r.archive, execution)
+}
+
+// RemoveAll implements the BulkRepository.RemoveAll method, removing the executions from both
+// repositories
+func (r *ArchivedRepository) RemoveAll(executions []MigrationExecution) error {
+	if err := RemoveAll(r.hot, executions); err != nil {
+		return err
+	}
+	defer r.forgetArchive()
+	return RemoveAll(r.archive, executions)
+}
+
+// FindOne implements the Repository.FindOne method, searching the hot repository, then the
+// archive
+func (r *ArchivedRepository) FindOne(version uint64) (*MigrationExecution, error) {
+	found, err := r.hot.FindOne(version)
+	if err != nil || found != nil {
+		return found, err
+	}
+	return r.archive.FindOne(version)
+}
+
+// Archive implements the Archiver.Archive method. The executions are saved to the archive
+// before being removed from the hot repository, so an interrupted archival only leaves
+// executions in both, which the next one moves again. The unfinished executions are never
+// archived.
+func (r *ArchivedRepository) Archive(cutoff time.Time) ([]MigrationExecution, error) {
+	executions, err := r.hot.LoadExecutions()
+	if err != nil {
+		return nil, err
+	}
+
+	archived := []MigrationExecution{}
+	for _, exec := range executions {
+		if exec.Finished() && exec.ExecutedAtMs < uint64(cutoff.UnixMilli()) {
+			archived = append(archived, exec)
+		}
+	}
+	if len(archived) == 0 {
+		return archived, nil
+	}
+
+	defer r.forgetArchive()
+	if err = SaveAll(r.archive, archived); err != nil {
+		return nil, fmt.Errorf("failed to save the archived executions with error: %w", err)
+	}
+	if err = RemoveAll(r.hot, archived); err != nil {
+		return nil, fmt.Errorf(
+			"failed to remove the archived executions from the hot repository with error: %w",
+			err,
+		)
+	}
+	return archived, nil
+}
+
+// StoredSchemaVersion implements the SchemaVersioner interface, with the schema version of the
+// hot repository (0 if it doesn't implement SchemaVersioner)
+func (r *ArchivedRepository) StoredSchemaVersion() (int, error) {
+	if versioner, ok := r.hot.(SchemaVersioner); ok {
+		return versioner.StoredSchemaVersion()
+	}
+	return 0, nil
+}
+
+// ReadOnly implements the ReadOnlyChecker interface, with the state of the hot repository,
+// where the executions are saved (false if it doesn't implement ReadOnlyChecker)
+func (r *ArchivedRepository) ReadOnly() (bool, error) {
+	return readOnly(r.hot)
+}
+
+// Ping implements the Pinger interface, pinging both repositories which implement it
+func (r *ArchivedRepository) Ping(ctx context.Context) error {
+	var errs []error
+	for _, repository := range []Repository{r.hot, r.archive} {
+		if pinger, ok := repository.(Pinger); ok {
+			errs = append(errs, pinger.Ping(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// InitStatements implements the InitDescriber interface, with the statements of both
+// repositories, which Init initializes
+func (r *ArchivedRepository) InitStatements() []string {
+	return append(initStatements(r.hot), initStatements(r.archive)...)
+}
+
+// SaveStatement implements the SaveDescriber interface, with the statement of the hot
+// repository (empty if it doesn't implement SaveDescriber)
+func (r *ArchivedRepository) SaveStatement(execution MigrationExecution) string {
+	if describer, ok := SaveDescriberOf(r.hot); ok {
+		return describer.SaveStatement(execution)
+	}
+	return ""
+}
+
+// RemoveStatement implements the RemoveDescriber interface, with the statements of both
+// repositories (empty if one doesn't implement RemoveDescriber)
+func (r *ArchivedRepository) RemoveStatement(execution MigrationExecution) string {
+	hot, hotOk := RemoveDescriberOf(r.hot)
+	archive, archiveOk := RemoveDescriberOf(r.archive)
+	if !hotOk || !archiveOk {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimSpace(hot.RemoveStatement(execution)), ";") + ";\n" +
+		archive.RemoveStatement(execution)
+}
+
+func (r *ArchivedRepository) describesSave() bool {
+	_, ok := SaveDescriberOf(r.hot)
+	return ok
+}
+
+func (r *ArchivedRepository) describesRemove() bool {
+	_, hotOk := RemoveDescriberOf(r.hot)
+	_, archiveOk := RemoveDescriberOf(r.archive)
+	return hotOk && archiveOk
+}
+
+// CheckPermissions implements the PermissionsChecker interface, with the privileges missing
+// for both repositories
+func (r *ArchivedRepository) CheckPermissions() ([]MissingPermission, error) {
+	missing, err := missingPermissions(r.hot)
+	if err != nil {
+		return nil, err
+	}
+	archiveMissing, err := missingPermissions(r.archive)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to check the permissions of the executions archive with error: %w", err,
+		)
+	}
+	return append(missing, archiveMissing...), nil
+}
+
+// Target implements the Targeter interface, with the target of the hot repository
+func (r *ArchivedRepository) Target() (string, error) {
+	return targetOf(r.hot)
+}
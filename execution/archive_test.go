@@ -0,0 +1,157 @@
+package execution
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ArchivedRepositoryTestSuite struct {
+	suite.Suite
+}
+
+func TestArchivedRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(ArchivedRepositoryTestSuite))
+}
+
+func (suite *ArchivedRepositoryTestSuite) TestItArchivesTheOldExecutions() {
+	old := MigrationExecution{Version: 1, ExecutedAtMs: 1000, FinishedAtMs: 2000}
+	recent := MigrationExecution{Version: 2, ExecutedAtMs: 5000, FinishedAtMs: 6000}
+	unfinished := MigrationExecution{Version: 3, ExecutedAtMs: 1500}
+	hot := &InMemoryRepository{PersistedExecutions: []MigrationExecution{old, recent, unfinished}}
+	archive := &InMemoryRepository{}
+	repo := NewArchivedRepository(hot, archive)
+
+	archived, err := repo.Archive(time.UnixMilli(5000))
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]MigrationExecution{old}, archived)
+	suite.Assert().Equal([]MigrationExecution{recent, unfinished}, hot.PersistedExecutions)
+	suite.Assert().Equal([]MigrationExecution{old}, archive.PersistedExecutions)
+
+	// the archived executions stay applied
+	executions, err := repo.LoadExecutions()
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]MigrationExecution{old, recent, unfinished}, executions)
+	found, err := repo.FindOne(1)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(old, *found)
+
+	// the hot execution of a version in both wins
+	redone := MigrationExecution{Version: 1, ExecutedAtMs: 7000, FinishedAtMs: 8000}
+	suite.Require().NoError(repo.Save(redone))
+	executions, err = repo.LoadExecutions()
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]MigrationExecution{recent, unfinished, redone}, executions)
+
+	suite.Require().NoError(repo.Remove(old))
+	suite.Assert().Empty(archive.PersistedExecutions)
+	found, err = repo.FindOne(1)
+	suite.Require().NoError(err)
+	suite.Assert().Nil(found)
+
+	archived, err = repo.Archive(time.UnixMilli(1000))
+	suite.Require().NoError(err)
+	suite.Assert().Empty(archived)
+}
+
+func (suite *ArchivedRepositoryTestSuite) TestItKeepsTheHotExecutionsIfTheArchiveFails() {
+	exec := MigrationExecution{Version: 1, ExecutedAtMs: 1000, FinishedAtMs: 2000}
+	hot := &InMemoryRepository{PersistedExecutions: []MigrationExecution{exec}}
+	archive := &InMemoryRepository{SaveErr: errors.New("archive is full")}
+	repo := NewArchivedRepository(hot, archive)
+
+	_, err := repo.Archive(time.UnixMilli(5000))
+	suite.Assert().ErrorContains(
+		err, "failed to save the archived executions with error: archive is full",
+	)
+	suite.Assert().Equal([]MigrationExecution{exec}, hot.PersistedExecutions)
+
+	// the read-only view is archived too, without writing to any of the repositories
+	readOnly := NewReadOnlyRepository(repo)
+	suite.Require().IsType(&ArchivedRepository{}, readOnly)
+	_, err = readOnly.(Archiver).Archive(time.UnixMilli(5000))
+	suite.Assert().ErrorIs(err, ErrReadOnlyRepository)
+}
+
+// countingRepository counts the loads of its executions
+type countingRepository struct {
+	InMemoryRepository
+	loads int
+}
+
+func (r *countingRepository) LoadExecutions() ([]MigrationExecution, error) {
+	r.loads++
+	return r.InMemoryRepository.LoadExecutions()
+}
+
+func (suite *ArchivedRepositoryTestSuite) TestItReadsTheArchiveOnlyWhenExecutionsLeftTheHotOne() {
+	old := MigrationExecution{Version: 1, ExecutedAtMs: 1000, FinishedAtMs: 2000}
+	recent := MigrationExecution{Version: 2, ExecutedAtMs: 5000, FinishedAtMs: 6000}
+	hot := &InMemoryRepository{PersistedExecutions: []MigrationExecution{recent}}
+	archive := &countingRepository{}
+	archive.PersistedExecutions = []MigrationExecution{old}
+	repo := NewArchivedRepository(hot, archive)
+
+	loadExecutions := func() []MigrationExecution {
+		executions, err := repo.LoadExecutions()
+		suite.Require().NoError(err)
+		return executions
+	}
+
+	suite.Assert().Equal([]MigrationExecution{old, recent}, loadExecutions())
+	suite.Assert().Equal([]MigrationExecution{old, recent}, loadExecutions())
+	suite.Assert().Equal(1, archive.loads)
+
+	// the executions archived by another process leave the hot repository
+	next := MigrationExecution{Version: 3, ExecutedAtMs: 7000, FinishedAtMs: 8000}
+	hot.PersistedExecutions = []MigrationExecution{next}
+	archive.PersistedExecutions = append(archive.PersistedExecutions, recent)
+	suite.Assert().Equal([]MigrationExecution{old, recent, next}, loadExecutions())
+	suite.Assert().Equal(2, archive.loads)
+
+	// the full history always reads the archive
+	archived, err := repo.LoadArchivedExecutions()
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]MigrationExecution{old, recent}, archived)
+	suite.Assert().Equal(3, archive.loads)
+
+	// so does the next load after the own archival and rollbacks
+	_, err = repo.Archive(time.UnixMilli(9000))
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]MigrationExecution{old, recent, next}, loadExecutions())
+	suite.Assert().Equal(4, archive.loads)
+	suite.Require().NoError(repo.Remove(next))
+	suite.Assert().Equal([]MigrationExecution{old, recent}, loadExecutions())
+	suite.Assert().Equal(5, archive.loads)
+}
+
+func (suite *ArchivedRepositoryTestSuite) TestItForwardsTheOptionalInterfaces() {
+	repo := NewArchivedRepository(&preflightRepository{}, &scriptedRepository{})
+	exec := MigrationExecution{Version: 3}
+
+	suite.Assert().Len(repo.InitStatements(), 4)
+	describer, ok := SaveDescriberOf(repo)
+	suite.Require().True(ok)
+	suite.Assert().Equal("INSERT INTO executions VALUES (3)", describer.SaveStatement(exec))
+	remover, ok := RemoveDescriberOf(repo)
+	suite.Require().True(ok)
+	suite.Assert().Equal(
+		"DELETE FROM executions WHERE version = 3;\nDELETE FROM executions WHERE version = 3",
+		remover.RemoveStatement(exec),
+	)
+	var permissionsErr *PermissionsError
+	suite.Require().ErrorAs(CheckPermissions(repo), &permissionsErr)
+	suite.Assert().Len(permissionsErr.Missing, 1)
+	target, err := TargetOf(repo)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("postgres://db:5432/app/public/executions", target)
+
+	// the removals can't be described without the statements of the archive
+	repo = NewArchivedRepository(&scriptedRepository{}, &InMemoryRepository{})
+	_, ok = SaveDescriberOf(repo)
+	suite.Assert().True(ok)
+	_, ok = RemoveDescriberOf(repo)
+	suite.Assert().False(ok)
+}
@@ -0,0 +1,43 @@
+package execution
+
+// BulkRepository is an optional interface for repositories which can save or remove many
+// executions in a few round trips (for example, with multi-row statements), instead of one
+// per execution. The handler uses it to baseline many versions at once (see SaveAll).
+type BulkRepository interface {
+	// SaveAll is the Repository.Save method for all the executions. If several executions
+	// have the same version, the last one is saved.
+	SaveAll(executions []MigrationExecution) error
+
+	// RemoveAll is the Repository.Remove method for all the executions
+	RemoveAll(executions []MigrationExecution) error
+}
+
+// SaveAll saves the executions at once if the repository implements BulkRepository, one by
+// one with Repository.Save otherwise
+func SaveAll(repository Repository, executions []MigrationExecution) error {
+	if bulkRepository, ok := repository.(BulkRepository); ok {
+		return bulkRepository.SaveAll(executions)
+	}
+
+	for _, execution := range executions {
+		if err := repository.Save(execution); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveAll removes the executions at once if the repository implements BulkRepository, one
+// by one with Repository.Remove otherwise
+func RemoveAll(repository Repository, executions []MigrationExecution) error {
+	if bulkRepository, ok := repository.(BulkRepository); ok {
+		return bulkRepository.RemoveAll(executions)
+	}
+
+	for _, execution := range executions {
+		if err := repository.Remove(execution); err != nil {
+			return err
+		}
+	}
+	return nil
+}
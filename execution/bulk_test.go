@@ -0,0 +1,48 @@
+package execution
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type BulkTestSuite struct {
+	suite.Suite
+}
+
+func TestBulkTestSuite(t *testing.T) {
+	suite.Run(t, new(BulkTestSuite))
+}
+
+// singleRepository hides the bulk methods of the repository it wraps
+type singleRepository struct {
+	Repository
+}
+
+func (suite *BulkTestSuite) TestItSavesAndRemovesManyExecutions() {
+	executions := []MigrationExecution{
+		{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+		{Version: 2, ExecutedAtMs: 3, FinishedAtMs: 4},
+		{Version: 3, ExecutedAtMs: 5, FinishedAtMs: 6},
+	}
+
+	for name, repo := range map[string]*InMemoryRepository{
+		"bulk":   {},
+		"single": {},
+	} {
+		var repository Repository = repo
+		if name == "single" {
+			repository = singleRepository{repo}
+		}
+
+		suite.Require().NoError(SaveAll(repository, executions), name)
+		suite.Assert().Equal(executions, repo.PersistedExecutions, name)
+
+		suite.Require().NoError(RemoveAll(repository, executions[:2]), name)
+		suite.Assert().Equal(executions[2:], repo.PersistedExecutions, name)
+
+		repo.SaveErr = errors.New("save failed")
+		suite.Assert().ErrorIs(SaveAll(repository, executions), repo.SaveErr, name)
+	}
+}
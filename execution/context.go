@@ -0,0 +1,59 @@
+package execution
+
+import "context"
+
+type versionKey struct{}
+
+type attemptKey struct{}
+
+// WithMigration returns a copy of ctx which carries the version of the migration being executed
+// and the attempt number of its execution within the run (1 for the first one)
+func WithMigration(ctx context.Context, version uint64, attempt int) context.Context {
+	return context.WithValue(context.WithValue(ctx, versionKey{}, version), attemptKey{}, attempt)
+}
+
+// VersionFrom returns the version of the migration carried by ctx. The second return value is
+// false if ctx carries none. Migrations and repositories can use it to tag their own logs.
+func VersionFrom(ctx context.Context) (uint64, bool) {
+	version, ok := ctx.Value(versionKey{}).(uint64)
+	return version, ok
+}
+
+// AttemptFrom returns the attempt number carried by ctx, or 0 if there is none
+func AttemptFrom(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+// ContextRepository is an optional interface for repositories whose write operations can run
+// with the given context, instead of the one the repository was built with. The handler uses
+// it to record an execution with the context of the migration (see WithMigration).
+type ContextRepository interface {
+	// SaveContext is the Repository.Save method, run with ctx
+	SaveContext(ctx context.Context, execution MigrationExecution) error
+
+	// RemoveContext is the Repository.Remove method, run with ctx
+	RemoveContext(ctx context.Context, execution MigrationExecution) error
+}
+
+// SaveContext saves the execution with ctx if the repository implements ContextRepository,
+// with Repository.Save otherwise
+func SaveContext(ctx context.Context, repository Repository, execution MigrationExecution) error {
+	if ctxRepository, ok := repository.(ContextRepository); ok {
+		return ctxRepository.SaveContext(ctx, execution)
+	}
+	return repository.Save(execution)
+}
+
+// RemoveContext removes the execution with ctx if the repository implements ContextRepository,
+// with Repository.Remove otherwise
+func RemoveContext(
+	ctx context.Context,
+	repository Repository,
+	execution MigrationExecution,
+) error {
+	if ctxRepository, ok := repository.(ContextRepository); ok {
+		return ctxRepository.RemoveContext(ctx, execution)
+	}
+	return repository.Remove(execution)
+}
@@ -0,0 +1,64 @@
+package execution
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ContextTestSuite struct {
+	suite.Suite
+}
+
+func TestContextTestSuite(t *testing.T) {
+	suite.Run(t, new(ContextTestSuite))
+}
+
+// contextRepository records the contexts of its writes
+type contextRepository struct {
+	InMemoryRepository
+	contexts []context.Context
+}
+
+func (r *contextRepository) SaveContext(ctx context.Context, execution MigrationExecution) error {
+	r.contexts = append(r.contexts, ctx)
+	return r.Save(execution)
+}
+
+func (r *contextRepository) RemoveContext(
+	ctx context.Context,
+	execution MigrationExecution,
+) error {
+	r.contexts = append(r.contexts, ctx)
+	return r.Remove(execution)
+}
+
+func (suite *ContextTestSuite) TestItCarriesTheMigrationOfTheContext() {
+	_, ok := VersionFrom(context.Background())
+	suite.Assert().False(ok)
+	suite.Assert().Zero(AttemptFrom(context.Background()))
+
+	ctx := WithMigration(context.Background(), 12, 2)
+	version, ok := VersionFrom(ctx)
+	suite.Assert().True(ok)
+	suite.Assert().Equal(uint64(12), version)
+	suite.Assert().Equal(2, AttemptFrom(ctx))
+}
+
+func (suite *ContextTestSuite) TestItWritesWithTheContextWhenSupported() {
+	ctx := WithMigration(context.Background(), 1, 1)
+	exec := MigrationExecution{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2}
+
+	repo := &contextRepository{}
+	suite.Require().NoError(SaveContext(ctx, repo, exec))
+	suite.Require().NoError(RemoveContext(ctx, repo, exec))
+	suite.Assert().Equal([]context.Context{ctx, ctx}, repo.contexts)
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	inMemory := &InMemoryRepository{}
+	suite.Require().NoError(SaveContext(ctx, inMemory, exec))
+	suite.Assert().Len(inMemory.PersistedExecutions, 1)
+	suite.Require().NoError(RemoveContext(ctx, inMemory, exec))
+	suite.Assert().Empty(inMemory.PersistedExecutions)
+}
@@ -0,0 +1,118 @@
+package execution
+
+import (
+	"errors"
+	"strings"
+)
+
+// InitDescriber is an optional interface for repositories which can describe the statements
+// Init() runs to create the executions storage, so that a privileged role (for example, a
+// DBA) can create it where the connected role is not allowed to. Once the storage exists at
+// the supported schema version, Init() no longer needs the privileges to create or change it.
+type InitDescriber interface {
+	// InitStatements returns the statements which create the executions storage at the
+	// supported SchemaVersion, in the order they must be run
+	InitStatements() []string
+}
+
+// SaveDescriber is an optional interface for repositories which can describe the statement
+// Save() runs, with its values inlined, so that the executions can be recorded by a SQL
+// script run offline by a privileged role (see the --sql-only flag of the up command)
+type SaveDescriber interface {
+	// SaveStatement returns the statement which saves the execution
+	SaveStatement(execution MigrationExecution) string
+}
+
+// RemoveDescriber is an optional interface for repositories which can describe the statement
+// Remove() runs, with its values inlined, so that the rollbacks written to a SQL script (see
+// the --sql-only flag of the down command) remove their executions
+type RemoveDescriber interface {
+	// RemoveStatement returns the statement which removes the execution
+	RemoveStatement(execution MigrationExecution) string
+}
+
+// describingWrapper is implemented by the wrappers of repositories (for example,
+// ThrottledRepository) which implement SaveDescriber and RemoveDescriber by forwarding the
+// descriptions, so they describe the statements only if the wrapped repositories do
+type describingWrapper interface {
+	describesSave() bool
+	describesRemove() bool
+}
+
+// SaveDescriberOf returns the repository as a SaveDescriber, and true if it can describe the
+// statement Save() runs, including through the wrapped repositories
+func SaveDescriberOf(repository Repository) (SaveDescriber, bool) {
+	describer, ok := repository.(SaveDescriber)
+	if wrapper, isWrapper := repository.(describingWrapper); isWrapper {
+		ok = ok && wrapper.describesSave()
+	}
+	return describer, ok
+}
+
+// RemoveDescriberOf returns the repository as a RemoveDescriber, and true if it can describe
+// the statement Remove() runs, including through the wrapped repositories
+func RemoveDescriberOf(repository Repository) (RemoveDescriber, bool) {
+	describer, ok := repository.(RemoveDescriber)
+	if wrapper, isWrapper := repository.(describingWrapper); isWrapper {
+		ok = ok && wrapper.describesRemove()
+	}
+	return describer, ok
+}
+
+// initStatements returns the statements which create the executions storage of the
+// repository, none if it doesn't implement InitDescriber
+func initStatements(repository Repository) []string {
+	if describer, ok := repository.(InitDescriber); ok {
+		return describer.InitStatements()
+	}
+	return nil
+}
+
+// InitError is returned by Initialize when the initialization of a repository which
+// implements InitDescriber fails. It holds the statements a privileged role can run instead.
+type InitError struct {
+	Err        error
+	Statements []string
+}
+
+func (e *InitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InitError) Unwrap() error {
+	return e.Err
+}
+
+// Script returns the statements as a script, each one terminated by a semicolon
+func (e *InitError) Script() string {
+	var script strings.Builder
+	for i, statement := range e.Statements {
+		if i > 0 {
+			script.WriteString("\n")
+		}
+		script.WriteString(strings.TrimSpace(statement) + ";\n")
+	}
+	return script.String()
+}
+
+// Initialize initializes the repository (see Repository.Init). If it fails, the error is
+// wrapped in an *InitError describing the statements which create the executions storage,
+// when the repository implements InitDescriber and describes some. An *InitError (for
+// example, of a wrapped repository) is returned as it is.
+func Initialize(repository Repository) error {
+	err := repository.Init()
+	var initErr *InitError
+	if err == nil || errors.As(err, &initErr) {
+		return err
+	}
+
+	statements := initStatements(repository)
+	if len(statements) == 0 {
+		return err
+	}
+
+	return &InitError{
+		Err:        err,
+		Statements: statements,
+	}
+}
@@ -0,0 +1,104 @@
+package execution
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DdlTestSuite struct {
+	suite.Suite
+}
+
+func TestDdlTestSuite(t *testing.T) {
+	suite.Run(t, new(DdlTestSuite))
+}
+
+type describedRepository struct {
+	InMemoryRepository
+}
+
+func (r *describedRepository) InitStatements() []string {
+	return []string{"CREATE TABLE executions (version BIGINT)", "COMMENT ON TABLE executions"}
+}
+
+// scriptedRepository describes the statements which save and remove the executions too
+type scriptedRepository struct {
+	describedRepository
+}
+
+func (r *scriptedRepository) SaveStatement(execution MigrationExecution) string {
+	return "INSERT INTO executions VALUES (" + strconv.FormatUint(execution.Version, 10) + ")"
+}
+
+func (r *scriptedRepository) RemoveStatement(execution MigrationExecution) string {
+	return "DELETE FROM executions WHERE version = " +
+		strconv.FormatUint(execution.Version, 10)
+}
+
+func (suite *DdlTestSuite) TestItDescribesTheStatementsOfFailedInitializations() {
+	initErr := errors.New("permission denied for schema public")
+	repo := &describedRepository{}
+	repo.InitErr = initErr
+
+	err := Initialize(repo)
+	var describedErr *InitError
+	suite.Require().ErrorAs(err, &describedErr)
+	suite.Assert().ErrorIs(err, initErr)
+	suite.Assert().EqualError(err, "permission denied for schema public")
+	suite.Assert().Equal(
+		"CREATE TABLE executions (version BIGINT);\n\nCOMMENT ON TABLE executions;\n",
+		describedErr.Script(),
+	)
+}
+
+func (suite *DdlTestSuite) TestItInitializesOrFailsWithoutDescription() {
+	suite.Assert().NoError(Initialize(&describedRepository{}))
+
+	initErr := errors.New("connection refused")
+	err := Initialize(&InMemoryRepository{InitErr: initErr})
+	var describedErr *InitError
+	suite.Assert().False(errors.As(err, &describedErr))
+	suite.Assert().ErrorIs(err, initErr)
+}
+
+func (suite *DdlTestSuite) TestItFindsTheDescribersThroughTheWrappers() {
+	_, ok := SaveDescriberOf(&scriptedRepository{})
+	suite.Assert().True(ok)
+	_, ok = RemoveDescriberOf(&InMemoryRepository{})
+	suite.Assert().False(ok)
+
+	throttled := NewThrottledRepository(&scriptedRepository{}, time.Millisecond)
+	describer, ok := SaveDescriberOf(throttled)
+	suite.Require().True(ok)
+	suite.Assert().Equal(
+		"INSERT INTO executions VALUES (3)", describer.SaveStatement(MigrationExecution{Version: 3}),
+	)
+
+	// the wrapper implements the describers, but the wrapped repository doesn't describe
+	throttled = NewThrottledRepository(&InMemoryRepository{}, time.Millisecond)
+	suite.Assert().Implements((*SaveDescriber)(nil), throttled)
+	_, ok = SaveDescriberOf(throttled)
+	suite.Assert().False(ok)
+	_, ok = RemoveDescriberOf(throttled)
+	suite.Assert().False(ok)
+}
+
+func (suite *DdlTestSuite) TestItKeepsTheDescriptionOfTheWrappedRepositories() {
+	initErr := errors.New("permission denied for schema public")
+	repo := &describedRepository{}
+	repo.InitErr = initErr
+
+	err := Initialize(NewThrottledRepository(repo, time.Millisecond))
+	var describedErr *InitError
+	suite.Require().ErrorAs(err, &describedErr)
+	suite.Assert().Same(initErr, describedErr.Err)
+	suite.Assert().Len(describedErr.Statements, 2)
+
+	err = Initialize(NewThrottledRepository(&InMemoryRepository{InitErr: initErr}, time.Millisecond))
+	suite.Assert().False(errors.As(err, &describedErr))
+	suite.Assert().ErrorIs(err, initErr)
+}
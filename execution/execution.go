@@ -28,8 +28,20 @@ type MigrationExecution struct {
 	// FinishedAtMs is the Unix timestamp in milliseconds when the migration execution finished
 	// A value of 0 indicates that the migration has not finished yet
 	FinishedAtMs uint64
+
+	// RunId is the ID of the run which executed the migration (see NewRunId). It is empty
+	// for executions saved before run IDs were recorded.
+	RunId string
+
+	// Checkpoint is the last progress marker saved by a checkpointable migration (see
+	// migration.Checkpointer) while the execution was unfinished. At most
+	// MaxCheckpointLength bytes long. It is cleared when the execution finishes.
+	Checkpoint string
 }
 
+// MaxCheckpointLength is the maximum length, in bytes, of an execution Checkpoint
+const MaxCheckpointLength = 1024
+
 // StartExecution creates a new MigrationExecution for the given migration and marks it as unfinished.
 // It sets the Version to the migration's version and ExecutedAtMs to the current time.
 //
@@ -39,14 +51,19 @@ type MigrationExecution struct {
 // Returns:
 //   - *MigrationExecution: A new execution instance for the migration
 func StartExecution(migration migration.Migration) *MigrationExecution {
-	return &MigrationExecution{migration.Version(), uint64(time.Now().UnixMilli()), 0}
+	return &MigrationExecution{
+		Version:      migration.Version(),
+		ExecutedAtMs: uint64(time.Now().UnixMilli()),
+	}
 }
 
 // FinishExecution marks the MigrationExecution as finished by setting FinishedAtMs to the current time.
-// If the execution is already marked as finished, this method does nothing.
+// If the execution is already marked as finished, this method does nothing. The Checkpoint is cleared,
+// since a finished execution has nothing to resume.
 func (execution *MigrationExecution) FinishExecution() {
 	if !execution.Finished() {
 		execution.FinishedAtMs = uint64(time.Now().UnixMilli())
+		execution.Checkpoint = ""
 	}
 }
 
@@ -188,10 +205,24 @@ func (repo *InMemoryRepository) FindOne(version uint64) (*MigrationExecution, er
 	return nil, repo.FindOneErr
 }
 
-// SaveAll is a convenience method that saves multiple executions at once.
-// It calls Save for each execution in the provided slice.
-func (repo *InMemoryRepository) SaveAll(executions []MigrationExecution) {
+// SaveAll implements the BulkRepository.SaveAll method.
+// It calls Save for each execution in the provided slice and returns the SaveErr field.
+func (repo *InMemoryRepository) SaveAll(executions []MigrationExecution) error {
+	for _, execution := range executions {
+		if err := repo.Save(execution); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveAll implements the BulkRepository.RemoveAll method.
+// It calls Remove for each execution in the provided slice and returns the RemoveErr field.
+func (repo *InMemoryRepository) RemoveAll(executions []MigrationExecution) error {
 	for _, execution := range executions {
-		_ = repo.Save(execution)
+		if err := repo.Remove(execution); err != nil {
+			return err
+		}
 	}
+	return nil
 }
@@ -0,0 +1,69 @@
+package execution
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MissingPermission is a privilege the connected role lacks for a migrations run
+type MissingPermission struct {
+	// Privilege is the missing privilege, in the backend terms (for example, CREATE)
+	Privilege string
+
+	// Target is what the privilege applies to (a schema or a table)
+	Target string
+
+	// Reason explains what needs the privilege
+	Reason string
+}
+
+func (p MissingPermission) String() string {
+	return fmt.Sprintf("%s on %s (%s)", p.Privilege, p.Target, p.Reason)
+}
+
+// PermissionsChecker is an optional interface for repositories which can verify, before a
+// run, that the connected role has the privileges the run needs: creating and changing the
+// executions table, writing executions and changing the target schema
+type PermissionsChecker interface {
+	// CheckPermissions returns all the missing privileges, empty if none
+	CheckPermissions() ([]MissingPermission, error)
+}
+
+// PermissionsError is the consolidated report of the privileges missing for a run
+type PermissionsError struct {
+	Missing []MissingPermission
+}
+
+func (e *PermissionsError) Error() string {
+	var report strings.Builder
+	report.WriteString("the connected role is missing the privileges needed by the run:")
+	for _, missing := range e.Missing {
+		report.WriteString("\n  - " + missing.String())
+	}
+	return report.String()
+}
+
+// CheckPermissions runs the permissions preflight of the repository. It returns a
+// *PermissionsError listing all the missing privileges, or nil if none is missing or if the
+// repository does not implement PermissionsChecker.
+func CheckPermissions(repository Repository) error {
+	missing, err := missingPermissions(repository)
+	if err != nil {
+		return fmt.Errorf("failed to check the permissions with error: %w", err)
+	}
+
+	if len(missing) > 0 {
+		return &PermissionsError{Missing: missing}
+	}
+
+	return nil
+}
+
+// missingPermissions returns the privileges missing for a run, none if the repository doesn't
+// implement PermissionsChecker
+func missingPermissions(repository Repository) ([]MissingPermission, error) {
+	if checker, ok := repository.(PermissionsChecker); ok {
+		return checker.CheckPermissions()
+	}
+	return nil, nil
+}
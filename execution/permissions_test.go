@@ -0,0 +1,53 @@
+package execution
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PermissionsTestSuite struct {
+	suite.Suite
+}
+
+func TestPermissionsTestSuite(t *testing.T) {
+	suite.Run(t, new(PermissionsTestSuite))
+}
+
+type checkedRepository struct {
+	InMemoryRepository
+	missing  []MissingPermission
+	checkErr error
+}
+
+func (r *checkedRepository) CheckPermissions() ([]MissingPermission, error) {
+	return r.missing, r.checkErr
+}
+
+func (suite *PermissionsTestSuite) TestItReportsAllMissingPermissions() {
+	repo := &checkedRepository{
+		missing: []MissingPermission{
+			{Privilege: "CREATE", Target: "schema public", Reason: "creating tables"},
+			{Privilege: "DELETE", Target: "table executions", Reason: "removing executions"},
+		},
+	}
+
+	err := CheckPermissions(repo)
+	var permissionsErr *PermissionsError
+	suite.Require().ErrorAs(err, &permissionsErr)
+	suite.Assert().Len(permissionsErr.Missing, 2)
+	suite.Assert().EqualError(
+		err, "the connected role is missing the privileges needed by the run:\n"+
+			"  - CREATE on schema public (creating tables)\n"+
+			"  - DELETE on table executions (removing executions)",
+	)
+}
+
+func (suite *PermissionsTestSuite) TestItPassesWhenNoPermissionIsMissingOrChecked() {
+	suite.Assert().NoError(CheckPermissions(&checkedRepository{}))
+	suite.Assert().NoError(CheckPermissions(&InMemoryRepository{}))
+
+	checkErr := errors.New("connection refused")
+	suite.Assert().ErrorIs(CheckPermissions(&checkedRepository{checkErr: checkErr}), checkErr)
+}
@@ -0,0 +1,12 @@
+package execution
+
+import "context"
+
+// Pinger is an optional interface for repositories which hold a connection to their storage.
+// During long runs, the handler pings them periodically (see handler.WithKeepalive), so the
+// connection is not closed for being idle (for example, by a proxy or a firewall), and a
+// connection which was closed anyway is re-established before the next execution is saved.
+type Pinger interface {
+	// Ping checks the connection to the storage, reconnecting if it was closed
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,104 @@
+package execution
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrReadOnlyRepository is returned by the write methods of the read-only repositories
+var ErrReadOnlyRepository = errors.New("the executions repository is read-only")
+
+// ReadOnlyChecker is an optional interface for repositories which can tell whether their
+// storage rejects the writes, for example, a replica promoted briefly during a disaster
+// recovery drill, or a database switched to a read-only mode. The handler checks it before
+// executing any migration (see CheckWritable), so the runs fail before executing migrations
+// whose executions could not be saved.
+type ReadOnlyChecker interface {
+	// ReadOnly returns true if the storage rejects the writes
+	ReadOnly() (bool, error)
+}
+
+// CheckWritable returns ErrReadOnlyRepository if the repository implements
+// ReadOnlyChecker and reports that its storage is read-only. It returns nil for the
+// repositories which don't implement ReadOnlyChecker.
+func CheckWritable(repository Repository) error {
+	isReadOnly, err := readOnly(repository)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to check whether the executions repository is read-only with error: %w",
+			err,
+		)
+	}
+	if isReadOnly {
+		return ErrReadOnlyRepository
+	}
+	return nil
+}
+
+// readOnly returns the state reported by the repository, false if it doesn't implement
+// ReadOnlyChecker
+func readOnly(repository Repository) (bool, error) {
+	if checker, ok := repository.(ReadOnlyChecker); ok {
+		return checker.ReadOnly()
+	}
+	return false, nil
+}
+
+// ReadOnlyRepository wraps a Repository so that it only reads the executions: Init() doesn't
+// create or upgrade the executions storage (and so doesn't wait for the locks held by a run
+// in progress elsewhere), and Save() and Remove() fail with ErrReadOnlyRepository. It
+// implements ReadOnlyChecker, so the handler refuses to execute migrations with it.
+type ReadOnlyRepository struct {
+	repository Repository
+}
+
+// NewReadOnlyRepository builds a read-only view of the given repository. The view implements
+// SchemaVersioner only if the repository does. The view of an ArchivedRepository is an
+// ArchivedRepository of the views of its repositories.
+func NewReadOnlyRepository(repository Repository) Repository {
+	if archived, ok := repository.(*ArchivedRepository); ok {
+		return NewArchivedRepository(
+			NewReadOnlyRepository(archived.hot), NewReadOnlyRepository(archived.archive),
+		)
+	}
+
+	readOnly := &ReadOnlyRepository{repository: repository}
+	if _, ok := repository.(SchemaVersioner); ok {
+		return &readOnlyVersionedRepository{readOnly}
+	}
+	return readOnly
+}
+
+// Init implements the Repository.Init method, without touching the executions storage
+func (r *ReadOnlyRepository) Init() error {
+	return nil
+}
+
+func (r *ReadOnlyRepository) LoadExecutions() ([]MigrationExecution, error) {
+	return r.repository.LoadExecutions()
+}
+
+func (r *ReadOnlyRepository) Save(MigrationExecution) error {
+	return ErrReadOnlyRepository
+}
+
+func (r *ReadOnlyRepository) Remove(MigrationExecution) error {
+	return ErrReadOnlyRepository
+}
+
+func (r *ReadOnlyRepository) FindOne(version uint64) (*MigrationExecution, error) {
+	return r.repository.FindOne(version)
+}
+
+// ReadOnly implements the ReadOnlyChecker interface, always true
+func (r *ReadOnlyRepository) ReadOnly() (bool, error) {
+	return true, nil
+}
+
+type readOnlyVersionedRepository struct {
+	*ReadOnlyRepository
+}
+
+func (r *readOnlyVersionedRepository) StoredSchemaVersion() (int, error) {
+	return r.repository.(SchemaVersioner).StoredSchemaVersion()
+}
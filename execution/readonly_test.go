@@ -0,0 +1,77 @@
+package execution
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ReadOnlyRepositoryTestSuite struct {
+	suite.Suite
+}
+
+func TestReadOnlyRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(ReadOnlyRepositoryTestSuite))
+}
+
+func (suite *ReadOnlyRepositoryTestSuite) TestItOnlyReadsTheExecutions() {
+	exec := MigrationExecution{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2}
+	repo := &InMemoryRepository{
+		InitErr:             errors.New("table is locked"),
+		PersistedExecutions: []MigrationExecution{exec},
+	}
+	readOnly := NewReadOnlyRepository(repo)
+
+	suite.Assert().NoError(readOnly.Init())
+	suite.Assert().ErrorIs(readOnly.Save(MigrationExecution{Version: 2}), ErrReadOnlyRepository)
+	suite.Assert().ErrorIs(readOnly.Remove(exec), ErrReadOnlyRepository)
+
+	executions, err := readOnly.LoadExecutions()
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]MigrationExecution{exec}, executions)
+
+	found, err := readOnly.FindOne(1)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(exec, *found)
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+}
+
+func (suite *ReadOnlyRepositoryTestSuite) TestItKeepsTheSchemaVersionerOfTheRepository() {
+	_, ok := NewReadOnlyRepository(&InMemoryRepository{}).(SchemaVersioner)
+	suite.Assert().False(ok)
+
+	readOnly := NewReadOnlyRepository(&versionedRepository{stored: SchemaVersion + 1})
+	var tooNewErr *SchemaTooNewError
+	suite.Assert().ErrorAs(CheckSchemaVersion(readOnly), &tooNewErr)
+}
+
+func (suite *ReadOnlyRepositoryTestSuite) TestItChecksWhetherTheRepositoryIsWritable() {
+	repo := &InMemoryRepository{}
+	suite.Assert().NoError(CheckWritable(repo))
+
+	readOnly := NewReadOnlyRepository(repo)
+	suite.Assert().ErrorIs(CheckWritable(readOnly), ErrReadOnlyRepository)
+	suite.Assert().ErrorIs(
+		CheckWritable(NewThrottledRepository(readOnly, time.Millisecond)), ErrReadOnlyRepository,
+	)
+	suite.Assert().ErrorIs(
+		CheckWritable(NewArchivedRepository(readOnly, repo)), ErrReadOnlyRepository,
+	)
+	suite.Assert().NoError(CheckWritable(NewArchivedRepository(repo, readOnly)))
+
+	suite.Assert().ErrorContains(
+		CheckWritable(failingReadOnlyChecker{repo}),
+		"failed to check whether the executions repository is read-only with error: timeout",
+	)
+}
+
+// failingReadOnlyChecker is a repository which fails to tell whether it is read-only
+type failingReadOnlyChecker struct {
+	*InMemoryRepository
+}
+
+func (r failingReadOnlyChecker) ReadOnly() (bool, error) {
+	return false, errors.New("timeout")
+}
@@ -0,0 +1,110 @@
+//go:build mysql || postgres || sqlite || mongo || spanner
+
+package repository
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golibry/go-migrations/execution"
+)
+
+// benchmarkRows is the number of executions the benchmarked repositories hold, like the
+// installations which track the executions of many tenants
+const benchmarkRows = 10_000
+
+// benchmarkExecution builds a finished execution with the version
+func benchmarkExecution(version uint64) execution.MigrationExecution {
+	return execution.MigrationExecution{
+		Version:      version,
+		ExecutedAtMs: version,
+		FinishedAtMs: version + 1,
+		RunId:        "01HZX3V9Q8M4K2J7N6P5R0S1T" + strconv.Itoa(int(version%10)),
+	}
+}
+
+// benchmarkRepository seeds the initialized repository with benchmarkRows executions, then
+// benchmarks loading all of them, finding one, and saving new ones, sequentially and from
+// concurrent savers
+func benchmarkRepository(b *testing.B, repository execution.Repository) {
+	for version := uint64(1); version <= benchmarkRows; version++ {
+		if err := repository.Save(benchmarkExecution(version)); err != nil {
+			b.Fatalf("failed to seed execution %d with error: %s", version, err)
+		}
+	}
+
+	b.Run(
+		"LoadExecutions", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				executions, err := repository.LoadExecutions()
+				if err != nil || len(executions) < benchmarkRows {
+					b.Fatalf("loaded %d executions with error: %v", len(executions), err)
+				}
+			}
+		},
+	)
+
+	b.Run(
+		"FindOne", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				version := uint64(i%benchmarkRows) + 1
+				if exec, err := repository.FindOne(version); err != nil || exec == nil {
+					b.Fatalf("failed to find execution %d with error: %v", version, err)
+				}
+			}
+		},
+	)
+
+	// the saved versions follow the seeded ones
+	nextVersion := atomic.Uint64{}
+	nextVersion.Store(benchmarkRows)
+
+	b.Run(
+		"Save", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := repository.Save(benchmarkExecution(nextVersion.Add(1))); err != nil {
+					b.Fatal(err)
+				}
+			}
+		},
+	)
+
+	b.Run(
+		"ConcurrentSave", func(b *testing.B) {
+			b.RunParallel(
+				func(pb *testing.PB) {
+					for pb.Next() {
+						exec := benchmarkExecution(nextVersion.Add(1))
+						if err := repository.Save(exec); err != nil {
+							b.Error(err)
+							return
+						}
+					}
+				},
+			)
+		},
+	)
+}
+
+// benchmarkSqlHandler benchmarks the SQL handler built by newHandler, which must start from an
+// empty executions table, with and without the prepared statements (see
+// WithoutPreparedStatements)
+func benchmarkSqlHandler(
+	b *testing.B,
+	newHandler func(b *testing.B, opts ...HandlerOption) execution.Repository,
+) {
+	for _, variant := range []struct {
+		name string
+		opts []HandlerOption
+	}{
+		{"Prepared", nil},
+		{"Unprepared", []HandlerOption{WithoutPreparedStatements()}},
+	} {
+		b.Run(
+			variant.name, func(b *testing.B) {
+				benchmarkRepository(b, newHandler(b, variant.opts...))
+			},
+		)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build mysql || postgres || sqlite || mongo || spanner
+
+package repository
+
+import (
+	"slices"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/stretchr/testify/suite"
+)
+
+// assertBulkOperations saves, updates and removes, with the bulk methods, more executions than
+// fit in a batch (see bulkBatchSize) into the empty, initialized repository
+func assertBulkOperations(s *suite.Suite, repository execution.BulkRepository) {
+	loader := repository.(execution.Repository)
+	executions := make([]execution.MigrationExecution, 0, 2*bulkBatchSize+50)
+	for version := uint64(1); version <= 2*bulkBatchSize+50; version++ {
+		executions = append(executions, benchmarkExecution(version))
+	}
+
+	s.Require().NoError(repository.SaveAll(nil))
+	s.Require().NoError(repository.SaveAll(executions))
+
+	// saving again upserts, the last execution of a version wins
+	updated := executions[0]
+	updated.FinishedAtMs++
+	s.Require().NoError(repository.SaveAll([]execution.MigrationExecution{executions[0], updated}))
+	executions[0] = updated
+
+	loaded, err := loader.LoadExecutions()
+	s.Require().NoError(err)
+	slices.SortFunc(
+		loaded, func(a, b execution.MigrationExecution) int {
+			return int(a.Version) - int(b.Version)
+		},
+	)
+	s.Assert().Equal(executions, loaded)
+
+	s.Require().NoError(repository.RemoveAll(executions[:bulkBatchSize+10]))
+	loaded, err = loader.LoadExecutions()
+	s.Require().NoError(err)
+	s.Assert().Len(loaded, len(executions)-bulkBatchSize-10)
+
+	found, err := loader.FindOne(1)
+	s.Require().NoError(err)
+	s.Assert().Nil(found)
+}
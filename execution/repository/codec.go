@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+)
+
+// ExecutionCodec maps the fields of the executions to their storage: the names of the columns
+// of the SQL handlers (MySQL, PostgreSQL and SQLite), or of the document fields of the MongoDB
+// handler. Set it with WithExecutionCodec to adopt an existing executions table or collection
+// (for example, one created by a legacy tool, with other column names) without migrating its
+// data. The stored values must have the types of the default storage. The empty names keep the
+// default ones.
+type ExecutionCodec struct {
+	Version      string
+	ExecutedAtMs string
+	FinishedAtMs string
+	RunId        string
+	Checkpoint   string
+}
+
+// sqlExecutionCodec holds the default column names of the SQL handlers
+var sqlExecutionCodec = ExecutionCodec{
+	Version:      "version",
+	ExecutedAtMs: "executed_at_ms",
+	FinishedAtMs: "finished_at_ms",
+	RunId:        "run_id",
+	Checkpoint:   "checkpoint",
+}
+
+// WithExecutionCodec sets the names the executions are stored with (see ExecutionCodec). The
+// executions table or collection is still created, and upgraded with the missing columns, by
+// Init(), with these names.
+func WithExecutionCodec(codec ExecutionCodec) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.codec = codec
+	}
+}
+
+// withDefaults fills the empty names of the codec with the default ones
+func (c ExecutionCodec) withDefaults(defaults ExecutionCodec) ExecutionCodec {
+	c.Version = cmp.Or(c.Version, defaults.Version)
+	c.ExecutedAtMs = cmp.Or(c.ExecutedAtMs, defaults.ExecutedAtMs)
+	c.FinishedAtMs = cmp.Or(c.FinishedAtMs, defaults.FinishedAtMs)
+	c.RunId = cmp.Or(c.RunId, defaults.RunId)
+	c.Checkpoint = cmp.Or(c.Checkpoint, defaults.Checkpoint)
+	return c
+}
+
+// quoted returns the codec with its names quoted with the given identifier quote (a backtick
+// for MySQL, a double quote for PostgreSQL and SQLite), for the SQL queries
+func (c ExecutionCodec) quoted(quote string) ExecutionCodec {
+	return ExecutionCodec{
+		Version:      quoteIdentifier(c.Version, quote),
+		ExecutedAtMs: quoteIdentifier(c.ExecutedAtMs, quote),
+		FinishedAtMs: quoteIdentifier(c.FinishedAtMs, quote),
+		RunId:        quoteIdentifier(c.RunId, quote),
+		Checkpoint:   quoteIdentifier(c.Checkpoint, quote),
+	}
+}
+
+// quoteIdentifier quotes the name with the identifier quote, doubling the quotes it contains
+func quoteIdentifier(name string, quote string) string {
+	return quote + strings.ReplaceAll(name, quote, quote+quote) + quote
+}
+
+// names returns the names of the fields, in the order of the executionArgs
+func (c ExecutionCodec) names() []string {
+	return []string{c.Version, c.ExecutedAtMs, c.FinishedAtMs, c.RunId, c.Checkpoint}
+}
+
+// columns returns the comma separated names of the fields, in the order of the executionArgs
+func (c ExecutionCodec) columns() string {
+	return strings.Join(c.names(), ", ")
+}
+
+// assignments builds the comma separated assignments of the fields other than the version,
+// formatted with the name of each field (for example, "%[1]s = VALUES(%[1]s)"), for the
+// upsert queries
+func (c ExecutionCodec) assignments(format string) string {
+	names := c.names()[1:]
+	list := make([]string, len(names))
+	for i, name := range names {
+		list[i] = fmt.Sprintf(format, name)
+	}
+	return strings.Join(list, ", ")
+}
@@ -1,6 +1,72 @@
 package repository
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+)
+
+// HandlerOption configures the optional behaviour of a repository handler. Options are
+// passed as the last arguments of the handler constructors.
+type HandlerOption func(*handlerOptions)
+
+// handlerOptions holds the values set by the HandlerOption functions
+type handlerOptions struct {
+	operationTimeout     time.Duration
+	unpreparedStatements bool
+	codec                ExecutionCodec
+	reconnectRetries     int
+}
+
+// WithOperationTimeout bounds each operation of the handler (loading, saving or removing
+// executions, creating the executions table) to the given duration, so a stuck metadata
+// write cannot hold the run, and the lock, indefinitely. The operation fails with
+// context.DeadlineExceeded once the timeout elapses. A zero or negative timeout disables it,
+// which is the default. MongoDB reads also send it to the server, as maxTimeMS.
+func WithOperationTimeout(timeout time.Duration) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.operationTimeout = timeout
+	}
+}
+
+// WithoutPreparedStatements makes the SQL handlers (MySQL, PostgreSQL and SQLite) send the
+// queries which load, find, save and remove the executions unprepared, on each call, instead
+// of preparing them once per handler. Use it behind connection poolers which don't support
+// prepared statements (for example, PgBouncer in transaction pooling mode).
+func WithoutPreparedStatements() HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.unpreparedStatements = true
+	}
+}
+
+// newHandlerOptions applies the options over the defaults
+func newHandlerOptions(options []HandlerOption) handlerOptions {
+	opts := handlerOptions{reconnectRetries: defaultReconnectRetries}
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
+
+// operationContext derives the context of a single handler operation from the handler
+// context, bounded by the operation timeout, if any. The returned cancel function must
+// be called once the operation is done.
+func operationContext(
+	ctx context.Context,
+	timeout time.Duration,
+) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
 
 func newDbHandle(dsn, driverName string) (*sql.DB, error) {
 	db, err := sql.Open(driverName, dsn)
@@ -15,3 +81,351 @@ func newDbHandle(dsn, driverName string) (*sql.DB, error) {
 	db.SetConnMaxLifetime(0)
 	return db, err
 }
+
+// executionsQueries holds the queries of a SQL handler which load, find, save and remove the
+// executions, built once per handler. The bulk ones are built for the number of executions.
+type executionsQueries struct {
+	load      string
+	findOne   string
+	save      string
+	remove    string
+	saveAll   func(rows int) string
+	removeAll func(rows int) string
+}
+
+// bulkBatchSize is the maximum number of executions saved or removed by a single statement of
+// the bulk operations, which keeps the statements below the placeholders limit of the
+// databases (999 for the older SQLite versions)
+const bulkBatchSize = 100
+
+// execBatches runs, in a transaction, the statement built by query for each batch of at most
+// bulkBatchSize executions, with the arguments of the batch executions. The executions are
+// deduplicated by version first, the last one wins, since an upsert can't update a row twice.
+func execBatches(
+	ctx context.Context,
+	db *sql.DB,
+	executions []execution.MigrationExecution,
+	query func(rows int) string,
+	args func(exec execution.MigrationExecution) []any,
+) error {
+	executions = uniqueExecutions(executions)
+	if len(executions) == 0 {
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(executions); start += bulkBatchSize {
+		batch := executions[start:min(start+bulkBatchSize, len(executions))]
+		var batchArgs []any
+		for _, exec := range batch {
+			batchArgs = append(batchArgs, args(exec)...)
+		}
+
+		if _, err = tx.ExecContext(ctx, query(len(batch)), batchArgs...); err != nil {
+			return errors.Join(err, tx.Rollback())
+		}
+	}
+
+	return tx.Commit()
+}
+
+// uniqueExecutions deduplicates the executions by version, keeping the last execution of each
+// version at the position of the first one
+func uniqueExecutions(executions []execution.MigrationExecution) []execution.MigrationExecution {
+	positions := make(map[uint64]int, len(executions))
+	unique := make([]execution.MigrationExecution, 0, len(executions))
+	for _, exec := range executions {
+		if position, ok := positions[exec.Version]; ok {
+			unique[position] = exec
+			continue
+		}
+		positions[exec.Version] = len(unique)
+		unique = append(unique, exec)
+	}
+	return unique
+}
+
+// executionArgs returns the arguments of an execution row, in the columns order of the save
+// queries
+func executionArgs(exec execution.MigrationExecution) []any {
+	return []any{exec.Version, exec.ExecutedAtMs, exec.FinishedAtMs, exec.RunId, exec.Checkpoint}
+}
+
+// versionArgs returns the argument which identifies an execution, for the remove queries
+func versionArgs(exec execution.MigrationExecution) []any {
+	return []any{exec.Version}
+}
+
+// placeholders builds count comma separated placeholders, numbered from first ($1, $2) for
+// PostgreSQL, or not (?, ?)
+func placeholders(first int, count int, numbered bool) string {
+	list := make([]string, count)
+	for i := range list {
+		list[i] = "?"
+		if numbered {
+			list[i] = "$" + strconv.Itoa(first+i)
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+// rowsPlaceholders builds the placeholders of rows values, of columns each: (?, ?), (?, ?)
+func rowsPlaceholders(rows int, columns int, numbered bool) string {
+	list := make([]string, rows)
+	for i := range list {
+		list[i] = "(" + placeholders(i*columns+1, columns, numbered) + ")"
+	}
+	return strings.Join(list, ", ")
+}
+
+// inlineArgs replaces the placeholders of the query, numbered ($1, $2) for PostgreSQL or not
+// (?, ?), with the SQL literals of the args, for the statements written to offline scripts
+// (see execution.SaveDescriber). The queries must not have question marks, or dollar signs
+// followed by digits, other than their placeholders. The backslashes of the strings are
+// escaped for MySQL.
+func inlineArgs(query string, args []any, numbered bool, backslashEscapes bool) string {
+	query = strings.TrimSpace(query)
+	if numbered {
+		// the greater numbers first, so $1 doesn't replace the beginning of $10
+		for i := len(args) - 1; i >= 0; i-- {
+			query = strings.ReplaceAll(
+				query, "$"+strconv.Itoa(i+1), sqlLiteral(args[i], backslashEscapes),
+			)
+		}
+		return query
+	}
+
+	for _, arg := range args {
+		query = strings.Replace(query, "?", sqlLiteral(arg, backslashEscapes), 1)
+	}
+	return query
+}
+
+// sqlLiteral returns the SQL literal of an execution value: a quoted string or a number
+func sqlLiteral(value any, backslashEscapes bool) string {
+	text, ok := value.(string)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+
+	if backslashEscapes {
+		text = strings.ReplaceAll(text, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(text, "'", "''") + "'"
+}
+
+// sqlStatements runs the executions queries of the SQL handlers. Unless disabled (see
+// WithoutPreparedStatements), each query is prepared once, on first use, and the statement is
+// reused: database/sql prepares it again on each new connection, for example after a
+// reconnect. A statement which fails is discarded, so it is prepared again on the next call
+// (after a schema change, for example). A query which fails to be prepared (for example,
+// because the executions table is missing) runs directly, so it fails with the same error. A
+// query which fails with a connection error is retried (see WithReconnectRetries).
+type sqlStatements struct {
+	db       *sql.DB
+	prepare  bool
+	mu       sync.Mutex
+	prepared map[string]*sql.Stmt
+
+	// retries is the number of reconnect retries, connErrors the connection errors specific
+	// to the driver
+	retries    int
+	connErrors []error
+}
+
+func newSqlStatements(
+	db *sql.DB,
+	options handlerOptions,
+	connErrors ...error,
+) *sqlStatements {
+	return &sqlStatements{
+		db:         db,
+		prepare:    !options.unpreparedStatements,
+		prepared:   make(map[string]*sql.Stmt),
+		retries:    options.reconnectRetries,
+		connErrors: connErrors,
+	}
+}
+
+// statement returns the prepared statement of the query, nil if it can't be prepared
+func (s *sqlStatements) statement(ctx context.Context, query string) *sql.Stmt {
+	if !s.prepare {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stmt, ok := s.prepared[query]; ok {
+		return stmt
+	}
+
+	stmt, err := s.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil
+	}
+	s.prepared[query] = stmt
+	return stmt
+}
+
+// discard closes the failed statement of the query, unless it was already replaced
+func (s *sqlStatements) discard(query string, stmt *sql.Stmt) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.prepared[query] == stmt {
+		delete(s.prepared, query)
+		_ = stmt.Close()
+	}
+}
+
+func (s *sqlStatements) exec(
+	ctx context.Context,
+	query string,
+	args ...any,
+) (result sql.Result, err error) {
+	err = s.retry(ctx, func() error {
+		result, err = s.execOnce(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+func (s *sqlStatements) execOnce(
+	ctx context.Context,
+	query string,
+	args ...any,
+) (sql.Result, error) {
+	stmt := s.statement(ctx, query)
+	if stmt == nil {
+		return s.db.ExecContext(ctx, query, args...)
+	}
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		s.discard(query, stmt)
+	}
+	return result, err
+}
+
+func (s *sqlStatements) query(
+	ctx context.Context,
+	query string,
+	args ...any,
+) (rows *sql.Rows, err error) {
+	err = s.retry(ctx, func() error {
+		rows, err = s.queryOnce(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (s *sqlStatements) queryOnce(
+	ctx context.Context,
+	query string,
+	args ...any,
+) (*sql.Rows, error) {
+	stmt := s.statement(ctx, query)
+	if stmt == nil {
+		return s.db.QueryContext(ctx, query, args...)
+	}
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		s.discard(query, stmt)
+	}
+	return rows, err
+}
+
+// scanRow runs the query and scans the first row into dest. It fails with sql.ErrNoRows if
+// there is none.
+func (s *sqlStatements) scanRow(ctx context.Context, query string, args []any, dest ...any) error {
+	return s.retry(ctx, func() error {
+		return s.scanRowOnce(ctx, query, args, dest...)
+	})
+}
+
+func (s *sqlStatements) scanRowOnce(
+	ctx context.Context,
+	query string,
+	args []any,
+	dest ...any,
+) error {
+	stmt := s.statement(ctx, query)
+	if stmt == nil {
+		return s.db.QueryRowContext(ctx, query, args...).Scan(dest...)
+	}
+
+	err := stmt.QueryRowContext(ctx, args...).Scan(dest...)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		s.discard(query, stmt)
+	}
+	return err
+}
+
+// close closes the prepared statements
+func (s *sqlStatements) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	for query, stmt := range s.prepared {
+		err = errors.Join(err, stmt.Close())
+		delete(s.prepared, query)
+	}
+	return err
+}
+
+// schemaCommentPrefix prefixes the schema version recorded in the comment of the executions
+// table (see execution.SchemaVersioner)
+const schemaCommentPrefix = "go-migrations schema "
+
+// schemaComment builds the executions table comment which records the schema version
+func schemaComment(version int) string {
+	return schemaCommentPrefix + strconv.Itoa(version)
+}
+
+// parseSchemaComment returns the schema version recorded in the executions table comment, 0 if
+// the comment does not record one
+func parseSchemaComment(comment string) int {
+	rawVersion, ok := strings.CutPrefix(comment, schemaCommentPrefix)
+	if !ok {
+		return 0
+	}
+
+	version, err := strconv.Atoi(rawVersion)
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// requiredPermission is a privilege a run needs, along with the check result
+type requiredPermission struct {
+	granted   bool
+	privilege string
+	target    string
+	reason    string
+}
+
+// missingPermissions lists the required permissions which are not granted
+func missingPermissions(required []requiredPermission) []execution.MissingPermission {
+	var missing []execution.MissingPermission
+	for _, permission := range required {
+		if !permission.granted {
+			missing = append(
+				missing,
+				execution.MissingPermission{
+					Privilege: permission.privilege,
+					Target:    permission.target,
+					Reason:    permission.reason,
+				},
+			)
+		}
+	}
+	return missing
+}
@@ -7,6 +7,8 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/golibry/go-migrations/execution"
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,25 +16,65 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type bsonExecution struct {
-	Version      uint64 `bson:"_id"`
-	ExecutedAtMs uint64 `bson:"executedAtMs"`
-	FinishedAtMs uint64 `bson:"finishedAtMs"`
+// mongoExecutionCodec holds the default field names of the MongoDB handler, which stores the
+// version as the document _id
+var mongoExecutionCodec = ExecutionCodec{
+	Version:      "_id",
+	ExecutedAtMs: "executedAtMs",
+	FinishedAtMs: "finishedAtMs",
+	RunId:        "runId",
+	Checkpoint:   "checkpoint",
 }
 
-func toBsonExecution(exec execution.MigrationExecution) bsonExecution {
-	return bsonExecution{
-		Version:      exec.Version,
-		ExecutedAtMs: exec.ExecutedAtMs,
-		FinishedAtMs: exec.FinishedAtMs,
+// mongoNamespacedExecutionCodec holds the default field names used when the handler tracks
+// executions per namespace. The version is no longer unique across the collection, so it can't
+// be used as the document _id; uniqueness is enforced by an index on (database, version).
+var mongoNamespacedExecutionCodec = ExecutionCodec{
+	Version:      "version",
+	ExecutedAtMs: "executedAtMs",
+	FinishedAtMs: "finishedAtMs",
+	RunId:        "runId",
+	Checkpoint:   "checkpoint",
+}
+
+// document builds the document of the execution, with the field names of the codec
+func (h *MongoHandler) document(exec execution.MigrationExecution) bson.D {
+	doc := make(bson.D, 0, 6)
+	if h.namespace != "" {
+		doc = append(doc, bson.E{Key: "database", Value: h.namespace})
+	}
+
+	doc = append(
+		doc,
+		bson.E{Key: h.codec.Version, Value: exec.Version},
+		bson.E{Key: h.codec.ExecutedAtMs, Value: exec.ExecutedAtMs},
+		bson.E{Key: h.codec.FinishedAtMs, Value: exec.FinishedAtMs},
+	)
+	if exec.RunId != "" {
+		doc = append(doc, bson.E{Key: h.codec.RunId, Value: exec.RunId})
 	}
+
+	// The checkpoint is always set, so saving a finished execution clears it
+	return append(doc, bson.E{Key: h.codec.Checkpoint, Value: exec.Checkpoint})
 }
 
-func toMigrationExecution(exec bsonExecution) execution.MigrationExecution {
+// toMigrationExecution reads the execution from its document, the missing fields are left empty
+func (h *MongoHandler) toMigrationExecution(doc bson.Raw) execution.MigrationExecution {
+	number := func(name string) uint64 {
+		value, _ := doc.Lookup(name).AsInt64OK()
+		return uint64(value)
+	}
+	text := func(name string) string {
+		value, _ := doc.Lookup(name).StringValueOK()
+		return value
+	}
+
 	return execution.MigrationExecution{
-		Version:      exec.Version,
-		ExecutedAtMs: exec.ExecutedAtMs,
-		FinishedAtMs: exec.FinishedAtMs,
+		Version:      number(h.codec.Version),
+		ExecutedAtMs: number(h.codec.ExecutedAtMs),
+		FinishedAtMs: number(h.codec.FinishedAtMs),
+		RunId:        text(h.codec.RunId),
+		Checkpoint:   text(h.codec.Checkpoint),
 	}
 }
 
@@ -40,6 +82,12 @@ func newMongoClient(dsn string, ctx context.Context) (*mongo.Client, error) {
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
 	opts := options.Client().ApplyURI(dsn).SetServerAPIOptions(serverAPI)
 	opts.SetMaxPoolSize(1)
+
+	// Retryable writes are the driver default, but they are enabled explicitly so the executions
+	// survive a primary failover, unless the dsn disables them
+	if opts.RetryWrites == nil {
+		opts.SetRetryWrites(true)
+	}
 	return mongo.Connect(ctx, opts)
 }
 
@@ -49,17 +97,30 @@ type MongoHandler struct {
 	databaseName   string
 	collectionName string
 	ctx            context.Context
+
+	// namespace, when not empty, scopes all executions to a logical database. This allows
+	// a single executions collection to track migrations for several Mongo databases.
+	namespace string
+
+	// operationTimeout bounds each operation, if positive (see WithOperationTimeout)
+	operationTimeout time.Duration
+
+	// codec names the document fields of the executions (see WithExecutionCodec)
+	codec ExecutionCodec
 }
 
 // NewMongoHandler Builds a new MongoHandler. If client is nil, it will try to build a client
-// from the provided dsn. It is recommended to share the same *mongo.Client handle between
-// your application and this handler to efficiently manage connection pools.
+// from the provided dsn, with retryable writes enabled (unless the dsn sets retryWrites=false).
+// It is recommended to share the same *mongo.Client handle between your application and this
+// handler to efficiently manage connection pools; keep retryable writes enabled on it, so an
+// execution saved during a primary failover is retried once on the new primary (see Save).
 func NewMongoHandler(
 	dsn string,
 	databaseName string,
 	collectionName string,
 	ctx context.Context,
 	client *mongo.Client,
+	opts ...HandlerOption,
 ) (*MongoHandler, error) {
 	if client == nil {
 		var err error
@@ -70,26 +131,142 @@ func NewMongoHandler(
 		}
 	}
 
-	return &MongoHandler{client, databaseName, collectionName, ctx}, nil
+	handlerOpts := newHandlerOptions(opts)
+	return &MongoHandler{
+		client:           client,
+		databaseName:     databaseName,
+		collectionName:   collectionName,
+		ctx:              ctx,
+		operationTimeout: handlerOpts.operationTimeout,
+		codec:            handlerOpts.codec.withDefaults(mongoExecutionCodec),
+	}, nil
+}
+
+// NewNamespacedMongoHandler Builds a new MongoHandler which tracks executions per namespace
+// (usually the name of the migrated database). Executions are saved in the collectionName
+// collection from the databaseName database, with a "database" field holding the namespace,
+// so one runner can manage migrations across several Mongo databases from the same cluster
+// by building a handler for each of them.
+func NewNamespacedMongoHandler(
+	dsn string,
+	databaseName string,
+	collectionName string,
+	namespace string,
+	ctx context.Context,
+	client *mongo.Client,
+	opts ...HandlerOption,
+) (*MongoHandler, error) {
+	if strings.TrimSpace(namespace) == "" {
+		return nil, errors.New("failed to build namespaced mongo handler, empty namespace")
+	}
+
+	handler, err := NewMongoHandler(dsn, databaseName, collectionName, ctx, client, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	handler.namespace = namespace
+	handler.codec = newHandlerOptions(opts).codec.withDefaults(mongoNamespacedExecutionCodec)
+	return handler, nil
 }
 
 func (h *MongoHandler) Context() context.Context {
 	return h.ctx
 }
 
+// Ping implements the execution.Pinger interface
+func (h *MongoHandler) Ping(ctx context.Context) error {
+	ctx, cancel := operationContext(ctx, h.operationTimeout)
+	defer cancel()
+
+	return h.client.Ping(ctx, nil)
+}
+
+// Namespace returns the logical database the executions are tracked for. It is empty
+// if the handler is not namespaced.
+func (h *MongoHandler) Namespace() string {
+	return h.namespace
+}
+
+// Target implements the execution.Targeter interface, with the database, the collection and
+// the namespace of the executions. The cluster is not part of it, so the runs against the
+// databases of the same name on different clusters exclude each other.
+func (h *MongoHandler) Target() (string, error) {
+	target := "mongodb://" + h.databaseName + "/" + h.collectionName
+	if h.namespace != "" {
+		target += "/" + h.namespace
+	}
+	return target, nil
+}
+
+func (h *MongoHandler) collection() *mongo.Collection {
+	return h.client.Database(h.databaseName).Collection(h.collectionName)
+}
+
+// filter builds the query which identifies the execution with the given version
+func (h *MongoHandler) filter(version uint64) bson.D {
+	if h.namespace == "" {
+		return bson.D{{Key: h.codec.Version, Value: version}}
+	}
+
+	return bson.D{{Key: "database", Value: h.namespace}, {Key: h.codec.Version, Value: version}}
+}
+
+// maxTime returns the operation timeout sent to the server as maxTimeMS, nil if none is set
+func (h *MongoHandler) maxTime() *time.Duration {
+	if h.operationTimeout <= 0 {
+		return nil
+	}
+	return &h.operationTimeout
+}
+
 func (h *MongoHandler) Init() error {
-	names, err := h.client.Database(h.databaseName).ListCollectionNames(h.ctx, bson.D{})
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	names, err := h.client.Database(h.databaseName).ListCollectionNames(ctx, bson.D{})
 
 	if err != nil {
 		return err
 	}
 
+	collectionExists := false
 	for _, name := range names {
 		if name == h.collectionName {
-			return nil
+			collectionExists = true
+			break
 		}
 	}
 
+	if h.namespace != "" {
+		return h.initNamespaced(ctx, collectionExists)
+	}
+
+	if !collectionExists {
+		if err = h.createCollection(ctx); err != nil {
+			return err
+		}
+	}
+
+	// A version stored in another field than the _id (see WithExecutionCodec) is kept unique by
+	// an index, which is a no-op to create again
+	if h.codec.Version == "_id" {
+		return nil
+	}
+
+	_, err = h.collection().Indexes().CreateOne(
+		ctx,
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: h.codec.Version, Value: 1}},
+			Options: options.Index().SetUnique(true).SetName(h.codec.Version + "_unique"),
+		},
+		&options.CreateIndexesOptions{MaxTime: h.maxTime()},
+	)
+	return err
+}
+
+// createCollection creates the executions collection, with the validation of its documents
+func (h *MongoHandler) createCollection(ctx context.Context) error {
 	collectionOpts := options.CreateCollection()
 	collectionOpts.SetValidator(
 		bson.D{
@@ -100,18 +277,18 @@ func (h *MongoHandler) Init() error {
 				{
 					Key: "properties", Value: bson.D{
 					{
-						Key: "_id", Value: bson.D{
+						Key: h.codec.Version, Value: bson.D{
 						{Key: "bsonType", Value: "long"},
 						{Key: "minimum", Value: 0},
 						{
 							Key: "description",
-							Value: "_id (executed version) must be greater or equal" +
-								" to 0",
+							Value: h.codec.Version + " (executed version) must be greater" +
+								" or equal to 0",
 						},
 					},
 					},
 					{
-						Key: "executedAtMs", Value: bson.D{
+						Key: h.codec.ExecutedAtMs, Value: bson.D{
 						{Key: "bsonType", Value: "long"},
 						{Key: "minimum", Value: 0},
 						{
@@ -121,7 +298,7 @@ func (h *MongoHandler) Init() error {
 					},
 					},
 					{
-						Key: "finishedAtMs", Value: bson.D{
+						Key: h.codec.FinishedAtMs, Value: bson.D{
 						{Key: "bsonType", Value: "long"},
 						{Key: "minimum", Value: 0},
 						{
@@ -138,62 +315,250 @@ func (h *MongoHandler) Init() error {
 	)
 
 	return h.client.Database(h.databaseName).CreateCollection(
-		h.ctx, h.collectionName, collectionOpts,
+		ctx, h.collectionName, collectionOpts,
 	)
 }
 
+// initNamespaced creates the namespaced executions collection (if needed) and the unique
+// index which guarantees one execution per version, for each namespace.
+func (h *MongoHandler) initNamespaced(ctx context.Context, collectionExists bool) error {
+	if !collectionExists {
+		longProperty := func(description string) bson.D {
+			return bson.D{
+				{Key: "bsonType", Value: "long"},
+				{Key: "minimum", Value: 0},
+				{Key: "description", Value: description},
+			}
+		}
+
+		collectionOpts := options.CreateCollection()
+		collectionOpts.SetValidator(
+			bson.D{{
+				Key: "$jsonSchema", Value: bson.D{
+					{Key: "bsonType", Value: "object"},
+					{Key: "title", Value: "namespaced migration execution object validation"},
+					{Key: "required", Value: bson.A{"database", h.codec.Version}},
+					{
+						Key: "properties", Value: bson.D{
+							{
+								Key: "database", Value: bson.D{
+									{Key: "bsonType", Value: "string"},
+									{Key: "description", Value: "database (namespace) must be a string"},
+								},
+							},
+							{
+								Key:   h.codec.Version,
+								Value: longProperty("executed version must be greater or equal to 0"),
+							},
+							{
+								Key:   h.codec.ExecutedAtMs,
+								Value: longProperty("executed at must be greater or equal to 0"),
+							},
+							{
+								Key:   h.codec.FinishedAtMs,
+								Value: longProperty("finished at must be greater or equal to 0"),
+							},
+						},
+					},
+				},
+			}},
+		)
+
+		err := h.client.Database(h.databaseName).CreateCollection(
+			ctx, h.collectionName, collectionOpts,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Creating an index with the same keys and options is a no-op, so this is safe to run
+	// on every Init() call, for every namespace sharing the collection
+	_, err := h.collection().Indexes().CreateOne(
+		ctx,
+		mongo.IndexModel{
+			Keys: bson.D{{Key: "database", Value: 1}, {Key: h.codec.Version, Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetName("database_" + h.codec.Version + "_unique"),
+		},
+		&options.CreateIndexesOptions{MaxTime: h.maxTime()},
+	)
+	return err
+}
+
 func (h *MongoHandler) LoadExecutions() (executions []execution.MigrationExecution, err error) {
-	collection := h.client.Database(h.databaseName).Collection(h.collectionName)
-	cursor, err := collection.Find(h.ctx, bson.D{})
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	if h.namespace != "" {
+		return h.loadNamespacedExecutions(ctx)
+	}
+
+	collection := h.collection()
+	cursor, err := collection.Find(ctx, bson.D{}, &options.FindOptions{MaxTime: h.maxTime()})
 
 	if err != nil {
 		return nil, err
 	}
 
-	var bsonExecutions []bsonExecution
-	if err = cursor.All(h.ctx, &bsonExecutions); err != nil {
+	var docs []bson.Raw
+	if err = cursor.All(ctx, &docs); err != nil {
 		return nil, err
 	}
 
-	var migrationExecutions []execution.MigrationExecution
-	for _, b := range bsonExecutions {
-		migrationExecutions = append(migrationExecutions, toMigrationExecution(b))
+	migrationExecutions := make([]execution.MigrationExecution, 0, len(docs))
+	for _, doc := range docs {
+		migrationExecutions = append(migrationExecutions, h.toMigrationExecution(doc))
 	}
 
 	return migrationExecutions, nil
 }
 
+func (h *MongoHandler) loadNamespacedExecutions(
+	ctx context.Context,
+) ([]execution.MigrationExecution, error) {
+	cursor, err := h.collection().Find(
+		ctx,
+		bson.D{{Key: "database", Value: h.namespace}},
+		&options.FindOptions{MaxTime: h.maxTime()},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []bson.Raw
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	migrationExecutions := make([]execution.MigrationExecution, 0, len(docs))
+	for _, doc := range docs {
+		migrationExecutions = append(migrationExecutions, h.toMigrationExecution(doc))
+	}
+
+	return migrationExecutions, nil
+}
+
+// Save upserts the execution, setting all of its fields, so saving the same execution again
+// has no further effect. With retryable writes, a save interrupted by a primary failover (a
+// network error or a "not writable primary" error) is retried once by the driver, on the new
+// primary, and applied exactly once. If the retry fails as well (for example, the election
+// took longer than the server selection timeout), the error is returned and saving again is
+// safe.
 func (h *MongoHandler) Save(exec execution.MigrationExecution) error {
-	collection := h.client.Database(h.databaseName).Collection(h.collectionName)
-	filter := bson.D{{Key: "_id", Value: exec.Version}}
+	return h.SaveContext(h.ctx, exec)
+}
+
+// SaveContext implements the execution.ContextRepository.SaveContext method
+func (h *MongoHandler) SaveContext(
+	ctx context.Context,
+	exec execution.MigrationExecution,
+) error {
+	ctx, cancel := operationContext(ctx, h.operationTimeout)
+	defer cancel()
+
 	updateOpts := options.Update()
 	updateOpts.SetUpsert(true)
-	_, err := collection.UpdateOne(
-		h.ctx, filter, bson.D{{Key: "$set", Value: toBsonExecution(exec)}}, updateOpts,
-	)
+	update := bson.D{{Key: "$set", Value: h.document(exec)}}
+	_, err := h.collection().UpdateOne(ctx, h.filter(exec.Version), update, updateOpts)
+
+	// Concurrent upserts of the same version may both try to insert the document, the one
+	// which lost is retried as an update of the inserted document
+	if mongo.IsDuplicateKeyError(err) {
+		_, err = h.collection().UpdateOne(ctx, h.filter(exec.Version), update, updateOpts)
+	}
+	return err
+}
+
+// SaveAll implements the execution.BulkRepository.SaveAll method. The executions are upserted
+// with a single bulk write, which the driver splits in batches if needed. Like Save, the bulk
+// write is retried once if concurrent upserts inserted the same version.
+func (h *MongoHandler) SaveAll(executions []execution.MigrationExecution) error {
+	if len(executions) == 0 {
+		return nil
+	}
+
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, 0, len(executions))
+	for _, exec := range executions {
+		models = append(
+			models,
+			mongo.NewUpdateOneModel().
+				SetFilter(h.filter(exec.Version)).
+				SetUpdate(bson.D{{Key: "$set", Value: h.document(exec)}}).
+				SetUpsert(true),
+		)
+	}
+
+	_, err := h.collection().BulkWrite(ctx, models)
+	if mongo.IsDuplicateKeyError(err) {
+		_, err = h.collection().BulkWrite(ctx, models)
+	}
 	return err
 }
 
 func (h *MongoHandler) Remove(exec execution.MigrationExecution) error {
-	collection := h.client.Database(h.databaseName).Collection(h.collectionName)
-	filter := bson.D{{Key: "_id", Value: exec.Version}}
-	_, err := collection.DeleteOne(h.ctx, filter)
+	return h.RemoveContext(h.ctx, exec)
+}
+
+// RemoveContext implements the execution.ContextRepository.RemoveContext method
+func (h *MongoHandler) RemoveContext(
+	ctx context.Context,
+	exec execution.MigrationExecution,
+) error {
+	ctx, cancel := operationContext(ctx, h.operationTimeout)
+	defer cancel()
+
+	_, err := h.collection().DeleteOne(ctx, h.filter(exec.Version))
+	return err
+}
+
+// RemoveAll implements the execution.BulkRepository.RemoveAll method. The executions are
+// removed with a single delete of their versions.
+func (h *MongoHandler) RemoveAll(executions []execution.MigrationExecution) error {
+	if len(executions) == 0 {
+		return nil
+	}
+
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	versions := make(bson.A, 0, len(executions))
+	for _, exec := range executions {
+		versions = append(versions, exec.Version)
+	}
+
+	filter := bson.D{{Key: h.codec.Version, Value: bson.D{{Key: "$in", Value: versions}}}}
+	if h.namespace != "" {
+		filter = bson.D{
+			{Key: "database", Value: h.namespace},
+			{Key: h.codec.Version, Value: bson.D{{Key: "$in", Value: versions}}},
+		}
+	}
+
+	_, err := h.collection().DeleteMany(ctx, filter)
 	return err
 }
 
 func (h *MongoHandler) FindOne(version uint64) (*execution.MigrationExecution, error) {
-	collection := h.client.Database(h.databaseName).Collection(h.collectionName)
-	filter := bson.D{{Key: "_id", Value: version}}
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
 
-	var result bsonExecution
-	err := collection.FindOne(h.ctx, filter).Decode(&result)
+	result := h.collection().FindOne(
+		ctx, h.filter(version), &options.FindOneOptions{MaxTime: h.maxTime()},
+	)
 
+	doc, err := result.Raw()
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return nil, nil
 	} else if err != nil {
 		return nil, err
 	}
 
-	exec := toMigrationExecution(result)
-	return &exec, err
+	exec := h.toMigrationExecution(doc)
+	return &exec, nil
 }
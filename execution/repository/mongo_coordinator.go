@@ -0,0 +1,152 @@
+//go:build mongo
+
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoRunCoordinator observes the executions collection of a MongoHandler through a change
+// stream, allowing application instances to delay their readiness until the migrations run
+// (done by another process) completes. Change streams require a replica set or a sharded
+// cluster.
+type MongoRunCoordinator struct {
+	handler *MongoHandler
+
+	// OnProgress, if set, is called for each finished execution observed while waiting
+	OnProgress func(exec execution.MigrationExecution)
+}
+
+// NewMongoRunCoordinator Builds a new MongoRunCoordinator which watches the executions
+// saved by the provided handler. Namespaced handlers only observe their own namespace.
+func NewMongoRunCoordinator(handler *MongoHandler) *MongoRunCoordinator {
+	return &MongoRunCoordinator{handler: handler}
+}
+
+// WaitUntilCurrent blocks until every migration from the registry has a finished execution
+// in the repository, or until the context is done. It returns immediately, without opening
+// a change stream, if there is nothing left to wait for.
+func (c *MongoRunCoordinator) WaitUntilCurrent(
+	ctx context.Context,
+	registry migration.MigrationsRegistry,
+) error {
+	errMsg := "failed to wait for migrations to be executed"
+
+	pending, err := c.pendingVersions(registry)
+	if err != nil {
+		return fmt.Errorf("%s, failed to load executions with error: %w", errMsg, err)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	stream, err := c.watch(ctx)
+	if err != nil {
+		return fmt.Errorf("%s, failed to open change stream with error: %w", errMsg, err)
+	}
+
+	defer func(stream *mongo.ChangeStream) {
+		_ = stream.Close(context.Background())
+	}(stream)
+
+	// Executions may have finished between the first load and opening the stream
+	if pending, err = c.pendingVersions(registry); err != nil {
+		return fmt.Errorf("%s, failed to load executions with error: %w", errMsg, err)
+	}
+
+	for len(pending) > 0 && stream.Next(ctx) {
+		exec, decodeErr := c.decodeChange(stream)
+		if decodeErr != nil {
+			return fmt.Errorf("%s, failed to decode change with error: %w", errMsg, decodeErr)
+		}
+
+		if exec == nil || !exec.Finished() {
+			continue
+		}
+
+		if _, ok := pending[exec.Version]; ok {
+			delete(pending, exec.Version)
+			if c.OnProgress != nil {
+				c.OnProgress(*exec)
+			}
+		}
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err = stream.Err(); err == nil {
+		err = ctx.Err()
+	}
+
+	return fmt.Errorf("%s, %d migrations still pending: %w", errMsg, len(pending), err)
+}
+
+// pendingVersions builds the set of registered versions without a finished execution
+func (c *MongoRunCoordinator) pendingVersions(
+	registry migration.MigrationsRegistry,
+) (map[uint64]struct{}, error) {
+	executions, err := c.handler.LoadExecutions()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[uint64]struct{})
+	for _, version := range registry.OrderedVersions() {
+		pending[version] = struct{}{}
+	}
+
+	for _, exec := range executions {
+		if exec.Finished() {
+			delete(pending, exec.Version)
+		}
+	}
+
+	return pending, nil
+}
+
+func (c *MongoRunCoordinator) watch(ctx context.Context) (*mongo.ChangeStream, error) {
+	match := bson.D{
+		{
+			Key:   "operationType",
+			Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update", "replace"}}},
+		},
+	}
+
+	if c.handler.namespace != "" {
+		match = append(match, bson.E{Key: "fullDocument.database", Value: c.handler.namespace})
+	}
+
+	streamOpts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	return c.handler.collection().Watch(
+		ctx, mongo.Pipeline{{{Key: "$match", Value: match}}}, streamOpts,
+	)
+}
+
+// decodeChange extracts the execution from the current change stream event. It returns nil
+// if the event has no full document (for example, when it was deleted in the meantime).
+func (c *MongoRunCoordinator) decodeChange(
+	stream *mongo.ChangeStream,
+) (*execution.MigrationExecution, error) {
+	fullDocument, err := stream.Current.LookupErr("fullDocument")
+	if err != nil || fullDocument.Type == bson.TypeNull {
+		return nil, nil
+	}
+
+	doc, ok := fullDocument.DocumentOK()
+	if !ok {
+		return nil, fmt.Errorf("invalid execution document of type %s", fullDocument.Type)
+	}
+
+	exec := c.handler.toMigrationExecution(doc)
+	return &exec, nil
+}
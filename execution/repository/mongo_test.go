@@ -4,11 +4,14 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
 	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
 	mongodbtc "github.com/testcontainers/testcontainers-go/modules/mongodb"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -59,7 +62,13 @@ func (suite *MongoTestSuite) SetupSuite() {
 	client, err := mongo.Connect(context.Background(), opts)
 	suite.Require().NoError(err)
 
-	suite.handler = &MongoHandler{client, suite.dbName, MongoCollectionName, context.Background()}
+	suite.handler = &MongoHandler{
+		client:         client,
+		databaseName:   suite.dbName,
+		collectionName: MongoCollectionName,
+		ctx:            context.Background(),
+		codec:          mongoExecutionCodec,
+	}
 	suite.client = suite.handler.client
 	suite.Require().NoError(suite.handler.Init())
 }
@@ -107,7 +116,7 @@ func (suite *MongoTestSuite) TestItCanLoadAllExecutions() {
 
 	for _, exec := range executions {
 		_, _ = suite.client.Database(suite.dbName).Collection(MongoCollectionName).InsertOne(
-			context.Background(), toBsonExecution(exec),
+			context.Background(), suite.handler.document(exec),
 		)
 	}
 
@@ -167,6 +176,10 @@ func (suite *MongoTestSuite) TestItCanRemoveExecution() {
 	suite.Assert().Len(savedExecs, 0)
 }
 
+func (suite *MongoTestSuite) TestItCanSaveAndRemoveExecutionsInBulk() {
+	assertBulkOperations(&suite.Suite, suite.handler)
+}
+
 func (suite *MongoTestSuite) TestItCanFindOne() {
 	executions := mongoExecutionsProvider()
 
@@ -183,3 +196,388 @@ func (suite *MongoTestSuite) TestItCanFindOne() {
 	suite.Assert().Nil(foundExec)
 	suite.Assert().Nil(err)
 }
+
+func (suite *MongoTestSuite) TestItIdentifiesTheDatabase() {
+	target, err := suite.handler.Target()
+	suite.Require().NoError(err)
+	suite.Assert().Equal("mongodb://"+suite.dbName+"/"+MongoCollectionName, target)
+}
+
+func (suite *MongoTestSuite) TestItFailsToBuildNamespacedHandlerWithEmptyNamespace() {
+	handler, err := NewNamespacedMongoHandler(
+		suite.dsn, suite.dbName, "namespaced_executions", " ", context.Background(), suite.client,
+	)
+	suite.Assert().Nil(handler)
+	suite.Assert().ErrorContains(err, "empty namespace")
+}
+
+func (suite *MongoTestSuite) TestItCanTrackExecutionsPerNamespace() {
+	collectionName := "namespaced_executions"
+	defer func() {
+		_ = suite.client.Database(suite.dbName).Collection(collectionName).
+			Drop(context.Background())
+	}()
+
+	billing, err := NewNamespacedMongoHandler(
+		suite.dsn, suite.dbName, collectionName, "billing", context.Background(), suite.client,
+	)
+	suite.Require().NoError(err)
+	auth, err := NewNamespacedMongoHandler(
+		suite.dsn, suite.dbName, collectionName, "auth", context.Background(), suite.client,
+	)
+	suite.Require().NoError(err)
+	suite.Require().NoError(billing.Init())
+	suite.Require().NoError(auth.Init())
+	suite.Assert().Equal("billing", billing.Namespace())
+
+	executions := mongoExecutionsProvider()
+	for _, exec := range executions {
+		suite.Assert().NoError(billing.Save(exec))
+	}
+
+	// Same version, different namespace, must not overwrite the billing execution
+	authExec := execution.MigrationExecution{Version: 4, ExecutedAtMs: 50, FinishedAtMs: 60}
+	suite.Assert().NoError(auth.Save(authExec))
+
+	billingExecs, err := billing.LoadExecutions()
+	suite.Assert().NoError(err)
+	suite.Assert().Len(billingExecs, len(executions))
+	for _, exec := range billingExecs {
+		suite.Assert().Equal(executions[exec.Version], exec)
+	}
+
+	authExecs, err := auth.LoadExecutions()
+	suite.Assert().NoError(err)
+	suite.Assert().Equal([]execution.MigrationExecution{authExec}, authExecs)
+
+	foundExec, err := billing.FindOne(4)
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(executions[4], *foundExec)
+
+	suite.Assert().NoError(auth.Remove(authExec))
+	foundExec, err = auth.FindOne(4)
+	suite.Assert().NoError(err)
+	suite.Assert().Nil(foundExec)
+
+	foundExec, err = billing.FindOne(4)
+	suite.Assert().NoError(err)
+	suite.Assert().NotNil(foundExec)
+}
+
+// MongoFailoverTestSuite runs against a single node replica set (retryable writes and change
+// streams are not supported by standalone servers), started with the test commands enabled, so
+// the failCommand fail point can kill the connection to the primary in the middle of a save
+type MongoFailoverTestSuite struct {
+	suite.Suite
+	handler   *MongoHandler
+	container *mongodbtc.MongoDBContainer
+}
+
+func TestMongoFailoverTestSuite(t *testing.T) {
+	suite.Run(t, new(MongoFailoverTestSuite))
+}
+
+func (suite *MongoFailoverTestSuite) SetupSuite() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	mongoC, err := mongodbtc.Run(
+		ctx,
+		"mongo:8.2",
+		mongodbtc.WithReplicaSet("rs0"),
+		testcontainers.CustomizeRequestOption(
+			func(req *testcontainers.GenericContainerRequest) error {
+				req.Cmd = append(req.Cmd, "--setParameter", "enableTestCommands=1")
+				return nil
+			},
+		),
+	)
+	suite.Require().NoError(err)
+	suite.container = mongoC
+
+	dsn, err := mongoC.ConnectionString(ctx)
+	suite.Require().NoError(err)
+
+	// The replica set member is not reachable from the host under its configured address
+	suite.handler, err = NewMongoHandler(
+		dsn+"/?directConnection=true", "migrations", MongoCollectionName,
+		context.Background(), nil,
+	)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.handler.Init())
+}
+
+func (suite *MongoFailoverTestSuite) TearDownSuite() {
+	if suite.handler != nil {
+		_ = suite.handler.client.Disconnect(context.Background())
+	}
+	if suite.container != nil {
+		_ = suite.container.Terminate(context.Background())
+	}
+}
+
+func (suite *MongoFailoverTestSuite) TearDownTest() {
+	_ = suite.configureFailPoint(bson.D{{Key: "mode", Value: "off"}})
+	_, _ = suite.handler.collection().DeleteMany(context.Background(), bson.D{})
+}
+
+func (suite *MongoFailoverTestSuite) configureFailPoint(settings bson.D) error {
+	command := append(bson.D{{Key: "configureFailPoint", Value: "failCommand"}}, settings...)
+	return suite.handler.client.Database("admin").
+		RunCommand(context.Background(), command).
+		Err()
+}
+
+func (suite *MongoFailoverTestSuite) countExecutions() int64 {
+	count, err := suite.handler.collection().CountDocuments(context.Background(), bson.D{})
+	suite.Require().NoError(err)
+	return count
+}
+
+func (suite *MongoFailoverTestSuite) TestItRetriesTheSaveWhenThePrimaryDiesMidSave() {
+	suite.Require().NoError(
+		suite.configureFailPoint(
+			bson.D{
+				{Key: "mode", Value: bson.D{{Key: "times", Value: 1}}},
+				{
+					Key: "data", Value: bson.D{
+						{Key: "failCommands", Value: bson.A{"update"}},
+						{Key: "closeConnection", Value: true},
+					},
+				},
+			},
+		),
+	)
+
+	exec := execution.MigrationExecution{Version: 1, ExecutedAtMs: 2, RunId: "r1"}
+	suite.Require().NoError(suite.handler.Save(exec))
+
+	found, err := suite.handler.FindOne(1)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(&exec, found)
+	suite.Assert().Equal(int64(1), suite.countExecutions())
+}
+
+func (suite *MongoFailoverTestSuite) TestItRetriesTheSaveWhenThePrimaryStepsDown() {
+	suite.Require().NoError(
+		suite.configureFailPoint(
+			bson.D{
+				{Key: "mode", Value: bson.D{{Key: "times", Value: 1}}},
+				{
+					Key: "data", Value: bson.D{
+						{Key: "failCommands", Value: bson.A{"update"}},
+						// NotWritablePrimary, as returned by a primary which stepped down
+						{Key: "errorCode", Value: 10107},
+						{Key: "errorLabels", Value: bson.A{"RetryableWriteError"}},
+					},
+				},
+			},
+		),
+	)
+
+	exec := execution.MigrationExecution{Version: 2, ExecutedAtMs: 3, FinishedAtMs: 4}
+	suite.Require().NoError(suite.handler.Save(exec))
+
+	found, err := suite.handler.FindOne(2)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(&exec, found)
+}
+
+func (suite *MongoFailoverTestSuite) TestItCanSaveTheSameExecutionAgainAfterAFailedSave() {
+	// The retry fails as well, as if the election took too long
+	suite.Require().NoError(
+		suite.configureFailPoint(
+			bson.D{
+				{Key: "mode", Value: bson.D{{Key: "times", Value: 2}}},
+				{
+					Key: "data", Value: bson.D{
+						{Key: "failCommands", Value: bson.A{"update"}},
+						{Key: "closeConnection", Value: true},
+					},
+				},
+			},
+		),
+	)
+
+	exec := execution.MigrationExecution{Version: 3, ExecutedAtMs: 4, Checkpoint: "id:100"}
+	suite.Assert().Error(suite.handler.Save(exec))
+
+	suite.Require().NoError(suite.handler.Save(exec))
+	suite.Require().NoError(suite.handler.Save(exec))
+
+	found, err := suite.handler.FindOne(3)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(&exec, found)
+	suite.Assert().Equal(int64(1), suite.countExecutions())
+}
+
+// namespacedHandler builds and initializes a handler of the namespace, sharing the client of
+// the suite handler
+func (suite *MongoFailoverTestSuite) namespacedHandler(
+	collectionName string, namespace string,
+) *MongoHandler {
+	handler, err := NewNamespacedMongoHandler(
+		"", "migrations", collectionName, namespace, context.Background(), suite.handler.client,
+	)
+	suite.Require().NoError(err)
+	suite.Require().NoError(handler.Init())
+	return handler
+}
+
+// changeStreamOpened checks whether a change stream cursor is open on the collection, either
+// idle or waiting for changes
+func (suite *MongoFailoverTestSuite) changeStreamOpened(
+	ctx context.Context, collectionName string,
+) (bool, error) {
+	cursor, err := suite.handler.client.Database("admin").Aggregate(
+		ctx, mongo.Pipeline{
+			{{Key: "$currentOp", Value: bson.D{{Key: "idleCursors", Value: true}}}},
+			{
+				{
+					Key: "$match", Value: bson.D{
+						{Key: "ns", Value: "migrations." + collectionName},
+						{
+							Key: "$or", Value: bson.A{
+								bson.D{{Key: "type", Value: "idleCursor"}},
+								bson.D{{Key: "op", Value: "getmore"}},
+							},
+						},
+					},
+				},
+			},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = cursor.Close(context.Background()) }()
+
+	return cursor.Next(ctx), cursor.Err()
+}
+
+func (suite *MongoFailoverTestSuite) TestCoordinatorReturnsWhenAllMigrationsAreExecuted() {
+	registry := migration.NewGenericRegistry()
+	for _, exec := range mongoExecutionsProvider() {
+		_ = registry.Register(migration.NewDummyMigration(exec.Version))
+		_ = suite.handler.Save(exec)
+	}
+
+	var observed []execution.MigrationExecution
+	coordinator := NewMongoRunCoordinator(suite.handler)
+	coordinator.OnProgress = func(exec execution.MigrationExecution) {
+		observed = append(observed, exec)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	suite.Assert().NoError(coordinator.WaitUntilCurrent(ctx, registry))
+	suite.Assert().Empty(observed)
+}
+
+func (suite *MongoFailoverTestSuite) TestCoordinatorWaitsForTheExecutionsOfItsNamespace() {
+	collectionName := "namespaced_executions"
+	defer func() {
+		_ = suite.handler.client.Database("migrations").Collection(collectionName).
+			Drop(context.Background())
+	}()
+
+	billing := suite.namespacedHandler(collectionName, "billing")
+	auth := suite.namespacedHandler(collectionName, "auth")
+
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	suite.Require().NoError(
+		billing.Save(execution.MigrationExecution{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2}),
+	)
+
+	var observed []execution.MigrationExecution
+	coordinator := NewMongoRunCoordinator(billing)
+	coordinator.OnProgress = func(exec execution.MigrationExecution) {
+		observed = append(observed, exec)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	billingExecs := []execution.MigrationExecution{
+		{Version: 2, ExecutedAtMs: 3, FinishedAtMs: 4},
+		{Version: 3, ExecutedAtMs: 5, FinishedAtMs: 6},
+	}
+	saved := make(chan error, 1)
+	go func() {
+		// the executions are saved once the coordinator watches them, so they are observed
+		// through the change stream rather than loaded
+		for {
+			opened, err := suite.changeStreamOpened(ctx, collectionName)
+			if err != nil {
+				saved <- err
+				return
+			}
+			if opened {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		saved <- errors.Join(
+			// the executions of another namespace don't make the billing migrations current
+			auth.Save(execution.MigrationExecution{Version: 2, ExecutedAtMs: 50, FinishedAtMs: 60}),
+			auth.Save(execution.MigrationExecution{Version: 3, ExecutedAtMs: 50, FinishedAtMs: 60}),
+			// the unfinished executions are not reported
+			billing.Save(execution.MigrationExecution{Version: 2, ExecutedAtMs: 3}),
+			billing.Save(billingExecs[0]),
+			billing.Save(billingExecs[1]),
+		)
+	}()
+
+	suite.Require().NoError(coordinator.WaitUntilCurrent(ctx, registry))
+	suite.Require().NoError(<-saved)
+	suite.Assert().Equal(billingExecs, observed)
+}
+
+func (suite *MongoFailoverTestSuite) TestCoordinatorFailsWhenTheContextIsDoneFirst() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(123))
+
+	// the change stream is opened, but nothing is executed before the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	err := NewMongoRunCoordinator(suite.handler).WaitUntilCurrent(ctx, registry)
+	suite.Assert().ErrorContains(err, "1 migrations still pending")
+	suite.Assert().NotContains(err.Error(), "failed to open change stream")
+}
+
+func BenchmarkMongoHandler(b *testing.B) {
+	ctx := context.Background()
+	mongoC, err := mongodbtc.Run(ctx, "mongo:8.2")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() { _ = mongoC.Terminate(ctx) }()
+
+	dsn, err := mongoC.ConnectionString(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for name, namespace := range map[string]string{"Global": "", "Namespaced": "tenant"} {
+		b.Run(
+			name, func(b *testing.B) {
+				handler, err := NewMongoHandler(dsn, "migrations", MongoCollectionName, ctx, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer func() { _ = handler.client.Disconnect(ctx) }()
+
+				handler.namespace = namespace
+				_ = handler.collection().Drop(ctx)
+				if err = handler.Init(); err != nil {
+					b.Fatal(err)
+				}
+				benchmarkRepository(b, handler)
+			},
+		)
+	}
+}
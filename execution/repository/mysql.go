@@ -6,8 +6,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/golibry/go-migrations/execution"
 )
 
@@ -16,6 +17,17 @@ type MysqlHandler struct {
 	db        *sql.DB
 	tableName string
 	ctx       context.Context
+
+	// operationTimeout bounds each operation, if positive (see WithOperationTimeout)
+	operationTimeout time.Duration
+
+	// codec names the columns of the executions (see WithExecutionCodec)
+	codec ExecutionCodec
+
+	// statements runs the executions queries, prepared unless disabled (see
+	// WithoutPreparedStatements)
+	queries    executionsQueries
+	statements *sqlStatements
 }
 
 // NewMysqlHandler Builds a new MysqlHandler. If db is nil, it will try to build a db handle
@@ -26,6 +38,7 @@ func NewMysqlHandler(
 	tableName string,
 	ctx context.Context,
 	db *sql.DB,
+	opts ...HandlerOption,
 ) (*MysqlHandler, error) {
 	if db == nil {
 		var err error
@@ -36,31 +49,205 @@ func NewMysqlHandler(
 		}
 	}
 
-	return &MysqlHandler{db, tableName, ctx}, nil
+	options := newHandlerOptions(opts)
+	codec := options.codec.withDefaults(sqlExecutionCodec)
+	return &MysqlHandler{
+		db:               db,
+		tableName:        tableName,
+		ctx:              ctx,
+		operationTimeout: options.operationTimeout,
+		codec:            codec,
+		queries:          mysqlQueries(tableName, codec),
+		statements:       newSqlStatements(db, options, mysql.ErrInvalidConn),
+	}, nil
+}
+
+// mysqlQueries builds the executions queries for the table, with the column names of the codec
+func mysqlQueries(tableName string, codec ExecutionCodec) executionsQueries {
+	columns := codec.quoted("`")
+	selectQuery := "SELECT " + columns.columns() + " FROM `" + tableName + "`"
+
+	saveAll := func(rows int) string {
+		return "INSERT INTO `" + tableName + "` (" + columns.columns() + ")" +
+			" VALUES " + rowsPlaceholders(rows, 5, false) + " ON DUPLICATE KEY UPDATE " +
+			columns.assignments("%[1]s = VALUES(%[1]s)")
+	}
+
+	return executionsQueries{
+		load:    selectQuery,
+		findOne: selectQuery + " WHERE " + columns.Version + " = ?",
+		save:    saveAll(1),
+		remove:  "DELETE FROM `" + tableName + "` WHERE " + columns.Version + " = ?",
+		saveAll: saveAll,
+		removeAll: func(rows int) string {
+			return "DELETE FROM `" + tableName + "` WHERE " + columns.Version + " IN (" +
+				placeholders(1, rows, false) + ")"
+		},
+	}
 }
 
 func (h *MysqlHandler) Context() context.Context {
 	return h.ctx
 }
 
+// Ping implements the execution.Pinger interface
+func (h *MysqlHandler) Ping(ctx context.Context) error {
+	ctx, cancel := operationContext(ctx, h.operationTimeout)
+	defer cancel()
+
+	return h.db.PingContext(ctx)
+}
+
+// ReadOnly implements the execution.ReadOnlyChecker interface, with the read_only system
+// variable of the server (enabled on the replicas, and by super_read_only)
+func (h *MysqlHandler) ReadOnly() (bool, error) {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	var readOnly bool
+	if err := h.db.QueryRowContext(ctx, "SELECT @@global.read_only").Scan(&readOnly); err != nil {
+		return false, err
+	}
+	return readOnly, nil
+}
+
+// Target implements the execution.Targeter interface, with the host and the port of the
+// server, the selected database and the executions table
+func (h *MysqlHandler) Target() (string, error) {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	var server string
+	err := h.db.QueryRowContext(
+		ctx, "SELECT CONCAT(@@hostname, ':', @@port, '/', COALESCE(DATABASE(), ''))",
+	).Scan(&server)
+	if err != nil {
+		return "", err
+	}
+	return "mysql://" + server + "/" + h.tableName, nil
+}
+
 func (h *MysqlHandler) Init() error {
-	_, err := h.db.ExecContext(
-		h.ctx,
-		"CREATE TABLE IF NOT EXISTS `"+h.tableName+"` ("+
-			"`version` BIGINT UNSIGNED NOT NULL,"+
-			"`executed_at_ms` BIGINT UNSIGNED NOT NULL,"+
-			"`finished_at_ms` BIGINT UNSIGNED NOT NULL,"+
-			"PRIMARY KEY (`version`)"+
-			") ENGINE=InnoDB CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci",
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	// the statements prepared before the executions table is upgraded are discarded
+	if err := h.statements.close(); err != nil {
+		return err
+	}
+
+	// a table created at the supported schema version (for example, by a DBA running the
+	// InitStatements) needs no change, nor the privileges to change it
+	stored, err := h.storedSchemaVersion(ctx)
+	if err != nil || stored >= execution.SchemaVersion {
+		return err
+	}
+
+	if _, err = h.db.ExecContext(ctx, h.createTableQuery()); err != nil {
+		return err
+	}
+
+	// Tables created by older versions don't have the columns added since
+	if err = h.addMissingColumn(ctx, h.codec.RunId, "VARCHAR(26) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	err = h.addMissingColumn(ctx, h.codec.Checkpoint, "VARCHAR(1024) NOT NULL DEFAULT ''")
+	if err != nil {
+		return err
+	}
+	return h.recordSchemaVersion(ctx)
+}
+
+// StoredSchemaVersion implements the execution.SchemaVersioner interface. The schema version
+// is recorded in the comment of the executions table.
+func (h *MysqlHandler) StoredSchemaVersion() (int, error) {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	return h.storedSchemaVersion(ctx)
+}
+
+func (h *MysqlHandler) storedSchemaVersion(ctx context.Context) (int, error) {
+	var comment string
+	err := h.db.QueryRowContext(
+		ctx,
+		"SELECT TABLE_COMMENT FROM information_schema.TABLES"+
+			" WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?",
+		h.tableName,
+	).Scan(&comment)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return parseSchemaComment(comment), nil
+}
+
+// recordSchemaVersion records the schema version of the executions table, unless a newer
+// library version recorded a greater one
+func (h *MysqlHandler) recordSchemaVersion(ctx context.Context) error {
+	stored, err := h.storedSchemaVersion(ctx)
+	if err != nil || stored >= execution.SchemaVersion {
+		return err
+	}
+
+	_, err = h.db.ExecContext(ctx, h.schemaCommentQuery())
+	return err
+}
+
+// InitStatements implements the execution.InitDescriber interface
+func (h *MysqlHandler) InitStatements() []string {
+	return []string{h.createTableQuery(), h.schemaCommentQuery()}
+}
+
+func (h *MysqlHandler) createTableQuery() string {
+	columns := h.codec.quoted("`")
+	return "CREATE TABLE IF NOT EXISTS `" + h.tableName + "` (" +
+		columns.Version + " BIGINT UNSIGNED NOT NULL," +
+		columns.ExecutedAtMs + " BIGINT UNSIGNED NOT NULL," +
+		columns.FinishedAtMs + " BIGINT UNSIGNED NOT NULL," +
+		columns.RunId + " VARCHAR(26) NOT NULL DEFAULT ''," +
+		columns.Checkpoint + " VARCHAR(1024) NOT NULL DEFAULT ''," +
+		"PRIMARY KEY (" + columns.Version + ")" +
+		") ENGINE=InnoDB CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"
+}
+
+func (h *MysqlHandler) schemaCommentQuery() string {
+	return "ALTER TABLE `" + h.tableName + "` COMMENT = '" +
+		schemaComment(execution.SchemaVersion) + "'"
+}
+
+// addMissingColumn adds the column to the executions table, if the table doesn't have it
+func (h *MysqlHandler) addMissingColumn(
+	ctx context.Context,
+	name string,
+	definition string,
+) error {
+	var columns int
+	err := h.db.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM information_schema.COLUMNS"+
+			" WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+		h.tableName, name,
+	).Scan(&columns)
+	if err != nil || columns > 0 {
+		return err
+	}
+
+	_, err = h.db.ExecContext(
+		ctx,
+		"ALTER TABLE `"+h.tableName+"` ADD COLUMN "+quoteIdentifier(name, "`")+" "+definition,
 	)
 	return err
 }
 
 func (h *MysqlHandler) LoadExecutions() (executions []execution.MigrationExecution, err error) {
-	rows, err := h.db.QueryContext(
-		h.ctx,
-		"SELECT version, executed_at_ms, finished_at_ms FROM `"+h.tableName+"`",
-	)
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	rows, err := h.statements.query(ctx, h.queries.load)
 
 	if err != nil {
 		return executions, err
@@ -74,7 +261,10 @@ func (h *MysqlHandler) LoadExecutions() (executions []execution.MigrationExecuti
 
 	for rows.Next() {
 		var exec execution.MigrationExecution
-		if err = rows.Scan(&exec.Version, &exec.ExecutedAtMs, &exec.FinishedAtMs); err != nil {
+		err = rows.Scan(
+			&exec.Version, &exec.ExecutedAtMs, &exec.FinishedAtMs, &exec.RunId, &exec.Checkpoint,
+		)
+		if err != nil {
 			return executions, err
 		}
 		executions = append(executions, exec)
@@ -85,38 +275,85 @@ func (h *MysqlHandler) LoadExecutions() (executions []execution.MigrationExecuti
 }
 
 func (h *MysqlHandler) Save(execution execution.MigrationExecution) error {
-	_, err := h.db.ExecContext(
-		h.ctx,
-		"INSERT INTO `"+h.tableName+"` VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE "+
-			" `executed_at_ms` = VALUES(`executed_at_ms`), "+
-			" `finished_at_ms` = VALUES(`finished_at_ms`)",
-		execution.Version, execution.ExecutedAtMs, execution.FinishedAtMs,
+	return h.SaveContext(h.ctx, execution)
+}
+
+// SaveContext implements the execution.ContextRepository.SaveContext method
+func (h *MysqlHandler) SaveContext(
+	ctx context.Context,
+	execution execution.MigrationExecution,
+) error {
+	ctx, cancel := operationContext(ctx, h.operationTimeout)
+	defer cancel()
+
+	_, err := h.statements.exec(
+		ctx,
+		h.queries.save,
+		execution.Version, execution.ExecutedAtMs, execution.FinishedAtMs, execution.RunId,
+		execution.Checkpoint,
 	)
 	return err
 }
 
+// SaveAll implements the execution.BulkRepository.SaveAll method. The executions are saved in
+// a transaction, with one statement per bulkBatchSize executions.
+func (h *MysqlHandler) SaveAll(executions []execution.MigrationExecution) error {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	return h.statements.retry(ctx, func() error {
+		return execBatches(ctx, h.db, executions, h.queries.saveAll, executionArgs)
+	})
+}
+
+// SaveStatement implements the execution.SaveDescriber interface
+func (h *MysqlHandler) SaveStatement(execution execution.MigrationExecution) string {
+	return inlineArgs(h.queries.save, executionArgs(execution), false, true)
+}
+
+// RemoveStatement implements the execution.RemoveDescriber interface
+func (h *MysqlHandler) RemoveStatement(execution execution.MigrationExecution) string {
+	return inlineArgs(h.queries.remove, versionArgs(execution), false, true)
+}
+
 func (h *MysqlHandler) Remove(execution execution.MigrationExecution) error {
-	_, err := h.db.ExecContext(
-		h.ctx,
-		"DELETE FROM `"+h.tableName+"` WHERE `version` = ?",
-		execution.Version,
-	)
+	return h.RemoveContext(h.ctx, execution)
+}
+
+// RemoveContext implements the execution.ContextRepository.RemoveContext method
+func (h *MysqlHandler) RemoveContext(
+	ctx context.Context,
+	execution execution.MigrationExecution,
+) error {
+	ctx, cancel := operationContext(ctx, h.operationTimeout)
+	defer cancel()
+
+	_, err := h.statements.exec(ctx, h.queries.remove, execution.Version)
 	return err
 }
 
-func (h *MysqlHandler) FindOne(version uint64) (*execution.MigrationExecution, error) {
-	row := h.db.QueryRowContext(
-		h.ctx,
-		"SELECT version, executed_at_ms, finished_at_ms FROM `"+h.tableName+"` WHERE `version` = ?",
-		version,
-	)
+// RemoveAll implements the execution.BulkRepository.RemoveAll method. The executions are
+// removed in a transaction, with one statement per bulkBatchSize executions.
+func (h *MysqlHandler) RemoveAll(executions []execution.MigrationExecution) error {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
 
-	if row == nil {
-		return nil, nil
-	}
+	return h.statements.retry(ctx, func() error {
+		return execBatches(ctx, h.db, executions, h.queries.removeAll, versionArgs)
+	})
+}
+
+func (h *MysqlHandler) FindOne(version uint64) (*execution.MigrationExecution, error) {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
 
 	var exec execution.MigrationExecution
-	err := row.Scan(&exec.Version, &exec.ExecutedAtMs, &exec.FinishedAtMs)
+	err := h.statements.scanRow(
+		ctx,
+		h.queries.findOne,
+		[]any{version},
+		&exec.Version, &exec.ExecutedAtMs, &exec.FinishedAtMs, &exec.RunId, &exec.Checkpoint,
+	)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -124,5 +361,83 @@ func (h *MysqlHandler) FindOne(version uint64) (*execution.MigrationExecution, e
 		return nil, err
 	}
 
-	return &exec, row.Err()
+	return &exec, nil
+}
+
+// mysqlGrantee builds the current user in the GRANTEE format of the information_schema
+// privileges tables ('user'@'host')
+const mysqlGrantee = "CONCAT('''', SUBSTRING_INDEX(CURRENT_USER(), '@', 1), '''@''', " +
+	"SUBSTRING_INDEX(CURRENT_USER(), '@', -1), '''')"
+
+// CheckPermissions implements the execution.PermissionsChecker interface. It checks the
+// privileges granted globally, on the current database and on the executions table. The
+// privileges granted through roles are not visible in information_schema, so they are
+// reported as missing.
+func (h *MysqlHandler) CheckPermissions() (_ []execution.MissingPermission, err error) {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	var schema sql.NullString
+	if err = h.db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&schema); err != nil {
+		return nil, err
+	}
+
+	if !schema.Valid {
+		return []execution.MissingPermission{
+			{Privilege: "USAGE", Target: "a database", Reason: "no database selected in the DSN"},
+		}, nil
+	}
+
+	rows, err := h.db.QueryContext(
+		ctx,
+		"SELECT PRIVILEGE_TYPE, 'schema' FROM information_schema.USER_PRIVILEGES"+
+			" WHERE GRANTEE = "+mysqlGrantee+
+			" UNION SELECT PRIVILEGE_TYPE, 'schema' FROM information_schema.SCHEMA_PRIVILEGES"+
+			" WHERE GRANTEE = "+mysqlGrantee+" AND DATABASE() LIKE TABLE_SCHEMA"+
+			" UNION SELECT PRIVILEGE_TYPE, 'table' FROM information_schema.TABLE_PRIVILEGES"+
+			" WHERE GRANTEE = "+mysqlGrantee+" AND TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?",
+		h.tableName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		if closeErr := rows.Close(); closeErr != nil && err != nil {
+			err = errors.Join(err, closeErr)
+		}
+	}(rows)
+
+	schemaGranted, tableGranted := map[string]bool{}, map[string]bool{}
+	for rows.Next() {
+		var privilege, level string
+		if err = rows.Scan(&privilege, &level); err != nil {
+			return nil, err
+		}
+		tableGranted[privilege] = true
+		if level == "schema" {
+			schemaGranted[privilege] = true
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	schemaTarget, tableTarget := "database "+schema.String, "table "+h.tableName
+	return missingPermissions(
+		[]requiredPermission{
+			{
+				schemaGranted["CREATE"], "CREATE", schemaTarget,
+				"creating the executions table and the migrated tables",
+			},
+			{
+				schemaGranted["ALTER"], "ALTER", schemaTarget,
+				"upgrading the executions table and the migrated tables",
+			},
+			{tableGranted["SELECT"], "SELECT", tableTarget, "loading the executions"},
+			{tableGranted["INSERT"], "INSERT", tableTarget, "saving the executions"},
+			{tableGranted["UPDATE"], "UPDATE", tableTarget, "saving the executions"},
+			{tableGranted["DELETE"], "DELETE", tableTarget, "removing the executions"},
+		},
+	), nil
 }
@@ -6,6 +6,7 @@ import (
     "context"
     "database/sql"
     "strconv"
+    "strings"
     "testing"
     "time"
 
@@ -121,6 +122,145 @@ func (suite *MysqlTestSuite) TestItCanInitializeExecutionsTable() {
 	suite.Assert().True(tableExists())
 }
 
+func (suite *MysqlTestSuite) TestItChecksThePermissionsOfTheConnectedUser() {
+	missing, err := suite.handler.CheckPermissions()
+	suite.Require().NoError(err)
+	suite.Assert().Empty(missing)
+
+	_, _ = suite.db.Exec("DROP USER IF EXISTS 'reader'@'%'")
+	_, err = suite.db.Exec("CREATE USER 'reader'@'%' IDENTIFIED BY 'reader'")
+	suite.Require().NoError(err)
+	_, err = suite.db.Exec(
+		"GRANT SELECT, INSERT ON `" + suite.dbName + "`.`" + ExecutionsTable + "` TO 'reader'@'%'",
+	)
+	suite.Require().NoError(err)
+
+	readerHandler, err := NewMysqlHandler(
+		strings.Replace(suite.dsn, "root:password@", "reader:reader@", 1),
+		ExecutionsTable,
+		context.Background(),
+		nil,
+	)
+	suite.Require().NoError(err)
+	defer func() {
+		_ = readerHandler.db.Close()
+	}()
+
+	missing, err = readerHandler.CheckPermissions()
+	suite.Require().NoError(err)
+
+	var privileges []string
+	for _, permission := range missing {
+		privileges = append(privileges, permission.Privilege+" on "+permission.Target)
+	}
+	suite.Assert().Equal(
+		[]string{
+			"CREATE on database " + suite.dbName,
+			"ALTER on database " + suite.dbName,
+			"UPDATE on table " + ExecutionsTable,
+			"DELETE on table " + ExecutionsTable,
+		},
+		privileges,
+	)
+}
+
+func (suite *MysqlTestSuite) TestItTellsWhetherTheServerIsReadOnly() {
+	readOnly, err := suite.handler.ReadOnly()
+	suite.Require().NoError(err)
+	suite.Assert().False(readOnly)
+
+	_, err = suite.db.Exec("SET GLOBAL read_only = ON")
+	suite.Require().NoError(err)
+	defer func() {
+		_, _ = suite.db.Exec("SET GLOBAL read_only = OFF")
+	}()
+
+	readOnly, err = suite.handler.ReadOnly()
+	suite.Require().NoError(err)
+	suite.Assert().True(readOnly)
+	suite.Assert().ErrorIs(
+		execution.CheckWritable(suite.handler), execution.ErrReadOnlyRepository,
+	)
+}
+
+func (suite *MysqlTestSuite) TestItIdentifiesTheDatabase() {
+	target, err := suite.handler.Target()
+	suite.Require().NoError(err)
+	suite.Assert().Regexp(`^mysql://[^/]+:3306/[^/]+/`+ExecutionsTable+`$`, target)
+}
+
+func (suite *MysqlTestSuite) TestItAddsMissingColumnsToExistingTables() {
+	_, _ = suite.db.Exec("DROP TABLE IF EXISTS " + ExecutionsTable)
+	_, _ = suite.db.Exec(
+		"CREATE TABLE `" + ExecutionsTable + "` (" +
+			"`version` BIGINT UNSIGNED NOT NULL," +
+			"`executed_at_ms` BIGINT UNSIGNED NOT NULL," +
+			"`finished_at_ms` BIGINT UNSIGNED NOT NULL," +
+			"PRIMARY KEY (`version`))",
+	)
+	_, _ = suite.db.Exec("insert into `" + ExecutionsTable + "` values (1,2,3)")
+
+	suite.Require().NoError(suite.handler.Init())
+	suite.Require().NoError(suite.handler.Init())
+
+	exec := execution.MigrationExecution{
+		Version: 4, ExecutedAtMs: 5, RunId: "r1", Checkpoint: "id:100",
+	}
+	suite.Require().NoError(suite.handler.Save(exec))
+
+	foundExec, err := suite.handler.FindOne(uint64(4))
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(&exec, foundExec)
+
+	foundExec, err = suite.handler.FindOne(uint64(1))
+	suite.Assert().NoError(err)
+	suite.Assert().Equal("", foundExec.RunId)
+}
+
+func (suite *MysqlTestSuite) TestItRecordsTheSchemaVersion() {
+	_, _ = suite.db.Exec("DROP TABLE IF EXISTS " + ExecutionsTable)
+	suite.Require().NoError(suite.handler.Init())
+
+	stored, err := suite.handler.StoredSchemaVersion()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(execution.SchemaVersion, stored)
+	suite.Assert().NoError(execution.CheckSchemaVersion(suite.handler))
+
+	// a newer library version upgraded the table
+	_, err = suite.db.Exec(
+		"ALTER TABLE `" + ExecutionsTable + "` COMMENT = 'go-migrations schema 99'",
+	)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.handler.Init())
+
+	stored, err = suite.handler.StoredSchemaVersion()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(99, stored)
+	var tooNewErr *execution.SchemaTooNewError
+	suite.Assert().ErrorAs(execution.CheckSchemaVersion(suite.handler), &tooNewErr)
+}
+
+func (suite *MysqlTestSuite) TestItDescribesTheStatementsWhichCreateTheTable() {
+	_, _ = suite.db.Exec("DROP TABLE IF EXISTS " + ExecutionsTable)
+
+	// a privileged role runs the statements, instead of Init()
+	for _, statement := range suite.handler.InitStatements() {
+		_, err := suite.db.Exec(statement)
+		suite.Require().NoError(err)
+	}
+
+	stored, err := suite.handler.StoredSchemaVersion()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(execution.SchemaVersion, stored)
+	suite.Assert().NoError(suite.handler.Init())
+
+	exec := execution.MigrationExecution{Version: 1, ExecutedAtMs: 2, RunId: "r1"}
+	suite.Require().NoError(suite.handler.Save(exec))
+	foundExec, err := suite.handler.FindOne(uint64(1))
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(&exec, foundExec)
+}
+
 func executionsProvider() map[uint64]execution.MigrationExecution {
 	return map[uint64]execution.MigrationExecution{
 		uint64(1): {Version: 1, ExecutedAtMs: 2, FinishedAtMs: 3},
@@ -134,7 +274,8 @@ func (suite *MysqlTestSuite) TestItCanLoadExecutions() {
 
 	for _, exec := range executions {
 		_, _ = suite.db.Exec(
-			"insert into " + ExecutionsTable + " values (" +
+			"insert into " + ExecutionsTable +
+				" (version, executed_at_ms, finished_at_ms) values (" +
 				strconv.Itoa(int(exec.Version)) + "," +
 				strconv.Itoa(int(exec.ExecutedAtMs)) + "," +
 				strconv.Itoa(int(exec.FinishedAtMs)) + ")",
@@ -175,7 +316,10 @@ func (suite *MysqlTestSuite) TestItFailsToLoadExecutionsFromInvalidRepoData() {
 		"alter table `" + ExecutionsTable +
 			"` modify column `finished_at_ms` bigint unsigned default null",
 	)
-	_, _ = suite.db.Exec("insert into `" + ExecutionsTable + "` values (1,2,1), (3,4,null)")
+	_, _ = suite.db.Exec(
+		"insert into `" + ExecutionsTable +
+			"` (version, executed_at_ms, finished_at_ms) values (1,2,1), (3,4,null)",
+	)
 	execs, err := suite.handler.LoadExecutions()
 	suite.Assert().Len(execs, 1)
 	suite.Assert().Error(err)
@@ -227,12 +371,17 @@ func (suite *MysqlTestSuite) TestItCanRemoveExecution() {
 	suite.Assert().Len(savedExecs, 0)
 }
 
+func (suite *MysqlTestSuite) TestItCanSaveAndRemoveExecutionsInBulk() {
+	assertBulkOperations(&suite.Suite, suite.handler)
+}
+
 func (suite *MysqlTestSuite) TestItCanFindOne() {
 	executions := executionsProvider()
 
 	for _, exec := range executions {
 		_, _ = suite.db.Exec(
-			"insert into " + ExecutionsTable + " values (" +
+			"insert into " + ExecutionsTable +
+				" (version, executed_at_ms, finished_at_ms) values (" +
 				strconv.Itoa(int(exec.Version)) + "," +
 				strconv.Itoa(int(exec.ExecutedAtMs)) + "," +
 				strconv.Itoa(int(exec.FinishedAtMs)) + ")",
@@ -248,3 +397,39 @@ func (suite *MysqlTestSuite) TestItCanFindOne() {
 	suite.Assert().Nil(foundExec)
 	suite.Assert().Nil(err)
 }
+
+func BenchmarkMysqlHandler(b *testing.B) {
+	ctx := context.Background()
+	mysqlC, err := mysqltc.Run(
+		ctx,
+		"mysql:8.0",
+		mysqltc.WithDatabase("migrations"),
+		mysqltc.WithUsername("root"),
+		mysqltc.WithPassword("password"),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() { _ = mysqlC.Terminate(ctx) }()
+
+	dsn, err := mysqlC.ConnectionString(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkSqlHandler(
+		b, func(b *testing.B, opts ...HandlerOption) execution.Repository {
+			handler, err := NewMysqlHandler(dsn, ExecutionsTable, ctx, nil, opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Cleanup(func() { _ = handler.db.Close() })
+
+			_, _ = handler.db.Exec("DROP TABLE IF EXISTS " + ExecutionsTable)
+			if err = handler.Init(); err != nil {
+				b.Fatal(err)
+			}
+			return handler
+		},
+	)
+}
@@ -7,6 +7,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/golibry/go-migrations/execution"
 	_ "github.com/lib/pq"
@@ -17,6 +18,17 @@ type PostgresHandler struct {
 	db        *sql.DB
 	tableName string
 	ctx       context.Context
+
+	// operationTimeout bounds each operation, if positive (see WithOperationTimeout)
+	operationTimeout time.Duration
+
+	// codec names the columns of the executions (see WithExecutionCodec)
+	codec ExecutionCodec
+
+	// statements runs the executions queries, prepared unless disabled (see
+	// WithoutPreparedStatements)
+	queries    executionsQueries
+	statements *sqlStatements
 }
 
 // NewPostgresHandler Builds a new PostgresHandler. If db is nil, it will try to build a db handle
@@ -27,6 +39,7 @@ func NewPostgresHandler(
 	tableName string,
 	ctx context.Context,
 	db *sql.DB,
+	opts ...HandlerOption,
 ) (*PostgresHandler, error) {
 	if db == nil {
 		var err error
@@ -37,33 +50,204 @@ func NewPostgresHandler(
 		}
 	}
 
-	return &PostgresHandler{db, tableName, ctx}, nil
+	options := newHandlerOptions(opts)
+	codec := options.codec.withDefaults(sqlExecutionCodec)
+	return &PostgresHandler{
+		db:               db,
+		tableName:        tableName,
+		ctx:              ctx,
+		operationTimeout: options.operationTimeout,
+		codec:            codec,
+		queries:          postgresQueries(tableName, codec),
+		statements:       newSqlStatements(db, options),
+	}, nil
+}
+
+// postgresQueries builds the executions queries for the table
+func postgresQueries(tableName string, codec ExecutionCodec) executionsQueries {
+	columns := codec.quoted(`"`)
+	selectQuery := fmt.Sprintf(`SELECT %s FROM "%s"`, columns.columns(), tableName)
+
+	// PostgresSQL uses ON CONFLICT for upsert operations
+	saveAll := func(rows int) string {
+		return fmt.Sprintf(
+			`
+		INSERT INTO "%s" (%s)
+		VALUES %s
+		ON CONFLICT (%s) DO UPDATE SET %s
+		`,
+			tableName, columns.columns(), rowsPlaceholders(rows, 5, true), columns.Version,
+			columns.assignments("%[1]s = EXCLUDED.%[1]s"),
+		)
+	}
+
+	return executionsQueries{
+		load:    selectQuery,
+		findOne: selectQuery + ` WHERE ` + columns.Version + ` = $1`,
+		save:    saveAll(1),
+		remove: fmt.Sprintf(
+			`DELETE FROM "%s" WHERE %s = $1`, tableName, columns.Version,
+		),
+		saveAll: saveAll,
+		removeAll: func(rows int) string {
+			return fmt.Sprintf(
+				`DELETE FROM "%s" WHERE %s IN (%s)`,
+				tableName, columns.Version, placeholders(1, rows, true),
+			)
+		},
+	}
 }
 
 func (h *PostgresHandler) Context() context.Context {
 	return h.ctx
 }
 
+// Ping implements the execution.Pinger interface
+func (h *PostgresHandler) Ping(ctx context.Context) error {
+	ctx, cancel := operationContext(ctx, h.operationTimeout)
+	defer cancel()
+
+	return h.db.PingContext(ctx)
+}
+
+// ReadOnly implements the execution.ReadOnlyChecker interface, with the transaction_read_only
+// setting of the session (enabled on the hot standby servers, and by
+// default_transaction_read_only)
+func (h *PostgresHandler) ReadOnly() (bool, error) {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	var readOnly string
+	if err := h.db.QueryRowContext(ctx, "SHOW transaction_read_only").Scan(&readOnly); err != nil {
+		return false, err
+	}
+	return readOnly == "on", nil
+}
+
+// Target implements the execution.Targeter interface, with the address and the port of the
+// server (empty on Unix socket connections), the current database and schema, and the
+// executions table
+func (h *PostgresHandler) Target() (string, error) {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	var server string
+	err := h.db.QueryRowContext(
+		ctx,
+		"SELECT COALESCE(host(inet_server_addr()), '') || ':' ||"+
+			" COALESCE(inet_server_port()::text, '') || '/' || current_database() || '/' ||"+
+			" COALESCE(current_schema(), '')",
+	).Scan(&server)
+	if err != nil {
+		return "", err
+	}
+	return "postgres://" + server + "/" + h.tableName, nil
+}
+
 func (h *PostgresHandler) Init() error {
-	query := fmt.Sprintf(
-		`
-		CREATE TABLE IF NOT EXISTS "%s" (
-			version BIGINT NOT NULL,
-			executed_at_ms BIGINT NOT NULL,
-			finished_at_ms BIGINT NOT NULL,
-			PRIMARY KEY (version)
-		)
-		`,
-		h.tableName,
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	// the statements prepared before the executions table is upgraded are discarded
+	if err := h.statements.close(); err != nil {
+		return err
+	}
+
+	// a table created at the supported schema version (for example, by a DBA running the
+	// InitStatements) needs no change, nor the privileges to change it
+	stored, err := h.storedSchemaVersion(ctx)
+	if err != nil || stored >= execution.SchemaVersion {
+		return err
+	}
+
+	if _, err = h.db.ExecContext(ctx, h.createTableQuery()); err != nil {
+		return err
+	}
+
+	// Tables created by older versions don't have the columns added since
+	columns := h.codec.quoted(`"`)
+	_, err = h.db.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`ALTER TABLE "%s"
+			ADD COLUMN IF NOT EXISTS %s VARCHAR(26) NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS %s VARCHAR(1024) NOT NULL DEFAULT ''`,
+			h.tableName, columns.RunId, columns.Checkpoint,
+		),
 	)
+	if err != nil {
+		return err
+	}
+	return h.recordSchemaVersion(ctx)
+}
+
+// StoredSchemaVersion implements the execution.SchemaVersioner interface. The schema version
+// is recorded in the comment of the executions table.
+func (h *PostgresHandler) StoredSchemaVersion() (int, error) {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
 
-	_, err := h.db.ExecContext(h.ctx, query)
+	return h.storedSchemaVersion(ctx)
+}
+
+func (h *PostgresHandler) storedSchemaVersion(ctx context.Context) (int, error) {
+	var comment string
+	err := h.db.QueryRowContext(
+		ctx,
+		`SELECT COALESCE(obj_description(to_regclass(quote_ident($1)), 'pg_class'), '')`,
+		h.tableName,
+	).Scan(&comment)
+	if err != nil {
+		return 0, err
+	}
+
+	return parseSchemaComment(comment), nil
+}
+
+// recordSchemaVersion records the schema version of the executions table, unless a newer
+// library version recorded a greater one
+func (h *PostgresHandler) recordSchemaVersion(ctx context.Context) error {
+	stored, err := h.storedSchemaVersion(ctx)
+	if err != nil || stored >= execution.SchemaVersion {
+		return err
+	}
+
+	_, err = h.db.ExecContext(ctx, h.schemaCommentQuery())
 	return err
 }
 
+// InitStatements implements the execution.InitDescriber interface
+func (h *PostgresHandler) InitStatements() []string {
+	return []string{h.createTableQuery(), h.schemaCommentQuery()}
+}
+
+func (h *PostgresHandler) createTableQuery() string {
+	columns := h.codec.quoted(`"`)
+	return fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS "%s" (
+	%s BIGINT NOT NULL,
+	%s BIGINT NOT NULL,
+	%s BIGINT NOT NULL,
+	%s VARCHAR(26) NOT NULL DEFAULT '',
+	%s VARCHAR(1024) NOT NULL DEFAULT '',
+	PRIMARY KEY (%s)
+)`,
+		h.tableName, columns.Version, columns.ExecutedAtMs, columns.FinishedAtMs, columns.RunId,
+		columns.Checkpoint, columns.Version,
+	)
+}
+
+func (h *PostgresHandler) schemaCommentQuery() string {
+	return fmt.Sprintf(
+		`COMMENT ON TABLE "%s" IS '%s'`, h.tableName, schemaComment(execution.SchemaVersion),
+	)
+}
+
 func (h *PostgresHandler) LoadExecutions() (executions []execution.MigrationExecution, err error) {
-	query := fmt.Sprintf(`SELECT * FROM "%s"`, h.tableName)
-	rows, err := h.db.QueryContext(h.ctx, query)
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	rows, err := h.statements.query(ctx, h.queries.load)
 
 	if err != nil {
 		return executions, err
@@ -77,7 +261,10 @@ func (h *PostgresHandler) LoadExecutions() (executions []execution.MigrationExec
 
 	for rows.Next() {
 		var exec execution.MigrationExecution
-		if err = rows.Scan(&exec.Version, &exec.ExecutedAtMs, &exec.FinishedAtMs); err != nil {
+		err = rows.Scan(
+			&exec.Version, &exec.ExecutedAtMs, &exec.FinishedAtMs, &exec.RunId, &exec.Checkpoint,
+		)
+		if err != nil {
 			return executions, err
 		}
 		executions = append(executions, exec)
@@ -88,51 +275,157 @@ func (h *PostgresHandler) LoadExecutions() (executions []execution.MigrationExec
 }
 
 func (h *PostgresHandler) Save(execution execution.MigrationExecution) error {
-	// PostgresSQL uses ON CONFLICT for upsert operations
-	query := fmt.Sprintf(
-		`
-		INSERT INTO "%s" (version, executed_at_ms, finished_at_ms) 
-		VALUES ($1, $2, $3) 
-		ON CONFLICT (version) DO UPDATE SET 
-		executed_at_ms = $2, 
-		finished_at_ms = $3
-		`,
-		h.tableName,
-	)
+	return h.SaveContext(h.ctx, execution)
+}
 
-	_, err := h.db.ExecContext(
-		h.ctx,
-		query,
-		execution.Version, execution.ExecutedAtMs, execution.FinishedAtMs,
+// SaveContext implements the execution.ContextRepository.SaveContext method
+func (h *PostgresHandler) SaveContext(
+	ctx context.Context,
+	execution execution.MigrationExecution,
+) error {
+	ctx, cancel := operationContext(ctx, h.operationTimeout)
+	defer cancel()
+
+	_, err := h.statements.exec(
+		ctx,
+		h.queries.save,
+		execution.Version, execution.ExecutedAtMs, execution.FinishedAtMs, execution.RunId,
+		execution.Checkpoint,
 	)
 	return err
 }
 
+// SaveAll implements the execution.BulkRepository.SaveAll method. The executions are saved in
+// a transaction, with one statement per bulkBatchSize executions.
+func (h *PostgresHandler) SaveAll(executions []execution.MigrationExecution) error {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	return h.statements.retry(ctx, func() error {
+		return execBatches(ctx, h.db, executions, h.queries.saveAll, executionArgs)
+	})
+}
+
+// SaveStatement implements the execution.SaveDescriber interface
+func (h *PostgresHandler) SaveStatement(execution execution.MigrationExecution) string {
+	return inlineArgs(h.queries.save, executionArgs(execution), true, false)
+}
+
+// RemoveStatement implements the execution.RemoveDescriber interface
+func (h *PostgresHandler) RemoveStatement(execution execution.MigrationExecution) string {
+	return inlineArgs(h.queries.remove, versionArgs(execution), true, false)
+}
+
 func (h *PostgresHandler) Remove(execution execution.MigrationExecution) error {
-	query := fmt.Sprintf(`DELETE FROM "%s" WHERE version = $1`, h.tableName)
-	_, err := h.db.ExecContext(h.ctx, query, execution.Version)
+	return h.RemoveContext(h.ctx, execution)
+}
+
+// RemoveContext implements the execution.ContextRepository.RemoveContext method
+func (h *PostgresHandler) RemoveContext(
+	ctx context.Context,
+	execution execution.MigrationExecution,
+) error {
+	ctx, cancel := operationContext(ctx, h.operationTimeout)
+	defer cancel()
+
+	_, err := h.statements.exec(ctx, h.queries.remove, execution.Version)
 	return err
 }
 
+// RemoveAll implements the execution.BulkRepository.RemoveAll method. The executions are
+// removed in a transaction, with one statement per bulkBatchSize executions.
+func (h *PostgresHandler) RemoveAll(executions []execution.MigrationExecution) error {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	return h.statements.retry(ctx, func() error {
+		return execBatches(ctx, h.db, executions, h.queries.removeAll, versionArgs)
+	})
+}
+
 func (h *PostgresHandler) FindOne(version uint64) (*execution.MigrationExecution, error) {
-	query := fmt.Sprintf(
-		`SELECT version, executed_at_ms, finished_at_ms FROM "%s" WHERE version = $1`,
-		h.tableName,
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	var exec execution.MigrationExecution
+	err := h.statements.scanRow(
+		ctx,
+		h.queries.findOne,
+		[]any{version},
+		&exec.Version, &exec.ExecutedAtMs, &exec.FinishedAtMs, &exec.RunId, &exec.Checkpoint,
 	)
-	row := h.db.QueryRowContext(h.ctx, query, version)
 
-	if row == nil {
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
+	} else if err != nil {
+		return nil, err
 	}
 
-	var exec execution.MigrationExecution
-	err := row.Scan(&exec.Version, &exec.ExecutedAtMs, &exec.FinishedAtMs)
+	return &exec, nil
+}
+
+// CheckPermissions implements the execution.PermissionsChecker interface. It checks the
+// privileges on the current schema and, if the executions table exists, on the table.
+// Changing a table requires its ownership in PostgreSQL, so the ownership (direct or through
+// a role) of the executions table is checked too.
+func (h *PostgresHandler) CheckPermissions() ([]execution.MissingPermission, error) {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
 
+	var schema sql.NullString
+	var usage, create bool
+	err := h.db.QueryRowContext(
+		ctx,
+		`SELECT current_schema(),
+		COALESCE(has_schema_privilege(current_schema(), 'USAGE'), false),
+		COALESCE(has_schema_privilege(current_schema(), 'CREATE'), false)`,
+	).Scan(&schema, &usage, &create)
+	if err != nil {
+		return nil, err
+	}
+
+	if !schema.Valid {
+		return []execution.MissingPermission{
+			{
+				Privilege: "USAGE",
+				Target:    "the search_path schemas",
+				Reason:    "no schema to create the executions table in",
+			},
+		}, nil
+	}
+
+	schemaTarget := "schema " + schema.String
+	required := []requiredPermission{
+		{usage, "USAGE", schemaTarget, "accessing the schema objects"},
+		{create, "CREATE", schemaTarget, "creating the executions table and the migrated objects"},
+	}
+
+	var canSelect, canInsert, canUpdate, canDelete, isOwner bool
+	err = h.db.QueryRowContext(
+		ctx,
+		`SELECT has_table_privilege(c.oid, 'SELECT'), has_table_privilege(c.oid, 'INSERT'),
+		has_table_privilege(c.oid, 'UPDATE'), has_table_privilege(c.oid, 'DELETE'),
+		pg_has_role(c.relowner, 'MEMBER')
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = current_schema() AND c.relname = $1`,
+		h.tableName,
+	).Scan(&canSelect, &canInsert, &canUpdate, &canDelete, &isOwner)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
+		// the table is created by Init(), which needs the CREATE privilege checked above
+		return missingPermissions(required), nil
 	} else if err != nil {
 		return nil, err
 	}
 
-	return &exec, row.Err()
+	tableTarget := "table " + h.tableName
+	required = append(
+		required,
+		requiredPermission{canSelect, "SELECT", tableTarget, "loading the executions"},
+		requiredPermission{canInsert, "INSERT", tableTarget, "saving the executions"},
+		requiredPermission{canUpdate, "UPDATE", tableTarget, "saving the executions"},
+		requiredPermission{canDelete, "DELETE", tableTarget, "removing the executions"},
+		requiredPermission{isOwner, "OWNERSHIP", tableTarget, "upgrading the table"},
+	)
+
+	return missingPermissions(required), nil
 }
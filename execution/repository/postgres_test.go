@@ -5,6 +5,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"testing"
 	"time"
 
@@ -133,6 +134,152 @@ func (suite *PostgresTestSuite) TestItCanInitializeExecutionsTable() {
 	suite.Assert().True(tableExists())
 }
 
+func (suite *PostgresTestSuite) TestItChecksThePermissionsOfTheConnectedRole() {
+	missing, err := suite.handler.CheckPermissions()
+	suite.Require().NoError(err)
+	suite.Assert().Empty(missing)
+
+	_, _ = suite.db.Exec(`DROP ROLE IF EXISTS reader`)
+	_, err = suite.db.Exec(`CREATE ROLE reader LOGIN PASSWORD 'reader'`)
+	suite.Require().NoError(err)
+	_, _ = suite.db.Exec(`REVOKE CREATE ON SCHEMA public FROM PUBLIC`)
+	_, _ = suite.db.Exec(`GRANT SELECT ON "` + PostgresExecutionsTable + `" TO reader`)
+
+	readerHandler, err := NewPostgresHandler(
+		strings.Replace(suite.dsn, "postgres:postgres@", "reader:reader@", 1),
+		PostgresExecutionsTable,
+		context.Background(),
+		nil,
+	)
+	suite.Require().NoError(err)
+	defer func() {
+		_ = readerHandler.db.Close()
+	}()
+
+	missing, err = readerHandler.CheckPermissions()
+	suite.Require().NoError(err)
+
+	var privileges []string
+	for _, permission := range missing {
+		privileges = append(privileges, permission.Privilege+" on "+permission.Target)
+	}
+	suite.Assert().Equal(
+		[]string{
+			"CREATE on schema public",
+			"INSERT on table " + PostgresExecutionsTable,
+			"UPDATE on table " + PostgresExecutionsTable,
+			"DELETE on table " + PostgresExecutionsTable,
+			"OWNERSHIP on table " + PostgresExecutionsTable,
+		},
+		privileges,
+	)
+}
+
+func (suite *PostgresTestSuite) TestItIdentifiesTheDatabase() {
+	target, err := suite.handler.Target()
+	suite.Require().NoError(err)
+	suite.Assert().Regexp(
+		`^postgres://[^/]*:5432/[^/]+/public/`+PostgresExecutionsTable+`$`, target,
+	)
+}
+
+func (suite *PostgresTestSuite) TestItTellsWhetherTheSessionIsReadOnly() {
+	readOnly, err := suite.handler.ReadOnly()
+	suite.Require().NoError(err)
+	suite.Assert().False(readOnly)
+
+	readOnlyHandler, err := NewPostgresHandler(
+		suite.dsn, PostgresExecutionsTable, context.Background(), nil,
+	)
+	suite.Require().NoError(err)
+	defer func() {
+		_ = readOnlyHandler.db.Close()
+	}()
+	readOnlyHandler.db.SetMaxOpenConns(1)
+	_, err = readOnlyHandler.db.Exec("SET SESSION CHARACTERISTICS AS TRANSACTION READ ONLY")
+	suite.Require().NoError(err)
+
+	readOnly, err = readOnlyHandler.ReadOnly()
+	suite.Require().NoError(err)
+	suite.Assert().True(readOnly)
+	suite.Assert().ErrorIs(
+		execution.CheckWritable(readOnlyHandler), execution.ErrReadOnlyRepository,
+	)
+}
+
+func (suite *PostgresTestSuite) TestItAddsMissingColumnsToExistingTables() {
+	_, _ = suite.db.Exec(`DROP TABLE IF EXISTS "` + PostgresExecutionsTable + `"`)
+	_, _ = suite.db.Exec(
+		`CREATE TABLE "` + PostgresExecutionsTable + `" (
+			version BIGINT NOT NULL,
+			executed_at_ms BIGINT NOT NULL,
+			finished_at_ms BIGINT NOT NULL,
+			PRIMARY KEY (version)
+		)`,
+	)
+	_, _ = suite.db.Exec(`INSERT INTO "` + PostgresExecutionsTable + `" VALUES (1, 2, 3)`)
+
+	suite.Require().NoError(suite.handler.Init())
+	suite.Require().NoError(suite.handler.Init())
+
+	exec := execution.MigrationExecution{
+		Version: 4, ExecutedAtMs: 5, RunId: "r1", Checkpoint: "id:100",
+	}
+	suite.Require().NoError(suite.handler.Save(exec))
+
+	foundExec, err := suite.handler.FindOne(uint64(4))
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(&exec, foundExec)
+
+	foundExec, err = suite.handler.FindOne(uint64(1))
+	suite.Assert().NoError(err)
+	suite.Assert().Equal("", foundExec.RunId)
+}
+
+func (suite *PostgresTestSuite) TestItRecordsTheSchemaVersion() {
+	_, _ = suite.db.Exec(`DROP TABLE IF EXISTS "` + PostgresExecutionsTable + `"`)
+	suite.Require().NoError(suite.handler.Init())
+
+	stored, err := suite.handler.StoredSchemaVersion()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(execution.SchemaVersion, stored)
+	suite.Assert().NoError(execution.CheckSchemaVersion(suite.handler))
+
+	// a newer library version upgraded the table
+	_, err = suite.db.Exec(
+		`COMMENT ON TABLE "` + PostgresExecutionsTable + `" IS 'go-migrations schema 99'`,
+	)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.handler.Init())
+
+	stored, err = suite.handler.StoredSchemaVersion()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(99, stored)
+	var tooNewErr *execution.SchemaTooNewError
+	suite.Assert().ErrorAs(execution.CheckSchemaVersion(suite.handler), &tooNewErr)
+}
+
+func (suite *PostgresTestSuite) TestItDescribesTheStatementsWhichCreateTheTable() {
+	_, _ = suite.db.Exec(`DROP TABLE IF EXISTS "` + PostgresExecutionsTable + `"`)
+
+	// a privileged role runs the statements, instead of Init()
+	for _, statement := range suite.handler.InitStatements() {
+		_, err := suite.db.Exec(statement)
+		suite.Require().NoError(err)
+	}
+
+	stored, err := suite.handler.StoredSchemaVersion()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(execution.SchemaVersion, stored)
+	suite.Assert().NoError(suite.handler.Init())
+
+	exec := execution.MigrationExecution{Version: 1, ExecutedAtMs: 2, RunId: "r1"}
+	suite.Require().NoError(suite.handler.Save(exec))
+	foundExec, err := suite.handler.FindOne(uint64(1))
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(&exec, foundExec)
+}
+
 func postgresExecutionsProvider() map[uint64]execution.MigrationExecution {
 	return map[uint64]execution.MigrationExecution{
 		uint64(1): {Version: 1, ExecutedAtMs: 2, FinishedAtMs: 3},
@@ -146,7 +293,8 @@ func (suite *PostgresTestSuite) TestItCanLoadExecutions() {
 
 	for _, exec := range executions {
 		_, _ = suite.db.Exec(
-			`INSERT INTO "`+PostgresExecutionsTable+`" VALUES ($1, $2, $3)`,
+			`INSERT INTO "`+PostgresExecutionsTable+`" (version, executed_at_ms, finished_at_ms)
+			VALUES ($1, $2, $3)`,
 			exec.Version, exec.ExecutedAtMs, exec.FinishedAtMs,
 		)
 	}
@@ -186,7 +334,7 @@ func (suite *PostgresTestSuite) TestItFailsToLoadExecutionsFromInvalidRepoData()
          ALTER COLUMN finished_at_ms DROP NOT NULL`,
 	)
 	_, _ = suite.db.Exec(
-		`INSERT INTO "` + PostgresExecutionsTable + `" 
+		`INSERT INTO "` + PostgresExecutionsTable + `" (version, executed_at_ms, finished_at_ms)
          VALUES (1, 2, 1), (3, 4, NULL)`,
 	)
 	execs, err := suite.handler.LoadExecutions()
@@ -240,12 +388,17 @@ func (suite *PostgresTestSuite) TestItCanRemoveExecution() {
 	suite.Assert().Len(savedExecs, 0)
 }
 
+func (suite *PostgresTestSuite) TestItCanSaveAndRemoveExecutionsInBulk() {
+	assertBulkOperations(&suite.Suite, suite.handler)
+}
+
 func (suite *PostgresTestSuite) TestItCanFindOne() {
 	executions := postgresExecutionsProvider()
 
 	for _, exec := range executions {
 		_, _ = suite.db.Exec(
-			`INSERT INTO "`+PostgresExecutionsTable+`" VALUES ($1, $2, $3)`,
+			`INSERT INTO "`+PostgresExecutionsTable+`" (version, executed_at_ms, finished_at_ms)
+			VALUES ($1, $2, $3)`,
 			exec.Version, exec.ExecutedAtMs, exec.FinishedAtMs,
 		)
 	}
@@ -259,3 +412,50 @@ func (suite *PostgresTestSuite) TestItCanFindOne() {
 	suite.Assert().Nil(foundExec)
 	suite.Assert().Nil(err)
 }
+
+func BenchmarkPostgresHandler(b *testing.B) {
+	ctx := context.Background()
+	pgC, err := pgcontainer.Run(
+		ctx,
+		"postgres:16",
+		pgcontainer.WithDatabase("migrations"),
+		pgcontainer.WithUsername("postgres"),
+		pgcontainer.WithPassword("postgres"),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() { _ = pgC.Terminate(ctx) }()
+
+	dsn, err := pgC.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// Wait for the database to become ready (max 20s)
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	deadline := time.Now().Add(20 * time.Second)
+	for db.PingContext(ctx) != nil && time.Now().Before(deadline) {
+		time.Sleep(500 * time.Millisecond)
+	}
+	_ = db.Close()
+
+	benchmarkSqlHandler(
+		b, func(b *testing.B, opts ...HandlerOption) execution.Repository {
+			handler, err := NewPostgresHandler(dsn, PostgresExecutionsTable, ctx, nil, opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Cleanup(func() { _ = handler.db.Close() })
+
+			_, _ = handler.db.Exec("DROP TABLE IF EXISTS " + PostgresExecutionsTable)
+			if err = handler.Init(); err != nil {
+				b.Fatal(err)
+			}
+			return handler
+		},
+	)
+}
@@ -0,0 +1,54 @@
+//go:build postgres
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// postgresTokenConnector opens Postgres connections authenticated with a fresh token
+type postgresTokenConnector struct {
+	dsn           string
+	tokenProvider TokenProvider
+}
+
+func (c *postgresTokenConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	token, err := c.tokenProvider(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get postgres access token with error: %w", err)
+	}
+
+	dsn, err := dsnWithPassword(c.dsn, token)
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return connector.Connect(ctx)
+}
+
+func (c *postgresTokenConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// NewPostgresTokenDb builds a db handle which authenticates each new connection with a token
+// from the provider (used as password, the dsn should not include one), for example Azure AD
+// tokens (see NewAzureManagedIdentityTokenProvider). Connections are recycled after
+// DefaultTokenConnMaxLifetime, so tokens are refreshed before they expire. The handle can be
+// passed to NewPostgresHandler and shared with the migrations.
+func NewPostgresTokenDb(dsn string, tokenProvider TokenProvider) *sql.DB {
+	db := sql.OpenDB(&postgresTokenConnector{dsn, tokenProvider})
+	db.SetMaxIdleConns(1)
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(DefaultTokenConnMaxLifetime)
+	return db
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// defaultReconnectRetries is the number of times an operation of the SQL handlers is retried
+// after a connection error, unless set with WithReconnectRetries
+const defaultReconnectRetries = 2
+
+// WithReconnectRetries sets the number of times the SQL handlers (MySQL, PostgreSQL and
+// SQLite) retry an operation which failed because its connection was closed (for example, by
+// a proxy killing the idle connections during a long migration). Before each retry, the
+// connection is re-established with a ping. The operations are safe to retry, since the
+// executions are saved with upserts and removed by version. The default is 2 retries; zero or
+// negative disables them. The MongoDB driver retries its reads and writes on its own.
+func WithReconnectRetries(retries int) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.reconnectRetries = retries
+	}
+}
+
+// retry runs the operation, running it again, up to the reconnect retries, while it fails
+// with a connection error. The broken connection is discarded by database/sql, so the ping
+// before each retry gets a new one.
+func (s *sqlStatements) retry(ctx context.Context, operation func() error) error {
+	err := operation()
+	for retry := 0; retry < s.retries && s.isConnectionError(err) && ctx.Err() == nil; retry++ {
+		if pingErr := s.db.PingContext(ctx); pingErr != nil {
+			return errors.Join(err, pingErr)
+		}
+		err = operation()
+	}
+	return err
+}
+
+// isConnectionError checks if the error reports a closed or broken connection, rather than a
+// failed query. The timeouts are not connection errors, so they are not retried.
+func (s *sqlStatements) isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	connErrors := append(
+		[]error{
+			driver.ErrBadConn, io.EOF, io.ErrUnexpectedEOF, net.ErrClosed, syscall.ECONNRESET,
+			syscall.EPIPE,
+		},
+		s.connErrors...,
+	)
+	for _, connErr := range connErrors {
+		if errors.Is(err, connErr) {
+			return true
+		}
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && !opErr.Timeout()
+}
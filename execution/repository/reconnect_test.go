@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ReconnectTestSuite struct {
+	suite.Suite
+}
+
+func TestReconnectTestSuite(t *testing.T) {
+	suite.Run(t, new(ReconnectTestSuite))
+}
+
+// flakyConnector builds connections whose statements fail with the queued errors first
+type flakyConnector struct {
+	errs  []error
+	execs int
+	pings int
+}
+
+func (c *flakyConnector) Connect(context.Context) (driver.Conn, error) {
+	return &flakyConn{connector: c}, nil
+}
+
+func (c *flakyConnector) Driver() driver.Driver {
+	return nil
+}
+
+type flakyConn struct {
+	connector *flakyConnector
+}
+
+func (c *flakyConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *flakyConn) Close() error {
+	return nil
+}
+
+func (c *flakyConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (c *flakyConn) Ping(context.Context) error {
+	c.connector.pings++
+	return nil
+}
+
+func (c *flakyConn) ExecContext(
+	context.Context,
+	string,
+	[]driver.NamedValue,
+) (driver.Result, error) {
+	c.connector.execs++
+	if len(c.connector.errs) > 0 {
+		err := c.connector.errs[0]
+		c.connector.errs = c.connector.errs[1:]
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (suite *ReconnectTestSuite) newStatements(
+	connector *flakyConnector,
+	opts ...HandlerOption,
+) *sqlStatements {
+	db := sql.OpenDB(connector)
+	suite.T().Cleanup(func() { _ = db.Close() })
+	return newSqlStatements(db, newHandlerOptions(append(opts, WithoutPreparedStatements())))
+}
+
+func (suite *ReconnectTestSuite) TestItReconnectsAndRetriesAfterConnectionErrors() {
+	connector := &flakyConnector{errs: []error{syscall.ECONNRESET, syscall.EPIPE}}
+	statements := suite.newStatements(connector)
+
+	result, err := statements.exec(context.Background(), "DELETE FROM executions")
+	suite.Require().NoError(err)
+	rows, _ := result.RowsAffected()
+	suite.Assert().Equal(int64(1), rows)
+	suite.Assert().Equal(3, connector.execs)
+	suite.Assert().Equal(2, connector.pings)
+
+	// the retries are bounded
+	connector.errs = []error{syscall.ECONNRESET, syscall.ECONNRESET, syscall.ECONNRESET}
+	connector.execs = 0
+	_, err = statements.exec(context.Background(), "DELETE FROM executions")
+	suite.Assert().ErrorIs(err, syscall.ECONNRESET)
+	suite.Assert().Equal(3, connector.execs)
+}
+
+func (suite *ReconnectTestSuite) TestItDoesNotRetryOtherErrors() {
+	queryErr := errors.New("syntax error")
+	connector := &flakyConnector{errs: []error{queryErr}}
+	statements := suite.newStatements(connector)
+
+	_, err := statements.exec(context.Background(), "DELETE FROM executions")
+	suite.Assert().ErrorIs(err, queryErr)
+	suite.Assert().Equal(1, connector.execs)
+
+	connector = &flakyConnector{errs: []error{syscall.ECONNRESET}}
+	statements = suite.newStatements(connector, WithReconnectRetries(0))
+	_, err = statements.exec(context.Background(), "DELETE FROM executions")
+	suite.Assert().ErrorIs(err, syscall.ECONNRESET)
+	suite.Assert().Equal(1, connector.execs)
+	suite.Assert().Zero(connector.pings)
+}
@@ -0,0 +1,421 @@
+//go:build spanner
+
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+)
+
+// DefaultSpannerBaseUrl is the Cloud Spanner REST API base url used when none is configured.
+// Set the base url of the emulator REST endpoint (for example, http://localhost:9020) for
+// local runs.
+const DefaultSpannerBaseUrl = "https://spanner.googleapis.com"
+
+// spannerDdlPollInterval is the interval at which the schema update operation is polled
+const spannerDdlPollInterval = time.Second
+
+// spannerSelectedColumns are the columns read into an execution. The columns added after the
+// table creation are NULL for the rows saved before.
+const spannerSelectedColumns = "version, executed_at_ms, finished_at_ms, " +
+	"IFNULL(run_id, ''), IFNULL(checkpoint, '')"
+
+// errSpannerSessionNotFound is returned when the session used by the handler expired
+var errSpannerSessionNotFound = errors.New("spanner session not found")
+
+// SpannerHandler Repository implementation for Google Cloud Spanner integration. It talks to
+// the Spanner REST API: the executions table is created through the database admin API
+// (schema updates are long-running operations, which are awaited), executions are saved
+// and removed with mutations, in single use read-write transactions, and loaded with
+// strong reads.
+type SpannerHandler struct {
+	baseUrl       string
+	database      string
+	tableName     string
+	ctx           context.Context
+	tokenProvider TokenProvider
+	client        *http.Client
+	mu            sync.Mutex
+	session       string
+
+	// operationTimeout bounds each request to the REST API, if positive (see
+	// WithOperationTimeout)
+	operationTimeout time.Duration
+}
+
+// NewSpannerHandler Builds a new SpannerHandler. The database must be the full database name
+// (projects/<project>/instances/<instance>/databases/<database>). The tokenProvider returns
+// the OAuth2 access tokens sent as bearer tokens (see NewGoogleMetadataTokenProvider); it can
+// be nil when running against the emulator. If baseUrl is empty, DefaultSpannerBaseUrl is
+// used. If client is nil, http.DefaultClient is used. With WithOperationTimeout, the timeout
+// bounds each request to the REST API, so awaiting the creation of the executions table is
+// not bounded as a whole.
+func NewSpannerHandler(
+	baseUrl string,
+	database string,
+	tableName string,
+	ctx context.Context,
+	tokenProvider TokenProvider,
+	client *http.Client,
+	opts ...HandlerOption,
+) *SpannerHandler {
+	if baseUrl == "" {
+		baseUrl = DefaultSpannerBaseUrl
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &SpannerHandler{
+		baseUrl:          strings.TrimRight(baseUrl, "/"),
+		database:         strings.Trim(database, "/"),
+		tableName:        tableName,
+		ctx:              ctx,
+		tokenProvider:    tokenProvider,
+		client:           client,
+		operationTimeout: newHandlerOptions(opts).operationTimeout,
+	}
+}
+
+func (h *SpannerHandler) Context() context.Context {
+	return h.ctx
+}
+
+// Target implements the execution.Targeter interface, with the endpoint of the REST API, the
+// full database name and the executions table
+func (h *SpannerHandler) Target() (string, error) {
+	return h.baseUrl + "/" + h.database + "/" + h.tableName, nil
+}
+
+func (h *SpannerHandler) Init() error {
+	errMsg := "failed to create spanner executions table"
+	statements := []string{
+		fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS `%s` ("+
+				"version INT64 NOT NULL, "+
+				"executed_at_ms INT64 NOT NULL, "+
+				"finished_at_ms INT64 NOT NULL, "+
+				"run_id STRING(26), "+
+				"checkpoint STRING(1024)"+
+				") PRIMARY KEY (version)",
+			h.tableName,
+		),
+		// Tables created by older versions don't have the columns added since
+		fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN IF NOT EXISTS run_id STRING(26)", h.tableName),
+		fmt.Sprintf(
+			"ALTER TABLE `%s` ADD COLUMN IF NOT EXISTS checkpoint STRING(1024)", h.tableName,
+		),
+	}
+
+	var operation struct {
+		Name  string          `json:"name"`
+		Done  bool            `json:"done"`
+		Error json.RawMessage `json:"error"`
+	}
+	err := h.call(
+		http.MethodPatch, "/v1/"+h.database+"/ddl",
+		map[string]any{"statements": statements}, &operation,
+	)
+	if err != nil {
+		return fmt.Errorf("%s with error: %w", errMsg, err)
+	}
+
+	for !operation.Done {
+		select {
+		case <-h.ctx.Done():
+			return fmt.Errorf("%s with error: %w", errMsg, h.ctx.Err())
+		case <-time.After(spannerDdlPollInterval):
+		}
+
+		if err = h.call(http.MethodGet, "/v1/"+operation.Name, nil, &operation); err != nil {
+			return fmt.Errorf("%s, failed to get operation status with error: %w", errMsg, err)
+		}
+	}
+
+	if len(operation.Error) > 0 {
+		return fmt.Errorf("%s, operation failed: %s", errMsg, operation.Error)
+	}
+
+	return nil
+}
+
+func (h *SpannerHandler) LoadExecutions() ([]execution.MigrationExecution, error) {
+	return h.query(
+		fmt.Sprintf(
+			"SELECT %s FROM `%s` ORDER BY version", spannerSelectedColumns, h.tableName,
+		),
+		nil,
+	)
+}
+
+func (h *SpannerHandler) Save(exec execution.MigrationExecution) error {
+	return h.commit(h.saveMutation([]execution.MigrationExecution{exec}))
+}
+
+func (h *SpannerHandler) Remove(exec execution.MigrationExecution) error {
+	return h.commit(h.removeMutation([]execution.MigrationExecution{exec}))
+}
+
+// SaveAll implements the execution.BulkRepository.SaveAll method. The executions are saved
+// with a single mutation, committed in one transaction.
+func (h *SpannerHandler) SaveAll(executions []execution.MigrationExecution) error {
+	if len(executions) == 0 {
+		return nil
+	}
+	return h.commit(h.saveMutation(executions))
+}
+
+// RemoveAll implements the execution.BulkRepository.RemoveAll method. The executions are
+// removed with a single mutation, committed in one transaction.
+func (h *SpannerHandler) RemoveAll(executions []execution.MigrationExecution) error {
+	if len(executions) == 0 {
+		return nil
+	}
+	return h.commit(h.removeMutation(executions))
+}
+
+// saveMutation builds the mutation which inserts or updates the executions, deduplicated by
+// version (the last one wins)
+func (h *SpannerHandler) saveMutation(executions []execution.MigrationExecution) map[string]any {
+	values := make([][]string, 0, len(executions))
+	for _, exec := range uniqueExecutions(executions) {
+		values = append(
+			values,
+			[]string{
+				strconv.FormatUint(exec.Version, 10),
+				strconv.FormatUint(exec.ExecutedAtMs, 10),
+				strconv.FormatUint(exec.FinishedAtMs, 10),
+				exec.RunId,
+				exec.Checkpoint,
+			},
+		)
+	}
+
+	return map[string]any{
+		"insertOrUpdate": map[string]any{
+			"table": h.tableName,
+			"columns": []string{
+				"version", "executed_at_ms", "finished_at_ms", "run_id", "checkpoint",
+			},
+			"values": values,
+		},
+	}
+}
+
+// removeMutation builds the mutation which deletes the executions
+func (h *SpannerHandler) removeMutation(executions []execution.MigrationExecution) map[string]any {
+	keys := make([][]string, 0, len(executions))
+	for _, exec := range executions {
+		keys = append(keys, []string{strconv.FormatUint(exec.Version, 10)})
+	}
+
+	return map[string]any{
+		"delete": map[string]any{
+			"table":  h.tableName,
+			"keySet": map[string]any{"keys": keys},
+		},
+	}
+}
+
+func (h *SpannerHandler) FindOne(version uint64) (*execution.MigrationExecution, error) {
+	executions, err := h.query(
+		fmt.Sprintf(
+			"SELECT %s FROM `%s` WHERE version = @version", spannerSelectedColumns, h.tableName,
+		),
+		map[string]string{"version": strconv.FormatUint(version, 10)},
+	)
+	if err != nil || len(executions) == 0 {
+		return nil, err
+	}
+
+	return &executions[0], nil
+}
+
+// Close deletes the session used by the handler, if any
+func (h *SpannerHandler) Close() error {
+	h.mu.Lock()
+	session := h.session
+	h.session = ""
+	h.mu.Unlock()
+
+	if session == "" {
+		return nil
+	}
+
+	return h.call(http.MethodDelete, "/v1/"+session, nil, nil)
+}
+
+// query runs a strong read with the given INT64 parameters
+func (h *SpannerHandler) query(
+	sql string,
+	params map[string]string,
+) ([]execution.MigrationExecution, error) {
+	request := map[string]any{"sql": sql}
+	if len(params) > 0 {
+		paramTypes := map[string]any{}
+		for name := range params {
+			paramTypes[name] = map[string]string{"code": "INT64"}
+		}
+		request["params"] = params
+		request["paramTypes"] = paramTypes
+	}
+
+	var result struct {
+		Rows [][]string `json:"rows"`
+	}
+	if err := h.sessionCall(":executeSql", request, &result); err != nil {
+		return nil, err
+	}
+
+	executions := make([]execution.MigrationExecution, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		if len(row) != 5 {
+			return nil, fmt.Errorf("unexpected spanner row with %d columns", len(row))
+		}
+
+		var values [3]uint64
+		for i, raw := range row[:3] {
+			value, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid spanner INT64 value %q: %w", raw, err)
+			}
+			values[i] = value
+		}
+
+		executions = append(
+			executions,
+			execution.MigrationExecution{
+				Version:      values[0],
+				ExecutedAtMs: values[1],
+				FinishedAtMs: values[2],
+				RunId:        row[3],
+				Checkpoint:   row[4],
+			},
+		)
+	}
+
+	return executions, nil
+}
+
+// commit applies the mutation in a single use read-write transaction
+func (h *SpannerHandler) commit(mutation map[string]any) error {
+	return h.sessionCall(
+		":commit",
+		map[string]any{
+			"singleUseTransaction": map[string]any{"readWrite": map[string]any{}},
+			"mutations":            []map[string]any{mutation},
+		},
+		nil,
+	)
+}
+
+// sessionCall calls a session method, creating the session if needed. If the session
+// expired, a new one is created and the call is retried once.
+func (h *SpannerHandler) sessionCall(method string, body any, out any) error {
+	for attempt := 0; ; attempt++ {
+		session, err := h.currentSession()
+		if err != nil {
+			return err
+		}
+
+		err = h.call(http.MethodPost, "/v1/"+session+method, body, out)
+		if !errors.Is(err, errSpannerSessionNotFound) || attempt > 0 {
+			return err
+		}
+
+		h.mu.Lock()
+		if h.session == session {
+			h.session = ""
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (h *SpannerHandler) currentSession() (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.session != "" {
+		return h.session, nil
+	}
+
+	var created struct {
+		Name string `json:"name"`
+	}
+	err := h.call(http.MethodPost, "/v1/"+h.database+"/sessions", map[string]any{}, &created)
+	if err != nil {
+		return "", fmt.Errorf("failed to create spanner session with error: %w", err)
+	}
+
+	h.session = created.Name
+	return h.session, nil
+}
+
+// call sends a request to the Spanner REST API and decodes the JSON response in out
+func (h *SpannerHandler) call(method string, path string, body any, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to build request body with error: %w", err)
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, h.baseUrl+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to build request with error: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if h.tokenProvider != nil {
+		token, tokenErr := h.tokenProvider(ctx)
+		if tokenErr != nil {
+			return fmt.Errorf("failed to get spanner access token with error: %w", tokenErr)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed with error: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode == http.StatusNotFound && bytes.Contains(respBody, []byte("Session")) {
+			return fmt.Errorf("%w: %s", errSpannerSessionNotFound, respBody)
+		}
+
+		return fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, respBody)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response with error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,277 @@
+//go:build spanner
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	SpannerExecutionsTable = "migration_executions"
+	spannerTestDatabase    = "projects/p/instances/i/databases/d"
+)
+
+// fakeSpanner emulates the subset of the Spanner REST API used by SpannerHandler
+type fakeSpanner struct {
+	mu          sync.Mutex
+	ddl         []string
+	sessions    int
+	session     string
+	rows        map[string][]string
+	tokens      []string
+	pendingPoll int
+}
+
+func (f *fakeSpanner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tokens = append(f.tokens, r.Header.Get("Authorization"))
+	var body map[string]json.RawMessage
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	sessionsPath := "/v1/" + spannerTestDatabase + "/sessions"
+	switch {
+	case r.Method == http.MethodPatch && r.URL.Path == "/v1/"+spannerTestDatabase+"/ddl":
+		var statements []string
+		_ = json.Unmarshal(body["statements"], &statements)
+		f.ddl = append(f.ddl, statements...)
+		f.pendingPoll = 0
+		_, _ = w.Write([]byte(`{"name":"` + spannerTestDatabase + `/operations/op1"}`))
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/operations/op1"):
+		f.pendingPoll--
+		done := strconv.FormatBool(f.pendingPoll < 0)
+		_, _ = w.Write([]byte(`{"name":"op1","done":` + done + `}`))
+	case r.Method == http.MethodPost && r.URL.Path == sessionsPath:
+		f.sessions++
+		f.session = spannerTestDatabase + "/sessions/s" + strconv.Itoa(f.sessions)
+		_, _ = w.Write([]byte(`{"name":"` + f.session + `"}`))
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, sessionsPath+"/"):
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		if !strings.HasPrefix(path, f.session+":") {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"message":"Session not found"}}`))
+			return
+		}
+
+		if strings.HasSuffix(path, ":commit") {
+			f.commit(body["mutations"])
+			_, _ = w.Write([]byte(`{"commitTimestamp":"2024-01-01T00:00:00Z"}`))
+			return
+		}
+
+		f.executeSql(w, body["params"])
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (f *fakeSpanner) commit(raw json.RawMessage) {
+	var mutations []struct {
+		InsertOrUpdate *struct {
+			Values [][]string `json:"values"`
+		} `json:"insertOrUpdate"`
+		Delete *struct {
+			KeySet struct {
+				Keys [][]string `json:"keys"`
+			} `json:"keySet"`
+		} `json:"delete"`
+	}
+	_ = json.Unmarshal(raw, &mutations)
+
+	for _, mutation := range mutations {
+		if mutation.InsertOrUpdate != nil {
+			for _, values := range mutation.InsertOrUpdate.Values {
+				f.rows[values[0]] = values
+			}
+		}
+		if mutation.Delete != nil {
+			for _, key := range mutation.Delete.KeySet.Keys {
+				delete(f.rows, key[0])
+			}
+		}
+	}
+}
+
+func (f *fakeSpanner) executeSql(w http.ResponseWriter, rawParams json.RawMessage) {
+	var params map[string]string
+	_ = json.Unmarshal(rawParams, &params)
+
+	rows := make([][]string, 0, len(f.rows))
+	for version, row := range f.rows {
+		if params["version"] == "" || params["version"] == version {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(
+		rows, func(i, j int) bool {
+			a, _ := strconv.ParseUint(rows[i][0], 10, 64)
+			b, _ := strconv.ParseUint(rows[j][0], 10, 64)
+			return a < b
+		},
+	)
+
+	_ = json.NewEncoder(w).Encode(map[string]any{"rows": rows})
+}
+
+type SpannerTestSuite struct {
+	suite.Suite
+	fake    *fakeSpanner
+	server  *httptest.Server
+	handler *SpannerHandler
+}
+
+func TestSpannerTestSuite(t *testing.T) {
+	suite.Run(t, new(SpannerTestSuite))
+}
+
+func (suite *SpannerTestSuite) SetupTest() {
+	suite.fake = &fakeSpanner{rows: map[string][]string{}}
+	suite.server = httptest.NewServer(suite.fake)
+	suite.handler = NewSpannerHandler(
+		suite.server.URL,
+		spannerTestDatabase,
+		SpannerExecutionsTable,
+		context.Background(),
+		func(ctx context.Context) (string, error) { return "token", nil },
+		nil,
+	)
+}
+
+func (suite *SpannerTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *SpannerTestSuite) TestItCanBuildHandlerWithProvidedContext() {
+	ctx := context.WithValue(context.Background(), "k", "v")
+	handler := NewSpannerHandler("", spannerTestDatabase, SpannerExecutionsTable, ctx, nil, nil)
+	suite.Assert().Same(ctx, handler.Context())
+	suite.Assert().Equal(DefaultSpannerBaseUrl, handler.baseUrl)
+}
+
+func (suite *SpannerTestSuite) TestItIdentifiesTheDatabase() {
+	target, err := suite.handler.Target()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(
+		suite.server.URL+"/"+spannerTestDatabase+"/"+SpannerExecutionsTable, target,
+	)
+}
+
+func (suite *SpannerTestSuite) TestItCanInitializeExecutionsTableAndAwaitsTheOperation() {
+	suite.Require().NoError(suite.handler.Init())
+
+	suite.Require().Len(suite.fake.ddl, 3)
+	suite.Assert().Contains(
+		suite.fake.ddl[0], "CREATE TABLE IF NOT EXISTS `"+SpannerExecutionsTable+"`",
+	)
+	suite.Assert().Contains(suite.fake.ddl[0], "PRIMARY KEY (version)")
+	suite.Assert().Contains(suite.fake.ddl[1], "ADD COLUMN IF NOT EXISTS run_id")
+	suite.Assert().Contains(suite.fake.ddl[2], "ADD COLUMN IF NOT EXISTS checkpoint")
+	suite.Assert().Equal(-1, suite.fake.pendingPoll)
+	suite.Assert().Equal("Bearer token", suite.fake.tokens[0])
+}
+
+func (suite *SpannerTestSuite) TestItCanSaveLoadFindAndRemoveExecutions() {
+	executions := []execution.MigrationExecution{
+		{Version: 3, ExecutedAtMs: 5, FinishedAtMs: 6, RunId: "run-3"},
+		{Version: 1, ExecutedAtMs: 1, Checkpoint: "id:100"},
+	}
+	for _, exec := range executions {
+		suite.Require().NoError(suite.handler.Save(exec))
+	}
+
+	// saving again upserts
+	executions[0].FinishedAtMs = 7
+	suite.Require().NoError(suite.handler.Save(executions[0]))
+
+	loaded, err := suite.handler.LoadExecutions()
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]execution.MigrationExecution{executions[1], executions[0]}, loaded)
+
+	found, err := suite.handler.FindOne(3)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(&executions[0], found)
+
+	suite.Require().NoError(suite.handler.Remove(executions[0]))
+	found, err = suite.handler.FindOne(3)
+	suite.Require().NoError(err)
+	suite.Assert().Nil(found)
+
+	suite.Assert().Equal(1, suite.fake.sessions)
+}
+
+func (suite *SpannerTestSuite) TestItCanSaveAndRemoveExecutionsInBulk() {
+	assertBulkOperations(&suite.Suite, suite.handler)
+}
+
+func (suite *SpannerTestSuite) TestItRecreatesExpiredSessions() {
+	suite.Require().NoError(suite.handler.Save(execution.MigrationExecution{Version: 1}))
+
+	// the server forgets the session
+	suite.fake.session = ""
+
+	_, err := suite.handler.LoadExecutions()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(2, suite.fake.sessions)
+}
+
+func (suite *SpannerTestSuite) TestItFailsOnUnexpectedResponses() {
+	handler := NewSpannerHandler(
+		suite.server.URL, "projects/p/instances/i/databases/missing", SpannerExecutionsTable,
+		context.Background(), nil, nil,
+	)
+
+	suite.Assert().ErrorContains(handler.Init(), "failed to create spanner executions table")
+	_, err := handler.LoadExecutions()
+	suite.Assert().ErrorContains(err, "failed to create spanner session")
+}
+
+func (suite *SpannerTestSuite) TestItBoundsRequestsWithTheOperationTimeout() {
+	release := make(chan struct{})
+	stuck := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-release }),
+	)
+	defer stuck.Close()
+	defer close(release)
+
+	handler := NewSpannerHandler(
+		stuck.URL, spannerTestDatabase, SpannerExecutionsTable, context.Background(), nil, nil,
+		WithOperationTimeout(50*time.Millisecond),
+	)
+
+	suite.Assert().ErrorIs(
+		handler.Save(execution.MigrationExecution{Version: 1}), context.DeadlineExceeded,
+	)
+}
+
+// BenchmarkSpannerHandler measures the overhead of the handler (encoding the requests and
+// decoding the responses), against the fake REST API
+func BenchmarkSpannerHandler(b *testing.B) {
+	server := httptest.NewServer(&fakeSpanner{rows: map[string][]string{}})
+	defer server.Close()
+
+	handler := NewSpannerHandler(
+		server.URL,
+		spannerTestDatabase,
+		SpannerExecutionsTable,
+		context.Background(),
+		func(ctx context.Context) (string, error) { return "token", nil },
+		nil,
+	)
+	if err := handler.Init(); err != nil {
+		b.Fatal(err)
+	}
+	benchmarkRepository(b, handler)
+}
@@ -0,0 +1,343 @@
+//go:build sqlite
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DefaultLocalStateTable is the executions table used by NewLocalStateHandler
+const DefaultLocalStateTable = "migration_executions"
+
+// SqliteHandler Repository implementation for SQLite integration
+type SqliteHandler struct {
+	db        *sql.DB
+	tableName string
+	ctx       context.Context
+
+	// operationTimeout bounds each operation, if positive (see WithOperationTimeout)
+	operationTimeout time.Duration
+
+	// codec names the columns of the executions (see WithExecutionCodec)
+	codec ExecutionCodec
+
+	// statements runs the executions queries, prepared unless disabled (see
+	// WithoutPreparedStatements)
+	queries    executionsQueries
+	statements *sqlStatements
+}
+
+// NewSqliteHandler Builds a new SqliteHandler. If db is nil, it will try to build a db handle
+// from the provided dsn (see github.com/mattn/go-sqlite3 for the supported dsn format).
+func NewSqliteHandler(
+	dsn string,
+	tableName string,
+	ctx context.Context,
+	db *sql.DB,
+	opts ...HandlerOption,
+) (*SqliteHandler, error) {
+	if db == nil {
+		var err error
+		db, err = newDbHandle(dsn, "sqlite3")
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	options := newHandlerOptions(opts)
+	codec := options.codec.withDefaults(sqlExecutionCodec)
+	return &SqliteHandler{
+		db:               db,
+		tableName:        tableName,
+		ctx:              ctx,
+		operationTimeout: options.operationTimeout,
+		codec:            codec,
+		queries:          sqliteQueries(tableName, codec),
+		statements:       newSqlStatements(db, options),
+	}, nil
+}
+
+// sqliteQueries builds the executions queries for the table, with the column names of the codec
+func sqliteQueries(tableName string, codec ExecutionCodec) executionsQueries {
+	columns := codec.quoted(`"`)
+	selectQuery := fmt.Sprintf(`SELECT %s FROM "%s"`, columns.columns(), tableName)
+
+	saveAll := func(rows int) string {
+		return fmt.Sprintf(
+			`
+		INSERT INTO "%s" (%s)
+		VALUES %s
+		ON CONFLICT (%s) DO UPDATE SET %s
+		`,
+			tableName, columns.columns(), rowsPlaceholders(rows, 5, false), columns.Version,
+			columns.assignments("%[1]s = excluded.%[1]s"),
+		)
+	}
+
+	return executionsQueries{
+		load:    selectQuery,
+		findOne: selectQuery + ` WHERE ` + columns.Version + ` = ?`,
+		save:    saveAll(1),
+		remove: fmt.Sprintf(
+			`DELETE FROM "%s" WHERE %s = ?`, tableName, columns.Version,
+		),
+		saveAll: saveAll,
+		removeAll: func(rows int) string {
+			return fmt.Sprintf(
+				`DELETE FROM "%s" WHERE %s IN (%s)`,
+				tableName, columns.Version, placeholders(1, rows, false),
+			)
+		},
+	}
+}
+
+// sqliteExecutionArgs returns the arguments of an execution row, as the INT64 values the
+// driver accepts
+func sqliteExecutionArgs(exec execution.MigrationExecution) []any {
+	return []any{
+		int64(exec.Version), int64(exec.ExecutedAtMs), int64(exec.FinishedAtMs), exec.RunId,
+		exec.Checkpoint,
+	}
+}
+
+// NewLocalStateHandler Builds a SqliteHandler which stores the executions in a local SQLite
+// file, creating the file (and its directory) if needed. Use it when the migrations do not
+// target a database (for example, API calls or file changes): the library then works as
+// a versioned change runner with durable local state. The file is opened in WAL mode,
+// with a busy timeout, so that it tolerates concurrent readers.
+func NewLocalStateHandler(
+	filePath string,
+	ctx context.Context,
+	opts ...HandlerOption,
+) (*SqliteHandler, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return nil, fmt.Errorf(
+			"failed to create the local state directory of %s with error: %w", filePath, err,
+		)
+	}
+
+	dsn := "file:" + filePath + "?_busy_timeout=5000&_journal_mode=WAL&_sync=FULL"
+	return NewSqliteHandler(dsn, DefaultLocalStateTable, ctx, nil, opts...)
+}
+
+func (h *SqliteHandler) Context() context.Context {
+	return h.ctx
+}
+
+// Ping implements the execution.Pinger interface
+func (h *SqliteHandler) Ping(ctx context.Context) error {
+	ctx, cancel := operationContext(ctx, h.operationTimeout)
+	defer cancel()
+
+	return h.db.PingContext(ctx)
+}
+
+// Close closes the prepared statements (see WithoutPreparedStatements) and the db handle of
+// the handler
+func (h *SqliteHandler) Close() error {
+	return errors.Join(h.statements.close(), h.db.Close())
+}
+
+// Target implements the execution.Targeter interface, with the file of the main database
+// (empty for the in-memory databases) and the executions table
+func (h *SqliteHandler) Target() (string, error) {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	var file string
+	err := h.db.QueryRowContext(
+		ctx, "SELECT file FROM pragma_database_list WHERE name = 'main'",
+	).Scan(&file)
+	if err != nil {
+		return "", err
+	}
+	return "sqlite://" + file + "/" + h.tableName, nil
+}
+
+func (h *SqliteHandler) Init() error {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	// the statements prepared before the executions table is upgraded are discarded
+	if err := h.statements.close(); err != nil {
+		return err
+	}
+
+	columns := h.codec.quoted(`"`)
+	query := fmt.Sprintf(
+		`
+		CREATE TABLE IF NOT EXISTS "%s" (
+			%s INTEGER NOT NULL PRIMARY KEY,
+			%s INTEGER NOT NULL,
+			%s INTEGER NOT NULL,
+			%s TEXT NOT NULL DEFAULT '',
+			%s TEXT NOT NULL DEFAULT ''
+		)
+		`,
+		h.tableName, columns.Version, columns.ExecutedAtMs, columns.FinishedAtMs, columns.RunId,
+		columns.Checkpoint,
+	)
+
+	if _, err := h.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	// Tables created by older versions don't have the columns added since
+	if err := h.addMissingColumn(ctx, h.codec.RunId, "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return h.addMissingColumn(ctx, h.codec.Checkpoint, "TEXT NOT NULL DEFAULT ''")
+}
+
+// addMissingColumn adds the column to the executions table, if the table doesn't have it
+func (h *SqliteHandler) addMissingColumn(
+	ctx context.Context,
+	name string,
+	definition string,
+) error {
+	var columns int
+	err := h.db.QueryRowContext(
+		ctx, "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", h.tableName, name,
+	).Scan(&columns)
+	if err != nil || columns > 0 {
+		return err
+	}
+
+	_, err = h.db.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`ALTER TABLE "%s" ADD COLUMN %s %s`,
+			h.tableName, quoteIdentifier(name, `"`), definition,
+		),
+	)
+	return err
+}
+
+func (h *SqliteHandler) LoadExecutions() (executions []execution.MigrationExecution, err error) {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	rows, err := h.statements.query(ctx, h.queries.load)
+
+	if err != nil {
+		return executions, err
+	}
+
+	defer func(rows *sql.Rows) {
+		if closeErr := rows.Close(); closeErr != nil && err != nil {
+			err = errors.Join(err, closeErr)
+		}
+	}(rows)
+
+	for rows.Next() {
+		var exec execution.MigrationExecution
+		err = rows.Scan(
+			&exec.Version, &exec.ExecutedAtMs, &exec.FinishedAtMs, &exec.RunId, &exec.Checkpoint,
+		)
+		if err != nil {
+			return executions, err
+		}
+		executions = append(executions, exec)
+	}
+
+	err = rows.Err()
+	return executions, err
+}
+
+func (h *SqliteHandler) Save(execution execution.MigrationExecution) error {
+	return h.SaveContext(h.ctx, execution)
+}
+
+// SaveContext implements the execution.ContextRepository.SaveContext method
+func (h *SqliteHandler) SaveContext(
+	ctx context.Context,
+	execution execution.MigrationExecution,
+) error {
+	ctx, cancel := operationContext(ctx, h.operationTimeout)
+	defer cancel()
+
+	_, err := h.statements.exec(ctx, h.queries.save, sqliteExecutionArgs(execution)...)
+	return err
+}
+
+// SaveAll implements the execution.BulkRepository.SaveAll method. The executions are saved in
+// a transaction, with one statement per bulkBatchSize executions.
+func (h *SqliteHandler) SaveAll(executions []execution.MigrationExecution) error {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	return h.statements.retry(ctx, func() error {
+		return execBatches(ctx, h.db, executions, h.queries.saveAll, sqliteExecutionArgs)
+	})
+}
+
+// SaveStatement implements the execution.SaveDescriber interface
+func (h *SqliteHandler) SaveStatement(execution execution.MigrationExecution) string {
+	return inlineArgs(h.queries.save, sqliteExecutionArgs(execution), false, false)
+}
+
+// RemoveStatement implements the execution.RemoveDescriber interface
+func (h *SqliteHandler) RemoveStatement(execution execution.MigrationExecution) string {
+	return inlineArgs(h.queries.remove, []any{int64(execution.Version)}, false, false)
+}
+
+func (h *SqliteHandler) Remove(execution execution.MigrationExecution) error {
+	return h.RemoveContext(h.ctx, execution)
+}
+
+// RemoveContext implements the execution.ContextRepository.RemoveContext method
+func (h *SqliteHandler) RemoveContext(
+	ctx context.Context,
+	execution execution.MigrationExecution,
+) error {
+	ctx, cancel := operationContext(ctx, h.operationTimeout)
+	defer cancel()
+
+	_, err := h.statements.exec(ctx, h.queries.remove, int64(execution.Version))
+	return err
+}
+
+// RemoveAll implements the execution.BulkRepository.RemoveAll method. The executions are
+// removed in a transaction, with one statement per bulkBatchSize executions.
+func (h *SqliteHandler) RemoveAll(executions []execution.MigrationExecution) error {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	return h.statements.retry(ctx, func() error {
+		return execBatches(
+			ctx, h.db, executions, h.queries.removeAll,
+			func(exec execution.MigrationExecution) []any { return []any{int64(exec.Version)} },
+		)
+	})
+}
+
+func (h *SqliteHandler) FindOne(version uint64) (*execution.MigrationExecution, error) {
+	ctx, cancel := operationContext(h.ctx, h.operationTimeout)
+	defer cancel()
+
+	var exec execution.MigrationExecution
+	err := h.statements.scanRow(
+		ctx,
+		h.queries.findOne,
+		[]any{int64(version)},
+		&exec.Version, &exec.ExecutedAtMs, &exec.FinishedAtMs, &exec.RunId, &exec.Checkpoint,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &exec, nil
+}
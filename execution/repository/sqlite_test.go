@@ -0,0 +1,313 @@
+//go:build sqlite
+
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type SqliteTestSuite struct {
+	suite.Suite
+	filePath string
+	handler  *SqliteHandler
+}
+
+func TestSqliteTestSuite(t *testing.T) {
+	suite.Run(t, new(SqliteTestSuite))
+}
+
+func (suite *SqliteTestSuite) SetupTest() {
+	suite.filePath = filepath.Join(suite.T().TempDir(), "state", "executions.db")
+
+	var err error
+	suite.handler, err = NewLocalStateHandler(suite.filePath, context.Background())
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.handler.Init())
+}
+
+func (suite *SqliteTestSuite) TearDownTest() {
+	_ = suite.handler.Close()
+}
+
+func sqliteExecutionsProvider() map[uint64]execution.MigrationExecution {
+	return map[uint64]execution.MigrationExecution{
+		uint64(1): {Version: 1, ExecutedAtMs: 2, FinishedAtMs: 3},
+		uint64(4): {Version: 4, ExecutedAtMs: 5, FinishedAtMs: 6, RunId: "run-4"},
+		uint64(7): {Version: 7, ExecutedAtMs: 8, Checkpoint: "id:100"},
+	}
+}
+
+func (suite *SqliteTestSuite) TestItCanBuildHandlerWithProvidedContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler, err := NewSqliteHandler("file::memory:", "migration_execs", ctx, nil)
+	suite.Assert().Nil(err)
+	suite.Assert().Same(ctx, handler.Context())
+	suite.Assert().Equal(1, handler.db.Stats().MaxOpenConnections)
+}
+
+func (suite *SqliteTestSuite) TestItIdentifiesTheDatabaseFile() {
+	target, err := suite.handler.Target()
+	suite.Require().NoError(err)
+	suite.Assert().Equal("sqlite://"+suite.filePath+"/"+DefaultLocalStateTable, target)
+}
+
+func (suite *SqliteTestSuite) TestItCanSaveAndLoadExecutions() {
+	executions := sqliteExecutionsProvider()
+
+	for _, exec := range executions {
+		suite.Assert().NoError(suite.handler.Save(exec))
+	}
+
+	// Update
+	for i, exec := range executions {
+		exec.FinishedAtMs++
+		exec.ExecutedAtMs++
+		executions[i] = exec
+		suite.Assert().NoError(suite.handler.Save(executions[i]))
+	}
+
+	savedExecs, err := suite.handler.LoadExecutions()
+	suite.Assert().NoError(err)
+	suite.Assert().Len(savedExecs, len(executions))
+	for _, exec := range savedExecs {
+		suite.Assert().Equal(executions[exec.Version], exec)
+	}
+}
+
+func (suite *SqliteTestSuite) TestItKeepsTheStateAcrossHandlers() {
+	executions := sqliteExecutionsProvider()
+	for _, exec := range executions {
+		_ = suite.handler.Save(exec)
+	}
+	_ = suite.handler.Close()
+
+	var err error
+	suite.handler, err = NewLocalStateHandler(suite.filePath, context.Background())
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.handler.Init())
+
+	foundExec, err := suite.handler.FindOne(uint64(4))
+	suite.Assert().Nil(err)
+	execToFind := executions[uint64(4)]
+	suite.Assert().Equal(&execToFind, foundExec)
+}
+
+func (suite *SqliteTestSuite) TestItCanRemoveAndFindExecutions() {
+	executions := sqliteExecutionsProvider()
+
+	for _, exec := range executions {
+		_ = suite.handler.Save(exec)
+		suite.Assert().NoError(suite.handler.Remove(exec))
+	}
+
+	savedExecs, _ := suite.handler.LoadExecutions()
+	suite.Assert().Len(savedExecs, 0)
+
+	foundExec, err := suite.handler.FindOne(uint64(4))
+	suite.Assert().Nil(foundExec)
+	suite.Assert().Nil(err)
+}
+
+func (suite *SqliteTestSuite) TestItDescribesTheStatementsWhichSaveAndRemoveAnExecution() {
+	exec := execution.MigrationExecution{
+		Version: 3, ExecutedAtMs: 4, FinishedAtMs: 5, RunId: "run-3", Checkpoint: "it's 50%",
+	}
+	statement := suite.handler.SaveStatement(exec)
+	suite.Assert().NotContains(statement, "?")
+
+	_, err := suite.handler.db.Exec(statement)
+	suite.Require().NoError(err)
+
+	found, err := suite.handler.FindOne(uint64(3))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(&exec, found)
+
+	_, err = suite.handler.db.Exec(suite.handler.RemoveStatement(exec))
+	suite.Require().NoError(err)
+	found, err = suite.handler.FindOne(uint64(3))
+	suite.Require().NoError(err)
+	suite.Assert().Nil(found)
+}
+
+func (suite *SqliteTestSuite) TestItAddsMissingColumnsToExistingTables() {
+	_, _ = suite.handler.db.Exec(`DROP TABLE "` + DefaultLocalStateTable + `"`)
+	_, _ = suite.handler.db.Exec(
+		`CREATE TABLE "` + DefaultLocalStateTable + `" (
+			version INTEGER NOT NULL PRIMARY KEY,
+			executed_at_ms INTEGER NOT NULL,
+			finished_at_ms INTEGER NOT NULL
+		)`,
+	)
+	_, _ = suite.handler.db.Exec(`INSERT INTO "` + DefaultLocalStateTable + `" VALUES (1, 2, 3)`)
+
+	suite.Require().NoError(suite.handler.Init())
+	suite.Require().NoError(suite.handler.Init())
+
+	exec := execution.MigrationExecution{
+		Version: 4, ExecutedAtMs: 5, RunId: "r1", Checkpoint: "id:100",
+	}
+	suite.Require().NoError(suite.handler.Save(exec))
+
+	savedExecs, err := suite.handler.LoadExecutions()
+	suite.Assert().NoError(err)
+	suite.Assert().ElementsMatch(
+		[]execution.MigrationExecution{{Version: 1, ExecutedAtMs: 2, FinishedAtMs: 3}, exec},
+		savedExecs,
+	)
+}
+
+func (suite *SqliteTestSuite) TestItAdoptsExistingTablesWithTheExecutionCodec() {
+	_, _ = suite.handler.db.Exec(
+		`CREATE TABLE "schema_history" (
+			migration_id INTEGER NOT NULL PRIMARY KEY,
+			started_ms INTEGER NOT NULL,
+			ended_ms INTEGER NOT NULL
+		)`,
+	)
+	_, _ = suite.handler.db.Exec(`INSERT INTO "schema_history" VALUES (1, 2, 3)`)
+
+	handler, err := NewSqliteHandler(
+		"", "schema_history", context.Background(), suite.handler.db,
+		WithExecutionCodec(
+			ExecutionCodec{
+				Version: "migration_id", ExecutedAtMs: "started_ms", FinishedAtMs: "ended_ms",
+			},
+		),
+	)
+	suite.Require().NoError(err)
+	suite.Require().NoError(handler.Init())
+
+	exec := execution.MigrationExecution{Version: 4, ExecutedAtMs: 5, RunId: "r1"}
+	suite.Require().NoError(handler.SaveAll([]execution.MigrationExecution{exec}))
+	exec.FinishedAtMs = 6
+	suite.Require().NoError(handler.Save(exec))
+
+	savedExecs, err := handler.LoadExecutions()
+	suite.Assert().NoError(err)
+	suite.Assert().ElementsMatch(
+		[]execution.MigrationExecution{{Version: 1, ExecutedAtMs: 2, FinishedAtMs: 3}, exec},
+		savedExecs,
+	)
+
+	var endedMs int
+	err = handler.db.QueryRow(`SELECT ended_ms FROM "schema_history" WHERE migration_id = 4`).
+		Scan(&endedMs)
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(6, endedMs)
+
+	suite.Require().NoError(handler.Remove(exec))
+	found, err := handler.FindOne(uint64(4))
+	suite.Assert().NoError(err)
+	suite.Assert().Nil(found)
+}
+
+func (suite *SqliteTestSuite) TestItBoundsOperationsWithTheOperationTimeout() {
+	handler, err := NewLocalStateHandler(
+		suite.filePath, context.Background(), WithOperationTimeout(time.Minute),
+	)
+	suite.Require().NoError(err)
+	defer func() { _ = handler.Close() }()
+
+	exec := execution.MigrationExecution{Version: 1, ExecutedAtMs: 2}
+	suite.Require().NoError(handler.Save(exec))
+	found, err := handler.FindOne(1)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(&exec, found)
+
+	expired, err := NewLocalStateHandler(
+		suite.filePath, context.Background(), WithOperationTimeout(time.Nanosecond),
+	)
+	suite.Require().NoError(err)
+	defer func() { _ = expired.Close() }()
+
+	suite.Assert().ErrorIs(expired.Save(exec), context.DeadlineExceeded)
+	_, err = expired.LoadExecutions()
+	suite.Assert().ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (suite *SqliteTestSuite) TestItCanSaveAndRemoveExecutionsInBulk() {
+	assertBulkOperations(&suite.Suite, suite.handler)
+}
+
+func (suite *SqliteTestSuite) TestItFailsToExecuteAnyChangesWhenMissingTable() {
+	_, _ = suite.handler.db.Exec(`DROP TABLE "` + DefaultLocalStateTable + `"`)
+	migrationExecution := execution.StartExecution(migration.NewDummyMigration(123))
+	_, errLoad := suite.handler.LoadExecutions()
+	errSave := suite.handler.Save(*migrationExecution)
+	errRemove := suite.handler.Remove(*migrationExecution)
+	_, errFindOne := suite.handler.FindOne(uint64(123))
+
+	suite.Assert().ErrorContains(errLoad, DefaultLocalStateTable)
+	suite.Assert().ErrorContains(errSave, DefaultLocalStateTable)
+	suite.Assert().ErrorContains(errRemove, DefaultLocalStateTable)
+	suite.Assert().ErrorContains(errFindOne, DefaultLocalStateTable)
+}
+
+func (suite *SqliteTestSuite) TestItCanUsePreparedStatements() {
+	handler, err := NewLocalStateHandler(suite.filePath, context.Background())
+	suite.Require().NoError(err)
+	defer func() { _ = handler.Close() }()
+
+	exec := execution.MigrationExecution{Version: 1, ExecutedAtMs: 2, FinishedAtMs: 3}
+	suite.Require().NoError(handler.Save(exec))
+	found, err := handler.FindOne(1)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(&exec, found)
+	suite.Assert().Len(handler.statements.prepared, 2)
+
+	// upgrading the executions table discards the prepared statements
+	suite.Require().NoError(handler.Init())
+	suite.Assert().Empty(handler.statements.prepared)
+
+	suite.Require().NoError(handler.Remove(exec))
+	executions, err := handler.LoadExecutions()
+	suite.Require().NoError(err)
+	suite.Assert().Empty(executions)
+
+	// the statements which can't be prepared fail like the unprepared ones
+	_, _ = handler.db.Exec(`DROP TABLE "` + DefaultLocalStateTable + `"`)
+	_, err = handler.FindOne(2)
+	suite.Assert().ErrorContains(err, DefaultLocalStateTable)
+}
+
+func (suite *SqliteTestSuite) TestItCanRunTheStatementsUnprepared() {
+	handler, err := NewLocalStateHandler(
+		suite.filePath, context.Background(), WithoutPreparedStatements(),
+	)
+	suite.Require().NoError(err)
+	defer func() { _ = handler.Close() }()
+
+	exec := execution.MigrationExecution{Version: 1, ExecutedAtMs: 2, FinishedAtMs: 3}
+	suite.Require().NoError(handler.Save(exec))
+	found, err := handler.FindOne(1)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(&exec, found)
+	suite.Assert().Empty(handler.statements.prepared)
+}
+
+func BenchmarkSqliteHandler(b *testing.B) {
+	benchmarkSqlHandler(
+		b, func(b *testing.B, opts ...HandlerOption) execution.Repository {
+			handler, err := NewLocalStateHandler(
+				filepath.Join(b.TempDir(), "executions.db"), context.Background(), opts...,
+			)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Cleanup(func() { _ = handler.Close() })
+
+			if err = handler.Init(); err != nil {
+				b.Fatal(err)
+			}
+			return handler
+		},
+	)
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenProvider returns a fresh access token, used as password when opening new database
+// connections (for example, Azure AD tokens for Azure Database for PostgreSQL). Tokens are
+// requested on each new connection, so expired tokens are refreshed on reconnect.
+type TokenProvider func(ctx context.Context) (string, error)
+
+// DefaultTokenConnMaxLifetime is the maximum lifetime of connections authenticated with
+// tokens, so that connections are recycled (and re-authenticated) before tokens expire
+const DefaultTokenConnMaxLifetime = 30 * time.Minute
+
+// Defaults used by NewAzureManagedIdentityTokenProvider
+const (
+	DefaultAzureImdsEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+	AzurePostgresResource    = "https://ossrdbms-aad.database.windows.net"
+	AzureSqlResource         = "https://database.windows.net/"
+)
+
+// DefaultGoogleMetadataEndpoint is the endpoint used by NewGoogleMetadataTokenProvider when
+// none is configured
+const DefaultGoogleMetadataEndpoint = "http://metadata.google.internal/computeMetadata/v1/" +
+	"instance/service-accounts/default/token"
+
+// tokenExpiryMargin is how long before its expiration a cached token is refreshed
+const tokenExpiryMargin = 5 * time.Minute
+
+// NewAzureManagedIdentityTokenProvider builds a TokenProvider which requests Azure AD tokens
+// for the given resource (AzurePostgresResource or AzureSqlResource) from the instance
+// metadata service, using the managed identity of the host. The clientId selects a user
+// assigned identity and can be empty. If endpoint is empty, DefaultAzureImdsEndpoint is used.
+// If client is nil, http.DefaultClient is used. Tokens are cached until shortly before
+// they expire.
+func NewAzureManagedIdentityTokenProvider(
+	endpoint string,
+	resource string,
+	clientId string,
+	client *http.Client,
+) TokenProvider {
+	if endpoint == "" {
+		endpoint = DefaultAzureImdsEndpoint
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	query := url.Values{"api-version": {"2018-02-01"}, "resource": {resource}}
+	if clientId != "" {
+		query.Set("client_id", clientId)
+	}
+	tokenUrl := endpoint + "?" + query.Encode()
+
+	var mu sync.Mutex
+	var cachedToken string
+	var expiresAt time.Time
+
+	return func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if cachedToken != "" && time.Now().Add(tokenExpiryMargin).Before(expiresAt) {
+			return cachedToken, nil
+		}
+
+		errMsg := "failed to get azure ad token"
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenUrl, nil)
+		if err != nil {
+			return "", fmt.Errorf("%s, failed to build request with error: %w", errMsg, err)
+		}
+		req.Header.Set("Metadata", "true")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("%s, request failed with error: %w", errMsg, err)
+		}
+
+		defer func(body io.ReadCloser) {
+			_ = body.Close()
+		}(resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			return "", fmt.Errorf(
+				"%s, unexpected response status %d: %s", errMsg, resp.StatusCode, respBody,
+			)
+		}
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+			ExpiresOn   string `json:"expires_on"`
+		}
+		if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return "", fmt.Errorf("%s, failed to decode response with error: %w", errMsg, err)
+		}
+
+		cachedToken = token.AccessToken
+		expiresAt = time.Time{}
+		if expiresOn, parseErr := strconv.ParseInt(token.ExpiresOn, 10, 64); parseErr == nil {
+			expiresAt = time.Unix(expiresOn, 0)
+		}
+
+		return cachedToken, nil
+	}
+}
+
+// NewGoogleMetadataTokenProvider builds a TokenProvider which requests OAuth2 access tokens
+// of the service account attached to the host (GCE, GKE, Cloud Run) from the metadata server,
+// for example, for the Cloud Spanner repository. If endpoint is empty,
+// DefaultGoogleMetadataEndpoint is used. If client is nil, http.DefaultClient is used.
+// Tokens are cached until shortly before they expire.
+func NewGoogleMetadataTokenProvider(endpoint string, client *http.Client) TokenProvider {
+	if endpoint == "" {
+		endpoint = DefaultGoogleMetadataEndpoint
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var mu sync.Mutex
+	var cachedToken string
+	var expiresAt time.Time
+
+	return func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if cachedToken != "" && time.Now().Add(tokenExpiryMargin).Before(expiresAt) {
+			return cachedToken, nil
+		}
+
+		errMsg := "failed to get google access token"
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return "", fmt.Errorf("%s, failed to build request with error: %w", errMsg, err)
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("%s, request failed with error: %w", errMsg, err)
+		}
+
+		defer func(body io.ReadCloser) {
+			_ = body.Close()
+		}(resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			return "", fmt.Errorf(
+				"%s, unexpected response status %d: %s", errMsg, resp.StatusCode, respBody,
+			)
+		}
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int64  `json:"expires_in"`
+		}
+		if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return "", fmt.Errorf("%s, failed to decode response with error: %w", errMsg, err)
+		}
+
+		cachedToken = token.AccessToken
+		expiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+
+		return cachedToken, nil
+	}
+}
+
+// dsnWithPassword sets the password of a libpq style DSN, in URL or key/value format
+func dsnWithPassword(dsn string, password string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		parsed, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("invalid dsn: %w", err)
+		}
+
+		parsed.User = url.UserPassword(parsed.User.Username(), password)
+		return parsed.String(), nil
+	}
+
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(password)
+	return strings.TrimSpace(dsn) + " password='" + escaped + "'", nil
+}
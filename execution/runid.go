@@ -0,0 +1,67 @@
+package execution
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+)
+
+// crockfordAlphabet is the base32 alphabet used by ULIDs
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+type runIdKey struct{}
+
+// NewRunId generates a new run ID: a ULID (26 characters, lexicographically sortable by
+// creation time), without any telemetry involved. Each run (invocation) gets its own ID,
+// which is saved with the executions, sent with the notifications and included in the
+// CLI output, so a specific run can be cross-referenced across systems.
+func NewRunId() string {
+	var entropy [10]byte
+	_, _ = rand.Read(entropy[:])
+
+	ms := uint64(time.Now().UnixMilli())
+	var id [26]byte
+
+	// 48 bits timestamp, in 10 characters
+	for i := 9; i >= 0; i-- {
+		id[i] = crockfordAlphabet[ms&0x1f]
+		ms >>= 5
+	}
+
+	// 80 bits entropy, in 16 characters (5 bytes per 8 characters)
+	for chunk := 0; chunk < 2; chunk++ {
+		var bits uint64
+		for _, b := range entropy[chunk*5 : chunk*5+5] {
+			bits = bits<<8 | uint64(b)
+		}
+		for i := 7; i >= 0; i-- {
+			id[10+chunk*8+i] = crockfordAlphabet[bits&0x1f]
+			bits >>= 5
+		}
+	}
+
+	return string(id[:])
+}
+
+// WithRunId returns a copy of ctx which carries the given run ID
+func WithRunId(ctx context.Context, runId string) context.Context {
+	return context.WithValue(ctx, runIdKey{}, runId)
+}
+
+// RunIdFrom returns the run ID carried by ctx, or an empty string if there is none.
+// Migrations can use it to tag their own logs.
+func RunIdFrom(ctx context.Context) string {
+	runId, _ := ctx.Value(runIdKey{}).(string)
+	return runId
+}
+
+// EnsureRunId returns ctx and the run ID it carries. If ctx has no run ID, a new one is
+// generated and a copy of ctx which carries it is returned.
+func EnsureRunId(ctx context.Context) (context.Context, string) {
+	if runId := RunIdFrom(ctx); runId != "" {
+		return ctx, runId
+	}
+
+	runId := NewRunId()
+	return WithRunId(ctx, runId), runId
+}
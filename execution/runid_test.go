@@ -0,0 +1,44 @@
+package execution
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RunIdTestSuite struct {
+	suite.Suite
+}
+
+func TestRunIdTestSuite(t *testing.T) {
+	suite.Run(t, new(RunIdTestSuite))
+}
+
+func (suite *RunIdTestSuite) TestItGeneratesUniqueSortableUlids() {
+	first := NewRunId()
+	time.Sleep(2 * time.Millisecond)
+	second := NewRunId()
+
+	ulidFormat := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+	suite.Assert().Regexp(ulidFormat, first)
+	suite.Assert().Regexp(ulidFormat, second)
+	suite.Assert().NotEqual(first, second)
+	suite.Assert().Less(first, second)
+}
+
+func (suite *RunIdTestSuite) TestItCarriesTheRunIdInTheContext() {
+	suite.Assert().Empty(RunIdFrom(context.Background()))
+
+	ctx, runId := EnsureRunId(context.Background())
+	suite.Assert().NotEmpty(runId)
+	suite.Assert().Equal(runId, RunIdFrom(ctx))
+
+	sameCtx, sameRunId := EnsureRunId(ctx)
+	suite.Assert().Same(ctx, sameCtx)
+	suite.Assert().Equal(runId, sameRunId)
+
+	suite.Assert().Equal("run-1", RunIdFrom(WithRunId(ctx, "run-1")))
+}
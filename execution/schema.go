@@ -0,0 +1,55 @@
+package execution
+
+import "fmt"
+
+// SchemaVersion is the version of the executions storage schema supported by this library
+// version. It is increased on each schema change:
+//   - 1: version, executed_at_ms and finished_at_ms
+//   - 2: run_id
+//   - 3: checkpoint
+const SchemaVersion = 3
+
+// SchemaVersioner is an optional interface for repositories which record the schema version of
+// the executions storage when Init() creates or upgrades it. A storage upgraded by a newer
+// library version keeps its (greater) schema version.
+type SchemaVersioner interface {
+	// StoredSchemaVersion returns the recorded schema version, 0 if none is recorded (for
+	// example, the storage was created by a library version which did not record it)
+	StoredSchemaVersion() (int, error)
+}
+
+// SchemaTooNewError is returned when the executions storage was created or upgraded by a
+// newer library version than the running one
+type SchemaTooNewError struct {
+	Stored    int
+	Supported int
+}
+
+func (e *SchemaTooNewError) Error() string {
+	return fmt.Sprintf(
+		"the executions schema version %d was created by a newer library version,"+
+			" this version supports up to schema version %d, upgrade the library",
+		e.Stored, e.Supported,
+	)
+}
+
+// CheckSchemaVersion checks that the running library version supports the schema version of
+// the executions storage. It returns a *SchemaTooNewError if the storage is newer, or nil if
+// it is supported or the repository does not implement SchemaVersioner.
+func CheckSchemaVersion(repository Repository) error {
+	versioner, ok := repository.(SchemaVersioner)
+	if !ok {
+		return nil
+	}
+
+	stored, err := versioner.StoredSchemaVersion()
+	if err != nil {
+		return fmt.Errorf("failed to check the executions schema version with error: %w", err)
+	}
+
+	if stored > SchemaVersion {
+		return &SchemaTooNewError{Stored: stored, Supported: SchemaVersion}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package execution
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SchemaTestSuite struct {
+	suite.Suite
+}
+
+func TestSchemaTestSuite(t *testing.T) {
+	suite.Run(t, new(SchemaTestSuite))
+}
+
+type versionedRepository struct {
+	InMemoryRepository
+	stored   int
+	checkErr error
+}
+
+func (r *versionedRepository) StoredSchemaVersion() (int, error) {
+	return r.stored, r.checkErr
+}
+
+func (suite *SchemaTestSuite) TestItFailsWhenTheSchemaIsNewerThanSupported() {
+	err := CheckSchemaVersion(&versionedRepository{stored: SchemaVersion + 1})
+
+	var tooNewErr *SchemaTooNewError
+	suite.Require().ErrorAs(err, &tooNewErr)
+	suite.Assert().Equal(SchemaVersion+1, tooNewErr.Stored)
+	suite.Assert().Equal(SchemaVersion, tooNewErr.Supported)
+	suite.Assert().ErrorContains(err, "was created by a newer library version")
+}
+
+func (suite *SchemaTestSuite) TestItPassesWhenTheSchemaIsSupportedOrNotRecorded() {
+	suite.Assert().NoError(CheckSchemaVersion(&versionedRepository{stored: SchemaVersion}))
+	suite.Assert().NoError(CheckSchemaVersion(&versionedRepository{}))
+	suite.Assert().NoError(CheckSchemaVersion(&InMemoryRepository{}))
+
+	checkErr := errors.New("connection refused")
+	suite.Assert().ErrorIs(
+		CheckSchemaVersion(&versionedRepository{checkErr: checkErr}), checkErr,
+	)
+}
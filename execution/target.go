@@ -0,0 +1,35 @@
+package execution
+
+import (
+	"fmt"
+)
+
+// Targeter is an optional interface for repositories which can identify the database whose
+// executions they record (for example, the server, the database and the executions table).
+// The CLI scopes the lock of the exclusive runs to it by default, so the runs against
+// different databases from the same host don't exclude each other.
+type Targeter interface {
+	// Target returns the identity of the migrated database. It may include the host, but
+	// never the credentials.
+	Target() (string, error)
+}
+
+// TargetOf returns the identity of the database migrated with the repository, or an empty
+// string if the repository doesn't implement Targeter
+func TargetOf(repository Repository) (string, error) {
+	target, err := targetOf(repository)
+	if err != nil {
+		return "", fmt.Errorf(
+			"failed to identify the database of the executions repository with error: %w", err,
+		)
+	}
+	return target, nil
+}
+
+// targetOf returns the target of the repository, empty if it doesn't implement Targeter
+func targetOf(repository Repository) (string, error) {
+	if targeter, ok := repository.(Targeter); ok {
+		return targeter.Target()
+	}
+	return "", nil
+}
@@ -0,0 +1,39 @@
+package execution
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TargetTestSuite struct {
+	suite.Suite
+}
+
+func TestTargetTestSuite(t *testing.T) {
+	suite.Run(t, new(TargetTestSuite))
+}
+
+type targetedRepository struct {
+	InMemoryRepository
+	target    string
+	targetErr error
+}
+
+func (r *targetedRepository) Target() (string, error) {
+	return r.target, r.targetErr
+}
+
+func (suite *TargetTestSuite) TestItIdentifiesTheDatabaseOfTheRepository() {
+	target, err := TargetOf(&targetedRepository{target: "mysql://db:3306/app/executions"})
+	suite.Require().NoError(err)
+	suite.Assert().Equal("mysql://db:3306/app/executions", target)
+
+	target, err = TargetOf(&InMemoryRepository{})
+	suite.Require().NoError(err)
+	suite.Assert().Empty(target)
+
+	_, err = TargetOf(&targetedRepository{targetErr: errors.New("connection refused")})
+	suite.Assert().ErrorContains(err, "connection refused")
+}
@@ -0,0 +1,208 @@
+package execution
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ThrottledRepository wraps a Repository so that its writes (Save, Remove and their context
+// and bulk variants) are spaced by a minimum interval, waiting before a write which follows
+// the previous one too closely. It reduces the load of the bookkeeping writes on the
+// databases billed or rate-limited per request (for example, the serverless ones) during the
+// runs of many small migrations. The reads are not throttled.
+//
+// Init initializes the wrapped repository with Initialize, so a failed initialization is still
+// described by an *InitError. Besides, it implements ContextRepository (the waits end early
+// with the error of a cancelled context), BulkRepository (a bulk write waits once),
+// SchemaVersioner, ReadOnlyChecker, Pinger, InitDescriber, SaveDescriber, RemoveDescriber (see
+// SaveDescriberOf), PermissionsChecker and Targeter, falling back to the Repository methods,
+// or to no descriptions, missing privileges or target, for the wrapped repositories which
+// don't implement them.
+type ThrottledRepository struct {
+	repository Repository
+	throttle   *writeThrottle
+}
+
+// NewThrottledRepository builds the repository whose writes to the given one are spaced by at
+// least minInterval. The throttled ArchivedRepository is an ArchivedRepository of its throttled
+// repositories, sharing the interval, so it is still an Archiver.
+func NewThrottledRepository(repository Repository, minInterval time.Duration) Repository {
+	throttle := &writeThrottle{interval: minInterval}
+	if archived, ok := repository.(*ArchivedRepository); ok {
+		return NewArchivedRepository(
+			&ThrottledRepository{archived.hot, throttle},
+			&ThrottledRepository{archived.archive, throttle},
+		)
+	}
+	return &ThrottledRepository{repository: repository, throttle: throttle}
+}
+
+// Init implements the Repository.Init method (see Initialize)
+func (r *ThrottledRepository) Init() error {
+	return Initialize(r.repository)
+}
+
+func (r *ThrottledRepository) LoadExecutions() ([]MigrationExecution, error) {
+	return r.repository.LoadExecutions()
+}
+
+func (r *ThrottledRepository) FindOne(version uint64) (*MigrationExecution, error) {
+	return r.repository.FindOne(version)
+}
+
+func (r *ThrottledRepository) Save(execution MigrationExecution) error {
+	return r.SaveContext(context.Background(), execution)
+}
+
+// SaveContext implements the ContextRepository.SaveContext method
+func (r *ThrottledRepository) SaveContext(ctx context.Context, execution MigrationExecution) error {
+	if err := r.throttle.wait(ctx); err != nil {
+		return err
+	}
+	return SaveContext(ctx, r.repository, execution)
+}
+
+func (r *ThrottledRepository) Remove(execution MigrationExecution) error {
+	return r.RemoveContext(context.Background(), execution)
+}
+
+// RemoveContext implements the ContextRepository.RemoveContext method
+func (r *ThrottledRepository) RemoveContext(
+	ctx context.Context,
+	execution MigrationExecution,
+) error {
+	if err := r.throttle.wait(ctx); err != nil {
+		return err
+	}
+	return RemoveContext(ctx, r.repository, execution)
+}
+
+// SaveAll implements the BulkRepository.SaveAll method. The executions are saved one by one,
+// each write being throttled, if the wrapped repository doesn't implement BulkRepository.
+func (r *ThrottledRepository) SaveAll(executions []MigrationExecution) error {
+	if _, ok := r.repository.(BulkRepository); !ok {
+		for _, execution := range executions {
+			if err := r.Save(execution); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := r.throttle.wait(context.Background()); err != nil {
+		return err
+	}
+	return SaveAll(r.repository, executions)
+}
+
+// RemoveAll implements the BulkRepository.RemoveAll method. The executions are removed one by
+// one, each write being throttled, if the wrapped repository doesn't implement BulkRepository.
+func (r *ThrottledRepository) RemoveAll(executions []MigrationExecution) error {
+	if _, ok := r.repository.(BulkRepository); !ok {
+		for _, execution := range executions {
+			if err := r.Remove(execution); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := r.throttle.wait(context.Background()); err != nil {
+		return err
+	}
+	return RemoveAll(r.repository, executions)
+}
+
+// StoredSchemaVersion implements the SchemaVersioner interface, with the schema version of the
+// wrapped repository (0 if it doesn't implement SchemaVersioner)
+func (r *ThrottledRepository) StoredSchemaVersion() (int, error) {
+	if versioner, ok := r.repository.(SchemaVersioner); ok {
+		return versioner.StoredSchemaVersion()
+	}
+	return 0, nil
+}
+
+// ReadOnly implements the ReadOnlyChecker interface, with the state of the wrapped repository
+// (false if it doesn't implement ReadOnlyChecker)
+func (r *ThrottledRepository) ReadOnly() (bool, error) {
+	return readOnly(r.repository)
+}
+
+// Ping implements the Pinger interface, pinging the wrapped repository if it implements it.
+// The pings are not throttled.
+func (r *ThrottledRepository) Ping(ctx context.Context) error {
+	if pinger, ok := r.repository.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// InitStatements implements the InitDescriber interface, with the statements of the wrapped
+// repository
+func (r *ThrottledRepository) InitStatements() []string {
+	return initStatements(r.repository)
+}
+
+// SaveStatement implements the SaveDescriber interface, with the statement of the wrapped
+// repository (empty if it doesn't implement SaveDescriber)
+func (r *ThrottledRepository) SaveStatement(execution MigrationExecution) string {
+	if describer, ok := SaveDescriberOf(r.repository); ok {
+		return describer.SaveStatement(execution)
+	}
+	return ""
+}
+
+// RemoveStatement implements the RemoveDescriber interface, with the statement of the wrapped
+// repository (empty if it doesn't implement RemoveDescriber)
+func (r *ThrottledRepository) RemoveStatement(execution MigrationExecution) string {
+	if describer, ok := RemoveDescriberOf(r.repository); ok {
+		return describer.RemoveStatement(execution)
+	}
+	return ""
+}
+
+func (r *ThrottledRepository) describesSave() bool {
+	_, ok := SaveDescriberOf(r.repository)
+	return ok
+}
+
+func (r *ThrottledRepository) describesRemove() bool {
+	_, ok := RemoveDescriberOf(r.repository)
+	return ok
+}
+
+// CheckPermissions implements the PermissionsChecker interface, with the privileges missing
+// for the wrapped repository
+func (r *ThrottledRepository) CheckPermissions() ([]MissingPermission, error) {
+	return missingPermissions(r.repository)
+}
+
+// Target implements the Targeter interface, with the target of the wrapped repository
+func (r *ThrottledRepository) Target() (string, error) {
+	return targetOf(r.repository)
+}
+
+// writeThrottle spaces the writes by its interval. It is shared by the repositories of an
+// ArchivedRepository.
+type writeThrottle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	last     time.Time
+}
+
+// wait waits until the interval has elapsed since the previous write, then records the write
+func (t *writeThrottle) wait(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if delay := time.Until(t.last.Add(t.interval)); !t.last.IsZero() && delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-timer.C:
+		}
+	}
+	t.last = time.Now()
+	return nil
+}
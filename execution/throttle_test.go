@@ -0,0 +1,112 @@
+package execution
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ThrottledRepositoryTestSuite struct {
+	suite.Suite
+}
+
+func TestThrottledRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(ThrottledRepositoryTestSuite))
+}
+
+func (suite *ThrottledRepositoryTestSuite) TestItSpacesTheWrites() {
+	repo := &InMemoryRepository{}
+	throttled := NewThrottledRepository(repo, 30*time.Millisecond)
+
+	start := time.Now()
+	suite.Require().NoError(throttled.Save(MigrationExecution{Version: 1}))
+	suite.Require().NoError(throttled.Save(MigrationExecution{Version: 2}))
+	suite.Require().NoError(throttled.Remove(MigrationExecution{Version: 1}))
+	suite.Assert().GreaterOrEqual(time.Since(start), 60*time.Millisecond)
+	suite.Assert().Equal([]MigrationExecution{{Version: 2}}, repo.PersistedExecutions)
+
+	// the reads are not throttled
+	start = time.Now()
+	executions, err := throttled.LoadExecutions()
+	suite.Require().NoError(err)
+	suite.Assert().Len(executions, 1)
+	found, err := throttled.FindOne(2)
+	suite.Require().NoError(err)
+	suite.Assert().NotNil(found)
+	suite.Assert().Less(time.Since(start), 30*time.Millisecond)
+}
+
+func (suite *ThrottledRepositoryTestSuite) TestItStopsWaitingWhenTheContextIsCancelled() {
+	repo := &InMemoryRepository{}
+	throttled := NewThrottledRepository(repo, time.Hour).(ContextRepository)
+	suite.Require().NoError(
+		throttled.SaveContext(context.Background(), MigrationExecution{Version: 1}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	suite.Assert().ErrorIs(
+		throttled.SaveContext(ctx, MigrationExecution{Version: 2}), context.Canceled,
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+}
+
+func (suite *ThrottledRepositoryTestSuite) TestItKeepsTheArchivedRepositoriesArchivers() {
+	hot := &InMemoryRepository{}
+	archive := &InMemoryRepository{}
+	throttled := NewThrottledRepository(NewArchivedRepository(hot, archive), time.Millisecond)
+	suite.Require().IsType(&ArchivedRepository{}, throttled)
+
+	suite.Require().NoError(
+		SaveAll(throttled, []MigrationExecution{{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2}}),
+	)
+	archived, err := throttled.(Archiver).Archive(time.UnixMilli(10))
+	suite.Require().NoError(err)
+	suite.Assert().Len(archived, 1)
+	suite.Assert().Empty(hot.PersistedExecutions)
+	suite.Assert().Len(archive.PersistedExecutions, 1)
+}
+
+// preflightRepository describes its statements, checks its permissions and identifies its
+// database
+type preflightRepository struct {
+	scriptedRepository
+}
+
+func (r *preflightRepository) CheckPermissions() ([]MissingPermission, error) {
+	return []MissingPermission{{Privilege: "CREATE", Target: "schema public"}}, nil
+}
+
+func (r *preflightRepository) Target() (string, error) {
+	return "postgres://db:5432/app/public/executions", nil
+}
+
+func (suite *ThrottledRepositoryTestSuite) TestItForwardsTheOptionalInterfaces() {
+	throttled := NewThrottledRepository(&preflightRepository{}, time.Millisecond)
+	exec := MigrationExecution{Version: 3}
+
+	suite.Assert().Len(throttled.(InitDescriber).InitStatements(), 2)
+	suite.Assert().Equal(
+		"INSERT INTO executions VALUES (3)", throttled.(SaveDescriber).SaveStatement(exec),
+	)
+	suite.Assert().Equal(
+		"DELETE FROM executions WHERE version = 3",
+		throttled.(RemoveDescriber).RemoveStatement(exec),
+	)
+	var permissionsErr *PermissionsError
+	suite.Assert().ErrorAs(CheckPermissions(throttled), &permissionsErr)
+	target, err := TargetOf(throttled)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("postgres://db:5432/app/public/executions", target)
+
+	// the repositories which don't implement them have no descriptions, privileges or target
+	throttled = NewThrottledRepository(&InMemoryRepository{}, time.Millisecond)
+	suite.Assert().Empty(throttled.(InitDescriber).InitStatements())
+	suite.Assert().Empty(throttled.(SaveDescriber).SaveStatement(exec))
+	suite.Assert().NoError(CheckPermissions(throttled))
+	target, err = TargetOf(throttled)
+	suite.Require().NoError(err)
+	suite.Assert().Empty(target)
+}
@@ -0,0 +1,147 @@
+// Package featureflag provides an integration point between migrations and feature flag
+// services, so a schema change and the flag state depending on it can be shipped as a single,
+// versioned unit.
+//
+// The Switcher interface abstracts the feature flag service. A LaunchDarkly implementation is
+// included, and FlaggedMigration can wrap any migration to flip a flag as part of Up/Down.
+// Conversely, Gate defers the migrations which depend on a flag until it is enabled.
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// Switcher represents a feature flag service which can turn flags on and off
+type Switcher interface {
+	// SetFlag must change the state of the flag identified by key. It should be idempotent,
+	// setting a flag to its current state must not fail.
+	SetFlag(ctx context.Context, key string, enabled bool) error
+}
+
+// FlaggedMigration wraps a migration and sets a feature flag after the wrapped Up() succeeds,
+// and reverts it before the wrapped Down() runs. This way, the flag is enabled only when the
+// schema supports it and disabled before the schema stops supporting it.
+//
+// It is a migration.Wrapper, so the wrapped migration keeps its optional interfaces (for
+// example, an Irreversible or Hotfix migration stays one), except CaptureCapable: setting the
+// flag can't be captured.
+type FlaggedMigration struct {
+	migration.Migration
+	switcher  Switcher
+	flagKey   string
+	stateOnUp bool
+}
+
+// Wrap builds a new FlaggedMigration. stateOnUp is the flag state set after the migration
+// Up() succeeds. Its opposite is set before Down() runs.
+//
+// Example (usually in the migration file init() function):
+//
+//	migration.Register(featureflag.Wrap(&Migration1712953077{}, switcher, "new-checkout", true))
+func Wrap(
+	mig migration.Migration,
+	switcher Switcher,
+	flagKey string,
+	stateOnUp bool,
+) *FlaggedMigration {
+	return &FlaggedMigration{mig, switcher, flagKey, stateOnUp}
+}
+
+// Up runs the wrapped migration Up() and then sets the flag. If setting the flag fails,
+// the error is returned, so the execution is not marked as finished and can be retried.
+func (m *FlaggedMigration) Up(ctx context.Context, db any) error {
+	if err := m.Migration.Up(ctx, db); err != nil {
+		return err
+	}
+
+	if err := m.switcher.SetFlag(ctx, m.flagKey, m.stateOnUp); err != nil {
+		return fmt.Errorf(
+			"migration %d up() succeeded, but setting flag %s failed with error: %w",
+			m.Version(), m.flagKey, err,
+		)
+	}
+
+	return nil
+}
+
+// Down sets the flag to the opposite of its Up() state and then runs the wrapped
+// migration Down(). If Down() fails, the flag is restored to its Up() state.
+func (m *FlaggedMigration) Down(ctx context.Context, db any) error {
+	if err := m.switcher.SetFlag(ctx, m.flagKey, !m.stateOnUp); err != nil {
+		return fmt.Errorf(
+			"failed to set flag %s before running migration %d down() with error: %w",
+			m.flagKey, m.Version(), err,
+		)
+	}
+
+	if err := m.Migration.Down(ctx, db); err != nil {
+		if restoreErr := m.switcher.SetFlag(ctx, m.flagKey, m.stateOnUp); restoreErr != nil {
+			return errors.Join(
+				err,
+				fmt.Errorf("failed to restore flag %s with error: %w", m.flagKey, restoreErr),
+			)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Unwrap implements the migration.Wrapper interface
+func (m *FlaggedMigration) Unwrap() migration.Migration {
+	return m.Migration
+}
+
+// CaptureCapable implements the migration.CaptureCapable interface, always false, since the
+// flag would be set while the statements are captured
+func (m *FlaggedMigration) CaptureCapable() bool {
+	return false
+}
+
+// FlagKey returns the key of the flag changed by the migration
+func (m *FlaggedMigration) FlagKey() string {
+	return m.flagKey
+}
+
+// InMemorySwitcher is an in-memory implementation of the Switcher interface.
+// It's primarily intended for use in unit tests and local development. All flags are
+// disabled until set.
+type InMemorySwitcher struct {
+	mu    sync.Mutex
+	flags map[string]bool
+
+	// SetErr is returned by the SetFlag method if set
+	SetErr error
+}
+
+// SetFlag implements the Switcher.SetFlag method
+func (s *InMemorySwitcher) SetFlag(_ context.Context, key string, enabled bool) error {
+	if s.SetErr != nil {
+		return s.SetErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.flags == nil {
+		s.flags = make(map[string]bool)
+	}
+	s.flags[key] = enabled
+	return nil
+}
+
+// FlagEnabled implements the Reader.FlagEnabled method
+func (s *InMemorySwitcher) FlagEnabled(_ context.Context, key string) (bool, error) {
+	return s.Enabled(key), nil
+}
+
+// Enabled returns the current state of the flag
+func (s *InMemorySwitcher) Enabled(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flags[key]
+}
@@ -0,0 +1,154 @@
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type FeatureFlagTestSuite struct {
+	suite.Suite
+}
+
+func TestFeatureFlagTestSuite(t *testing.T) {
+	suite.Run(t, new(FeatureFlagTestSuite))
+}
+
+type fakeMigration struct {
+	migration.DummyMigration
+	upErr   error
+	downErr error
+}
+
+func (f *fakeMigration) Up(_ context.Context, _ any) error {
+	return f.upErr
+}
+
+func (f *fakeMigration) Down(_ context.Context, _ any) error {
+	return f.downErr
+}
+
+func (suite *FeatureFlagTestSuite) TestItSetsTheFlagAfterUpAndRevertsItBeforeDown() {
+	switcher := &InMemorySwitcher{}
+	mig := Wrap(&fakeMigration{DummyMigration: *migration.NewDummyMigration(7)}, switcher, "f", true)
+
+	suite.Assert().Equal(uint64(7), mig.Version())
+	suite.Assert().Equal("f", mig.FlagKey())
+	suite.Assert().NoError(mig.Up(context.Background(), nil))
+	suite.Assert().True(switcher.Enabled("f"))
+	suite.Assert().NoError(mig.Down(context.Background(), nil))
+	suite.Assert().False(switcher.Enabled("f"))
+}
+
+func (suite *FeatureFlagTestSuite) TestItDoesNotSetTheFlagWhenUpFails() {
+	switcher := &InMemorySwitcher{}
+	upErr := errors.New("up failed")
+	mig := Wrap(
+		&fakeMigration{DummyMigration: *migration.NewDummyMigration(7), upErr: upErr},
+		switcher, "f", true,
+	)
+
+	suite.Assert().ErrorIs(mig.Up(context.Background(), nil), upErr)
+	suite.Assert().False(switcher.Enabled("f"))
+}
+
+func (suite *FeatureFlagTestSuite) TestItFailsUpWhenTheFlagCanNotBeSet() {
+	switcher := &InMemorySwitcher{SetErr: errors.New("set failed")}
+	mig := Wrap(&fakeMigration{DummyMigration: *migration.NewDummyMigration(7)}, switcher, "f", true)
+
+	err := mig.Up(context.Background(), nil)
+	suite.Assert().ErrorContains(err, "up() succeeded")
+	suite.Assert().ErrorIs(err, switcher.SetErr)
+}
+
+func (suite *FeatureFlagTestSuite) TestItRestoresTheFlagWhenDownFails() {
+	switcher := &InMemorySwitcher{}
+	downErr := errors.New("down failed")
+	mig := Wrap(
+		&fakeMigration{DummyMigration: *migration.NewDummyMigration(7), downErr: downErr},
+		switcher, "f", false,
+	)
+	_ = switcher.SetFlag(context.Background(), "f", false)
+
+	suite.Assert().ErrorIs(mig.Down(context.Background(), nil), downErr)
+	suite.Assert().False(switcher.Enabled("f"))
+}
+
+// failingReader fails to read the flags
+// describedMigration declares its transaction and describes itself
+type describedMigration struct {
+	fakeMigration
+}
+
+func (m *describedMigration) Transactional() bool {
+	return true
+}
+
+func (m *describedMigration) Description() string {
+	return "add users phone index"
+}
+
+func (m *describedMigration) CaptureCapable() bool {
+	return true
+}
+
+func (suite *FeatureFlagTestSuite) TestItKeepsTheOptionalInterfacesOfTheWrappedMigration() {
+	switcher := &InMemorySwitcher{}
+	hotfix := Wrap(
+		migration.NewHotfix(10, 7, func(context.Context, any) error { return nil }),
+		switcher, "f", true,
+	)
+
+	fixed, isHotfix := migration.FixedVersionOf(hotfix)
+	suite.Assert().True(isHotfix)
+	suite.Assert().Equal(uint64(7), fixed)
+	suite.Assert().ErrorIs(
+		migration.ValidateReversible([]migration.Migration{hotfix}),
+		migration.ErrIrreversibleHotfix,
+	)
+
+	described := Wrap(
+		&describedMigration{fakeMigration{DummyMigration: *migration.NewDummyMigration(8)}},
+		switcher, "f", true,
+	)
+	transactional, declared := migration.TransactionalOf(described)
+	suite.Assert().True(declared)
+	suite.Assert().True(transactional)
+	suite.Assert().Equal("add users phone index", migration.DescriptionOf(described))
+
+	// the flag would be set while capturing
+	suite.Assert().False(migration.IsCaptureCapable(described))
+}
+
+type failingReader struct{}
+
+func (r failingReader) FlagEnabled(context.Context, string) (bool, error) {
+	return false, errors.New("flags service down")
+}
+
+func (suite *FeatureFlagTestSuite) TestItGatesTheMigrationsOnTheirFlags() {
+	switcher := &InMemorySwitcher{}
+	gate := Gate(switcher)
+	ctx := context.Background()
+	flagged := migration.Metadata{Tags: []string{"users", "flag:new-checkout"}}
+
+	decision, err := gate(ctx, 1, migration.Metadata{Tags: []string{"users"}})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(handler.GateAllow, decision)
+
+	decision, err = gate(ctx, 2, flagged)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(handler.GateDefer, decision)
+
+	suite.Require().NoError(switcher.SetFlag(ctx, "new-checkout", true))
+	decision, err = gate(ctx, 2, flagged)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(handler.GateAllow, decision)
+
+	_, err = Gate(failingReader{})(ctx, 2, flagged)
+	suite.Assert().ErrorContains(err, "failed to read flag new-checkout")
+}
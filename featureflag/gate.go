@@ -0,0 +1,53 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// GateTagPrefix prefixes the metadata tags of the migrations gated by a feature flag (see Gate):
+// a migration tagged "flag:new-checkout" is executed once the new-checkout flag is enabled
+const GateTagPrefix = "flag:"
+
+// Reader represents a feature flag service which can read the state of flags
+type Reader interface {
+	// FlagEnabled must return the state of the flag identified by key
+	FlagEnabled(ctx context.Context, key string) (bool, error)
+}
+
+// Gate builds a handler.Gate which defers the migrations tagged with GateTagPrefix (for example,
+// the backfills of a feature being rolled out gradually) until all their flags are enabled, so
+// they are executed by the first run after the rollout, without any intervention. The other
+// migrations are allowed.
+//
+// Example:
+//
+//	settings := &cli.BootstrapSettings{Gate: featureflag.Gate(flagsReader)}
+func Gate(reader Reader) handler.Gate {
+	return func(ctx context.Context, version uint64, metadata migration.Metadata) (
+		handler.GateDecision,
+		error,
+	) {
+		for _, tag := range metadata.Tags {
+			key, ok := strings.CutPrefix(tag, GateTagPrefix)
+			if !ok {
+				continue
+			}
+
+			enabled, err := reader.FlagEnabled(ctx, key)
+			if err != nil {
+				return handler.GateDeny, fmt.Errorf(
+					"failed to read flag %s with error: %w", key, err,
+				)
+			}
+			if !enabled {
+				return handler.GateDefer, nil
+			}
+		}
+		return handler.GateAllow, nil
+	}
+}
@@ -0,0 +1,107 @@
+package featureflag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// DefaultLaunchDarklyBaseUrl is the LaunchDarkly API base url used when none is configured
+const DefaultLaunchDarklyBaseUrl = "https://app.launchdarkly.com"
+
+// LaunchDarklySwitcher is a Switcher implementation which turns flags on and off through the
+// LaunchDarkly REST API (semantic patch), in a single project environment. It requires an
+// access token with permission to update the flags.
+type LaunchDarklySwitcher struct {
+	accessToken    string
+	projectKey     string
+	environmentKey string
+	baseUrl        string
+	client         *http.Client
+}
+
+// NewLaunchDarklySwitcher builds a new LaunchDarklySwitcher. If client is nil,
+// http.DefaultClient is used. If baseUrl is empty, DefaultLaunchDarklyBaseUrl is used
+// (set it for federal or self-hosted instances).
+func NewLaunchDarklySwitcher(
+	accessToken string,
+	projectKey string,
+	environmentKey string,
+	baseUrl string,
+	client *http.Client,
+) *LaunchDarklySwitcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if baseUrl == "" {
+		baseUrl = DefaultLaunchDarklyBaseUrl
+	}
+
+	return &LaunchDarklySwitcher{accessToken, projectKey, environmentKey, baseUrl, client}
+}
+
+type launchDarklyInstruction struct {
+	Kind string `json:"kind"`
+}
+
+type launchDarklySemanticPatch struct {
+	EnvironmentKey string                    `json:"environmentKey"`
+	Comment        string                    `json:"comment"`
+	Instructions   []launchDarklyInstruction `json:"instructions"`
+}
+
+// SetFlag implements the Switcher.SetFlag method
+func (s *LaunchDarklySwitcher) SetFlag(ctx context.Context, key string, enabled bool) error {
+	errMsg := fmt.Sprintf("failed to set launchdarkly flag %s", key)
+	kind := "turnFlagOff"
+	if enabled {
+		kind = "turnFlagOn"
+	}
+
+	body, err := json.Marshal(
+		launchDarklySemanticPatch{
+			EnvironmentKey: s.environmentKey,
+			Comment:        "Changed by go-migrations",
+			Instructions:   []launchDarklyInstruction{{Kind: kind}},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("%s, failed to build request body with error: %w", errMsg, err)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPatch,
+		s.baseUrl+"/api/v2/flags/"+url.PathEscape(s.projectKey)+"/"+url.PathEscape(key),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return fmt.Errorf("%s, failed to build request with error: %w", errMsg, err)
+	}
+
+	req.Header.Set("Authorization", s.accessToken)
+	req.Header.Set("Content-Type", "application/json; domain-model=launchdarkly.semanticpatch")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s, request failed with error: %w", errMsg, err)
+	}
+
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf(
+			"%s, unexpected response status %d: %s", errMsg, resp.StatusCode, respBody,
+		)
+	}
+
+	return nil
+}
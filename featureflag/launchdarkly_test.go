@@ -0,0 +1,77 @@
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LaunchDarklyTestSuite struct {
+	suite.Suite
+}
+
+func TestLaunchDarklyTestSuite(t *testing.T) {
+	suite.Run(t, new(LaunchDarklyTestSuite))
+}
+
+func (suite *LaunchDarklyTestSuite) TestItSendsSemanticPatchRequests() {
+	scenarios := map[string]struct {
+		enabled      bool
+		expectedKind string
+	}{
+		"turn on":  {true, "turnFlagOn"},
+		"turn off": {false, "turnFlagOff"},
+	}
+
+	for name, scenario := range scenarios {
+		var actualReq *http.Request
+		var actualBody launchDarklySemanticPatch
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					actualReq = r
+					body, _ := io.ReadAll(r.Body)
+					_ = json.Unmarshal(body, &actualBody)
+				},
+			),
+		)
+
+		switcher := NewLaunchDarklySwitcher("token", "proj", "production", server.URL, nil)
+		err := switcher.SetFlag(context.Background(), "new-checkout", scenario.enabled)
+		server.Close()
+
+		suite.Require().NoError(err, "failed scenario: %s", name)
+		suite.Assert().Equal(http.MethodPatch, actualReq.Method, "failed scenario: %s", name)
+		suite.Assert().Equal(
+			"/api/v2/flags/proj/new-checkout", actualReq.URL.Path, "failed scenario: %s", name,
+		)
+		suite.Assert().Equal("token", actualReq.Header.Get("Authorization"))
+		suite.Assert().Contains(actualReq.Header.Get("Content-Type"), "semanticpatch")
+		suite.Assert().Equal("production", actualBody.EnvironmentKey)
+		suite.Assert().Equal(
+			[]launchDarklyInstruction{{Kind: scenario.expectedKind}}, actualBody.Instructions,
+			"failed scenario: %s", name,
+		)
+	}
+}
+
+func (suite *LaunchDarklyTestSuite) TestItFailsOnUnexpectedResponseStatus() {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte("forbidden"))
+			},
+		),
+	)
+	defer server.Close()
+
+	switcher := NewLaunchDarklySwitcher("token", "proj", "production", server.URL, nil)
+	err := switcher.SetFlag(context.Background(), "new-checkout", true)
+	suite.Assert().ErrorContains(err, "unexpected response status 403: forbidden")
+}
@@ -0,0 +1,110 @@
+// Package fleet provides the sources of the version the running app fleet is compatible with,
+// used by the blue/green deployment guard (see handler.WithDeploymentGuard) to refuse contract
+// migrations while old app versions are still serving.
+//
+// Each app version declares the migration version it is compatible with (usually, the latest
+// migration it was built with), for example in a table the app instances register in, or
+// through a deployment service endpoint. The sources report the minimum across the fleet.
+package fleet
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+)
+
+// SqlSource is a handler.FleetVersionSource which reads the version from a table, with a
+// query returning a single row and column: the minimum version the running app instances
+// declared. A NULL value means that no app instance is running, so no contract migration is
+// blocked.
+//
+// Example query:
+//
+//	SELECT MIN(compatible_version) FROM app_instances WHERE heartbeat_at > NOW() - INTERVAL 1 MINUTE
+type SqlSource struct {
+	db    *sql.DB
+	query string
+	args  []any
+}
+
+// NewSqlSource builds a new SqlSource, which runs the query with the given args
+func NewSqlSource(db *sql.DB, query string, args ...any) *SqlSource {
+	return &SqlSource{db: db, query: query, args: args}
+}
+
+// MinCompatibleVersion implements the handler.FleetVersionSource.MinCompatibleVersion method
+func (s *SqlSource) MinCompatibleVersion(ctx context.Context) (uint64, error) {
+	var version sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, s.query, s.args...).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to query the fleet version with error: %w", err)
+	}
+
+	if !version.Valid {
+		return math.MaxUint64, nil
+	}
+	return uint64(version.Int64), nil
+}
+
+// HttpResponse is the body the endpoint of an HttpSource must respond with. A null
+// minCompatibleVersion means that no app instance is running, so no contract migration is
+// blocked.
+type HttpResponse struct {
+	MinCompatibleVersion *uint64 `json:"minCompatibleVersion"`
+}
+
+// HttpSource is a handler.FleetVersionSource which reads the version from an HTTP endpoint
+// (for example, of the deployment service), with a GET request answered with a JSON
+// HttpResponse
+type HttpSource struct {
+	url    string
+	client *http.Client
+}
+
+// NewHttpSource builds a new HttpSource. If client is nil, http.DefaultClient is used.
+func NewHttpSource(url string, client *http.Client) *HttpSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &HttpSource{url: url, client: client}
+}
+
+// MinCompatibleVersion implements the handler.FleetVersionSource.MinCompatibleVersion method
+func (s *HttpSource) MinCompatibleVersion(ctx context.Context) (uint64, error) {
+	errMsg := "failed to load the fleet version from " + s.url
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s, failed to build request with error: %w", errMsg, err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%s, request failed with error: %w", errMsg, err)
+	}
+
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return 0, fmt.Errorf(
+			"%s, unexpected response status %d: %s", errMsg, resp.StatusCode, respBody,
+		)
+	}
+
+	var body HttpResponse
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("%s, failed to decode response with error: %w", errMsg, err)
+	}
+
+	if body.MinCompatibleVersion == nil {
+		return math.MaxUint64, nil
+	}
+	return *body.MinCompatibleVersion, nil
+}
@@ -0,0 +1,145 @@
+package fleet
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeConnector opens connections which answer each query with a single row holding value
+type fakeConnector struct {
+	value    driver.Value
+	queryErr error
+	query    string
+	args     []driver.NamedValue
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{c}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	connector *fakeConnector
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) QueryContext(
+	_ context.Context,
+	query string,
+	args []driver.NamedValue,
+) (driver.Rows, error) {
+	c.connector.query, c.connector.args = query, args
+	if c.connector.queryErr != nil {
+		return nil, c.connector.queryErr
+	}
+	return &fakeRows{values: []driver.Value{c.connector.value}}, nil
+}
+
+type fakeRows struct {
+	values []driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"version"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+type FleetTestSuite struct {
+	suite.Suite
+}
+
+func TestFleetTestSuite(t *testing.T) {
+	suite.Run(t, new(FleetTestSuite))
+}
+
+func (suite *FleetTestSuite) TestItReadsTheFleetVersionFromATable() {
+	connector := &fakeConnector{value: int64(1712953077)}
+	source := NewSqlSource(
+		sql.OpenDB(connector), "SELECT MIN(version) FROM app_instances WHERE env = ?", "prod",
+	)
+
+	version, err := source.MinCompatibleVersion(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(1712953077), version)
+	suite.Assert().Equal("SELECT MIN(version) FROM app_instances WHERE env = ?", connector.query)
+	suite.Assert().Equal("prod", connector.args[0].Value)
+
+	connector.value = nil
+	version, err = source.MinCompatibleVersion(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(math.MaxUint64), version)
+
+	connector.queryErr = errors.New("table not found")
+	_, err = source.MinCompatibleVersion(context.Background())
+	suite.Assert().ErrorContains(err, "failed to query the fleet version with error")
+}
+
+func (suite *FleetTestSuite) TestItReadsTheFleetVersionFromAnEndpoint() {
+	scenarios := map[string]struct {
+		status          int
+		body            string
+		expectedVersion uint64
+		expectedErr     string
+	}{
+		"version":         {http.StatusOK, `{"minCompatibleVersion":3}`, 3, ""},
+		"no running apps": {http.StatusOK, `{"minCompatibleVersion":null}`, math.MaxUint64, ""},
+		"error status":    {http.StatusBadGateway, "down", 0, "unexpected response status 502"},
+		"invalid body":    {http.StatusOK, "3", 0, "failed to decode response"},
+	}
+
+	for name, scenario := range scenarios {
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					suite.Assert().Equal(http.MethodGet, r.Method)
+					w.WriteHeader(scenario.status)
+					_, _ = w.Write([]byte(scenario.body))
+				},
+			),
+		)
+
+		version, err := NewHttpSource(server.URL, nil).MinCompatibleVersion(context.Background())
+		server.Close()
+
+		if scenario.expectedErr != "" {
+			suite.Assert().ErrorContains(err, scenario.expectedErr, "scenario %s", name)
+			continue
+		}
+		suite.Assert().NoError(err, "scenario %s", name)
+		suite.Assert().Equal(scenario.expectedVersion, version, "scenario %s", name)
+	}
+}
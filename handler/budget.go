@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// timeBudgetCtxKey is the context key used to pass the deadline of a time-boxed run
+type timeBudgetCtxKey struct{}
+
+// WithTimeBudget returns a copy of ctx which carries a time budget for the Up() runs of the
+// handler (MigrateUp, MigrateUpMatching, MigrateUpTo). The run stops between migrations, never
+// in the middle of one, once the time left is shorter than the longest migration executed so
+// far in the run (the estimate for the next one). Unlike context.WithTimeout, ctx is not cancelled,
+// so an executing migration is never interrupted.
+func WithTimeBudget(ctx context.Context, maxDuration time.Duration) context.Context {
+	return context.WithValue(ctx, timeBudgetCtxKey{}, time.Now().Add(maxDuration))
+}
+
+// timeBudgetDeadline returns the deadline set with WithTimeBudget. The second return value is
+// false if ctx carries no time budget.
+func timeBudgetDeadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Value(timeBudgetCtxKey{}).(time.Time)
+	return deadline, ok
+}
+
+// BudgetExhaustedError is returned along with the executed migrations when an Up() run
+// stopped at its time budget (see WithTimeBudget)
+type BudgetExhaustedError struct {
+	// Remaining holds the migrations which would have been executed within the run
+	Remaining []migration.Migration
+}
+
+func (e *BudgetExhaustedError) Error() string {
+	return fmt.Sprintf(
+		"time budget exhausted, %d migrations remaining to be executed", len(e.Remaining),
+	)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/golibry/go-migrations/execution"
+)
+
+// executionCheckpointer implements migration.Checkpointer by saving the marker in the
+// unfinished execution of the migration
+type executionCheckpointer struct {
+	mu         sync.Mutex
+	ctx        context.Context
+	repository execution.Repository
+	exec       *execution.MigrationExecution
+}
+
+func newExecutionCheckpointer(
+	ctx context.Context,
+	repository execution.Repository,
+	exec *execution.MigrationExecution,
+) *executionCheckpointer {
+	return &executionCheckpointer{ctx: ctx, repository: repository, exec: exec}
+}
+
+func (c *executionCheckpointer) Checkpoint() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.exec.Checkpoint
+}
+
+func (c *executionCheckpointer) SaveCheckpoint(marker string) error {
+	if len(marker) > execution.MaxCheckpointLength {
+		return fmt.Errorf(
+			"checkpoint of migration %d exceeds %d bytes",
+			c.exec.Version, execution.MaxCheckpointLength,
+		)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.exec.Checkpoint = marker
+	if err := execution.SaveContext(c.ctx, c.repository, *c.exec); err != nil {
+		return fmt.Errorf(
+			"failed to save checkpoint of migration %d with error: %w", c.exec.Version, err,
+		)
+	}
+	return nil
+}
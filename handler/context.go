@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// migrationTimeoutCtxKey is the context key used to pass the timeout of each migration
+type migrationTimeoutCtxKey struct{}
+
+// WithMigrationTimeout returns a copy of ctx which bounds each Up() or Down() call of the
+// handler runs to the given duration: the context the migration gets is cancelled once it
+// elapses. Unlike WithTimeBudget, an executing migration is interrupted, if it honours its
+// context.
+func WithMigrationTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, migrationTimeoutCtxKey{}, timeout)
+}
+
+// migrationContext derives the context of an execution of the migration with the version from
+// the run context: it carries the run ID, the version and the attempt number (see
+// execution.WithMigration) and the run logger, with the version and the attempt as attributes
+// (see migration.WithLogger), and it is cancelled with the run context (for example, on a
+// signal) or once the migration timeout elapses, if any. The returned cancel function must be
+// called once the execution is recorded.
+func migrationContext(
+	ctx context.Context,
+	version uint64,
+	attempt int,
+) (context.Context, context.CancelFunc) {
+	ctx = execution.WithMigration(ctx, version, attempt)
+	ctx = migration.WithLogger(
+		ctx, migration.LoggerFrom(ctx).With("version", version, "attempt", attempt),
+	)
+	if timeout, ok := ctx.Value(migrationTimeoutCtxKey{}).(time.Duration); ok && timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// recordContext returns the context used to record the execution of the migration: the
+// migration context, without its cancellation, so a cancelled (interrupted) execution is
+// still recorded
+func recordContext(migCtx context.Context) context.Context {
+	return context.WithoutCancel(migCtx)
+}
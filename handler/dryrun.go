@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// PlanUp resolves the execution plan and returns the migrations MigrateUpMatching would
+// execute, in order, without calling Up() or saving any execution (dry run)
+func (handler *MigrationsHandler) PlanUp(
+	numOfRuns NumOfRuns,
+	matcher *Matcher,
+) ([]migration.Migration, error) {
+	return handler.planUp(selectUp(numOfRuns, matcher, nil))
+}
+
+// PlanUpTo resolves the execution plan and returns the migrations MigrateUpTo would execute,
+// in order, without calling Up() or saving any execution (dry run)
+func (handler *MigrationsHandler) PlanUpTo(
+	target uint64,
+	matcher *Matcher,
+) ([]migration.Migration, error) {
+	if handler.registry.Get(target) == nil {
+		return []migration.Migration{}, fmt.Errorf(
+			"failed to plan up to version %d, the version is not registered", target,
+		)
+	}
+
+	return handler.planUp(selectUp(NumOfRuns(handler.registry.Count()), matcher, &target))
+}
+
+// PlanUpOnly resolves the execution plan and returns the migration MigrateUpOnly would
+// execute, without calling Up() or saving any execution (dry run)
+func (handler *MigrationsHandler) PlanUpOnly(version uint64) ([]migration.Migration, error) {
+	if handler.registry.Get(version) == nil {
+		return []migration.Migration{}, fmt.Errorf(
+			"failed to plan up only version %d, the version is not registered", version,
+		)
+	}
+
+	return handler.planUp(selectOnly(version))
+}
+
+// SkippedBy resolves the execution plan and returns the migrations, not executed yet, which
+// precede the version, in order. An out of order execution of the version (see MigrateUpOnly)
+// skips them.
+func (handler *MigrationsHandler) SkippedBy(version uint64) ([]migration.Migration, error) {
+	plan, err := handler.newExecutionPlan(handler.registry, handler.repository)
+	if err != nil {
+		return []migration.Migration{}, fmt.Errorf(
+			"failed to find the skipped migrations, failed to create execution plan with"+
+				" error: %w", err,
+		)
+	}
+	return skippedBy(plan, version), nil
+}
+
+func (handler *MigrationsHandler) planUp(selectMigrations upSelector) (
+	[]migration.Migration,
+	error,
+) {
+	errMsg := "failed to plan migrations up"
+	plan, err := handler.newExecutionPlan(handler.registry, handler.repository)
+	if err != nil {
+		return []migration.Migration{}, fmt.Errorf(
+			"%s, failed to create execution plan with error: %w", errMsg, err,
+		)
+	}
+
+	planned, err := selectMigrations(plan)
+	if err != nil {
+		return []migration.Migration{}, fmt.Errorf("%s, %w", errMsg, err)
+	}
+
+	return planned, nil
+}
+
+// PlanDown resolves the execution plan and returns the migrations MigrateDown would roll
+// back, in order (the highest version first), without calling Down() or removing any
+// execution (dry run). Like MigrateDown, it fails if one of them is migration.Irreversible.
+func (handler *MigrationsHandler) PlanDown(numOfRuns NumOfRuns) ([]migration.Migration, error) {
+	plan, err := handler.newExecutionPlan(handler.registry, handler.repository)
+	if err != nil {
+		return []migration.Migration{}, fmt.Errorf(
+			"failed to plan migrations down, failed to create execution plan with error: %w",
+			err,
+		)
+	}
+
+	execMigrations := plan.AllExecuted()
+	slices.Reverse(execMigrations)
+
+	planned := []migration.Migration{}
+	for _, execMig := range execMigrations[:min(len(execMigrations), int(numOfRuns))] {
+		planned = append(planned, execMig.Migration)
+	}
+	if err = migration.ValidateReversible(planned); err != nil {
+		return planned, fmt.Errorf("failed to plan migrations down, %w", err)
+	}
+	return planned, nil
+}
@@ -0,0 +1,52 @@
+package handler
+
+// MigrationError is the error of a migration Up()/Down() call. Its message is the one of the
+// wrapped error, so it only adds the category (see the exit codes of the cli package).
+type MigrationError struct {
+	// Version of the failed migration
+	Version uint64
+
+	// Direction is DirectionUp or DirectionDown
+	Direction string
+
+	Err error
+}
+
+func (e *MigrationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// RepositoryError is the error of the executions repository (loading, saving or removing the
+// executions). Its message is the one of the wrapped error, so it only adds the category.
+type RepositoryError struct {
+	Err error
+}
+
+func (e *RepositoryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RepositoryError) Unwrap() error {
+	return e.Err
+}
+
+// newMigrationError wraps the error of the Up()/Down() call of the migration version in a
+// *MigrationError. A nil error stays nil.
+func newMigrationError(version uint64, direction string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &MigrationError{Version: version, Direction: direction, Err: err}
+}
+
+// newRepositoryError wraps the error in a *RepositoryError. A nil error stays nil.
+func newRepositoryError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RepositoryError{Err: err}
+}
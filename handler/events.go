@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// Directions in which a migration can be executed
+const (
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+// ExecutionEvent describes a migration Up()/Down() call made by the MigrationsHandler
+type ExecutionEvent struct {
+	// Direction is DirectionUp or DirectionDown
+	Direction string
+
+	// Forced is true for ForceUp and ForceDown calls
+	Forced bool
+
+	// Migration is the handled migration. For failed Down() calls, its Execution is nil.
+	Migration ExecutedMigration
+
+	// Err is the error of the Up()/Down() call or of the executions state persistence,
+	// nil on success
+	Err error
+
+	// Duration of the Up()/Down() call, including the executions state persistence
+	Duration time.Duration
+
+	// RunId is the ID of the run which handled the migration (see execution.NewRunId)
+	RunId string
+}
+
+// Succeeded checks if the migration was executed and its state was persisted
+func (event ExecutionEvent) Succeeded() bool {
+	return event.Err == nil
+}
+
+// ExecutionListener is notified after each migration Up()/Down() call. If it fails, the
+// run is stopped and its error is returned by the handler.
+type ExecutionListener func(ctx context.Context, event ExecutionEvent) error
+
+// AddListener registers a listener which is notified after each migration Up()/Down() call
+func (handler *MigrationsHandler) AddListener(listener ExecutionListener) {
+	handler.listeners = append(handler.listeners, listener)
+}
+
+// notify calls all the registered listeners with the given event
+func (handler *MigrationsHandler) notify(ctx context.Context, event ExecutionEvent) error {
+	logEvent(ctx, event)
+
+	var errs []error
+	for _, listener := range handler.listeners {
+		if err := listener(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(append([]error{errors.New("execution listener failed")}, errs...)...)
+	}
+
+	return nil
+}
+
+// logPlan logs, at the info level, the versions of the migrations a run is about to execute,
+// with the logger carried by ctx (see migration.LoggerFrom)
+func logPlan(ctx context.Context, direction string, planned []migration.Migration) {
+	versions := make([]uint64, len(planned))
+	for i, mig := range planned {
+		versions[i] = mig.Version()
+	}
+
+	migration.LoggerFrom(ctx).InfoContext(
+		ctx, "plan computed", "direction", direction, "count", len(versions),
+		"versions", versions,
+	)
+}
+
+// logStart logs, at the info level, the Up()/Down() call about to be made, with the logger
+// carried by the migration context (see migrationContext)
+func logStart(migCtx context.Context, direction string, forced bool) {
+	migration.LoggerFrom(migCtx).InfoContext(
+		migCtx, "migration started", "direction", direction, "forced", forced,
+	)
+}
+
+// logEvent logs the handled migration with the logger carried by ctx (see
+// migration.LoggerFrom): at the info level on success, at the error level otherwise
+func logEvent(ctx context.Context, event ExecutionEvent) {
+	attrs := []any{
+		"version", event.Migration.Migration.Version(),
+		"direction", event.Direction,
+		"forced", event.Forced,
+		"duration", event.Duration,
+	}
+	if event.Migration.RowsAffected != nil {
+		attrs = append(attrs, "rows_affected", *event.Migration.RowsAffected)
+	}
+
+	logger := migration.LoggerFrom(ctx)
+	if event.Err != nil {
+		logger.ErrorContext(ctx, "migration failed", append(attrs, "error", event.Err)...)
+		return
+	}
+	logger.InfoContext(ctx, "migration finished", attrs...)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// gateCtxKey is the context key used to pass the gate of the Up() runs
+type gateCtxKey struct{}
+
+// GateDecision is the decision of a Gate about the execution of a migration
+type GateDecision int
+
+const (
+	// GateAllow executes the migration
+	GateAllow GateDecision = iota
+
+	// GateDefer stops the run before the migration, with a *GateDeferredError: the migration
+	// is not executed yet, and the next run asks the gate again
+	GateDefer
+
+	// GateDeny stops the run before the migration, with a *GateDeniedError
+	GateDeny
+)
+
+func (d GateDecision) String() string {
+	switch d {
+	case GateAllow:
+		return "allow"
+	case GateDefer:
+		return "defer"
+	case GateDeny:
+		return "deny"
+	default:
+		return fmt.Sprintf("GateDecision(%d)", int(d))
+	}
+}
+
+// Gate decides, right before the execution of its Up(), whether a migration is executed now.
+// For example, a backfill coordinated with the gradual rollout of a feature can be deferred
+// until the feature flag is enabled (see the featureflag package). The metadata is the one of
+// the migration (see migration.MetadataOf), empty if it declares none.
+type Gate func(ctx context.Context, version uint64, metadata migration.Metadata) (
+	GateDecision,
+	error,
+)
+
+// WithGate returns a copy of ctx which enables the gate for the Up() runs of the handler
+// (MigrateUp, MigrateUpMatching, MigrateUpTo): the gate is asked about each migration before
+// it is executed. The plan is linear, so the run stops before a deferred or denied migration.
+// A deferred migration is expected to be executed by a later run, without any intervention.
+func WithGate(ctx context.Context, gate Gate) context.Context {
+	return context.WithValue(ctx, gateCtxKey{}, gate)
+}
+
+// GateDeferredError is returned along with the executed migrations when an Up() run stopped
+// before a migration deferred by the gate (see WithGate)
+type GateDeferredError struct {
+	// Remaining holds the migrations which would have been executed within the run, starting
+	// with the deferred migration
+	Remaining []migration.Migration
+}
+
+func (e *GateDeferredError) Error() string {
+	return fmt.Sprintf(
+		"migration %d was deferred by the gate, %d migrations remaining to be executed",
+		e.Remaining[0].Version(), len(e.Remaining),
+	)
+}
+
+// GateDeniedError is returned along with the executed migrations when an Up() run stopped
+// before a migration denied by the gate (see WithGate)
+type GateDeniedError struct {
+	// Remaining holds the migrations which would have been executed within the run, starting
+	// with the denied migration
+	Remaining []migration.Migration
+}
+
+func (e *GateDeniedError) Error() string {
+	return fmt.Sprintf(
+		"migration %d was denied by the gate, %d migrations remaining to be executed",
+		e.Remaining[0].Version(), len(e.Remaining),
+	)
+}
+
+// gateStop asks the gate of ctx, if any, about the first of the remaining migrations, and
+// returns the error which stops the run before it, or nil if it is allowed
+func gateStop(ctx context.Context, remaining []migration.Migration) error {
+	gate, ok := ctx.Value(gateCtxKey{}).(Gate)
+	if !ok || gate == nil {
+		return nil
+	}
+
+	mig := remaining[0]
+	metadata, _ := migration.MetadataOf(mig)
+	decision, err := gate(ctx, mig.Version(), metadata)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to evaluate the gate of migration %d with error: %w", mig.Version(), err,
+		)
+	}
+
+	switch decision {
+	case GateAllow:
+		return nil
+	case GateDefer:
+		migration.LoggerFrom(ctx).InfoContext(
+			ctx, "migration deferred by the gate", "version", mig.Version(),
+		)
+		return &GateDeferredError{Remaining: slices.Clone(remaining)}
+	case GateDeny:
+		return &GateDeniedError{Remaining: slices.Clone(remaining)}
+	default:
+		return fmt.Errorf(
+			"the gate of migration %d returned the unknown decision %s", mig.Version(), decision,
+		)
+	}
+}
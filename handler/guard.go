@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// deploymentGuardCtxKey is the context key used to pass the fleet version source of the
+// blue/green deployment guard
+type deploymentGuardCtxKey struct{}
+
+// FleetVersionSource provides the schema version the running app fleet is compatible with (see
+// the fleet package for the SQL table and HTTP endpoint implementations)
+type FleetVersionSource interface {
+	// MinCompatibleVersion must return the minimum, across the app versions still serving, of
+	// the migration version each one declares it is compatible with
+	MinCompatibleVersion(ctx context.Context) (uint64, error)
+}
+
+// WithDeploymentGuard returns a copy of ctx which enables the blue/green deployment guard for
+// the Up() runs of the handler (MigrateUp, MigrateUpMatching, MigrateUpTo): a contract
+// migration (see migration.IsContract) newer than the version the running app fleet is
+// compatible with is not executed while old app versions are still serving. The run stops
+// before it, with a *ContractBlockedError. The source is queried once per run, only if a
+// contract migration is to be executed.
+func WithDeploymentGuard(ctx context.Context, source FleetVersionSource) context.Context {
+	return context.WithValue(ctx, deploymentGuardCtxKey{}, source)
+}
+
+// ContractBlockedError is returned along with the executed migrations when an Up() run stopped
+// before a contract migration, because the running app fleet is not compatible with it yet
+// (see WithDeploymentGuard)
+type ContractBlockedError struct {
+	// FleetVersion is the version the running app fleet is compatible with
+	FleetVersion uint64
+
+	// Remaining holds the migrations which would have been executed within the run, starting
+	// with the blocked contract migration
+	Remaining []migration.Migration
+}
+
+func (e *ContractBlockedError) Error() string {
+	return fmt.Sprintf(
+		"contract migration %d can't be executed while the running app fleet is compatible "+
+			"only up to version %d, %d migrations remaining to be executed",
+		e.Remaining[0].Version(), e.FleetVersion, len(e.Remaining),
+	)
+}
+
+// contractBlockedAt returns the index of the first migration which the deployment guard of ctx
+// blocks and the fleet version, or -1 if none is blocked
+func contractBlockedAt(ctx context.Context, migrations []migration.Migration) (int, uint64, error) {
+	source, ok := ctx.Value(deploymentGuardCtxKey{}).(FleetVersionSource)
+	if !ok || source == nil {
+		return -1, 0, nil
+	}
+
+	var fleetVersion uint64
+	loaded := false
+	for i, mig := range migrations {
+		if !migration.IsContract(mig) {
+			continue
+		}
+
+		if !loaded {
+			var err error
+			if fleetVersion, err = source.MinCompatibleVersion(ctx); err != nil {
+				return -1, 0, fmt.Errorf(
+					"failed to load the running app fleet version with error: %w", err,
+				)
+			}
+			loaded = true
+		}
+
+		if mig.Version() > fleetVersion {
+			return i, fleetVersion, nil
+		}
+	}
+
+	return -1, fleetVersion, nil
+}
@@ -9,6 +9,7 @@
 package handler
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -16,6 +17,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golibry/go-migrations/execution"
 	"github.com/golibry/go-migrations/migration"
@@ -32,6 +34,40 @@ type ExecutedMigration struct {
 	// and whether it completed successfully. It may be nil if the migration
 	// has not been executed yet.
 	Execution *execution.MigrationExecution
+
+	// RowsAffected is the number of rows affected by the migration, as recorded via
+	// migration.RecordRowsAffected (see the sqlhelper package). It is nil if the
+	// migration did not record any affected rows.
+	RowsAffected *int64
+}
+
+// newExecutedMigration builds a new ExecutedMigration with the affected rows collected by
+// the given counter
+func newExecutedMigration(
+	mig migration.Migration,
+	exec *execution.MigrationExecution,
+	counter *migration.RowsCounter,
+) ExecutedMigration {
+	executed := ExecutedMigration{Migration: mig, Execution: exec}
+	if counter != nil && counter.Recorded() {
+		rows := counter.Count()
+		executed.RowsAffected = &rows
+	}
+	return executed
+}
+
+// TotalRowsAffected sums up the affected rows of the given executed migrations. The second
+// return value is false if none of them recorded affected rows.
+func TotalRowsAffected(executedMigrations []ExecutedMigration) (int64, bool) {
+	var total int64
+	recorded := false
+	for _, executed := range executedMigrations {
+		if executed.RowsAffected != nil {
+			total += *executed.RowsAffected
+			recorded = true
+		}
+	}
+	return total, recorded
 }
 
 // ExecutionPlan determines which migrations need to be executed and in what order.
@@ -43,15 +79,40 @@ type ExecutionPlan struct {
 
 	// orderedExecutions contains all executed migrations in order of their version numbers
 	orderedExecutions []execution.MigrationExecution
+
+	// outOfOrder is true if the executions may skip registered versions (see
+	// NewOutOfOrderPlan)
+	outOfOrder bool
 }
 
 // NewPlan Creates a new ExecutionPlan. Errors if it finds that migrations and executions
 // loaded from the provided registry & repository are in an inconsistent state. An inconsistent
 // state can be: more executions in the repository than the total number of registered
-// migrations
+// migrations, executions which don't match the registered migrations in order (including the
+// versions skipped by later executions) or unfinished executions other than the last one.
+// Use NewOutOfOrderPlan to allow the executions recorded out of order.
 func NewPlan(
 	registry migration.MigrationsRegistry,
 	repository execution.Repository,
+) (*ExecutionPlan, error) {
+	return newPlan(registry, repository, false)
+}
+
+// NewOutOfOrderPlan works like NewPlan, but allows the executions to skip registered versions,
+// as recorded by MigrationsHandler.MigrateUpOnly: the skipped versions are still to be
+// executed. The executions must still match registered versions, and only the last started
+// execution may be unfinished. Build the handler with it to run migrations out of order.
+func NewOutOfOrderPlan(
+	registry migration.MigrationsRegistry,
+	repository execution.Repository,
+) (*ExecutionPlan, error) {
+	return newPlan(registry, repository, true)
+}
+
+func newPlan(
+	registry migration.MigrationsRegistry,
+	repository execution.Repository,
+	outOfOrder bool,
 ) (*ExecutionPlan, error) {
 	genericErrMsg := "failed to create new execution plan"
 	errHelpMsg := "Fix executions issues before trying to manipulate their state"
@@ -59,7 +120,8 @@ func NewPlan(
 	executions, err := repository.LoadExecutions()
 	if err != nil {
 		return nil, fmt.Errorf(
-			"%s, failed to load executions with error: %w. %s", genericErrMsg, err, errHelpMsg,
+			"%s, failed to load executions with error: %w. %s",
+			genericErrMsg, newRepositoryError(err), errHelpMsg,
 		)
 	}
 
@@ -72,6 +134,7 @@ func NewPlan(
 	plan := &ExecutionPlan{
 		orderedMigrations: registry.OrderedMigrations(),
 		orderedExecutions: executions,
+		outOfOrder:        outOfOrder,
 	}
 
 	if len(plan.orderedExecutions) > len(plan.orderedMigrations) {
@@ -81,6 +144,13 @@ func NewPlan(
 		)
 	}
 
+	if outOfOrder {
+		if err := plan.validateOutOfOrder(genericErrMsg, errHelpMsg); err != nil {
+			return nil, err
+		}
+		return plan, nil
+	}
+
 	for i, exec := range plan.orderedExecutions {
 		if !exec.Finished() && i != len(plan.orderedExecutions)-1 {
 			return nil, fmt.Errorf(
@@ -102,35 +172,95 @@ func NewPlan(
 	return plan, err
 }
 
+// validateOutOfOrder checks that the executions match registered versions, in any order, and
+// that only the last started execution is unfinished
+func (plan *ExecutionPlan) validateOutOfOrder(genericErrMsg string, errHelpMsg string) error {
+	lastStarted := 0
+	for i, exec := range plan.orderedExecutions {
+		if plan.migration(exec.Version) == nil {
+			return fmt.Errorf(
+				"%s, execution %d at index %d does not match with any registered migration."+
+					" Migrations and executions are out of order. %s",
+				genericErrMsg, exec.Version, i, errHelpMsg,
+			)
+		}
+		if exec.ExecutedAtMs >= plan.orderedExecutions[lastStarted].ExecutedAtMs {
+			lastStarted = i
+		}
+	}
+
+	for i, exec := range plan.orderedExecutions {
+		if !exec.Finished() && i != lastStarted {
+			return fmt.Errorf(
+				"%s, execution %d is not finished, but it is not the last started execution."+
+					" Only the last started execution should have an \"unfinished\" state. %s",
+				genericErrMsg, exec.Version, errHelpMsg,
+			)
+		}
+	}
+	return nil
+}
+
+// migration returns the registered migration of the version, nil if it is not registered
+func (plan *ExecutionPlan) migration(version uint64) migration.Migration {
+	i, found := slices.BinarySearchFunc(
+		plan.orderedMigrations, version, func(mig migration.Migration, version uint64) int {
+			return cmp.Compare(mig.Version(), version)
+		},
+	)
+	if !found {
+		return nil
+	}
+	return plan.orderedMigrations[i]
+}
+
 func (plan *ExecutionPlan) RegisteredMigrationsCount() int {
 	return len(plan.orderedMigrations)
 }
 
 func (plan *ExecutionPlan) FinishedExecutionsCount() int {
 	count := len(plan.orderedExecutions)
-	if count > 0 && !plan.orderedExecutions[count-1].Finished() {
+	if plan.Unfinished() != nil {
 		count--
 	}
 	return count
 }
 
+// AllToBeExecuted returns the registered migrations without a finished execution, in order,
+// including the versions skipped by the executions of later versions
 func (plan *ExecutionPlan) AllToBeExecuted() []migration.Migration {
-	finishedExecCount := plan.FinishedExecutionsCount()
+	finished := make(map[uint64]bool, len(plan.orderedExecutions))
+	for _, exec := range plan.orderedExecutions {
+		finished[exec.Version] = exec.Finished()
+	}
 
-	if finishedExecCount < plan.RegisteredMigrationsCount() {
-		return plan.orderedMigrations[finishedExecCount:]
+	toBeExecuted := []migration.Migration{}
+	for _, mig := range plan.orderedMigrations {
+		if !finished[mig.Version()] {
+			toBeExecuted = append(toBeExecuted, mig)
+		}
 	}
+	return toBeExecuted
+}
 
-	return []migration.Migration{}
+// Unfinished returns the execution which is not finished (its migration failed or the run
+// was interrupted), nil if there is none
+func (plan *ExecutionPlan) Unfinished() *execution.MigrationExecution {
+	for _, exec := range plan.orderedExecutions {
+		if !exec.Finished() {
+			return &exec
+		}
+	}
+	return nil
 }
 
 func (plan *ExecutionPlan) AllExecuted() []ExecutedMigration {
 	var execMigrations []ExecutedMigration
 
-	for i, exec := range plan.orderedExecutions {
+	for _, exec := range plan.orderedExecutions {
 		execMigrations = append(
 			execMigrations, ExecutedMigration{
-				Migration: plan.orderedMigrations[i],
+				Migration: plan.migration(exec.Version),
 				Execution: &exec,
 			},
 		)
@@ -171,6 +301,7 @@ type MigrationsHandler struct {
 	repository       execution.Repository
 	newExecutionPlan ExecutionPlanBuilder
 	db               any
+	listeners        []ExecutionListener
 }
 
 func NewHandler(
@@ -187,7 +318,7 @@ func NewHandlerWithDB(
 	newExecutionPlan ExecutionPlanBuilder,
 	db any,
 ) (*MigrationsHandler, error) {
-	err := repository.Init()
+	err := execution.Initialize(repository)
 
 	if err != nil {
 		return nil, fmt.Errorf(
@@ -239,12 +370,219 @@ func NewNumOfRuns(num string) (NumOfRuns, error) {
 func (handler *MigrationsHandler) MigrateUp(
 	ctx context.Context,
 	numOfRuns NumOfRuns,
+) ([]ExecutedMigration, error) {
+	return handler.MigrateUpMatching(ctx, numOfRuns, nil)
+}
+
+// MigrateUpMatching executes Up() for the next numOfRuns migrations, like MigrateUp, but only
+// while they match the given matcher (nil matches all). Fails without executing anything if
+// a migration which does not match must run before a matching one.
+//
+// If ctx carries a time budget (see WithTimeBudget), the run may stop before executing all
+// the migrations, returning a *BudgetExhaustedError with the remaining ones. Likewise, if ctx
+// carries a gate (see WithGate), it stops before a deferred or denied migration.
+//
+// The migrations get a migration.Checkpointer through their context. The execution of a
+// migration which failed or was interrupted resumes from its last saved checkpoint.
+//
+// If ctx enables the safe rerun mode (see WithRerunCheck), the migrations which are not
+// idempotent are reported with a *RerunUnsafeError, after running all the migrations.
+//
+// The repository is pinged during the run, if it implements execution.Pinger, so its
+// connection is kept alive while long migrations execute (see WithKeepalive).
+func (handler *MigrationsHandler) MigrateUpMatching(
+	ctx context.Context,
+	numOfRuns NumOfRuns,
+	matcher *Matcher,
+) ([]ExecutedMigration, error) {
+	return handler.migrateUp(ctx, selectUp(numOfRuns, matcher, nil))
+}
+
+// MigrateUpTo executes Up() for all the registered and not yet executed migrations up to and
+// including the target version, which must be registered, so a rollout can be staged (for
+// example, the additive migrations of a release before the destructive ones). Only the
+// migrations which match the given matcher (nil matches all) are executed, as in
+// MigrateUpMatching. Executing nothing is not an error if the target is already executed.
+func (handler *MigrationsHandler) MigrateUpTo(
+	ctx context.Context,
+	target uint64,
+	matcher *Matcher,
+) ([]ExecutedMigration, error) {
+	if handler.registry.Get(target) == nil {
+		return []ExecutedMigration{}, fmt.Errorf(
+			"failed to migrate up to version %d, the version is not registered", target,
+		)
+	}
+
+	return handler.migrateUp(
+		ctx, selectUp(NumOfRuns(handler.registry.Count()), matcher, &target),
+	)
+}
+
+// MigrateUpOnly executes Up() for the registered and not yet executed migration of the version
+// only, out of order: the earlier migrations which are not executed yet are skipped, and are
+// executed by the next up runs. Useful for the hotfix migrations which must ship before
+// earlier, slower ones (for example, backfills) are ready. Running a migration out of order is
+// logged as a warning, with the skipped versions. Skipping versions requires a handler built
+// with NewOutOfOrderPlan, so the next runs accept the executions recorded out of order.
+//
+// Fails without executing anything if another execution is unfinished, since it must be
+// resumed (or rolled back) first, if earlier versions would be skipped without an out-of-order
+// execution plan, or if the migration is a hotfix (see migration.Hotfix) of a
+// version which is not executed yet. The context options of MigrateUpMatching apply.
+func (handler *MigrationsHandler) MigrateUpOnly(
+	ctx context.Context,
+	version uint64,
+) ([]ExecutedMigration, error) {
+	if handler.registry.Get(version) == nil {
+		return []ExecutedMigration{}, fmt.Errorf(
+			"failed to migrate up only version %d, the version is not registered", version,
+		)
+	}
+
+	return handler.migrateUp(
+		ctx, func(plan *ExecutionPlan) ([]migration.Migration, error) {
+			selected, err := selectOnly(version)(plan)
+			if err == nil {
+				warnOutOfOrder(ctx, plan, version)
+			}
+			return selected, err
+		},
+	)
+}
+
+// upSelector selects, from the plan, the migrations to be executed by an up run, in order
+type upSelector func(plan *ExecutionPlan) ([]migration.Migration, error)
+
+// selectUp selects the next numOfRuns migrations to be executed, which match the matcher (nil
+// matches all), stopping after the target version, if any
+func selectUp(numOfRuns NumOfRuns, matcher *Matcher, target *uint64) upSelector {
+	return func(plan *ExecutionPlan) ([]migration.Migration, error) {
+		allToBeExec := plan.AllToBeExecuted()
+		if target != nil {
+			allToBeExec = slices.DeleteFunc(
+				slices.Clone(allToBeExec),
+				func(mig migration.Migration) bool { return mig.Version() > *target },
+			)
+		}
+
+		if matcher != nil {
+			var err error
+			if allToBeExec, err = matcher.selectLeading(allToBeExec, int(numOfRuns)); err != nil {
+				return nil, err
+			}
+		}
+
+		return allToBeExec[:min(len(allToBeExec), int(numOfRuns))], nil
+	}
+}
+
+// selectOnly selects the migration of the version, if it is not executed yet (see
+// MigrationsHandler.MigrateUpOnly)
+func selectOnly(version uint64) upSelector {
+	return func(plan *ExecutionPlan) ([]migration.Migration, error) {
+		allToBeExec := plan.AllToBeExecuted()
+		i := slices.IndexFunc(
+			allToBeExec, func(mig migration.Migration) bool { return mig.Version() == version },
+		)
+		if i < 0 {
+			return nil, fmt.Errorf("the version %d is already executed", version)
+		}
+
+		if unfinished := plan.Unfinished(); unfinished != nil && unfinished.Version != version {
+			return nil, fmt.Errorf(
+				"the execution of version %d is unfinished, resume or roll it back first",
+				unfinished.Version,
+			)
+		}
+
+		if i > 0 && !plan.outOfOrder {
+			return nil, fmt.Errorf(
+				"the version %d can't run out of order, before %d earlier migrations, with an"+
+					" execution plan which doesn't allow it (see NewOutOfOrderPlan)",
+				version, i,
+			)
+		}
+
+		// a hotfix must not run before the migration it reverses
+		fixed, isHotfix := migration.FixedVersionOf(allToBeExec[i])
+		if isHotfix && slices.ContainsFunc(
+			allToBeExec[:i], func(mig migration.Migration) bool { return mig.Version() == fixed },
+		) {
+			return nil, fmt.Errorf(
+				"the hotfix migration %d can't run before the version %d it fixes",
+				version, fixed,
+			)
+		}
+
+		return allToBeExec[i : i+1], nil
+	}
+}
+
+// skippedBy returns the migrations to be executed before the version, in order
+func skippedBy(plan *ExecutionPlan, version uint64) []migration.Migration {
+	allToBeExec := plan.AllToBeExecuted()
+	i := slices.IndexFunc(
+		allToBeExec, func(mig migration.Migration) bool { return mig.Version() >= version },
+	)
+	if i < 0 {
+		return allToBeExec
+	}
+	return allToBeExec[:i]
+}
+
+// warnOutOfOrder logs a warning with the versions skipped by the execution of the version, if
+// it runs out of order
+func warnOutOfOrder(ctx context.Context, plan *ExecutionPlan, version uint64) {
+	var skipped []uint64
+	for _, mig := range skippedBy(plan, version) {
+		skipped = append(skipped, mig.Version())
+	}
+
+	if len(skipped) > 0 {
+		migration.LoggerFrom(ctx).WarnContext(
+			ctx, "executing a migration out of order", "version", version, "skipped", skipped,
+		)
+	}
+}
+
+// checkWritable fails the runs of migrations with a read-only repository (see
+// execution.CheckWritable) before executing any, since their executions could not be saved.
+// The runs without migrations to execute don't check the repository.
+func (handler *MigrationsHandler) checkWritable(numOfRuns int) error {
+	if numOfRuns == 0 {
+		return nil
+	}
+	return newRepositoryError(execution.CheckWritable(handler.repository))
+}
+
+// unhandledCollisions returns a *migration.CollisionError if the registry quarantined
+// colliding versions which no collision policy handled (for example, those registered to
+// migration.DefaultRegistry, used as it is), so the up runs fail before executing anything,
+// as the registration of a duplicate version fails without a collision policy
+func unhandledCollisions(registry migration.MigrationsRegistry) error {
+	resolver, ok := registry.(migration.CollisionResolver)
+	if !ok || resolver.CollisionPolicy() != migration.CollisionFail {
+		return nil
+	}
+
+	if collisions := resolver.Collisions(); len(collisions) > 0 {
+		return &migration.CollisionError{Quarantined: collisions}
+	}
+	return nil
+}
+
+// migrateUp executes Up() for the migrations selected from the execution plan
+func (handler *MigrationsHandler) migrateUp(
+	ctx context.Context,
+	selectMigrations upSelector,
 ) ([]ExecutedMigration, error) {
 	if handler.registry.Count() == 0 {
 		return []ExecutedMigration{}, nil
 	}
 
 	errMsg := "failed to migrate all up"
+	ctx, runId := execution.EnsureRunId(ctx)
 
 	plan, err := handler.newExecutionPlan(handler.registry, handler.repository)
 	if err != nil {
@@ -253,35 +591,139 @@ func (handler *MigrationsHandler) MigrateUp(
 		)
 	}
 
-	allToBeExec := plan.AllToBeExecuted()
-	actualNumOfRuns := min(len(allToBeExec), int(numOfRuns))
+	allToBeExec, err := selectMigrations(plan)
+	if err != nil {
+		return []ExecutedMigration{}, fmt.Errorf("%s, %w", errMsg, err)
+	}
+	if err = unhandledCollisions(handler.registry); err != nil {
+		return []ExecutedMigration{}, fmt.Errorf("%s, %w", errMsg, err)
+	}
+	actualNumOfRuns := len(allToBeExec)
+	if err = handler.checkWritable(actualNumOfRuns); err != nil {
+		return []ExecutedMigration{}, fmt.Errorf("%s, %w", errMsg, err)
+	}
+	logPlan(ctx, DirectionUp, allToBeExec)
+	progress := newRunProgress(ctx, DirectionUp, actualNumOfRuns)
+	blockedAt, fleetVersion, err := contractBlockedAt(ctx, allToBeExec)
+	if err != nil {
+		return []ExecutedMigration{}, fmt.Errorf("%s, %w", errMsg, err)
+	}
+	defer startKeepalive(ctx, handler.repository)()
+	deadline, budgeted := timeBudgetDeadline(ctx)
+	var longest time.Duration
+	rerunCheck := rerunCheckEnabled(ctx)
+	var rerunUnsafe []RerunUnsafeMigration
 
 	var handledMigrations []ExecutedMigration
 	for i := 0; i < actualNumOfRuns; i++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = fmt.Errorf("%s, the run was cancelled: %w", errMsg, ctxErr)
+			break
+		}
+
+		// the plan is linear, so the run stops at a quarantined version
+		if collisionErr := migration.CollisionErrorOf(allToBeExec[i]); collisionErr != nil {
+			err = fmt.Errorf("%s, %w", errMsg, collisionErr)
+			break
+		}
+
+		// a hotfix must not run before, or without, the migration it reverses
+		if err = migration.ValidateHotfix(handler.registry, allToBeExec[i]); err != nil {
+			err = fmt.Errorf("%s, %w", errMsg, err)
+			break
+		}
+
+		if i == blockedAt {
+			err = &ContractBlockedError{
+				FleetVersion: fleetVersion,
+				Remaining:    slices.Clone(allToBeExec[i:actualNumOfRuns]),
+			}
+			break
+		}
+
+		if budgeted && time.Until(deadline) <= longest {
+			err = &BudgetExhaustedError{Remaining: slices.Clone(allToBeExec[i:actualNumOfRuns])}
+			break
+		}
+
+		if err = gateStop(ctx, allToBeExec[i:actualNumOfRuns]); err != nil {
+			break
+		}
+
 		migrationToExec := allToBeExec[i]
+		start := time.Now()
 		exec := execution.StartExecution(migrationToExec)
+		exec.RunId = runId
+		if unfinished := plan.Unfinished(); unfinished != nil && unfinished.Version == exec.Version {
+			exec.Checkpoint = unfinished.Checkpoint
+		}
+
+		migCtx, cancel := migrationContext(ctx, migrationToExec.Version(), 1)
+		migCtx, counter := migration.WithRowsCounter(migCtx)
+		migCtx = migration.WithCheckpointer(
+			migCtx, newExecutionCheckpointer(migCtx, handler.repository, exec),
+		)
 
-		if err = migrationToExec.Up(ctx, handler.db); err == nil {
+		logStart(migCtx, DirectionUp, false)
+		progress.started(ctx, i, exec.Version)
+		err = newMigrationError(
+			exec.Version, DirectionUp, migrationToExec.Up(migCtx, handler.db),
+		)
+		if err == nil {
 			exec.FinishExecution()
 		}
 
-		handledMigrations = append(handledMigrations, ExecutedMigration{migrationToExec, exec})
-		saveErr := handler.repository.Save(*exec)
+		executed := newExecutedMigration(migrationToExec, exec, counter)
+		handledMigrations = append(handledMigrations, executed)
+		saveErr := newRepositoryError(
+			execution.SaveContext(recordContext(migCtx), handler.repository, *exec),
+		)
+		cancel()
+		progress.finished(ctx, i, exec.Version, time.Since(start), errors.Join(err, saveErr))
+		notifyErr := handler.notify(
+			ctx,
+			ExecutionEvent{
+				DirectionUp, false, executed, errors.Join(err, saveErr), time.Since(start), runId,
+			},
+		)
+
+		if rerunCheck && err == nil && saveErr == nil {
+			if unsafe := handler.rerun(ctx, migrationToExec); unsafe != nil {
+				rerunUnsafe = append(rerunUnsafe, *unsafe)
+			}
+		}
+
+		longest = max(longest, time.Since(start))
 
 		if err != nil || saveErr != nil {
 			err = fmt.Errorf("%s, errors: %w, %w", errMsg, err, saveErr)
+		}
+
+		if notifyErr != nil {
+			err = errors.Join(err, notifyErr)
+		}
+
+		if err != nil {
 			break
 		}
 	}
 
+	if len(rerunUnsafe) > 0 {
+		err = errors.Join(err, &RerunUnsafeError{Migrations: rerunUnsafe})
+	}
+
 	return handledMigrations, err
 }
 
+// MigrateDown executes Down() for the last numOfRuns executed migrations, the highest version
+// first, removing their executions. Fails without rolling back anything if one of them is
+// migration.Irreversible (for example, a hotfix built by migration.NewHotfix).
 func (handler *MigrationsHandler) MigrateDown(
 	ctx context.Context,
 	numOfRuns NumOfRuns,
 ) ([]ExecutedMigration, error) {
 	errMsg := "failed to migrate all down"
+	ctx, runId := execution.EnsureRunId(ctx)
 
 	plan, err := handler.newExecutionPlan(handler.registry, handler.repository)
 	if err != nil {
@@ -293,45 +735,201 @@ func (handler *MigrationsHandler) MigrateDown(
 	execMigrations := plan.AllExecuted()
 	slices.Reverse(execMigrations)
 	actualNumOfRuns := min(len(execMigrations), int(numOfRuns))
+	if err = handler.checkWritable(actualNumOfRuns); err != nil {
+		return []ExecutedMigration{}, fmt.Errorf("%s, %w", errMsg, err)
+	}
+	planned := make([]migration.Migration, actualNumOfRuns)
+	for i := range planned {
+		planned[i] = execMigrations[i].Migration
+	}
+	if err = migration.ValidateReversible(planned); err != nil {
+		return []ExecutedMigration{}, fmt.Errorf("%s, %w", errMsg, err)
+	}
+	logPlan(ctx, DirectionDown, planned)
+	progress := newRunProgress(ctx, DirectionDown, actualNumOfRuns)
+	defer startKeepalive(ctx, handler.repository)()
 
 	var handledMigrations []ExecutedMigration
 	for i := 0; i < actualNumOfRuns; i++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = fmt.Errorf("%s, the run was cancelled: %w", errMsg, ctxErr)
+			break
+		}
+
 		execMig := execMigrations[i]
-		if err = execMig.Migration.Down(ctx, handler.db); err != nil {
-			handledMigrations = append(handledMigrations, ExecutedMigration{execMig.Migration, nil})
+		if collisionErr := migration.CollisionErrorOf(execMig.Migration); collisionErr != nil {
+			err = fmt.Errorf("%s, %w", errMsg, collisionErr)
 			break
 		}
 
-		err = handler.repository.Remove(*execMig.Execution)
+		start := time.Now()
+		migCtx, cancel := migrationContext(ctx, execMig.Migration.Version(), 1)
+		migCtx, counter := migration.WithRowsCounter(migCtx)
+		logStart(migCtx, DirectionDown, false)
+		progress.started(ctx, i, execMig.Execution.Version)
+		err = newMigrationError(
+			execMig.Execution.Version, DirectionDown, execMig.Migration.Down(migCtx, handler.db),
+		)
+		if err == nil {
+			err = newRepositoryError(
+				execution.RemoveContext(
+					recordContext(migCtx), handler.repository, *execMig.Execution,
+				),
+			)
+		}
+		cancel()
 
+		executed := newExecutedMigration(execMig.Migration, execMig.Execution, counter)
 		if err != nil {
-			handledMigrations = append(handledMigrations, ExecutedMigration{execMig.Migration, nil})
-			break
+			executed = newExecutedMigration(execMig.Migration, nil, counter)
+		}
+
+		handledMigrations = append(handledMigrations, executed)
+		progress.finished(ctx, i, execMig.Execution.Version, time.Since(start), err)
+		notifyErr := handler.notify(
+			ctx, ExecutionEvent{DirectionDown, false, executed, err, time.Since(start), runId},
+		)
+
+		if notifyErr != nil {
+			err = errors.Join(err, notifyErr)
 		}
 
-		handledMigrations = append(handledMigrations, execMig)
+		if err != nil {
+			break
+		}
 	}
 
 	return handledMigrations, err
 }
 
+// CapturedMigration holds the statements a migration would execute, as recorded in
+// capture mode (see migration.WithCapture)
+type CapturedMigration struct {
+	Migration migration.Migration
+
+	// Capturable is false if the migration is not migration.CaptureCapable. Such migrations
+	// are not called, so they have no statements.
+	Capturable bool
+
+	Statements []migration.CapturedStatement
+}
+
+// CaptureUp calls Up() with a capturing context for the next numOfRuns migrations to be
+// executed, without saving any execution. Only capture capable migrations are called, so
+// the database state is not changed. Useful to inspect (explain, for example) what a run
+// would do.
+func (handler *MigrationsHandler) CaptureUp(
+	ctx context.Context,
+	numOfRuns NumOfRuns,
+) ([]CapturedMigration, error) {
+	errMsg := "failed to capture migrations up"
+	ctx, _ = execution.EnsureRunId(ctx)
+
+	plan, err := handler.newExecutionPlan(handler.registry, handler.repository)
+	if err != nil {
+		return []CapturedMigration{}, fmt.Errorf(
+			"%s, failed to create execution plan with error: %w", errMsg, err,
+		)
+	}
+
+	allToBeExec := plan.AllToBeExecuted()
+	actualNumOfRuns := min(len(allToBeExec), int(numOfRuns))
+
+	return handler.CaptureMigrationsUp(ctx, allToBeExec[:actualNumOfRuns])
+}
+
+// CaptureMigrationsUp calls Up() with a capturing context for the given migrations (planned
+// with PlanUp or PlanUpTo, for example), in order, without saving any execution. Like
+// CaptureUp, only capture capable migrations are called.
+func (handler *MigrationsHandler) CaptureMigrationsUp(
+	ctx context.Context,
+	migrations []migration.Migration,
+) ([]CapturedMigration, error) {
+	return handler.captureMigrations(ctx, migrations, DirectionUp)
+}
+
+// CaptureMigrationsDown calls Down() with a capturing context for the given migrations
+// (planned with PlanDown, for example), in order, without removing any execution. Like
+// CaptureUp, only capture capable migrations are called.
+func (handler *MigrationsHandler) CaptureMigrationsDown(
+	ctx context.Context,
+	migrations []migration.Migration,
+) ([]CapturedMigration, error) {
+	return handler.captureMigrations(ctx, migrations, DirectionDown)
+}
+
+// captureMigrations calls Up() or Down(), depending on the direction, with a capturing
+// context for the capture capable migrations
+func (handler *MigrationsHandler) captureMigrations(
+	ctx context.Context,
+	migrations []migration.Migration,
+	direction string,
+) ([]CapturedMigration, error) {
+	ctx, _ = execution.EnsureRunId(ctx)
+
+	var captured []CapturedMigration
+	for _, mig := range migrations {
+		if !migration.IsCaptureCapable(mig) {
+			captured = append(captured, CapturedMigration{Migration: mig})
+			continue
+		}
+
+		migCtx, capture := migration.WithCapture(ctx)
+		run := mig.Up
+		if direction == DirectionDown {
+			run = mig.Down
+		}
+		if err := run(migCtx, handler.db); err != nil {
+			return captured, fmt.Errorf(
+				"failed to capture migrations %s, migration %d %s() failed with error: %w",
+				direction, mig.Version(), direction, err,
+			)
+		}
+
+		captured = append(captured, CapturedMigration{mig, true, capture.Statements()})
+	}
+
+	return captured, nil
+}
+
 func (handler *MigrationsHandler) ForceUp(ctx context.Context, version uint64) (
 	ExecutedMigration,
 	error,
 ) {
 	migrationToExec := handler.registry.Get(version)
 	if migrationToExec == nil {
-		return ExecutedMigration{nil, nil}, nil
+		return ExecutedMigration{}, nil
+	}
+
+	if err := migration.CollisionErrorOf(migrationToExec); err != nil {
+		return ExecutedMigration{Migration: migrationToExec}, fmt.Errorf(
+			"failed to migrate up forcefully, %w", err,
+		)
+	}
+
+	if err := handler.checkWritable(1); err != nil {
+		return ExecutedMigration{Migration: migrationToExec}, fmt.Errorf(
+			"failed to migrate up forcefully, %w", err,
+		)
 	}
 
+	ctx, runId := execution.EnsureRunId(ctx)
+	start := time.Now()
 	exec := execution.StartExecution(migrationToExec)
+	exec.RunId = runId
+	migCtx, cancel := migrationContext(ctx, version, 1)
+	defer cancel()
+	migCtx, counter := migration.WithRowsCounter(migCtx)
 
-	err := migrationToExec.Up(ctx, handler.db)
+	logStart(migCtx, DirectionUp, true)
+	err := newMigrationError(version, DirectionUp, migrationToExec.Up(migCtx, handler.db))
 	if err == nil {
 		exec.FinishExecution()
 	}
 
-	errSave := handler.repository.Save(*exec)
+	errSave := newRepositoryError(
+		execution.SaveContext(recordContext(migCtx), handler.repository, *exec),
+	)
 
 	if err == nil {
 		err = errSave
@@ -339,7 +937,16 @@ func (handler *MigrationsHandler) ForceUp(ctx context.Context, version uint64) (
 		err = fmt.Errorf("%w, %w", err, errSave)
 	}
 
-	return ExecutedMigration{migrationToExec, exec}, err
+	executed := newExecutedMigration(migrationToExec, exec, counter)
+	notifyErr := handler.notify(
+		ctx, ExecutionEvent{DirectionUp, true, executed, err, time.Since(start), runId},
+	)
+
+	if notifyErr != nil {
+		err = errors.Join(err, notifyErr)
+	}
+
+	return executed, err
 }
 
 func (handler *MigrationsHandler) ForceDown(ctx context.Context, version uint64) (
@@ -350,29 +957,57 @@ func (handler *MigrationsHandler) ForceDown(ctx context.Context, version uint64)
 
 	migrationToExec := handler.registry.Get(version)
 	if migrationToExec == nil {
-		return ExecutedMigration{nil, nil}, nil
+		return ExecutedMigration{}, nil
+	}
+
+	if err := migration.CollisionErrorOf(migrationToExec); err != nil {
+		return ExecutedMigration{Migration: migrationToExec}, fmt.Errorf("%s, %w", errMsg, err)
+	}
+
+	if err := handler.checkWritable(1); err != nil {
+		return ExecutedMigration{Migration: migrationToExec}, fmt.Errorf("%s, %w", errMsg, err)
 	}
 
 	exec, err := handler.repository.FindOne(version)
 	if err != nil {
-		return ExecutedMigration{migrationToExec, nil}, fmt.Errorf(
-			"%s, failed to load execution with error: %w", errMsg, err,
+		return ExecutedMigration{Migration: migrationToExec}, fmt.Errorf(
+			"%s, failed to load execution with error: %w", errMsg, newRepositoryError(err),
 		)
 	}
 
 	if exec == nil {
-		return ExecutedMigration{migrationToExec, nil}, fmt.Errorf(
+		return ExecutedMigration{Migration: migrationToExec}, fmt.Errorf(
 			"%s, execution not found. Maybe the migration was never executed", errMsg,
 		)
 	}
 
-	if errDown := migrationToExec.Down(ctx, handler.db); errDown != nil {
-		return ExecutedMigration{migrationToExec, nil}, fmt.Errorf(
-			"%s, down() failed with error: %w", errMsg, errDown,
+	ctx, runId := execution.EnsureRunId(ctx)
+	start := time.Now()
+	migCtx, cancel := migrationContext(ctx, version, 1)
+	defer cancel()
+	migCtx, counter := migration.WithRowsCounter(migCtx)
+	executed := newExecutedMigration(migrationToExec, exec, counter)
+
+	logStart(migCtx, DirectionDown, true)
+	if errDown := migrationToExec.Down(migCtx, handler.db); errDown != nil {
+		err = fmt.Errorf(
+			"%s, down() failed with error: %w",
+			errMsg, newMigrationError(version, DirectionDown, errDown),
+		)
+		executed = newExecutedMigration(migrationToExec, nil, counter)
+	} else {
+		err = newRepositoryError(
+			execution.RemoveContext(recordContext(migCtx), handler.repository, *exec),
 		)
 	}
 
-	err = handler.repository.Remove(*exec)
+	notifyErr := handler.notify(
+		ctx, ExecutionEvent{DirectionDown, true, executed, err, time.Since(start), runId},
+	)
+
+	if notifyErr != nil {
+		err = errors.Join(err, notifyErr)
+	}
 
-	return ExecutedMigration{migrationToExec, exec}, err
+	return executed, err
 }
@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -119,6 +125,74 @@ func (suite *HandlerTestSuite) TestItFailsToCreateExecutionPlanFromInvalidState(
 	}
 }
 
+func (suite *HandlerTestSuite) TestItPlansTheVersionsSkippedByLaterExecutions() {
+	repo := &execution.InMemoryRepository{}
+	_ = repo.SaveAll(
+		[]execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: 2, FinishedAtMs: 3},
+			{Version: 2, ExecutedAtMs: 6, FinishedAtMs: 0},
+			{Version: 4, ExecutedAtMs: 4, FinishedAtMs: 5},
+		},
+	)
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3, 4, 5} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+
+	_, err := NewPlan(registry, repo)
+	suite.Require().ErrorContains(err, "multiple executions which are not finished")
+
+	plan, err := NewOutOfOrderPlan(registry, repo)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(
+		[]migration.Migration{registry.Get(2), registry.Get(3), registry.Get(5)},
+		plan.AllToBeExecuted(),
+	)
+	suite.Assert().Equal(2, plan.FinishedExecutionsCount())
+	suite.Assert().Equal(uint64(2), plan.Unfinished().Version)
+	suite.Assert().Same(registry.Get(4), plan.LastExecuted().Migration)
+	suite.Assert().Same(registry.Get(2), plan.AllExecuted()[1].Migration)
+}
+
+func (suite *HandlerTestSuite) TestItFailsToCreateOutOfOrderPlanFromInvalidState() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3, 4} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+
+	scenarios := map[string]struct {
+		persistedExecutions []execution.MigrationExecution
+		errString           string
+	}{
+		"unfinished execution started before a later one": {
+			[]execution.MigrationExecution{
+				{Version: 1, ExecutedAtMs: 2, FinishedAtMs: 3},
+				{Version: 2, ExecutedAtMs: 4, FinishedAtMs: 0},
+				{Version: 4, ExecutedAtMs: 6, FinishedAtMs: 7},
+			},
+			"execution 2 is not finished, but it is not the last started execution",
+		},
+		"execution of an unregistered version": {
+			[]execution.MigrationExecution{
+				{Version: 1, ExecutedAtMs: 2, FinishedAtMs: 3},
+				{Version: 5, ExecutedAtMs: 4, FinishedAtMs: 5},
+			},
+			"execution 5 at index 1 does not match with any registered migration",
+		},
+	}
+
+	for scenarioName, scenarioData := range scenarios {
+		repo := &execution.InMemoryRepository{}
+		_ = repo.SaveAll(scenarioData.persistedExecutions)
+
+		plan, err := NewOutOfOrderPlan(registry, repo)
+		suite.Assert().Nil(plan, "Failed scenario: %s", scenarioName)
+		suite.Assert().ErrorContains(
+			err, scenarioData.errString, "Failed scenario: %s", scenarioName,
+		)
+	}
+}
+
 func (suite *HandlerTestSuite) TestItFailsToCreateExecutionsPlanWhenLoadingFromRepoFails() {
 	loadErr := errors.New("load err")
 	repo := &execution.InMemoryRepository{LoadErr: loadErr}
@@ -484,6 +558,38 @@ func (suite *HandlerTestSuite) TestItCanHandleFailureWhenMigratingUp() {
 	}
 }
 
+func (suite *HandlerTestSuite) TestItRefusesToRunMigrationsWithAReadOnlyRepository() {
+	registry := migration.NewGenericRegistry()
+	executed := &FakeUpMigration{DummyMigration: *migration.NewDummyMigration(1)}
+	pending := &FakeUpMigration{DummyMigration: *migration.NewDummyMigration(2)}
+	_ = registry.Register(executed)
+	_ = registry.Register(pending)
+	repo := &execution.InMemoryRepository{
+		PersistedExecutions: []execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+		},
+	}
+	handler, _ := NewHandler(registry, execution.NewReadOnlyRepository(repo), nil)
+	ctx := context.Background()
+
+	var repositoryErr *RepositoryError
+	_, err := handler.MigrateUp(ctx, NumOfRuns(1))
+	suite.Assert().ErrorIs(err, execution.ErrReadOnlyRepository)
+	suite.Assert().ErrorAs(err, &repositoryErr)
+	_, err = handler.MigrateDown(ctx, NumOfRuns(1))
+	suite.Assert().ErrorIs(err, execution.ErrReadOnlyRepository)
+	_, err = handler.ForceUp(ctx, 2)
+	suite.Assert().ErrorIs(err, execution.ErrReadOnlyRepository)
+	_, err = handler.ForceDown(ctx, 1)
+	suite.Assert().ErrorIs(err, execution.ErrReadOnlyRepository)
+	suite.Assert().False(executed.downRan)
+	suite.Assert().False(pending.upRan)
+
+	// the runs without migrations to execute succeed
+	_, err = handler.MigrateUpTo(ctx, 1, nil)
+	suite.Assert().NoError(err)
+}
+
 func (suite *HandlerTestSuite) TestItCanMigrateUp() {
 	allRuns, _ := NewNumOfRuns("all")
 	someRuns, _ := NewNumOfRuns("2")
@@ -749,3 +855,1130 @@ func (suite *HandlerTestSuite) TestItCanMigrateDown() {
 		)
 	}
 }
+
+type RowsRecordingMigration struct {
+	migration.DummyMigration
+	rows int64
+}
+
+func (r *RowsRecordingMigration) Up(ctx context.Context, db any) error {
+	migration.RecordRowsAffected(ctx, r.rows)
+	return nil
+}
+
+func (r *RowsRecordingMigration) Down(ctx context.Context, db any) error {
+	migration.RecordRowsAffected(ctx, r.rows)
+	return nil
+}
+
+func (suite *HandlerTestSuite) TestItReportsRowsAffectedByMigrations() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(
+		&RowsRecordingMigration{DummyMigration: *migration.NewDummyMigration(1), rows: 3},
+	)
+	_ = registry.Register(
+		&RowsRecordingMigration{DummyMigration: *migration.NewDummyMigration(2), rows: 4},
+	)
+	_ = registry.Register(migration.NewDummyMigration(3))
+
+	handler, _ := NewHandler(registry, &execution.InMemoryRepository{}, nil)
+	allRuns, _ := NewNumOfRuns("all")
+	upped, err := handler.MigrateUp(context.Background(), allRuns)
+
+	suite.Require().NoError(err)
+	suite.Require().Len(upped, 3)
+	suite.Assert().Equal(int64(3), *upped[0].RowsAffected)
+	suite.Assert().Equal(int64(4), *upped[1].RowsAffected)
+	suite.Assert().Nil(upped[2].RowsAffected)
+	total, recorded := TotalRowsAffected(upped)
+	suite.Assert().True(recorded)
+	suite.Assert().Equal(int64(7), total)
+
+	downed, err := handler.MigrateDown(context.Background(), allRuns)
+	suite.Require().NoError(err)
+	total, recorded = TotalRowsAffected(downed)
+	suite.Assert().True(recorded)
+	suite.Assert().Equal(int64(7), total)
+
+	forced, err := handler.ForceUp(context.Background(), 2)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(int64(4), *forced.RowsAffected)
+
+	total, recorded = TotalRowsAffected([]ExecutedMigration{{}})
+	suite.Assert().False(recorded)
+	suite.Assert().Equal(int64(0), total)
+}
+
+type CapturingMigration struct {
+	migration.DummyMigration
+	capable bool
+	upRan   bool
+}
+
+func (c *CapturingMigration) Up(ctx context.Context, db any) error {
+	c.upRan = true
+	if capture, ok := migration.CaptureFromContext(ctx); ok {
+		capture.Add("UPDATE a SET v = $1", c.Version())
+	}
+	return nil
+}
+
+func (c *CapturingMigration) CaptureCapable() bool {
+	return c.capable
+}
+
+func (suite *HandlerTestSuite) TestItCanCaptureStatementsOfMigrationsToBeExecuted() {
+	registry := migration.NewGenericRegistry()
+	executed := &CapturingMigration{DummyMigration: *migration.NewDummyMigration(1), capable: true}
+	capable := &CapturingMigration{DummyMigration: *migration.NewDummyMigration(2), capable: true}
+	notCapable := &CapturingMigration{DummyMigration: *migration.NewDummyMigration(3)}
+	_ = registry.Register(executed)
+	_ = registry.Register(capable)
+	_ = registry.Register(notCapable)
+
+	repo := &execution.InMemoryRepository{}
+	repo.SaveAll([]execution.MigrationExecution{{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2}})
+	handler, _ := NewHandler(registry, repo, nil)
+	allRuns, _ := NewNumOfRuns("all")
+
+	captured, err := handler.CaptureUp(context.Background(), allRuns)
+
+	suite.Require().NoError(err)
+	suite.Require().Len(captured, 2)
+	suite.Assert().Equal(uint64(2), captured[0].Migration.Version())
+	suite.Assert().True(captured[0].Capturable)
+	suite.Assert().Equal(
+		[]migration.CapturedStatement{{Query: "UPDATE a SET v = $1", Args: []any{uint64(2)}}},
+		captured[0].Statements,
+	)
+	suite.Assert().False(captured[1].Capturable)
+	suite.Assert().Empty(captured[1].Statements)
+	suite.Assert().False(executed.upRan)
+	suite.Assert().False(notCapable.upRan)
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+}
+
+func (suite *HandlerTestSuite) TestItNotifiesListenersOfHandledMigrations() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	var events []ExecutionEvent
+	handler.AddListener(
+		func(_ context.Context, event ExecutionEvent) error {
+			events = append(events, event)
+			return nil
+		},
+	)
+
+	_, err := handler.MigrateUp(context.Background(), NumOfRuns(2))
+	suite.Require().NoError(err)
+	_, err = handler.MigrateDown(context.Background(), NumOfRuns(1))
+	suite.Require().NoError(err)
+	repo.RemoveErr = errors.New("remove failed")
+	_, err = handler.ForceDown(context.Background(), 1)
+	suite.Require().Error(err)
+
+	suite.Require().Len(events, 4)
+	suite.Assert().Equal(DirectionUp, events[0].Direction)
+	suite.Assert().Equal(uint64(1), events[0].Migration.Migration.Version())
+	suite.Assert().True(events[1].Succeeded())
+	suite.Assert().Equal(DirectionDown, events[2].Direction)
+	suite.Assert().Equal(uint64(2), events[2].Migration.Migration.Version())
+	suite.Assert().False(events[2].Forced)
+	suite.Assert().True(events[3].Forced)
+	suite.Assert().ErrorIs(events[3].Err, repo.RemoveErr)
+}
+
+func (suite *HandlerTestSuite) TestItRecordsTheRunIdOfExecutions() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	var events []ExecutionEvent
+	handler.AddListener(
+		func(ctx context.Context, event ExecutionEvent) error {
+			suite.Assert().Equal(event.RunId, execution.RunIdFrom(ctx))
+			events = append(events, event)
+			return nil
+		},
+	)
+
+	ctx := execution.WithRunId(context.Background(), "run-1")
+	_, err := handler.MigrateUp(ctx, NumOfRuns(1))
+	suite.Require().NoError(err)
+	_, err = handler.ForceUp(context.Background(), 2)
+	suite.Require().NoError(err)
+
+	suite.Require().Len(repo.PersistedExecutions, 2)
+	suite.Assert().Equal("run-1", repo.PersistedExecutions[0].RunId)
+	suite.Assert().Equal("run-1", events[0].RunId)
+
+	// without a run ID in the context, a new one is generated
+	suite.Assert().Len(repo.PersistedExecutions[1].RunId, 26)
+	suite.Assert().Equal(repo.PersistedExecutions[1].RunId, events[1].RunId)
+}
+
+type DescribedMigration struct {
+	migration.DummyMigration
+	description string
+}
+
+func (d *DescribedMigration) Metadata() migration.Metadata {
+	return migration.Metadata{Description: d.description}
+}
+
+func (suite *HandlerTestSuite) TestItCanMatchMigrationsByVersionOrDescription() {
+	described := &DescribedMigration{*migration.NewDummyMigration(2023120101), "backfill users"}
+
+	scenarios := []struct {
+		pattern string
+		matches []bool
+	}{
+		{"2024*", []bool{false, true}},
+		{"^20231", []bool{true, false}},
+		{"users", []bool{true, false}},
+		{"2024.*01$", []bool{false, true}},
+	}
+
+	for _, scenario := range scenarios {
+		matcher, err := NewMatcher(scenario.pattern)
+		suite.Require().NoError(err, scenario.pattern)
+		suite.Assert().Equal(scenario.matches[0], matcher.Matches(described), scenario.pattern)
+		suite.Assert().Equal(
+			scenario.matches[1],
+			matcher.Matches(migration.NewDummyMigration(2024010101)),
+			scenario.pattern,
+		)
+	}
+
+	_, err := NewMatcher("2024[")
+	suite.Assert().ErrorContains(err, "invalid migrations match pattern")
+	_, err = NewMatcher(" ")
+	suite.Assert().Error(err)
+}
+
+func (suite *HandlerTestSuite) TestItCanMigrateUpOnlyMatchingMigrations() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(2023120101))
+	_ = registry.Register(migration.NewDummyMigration(2024010101))
+	_ = registry.Register(migration.NewDummyMigration(2024020101))
+	_ = registry.Register(migration.NewDummyMigration(2025010101))
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+	matcher, _ := NewMatcher("2024*")
+
+	// 2023120101 must be executed before the matching migrations
+	handled, err := handler.MigrateUpMatching(context.Background(), NumOfRuns(99999), matcher)
+	suite.Assert().ErrorContains(
+		err, "migration 2023120101 does not match \"2024*\" and must be executed before"+
+			" the matching migration 2024010101",
+	)
+	suite.Assert().Empty(handled)
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	_, err = handler.MigrateUp(context.Background(), NumOfRuns(1))
+	suite.Require().NoError(err)
+
+	handled, err = handler.MigrateUpMatching(context.Background(), NumOfRuns(99999), matcher)
+	suite.Require().NoError(err)
+	suite.Require().Len(handled, 2)
+	suite.Assert().Equal(uint64(2024010101), handled[0].Migration.Version())
+	suite.Assert().Equal(uint64(2024020101), handled[1].Migration.Version())
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+
+	// no more matching migrations
+	handled, err = handler.MigrateUpMatching(context.Background(), NumOfRuns(99999), matcher)
+	suite.Require().NoError(err)
+	suite.Assert().Empty(handled)
+}
+
+func (suite *HandlerTestSuite) TestItCanMigrateUpToATargetVersion() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3, 4} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	handled, err := handler.MigrateUpTo(context.Background(), 5, nil)
+	suite.Assert().EqualError(
+		err, "failed to migrate up to version 5, the version is not registered",
+	)
+	suite.Assert().Empty(handled)
+
+	handled, err = handler.MigrateUpTo(context.Background(), 2, nil)
+	suite.Require().NoError(err)
+	suite.Require().Len(handled, 2)
+	suite.Assert().Equal(uint64(1), handled[0].Migration.Version())
+	suite.Assert().Equal(uint64(2), handled[1].Migration.Version())
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+
+	// the target is already executed
+	handled, err = handler.MigrateUpTo(context.Background(), 1, nil)
+	suite.Require().NoError(err)
+	suite.Assert().Empty(handled)
+
+	matcher, _ := NewMatcher("^3$")
+	handled, err = handler.MigrateUpTo(context.Background(), 4, matcher)
+	suite.Require().NoError(err)
+	suite.Require().Len(handled, 1)
+	suite.Assert().Equal(uint64(3), handled[0].Migration.Version())
+
+	handled, err = handler.MigrateUpTo(context.Background(), 4, nil)
+	suite.Require().NoError(err)
+	suite.Require().Len(handled, 1)
+	suite.Assert().Len(repo.PersistedExecutions, 4)
+}
+
+func (suite *HandlerTestSuite) TestItCanMigrateUpOnlyOneVersionOutOfOrder() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3, 4} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	_ = registry.Register(
+		migration.NewHotfix(5, 2, func(context.Context, any) error { return nil }),
+	)
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+	_, err := handler.MigrateUp(context.Background(), 1)
+	suite.Require().NoError(err)
+
+	_, err = handler.MigrateUpOnly(context.Background(), 3)
+	suite.Assert().ErrorContains(err, "the version 3 can't run out of order, before 1 earlier")
+	handler, _ = NewHandler(registry, repo, NewOutOfOrderPlan)
+
+	var logs bytes.Buffer
+	ctx := migration.WithLogger(context.Background(), slog.New(slog.NewTextHandler(&logs, nil)))
+	planned, err := handler.PlanUpOnly(3)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]migration.Migration{registry.Get(3)}, planned)
+	skipped, err := handler.SkippedBy(3)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]migration.Migration{registry.Get(2)}, skipped)
+
+	handled, err := handler.MigrateUpOnly(ctx, 3)
+	suite.Require().NoError(err)
+	suite.Require().Len(handled, 1)
+	suite.Assert().Equal(uint64(3), handled[0].Migration.Version())
+	suite.Assert().Contains(logs.String(), `msg="executing a migration out of order" version=3`)
+	suite.Assert().Contains(logs.String(), "skipped=[2]")
+
+	_, err = handler.MigrateUpOnly(ctx, 3)
+	suite.Assert().ErrorContains(err, "the version 3 is already executed")
+	_, err = handler.MigrateUpOnly(ctx, 6)
+	suite.Assert().EqualError(
+		err, "failed to migrate up only version 6, the version is not registered",
+	)
+	_, err = handler.MigrateUpOnly(ctx, 5)
+	suite.Assert().ErrorContains(err, "the hotfix migration 5 can't run before the version 2")
+
+	// the skipped versions are executed by the next runs
+	handled, err = handler.MigrateUp(ctx, 99999)
+	suite.Require().NoError(err)
+	suite.Require().Len(handled, 3)
+	suite.Assert().Equal(uint64(2), handled[0].Migration.Version())
+	suite.Assert().Equal(uint64(4), handled[1].Migration.Version())
+	suite.Assert().Len(repo.PersistedExecutions, 5)
+
+	repo = &execution.InMemoryRepository{}
+	_ = repo.Save(execution.MigrationExecution{Version: 1, ExecutedAtMs: 2})
+	handler, _ = NewHandler(registry, repo, nil)
+	_, err = handler.MigrateUpOnly(ctx, 3)
+	suite.Assert().ErrorContains(err, "the execution of version 1 is unfinished")
+}
+
+func (suite *HandlerTestSuite) TestItPlansMigrationsWithoutExecutingThem() {
+	registry := migration.NewGenericRegistry()
+	migrations := []*CountingMigration{}
+	for _, version := range []uint64{1, 2, 3, 4} {
+		mig := &CountingMigration{DummyMigration: *migration.NewDummyMigration(version)}
+		migrations = append(migrations, mig)
+		_ = registry.Register(mig)
+	}
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+	_, err := handler.MigrateUp(context.Background(), 1)
+	suite.Require().NoError(err)
+
+	planned, err := handler.PlanUp(2, nil)
+	suite.Require().NoError(err)
+	suite.Require().Len(planned, 2)
+	suite.Assert().Equal(uint64(2), planned[0].Version())
+	suite.Assert().Equal(uint64(3), planned[1].Version())
+
+	matcher, _ := NewMatcher("^2$")
+	planned, err = handler.PlanUpTo(3, matcher)
+	suite.Require().NoError(err)
+	suite.Require().Len(planned, 1)
+	suite.Assert().Equal(uint64(2), planned[0].Version())
+
+	_, err = handler.PlanUpTo(5, nil)
+	suite.Assert().EqualError(err, "failed to plan up to version 5, the version is not registered")
+
+	planned, err = handler.PlanDown(3)
+	suite.Require().NoError(err)
+	suite.Require().Len(planned, 1)
+	suite.Assert().Equal(uint64(1), planned[0].Version())
+
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+	for _, mig := range migrations[1:] {
+		suite.Assert().Zero(mig.calls)
+	}
+}
+
+func (suite *HandlerTestSuite) TestItMarksVersionsAsExecutedWithoutCallingUp() {
+	registry := migration.NewGenericRegistry()
+	mig := &CountingMigration{DummyMigration: *migration.NewDummyMigration(1)}
+	_ = registry.Register(mig)
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	executed, err := handler.MarkExecuted(context.Background(), 1, false)
+	suite.Require().NoError(err)
+	suite.Assert().True(executed.Execution.Finished())
+	suite.Assert().NotEmpty(executed.Execution.RunId)
+	suite.Assert().Zero(mig.calls)
+	suite.Require().Len(repo.PersistedExecutions, 1)
+
+	_, err = handler.MarkExecuted(context.Background(), 1, false)
+	suite.Assert().EqualError(
+		err, "failed to mark version 1 as executed, the version is already executed",
+	)
+
+	_, err = handler.MarkExecuted(context.Background(), 2, false)
+	suite.Assert().EqualError(
+		err,
+		"failed to mark version 2 as executed, the version is not registered "+
+			"(force it to record it anyway)",
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+
+	_, err = handler.MarkExecuted(context.Background(), 2, true)
+	suite.Require().NoError(err)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+}
+
+func (suite *HandlerTestSuite) TestItMarksTheVersionsUpToTheGivenOneAsExecuted() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3, 4} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	repo.SaveAll(
+		[]execution.MigrationExecution{
+			{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+			{Version: 2, ExecutedAtMs: 1},
+		},
+	)
+	handler, _ := NewHandler(registry, repo, nil)
+	var events []ExecutionEvent
+	handler.AddListener(
+		func(_ context.Context, event ExecutionEvent) error {
+			events = append(events, event)
+			return nil
+		},
+	)
+
+	marked, err := handler.MarkExecutedUpTo(context.Background(), 3)
+	suite.Require().NoError(err)
+	suite.Require().Len(marked, 2)
+	suite.Assert().Equal(uint64(2), marked[0].Migration.Version())
+	suite.Assert().Equal(uint64(3), marked[1].Migration.Version())
+	suite.Require().Len(events, 2)
+	suite.Assert().True(events[0].Forced)
+
+	executions, _ := repo.LoadExecutions()
+	suite.Require().Len(executions, 3)
+	for _, exec := range executions {
+		suite.Assert().True(exec.Finished())
+	}
+	suite.Assert().Equal(marked[0].Execution.RunId, executions[2].RunId)
+
+	repo.SaveErr = errors.New("save failed")
+	_, err = handler.MarkExecutedUpTo(context.Background(), 4)
+	suite.Assert().ErrorContains(
+		err, "failed to mark the versions up to 4 as executed, failed to save executions",
+	)
+}
+
+func (suite *HandlerTestSuite) TestItRedoesExecutedMigrations() {
+	registry := migration.NewGenericRegistry()
+	migrations := []*CountingMigration{}
+	for _, version := range []uint64{1, 2} {
+		mig := &CountingMigration{DummyMigration: *migration.NewDummyMigration(version)}
+		migrations = append(migrations, mig)
+		_ = registry.Register(mig)
+	}
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+	ctx := context.Background()
+
+	_, _, err := handler.RedoLast(ctx)
+	suite.Assert().EqualError(err, "failed to redo the last migration, no migration is executed")
+
+	_, err = handler.MigrateUp(ctx, 2)
+	suite.Require().NoError(err)
+
+	down, up, err := handler.RedoLast(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(uint64(2), down.Migration.Version())
+	suite.Assert().Equal(uint64(2), up.Migration.Version())
+	suite.Assert().True(up.Execution.Finished())
+	suite.Assert().Equal(1, migrations[0].calls)
+	suite.Assert().Equal(2, migrations[1].calls)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+
+	_, _, err = handler.Redo(ctx, 1)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(2, migrations[0].calls)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+
+	_, _, err = handler.Redo(ctx, 3)
+	suite.Assert().EqualError(err, "failed to redo version 3, the version is not registered")
+
+	_, err = handler.ForceDown(ctx, 2)
+	suite.Require().NoError(err)
+	_, _, err = handler.Redo(ctx, 2)
+	suite.Assert().EqualError(err, "failed to redo version 2, the version is not executed")
+}
+
+// ContractMigration is tagged as a contract migration
+type ContractMigration struct {
+	migration.DummyMigration
+}
+
+func (c *ContractMigration) Metadata() migration.Metadata {
+	return migration.Metadata{Tags: []string{migration.TagContract}}
+}
+
+// fleetVersion is a FleetVersionSource which reports a fixed version
+type fleetVersion struct {
+	version uint64
+	err     error
+	calls   int
+}
+
+func (f *fleetVersion) MinCompatibleVersion(context.Context) (uint64, error) {
+	f.calls++
+	return f.version, f.err
+}
+
+func (suite *HandlerTestSuite) TestItRefusesContractMigrationsNotSupportedByTheFleet() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(&ContractMigration{*migration.NewDummyMigration(2)})
+	_ = registry.Register(migration.NewDummyMigration(3))
+	_ = registry.Register(&ContractMigration{*migration.NewDummyMigration(4)})
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	fleet := &fleetVersion{err: errors.New("endpoint down")}
+	ctx := WithDeploymentGuard(context.Background(), fleet)
+	handled, err := handler.MigrateUp(ctx, 1)
+	suite.Require().NoError(err)
+	suite.Assert().Len(handled, 1)
+	suite.Assert().Zero(fleet.calls)
+
+	handled, err = handler.MigrateUp(ctx, 3)
+	suite.Assert().ErrorContains(err, "failed to load the running app fleet version")
+	suite.Assert().Empty(handled)
+
+	fleet.err, fleet.version = nil, 2
+	handled, err = handler.MigrateUp(ctx, 3)
+	var blockedErr *ContractBlockedError
+	suite.Require().ErrorAs(err, &blockedErr)
+	suite.Assert().EqualError(
+		err,
+		"contract migration 4 can't be executed while the running app fleet is compatible "+
+			"only up to version 2, 1 migrations remaining to be executed",
+	)
+	suite.Assert().Equal(uint64(2), blockedErr.FleetVersion)
+	suite.Require().Len(handled, 2)
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+
+	fleet.version = 4
+	handled, err = handler.MigrateUp(ctx, 1)
+	suite.Require().NoError(err)
+	suite.Assert().Len(handled, 1)
+}
+
+func (suite *HandlerTestSuite) TestItAsksTheGateBeforeEachMigration() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(&ContractMigration{*migration.NewDummyMigration(2)})
+	_ = registry.Register(migration.NewDummyMigration(3))
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	decisions := map[uint64]GateDecision{2: GateDefer}
+	var asked []uint64
+	var gateErr error
+	ctx := WithGate(
+		context.Background(),
+		func(_ context.Context, version uint64, metadata migration.Metadata) (
+			GateDecision,
+			error,
+		) {
+			asked = append(asked, version)
+			if version == 2 {
+				suite.Assert().Equal([]string{migration.TagContract}, metadata.Tags)
+			}
+			return decisions[version], gateErr
+		},
+	)
+
+	handled, err := handler.MigrateUp(ctx, 3)
+	var deferredErr *GateDeferredError
+	suite.Require().ErrorAs(err, &deferredErr)
+	suite.Assert().EqualError(
+		err, "migration 2 was deferred by the gate, 2 migrations remaining to be executed",
+	)
+	suite.Assert().Len(deferredErr.Remaining, 2)
+	suite.Assert().Len(handled, 1)
+	suite.Assert().Equal([]uint64{1, 2}, asked)
+
+	decisions[2] = GateDeny
+	_, err = handler.MigrateUp(ctx, 3)
+	var deniedErr *GateDeniedError
+	suite.Require().ErrorAs(err, &deniedErr)
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+
+	gateErr = errors.New("flags service down")
+	_, err = handler.MigrateUp(ctx, 3)
+	suite.Assert().ErrorContains(err, "failed to evaluate the gate of migration 2")
+
+	// the deferred migration is executed by the next run, once allowed
+	decisions[2], gateErr = GateAllow, nil
+	handled, err = handler.MigrateUp(ctx, 3)
+	suite.Require().NoError(err)
+	suite.Assert().Len(handled, 2)
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+}
+
+type SleepingMigration struct {
+	migration.DummyMigration
+	duration time.Duration
+}
+
+func (s *SleepingMigration) Up(ctx context.Context, db any) error {
+	time.Sleep(s.duration)
+	return nil
+}
+
+func (suite *HandlerTestSuite) TestItStopsMigratingUpBetweenMigrationsAtTheTimeBudget() {
+	registry := migration.NewGenericRegistry()
+	for version := uint64(1); version <= 3; version++ {
+		_ = registry.Register(
+			&SleepingMigration{*migration.NewDummyMigration(version), 40 * time.Millisecond},
+		)
+	}
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	// after the first migration, the time left is shorter than its duration
+	handled, err := handler.MigrateUp(
+		WithTimeBudget(context.Background(), 60*time.Millisecond), NumOfRuns(99999),
+	)
+	var budgetErr *BudgetExhaustedError
+	suite.Require().ErrorAs(err, &budgetErr)
+	suite.Assert().EqualError(err, "time budget exhausted, 2 migrations remaining to be executed")
+	suite.Require().Len(handled, 1)
+	suite.Assert().True(handled[0].Execution.Finished())
+	suite.Require().Len(budgetErr.Remaining, 2)
+	suite.Assert().Equal(uint64(2), budgetErr.Remaining[0].Version())
+	suite.Assert().Len(repo.PersistedExecutions, 1)
+
+	// no migration is started once the budget is exhausted
+	handled, err = handler.MigrateUp(WithTimeBudget(context.Background(), 0), NumOfRuns(1))
+	suite.Require().ErrorAs(err, &budgetErr)
+	suite.Assert().Empty(handled)
+	suite.Assert().Len(budgetErr.Remaining, 1)
+
+	handled, err = handler.MigrateUp(
+		WithTimeBudget(context.Background(), time.Minute), NumOfRuns(99999),
+	)
+	suite.Require().NoError(err)
+	suite.Assert().Len(handled, 2)
+}
+
+// BackfillMigration processes items 1 to 5, saving the last processed one as checkpoint
+type BackfillMigration struct {
+	migration.DummyMigration
+	failAt    int
+	processed []int
+}
+
+func (b *BackfillMigration) Up(ctx context.Context, db any) error {
+	checkpointer, ok := migration.CheckpointerFromContext(ctx)
+	if !ok {
+		return errors.New("missing checkpointer")
+	}
+
+	start := 1
+	if checkpoint := checkpointer.Checkpoint(); checkpoint != "" {
+		last, _ := strconv.Atoi(checkpoint)
+		start = last + 1
+	}
+
+	for item := start; item <= 5; item++ {
+		if item == b.failAt {
+			return errors.New("interrupted")
+		}
+		b.processed = append(b.processed, item)
+		if err := checkpointer.SaveCheckpoint(strconv.Itoa(item)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (suite *HandlerTestSuite) TestItResumesCheckpointedMigrations() {
+	backfill := &BackfillMigration{DummyMigration: *migration.NewDummyMigration(1), failAt: 3}
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(backfill)
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	_, err := handler.MigrateUp(context.Background(), NumOfRuns(1))
+	suite.Require().ErrorContains(err, "interrupted")
+	suite.Require().Len(repo.PersistedExecutions, 1)
+	suite.Assert().False(repo.PersistedExecutions[0].Finished())
+	suite.Assert().Equal("2", repo.PersistedExecutions[0].Checkpoint)
+
+	backfill.failAt = 0
+	handled, err := handler.MigrateUp(context.Background(), NumOfRuns(1))
+	suite.Require().NoError(err)
+	suite.Require().Len(handled, 1)
+	suite.Assert().Equal([]int{1, 2, 3, 4, 5}, backfill.processed)
+	suite.Assert().True(repo.PersistedExecutions[0].Finished())
+	suite.Assert().Equal("", repo.PersistedExecutions[0].Checkpoint)
+}
+
+func (suite *HandlerTestSuite) TestItFailsToSaveInvalidCheckpoints() {
+	repo := &execution.InMemoryRepository{}
+	exec := &execution.MigrationExecution{Version: 1}
+	checkpointer := newExecutionCheckpointer(context.Background(), repo, exec)
+
+	err := checkpointer.SaveCheckpoint(strings.Repeat("x", execution.MaxCheckpointLength+1))
+	suite.Assert().ErrorContains(err, "checkpoint of migration 1 exceeds 1024 bytes")
+	suite.Assert().Empty(repo.PersistedExecutions)
+
+	repo.SaveErr = errors.New("save failed")
+	suite.Assert().ErrorIs(checkpointer.SaveCheckpoint("1"), repo.SaveErr)
+}
+
+// CountingMigration inserts a row on each Up() call, unless it is idempotent. It fails on
+// reruns if rerunErr is set.
+type CountingMigration struct {
+	migration.DummyMigration
+	idempotent bool
+	rerunErr   error
+	calls      int
+}
+
+func (c *CountingMigration) Up(ctx context.Context, db any) error {
+	c.calls++
+	if c.calls > 1 && c.rerunErr != nil {
+		return c.rerunErr
+	}
+	if c.calls > 1 && c.idempotent {
+		migration.RecordRowsAffected(ctx, 0)
+		return nil
+	}
+	migration.RecordRowsAffected(ctx, 1)
+	return nil
+}
+
+func (suite *HandlerTestSuite) TestItReportsMigrationsWhichAreNotSafeToRerun() {
+	idempotent := &CountingMigration{DummyMigration: *migration.NewDummyMigration(1)}
+	idempotent.idempotent = true
+	notIdempotent := &CountingMigration{DummyMigration: *migration.NewDummyMigration(2)}
+	failing := &CountingMigration{DummyMigration: *migration.NewDummyMigration(3)}
+	failing.rerunErr = errors.New("table already exists")
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(idempotent)
+	_ = registry.Register(notIdempotent)
+	_ = registry.Register(failing)
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	handled, err := handler.MigrateUp(WithRerunCheck(context.Background()), NumOfRuns(3))
+	suite.Assert().Len(handled, 3)
+	suite.Assert().Equal(2, idempotent.calls)
+	suite.Assert().Equal(2, notIdempotent.calls)
+	suite.Assert().Equal(int64(1), *handled[1].RowsAffected)
+
+	var rerunErr *RerunUnsafeError
+	suite.Require().ErrorAs(err, &rerunErr)
+	suite.Assert().EqualError(
+		err, "migrations not safe to rerun: 2 (second Up() affected 1 rows), "+
+			"3 (second Up() failed with error: table already exists)",
+	)
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+	suite.Assert().True(repo.PersistedExecutions[2].Finished())
+
+	// without the safe rerun mode, migrations are executed once
+	_, err = handler.MigrateDown(context.Background(), NumOfRuns(2))
+	suite.Require().NoError(err)
+	_, err = handler.MigrateUp(context.Background(), NumOfRuns(1))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(3, notIdempotent.calls)
+}
+
+// ContextMigration records the context values it gets, waiting for its context to be
+// cancelled if blocking
+type ContextMigration struct {
+	migration.DummyMigration
+	blocking bool
+	runIds   []string
+	attempts []int
+}
+
+func (c *ContextMigration) Up(ctx context.Context, db any) error {
+	c.runIds = append(c.runIds, execution.RunIdFrom(ctx))
+	c.attempts = append(c.attempts, execution.AttemptFrom(ctx))
+	if version, _ := execution.VersionFrom(ctx); version != c.Version() {
+		return fmt.Errorf("unexpected version %d", version)
+	}
+
+	if c.blocking {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	migration.RecordRowsAffected(ctx, 0)
+	return nil
+}
+
+// contextRepository records the migration versions carried by the contexts of its writes
+type contextRepository struct {
+	execution.InMemoryRepository
+	savedVersions   []uint64
+	removedVersions []uint64
+}
+
+func (r *contextRepository) SaveContext(
+	ctx context.Context,
+	exec execution.MigrationExecution,
+) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	version, _ := execution.VersionFrom(ctx)
+	r.savedVersions = append(r.savedVersions, version)
+	return r.Save(exec)
+}
+
+func (r *contextRepository) RemoveContext(
+	ctx context.Context,
+	exec execution.MigrationExecution,
+) error {
+	version, _ := execution.VersionFrom(ctx)
+	r.removedVersions = append(r.removedVersions, version)
+	return r.Remove(exec)
+}
+
+func (suite *HandlerTestSuite) TestItRunsEachMigrationWithItsOwnContext() {
+	first := &ContextMigration{DummyMigration: *migration.NewDummyMigration(1)}
+	second := &ContextMigration{DummyMigration: *migration.NewDummyMigration(2)}
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(first)
+	_ = registry.Register(second)
+	repo := &contextRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	ctx := execution.WithRunId(context.Background(), "run-1")
+	_, err := handler.MigrateUp(WithRerunCheck(ctx), NumOfRuns(2))
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{"run-1", "run-1"}, first.runIds)
+	suite.Assert().Equal([]int{1, 2}, first.attempts)
+	suite.Assert().Equal([]uint64{1, 2}, repo.savedVersions)
+
+	_, err = handler.MigrateDown(ctx, NumOfRuns(2))
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]uint64{2, 1}, repo.removedVersions)
+}
+
+func (suite *HandlerTestSuite) TestItCancelsTheMigrationContextAtTheMigrationTimeout() {
+	blocking := &ContextMigration{DummyMigration: *migration.NewDummyMigration(1)}
+	blocking.blocking = true
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(blocking)
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &contextRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	ctx := WithMigrationTimeout(context.Background(), 10*time.Millisecond)
+	handled, err := handler.MigrateUp(ctx, NumOfRuns(2))
+	suite.Assert().ErrorIs(err, context.DeadlineExceeded)
+	suite.Require().Len(handled, 1)
+
+	// the interrupted execution is still recorded, as unfinished
+	suite.Require().Len(repo.PersistedExecutions, 1)
+	suite.Assert().False(repo.PersistedExecutions[0].Finished())
+
+	// a cancelled run doesn't start the next migrations
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	handled, err = handler.MigrateDown(cancelledCtx, NumOfRuns(1))
+	suite.Assert().ErrorContains(err, "the run was cancelled")
+	suite.Assert().Empty(handled)
+}
+
+func (suite *HandlerTestSuite) TestItRefusesToRunQuarantinedMigrations() {
+	registry := migration.NewGenericRegistryWithCollisionPolicy(migration.CollisionQuarantine)
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	_ = registry.Register(migration.NewDummyMigration(3))
+	repo := &contextRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	// the versions before the quarantined one can run
+	var collisionErr *migration.CollisionError
+	handled, err := handler.MigrateUp(context.Background(), NumOfRuns(3))
+	suite.Assert().ErrorAs(err, &collisionErr)
+	suite.Require().Len(handled, 1)
+	suite.Assert().Equal([]uint64{1}, repo.savedVersions)
+
+	_, err = handler.ForceUp(context.Background(), 2)
+	suite.Assert().ErrorAs(err, &collisionErr)
+	_, err = handler.ForceDown(context.Background(), 2)
+	suite.Assert().ErrorAs(err, &collisionErr)
+	suite.Assert().Equal([]uint64{1}, repo.savedVersions)
+
+	suite.Require().NoError(registry.ResolveCollision(2, 0))
+	_, err = handler.MigrateUp(context.Background(), NumOfRuns(3))
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]uint64{1, 2, 3}, repo.savedVersions)
+}
+
+// unhandledCollisionsRegistry quarantines the colliding versions without a collision policy,
+// like migration.DefaultRegistry used as it is
+type unhandledCollisionsRegistry struct {
+	*migration.GenericRegistry
+}
+
+func (r unhandledCollisionsRegistry) CollisionPolicy() migration.CollisionPolicy {
+	return migration.CollisionFail
+}
+
+func (suite *HandlerTestSuite) TestItRefusesToRunRegistriesWithUnhandledCollisions() {
+	registry := unhandledCollisionsRegistry{
+		migration.NewGenericRegistryWithCollisionPolicy(migration.CollisionQuarantine),
+	}
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &contextRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	// no version runs, not even the ones before the quarantined one
+	var collisionErr *migration.CollisionError
+	handled, err := handler.MigrateUp(context.Background(), NumOfRuns(2))
+	suite.Assert().ErrorAs(err, &collisionErr)
+	suite.Assert().Empty(handled)
+	suite.Assert().Empty(repo.savedVersions)
+
+	suite.Require().NoError(registry.ResolveCollision(2, 0))
+	_, err = handler.MigrateUp(context.Background(), NumOfRuns(2))
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]uint64{1, 2}, repo.savedVersions)
+}
+
+func (suite *HandlerTestSuite) TestItRunsHotfixesAfterTheMigrationsTheyFix() {
+	var reversed []uint64
+	reverse := func(version uint64) func(context.Context, any) error {
+		return func(context.Context, any) error {
+			reversed = append(reversed, version)
+			return nil
+		}
+	}
+
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewHotfix(2, 1, reverse(1)))
+	_ = registry.Register(migration.NewHotfix(3, 4, reverse(4)))
+	_ = registry.Register(migration.NewDummyMigration(4))
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	handled, err := handler.MigrateUp(context.Background(), NumOfRuns(4))
+	suite.Assert().ErrorContains(
+		err, "the hotfix migration 3 must fix an earlier registered version, not 4",
+	)
+	suite.Assert().Len(handled, 2)
+	suite.Assert().Equal([]uint64{1}, reversed)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+
+	_, err = handler.MigrateDown(context.Background(), NumOfRuns(1))
+	suite.Assert().ErrorIs(err, migration.ErrIrreversibleHotfix)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+}
+
+func (suite *HandlerTestSuite) TestItRefusesTheRollbacksOfHotfixesUpFront() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewHotfix(2, 1, func(context.Context, any) error { return nil }))
+	_ = registry.Register(migration.NewDummyMigration(3))
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+	_, err := handler.MigrateUp(context.Background(), NumOfRuns(3))
+	suite.Require().NoError(err)
+
+	planned, err := handler.PlanDown(NumOfRuns(3))
+	suite.Assert().Len(planned, 3)
+	suite.Assert().ErrorContains(err, "the migration 2 can't be rolled back, nothing was rolled")
+
+	handled, err := handler.MigrateDown(context.Background(), NumOfRuns(99999))
+	suite.Assert().ErrorIs(err, migration.ErrIrreversibleHotfix)
+	suite.Assert().Empty(handled)
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+
+	_, _, err = handler.Redo(context.Background(), 2)
+	suite.Assert().ErrorIs(err, migration.ErrIrreversibleHotfix)
+	suite.Assert().Len(repo.PersistedExecutions, 3)
+
+	// the newer versions can still be rolled back
+	handled, err = handler.MigrateDown(context.Background(), NumOfRuns(1))
+	suite.Require().NoError(err)
+	suite.Assert().Len(handled, 1)
+	suite.Assert().Len(repo.PersistedExecutions, 2)
+}
+
+// progressRecorder is a ProgressReporter which records the reported progress
+type progressRecorder struct {
+	started  []Progress
+	finished []Progress
+}
+
+func (r *progressRecorder) Started(_ context.Context, progress Progress) {
+	r.started = append(r.started, progress)
+}
+
+func (r *progressRecorder) Finished(_ context.Context, progress Progress) {
+	r.finished = append(r.finished, progress)
+}
+
+func (suite *HandlerTestSuite) TestItReportsTheProgressOfBatchRuns() {
+	registry := migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = registry.Register(migration.NewDummyMigration(version))
+	}
+	repo := &execution.InMemoryRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+	recorder := &progressRecorder{}
+	ctx := WithProgressReporter(context.Background(), recorder)
+
+	_, err := handler.MigrateUp(ctx, NumOfRuns(3))
+	suite.Require().NoError(err)
+	suite.Require().Len(recorder.started, 3)
+	suite.Require().Len(recorder.finished, 3)
+	suite.Assert().Equal(uint64(2), recorder.started[1].Version)
+	suite.Assert().Equal(2, recorder.started[1].Position)
+	suite.Assert().Equal(3, recorder.started[1].Total)
+	suite.Assert().Equal(1, recorder.started[1].Done)
+	suite.Assert().Equal(2, recorder.finished[1].Done)
+	suite.Assert().Equal(DirectionUp, recorder.finished[2].Direction)
+	suite.Assert().GreaterOrEqual(recorder.finished[2].Elapsed, recorder.finished[0].Elapsed)
+
+	recorder = &progressRecorder{}
+	ctx = WithProgressReporter(context.Background(), recorder)
+	repo.RemoveErr = errors.New("remove failed")
+	_, err = handler.MigrateDown(ctx, NumOfRuns(2))
+	suite.Require().Error(err)
+	suite.Require().Len(recorder.finished, 1)
+	suite.Assert().Equal(uint64(3), recorder.finished[0].Version)
+	suite.Assert().Equal(2, recorder.finished[0].Total)
+	suite.Assert().Zero(recorder.finished[0].Done)
+	suite.Assert().ErrorIs(recorder.finished[0].Err, repo.RemoveErr)
+}
+
+// pingingRepository counts the keepalive pings
+type pingingRepository struct {
+	execution.InMemoryRepository
+	pings atomic.Int32
+}
+
+func (r *pingingRepository) Ping(context.Context) error {
+	r.pings.Add(1)
+	return nil
+}
+
+func (suite *HandlerTestSuite) TestItPingsTheRepositoryDuringTheRuns() {
+	registry := migration.NewGenericRegistry()
+	for version := uint64(1); version <= 2; version++ {
+		_ = registry.Register(
+			&SleepingMigration{*migration.NewDummyMigration(version), 40 * time.Millisecond},
+		)
+	}
+	repo := &pingingRepository{}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	ctx := WithKeepalive(context.Background(), 10*time.Millisecond)
+	_, err := handler.MigrateUp(ctx, NumOfRuns(99999))
+	suite.Require().NoError(err)
+	pings := repo.pings.Load()
+	suite.Assert().GreaterOrEqual(pings, int32(2))
+
+	// the pings stop with the run
+	time.Sleep(30 * time.Millisecond)
+	suite.Assert().Equal(pings, repo.pings.Load())
+
+	_, err = handler.MigrateDown(WithKeepalive(context.Background(), 0), NumOfRuns(2))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(pings, repo.pings.Load())
+}
+
+func (suite *HandlerTestSuite) TestItFindsAndRepairsTheExecutionIssues() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &execution.InMemoryRepository{
+		PersistedExecutions: []execution.MigrationExecution{
+			{Version: 3, ExecutedAtMs: 5},
+			{Version: 1, ExecutedAtMs: 3},
+			{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+			{Version: 2, ExecutedAtMs: 4},
+		},
+	}
+	handler, _ := NewHandler(registry, repo, nil)
+
+	issues, err := handler.ExecutionIssues()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(
+		[]ExecutionIssue{
+			{IssueDuplicate, repo.PersistedExecutions[2], 1},
+			{Kind: IssueUnfinished, Execution: repo.PersistedExecutions[3]},
+			{Kind: IssueOrphan, Execution: repo.PersistedExecutions[0]},
+		},
+		issues,
+	)
+
+	// the repair stops at the first failure
+	repo.RemoveErr = errors.New("connection lost")
+	fixed, err := handler.Repair(issues)
+	suite.Assert().ErrorContains(err, "failed to keep one execution of version 1")
+	var repoErr *RepositoryError
+	suite.Assert().ErrorAs(err, &repoErr)
+	suite.Assert().Empty(fixed)
+
+	repo.RemoveErr = nil
+	fixed, err = handler.Repair(issues)
+	suite.Require().NoError(err)
+	suite.Require().Len(fixed, 3)
+	suite.Assert().Equal(
+		[]string{IssueDuplicate, IssueOrphan, IssueUnfinished},
+		[]string{fixed[0].Kind, fixed[1].Kind, fixed[2].Kind},
+	)
+	issues, err = handler.ExecutionIssues()
+	suite.Require().NoError(err)
+	suite.Assert().Empty(issues)
+
+	_, err = handler.MigrateUp(context.Background(), NumOfRuns(99999))
+	suite.Assert().NoError(err)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// DefaultKeepaliveInterval is the interval the repository is pinged at during the runs, unless
+// ctx sets another one (see WithKeepalive)
+const DefaultKeepaliveInterval = time.Minute
+
+// keepaliveCtxKey is the context key used to pass the keepalive interval
+type keepaliveCtxKey struct{}
+
+// WithKeepalive returns a copy of ctx which sets the interval the repository is pinged at
+// during the Up() and Down() runs of the handler, when it implements execution.Pinger. The
+// pings keep its connection from being closed for being idle while long migrations execute.
+// A zero or negative interval disables the pings.
+func WithKeepalive(ctx context.Context, interval time.Duration) context.Context {
+	return context.WithValue(ctx, keepaliveCtxKey{}, interval)
+}
+
+// keepaliveInterval returns the interval set with WithKeepalive, DefaultKeepaliveInterval if
+// ctx sets none
+func keepaliveInterval(ctx context.Context) time.Duration {
+	if interval, ok := ctx.Value(keepaliveCtxKey{}).(time.Duration); ok {
+		return interval
+	}
+	return DefaultKeepaliveInterval
+}
+
+// startKeepalive pings the repository, if it implements execution.Pinger, at the keepalive
+// interval of ctx, until the returned stop function is called. A failed ping is only logged:
+// the next repository operation reconnects, or fails, on its own.
+func startKeepalive(ctx context.Context, repository execution.Repository) (stop func()) {
+	pinger, ok := repository.(execution.Pinger)
+	interval := keepaliveInterval(ctx)
+	if !ok || interval <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := pinger.Ping(ctx); err != nil && ctx.Err() == nil {
+					migration.LoggerFrom(ctx).Warn("repository keepalive ping failed", "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
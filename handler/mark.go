@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// MarkExecuted records a finished execution of the version without calling Up(), for a change
+// which was already applied manually or by another tool (baselining). An unfinished execution
+// of the version is marked as finished. Unregistered versions are refused, unless force is
+// true. The listeners are notified with a forced up event, so the audit and the history record
+// the change.
+func (handler *MigrationsHandler) MarkExecuted(
+	ctx context.Context,
+	version uint64,
+	force bool,
+) (ExecutedMigration, error) {
+	errMsg := fmt.Sprintf("failed to mark version %d as executed", version)
+
+	mig := handler.registry.Get(version)
+	if mig == nil {
+		if !force {
+			return ExecutedMigration{}, fmt.Errorf(
+				"%s, the version is not registered (force it to record it anyway)", errMsg,
+			)
+		}
+		mig = migration.NewDummyMigration(version)
+	}
+
+	exec, err := handler.repository.FindOne(version)
+	if err != nil {
+		return ExecutedMigration{Migration: mig}, fmt.Errorf(
+			"%s, failed to load execution with error: %w", errMsg, newRepositoryError(err),
+		)
+	}
+
+	if exec != nil && exec.Finished() {
+		return ExecutedMigration{Migration: mig, Execution: exec}, fmt.Errorf(
+			"%s, the version is already executed", errMsg,
+		)
+	}
+
+	ctx, runId := execution.EnsureRunId(ctx)
+	start := time.Now()
+	if exec == nil {
+		exec = execution.StartExecution(mig)
+	}
+	exec.RunId = runId
+	exec.FinishExecution()
+
+	executed := newExecutedMigration(mig, exec, nil)
+	if err = handler.repository.Save(*exec); err != nil {
+		err = fmt.Errorf(
+			"%s, failed to save execution with error: %w", errMsg, newRepositoryError(err),
+		)
+	}
+
+	notifyErr := handler.notify(
+		ctx, ExecutionEvent{DirectionUp, true, executed, err, time.Since(start), runId},
+	)
+
+	if notifyErr != nil {
+		err = errors.Join(err, notifyErr)
+	}
+
+	return executed, err
+}
+
+// MarkExecutedUpTo baselines the registered versions up to (and including) the given one
+// which are not executed yet: their finished executions are recorded at once (see
+// execution.SaveAll), without calling Up(). The listeners are notified with a forced up event
+// for each version. Returns the marked migrations, in version order.
+func (handler *MigrationsHandler) MarkExecutedUpTo(
+	ctx context.Context,
+	version uint64,
+) ([]ExecutedMigration, error) {
+	errMsg := fmt.Sprintf("failed to mark the versions up to %d as executed", version)
+
+	executions, err := handler.repository.LoadExecutions()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%s, failed to load executions with error: %w", errMsg, newRepositoryError(err),
+		)
+	}
+
+	known := make(map[uint64]execution.MigrationExecution, len(executions))
+	for _, exec := range executions {
+		known[exec.Version] = exec
+	}
+
+	ctx, runId := execution.EnsureRunId(ctx)
+	start := time.Now()
+	var marked []ExecutedMigration
+	var toSave []execution.MigrationExecution
+	for _, mig := range handler.registry.OrderedMigrations() {
+		if mig.Version() > version {
+			break
+		}
+
+		if err = migration.CollisionErrorOf(mig); err != nil {
+			return nil, fmt.Errorf("%s: %w", errMsg, err)
+		}
+
+		exec, ok := known[mig.Version()]
+		if ok && exec.Finished() {
+			continue
+		}
+		if !ok {
+			exec = *execution.StartExecution(mig)
+		}
+		exec.RunId = runId
+		exec.FinishExecution()
+
+		marked = append(marked, newExecutedMigration(mig, &exec, nil))
+		toSave = append(toSave, exec)
+	}
+
+	if err = execution.SaveAll(handler.repository, toSave); err != nil {
+		err = fmt.Errorf(
+			"%s, failed to save executions with error: %w", errMsg, newRepositoryError(err),
+		)
+	}
+
+	for _, executed := range marked {
+		notifyErr := handler.notify(
+			ctx, ExecutionEvent{DirectionUp, true, executed, err, time.Since(start), runId},
+		)
+		if notifyErr != nil {
+			err = errors.Join(err, notifyErr)
+		}
+	}
+
+	return marked, err
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// Matcher selects migrations by their version or description (see migration.Metadata), to
+// run only a part of a large registry (for example, the migrations of the current quarter)
+type Matcher struct {
+	pattern string
+	prefix  string
+	regex   *regexp.Regexp
+}
+
+// NewMatcher builds a new Matcher from a pattern. A pattern ending with "*", with no other
+// regular expression characters, matches the versions and descriptions starting with the
+// text before "*" (2024* matches all 2024 versions). Any other pattern is a regular
+// expression, matched against the version and the description.
+func NewMatcher(pattern string) (*Matcher, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("empty migrations match pattern")
+	}
+
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok && regexp.QuoteMeta(prefix) == prefix {
+		return &Matcher{pattern: pattern, prefix: prefix}, nil
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid migrations match pattern %q with error: %w", pattern, err)
+	}
+
+	return &Matcher{pattern: pattern, regex: regex}, nil
+}
+
+// Matches checks if the version or the description of the migration matches the pattern
+func (m *Matcher) Matches(mig migration.Migration) bool {
+	candidates := []string{strconv.FormatUint(mig.Version(), 10)}
+	if metadata, ok := migration.MetadataOf(mig); ok && metadata.Description != "" {
+		candidates = append(candidates, metadata.Description)
+	}
+
+	for _, candidate := range candidates {
+		if m.regex == nil && strings.HasPrefix(candidate, m.prefix) {
+			return true
+		}
+		if m.regex != nil && m.regex.MatchString(candidate) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Matcher) String() string {
+	return m.pattern
+}
+
+// selectLeading returns the leading migrations (at most numOfRuns) which match. Executions
+// must follow the registry order, so the selection stops at the first migration which does
+// not match. Errors if that migration is followed by a matching one, since the matching
+// migration could not run before it.
+func (m *Matcher) selectLeading(
+	migrations []migration.Migration,
+	numOfRuns int,
+) ([]migration.Migration, error) {
+	for i, mig := range migrations {
+		if i >= numOfRuns {
+			return migrations[:i], nil
+		}
+
+		if m.Matches(mig) {
+			continue
+		}
+
+		for _, later := range migrations[i+1:] {
+			if m.Matches(later) {
+				return nil, fmt.Errorf(
+					"migration %d does not match %q and must be executed before"+
+						" the matching migration %d",
+					mig.Version(), m.pattern, later.Version(),
+				)
+			}
+		}
+
+		return migrations[:i], nil
+	}
+
+	return migrations, nil
+}
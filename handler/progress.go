@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"time"
+)
+
+// progressReporterCtxKey is the context key used to pass a ProgressReporter to the handler
+type progressReporterCtxKey struct{}
+
+// Progress describes the state of a batch run when one of its migrations starts or finishes
+type Progress struct {
+	// Direction is DirectionUp or DirectionDown
+	Direction string
+
+	// Version of the migration
+	Version uint64
+
+	// Position of the migration in the run, from 1 to Total (the number of planned migrations)
+	Position int
+	Total    int
+
+	// Done is the number of migrations handled successfully so far
+	Done int
+
+	// Elapsed is the time since the run started
+	Elapsed time.Duration
+
+	// Duration and Err of the migration Up()/Down() call (including the executions state
+	// persistence), only set when the migration finished
+	Duration time.Duration
+	Err      error
+}
+
+// ProgressReporter is notified of the progress of the batch runs of the handler (MigrateUp,
+// MigrateUpMatching, MigrateUpTo and MigrateDown), so long runs are not silent until the end.
+// Unlike the ExecutionListener, it can't stop the run.
+type ProgressReporter interface {
+	// Started is called before each migration Up()/Down() call
+	Started(ctx context.Context, progress Progress)
+
+	// Finished is called after each migration Up()/Down() call
+	Finished(ctx context.Context, progress Progress)
+}
+
+// WithProgressReporter returns a copy of ctx which carries the reporter of the progress of the
+// batch runs
+func WithProgressReporter(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressReporterCtxKey{}, reporter)
+}
+
+// runProgress tracks the progress of a batch run and reports it to the reporter carried by the
+// run context, if any
+type runProgress struct {
+	reporter  ProgressReporter
+	direction string
+	total     int
+	done      int
+	start     time.Time
+}
+
+// newRunProgress starts tracking the progress of a run of total migrations
+func newRunProgress(ctx context.Context, direction string, total int) *runProgress {
+	reporter, _ := ctx.Value(progressReporterCtxKey{}).(ProgressReporter)
+	return &runProgress{reporter: reporter, direction: direction, total: total, start: time.Now()}
+}
+
+// started reports the start of the migration at the given (0 based) index of the run
+func (p *runProgress) started(ctx context.Context, index int, version uint64) {
+	if p.reporter != nil {
+		p.reporter.Started(ctx, p.progress(index, version))
+	}
+}
+
+// finished reports the end of the migration at the given (0 based) index of the run
+func (p *runProgress) finished(
+	ctx context.Context,
+	index int,
+	version uint64,
+	duration time.Duration,
+	err error,
+) {
+	if err == nil {
+		p.done++
+	}
+
+	if p.reporter != nil {
+		progress := p.progress(index, version)
+		progress.Duration, progress.Err = duration, err
+		p.reporter.Finished(ctx, progress)
+	}
+}
+
+func (p *runProgress) progress(index int, version uint64) Progress {
+	return Progress{
+		Direction: p.direction,
+		Version:   version,
+		Position:  index + 1,
+		Total:     p.total,
+		Done:      p.done,
+		Elapsed:   time.Since(p.start),
+	}
+}
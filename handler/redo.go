@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// Redo rolls back the executed migration with the version, with Down(), then executes it
+// again, with Up(), updating the executions accordingly. Meant for development, while
+// iterating on a migration. Up() is not executed if the rollback fails. Both steps notify the
+// listeners with forced events.
+func (handler *MigrationsHandler) Redo(ctx context.Context, version uint64) (
+	down ExecutedMigration,
+	up ExecutedMigration,
+	err error,
+) {
+	errMsg := fmt.Sprintf("failed to redo version %d", version)
+
+	mig := handler.registry.Get(version)
+	if mig == nil {
+		return down, up, fmt.Errorf("%s, the version is not registered", errMsg)
+	}
+	if err = migration.ValidateReversible([]migration.Migration{mig}); err != nil {
+		return down, up, fmt.Errorf("%s, %w", errMsg, err)
+	}
+
+	exec, err := handler.repository.FindOne(version)
+	if err != nil {
+		return down, up, fmt.Errorf(
+			"%s, failed to load execution with error: %w", errMsg, newRepositoryError(err),
+		)
+	}
+
+	if exec == nil || !exec.Finished() {
+		return down, up, fmt.Errorf("%s, the version is not executed", errMsg)
+	}
+
+	ctx, _ = execution.EnsureRunId(ctx)
+	if down, err = handler.ForceDown(ctx, version); err != nil {
+		return down, up, err
+	}
+
+	up, err = handler.ForceUp(ctx, version)
+	return down, up, err
+}
+
+// RedoLast works like Redo, for the executed migration with the highest version
+func (handler *MigrationsHandler) RedoLast(ctx context.Context) (
+	down ExecutedMigration,
+	up ExecutedMigration,
+	err error,
+) {
+	plan, err := handler.newExecutionPlan(handler.registry, handler.repository)
+	if err != nil {
+		return down, up, fmt.Errorf(
+			"failed to redo the last migration, failed to create execution plan with error: %w",
+			err,
+		)
+	}
+
+	last := plan.LastExecuted()
+	if last.Migration == nil {
+		return down, up, errors.New("failed to redo the last migration, no migration is executed")
+	}
+
+	return handler.Redo(ctx, last.Migration.Version())
+}
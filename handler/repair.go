@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/golibry/go-migrations/execution"
+)
+
+// The kinds of the execution issues (see ExecutionIssue)
+const (
+	// IssueDuplicate is a version recorded by several executions. The fix keeps one of them:
+	// the last finished one, or the last one if none is finished.
+	IssueDuplicate = "duplicate"
+
+	// IssueOrphan is an execution of a version which is not registered. The fix deletes it.
+	IssueOrphan = "orphan"
+
+	// IssueUnfinished is an execution whose migration failed or was interrupted. The fix marks
+	// it as finished, for a migration which was completed manually.
+	IssueUnfinished = "unfinished"
+)
+
+// IssueKinds lists the kinds of the execution issues, in the order their fixes are applied
+var IssueKinds = []string{IssueDuplicate, IssueOrphan, IssueUnfinished}
+
+// ExecutionIssue is an inconsistency of the recorded executions, which prevents the handler
+// from planning the runs, or which hides a failed migration
+type ExecutionIssue struct {
+	// Kind is IssueDuplicate, IssueOrphan or IssueUnfinished
+	Kind string
+
+	// Execution is the execution with the issue. For the duplicates, it is the one kept by
+	// the fix.
+	Execution execution.MigrationExecution
+
+	// Duplicates is the number of the other executions of the version, for the duplicates
+	Duplicates int
+}
+
+func (i ExecutionIssue) String() string {
+	switch i.Kind {
+	case IssueDuplicate:
+		return fmt.Sprintf("version %d is recorded %d times", i.Execution.Version, i.Duplicates+1)
+	case IssueOrphan:
+		return fmt.Sprintf("version %d is executed but not registered", i.Execution.Version)
+	default:
+		return fmt.Sprintf("version %d is not finished", i.Execution.Version)
+	}
+}
+
+// Fix describes the change the fix of the issue makes
+func (i ExecutionIssue) Fix() string {
+	switch i.Kind {
+	case IssueDuplicate:
+		return fmt.Sprintf("keep one execution of version %d", i.Execution.Version)
+	case IssueOrphan:
+		return fmt.Sprintf("delete the execution of version %d", i.Execution.Version)
+	default:
+		return fmt.Sprintf("mark version %d as finished", i.Execution.Version)
+	}
+}
+
+// ExecutionIssues finds the inconsistencies of the recorded executions (see ExecutionIssue),
+// in version order
+func (handler *MigrationsHandler) ExecutionIssues() ([]ExecutionIssue, error) {
+	executions, err := handler.repository.LoadExecutions()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find the execution issues, failed to load executions with error: %w",
+			newRepositoryError(err),
+		)
+	}
+
+	byVersion := make(map[uint64][]execution.MigrationExecution)
+	for _, exec := range executions {
+		byVersion[exec.Version] = append(byVersion[exec.Version], exec)
+	}
+
+	issues := []ExecutionIssue{}
+	for _, version := range slices.Sorted(maps.Keys(byVersion)) {
+		recorded := byVersion[version]
+		kept := slices.MaxFunc(recorded, compareKept)
+		if len(recorded) > 1 {
+			issues = append(issues, ExecutionIssue{IssueDuplicate, kept, len(recorded) - 1})
+		}
+
+		switch {
+		case handler.registry.Get(version) == nil:
+			issues = append(issues, ExecutionIssue{Kind: IssueOrphan, Execution: kept})
+		case !kept.Finished():
+			issues = append(issues, ExecutionIssue{Kind: IssueUnfinished, Execution: kept})
+		}
+	}
+	return issues, nil
+}
+
+// compareKept orders the executions of a version by preference: the finished ones, then the
+// latest ones
+func compareKept(a execution.MigrationExecution, b execution.MigrationExecution) int {
+	if a.Finished() != b.Finished() {
+		if a.Finished() {
+			return 1
+		}
+		return -1
+	}
+	return cmp.Compare(a.ExecutedAtMs, b.ExecutedAtMs)
+}
+
+// Repair applies the fixes of the issues (see ExecutionIssue): the duplicates first, then the
+// orphans and the unfinished executions. It stops at the first failure and returns the fixed
+// issues.
+func (handler *MigrationsHandler) Repair(issues []ExecutionIssue) ([]ExecutionIssue, error) {
+	issues = slices.Clone(issues)
+	slices.SortStableFunc(
+		issues, func(a ExecutionIssue, b ExecutionIssue) int {
+			return slices.Index(IssueKinds, a.Kind) - slices.Index(IssueKinds, b.Kind)
+		},
+	)
+
+	fixed := []ExecutionIssue{}
+	for _, issue := range issues {
+		var err error
+		exec := issue.Execution
+		switch issue.Kind {
+		case IssueDuplicate:
+			// the executions are removed by version, so all of them are, before saving one
+			if err = handler.repository.Remove(exec); err == nil {
+				err = handler.repository.Save(exec)
+			}
+		case IssueOrphan:
+			err = handler.repository.Remove(exec)
+		case IssueUnfinished:
+			exec.FinishExecution()
+			err = handler.repository.Save(exec)
+		default:
+			return fixed, fmt.Errorf(
+				"failed to repair the executions, unknown issue kind %q, expected one of: %s",
+				issue.Kind, strings.Join(IssueKinds, ", "),
+			)
+		}
+
+		if err != nil {
+			return fixed, fmt.Errorf(
+				"failed to repair the executions, failed to %s with error: %w",
+				issue.Fix(), newRepositoryError(err),
+			)
+		}
+		fixed = append(fixed, issue)
+	}
+	return fixed, nil
+}
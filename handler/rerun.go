@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// rerunCheckCtxKey is the context key used to enable the safe rerun mode
+type rerunCheckCtxKey struct{}
+
+// WithRerunCheck returns a copy of ctx which enables the safe rerun mode for the Up() runs of
+// the handler (MigrateUp, MigrateUpMatching, MigrateUpTo): each migration whose Up() succeeded
+// is executed a second time, which must succeed without affecting any rows (as recorded through
+// migration.RecordRowsAffected), enforcing idempotent migrations. Meant for non-production
+// verification (CI, staging), since a migration which is not idempotent may corrupt data.
+func WithRerunCheck(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rerunCheckCtxKey{}, true)
+}
+
+// rerunCheckEnabled checks if ctx enables the safe rerun mode
+func rerunCheckEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(rerunCheckCtxKey{}).(bool)
+	return enabled
+}
+
+// RerunUnsafeMigration describes a migration whose second Up() failed or affected rows
+type RerunUnsafeMigration struct {
+	Version uint64
+
+	// Err is the error returned by the second Up(), if any
+	Err error
+
+	// RowsAffected is the number of rows affected by the second Up()
+	RowsAffected int64
+}
+
+func (m RerunUnsafeMigration) String() string {
+	if m.Err != nil {
+		return fmt.Sprintf("%d (second Up() failed with error: %s)", m.Version, m.Err)
+	}
+	return fmt.Sprintf("%d (second Up() affected %d rows)", m.Version, m.RowsAffected)
+}
+
+// RerunUnsafeError is returned, along with the executed migrations, when some of them are not
+// safe to rerun (see WithRerunCheck)
+type RerunUnsafeError struct {
+	Migrations []RerunUnsafeMigration
+}
+
+func (e *RerunUnsafeError) Error() string {
+	descriptions := make([]string, 0, len(e.Migrations))
+	for _, mig := range e.Migrations {
+		descriptions = append(descriptions, mig.String())
+	}
+	return "migrations not safe to rerun: " + strings.Join(descriptions, ", ")
+}
+
+// rerun executes Up() a second time (the second attempt), returning nil if the migration is
+// safe to rerun. The migration gets no checkpointer, since its execution is already finished.
+func (handler *MigrationsHandler) rerun(
+	ctx context.Context,
+	mig migration.Migration,
+) *RerunUnsafeMigration {
+	rerunCtx, cancel := migrationContext(ctx, mig.Version(), 2)
+	defer cancel()
+	rerunCtx, counter := migration.WithRowsCounter(rerunCtx)
+	migration.LoggerFrom(rerunCtx).DebugContext(rerunCtx, "calling Up() again, to check the rerun")
+	if err := mig.Up(rerunCtx, handler.db); err != nil {
+		return &RerunUnsafeMigration{Version: mig.Version(), Err: err}
+	}
+
+	if counter.Count() > 0 {
+		return &RerunUnsafeMigration{Version: mig.Version(), RowsAffected: counter.Count()}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BufferedStore wraps a Store so that the records are appended in batches: Append keeps the
+// records in memory until the batch is full, then appends them at once (in a single write if
+// the store implements BatchStore). It reduces the writes of the history during the runs of
+// many small migrations, for example on the databases billed or rate-limited per request.
+//
+// The buffered records are lost if the process stops without calling Flush, which breaks the
+// chain between the executions and the history (see VerifyExecutions), so flush the store at
+// the end of the runs. Load flushes the buffered records first.
+type BufferedStore struct {
+	mu        sync.Mutex
+	store     Store
+	batchSize int
+	buffered  []Record
+}
+
+// NewBufferedStore builds a new BufferedStore which appends the records to the given store by
+// batches of batchSize records (at least 1)
+func NewBufferedStore(store Store, batchSize int) *BufferedStore {
+	return &BufferedStore{store: store, batchSize: max(batchSize, 1)}
+}
+
+// Append implements the Store.Append method, buffering the record and flushing the buffered
+// records once there are batchSize of them
+func (s *BufferedStore) Append(ctx context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buffered = append(s.buffered, record)
+	if len(s.buffered) < s.batchSize {
+		return nil
+	}
+	return s.flush(ctx)
+}
+
+// Load implements the Store.Load method, flushing the buffered records first
+func (s *BufferedStore) Load(ctx context.Context) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.flush(ctx); err != nil {
+		return nil, err
+	}
+	return s.store.Load(ctx)
+}
+
+// Flush appends the buffered records to the store. The records are kept buffered if it fails,
+// so the next flush retries them.
+func (s *BufferedStore) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flush(ctx)
+}
+
+func (s *BufferedStore) flush(ctx context.Context) error {
+	if len(s.buffered) == 0 {
+		return nil
+	}
+
+	var err error
+	if batchStore, ok := s.store.(BatchStore); ok {
+		err = batchStore.AppendAll(ctx, s.buffered)
+	} else {
+		for len(s.buffered) > 0 && err == nil {
+			if err = s.store.Append(ctx, s.buffered[0]); err == nil {
+				s.buffered = s.buffered[1:]
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"failed to flush %d history records with error: %w", len(s.buffered), err,
+		)
+	}
+
+	s.buffered = nil
+	return nil
+}
@@ -0,0 +1,102 @@
+package history
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileStore is a Store which keeps the records in a file, one JSON document per line. The
+// file is opened in append mode and synced after each append (of a record, or of a batch of
+// records, see BatchStore).
+type FileStore struct {
+	mu       sync.Mutex
+	filePath string
+}
+
+// NewFileStore builds a new FileStore. The file is created on the first append.
+func NewFileStore(filePath string) *FileStore {
+	return &FileStore{filePath: filePath}
+}
+
+// Append implements the Store.Append method
+func (s *FileStore) Append(ctx context.Context, record Record) error {
+	return s.AppendAll(ctx, []Record{record})
+}
+
+// AppendAll implements the BatchStore.AppendAll method, writing and syncing the records at once
+func (s *FileStore) AppendAll(_ context.Context, records []Record) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var encoded []byte
+	for _, record := range records {
+		encodedRecord, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode history record with error: %w", err)
+		}
+		encoded = append(append(encoded, encodedRecord...), '\n')
+	}
+
+	file, err := os.OpenFile(s.filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open history file %s with error: %w", s.filePath, err)
+	}
+
+	defer func(file *os.File) {
+		if closeErr := file.Close(); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		}
+	}(file)
+
+	if _, err = file.Write(encoded); err != nil {
+		return fmt.Errorf("failed to write history file %s with error: %w", s.filePath, err)
+	}
+
+	return file.Sync()
+}
+
+// Load implements the Store.Load method. A missing file is an empty history.
+func (s *FileStore) Load(_ context.Context) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open history file %s with error: %w", s.filePath, err)
+	}
+
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record Record
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return records, fmt.Errorf(
+				"invalid history record at line %d of %s: %w", line, s.filePath, err,
+			)
+		}
+		records = append(records, record)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return records, fmt.Errorf(
+			"failed to read history file %s with error: %w", s.filePath, err,
+		)
+	}
+
+	return records, nil
+}
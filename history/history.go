@@ -0,0 +1,244 @@
+// Package history keeps a tamper-evident, append-only history of the migrations applied and
+// rolled back, for regulated environments. Each record includes the hash of the previous
+// one, so changing or removing a record breaks the chain, which is detected by Verify.
+//
+// Records are produced by a handler.ExecutionListener, see NewListener. VerifyExecutions
+// also checks that the executions state matches the one replayed from the history, which
+// detects direct changes to the executions table (or collection).
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+)
+
+// Statuses of a history record
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// Record is a link of the history chain, describing a migration Up()/Down() call
+type Record struct {
+	// Sequence is the position of the record in the chain, starting from 1
+	Sequence uint64 `json:"sequence"`
+
+	TimeMs       int64  `json:"timeMs"`
+	RunId        string `json:"runId,omitempty"`
+	Version      uint64 `json:"version"`
+	Direction    string `json:"direction"`
+	Forced       bool   `json:"forced"`
+	Status       string `json:"status"`
+	ExecutedAtMs uint64 `json:"executedAtMs"`
+	FinishedAtMs uint64 `json:"finishedAtMs"`
+
+	// PrevHash is the hash of the previous record, empty for the first one
+	PrevHash string `json:"prevHash"`
+
+	// Hash is the hex encoded SHA-256 of the record content, PrevHash included
+	Hash string `json:"hash"`
+}
+
+// ComputeHash computes the hash of the record content (all the fields, except Hash)
+func (r Record) ComputeHash() string {
+	r.Hash = ""
+	encoded, _ := json.Marshal(r)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// Store persists the history records. Implementations must be append-only.
+type Store interface {
+	// Append persists the record after the existing ones
+	Append(ctx context.Context, record Record) error
+
+	// Load returns all the records, in the order they were appended
+	Load(ctx context.Context) ([]Record, error)
+}
+
+// BatchStore is an optional interface for stores which can append many records in a single
+// write (for example, with a multi-row insert), used by the BufferedStore to flush its records
+type BatchStore interface {
+	// AppendAll is the Store.Append method for all the records, in order
+	AppendAll(ctx context.Context, records []Record) error
+}
+
+// TamperError is returned by Verify and VerifyExecutions when the history (or the
+// executions state) was changed outside the migrations runs
+type TamperError struct {
+	// Sequence is the sequence of the offending record, 0 if the error is not about one
+	Sequence uint64
+	Reason   string
+}
+
+func (e *TamperError) Error() string {
+	if e.Sequence == 0 {
+		return "history tampering detected: " + e.Reason
+	}
+	return fmt.Sprintf("history tampering detected at record %d: %s", e.Sequence, e.Reason)
+}
+
+// Recorder appends the records of the handled migrations to a store, chaining each record
+// to the previous one
+type Recorder struct {
+	mu    sync.Mutex
+	store Store
+	last  *Record
+}
+
+// NewRecorder builds a new Recorder which appends to the given store
+func NewRecorder(store Store) *Recorder {
+	return &Recorder{store: store}
+}
+
+// Record appends the record of the execution event to the store
+func (r *Recorder) Record(ctx context.Context, event handler.ExecutionEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.last == nil {
+		records, err := r.store.Load(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load history with error: %w", err)
+		}
+
+		r.last = &Record{}
+		if len(records) > 0 {
+			r.last = &records[len(records)-1]
+		}
+	}
+
+	record := Record{
+		Sequence:  r.last.Sequence + 1,
+		TimeMs:    time.Now().UnixMilli(),
+		RunId:     event.RunId,
+		Direction: event.Direction,
+		Forced:    event.Forced,
+		Status:    StatusSuccess,
+		PrevHash:  r.last.Hash,
+	}
+
+	if event.Migration.Migration != nil {
+		record.Version = event.Migration.Migration.Version()
+	}
+
+	if exec := event.Migration.Execution; exec != nil {
+		record.ExecutedAtMs = exec.ExecutedAtMs
+		record.FinishedAtMs = exec.FinishedAtMs
+	}
+
+	if event.Err != nil {
+		record.Status = StatusFailure
+	}
+
+	record.Hash = record.ComputeHash()
+	if err := r.store.Append(ctx, record); err != nil {
+		// the store state is unknown, so the last record is reloaded on the next call
+		r.last = nil
+		return fmt.Errorf("failed to append history record with error: %w", err)
+	}
+
+	r.last = &record
+	return nil
+}
+
+// NewListener builds a handler.ExecutionListener which appends a history record to the
+// store for each migration Up()/Down() call. If the store fails, the migrations run is
+// stopped.
+func NewListener(store Store) handler.ExecutionListener {
+	return NewRecorder(store).Record
+}
+
+// Verify checks that the records form an unbroken chain: sequences follow each other, each
+// record links to the previous one and its hash matches its content. Removing records from
+// the end of the history can't be detected by the chain alone (see VerifyExecutions).
+func Verify(records []Record) error {
+	prevHash := ""
+	for i, record := range records {
+		if record.Sequence != uint64(i+1) {
+			return &TamperError{
+				record.Sequence, fmt.Sprintf("expected sequence %d", i+1),
+			}
+		}
+
+		if record.PrevHash != prevHash {
+			return &TamperError{record.Sequence, "previous record hash mismatch"}
+		}
+
+		if record.ComputeHash() != record.Hash {
+			return &TamperError{record.Sequence, "record hash mismatch"}
+		}
+
+		prevHash = record.Hash
+	}
+
+	return nil
+}
+
+// VerifyExecutions checks that the executions state matches the one replayed from the
+// records. Executions which started before the first record (saved before the history was
+// enabled) are not verified.
+func VerifyExecutions(records []Record, executions []execution.MigrationExecution) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	replayed := map[uint64]Record{}
+	for _, record := range records {
+		switch {
+		case record.Direction == handler.DirectionDown && record.Status == StatusSuccess:
+			delete(replayed, record.Version)
+		case record.Direction == handler.DirectionUp && record.ExecutedAtMs > 0:
+			replayed[record.Version] = record
+		}
+	}
+
+	historyStartMs := uint64(records[0].TimeMs)
+	var errs []error
+	for _, exec := range executions {
+		record, ok := replayed[exec.Version]
+		delete(replayed, exec.Version)
+
+		if !ok {
+			if exec.ExecutedAtMs >= historyStartMs {
+				errs = append(
+					errs, &TamperError{
+						Reason: fmt.Sprintf("execution %d is missing from history", exec.Version),
+					},
+				)
+			}
+			continue
+		}
+
+		if record.ExecutedAtMs != exec.ExecutedAtMs || record.FinishedAtMs != exec.FinishedAtMs {
+			errs = append(
+				errs, &TamperError{
+					record.Sequence,
+					fmt.Sprintf("execution %d does not match its history record", exec.Version),
+				},
+			)
+		}
+	}
+
+	for version, record := range replayed {
+		// the state of failed executions may not have been saved
+		if record.Status == StatusSuccess {
+			errs = append(
+				errs, &TamperError{
+					record.Sequence, fmt.Sprintf("execution %d is missing", version),
+				},
+			)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,224 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type HistoryTestSuite struct {
+	suite.Suite
+	filePath string
+	store    *FileStore
+	repo     *execution.InMemoryRepository
+}
+
+func TestHistoryTestSuite(t *testing.T) {
+	suite.Run(t, new(HistoryTestSuite))
+}
+
+func (suite *HistoryTestSuite) SetupTest() {
+	suite.filePath = filepath.Join(suite.T().TempDir(), "history.jsonl")
+	suite.store = NewFileStore(suite.filePath)
+	suite.repo = &execution.InMemoryRepository{}
+}
+
+// migrate runs all migrations up and one down, recording the history
+func (suite *HistoryTestSuite) migrate() []Record {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	_ = registry.Register(migration.NewDummyMigration(3))
+	migrationsHandler, _ := handler.NewHandler(registry, suite.repo, nil)
+	migrationsHandler.AddListener(NewListener(suite.store))
+
+	_, err := migrationsHandler.MigrateUp(context.Background(), handler.NumOfRuns(3))
+	suite.Require().NoError(err)
+	_, err = migrationsHandler.MigrateDown(context.Background(), handler.NumOfRuns(1))
+	suite.Require().NoError(err)
+
+	records, err := suite.store.Load(context.Background())
+	suite.Require().NoError(err)
+	return records
+}
+
+func (suite *HistoryTestSuite) TestItChainsTheRecordsOfHandledMigrations() {
+	records := suite.migrate()
+
+	suite.Require().Len(records, 4)
+	suite.Assert().Equal("", records[0].PrevHash)
+	for i, record := range records {
+		suite.Assert().Equal(uint64(i+1), record.Sequence)
+		suite.Assert().Len(record.Hash, 64)
+		suite.Assert().Len(record.RunId, 26)
+		if i > 0 {
+			suite.Assert().Equal(records[i-1].Hash, record.PrevHash)
+		}
+	}
+	suite.Assert().Equal(handler.DirectionDown, records[3].Direction)
+	suite.Assert().Equal(uint64(3), records[3].Version)
+
+	suite.Assert().NoError(Verify(records))
+	suite.Assert().NoError(VerifyExecutions(records, suite.repo.PersistedExecutions))
+}
+
+func (suite *HistoryTestSuite) TestItContinuesTheChainOfAnExistingHistory() {
+	suite.migrate()
+
+	// a new process (handler and recorder) appends to the same history
+	records := suite.migrate()
+
+	suite.Require().Len(records, 6)
+	suite.Assert().Equal(records[3].Hash, records[4].PrevHash)
+	suite.Assert().NoError(Verify(records))
+}
+
+func (suite *HistoryTestSuite) TestItDetectsTamperedRecords() {
+	scenarios := map[string]struct {
+		tamper         func(records []Record) []Record
+		expectedReason string
+	}{
+		"changed record": {
+			func(records []Record) []Record {
+				records[1].Version = 7
+				return records
+			},
+			"history tampering detected at record 2: record hash mismatch",
+		},
+		"rehashed record": {
+			func(records []Record) []Record {
+				records[1].Forced = true
+				records[1].Hash = records[1].ComputeHash()
+				return records
+			},
+			"history tampering detected at record 3: previous record hash mismatch",
+		},
+		"removed record": {
+			func(records []Record) []Record {
+				return append(records[:1], records[2:]...)
+			},
+			"history tampering detected at record 3: expected sequence 2",
+		},
+	}
+
+	for name, scenario := range scenarios {
+		suite.SetupTest()
+		records := scenario.tamper(suite.migrate())
+
+		err := Verify(records)
+		var tamperErr *TamperError
+		suite.Assert().True(errors.As(err, &tamperErr), name)
+		suite.Assert().EqualError(err, scenario.expectedReason, name)
+	}
+}
+
+func (suite *HistoryTestSuite) TestItDetectsExecutionsChangedOutsideTheRuns() {
+	records := suite.migrate()
+
+	// version 2 is changed, version 3 is re-added, version 1 is removed
+	executions := []execution.MigrationExecution{
+		suite.repo.PersistedExecutions[1],
+		{Version: 3, ExecutedAtMs: uint64(records[0].TimeMs), FinishedAtMs: 1},
+	}
+	executions[0].FinishedAtMs++
+
+	err := VerifyExecutions(records, executions)
+	suite.Assert().ErrorContains(err, "execution 2 does not match its history record")
+	suite.Assert().ErrorContains(err, "execution 3 is missing from history")
+	suite.Assert().ErrorContains(err, "execution 1 is missing")
+
+	// executions saved before the history was enabled are not verified
+	suite.Assert().NoError(
+		VerifyExecutions(
+			records,
+			append(
+				suite.repo.PersistedExecutions,
+				execution.MigrationExecution{Version: 9, ExecutedAtMs: 1, FinishedAtMs: 2},
+			),
+		),
+	)
+}
+
+func (suite *HistoryTestSuite) TestItFailsToLoadInvalidHistoryFiles() {
+	suite.migrate()
+
+	content, _ := os.ReadFile(suite.filePath)
+	_ = os.WriteFile(
+		suite.filePath, []byte(strings.Replace(string(content), "{", "[", 1)), 0o640,
+	)
+
+	_, err := suite.store.Load(context.Background())
+	suite.Assert().ErrorContains(err, "invalid history record at line 1")
+
+	records, err := NewFileStore(filepath.Join(suite.T().TempDir(), "missing")).Load(
+		context.Background(),
+	)
+	suite.Assert().NoError(err)
+	suite.Assert().Empty(records)
+}
+
+func (suite *HistoryTestSuite) TestItAppendsTheRecordsInBatches() {
+	ctx := context.Background()
+	buffered := NewBufferedStore(suite.store, 3)
+	for sequence := uint64(1); sequence <= 4; sequence++ {
+		suite.Require().NoError(buffered.Append(ctx, Record{Sequence: sequence}))
+		if sequence == 2 {
+			// the first batch is not full yet
+			_, err := os.Stat(suite.filePath)
+			suite.Assert().ErrorIs(err, os.ErrNotExist)
+		}
+	}
+
+	records, err := suite.store.Load(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().Len(records, 3)
+
+	// Load flushes the buffered records first
+	records, err = buffered.Load(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().Len(records, 4)
+	suite.Assert().Equal(uint64(4), records[3].Sequence)
+}
+
+func (suite *HistoryTestSuite) TestItKeepsTheBufferedRecordsIfTheFlushFails() {
+	ctx := context.Background()
+	store := &failingStore{failures: 1}
+	buffered := NewBufferedStore(store, 10)
+	suite.Require().NoError(buffered.Append(ctx, Record{Sequence: 1}))
+	suite.Require().NoError(buffered.Append(ctx, Record{Sequence: 2}))
+
+	suite.Assert().ErrorContains(
+		buffered.Flush(ctx), "failed to flush 2 history records with error: append failed",
+	)
+	suite.Require().NoError(buffered.Flush(ctx))
+	suite.Assert().Equal([]Record{{Sequence: 1}, {Sequence: 2}}, store.records)
+	suite.Require().NoError(buffered.Flush(ctx))
+	suite.Assert().Len(store.records, 2)
+}
+
+// failingStore is a Store without batches, whose first appends fail
+type failingStore struct {
+	failures int
+	records  []Record
+}
+
+func (s *failingStore) Append(_ context.Context, record Record) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("append failed")
+	}
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *failingStore) Load(_ context.Context) ([]Record, error) {
+	return s.records, nil
+}
@@ -0,0 +1,193 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultConsulLockTtl is the session TTL used for Consul locks when none is provided
+const DefaultConsulLockTtl = 15 * time.Minute
+
+// DefaultConsulAddress is the Consul agent address used when none is configured
+const DefaultConsulAddress = "http://127.0.0.1:8500"
+
+// ConsulLocker is a Locker implementation based on a Consul session and a KV key acquired
+// with it, through the Consul HTTP API. The session is created with the "delete" behavior,
+// so the key is removed when the session expires or is destroyed. The session is renewed
+// while the lock is held, so only the lock of a dead process expires after the TTL.
+type ConsulLocker struct {
+	address string
+	token   string
+	key     string
+	ttl     time.Duration
+	client  *http.Client
+	mu      sync.Mutex
+	session string
+	renewal *renewal
+}
+
+// NewConsulLocker builds a new ConsulLocker which locks the given KV key. If address is empty,
+// DefaultConsulAddress is used. The token is sent as ACL token and can be empty. If ttl is
+// not positive, DefaultConsulLockTtl is used. If client is nil, http.DefaultClient is used.
+func NewConsulLocker(
+	address string,
+	token string,
+	key string,
+	ttl time.Duration,
+	client *http.Client,
+) *ConsulLocker {
+	if address == "" {
+		address = DefaultConsulAddress
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultConsulLockTtl
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &ConsulLocker{
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		key:     strings.TrimLeft(key, "/"),
+		ttl:     ttl,
+		client:  client,
+	}
+}
+
+// Key returns the Consul KV key used as lock
+func (l *ConsulLocker) Key() string {
+	return l.key
+}
+
+// Lock implements the Locker.Lock method
+func (l *ConsulLocker) Lock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.session != "" {
+		return ErrAlreadyLocked
+	}
+
+	var created struct {
+		ID string
+	}
+	err := doJSON(
+		ctx, l.client, http.MethodPut, l.address+"/v1/session/create", l.headers(),
+		map[string]string{
+			"Name":      l.key,
+			"TTL":       fmt.Sprintf("%ds", int(l.ttl.Seconds())),
+			"Behavior":  "delete",
+			"LockDelay": "0s",
+		},
+		&created,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create consul session for lock %s with error: %w", l.key, err)
+	}
+
+	var acquired bool
+	err = doJSON(
+		ctx, l.client, http.MethodPut,
+		l.kvUrl()+"?acquire="+url.QueryEscape(created.ID), l.headers(), nil, &acquired,
+	)
+	if err != nil || !acquired {
+		_ = l.destroySession(ctx, created.ID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to acquire consul lock %s with error: %w", l.key, err)
+	}
+
+	if !acquired {
+		return ErrLockHeld
+	}
+
+	l.session = created.ID
+	l.renewal = startRenewal(l.ttl, l.renew)
+	return nil
+}
+
+// Unlock implements the Locker.Unlock method. It returns ErrNotLocked if the session expired
+// in the meantime (and the lock may be held by someone else).
+func (l *ConsulLocker) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.session == "" {
+		return ErrNotLocked
+	}
+
+	l.renewal.stop()
+	session := l.session
+	l.session = ""
+
+	var released bool
+	err := doJSON(
+		ctx, l.client, http.MethodPut,
+		l.kvUrl()+"?release="+url.QueryEscape(session), l.headers(), nil, &released,
+	)
+	destroyErr := l.destroySession(ctx, session)
+
+	if err != nil {
+		return fmt.Errorf("failed to release consul lock %s with error: %w", l.key, err)
+	}
+
+	if !released {
+		return ErrNotLocked
+	}
+
+	if destroyErr != nil {
+		return fmt.Errorf(
+			"failed to destroy consul session for lock %s with error: %w", l.key, destroyErr,
+		)
+	}
+
+	return nil
+}
+
+// renew renews the session of the held lock. It is called from the renewal goroutine, which
+// is stopped (under l.mu) before the session is cleared, so it can be read without locking.
+func (l *ConsulLocker) renew(ctx context.Context) error {
+	err := doJSON(
+		ctx, l.client, http.MethodPut,
+		l.address+"/v1/session/renew/"+url.PathEscape(l.session), l.headers(), nil, nil,
+	)
+
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return errLockLost
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to renew consul session for lock %s with error: %w", l.key, err)
+	}
+
+	return nil
+}
+
+func (l *ConsulLocker) destroySession(ctx context.Context, session string) error {
+	return doJSON(
+		ctx, l.client, http.MethodPut,
+		l.address+"/v1/session/destroy/"+url.PathEscape(session), l.headers(), nil, nil,
+	)
+}
+
+func (l *ConsulLocker) kvUrl() string {
+	return l.address + "/v1/kv/" + l.key
+}
+
+func (l *ConsulLocker) headers() map[string]string {
+	if l.token == "" {
+		return nil
+	}
+	return map[string]string{"X-Consul-Token": l.token}
+}
@@ -0,0 +1,166 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeConsul emulates the Consul session and KV lock endpoints used by ConsulLocker
+type fakeConsul struct {
+	mu       sync.Mutex
+	sessions map[string]bool
+	holders  map[string]string
+	nextId   int
+	tokens   []string
+	renewals int
+}
+
+func newFakeConsul() *fakeConsul {
+	return &fakeConsul{sessions: map[string]bool{}, holders: map[string]string{}}
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens = append(f.tokens, r.Header.Get("X-Consul-Token"))
+
+	switch {
+	case r.URL.Path == "/v1/session/create":
+		f.nextId++
+		id := "session-" + strconv.Itoa(f.nextId)
+		f.sessions[id] = true
+		_ = json.NewEncoder(w).Encode(map[string]string{"ID": id})
+	case strings.HasPrefix(r.URL.Path, "/v1/session/renew/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/session/renew/")
+		if !f.sessions[id] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.renewals++
+		_ = json.NewEncoder(w).Encode([]map[string]string{{"ID": id}})
+	case strings.HasPrefix(r.URL.Path, "/v1/session/destroy/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/session/destroy/")
+		delete(f.sessions, id)
+		for key, holder := range f.holders {
+			if holder == id {
+				delete(f.holders, key)
+			}
+		}
+		_, _ = w.Write([]byte("true"))
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/"):
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		if session := r.URL.Query().Get("acquire"); session != "" {
+			holder, held := f.holders[key]
+			ok := !held || holder == session
+			if ok {
+				f.holders[key] = session
+			}
+			_ = json.NewEncoder(w).Encode(ok)
+			return
+		}
+
+		session := r.URL.Query().Get("release")
+		ok := f.holders[key] == session
+		if ok {
+			delete(f.holders, key)
+		}
+		_ = json.NewEncoder(w).Encode(ok)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type ConsulLockerTestSuite struct {
+	suite.Suite
+	consul *fakeConsul
+	server *httptest.Server
+}
+
+func TestConsulLockerTestSuite(t *testing.T) {
+	suite.Run(t, new(ConsulLockerTestSuite))
+}
+
+func (suite *ConsulLockerTestSuite) SetupTest() {
+	suite.consul = newFakeConsul()
+	suite.server = httptest.NewServer(suite.consul)
+}
+
+func (suite *ConsulLockerTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *ConsulLockerTestSuite) TestItCanLockAndUnlockExclusively() {
+	ctx := context.Background()
+	first := NewConsulLocker(suite.server.URL, "acl", "locks/migrations", 0, nil)
+	second := NewConsulLocker(suite.server.URL, "acl", "locks/migrations", 0, nil)
+
+	suite.Require().NoError(first.Lock(ctx))
+	suite.Assert().ErrorIs(second.Lock(ctx), ErrLockHeld)
+	suite.Assert().ErrorIs(first.Lock(ctx), ErrAlreadyLocked)
+
+	// the session of the failed attempt must be destroyed
+	suite.Assert().Len(suite.consul.sessions, 1)
+
+	suite.Require().NoError(first.Unlock(ctx))
+	suite.Assert().Empty(suite.consul.sessions)
+	suite.Require().NoError(second.Lock(ctx))
+	suite.Require().NoError(second.Unlock(ctx))
+	suite.Assert().ErrorIs(second.Unlock(ctx), ErrNotLocked)
+
+	for _, token := range suite.consul.tokens {
+		suite.Assert().Equal("acl", token)
+	}
+}
+
+func (suite *ConsulLockerTestSuite) TestItReportsALockLostInTheMeantime() {
+	ctx := context.Background()
+	locker := NewConsulLocker(suite.server.URL, "", "locks/migrations", 0, nil)
+	suite.Require().NoError(locker.Lock(ctx))
+	suite.consul.holders["locks/migrations"] = "someone-else"
+
+	suite.Assert().ErrorIs(locker.Unlock(ctx), ErrNotLocked)
+}
+
+func (suite *ConsulLockerTestSuite) TestItRenewsTheSessionWhileTheLockIsHeld() {
+	ctx := context.Background()
+	locker := NewConsulLocker(suite.server.URL, "", "locks/migrations", 150*time.Millisecond, nil)
+	suite.Require().NoError(locker.Lock(ctx))
+	time.Sleep(250 * time.Millisecond)
+	suite.Require().NoError(locker.Unlock(ctx))
+
+	suite.consul.mu.Lock()
+	defer suite.consul.mu.Unlock()
+	suite.Assert().GreaterOrEqual(suite.consul.renewals, 2)
+}
+
+func (suite *ConsulLockerTestSuite) TestItFailsOnUnexpectedResponseStatus() {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte("Permission denied"))
+			},
+		),
+	)
+	defer server.Close()
+
+	err := NewConsulLocker(server.URL, "", "locks/migrations", 0, nil).Lock(context.Background())
+	suite.Assert().ErrorContains(err, "unexpected response status 403: Permission denied")
+	suite.Assert().NotErrorIs(err, ErrLockHeld)
+}
+
+func (suite *ConsulLockerTestSuite) TestItUsesTheDefaults() {
+	locker := NewConsulLocker("", "", "/locks/migrations", 0, nil)
+	suite.Assert().Equal(DefaultConsulAddress, locker.address)
+	suite.Assert().Equal(DefaultConsulLockTtl, locker.ttl)
+	suite.Assert().Equal("locks/migrations", locker.Key())
+}
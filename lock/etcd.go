@@ -0,0 +1,215 @@
+package lock
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEtcdLockTtl is the lease TTL used for etcd locks when none is provided
+const DefaultEtcdLockTtl = 15 * time.Minute
+
+// DefaultEtcdAddress is the etcd endpoint used when none is configured
+const DefaultEtcdAddress = "http://127.0.0.1:2379"
+
+// EtcdLocker is a Locker implementation based on an etcd key attached to a lease, through the
+// etcd v3 JSON gateway. The key is created in a transaction only if it does not exist, and it
+// is deleted when the lease is revoked or expires. The lease is kept alive while the lock is
+// held, so only the lock of a dead process expires after the TTL.
+type EtcdLocker struct {
+	address string
+	token   string
+	key     string
+	ttl     time.Duration
+	client  *http.Client
+	mu      sync.Mutex
+	leaseId string
+	renewal *renewal
+}
+
+// NewEtcdLocker builds a new EtcdLocker which locks the given key. If address is empty,
+// DefaultEtcdAddress is used. The token is sent as auth token and can be empty. If ttl is
+// not positive, DefaultEtcdLockTtl is used. If client is nil, http.DefaultClient is used.
+func NewEtcdLocker(
+	address string,
+	token string,
+	key string,
+	ttl time.Duration,
+	client *http.Client,
+) *EtcdLocker {
+	if address == "" {
+		address = DefaultEtcdAddress
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultEtcdLockTtl
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &EtcdLocker{
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		key:     key,
+		ttl:     ttl,
+		client:  client,
+	}
+}
+
+// Key returns the etcd key used as lock
+func (l *EtcdLocker) Key() string {
+	return l.key
+}
+
+type etcdCompare struct {
+	Key            string `json:"key"`
+	Result         string `json:"result"`
+	Target         string `json:"target"`
+	CreateRevision string `json:"create_revision"`
+}
+
+type etcdPutRequest struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Lease string `json:"lease"`
+}
+
+type etcdRequestOp struct {
+	RequestPut etcdPutRequest `json:"request_put"`
+}
+
+type etcdTxnRequest struct {
+	Compare []etcdCompare   `json:"compare"`
+	Success []etcdRequestOp `json:"success"`
+}
+
+// Lock implements the Locker.Lock method
+func (l *EtcdLocker) Lock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.leaseId != "" {
+		return ErrAlreadyLocked
+	}
+
+	var granted struct {
+		ID string `json:"ID"`
+	}
+	err := doJSON(
+		ctx, l.client, http.MethodPost, l.address+"/v3/lease/grant", l.headers(),
+		map[string]string{"TTL": strconv.Itoa(int(l.ttl.Seconds()))},
+		&granted,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to grant etcd lease for lock %s with error: %w", l.key, err)
+	}
+
+	encodedKey := base64.StdEncoding.EncodeToString([]byte(l.key))
+	var txn struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	err = doJSON(
+		ctx, l.client, http.MethodPost, l.address+"/v3/kv/txn", l.headers(),
+		etcdTxnRequest{
+			Compare: []etcdCompare{{encodedKey, "EQUAL", "CREATE", "0"}},
+			Success: []etcdRequestOp{
+				{
+					etcdPutRequest{
+						Key:   encodedKey,
+						Value: base64.StdEncoding.EncodeToString([]byte(granted.ID)),
+						Lease: granted.ID,
+					},
+				},
+			},
+		},
+		&txn,
+	)
+	if err != nil || !txn.Succeeded {
+		_ = l.revokeLease(ctx, granted.ID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to acquire etcd lock %s with error: %w", l.key, err)
+	}
+
+	if !txn.Succeeded {
+		return ErrLockHeld
+	}
+
+	l.leaseId = granted.ID
+	l.renewal = startRenewal(l.ttl, l.keepAlive)
+	return nil
+}
+
+// Unlock implements the Locker.Unlock method. Revoking the lease deletes the lock key.
+// It returns ErrNotLocked if the lease expired in the meantime (and the lock may be held
+// by someone else).
+func (l *EtcdLocker) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.leaseId == "" {
+		return ErrNotLocked
+	}
+
+	l.renewal.stop()
+	leaseId := l.leaseId
+	l.leaseId = ""
+
+	if err := l.revokeLease(ctx, leaseId); err != nil {
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return ErrNotLocked
+		}
+
+		return fmt.Errorf("failed to release etcd lock %s with error: %w", l.key, err)
+	}
+
+	return nil
+}
+
+// keepAlive refreshes the lease of the held lock. It is called from the renewal goroutine,
+// which is stopped (under l.mu) before the lease is cleared, so it can be read without locking.
+func (l *EtcdLocker) keepAlive(ctx context.Context) error {
+	var kept struct {
+		Result struct {
+			TTL string `json:"TTL"`
+		} `json:"result"`
+	}
+	err := doJSON(
+		ctx, l.client, http.MethodPost, l.address+"/v3/lease/keepalive", l.headers(),
+		map[string]string{"ID": l.leaseId}, &kept,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to keep alive etcd lease for lock %s with error: %w", l.key, err)
+	}
+
+	// the TTL of an expired or revoked lease is omitted (or zero) in the response
+	if ttl, _ := strconv.Atoi(kept.Result.TTL); ttl <= 0 {
+		return errLockLost
+	}
+
+	return nil
+}
+
+func (l *EtcdLocker) revokeLease(ctx context.Context, leaseId string) error {
+	return doJSON(
+		ctx, l.client, http.MethodPost, l.address+"/v3/lease/revoke", l.headers(),
+		map[string]string{"ID": leaseId}, nil,
+	)
+}
+
+func (l *EtcdLocker) headers() map[string]string {
+	if l.token == "" {
+		return nil
+	}
+	return map[string]string{"Authorization": l.token}
+}
@@ -0,0 +1,147 @@
+package lock
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeEtcd emulates the etcd v3 JSON gateway lease and txn endpoints used by EtcdLocker
+type fakeEtcd struct {
+	mu       sync.Mutex
+	leases   map[string]bool
+	keys     map[string]string
+	nextId   int
+	lastTtl  string
+	renewals int
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{leases: map[string]bool{}, keys: map[string]string{}}
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.lastTtl = req["TTL"]
+		f.nextId++
+		id := strconv.Itoa(f.nextId)
+		f.leases[id] = true
+		_ = json.NewEncoder(w).Encode(map[string]string{"ID": id, "TTL": req["TTL"]})
+	case "/v3/lease/keepalive":
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		result := map[string]string{"ID": req["ID"]}
+		if f.leases[req["ID"]] {
+			f.renewals++
+			result["TTL"] = f.lastTtl
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": result})
+	case "/v3/lease/revoke":
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if !f.leases[req["ID"]] {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"etcdserver: requested lease not found"}`))
+			return
+		}
+		delete(f.leases, req["ID"])
+		for key, lease := range f.keys {
+			if lease == req["ID"] {
+				delete(f.keys, key)
+			}
+		}
+		_, _ = w.Write([]byte("{}"))
+	case "/v3/kv/txn":
+		var req etcdTxnRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		key, _ := base64.StdEncoding.DecodeString(req.Compare[0].Key)
+		_, exists := f.keys[string(key)]
+		if !exists {
+			f.keys[string(key)] = req.Success[0].RequestPut.Lease
+		}
+		_ = json.NewEncoder(w).Encode(map[string]bool{"succeeded": !exists})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type EtcdLockerTestSuite struct {
+	suite.Suite
+	etcd   *fakeEtcd
+	server *httptest.Server
+}
+
+func TestEtcdLockerTestSuite(t *testing.T) {
+	suite.Run(t, new(EtcdLockerTestSuite))
+}
+
+func (suite *EtcdLockerTestSuite) SetupTest() {
+	suite.etcd = newFakeEtcd()
+	suite.server = httptest.NewServer(suite.etcd)
+}
+
+func (suite *EtcdLockerTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *EtcdLockerTestSuite) TestItCanLockAndUnlockExclusively() {
+	ctx := context.Background()
+	first := NewEtcdLocker(suite.server.URL, "", "/locks/migrations", 0, nil)
+	second := NewEtcdLocker(suite.server.URL, "", "/locks/migrations", 0, nil)
+
+	suite.Require().NoError(first.Lock(ctx))
+	suite.Assert().Equal("900", suite.etcd.lastTtl)
+	suite.Assert().ErrorIs(second.Lock(ctx), ErrLockHeld)
+	suite.Assert().ErrorIs(first.Lock(ctx), ErrAlreadyLocked)
+
+	// the lease of the failed attempt must be revoked
+	suite.Assert().Len(suite.etcd.leases, 1)
+
+	suite.Require().NoError(first.Unlock(ctx))
+	suite.Assert().Empty(suite.etcd.keys)
+	suite.Require().NoError(second.Lock(ctx))
+	suite.Require().NoError(second.Unlock(ctx))
+	suite.Assert().ErrorIs(second.Unlock(ctx), ErrNotLocked)
+}
+
+func (suite *EtcdLockerTestSuite) TestItReportsAnExpiredLease() {
+	ctx := context.Background()
+	locker := NewEtcdLocker(suite.server.URL, "", "/locks/migrations", 0, nil)
+	suite.Require().NoError(locker.Lock(ctx))
+	suite.etcd.leases = map[string]bool{}
+
+	suite.Assert().ErrorIs(locker.Unlock(ctx), ErrNotLocked)
+}
+
+func (suite *EtcdLockerTestSuite) TestItKeepsTheLeaseAliveWhileTheLockIsHeld() {
+	ctx := context.Background()
+	locker := NewEtcdLocker(suite.server.URL, "", "/locks/migrations", time.Second, nil)
+	suite.Require().NoError(locker.Lock(ctx))
+	time.Sleep(800 * time.Millisecond)
+	suite.Require().NoError(locker.Unlock(ctx))
+
+	suite.etcd.mu.Lock()
+	defer suite.etcd.mu.Unlock()
+	suite.Assert().GreaterOrEqual(suite.etcd.renewals, 2)
+}
+
+func (suite *EtcdLockerTestSuite) TestItUsesTheDefaults() {
+	locker := NewEtcdLocker("", "", "/locks/migrations", 0, nil)
+	suite.Assert().Equal(DefaultEtcdAddress, locker.address)
+	suite.Assert().Equal(DefaultEtcdLockTtl, locker.ttl)
+	suite.Assert().Equal("/locks/migrations", locker.Key())
+}
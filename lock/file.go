@@ -0,0 +1,190 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+)
+
+// FileLocker is a Locker implementation based on OS level file locks (flock on Unix,
+// LockFileEx on Windows). The lock is released by the OS if the process dies, so
+// a crashed run can't leave a stale lock behind. The lock file itself is not removed.
+//
+// The lock file records the holder process (see Holder). FileLocker implements Breaker, for
+// the locks which outlive their holder anyway (for example, inherited by a child process or
+// held on a network file system) or are held by a hung process.
+type FileLocker struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileLocker builds a new FileLocker which locks the file from the given path. The file
+// is created if it does not exist.
+func NewFileLocker(path string) *FileLocker {
+	return &FileLocker{path: path}
+}
+
+// Path returns the path of the lock file
+func (l *FileLocker) Path() string {
+	return l.path
+}
+
+// Lock implements the Locker.Lock method
+func (l *FileLocker) Lock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		return ErrAlreadyLocked
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file %s with error: %w", l.path, err)
+	}
+
+	if err = tryLockFile(file); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	writeHolder(file, execution.RunIdFrom(ctx))
+	l.file = file
+	return nil
+}
+
+// Unlock implements the Locker.Unlock method
+func (l *FileLocker) Unlock(_ context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrNotLocked
+	}
+
+	unlockErr := unlockFile(l.file)
+	closeErr := l.file.Close()
+	l.file = nil
+
+	if unlockErr != nil {
+		return fmt.Errorf("failed to unlock file %s with error: %w", l.path, unlockErr)
+	}
+
+	return closeErr
+}
+
+// Inspect implements the Breaker.Inspect method. If the lock is not held, the lock is
+// acquired and released right away, so a concurrent Lock call may fail with ErrLockHeld.
+func (l *FileLocker) Inspect(_ context.Context) (Info, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inspect()
+}
+
+func (l *FileLocker) inspect() (Info, error) {
+	if l.file != nil {
+		return Info{Held: true, Holder: readHolder(l.file)}, nil
+	}
+
+	file, err := os.OpenFile(l.path, os.O_RDWR, 0644)
+	if errors.Is(err, os.ErrNotExist) {
+		return Info{}, nil
+	}
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to open lock file %s with error: %w", l.path, err)
+	}
+
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	info := Info{Holder: readHolder(file)}
+	err = tryLockFile(file)
+	if errors.Is(err, ErrLockHeld) {
+		info.Held = true
+		info.Stale = info.Holder != nil && holderIsDead(*info.Holder)
+		return info, nil
+	}
+	if err != nil {
+		return Info{}, err
+	}
+
+	if err = unlockFile(file); err != nil {
+		return Info{}, fmt.Errorf("failed to unlock file %s with error: %w", l.path, err)
+	}
+	return info, nil
+}
+
+// Break implements the Breaker.Break method, by removing the lock file: the holder keeps its
+// lock on the removed file, while the next runs lock a new one. So, the lock of a live holder
+// should be broken (forced) only when the holder is hung.
+func (l *FileLocker) Break(_ context.Context, force bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		return ErrAlreadyLocked
+	}
+
+	info, err := l.inspect()
+	if err != nil || !info.Held {
+		return err
+	}
+
+	if !info.Stale && !force {
+		if info.Holder == nil {
+			return fmt.Errorf("%w, holder unknown", ErrLockHeld)
+		}
+		return fmt.Errorf(
+			"%w, by pid %d on %s since %s", ErrLockHeld, info.Holder.Pid, info.Holder.Hostname,
+			info.Holder.AcquiredAt.Format(time.RFC3339),
+		)
+	}
+
+	if err = os.Remove(l.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove lock file %s with error: %w", l.path, err)
+	}
+	return nil
+}
+
+// writeHolder records the current process, running the given run, as the holder of the lock,
+// in the lock file. The record is informative, so failing to write it doesn't fail the lock.
+func writeHolder(file *os.File, runId string) {
+	hostname, _ := os.Hostname()
+	contents, err := json.Marshal(
+		Holder{
+			Pid: os.Getpid(), Hostname: hostname, AcquiredAt: time.Now().UTC(), RunId: runId,
+		},
+	)
+
+	if err == nil && file.Truncate(0) == nil {
+		_, _ = file.WriteAt(contents, 0)
+	}
+}
+
+// readHolder reads the holder recorded in the lock file, nil if none can be read (for
+// example, on Windows the locked file range can't be read by the other processes)
+func readHolder(file *os.File) *Holder {
+	contents := make([]byte, 1024)
+	n, _ := file.ReadAt(contents, 0)
+
+	var holder Holder
+	if n == 0 || json.Unmarshal(contents[:n], &holder) != nil || holder.Pid <= 0 {
+		return nil
+	}
+	return &holder
+}
+
+// holderIsDead checks if the holder is a process of this host which is not running anymore
+func holderIsDead(holder Holder) bool {
+	hostname, err := os.Hostname()
+	return err == nil && hostname == holder.Hostname && !processAlive(holder.Pid)
+}
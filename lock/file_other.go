@@ -0,0 +1,21 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+func tryLockFile(file *os.File) error {
+	return fmt.Errorf("failed to lock file %s: %w", file.Name(), errors.ErrUnsupported)
+}
+
+func unlockFile(*os.File) error {
+	return errors.ErrUnsupported
+}
+
+func processAlive(int) bool {
+	return true
+}
@@ -0,0 +1,122 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/stretchr/testify/suite"
+)
+
+type FileLockerTestSuite struct {
+	suite.Suite
+	lockPath string
+}
+
+func TestFileLockerTestSuite(t *testing.T) {
+	suite.Run(t, new(FileLockerTestSuite))
+}
+
+func (suite *FileLockerTestSuite) SetupTest() {
+	suite.lockPath = filepath.Join(suite.T().TempDir(), "test.lock")
+}
+
+func (suite *FileLockerTestSuite) TestItCanLockAndUnlockExclusively() {
+	ctx := context.Background()
+	first := NewFileLocker(suite.lockPath)
+	second := NewFileLocker(suite.lockPath)
+
+	suite.Assert().Equal(suite.lockPath, first.Path())
+	suite.Require().NoError(first.Lock(ctx))
+	suite.Assert().ErrorIs(second.Lock(ctx), ErrLockHeld)
+
+	suite.Require().NoError(first.Unlock(ctx))
+	suite.Require().NoError(second.Lock(ctx))
+	suite.Assert().ErrorIs(first.Lock(ctx), ErrLockHeld)
+	suite.Require().NoError(second.Unlock(ctx))
+}
+
+func (suite *FileLockerTestSuite) TestItFailsToLockTwiceWithTheSameLocker() {
+	locker := NewFileLocker(suite.lockPath)
+	suite.Require().NoError(locker.Lock(context.Background()))
+	suite.Assert().ErrorIs(locker.Lock(context.Background()), ErrAlreadyLocked)
+	suite.Assert().NoError(locker.Unlock(context.Background()))
+}
+
+func (suite *FileLockerTestSuite) TestItFailsToUnlockWhenNotLocked() {
+	locker := NewFileLocker(suite.lockPath)
+	suite.Assert().ErrorIs(locker.Unlock(context.Background()), ErrNotLocked)
+}
+
+func (suite *FileLockerTestSuite) TestItFailsToLockWhenTheLockFileCanNotBeOpened() {
+	locker := NewFileLocker(filepath.Join(suite.T().TempDir(), "missing", "test.lock"))
+	err := locker.Lock(context.Background())
+	suite.Assert().ErrorContains(err, "failed to open lock file")
+	suite.Assert().NotErrorIs(err, ErrLockHeld)
+}
+
+func (suite *FileLockerTestSuite) TestItInspectsTheLockHolder() {
+	ctx := context.Background()
+	holder := NewFileLocker(suite.lockPath)
+	inspector := NewFileLocker(suite.lockPath)
+
+	info, err := inspector.Inspect(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(Info{}, info)
+
+	suite.Require().NoError(holder.Lock(execution.WithRunId(ctx, "run-1")))
+	info, err = inspector.Inspect(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().True(info.Held)
+	suite.Assert().False(info.Stale)
+	suite.Require().NotNil(info.Holder)
+	suite.Assert().Equal(os.Getpid(), info.Holder.Pid)
+	suite.Assert().Equal("run-1", info.Holder.RunId)
+
+	suite.Assert().ErrorIs(inspector.Break(ctx, false), ErrLockHeld)
+	suite.Require().NoError(holder.Unlock(ctx))
+
+	info, err = inspector.Inspect(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().False(info.Held)
+	suite.Assert().NotNil(info.Holder)
+	suite.Assert().NoError(inspector.Break(ctx, false))
+	suite.Assert().FileExists(suite.lockPath)
+}
+
+func (suite *FileLockerTestSuite) TestItBreaksStaleAndForcedLocks() {
+	if runtime.GOOS == "windows" {
+		suite.T().Skip("the holder processes are not checked on Windows")
+	}
+
+	ctx := context.Background()
+	holder := NewFileLocker(suite.lockPath)
+	breaker := NewFileLocker(suite.lockPath)
+	suite.Require().NoError(holder.Lock(ctx))
+	defer func() {
+		_ = holder.Unlock(ctx)
+	}()
+
+	hostname, _ := os.Hostname()
+	deadHolder, _ := json.Marshal(Holder{Pid: math.MaxInt32, Hostname: hostname})
+	suite.Require().NoError(os.WriteFile(suite.lockPath, deadHolder, 0644))
+
+	info, err := breaker.Inspect(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().True(info.Stale)
+
+	suite.Require().NoError(breaker.Break(ctx, false))
+	suite.Assert().NoFileExists(suite.lockPath)
+	suite.Require().NoError(breaker.Lock(ctx))
+	suite.Assert().ErrorIs(breaker.Break(ctx, true), ErrAlreadyLocked)
+
+	forced := NewFileLocker(suite.lockPath)
+	suite.Require().NoError(forced.Break(ctx, true))
+	suite.Assert().NoFileExists(suite.lockPath)
+	suite.Assert().NoError(breaker.Unlock(ctx))
+}
@@ -0,0 +1,71 @@
+// Package lock provides the locking abstraction used to run migration commands exclusively,
+// along with a file based implementation which works on Unix (flock) and Windows (LockFileEx)
+// and distributed implementations based on Consul, etcd and Redis (build tag redis).
+//
+// Locks are non-blocking: Lock fails with ErrLockHeld if the lock is held by someone else.
+// Wrap a locker with NewWaitingLocker to wait for the lock instead.
+package lock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrLockHeld is returned when the lock is held by another process (or another locker)
+	ErrLockHeld = errors.New("lock is held by another process")
+
+	// ErrAlreadyLocked is returned when trying to lock a locker which already holds the lock
+	ErrAlreadyLocked = errors.New("lock is already held by this locker")
+
+	// ErrNotLocked is returned when trying to unlock a locker which does not hold the lock
+	ErrNotLocked = errors.New("lock is not held by this locker")
+)
+
+// Locker represents an exclusive, non-blocking lock
+type Locker interface {
+	// Lock must acquire the lock, without waiting for it. It must return ErrLockHeld if the
+	// lock is held by another process.
+	Lock(ctx context.Context) error
+
+	// Unlock must release the lock. It must return ErrNotLocked if the lock is not held.
+	Unlock(ctx context.Context) error
+}
+
+// Holder describes the process which holds (or last held) a lock
+type Holder struct {
+	Pid        int       `json:"pid"`
+	Hostname   string    `json:"hostname"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+
+	// RunId is the ID of the run which acquired the lock (see execution.RunIdFrom), if known
+	RunId string `json:"runId,omitempty"`
+}
+
+// Info is the state of a lock, as inspected by a Breaker
+type Info struct {
+	// Held is true if the lock is held by a locker
+	Held bool
+
+	// Holder is the process which holds the lock (or which last held it, if the lock is not
+	// held), nil if unknown
+	Holder *Holder
+
+	// Stale is true if the lock is held, but the holder process is known to be dead (for
+	// example, when a child process inherited the lock or the lock is on a network file
+	// system)
+	Stale bool
+}
+
+// Breaker is an optional interface of the lockers which can inspect the lock and break it,
+// when it is held by another process which can't release it
+type Breaker interface {
+	// Inspect must return the state of the lock, without acquiring it
+	Inspect(ctx context.Context) (Info, error)
+
+	// Break must release the lock held by another process. Unless force is true, it must
+	// refuse to break a lock which is not stale with ErrLockHeld. Breaking a lock which is
+	// not held must succeed.
+	Break(ctx context.Context, force bool) error
+}
@@ -0,0 +1,144 @@
+//go:build redis
+
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisLockTtl is the expiration used for Redis locks when none is provided
+const DefaultRedisLockTtl = 15 * time.Minute
+
+// releaseScript deletes the lock key only if it still holds the token of the releasing locker,
+// so a locker never releases a lock which expired and was acquired by someone else
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// renewScript extends the expiration of the lock key only if it still holds the token of the
+// renewing locker
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// RedisLocker is a Locker implementation based on a Redis key, acquired with SET NX PX and
+// released only by the locker holding it (via a random token check). The key expiration is
+// extended while the lock is held, so only the lock of a dead process expires after the TTL.
+type RedisLocker struct {
+	client  redis.UniversalClient
+	key     string
+	ttl     time.Duration
+	mu      sync.Mutex
+	token   string
+	renewal *renewal
+}
+
+// NewRedisLocker builds a new RedisLocker which locks the given key. If ttl is not positive,
+// DefaultRedisLockTtl is used.
+func NewRedisLocker(client redis.UniversalClient, key string, ttl time.Duration) *RedisLocker {
+	if ttl <= 0 {
+		ttl = DefaultRedisLockTtl
+	}
+
+	return &RedisLocker{client: client, key: key, ttl: ttl}
+}
+
+// Key returns the Redis key used as lock
+func (l *RedisLocker) Key() string {
+	return l.key
+}
+
+// Lock implements the Locker.Lock method
+func (l *RedisLocker) Lock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.token != "" {
+		return ErrAlreadyLocked
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate lock token with error: %w", err)
+	}
+
+	acquired, err := l.client.SetNX(ctx, l.key, token, l.ttl).Result()
+	if err != nil {
+		return fmt.Errorf("failed to acquire redis lock %s with error: %w", l.key, err)
+	}
+
+	if !acquired {
+		return ErrLockHeld
+	}
+
+	l.token = token
+	l.renewal = startRenewal(l.ttl, l.renew)
+	return nil
+}
+
+// Unlock implements the Locker.Unlock method. It returns ErrNotLocked if the key expired
+// in the meantime (and may be held by someone else).
+func (l *RedisLocker) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.token == "" {
+		return ErrNotLocked
+	}
+
+	l.renewal.stop()
+	token := l.token
+	l.token = ""
+
+	released, err := releaseScript.Run(ctx, l.client, []string{l.key}, token).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to release redis lock %s with error: %w", l.key, err)
+	}
+
+	if released == 0 {
+		return ErrNotLocked
+	}
+
+	return nil
+}
+
+// renew extends the expiration of the lock key, while it still holds the token of the locker.
+// It is called from the renewal goroutine, which is stopped (under l.mu) before the token
+// is cleared, so the token can be read without locking.
+func (l *RedisLocker) renew(ctx context.Context) error {
+	renewed, err := renewScript.Run(
+		ctx, l.client, []string{l.key}, l.token, l.ttl.Milliseconds(),
+	).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to renew redis lock %s with error: %w", l.key, err)
+	}
+
+	if renewed == 0 {
+		return errLockLost
+	}
+
+	return nil
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
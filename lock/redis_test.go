@@ -0,0 +1,112 @@
+//go:build redis
+
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const RedisLockKey = "migrations-lock"
+
+type RedisLockerTestSuite struct {
+	suite.Suite
+	client    *redis.Client
+	container testcontainers.Container
+}
+
+func TestRedisLockerTestSuite(t *testing.T) {
+	suite.Run(t, new(RedisLockerTestSuite))
+}
+
+func (suite *RedisLockerTestSuite) SetupSuite() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	redisC, err := testcontainers.GenericContainer(
+		ctx, testcontainers.GenericContainerRequest{
+			ContainerRequest: testcontainers.ContainerRequest{
+				Image:        "redis:7",
+				ExposedPorts: []string{"6379/tcp"},
+				WaitingFor:   wait.ForLog("Ready to accept connections"),
+			},
+			Started: true,
+		},
+	)
+	suite.Require().NoError(err)
+	suite.container = redisC
+
+	endpoint, err := redisC.PortEndpoint(ctx, "6379/tcp", "")
+	suite.Require().NoError(err)
+
+	suite.client = redis.NewClient(&redis.Options{Addr: endpoint})
+	suite.Require().NoError(suite.client.Ping(ctx).Err())
+}
+
+func (suite *RedisLockerTestSuite) TearDownSuite() {
+	_ = suite.client.Close()
+	if suite.container != nil {
+		_ = suite.container.Terminate(context.Background())
+	}
+}
+
+func (suite *RedisLockerTestSuite) SetupTest() {
+	_ = suite.client.Del(context.Background(), RedisLockKey).Err()
+}
+
+func (suite *RedisLockerTestSuite) TestItCanLockAndUnlockExclusively() {
+	ctx := context.Background()
+	first := NewRedisLocker(suite.client, RedisLockKey, time.Minute)
+	second := NewRedisLocker(suite.client, RedisLockKey, time.Minute)
+
+	suite.Require().NoError(first.Lock(ctx))
+	suite.Assert().ErrorIs(second.Lock(ctx), ErrLockHeld)
+	suite.Assert().ErrorIs(first.Lock(ctx), ErrAlreadyLocked)
+
+	ttl, _ := suite.client.PTTL(ctx, RedisLockKey).Result()
+	suite.Assert().Greater(ttl, time.Duration(0))
+
+	suite.Require().NoError(first.Unlock(ctx))
+	suite.Require().NoError(second.Lock(ctx))
+	suite.Require().NoError(second.Unlock(ctx))
+	suite.Assert().ErrorIs(second.Unlock(ctx), ErrNotLocked)
+}
+
+func (suite *RedisLockerTestSuite) TestItDoesNotReleaseALockHeldBySomeoneElse() {
+	ctx := context.Background()
+	expired := NewRedisLocker(suite.client, RedisLockKey, time.Minute)
+	suite.Require().NoError(expired.Lock(ctx))
+	// the key of a held lock is renewed, so emulate its expiration
+	suite.Require().NoError(suite.client.Del(ctx, RedisLockKey).Err())
+
+	holder := NewRedisLocker(suite.client, RedisLockKey, time.Minute)
+	suite.Require().NoError(holder.Lock(ctx))
+
+	suite.Assert().ErrorIs(expired.Unlock(ctx), ErrNotLocked)
+	suite.Assert().Equal(int64(1), suite.client.Exists(ctx, RedisLockKey).Val())
+	suite.Assert().NoError(holder.Unlock(ctx))
+}
+
+func (suite *RedisLockerTestSuite) TestItRenewsTheLockWhileItIsHeld() {
+	ctx := context.Background()
+	holder := NewRedisLocker(suite.client, RedisLockKey, 300*time.Millisecond)
+	suite.Require().NoError(holder.Lock(ctx))
+	time.Sleep(time.Second)
+
+	second := NewRedisLocker(suite.client, RedisLockKey, time.Minute)
+	suite.Assert().ErrorIs(second.Lock(ctx), ErrLockHeld)
+	suite.Require().NoError(holder.Unlock(ctx))
+	suite.Assert().Equal(int64(0), suite.client.Exists(ctx, RedisLockKey).Val())
+}
+
+func (suite *RedisLockerTestSuite) TestItUsesTheDefaultTtl() {
+	locker := NewRedisLocker(suite.client, RedisLockKey, 0)
+	suite.Assert().Equal(DefaultRedisLockTtl, locker.ttl)
+	suite.Assert().Equal(RedisLockKey, locker.Key())
+}
@@ -0,0 +1,54 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// errLockLost is returned by a renew function when the lock is no longer held by the locker,
+// which stops the renewal
+var errLockLost = errors.New("lock lost")
+
+// renewal extends the TTL of a held lock, in a separate goroutine, until it is stopped
+type renewal struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startRenewal calls renew every third of the ttl, so a lock held by a running process
+// never expires, while the lock of a dead process still does after the ttl. Failed renewals
+// are retried at the next interval; the renewal ends when renew returns errLockLost.
+func startRenewal(ttl time.Duration, renew func(ctx context.Context) error) *renewal {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &renewal{cancel: cancel, done: make(chan struct{})}
+	interval := ttl / 3
+
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				renewCtx, cancelRenew := context.WithTimeout(ctx, interval)
+				err := renew(renewCtx)
+				cancelRenew()
+				if errors.Is(err, errLockLost) {
+					return
+				}
+			}
+		}
+	}()
+
+	return r
+}
+
+// stop ends the renewal and waits for a renewal in progress to finish
+func (r *renewal) stop() {
+	r.cancel()
+	<-r.done
+}
@@ -0,0 +1,50 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RenewalTestSuite struct {
+	suite.Suite
+}
+
+func TestRenewalTestSuite(t *testing.T) {
+	suite.Run(t, new(RenewalTestSuite))
+}
+
+func (suite *RenewalTestSuite) TestItRenewsUntilStopped() {
+	var calls atomic.Int32
+	r := startRenewal(
+		30*time.Millisecond, func(ctx context.Context) error {
+			calls.Add(1)
+			return errors.New("temporary failure")
+		},
+	)
+	time.Sleep(100 * time.Millisecond)
+	r.stop()
+
+	renewed := calls.Load()
+	suite.Assert().GreaterOrEqual(renewed, int32(3))
+	time.Sleep(30 * time.Millisecond)
+	suite.Assert().Equal(renewed, calls.Load())
+}
+
+func (suite *RenewalTestSuite) TestItStopsOnceTheLockIsLost() {
+	var calls atomic.Int32
+	r := startRenewal(
+		30*time.Millisecond, func(ctx context.Context) error {
+			calls.Add(1)
+			return errLockLost
+		},
+	)
+	time.Sleep(100 * time.Millisecond)
+
+	suite.Assert().Equal(int32(1), calls.Load())
+	r.stop()
+}
@@ -0,0 +1,52 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The bounds of the delay between the attempts of WaitingLocker.Lock, which doubles after each
+// attempt
+const (
+	minWaitInterval = 50 * time.Millisecond
+	maxWaitInterval = 2 * time.Second
+)
+
+// WaitingLocker wraps a locker so that Lock waits for the lock held by another process, instead
+// of failing immediately: it retries with an exponential backoff until the lock is acquired,
+// the timeout is exceeded or the context is done, and fails with ErrLockHeld in both latter
+// cases. The other methods are the ones of the wrapped locker.
+type WaitingLocker struct {
+	Locker
+	timeout time.Duration
+}
+
+// NewWaitingLocker builds a locker which waits for at most the timeout for the lock of locker
+func NewWaitingLocker(locker Locker, timeout time.Duration) *WaitingLocker {
+	return &WaitingLocker{Locker: locker, timeout: timeout}
+}
+
+func (l *WaitingLocker) Lock(ctx context.Context) error {
+	deadline := time.Now().Add(l.timeout)
+	interval := minWaitInterval
+	for {
+		err := l.Locker.Lock(ctx)
+		if !errors.Is(err, ErrLockHeld) {
+			return err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w, waited for %s", err, l.timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w, stopped waiting: %w", err, ctx.Err())
+		case <-time.After(min(interval, remaining)):
+		}
+		interval = min(2*interval, maxWaitInterval)
+	}
+}
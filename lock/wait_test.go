@@ -0,0 +1,56 @@
+package lock
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type WaitingLockerTestSuite struct {
+	suite.Suite
+	lockPath string
+}
+
+func TestWaitingLockerTestSuite(t *testing.T) {
+	suite.Run(t, new(WaitingLockerTestSuite))
+}
+
+func (suite *WaitingLockerTestSuite) SetupTest() {
+	suite.lockPath = filepath.Join(suite.T().TempDir(), "test.lock")
+}
+
+func (suite *WaitingLockerTestSuite) TestItWaitsForTheLockToBeReleased() {
+	ctx := context.Background()
+	holder := NewFileLocker(suite.lockPath)
+	suite.Require().NoError(holder.Lock(ctx))
+	time.AfterFunc(200*time.Millisecond, func() { _ = holder.Unlock(ctx) })
+
+	waiting := NewWaitingLocker(NewFileLocker(suite.lockPath), 5*time.Second)
+	suite.Require().NoError(waiting.Lock(ctx))
+	suite.Assert().NoError(waiting.Unlock(ctx))
+}
+
+func (suite *WaitingLockerTestSuite) TestItFailsOnceTheTimeoutIsExceeded() {
+	ctx := context.Background()
+	holder := NewFileLocker(suite.lockPath)
+	suite.Require().NoError(holder.Lock(ctx))
+	defer func() {
+		_ = holder.Unlock(ctx)
+	}()
+
+	start := time.Now()
+	waiting := NewWaitingLocker(NewFileLocker(suite.lockPath), 300*time.Millisecond)
+	err := waiting.Lock(ctx)
+	suite.Assert().ErrorIs(err, ErrLockHeld)
+	suite.Assert().ErrorContains(err, "waited for 300ms")
+	suite.Assert().GreaterOrEqual(time.Since(start), 300*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = NewWaitingLocker(NewFileLocker(suite.lockPath), time.Minute).Lock(cancelled)
+	suite.Assert().ErrorIs(err, ErrLockHeld)
+	suite.Assert().ErrorIs(err, context.Canceled)
+}
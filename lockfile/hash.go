@@ -0,0 +1,240 @@
+package lockfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// The names of the built-in hashing strategies (see NewHasher)
+const (
+	HashingDeclared = "declared"
+	HashingFile     = "file"
+	HashingGoAst    = "go-ast"
+	HashingSql      = "sql"
+)
+
+// Hashings lists the names of the built-in hashing strategies
+var Hashings = []string{HashingDeclared, HashingFile, HashingGoAst, HashingSql}
+
+// Hasher is a hashing strategy: it computes the hash of the content of a migration, covered by
+// its pinned checksum. Only the changes of the hashed content change the checksum, so choose
+// the strategy which ignores the cosmetic changes (for example, fixed comments) of the
+// migrations. The lockfile records the name of its strategy, so it is checked with the same one.
+type Hasher interface {
+	// Name identifies the strategy in the lockfiles
+	Name() string
+
+	// Hash returns the hash of the content of the migration
+	Hash(mig migration.Migration) (string, error)
+}
+
+// NewHasher builds the built-in hashing strategy with the given name. The file and go-ast
+// strategies read the migration files from dir.
+func NewHasher(name string, dir migration.MigrationsDirPath) (Hasher, error) {
+	switch name {
+	case HashingDeclared, "":
+		return DeclaredHasher{}, nil
+	case HashingFile:
+		return FileHasher{Dir: dir}, nil
+	case HashingGoAst:
+		return GoAstHasher{Dir: dir}, nil
+	case HashingSql:
+		return SqlHasher{}, nil
+	default:
+		return nil, fmt.Errorf(
+			"unknown hashing strategy %q, expected one of: %s",
+			name, strings.Join(Hashings, ", "),
+		)
+	}
+}
+
+// DeclaredHasher hashes the checksum the migrations declare by implementing Checksummer. It is
+// the default strategy. The content of the other migrations is not hashed.
+type DeclaredHasher struct{}
+
+func (h DeclaredHasher) Name() string {
+	return HashingDeclared
+}
+
+func (h DeclaredHasher) Hash(mig migration.Migration) (string, error) {
+	if checksummer, ok := mig.(Checksummer); ok {
+		return checksummer.Checksum(), nil
+	}
+	return "", nil
+}
+
+// FileHasher hashes the bytes of the migration files (version_<version>.go, named or not) of
+// Dir, so any change of a file, even a cosmetic one, changes the checksum of its migration
+type FileHasher struct {
+	Dir migration.MigrationsDirPath
+}
+
+func (h FileHasher) Name() string {
+	return HashingFile
+}
+
+func (h FileHasher) Hash(mig migration.Migration) (string, error) {
+	source, err := readMigrationFile(h.Dir, mig)
+	if err != nil {
+		return "", err
+	}
+	return hashOf(source), nil
+}
+
+// GoAstHasher hashes the syntax tree of the migration files (version_<version>.go, named or
+// not) of Dir, without the comments and the formatting, so fixing a comment or reformatting the
+// code doesn't change the checksum of the migration
+type GoAstHasher struct {
+	Dir migration.MigrationsDirPath
+}
+
+func (h GoAstHasher) Name() string {
+	return HashingGoAst
+}
+
+func (h GoAstHasher) Hash(mig migration.Migration) (string, error) {
+	source, err := readMigrationFile(h.Dir, mig)
+	if err != nil {
+		return "", err
+	}
+
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "", source, parser.SkipObjectResolution)
+	if err != nil {
+		return "", fmt.Errorf(
+			"failed to parse the file of the migration %d with error: %w", mig.Version(), err,
+		)
+	}
+
+	var printed bytes.Buffer
+	if err = format.Node(&printed, fileSet, file); err != nil {
+		return "", fmt.Errorf(
+			"failed to print the file of the migration %d with error: %w", mig.Version(), err,
+		)
+	}
+
+	// the tokens of the printed tree, without the line breaks left by the blank lines and
+	// the wrapped lines of the source
+	var tokens strings.Builder
+	var scan scanner.Scanner
+	printedFile := fileSet.AddFile("", -1, printed.Len())
+	scan.Init(printedFile, printed.Bytes(), nil, 0)
+	for {
+		_, tok, lit := scan.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		tokens.WriteString(tok.String() + " " + lit + "\n")
+	}
+	return hashOf([]byte(tokens.String())), nil
+}
+
+// SqlSource is an optional interface for the migrations which declare the SQL they run, hashed
+// by the SqlHasher
+type SqlSource interface {
+	Sql() string
+}
+
+// SqlHasher hashes the SQL the migrations declare by implementing SqlSource, normalized: without
+// its comments and with its whitespace collapsed, so fixing a comment or reindenting a statement
+// doesn't change the checksum of the migration. The content of the other migrations is not
+// hashed.
+type SqlHasher struct{}
+
+func (h SqlHasher) Name() string {
+	return HashingSql
+}
+
+func (h SqlHasher) Hash(mig migration.Migration) (string, error) {
+	source, ok := mig.(SqlSource)
+	if !ok {
+		return "", nil
+	}
+	return hashOf([]byte(NormalizeSql(source.Sql()))), nil
+}
+
+// NormalizeSql strips the comments (-- and /* */) of the SQL and collapses its whitespace to
+// single spaces, outside the quoted strings and identifiers
+func NormalizeSql(sql string) string {
+	var normalized strings.Builder
+	space := false
+	for i := 0; i < len(sql); i++ {
+		char := sql[i]
+		switch {
+		case char == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end == -1 {
+				end = len(sql) - i
+			}
+			i += end - 1
+			space = true
+			continue
+		case char == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				end = len(sql) - i - 4
+			}
+			i += end + 3
+			space = true
+			continue
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			space = true
+			continue
+		}
+
+		if space && normalized.Len() > 0 {
+			normalized.WriteByte(' ')
+		}
+		space = false
+
+		if char != '\'' && char != '"' && char != '`' {
+			normalized.WriteByte(char)
+			continue
+		}
+
+		// the quoted strings and identifiers are kept as they are, up to their closing quote
+		end := strings.IndexByte(sql[i+1:], char)
+		if end == -1 {
+			end = len(sql) - i - 2
+		}
+		normalized.WriteString(sql[i : i+end+2])
+		i += end + 1
+	}
+	return normalized.String()
+}
+
+// readMigrationFile reads the file of the migration (named or not) from the migrations
+// directory
+func readMigrationFile(dir migration.MigrationsDirPath, mig migration.Migration) ([]byte, error) {
+	fileName, ok := migration.FileNameInDir(dir, mig.Version())
+	if !ok {
+		fileName = migration.FileName(mig.Version(), "")
+	}
+	source, err := os.ReadFile(filepath.Join(string(dir), fileName))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to read the file of the migration %d with error: %w", mig.Version(), err,
+		)
+	}
+	return source, nil
+}
+
+// hashOf returns the hex encoded SHA-256 hash of the content
+func hashOf(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
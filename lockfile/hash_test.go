@@ -0,0 +1,173 @@
+package lockfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type HashTestSuite struct {
+	suite.Suite
+}
+
+func TestHashTestSuite(t *testing.T) {
+	suite.Run(t, new(HashTestSuite))
+}
+
+// sourceMigration declares the SQL it runs
+type sourceMigration struct {
+	migration.DummyMigration
+	sql string
+}
+
+func (m *sourceMigration) Sql() string {
+	return m.sql
+}
+
+const migrationSource = `package migrations
+
+import "context"
+
+// Migration1 creates the users table
+type Migration1 struct{}
+
+func (m *Migration1) Up(ctx context.Context) error {
+	return exec(ctx, "CREATE TABLE users (id INT)")
+}
+`
+
+func (suite *HashTestSuite) writeMigration(dir string, source string) {
+	suite.Require().NoError(
+		os.WriteFile(filepath.Join(dir, "version_1.go"), []byte(source), 0o644),
+	)
+}
+
+func (suite *HashTestSuite) TestItIgnoresTheCosmeticChangesOfTheMigrationFiles() {
+	dir := suite.T().TempDir()
+	mig := migration.NewDummyMigration(1)
+	goAst := GoAstHasher{Dir: migration.MigrationsDirPath(dir)}
+	file := FileHasher{Dir: migration.MigrationsDirPath(dir)}
+
+	suite.writeMigration(dir, migrationSource)
+	astHash, err := goAst.Hash(mig)
+	suite.Require().NoError(err)
+	fileHash, err := file.Hash(mig)
+	suite.Require().NoError(err)
+
+	// a fixed comment and a reformatted function only change the bytes of the file
+	suite.writeMigration(
+		dir,
+		`package migrations
+
+import "context"
+
+// Migration1 creates the table of the users
+type Migration1 struct{}
+
+func (m *Migration1) Up(ctx context.Context) error {
+	// the users are identified by their id
+
+	return exec(ctx,
+		"CREATE TABLE users (id INT)")
+}
+`,
+	)
+	suite.Assert().Equal(astHash, suite.hash(goAst, mig))
+	suite.Assert().NotEqual(fileHash, suite.hash(file, mig))
+
+	// a changed statement changes both
+	suite.writeMigration(dir, `package migrations
+
+import "context"
+
+// Migration1 creates the users table
+type Migration1 struct{}
+
+func (m *Migration1) Up(ctx context.Context) error {
+	return exec(ctx, "CREATE TABLE users (id BIGINT)")
+}
+`)
+	suite.Assert().NotEqual(astHash, suite.hash(goAst, mig))
+	suite.Assert().NotEqual(fileHash, suite.hash(file, mig))
+
+	suite.writeMigration(dir, "package migrations\n\nfunc {")
+	_, err = goAst.Hash(mig)
+	suite.Assert().ErrorContains(err, "failed to parse the file of the migration 1")
+
+	_, err = file.Hash(migration.NewDummyMigration(2))
+	suite.Assert().ErrorContains(err, "failed to read the file of the migration 2")
+}
+
+func (suite *HashTestSuite) hash(hasher Hasher, mig migration.Migration) string {
+	hash, err := hasher.Hash(mig)
+	suite.Require().NoError(err)
+	return hash
+}
+
+func (suite *HashTestSuite) TestItNormalizesTheSql() {
+	suite.Assert().Equal(
+		"CREATE TABLE users ( id INT, name VARCHAR(10) DEFAULT 'a  -- b' )",
+		NormalizeSql(
+			"-- the users\nCREATE  TABLE users (\n\tid INT, /* the key */\n"+
+				"\tname VARCHAR(10) DEFAULT 'a  -- b'\n)\n",
+		),
+	)
+	suite.Assert().Equal("SELECT 1", NormalizeSql("SELECT 1 -- unterminated"))
+	suite.Assert().Equal("SELECT 1", NormalizeSql("SELECT 1 /* unterminated"))
+	suite.Assert().Equal(`SELECT "a b`, NormalizeSql(`SELECT "a b`))
+
+	hasher := SqlHasher{}
+	mig := &sourceMigration{*migration.NewDummyMigration(1), "SELECT 1 -- one"}
+	suite.Assert().Equal(
+		suite.hash(hasher, mig),
+		suite.hash(hasher, &sourceMigration{*migration.NewDummyMigration(1), "SELECT\n  1"}),
+	)
+	suite.Assert().NotEqual(
+		suite.hash(hasher, mig),
+		suite.hash(hasher, &sourceMigration{*migration.NewDummyMigration(1), "SELECT 2"}),
+	)
+	suite.Assert().Empty(suite.hash(hasher, migration.NewDummyMigration(1)))
+}
+
+func (suite *HashTestSuite) TestItChecksTheLockfileWithItsHashingStrategy() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(&sourceMigration{*migration.NewDummyMigration(1), "SELECT 1"})
+
+	lockfile, err := New(registry, SqlHasher{})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(HashingSql, lockfile.Hashing)
+
+	planned := []migration.Migration{
+		&sourceMigration{*migration.NewDummyMigration(1), "SELECT   1 -- fixed"},
+	}
+	suite.Assert().NoError(lockfile.Check(planned, SqlHasher{}))
+	suite.Assert().EqualError(
+		lockfile.Check(planned, nil),
+		"the run is not pinned by the lockfile: the checksums are computed with the sql "+
+			"hashing strategy, not declared",
+	)
+
+	hasher, err := NewHasher("", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(HashingDeclared, hasher.Name())
+	_, err = NewHasher("md5", "")
+	suite.Assert().EqualError(
+		err, `unknown hashing strategy "md5", expected one of: declared, file, go-ast, sql`,
+	)
+}
+
+func (suite *HashTestSuite) TestItHashesTheNamedMigrationFiles() {
+	dir := suite.T().TempDir()
+	mig := migration.NewDummyMigration(1)
+	file := FileHasher{Dir: migration.MigrationsDirPath(dir)}
+
+	suite.writeMigration(dir, migrationSource)
+	fileHash := suite.hash(file, mig)
+	suite.Require().NoError(
+		os.Rename(filepath.Join(dir, "version_1.go"), filepath.Join(dir, "version_1_add_users.go")),
+	)
+	suite.Assert().Equal(fileHash, suite.hash(file, mig))
+}
@@ -0,0 +1,184 @@
+// Package lockfile pins the migrations of a release in a lockfile (DefaultFile), usually
+// generated in CI when the release is cut: the versions of the registered migrations and their
+// checksums. The production runner, given the lockfile, executes only the pinned migrations: it
+// refuses a run which would execute a migration the lockfile doesn't list (for example, one
+// merged after the release was cut), or one whose checksum changed.
+//
+// The checksum of a migration covers its version, its type and its metadata, along with the
+// hash of its content computed by the hashing strategy of the lockfile (see Hasher): by
+// default, the checksum it declares by implementing Checksummer (for example, the checksum of
+// the SQL it embeds). The code of the migrations is compiled in the binary, so pin the binary
+// which runs the release in the deployment pipeline too, or pin the migration files with the
+// file or go-ast strategies.
+package lockfile
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// DefaultFile is the conventional name of the lockfile
+const DefaultFile = "migrations.lock"
+
+// FormatVersion is the version of the lockfile format written by this package
+const FormatVersion = 1
+
+// ErrNotPinned is returned by Lockfile.Check when a migration of the run is not pinned by the
+// lockfile, or doesn't match its pinned checksum
+var ErrNotPinned = errors.New("the run is not pinned by the lockfile")
+
+// Checksummer is an optional interface for the migrations which declare the checksum of their
+// content, hashed by the DeclaredHasher
+type Checksummer interface {
+	Checksum() string
+}
+
+// Pin is a pinned migration
+type Pin struct {
+	Version  uint64 `json:"version"`
+	Checksum string `json:"checksum"`
+}
+
+// Lockfile holds the migrations pinned for a release
+type Lockfile struct {
+	FormatVersion int       `json:"formatVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+
+	// Hashing is the name of the hashing strategy of the checksums (see Hasher). Empty for
+	// the default one.
+	Hashing string `json:"hashing,omitempty"`
+
+	// Migrations holds the pinned migrations, in version order
+	Migrations []Pin `json:"migrations"`
+}
+
+// New builds the lockfile pinning all the registered migrations, with the checksums computed
+// by the hashing strategy (the DeclaredHasher, if nil)
+func New(registry migration.MigrationsRegistry, hasher Hasher) (Lockfile, error) {
+	hasher = defaultHasher(hasher)
+	migrations := registry.OrderedMigrations()
+	lockfile := Lockfile{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Hashing:       hasher.Name(),
+		Migrations:    make([]Pin, len(migrations)),
+	}
+	for i, mig := range migrations {
+		checksum, err := ChecksumWith(mig, hasher)
+		if err != nil {
+			return Lockfile{}, err
+		}
+		lockfile.Migrations[i] = Pin{Version: mig.Version(), Checksum: checksum}
+	}
+	return lockfile, nil
+}
+
+// Checksum computes the checksum of the migration with the DeclaredHasher (see ChecksumWith)
+func Checksum(mig migration.Migration) string {
+	checksum, _ := ChecksumWith(mig, DeclaredHasher{})
+	return checksum
+}
+
+// ChecksumWith computes the hex encoded SHA-256 checksum of the migration: its version, its
+// type, its metadata and the hash of its content computed by the hashing strategy
+func ChecksumWith(mig migration.Migration, hasher Hasher) (string, error) {
+	content, err := hasher.Hash(mig)
+	if err != nil {
+		return "", fmt.Errorf(
+			"failed to compute the checksum of the migration %d with error: %w",
+			mig.Version(), err,
+		)
+	}
+
+	metadata, _ := migration.MetadataOf(mig)
+	encoded, _ := json.Marshal(metadata)
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%d %T %s\n", mig.Version(), mig, encoded)
+	_, _ = io.WriteString(hash, content+"\n")
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// defaultHasher returns the hasher, or the DeclaredHasher if it is nil
+func defaultHasher(hasher Hasher) Hasher {
+	if hasher == nil {
+		return DeclaredHasher{}
+	}
+	return hasher
+}
+
+// Check verifies that the migrations of a run are all pinned, with their pinned checksums
+// computed by the hashing strategy (the DeclaredHasher, if nil), which must be the one of the
+// lockfile. Fails with ErrNotPinned, describing all the differences, otherwise.
+func (l Lockfile) Check(planned []migration.Migration, hasher Hasher) error {
+	if l.FormatVersion != FormatVersion {
+		return fmt.Errorf("%w: unsupported format version %d", ErrNotPinned, l.FormatVersion)
+	}
+
+	hasher = defaultHasher(hasher)
+	if hashing := cmp.Or(l.Hashing, HashingDeclared); hashing != hasher.Name() {
+		return fmt.Errorf(
+			"%w: the checksums are computed with the %s hashing strategy, not %s",
+			ErrNotPinned, hashing, hasher.Name(),
+		)
+	}
+
+	checksums := make(map[uint64]string, len(l.Migrations))
+	for _, pin := range l.Migrations {
+		checksums[pin.Version] = pin.Checksum
+	}
+
+	var differences []string
+	for _, mig := range planned {
+		pinned, ok := checksums[mig.Version()]
+		if !ok {
+			differences = append(
+				differences, fmt.Sprintf("the version %d is not pinned", mig.Version()),
+			)
+			continue
+		}
+
+		checksum, err := ChecksumWith(mig, hasher)
+		if err != nil {
+			return err
+		}
+		if checksum != pinned {
+			differences = append(
+				differences,
+				fmt.Sprintf("the version %d does not match its pinned checksum", mig.Version()),
+			)
+		}
+	}
+
+	if len(differences) > 0 {
+		return fmt.Errorf("%w: %s", ErrNotPinned, strings.Join(differences, ", "))
+	}
+	return nil
+}
+
+// Write writes the lockfile as indented JSON
+func Write(w io.Writer, lockfile Lockfile) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(lockfile); err != nil {
+		return fmt.Errorf("failed to write the lockfile with error: %w", err)
+	}
+	return nil
+}
+
+// Read reads a lockfile written by Write
+func Read(r io.Reader) (Lockfile, error) {
+	var lockfile Lockfile
+	if err := json.NewDecoder(r).Decode(&lockfile); err != nil {
+		return Lockfile{}, fmt.Errorf("failed to read the lockfile with error: %w", err)
+	}
+	return lockfile, nil
+}
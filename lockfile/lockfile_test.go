@@ -0,0 +1,76 @@
+package lockfile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type LockfileTestSuite struct {
+	suite.Suite
+}
+
+func TestLockfileTestSuite(t *testing.T) {
+	suite.Run(t, new(LockfileTestSuite))
+}
+
+// sqlMigration declares the checksum of the SQL it runs
+type sqlMigration struct {
+	migration.DummyMigration
+	sql string
+}
+
+func (m *sqlMigration) Checksum() string {
+	return m.sql
+}
+
+func (suite *LockfileTestSuite) TestItPinsTheRegisteredMigrations() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(
+		&sqlMigration{*migration.NewDummyMigration(2), "CREATE TABLE users (id INT)"},
+	)
+
+	pinned, err := New(registry, nil)
+	suite.Require().NoError(err)
+	var buf bytes.Buffer
+	suite.Require().NoError(Write(&buf, pinned))
+	lockfile, err := Read(&buf)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(HashingDeclared, lockfile.Hashing)
+	suite.Require().Len(lockfile.Migrations, 2)
+	suite.Assert().Equal(uint64(2), lockfile.Migrations[1].Version)
+	suite.Assert().Equal(Checksum(registry.Get(2)), lockfile.Migrations[1].Checksum)
+	suite.Assert().NoError(lockfile.Check(registry.OrderedMigrations(), nil))
+
+	// the content of the migrations is covered by their checksum
+	changed := &sqlMigration{*migration.NewDummyMigration(2), "CREATE TABLE users (id BIGINT)"}
+	suite.Assert().NotEqual(Checksum(registry.Get(2)), Checksum(changed))
+	suite.Assert().NotEqual(Checksum(migration.NewDummyMigration(2)), Checksum(changed))
+}
+
+func (suite *LockfileTestSuite) TestItRefusesTheMigrationsWhichAreNotPinned() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(&sqlMigration{*migration.NewDummyMigration(1), "SELECT 1"})
+	lockfile, _ := New(registry, nil)
+
+	planned := []migration.Migration{
+		&sqlMigration{*migration.NewDummyMigration(1), "SELECT 2"},
+		migration.NewDummyMigration(2),
+	}
+	err := lockfile.Check(planned, nil)
+	suite.Assert().ErrorIs(err, ErrNotPinned)
+	suite.Assert().EqualError(
+		err,
+		"the run is not pinned by the lockfile: the version 1 does not match its pinned "+
+			"checksum, the version 2 is not pinned",
+	)
+
+	lockfile.FormatVersion = 2
+	suite.Assert().ErrorContains(lockfile.Check(nil, nil), "unsupported format version 2")
+
+	_, err = Read(bytes.NewBufferString(`{`))
+	suite.Assert().ErrorContains(err, "failed to read the lockfile")
+}
@@ -0,0 +1,71 @@
+// Package masking provides the data masking (anonymization) routines run on the databases of
+// the lower environments, for example a staging database refreshed from a production dump, so
+// they stay compliant without a separate toolchain.
+//
+// The cli package runs the routines after each successful up (and fresh) run, and with the
+// mask command, only in the environments flagged as non-production (see
+// cli.BootstrapSettings.MaskingRoutines). The routines must be idempotent, since they run
+// again after each run.
+//
+// Example:
+//
+//	routines := []masking.Routine{
+//		masking.SqlRoutine(
+//			"users",
+//			"UPDATE users SET email = CONCAT('user', id, '@example.com'), phone = NULL",
+//		),
+//		{Name: "documents", Mask: maskDocuments},
+//	}
+package masking
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golibry/go-migrations/sqlhelper"
+)
+
+// Routine masks a part of the data
+type Routine struct {
+	// Name identifies the routine in the output and in the errors
+	Name string
+
+	// Mask must mask the data. It gets the db passed to the migrations.
+	Mask func(ctx context.Context, db any) error
+}
+
+// SqlRoutine builds a Routine which executes the statements in order, through the sqlhelper
+// package. The db must be a sqlhelper.Execer (for example, *sql.DB).
+func SqlRoutine(name string, statements ...string) Routine {
+	return Routine{
+		Name: name,
+		Mask: func(ctx context.Context, db any) error {
+			execer, ok := db.(sqlhelper.Execer)
+			if !ok {
+				return fmt.Errorf("the masking routine %s needs a sqlhelper.Execer db", name)
+			}
+
+			for _, statement := range statements {
+				if _, err := sqlhelper.Exec(ctx, execer, statement); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// Run runs the routines in order and returns the names of the ones which succeeded. It stops
+// at the first failure.
+func Run(ctx context.Context, db any, routines []Routine) ([]string, error) {
+	var masked []string
+	for _, routine := range routines {
+		if err := routine.Mask(ctx, db); err != nil {
+			return masked, fmt.Errorf(
+				"masking routine %s failed with error: %w", routine.Name, err,
+			)
+		}
+		masked = append(masked, routine.Name)
+	}
+	return masked, nil
+}
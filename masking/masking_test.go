@@ -0,0 +1,53 @@
+package masking
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MaskingTestSuite struct {
+	suite.Suite
+}
+
+func TestMaskingTestSuite(t *testing.T) {
+	suite.Run(t, new(MaskingTestSuite))
+}
+
+// recordingExecer records the executed statements
+type recordingExecer struct {
+	statements []string
+}
+
+func (e *recordingExecer) ExecContext(
+	_ context.Context,
+	query string,
+	_ ...any,
+) (sql.Result, error) {
+	e.statements = append(e.statements, query)
+	return nil, nil
+}
+
+func (suite *MaskingTestSuite) TestItRunsTheRoutinesInOrderUntilOneFails() {
+	db := &recordingExecer{}
+	maskErr := errors.New("table missing")
+	routines := []Routine{
+		SqlRoutine("users", "UPDATE users SET email = NULL", "UPDATE users SET phone = NULL"),
+		{Name: "documents", Mask: func(context.Context, any) error { return maskErr }},
+		SqlRoutine("orders", "UPDATE orders SET address = NULL"),
+	}
+
+	masked, err := Run(context.Background(), db, routines)
+	suite.Assert().ErrorIs(err, maskErr)
+	suite.Assert().ErrorContains(err, "masking routine documents failed")
+	suite.Assert().Equal([]string{"users"}, masked)
+	suite.Assert().Equal(
+		[]string{"UPDATE users SET email = NULL", "UPDATE users SET phone = NULL"}, db.statements,
+	)
+
+	_, err = Run(context.Background(), "not a db", routines[:1])
+	suite.Assert().ErrorContains(err, "needs a sqlhelper.Execer db")
+}
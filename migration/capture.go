@@ -0,0 +1,66 @@
+package migration
+
+import (
+	"context"
+	"sync"
+)
+
+// captureCtxKey is the context key used to pass a StatementsCapture to a migration
+type captureCtxKey struct{}
+
+// CapturedStatement is a statement which was recorded instead of being executed
+type CapturedStatement struct {
+	Query string
+	Args  []any
+}
+
+// StatementsCapture records the statements a migration would execute. It is safe for
+// concurrent use.
+type StatementsCapture struct {
+	mu         sync.Mutex
+	statements []CapturedStatement
+}
+
+// Add records a new statement
+func (c *StatementsCapture) Add(query string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = append(c.statements, CapturedStatement{query, args})
+}
+
+// Statements returns a copy of the recorded statements, in the order they were added
+func (c *StatementsCapture) Statements() []CapturedStatement {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CapturedStatement{}, c.statements...)
+}
+
+// WithCapture returns a copy of ctx which carries a new StatementsCapture, along with the
+// capture. Helpers which honor the capture mode (see the sqlhelper package) must record
+// their statements in it, instead of executing them.
+func WithCapture(ctx context.Context) (context.Context, *StatementsCapture) {
+	capture := &StatementsCapture{}
+	return context.WithValue(ctx, captureCtxKey{}, capture), capture
+}
+
+// CaptureFromContext returns the StatementsCapture carried by ctx, if any
+func CaptureFromContext(ctx context.Context) (*StatementsCapture, bool) {
+	capture, ok := ctx.Value(captureCtxKey{}).(*StatementsCapture)
+	return capture, ok
+}
+
+// CaptureCapable is an optional interface for migrations which execute all their statements
+// via helpers honoring the capture mode. Only these migrations can be called with a capturing
+// context, as any other migration would change the database state.
+type CaptureCapable interface {
+	// CaptureCapable must return true if Up() and Down() are safe to be called with a
+	// capturing context
+	CaptureCapable() bool
+}
+
+// IsCaptureCapable checks if the migration, or the one it wraps (see As), declares itself as
+// capture capable
+func IsCaptureCapable(mig Migration) bool {
+	capable, ok := As[CaptureCapable](mig)
+	return ok && capable.CaptureCapable()
+}
@@ -0,0 +1,51 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CaptureTestSuite struct {
+	suite.Suite
+}
+
+func TestCaptureTestSuite(t *testing.T) {
+	suite.Run(t, new(CaptureTestSuite))
+}
+
+type captureCapableMigration struct {
+	DummyMigration
+	capable bool
+}
+
+func (m *captureCapableMigration) CaptureCapable() bool {
+	return m.capable
+}
+
+func (suite *CaptureTestSuite) TestItCanCaptureStatementsViaContext() {
+	_, ok := CaptureFromContext(context.Background())
+	suite.Assert().False(ok)
+
+	ctx, capture := WithCapture(context.Background())
+	fromCtx, ok := CaptureFromContext(ctx)
+	suite.Assert().True(ok)
+	suite.Assert().Same(capture, fromCtx)
+
+	capture.Add("UPDATE a SET b = $1", 1)
+	capture.Add("DELETE FROM a")
+	suite.Assert().Equal(
+		[]CapturedStatement{
+			{"UPDATE a SET b = $1", []any{1}},
+			{"DELETE FROM a", nil},
+		},
+		capture.Statements(),
+	)
+}
+
+func (suite *CaptureTestSuite) TestItCanDetectCaptureCapableMigrations() {
+	suite.Assert().False(IsCaptureCapable(NewDummyMigration(1)))
+	suite.Assert().False(IsCaptureCapable(&captureCapableMigration{DummyMigration{1}, false}))
+	suite.Assert().True(IsCaptureCapable(&captureCapableMigration{DummyMigration{1}, true}))
+}
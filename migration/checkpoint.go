@@ -0,0 +1,32 @@
+package migration
+
+import "context"
+
+// checkpointerCtxKey is the context key used to pass a Checkpointer to a migration
+type checkpointerCtxKey struct{}
+
+// Checkpointer lets a long-running migration (for example, a multi-hour backfill) persist a
+// progress marker through the executions repository, so an interrupted or failed execution
+// resumes where it left off, instead of restarting. The migrations handler passes one to Up()
+// via the context (see CheckpointerFromContext).
+type Checkpointer interface {
+	// Checkpoint returns the last saved marker, by this execution or by a previous, unfinished
+	// one. It is empty if the migration starts from scratch.
+	Checkpoint() string
+
+	// SaveCheckpoint persists the marker (for example, the last processed ID). It must be
+	// called after the work it marks is committed.
+	SaveCheckpoint(marker string) error
+}
+
+// WithCheckpointer returns a copy of ctx which carries the checkpointer
+func WithCheckpointer(ctx context.Context, checkpointer Checkpointer) context.Context {
+	return context.WithValue(ctx, checkpointerCtxKey{}, checkpointer)
+}
+
+// CheckpointerFromContext returns the Checkpointer carried by ctx, if any. Migrations must
+// handle its absence (for example, when forced or captured), by processing everything.
+func CheckpointerFromContext(ctx context.Context) (Checkpointer, bool) {
+	checkpointer, ok := ctx.Value(checkpointerCtxKey{}).(Checkpointer)
+	return checkpointer, ok
+}
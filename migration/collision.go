@@ -0,0 +1,138 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CollisionPolicy decides what happens when several migrations declare the same version
+// (for example, a copied migration file whose Version() was not updated)
+type CollisionPolicy string
+
+const (
+	// CollisionFail fails the registration (the default)
+	CollisionFail CollisionPolicy = "fail"
+
+	// CollisionPreferNewestFile keeps the migration from the most recently modified source
+	// file, which must be known (see Register)
+	CollisionPreferNewestFile CollisionPolicy = "prefer-newest-file"
+
+	// CollisionQuarantine keeps none of the migrations: the version is quarantined (see
+	// QuarantinedMigration) and refused by the runs, while the other migrations can run
+	CollisionQuarantine CollisionPolicy = "quarantine"
+
+	// CollisionInteractive quarantines the version, like CollisionQuarantine, until the
+	// migration to keep is chosen (for example, by the prompt of the cli package)
+	CollisionInteractive CollisionPolicy = "interactive"
+)
+
+// CollisionResolver is an optional interface for the registries which quarantine the
+// migrations declaring the same version, so the collisions can be listed and resolved
+type CollisionResolver interface {
+	// CollisionPolicy returns the policy applied to the collisions
+	CollisionPolicy() CollisionPolicy
+
+	// Collisions returns the quarantined versions, in version order
+	Collisions() []*QuarantinedMigration
+
+	// ResolveCollision keeps the candidate with the given index (see
+	// QuarantinedMigration.Candidates) for the quarantined version
+	ResolveCollision(version uint64, candidate int) error
+}
+
+// QuarantinedMigration stands in a registry for a version declared by several migrations.
+// None of them is executed: its Up() and Down() fail with a *CollisionError, and the handler
+// refuses to run it, until the collision is resolved.
+type QuarantinedMigration struct {
+	version uint64
+
+	// Candidates holds the migrations which declare the version, in registration order
+	Candidates []Migration
+
+	// Files holds the source files of the candidates, empty for the unknown ones
+	Files []string
+}
+
+func (m *QuarantinedMigration) Version() uint64 {
+	return m.version
+}
+
+func (m *QuarantinedMigration) Up(context.Context, any) error {
+	return &CollisionError{Quarantined: []*QuarantinedMigration{m}}
+}
+
+func (m *QuarantinedMigration) Down(context.Context, any) error {
+	return &CollisionError{Quarantined: []*QuarantinedMigration{m}}
+}
+
+// String describes the version and the candidates
+func (m *QuarantinedMigration) String() string {
+	candidates := make([]string, 0, len(m.Candidates))
+	for i, candidate := range m.Candidates {
+		candidates = append(candidates, CandidateLabel(candidate, m.Files[i]))
+	}
+	return strconv.FormatUint(m.version, 10) + " (declared by " +
+		strings.Join(candidates, ", ") + ")"
+}
+
+// CandidateLabel describes a migration declaring a colliding version, by its source file if
+// known, by its type otherwise
+func CandidateLabel(candidate Migration, file string) string {
+	if file == "" {
+		return fmt.Sprintf("%T", candidate)
+	}
+	return filepath.Base(file)
+}
+
+// CollisionErrorOf returns a *CollisionError if the migration is quarantined, nil otherwise
+func CollisionErrorOf(mig Migration) error {
+	if quarantined, ok := mig.(*QuarantinedMigration); ok {
+		return &CollisionError{Quarantined: []*QuarantinedMigration{quarantined}}
+	}
+	return nil
+}
+
+// CollisionError is returned for the versions declared by several migrations
+type CollisionError struct {
+	Quarantined []*QuarantinedMigration
+}
+
+func (e *CollisionError) Error() string {
+	descriptions := make([]string, 0, len(e.Quarantined))
+	for _, quarantined := range e.Quarantined {
+		descriptions = append(descriptions, quarantined.String())
+	}
+	return "migration versions declared by several migrations: " +
+		strings.Join(descriptions, ", ")
+}
+
+// newestCandidate returns the index of the candidate from the most recently modified file
+func (m *QuarantinedMigration) newestCandidate() (int, error) {
+	newest := -1
+	var newestInfo os.FileInfo
+	for i, file := range m.Files {
+		if file == "" {
+			return 0, fmt.Errorf(
+				"failed to find the newest migration of version %d, the file of %T is unknown",
+				m.version, m.Candidates[i],
+			)
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return 0, fmt.Errorf(
+				"failed to find the newest migration of version %d with error: %w",
+				m.version, err,
+			)
+		}
+
+		if newest < 0 || !info.ModTime().Before(newestInfo.ModTime()) {
+			newest, newestInfo = i, info
+		}
+	}
+	return newest, nil
+}
@@ -0,0 +1,121 @@
+package migration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CollisionTestSuite struct {
+	suite.Suite
+	dirPath string
+}
+
+func TestCollisionTestSuite(t *testing.T) {
+	suite.Run(t, new(CollisionTestSuite))
+}
+
+func (suite *CollisionTestSuite) SetupTest() {
+	suite.dirPath = suite.T().TempDir()
+}
+
+// writeFile creates the migration file with the name, modified at the time
+func (suite *CollisionTestSuite) writeFile(name string, modifiedAt time.Time) string {
+	path := filepath.Join(suite.dirPath, name)
+	suite.Require().NoError(os.WriteFile(path, []byte("package migrations\n"), 0600))
+	suite.Require().NoError(os.Chtimes(path, modifiedAt, modifiedAt))
+	return path
+}
+
+func (suite *CollisionTestSuite) TestItQuarantinesTheCollidingVersions() {
+	first, second := &DummyMigration{123}, &DummyMigration{123}
+	registry := NewGenericRegistryWithCollisionPolicy(CollisionQuarantine)
+	suite.Require().NoError(registry.RegisterFromFile(first, "/migrations/version_123.go"))
+	suite.Require().NoError(registry.Register(second))
+	suite.Require().NoError(registry.Register(&DummyMigration{124}))
+
+	collisions := registry.Collisions()
+	suite.Require().Len(collisions, 1)
+	suite.Assert().Same(collisions[0], registry.Get(123))
+	suite.Assert().Equal([]Migration{first, second}, collisions[0].Candidates)
+	suite.Assert().Equal(CollisionQuarantine, registry.CollisionPolicy())
+
+	var collisionErr *CollisionError
+	err := registry.Get(123).Up(context.Background(), nil)
+	suite.Require().ErrorAs(err, &collisionErr)
+	suite.Assert().EqualError(
+		err,
+		"migration versions declared by several migrations: "+
+			"123 (declared by version_123.go, *migration.DummyMigration)",
+	)
+	suite.Assert().ErrorAs(registry.Get(123).Down(context.Background(), nil), &collisionErr)
+	suite.Assert().ErrorAs(CollisionErrorOf(registry.Get(123)), &collisionErr)
+	suite.Assert().NoError(CollisionErrorOf(registry.Get(124)))
+
+	suite.Assert().Error(registry.ResolveCollision(124, 0))
+	suite.Assert().Error(registry.ResolveCollision(123, 2))
+	suite.Require().NoError(registry.ResolveCollision(123, 1))
+	suite.Assert().Same(second, registry.Get(123))
+	suite.Assert().Empty(registry.Collisions())
+}
+
+func (suite *CollisionTestSuite) TestItPrefersTheMigrationFromTheNewestFile() {
+	now := time.Now()
+	older := suite.writeFile("version_123.go", now.Add(-time.Hour))
+	newer := suite.writeFile("version_124.go", now)
+	first, second := &DummyMigration{123}, &DummyMigration{123}
+
+	registry := NewGenericRegistryWithCollisionPolicy(CollisionPreferNewestFile)
+	suite.Require().NoError(registry.RegisterFromFile(second, newer))
+	suite.Require().NoError(registry.RegisterFromFile(first, older))
+	suite.Assert().Same(second, registry.Get(123))
+	suite.Assert().Empty(registry.Collisions())
+
+	suite.Assert().ErrorContains(
+		registry.Register(&DummyMigration{123}),
+		"failed to find the newest migration of version 123",
+	)
+}
+
+func (suite *CollisionTestSuite) TestItAppliesTheCollisionPolicyToTheAutoDirRegistry() {
+	defaultRegistry := DefaultRegistry
+	defer func() { DefaultRegistry = defaultRegistry }()
+	newDefaultRegistry := func() {
+		DefaultRegistry = NewGenericRegistryWithCollisionPolicy(CollisionQuarantine)
+		DefaultRegistry.policy = ""
+		suite.Require().NoError(
+			DefaultRegistry.RegisterFromFile(&DummyMigration{123}, suite.dirPath+"/version_123.go"),
+		)
+		suite.Require().NoError(
+			DefaultRegistry.RegisterFromFile(&DummyMigration{123}, suite.dirPath+"/version_124.go"),
+		)
+		suite.Require().NoError(
+			DefaultRegistry.RegisterFromFile(&DummyMigration{125}, suite.dirPath+"/version_125.go"),
+		)
+	}
+	for _, name := range []string{"version_123.go", "version_124.go", "version_125.go"} {
+		suite.writeFile(name, time.Now())
+	}
+	dirPath, _ := NewMigrationsDirPath(suite.dirPath)
+
+	newDefaultRegistry()
+	suite.Assert().Panics(func() { NewAutoDirMigrationsRegistry(dirPath) })
+	suite.Assert().Panics(func() { NewAutoDirMigrationsRegistryWithPolicy(dirPath, "newest") })
+
+	newDefaultRegistry()
+	registry := NewAutoDirMigrationsRegistryWithPolicy(dirPath, CollisionQuarantine)
+	suite.Assert().Equal([]uint64{123, 125}, registry.OrderedVersions())
+	suite.Assert().Len(registry.Collisions(), 1)
+	suite.Assert().NoError(registry.Validate())
+
+	newDefaultRegistry()
+	registry = NewAutoDirMigrationsRegistryWithPolicy(dirPath, CollisionInteractive)
+	suite.Assert().Equal(CollisionInteractive, registry.CollisionPolicy())
+	suite.Require().NoError(registry.ResolveCollision(123, 0))
+	suite.Assert().Empty(registry.Collisions())
+	suite.Assert().NoError(registry.Validate())
+}
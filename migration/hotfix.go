@@ -0,0 +1,116 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrIrreversibleHotfix is returned by Down() of the hotfix migrations built by NewHotfix: the
+// reversal of a broken migration is not rolled back, another migration must be shipped instead
+var ErrIrreversibleHotfix = errors.New("a hotfix migration can't be rolled back")
+
+// Hotfix is an optional interface for the corrective migrations which reverse an earlier,
+// broken migration, so that emergency reversals are shipped as code, and tracked as
+// executions, instead of being run as ad-hoc SQL. A hotfix is registered with its own, newer
+// version, and its Up() runs the Down() semantics of the fixed version (see NewHotfix).
+type Hotfix interface {
+	// Fixes returns the version of the broken migration the hotfix reverses
+	Fixes() uint64
+}
+
+// FixedVersionOf returns the version fixed by the migration. The second return value is false
+// if neither the migration nor the ones it wraps implement Hotfix (see As).
+func FixedVersionOf(mig Migration) (uint64, bool) {
+	if hotfix, ok := As[Hotfix](mig); ok {
+		return hotfix.Fixes(), true
+	}
+	return 0, false
+}
+
+// ValidateHotfix checks that the version fixed by the migration, if it is a Hotfix, is an
+// earlier registered version, so the hotfix can only run after the migration it reverses
+func ValidateHotfix(registry MigrationsRegistry, mig Migration) error {
+	fixed, ok := FixedVersionOf(mig)
+	if !ok {
+		return nil
+	}
+
+	if fixed >= mig.Version() || registry.Get(fixed) == nil {
+		return fmt.Errorf(
+			"the hotfix migration %d must fix an earlier registered version, not %d",
+			mig.Version(), fixed,
+		)
+	}
+	return nil
+}
+
+// Irreversible is an optional interface for the migrations which can't be rolled back, like
+// the hotfixes built by NewHotfix. The rollbacks which include them are refused before any
+// Down() runs (see ValidateReversible), instead of failing part way, after the newer versions
+// were rolled back.
+type Irreversible interface {
+	// Irreversible returns the error Down() fails with
+	Irreversible() error
+}
+
+// ValidateReversible checks that the migrations to be rolled back can all be rolled back,
+// returning the error of the first one which is, or wraps (see As), an Irreversible migration
+func ValidateReversible(migrations []Migration) error {
+	for _, mig := range migrations {
+		if irreversible, ok := As[Irreversible](mig); ok {
+			return fmt.Errorf(
+				"the migration %d can't be rolled back, nothing was rolled back: %w",
+				mig.Version(), irreversible.Irreversible(),
+			)
+		}
+	}
+	return nil
+}
+
+// hotfixMigration is a down-only Hotfix (see NewHotfix)
+type hotfixMigration struct {
+	version uint64
+	fixes   uint64
+	reverse func(ctx context.Context, db any) error
+}
+
+// NewHotfix builds a down-only hotfix migration, registered with its own version, which
+// reverses the fixed version: Up() calls reverse, the Down() semantics of the broken
+// migration, while Down() fails with ErrIrreversibleHotfix. The hotfix is Irreversible, so the
+// rollbacks which include it are refused up front.
+//
+// Example, in the file of the version 1712960000:
+//
+//	func init() {
+//		migration.Register(migration.NewHotfix(1712960000, 1712953083, dropBrokenIndex))
+//	}
+func NewHotfix(
+	version uint64,
+	fixes uint64,
+	reverse func(ctx context.Context, db any) error,
+) Migration {
+	return &hotfixMigration{version: version, fixes: fixes, reverse: reverse}
+}
+
+func (m *hotfixMigration) Version() uint64 {
+	return m.version
+}
+
+func (m *hotfixMigration) Fixes() uint64 {
+	return m.fixes
+}
+
+func (m *hotfixMigration) Up(ctx context.Context, db any) error {
+	return m.reverse(ctx, db)
+}
+
+func (m *hotfixMigration) Down(context.Context, any) error {
+	return m.Irreversible()
+}
+
+func (m *hotfixMigration) Irreversible() error {
+	return fmt.Errorf(
+		"failed to roll back the hotfix of version %d: %w", m.fixes, ErrIrreversibleHotfix,
+	)
+}
@@ -0,0 +1,57 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HotfixTestSuite struct {
+	suite.Suite
+}
+
+func TestHotfixTestSuite(t *testing.T) {
+	suite.Run(t, new(HotfixTestSuite))
+}
+
+func (suite *HotfixTestSuite) TestItBuildsDownOnlyHotfixes() {
+	var calledWith any
+	hotfix := NewHotfix(
+		5, 2, func(_ context.Context, db any) error {
+			calledWith = db
+			return nil
+		},
+	)
+
+	fixed, ok := FixedVersionOf(hotfix)
+	suite.Assert().True(ok)
+	suite.Assert().Equal(uint64(2), fixed)
+	suite.Assert().Equal(uint64(5), hotfix.Version())
+
+	suite.Assert().NoError(hotfix.Up(context.Background(), "db"))
+	suite.Assert().Equal("db", calledWith)
+	suite.Assert().ErrorIs(hotfix.Down(context.Background(), "db"), ErrIrreversibleHotfix)
+
+	_, ok = FixedVersionOf(NewDummyMigration(1))
+	suite.Assert().False(ok)
+
+	suite.Assert().NoError(ValidateReversible([]Migration{NewDummyMigration(7)}))
+	suite.Assert().ErrorIs(
+		ValidateReversible([]Migration{NewDummyMigration(7), hotfix}), ErrIrreversibleHotfix,
+	)
+}
+
+func (suite *HotfixTestSuite) TestItValidatesTheFixedVersions() {
+	registry := NewGenericRegistry()
+	_ = registry.Register(NewDummyMigration(2))
+	noop := func(context.Context, any) error { return nil }
+
+	suite.Assert().NoError(ValidateHotfix(registry, NewDummyMigration(3)))
+	suite.Assert().NoError(ValidateHotfix(registry, NewHotfix(5, 2, noop)))
+	suite.Assert().ErrorContains(
+		ValidateHotfix(registry, NewHotfix(5, 3, noop)),
+		"the hotfix migration 5 must fix an earlier registered version, not 3",
+	)
+	suite.Assert().Error(ValidateHotfix(registry, NewHotfix(1, 2, noop)))
+}
@@ -0,0 +1,572 @@
+// Package inspect lists the migrations of a module statically, from the source of their files,
+// without building the module nor connecting to a database: the migration files, the declared
+// versions, the descriptions and whether the migrations are registered to
+// migration.DefaultRegistry. It powers the tooling which can't run the migrations binary, such
+// as language servers, developer portals and pre-commit hooks.
+//
+// The migrations are the types with the Version, Up and Down methods. Their versions are read
+// from the constants their Version methods return, their descriptions from their Metadata
+// methods (when they return a migration.Metadata literal), from their Description methods (when
+// they return a string literal) or from the comments of their types, and their registrations
+// from the migration.Register calls of the package. The values which are only known at run time
+// (for example, a version computed by a function) are reported as problems.
+package inspect
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// migrationImport is the import path of the migration package, which declares Register
+const migrationImport = "github.com/golibry/go-migrations/migration"
+
+// Migration is a migration found in the source of a package
+type Migration struct {
+	// File is the name of the file which declares the migration type, in the package directory
+	File string `json:"file"`
+
+	// Line is the line of the declaration of the migration type in the File
+	Line int `json:"line"`
+
+	// Type is the name of the migration type
+	Type string `json:"type"`
+
+	// Version is the version returned by the Version method, 0 if it is not a constant
+	Version uint64 `json:"version"`
+
+	// FileVersion is the version in the name of the File (version_<version>.go or
+	// version_<version>_<name>.go), 0 if the name doesn't follow the convention
+	FileVersion uint64 `json:"fileVersion"`
+
+	// Description is the description of the Metadata, the one returned by the Description
+	// method, or the comment of the migration type
+	Description string `json:"description,omitempty"`
+
+	// Metadata holds the constant fields of the migration.Metadata literal returned by the
+	// Metadata method, along with the author and ticket comments of the generated migrations
+	Metadata migration.Metadata `json:"metadata"`
+
+	// Registered is true if the package registers the migration with migration.Register
+	Registered bool `json:"registered"`
+}
+
+// Problem is an issue found in the source of the migrations, which the registry would
+// report at run time, or which prevents the static inspection
+type Problem struct {
+	// File is the name of the file with the problem, in the package directory
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+// Report is the result of the inspection of a package
+type Report struct {
+	// Dir is the directory of the package
+	Dir string `json:"dir"`
+
+	// Package is the name of the package
+	Package string `json:"package"`
+
+	// Migrations holds the migrations of the package, in version order
+	Migrations []Migration `json:"migrations"`
+
+	// Problems holds the problems found in the package, in file and line order
+	Problems []Problem `json:"problems"`
+}
+
+// Dir inspects the package of the directory, usually a migrations directory. The syntax errors
+// of its files are reported as problems, with the migrations of the rest of the files.
+func Dir(dir string) (Report, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to read the directory %s with error: %w", dir, err)
+	}
+
+	inspector := &inspector{
+		report:     Report{Dir: dir, Migrations: []Migration{}, Problems: []Problem{}},
+		fileSet:    token.NewFileSet(),
+		types:      make(map[string]*Migration),
+		constants:  make(map[string]uint64),
+		registered: make(map[string]bool),
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !isSourceFile(name) {
+			continue
+		}
+		if err = inspector.parse(filepath.Join(dir, name)); err != nil {
+			return Report{}, err
+		}
+	}
+	return inspector.inspect(), nil
+}
+
+// Module inspects the packages of the module rooted at the directory which declare migrations,
+// in directory order. Like the go tool, it skips the directories named vendor or testdata and
+// the ones whose names start with "." or "_".
+func Module(root string) ([]Report, error) {
+	reports := []Report{}
+	err := filepath.WalkDir(
+		root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() {
+				return nil
+			}
+
+			name := entry.Name()
+			if path != root && (name == "vendor" || name == "testdata" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+
+			report, err := Dir(path)
+			if err != nil {
+				return err
+			}
+			if len(report.Migrations) > 0 {
+				reports = append(reports, report)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect the module %s with error: %w", root, err)
+	}
+	return reports, nil
+}
+
+// inspector collects the declarations of the files of a package
+type inspector struct {
+	report  Report
+	fileSet *token.FileSet
+	files   []*ast.File
+
+	// types holds the candidate migration types by name, and methods their methods by type
+	// and method names
+	types   map[string]*Migration
+	methods map[string]map[string]*ast.FuncDecl
+
+	// constants holds the integer constants of the package, by name
+	constants map[string]uint64
+
+	// registered holds the names of the types registered with migration.Register
+	registered map[string]bool
+}
+
+func isSourceFile(name string) bool {
+	return strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go")
+}
+
+// parse parses the file, reporting its syntax errors as problems
+func (i *inspector) parse(path string) error {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the file %s with error: %w", path, err)
+	}
+
+	file, err := parser.ParseFile(i.fileSet, path, source, parser.ParseComments)
+	var syntaxErrs scanner.ErrorList
+	if errors.As(err, &syntaxErrs) {
+		for _, syntaxErr := range syntaxErrs {
+			i.problemAt(syntaxErr.Pos, "%s", syntaxErr.Msg)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to parse the file %s with error: %w", path, err)
+	}
+	if file == nil {
+		return nil
+	}
+
+	if i.report.Package == "" {
+		i.report.Package = file.Name.Name
+	}
+	i.files = append(i.files, file)
+	return nil
+}
+
+// inspect finds the migrations of the parsed files
+func (i *inspector) inspect() Report {
+	i.methods = make(map[string]map[string]*ast.FuncDecl)
+	for _, file := range i.files {
+		i.collectDeclarations(file)
+	}
+	for _, file := range i.files {
+		i.collectRegistrations(file)
+	}
+
+	// in declaration order, so the duplicated versions are reported on the later declarations
+	declared := slices.SortedFunc(
+		maps.Values(i.types), func(a *Migration, b *Migration) int {
+			return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line))
+		},
+	)
+	versions := make(map[uint64][]*Migration)
+	for _, mig := range declared {
+		methods := i.methods[mig.Type]
+		if methods["Version"] == nil || methods["Up"] == nil || methods["Down"] == nil {
+			continue
+		}
+
+		mig.Registered = i.registered[mig.Type]
+		i.readVersion(mig, methods["Version"])
+		i.readDescription(mig, methods["Description"])
+		i.readMetadata(mig, methods["Metadata"])
+		if mig.Version != 0 {
+			versions[mig.Version] = append(versions[mig.Version], mig)
+		}
+		i.report.Migrations = append(i.report.Migrations, *mig)
+
+		pos := token.Position{Filename: mig.File, Line: mig.Line}
+		switch {
+		case mig.FileVersion == 0:
+			i.problemAt(
+				pos, "the file of %s doesn't follow the %s%s<version>[%s<name>].go convention",
+				mig.Type, migration.FileNamePrefix, migration.FileNameSeparator,
+				migration.FileNameSeparator,
+			)
+		case mig.Version != 0 && mig.Version != mig.FileVersion:
+			i.problemAt(
+				pos, "%s declares the version %d, but its file name has the version %d",
+				mig.Type, mig.Version, mig.FileVersion,
+			)
+		}
+		if !mig.Registered {
+			i.problemAt(pos, "%s is not registered (see migration.Register)", mig.Type)
+		}
+	}
+
+	for version, migs := range versions {
+		for _, mig := range migs[1:] {
+			i.problemAt(
+				token.Position{Filename: mig.File, Line: mig.Line},
+				"%s declares the version %d, already declared by %s",
+				mig.Type, version, migs[0].Type,
+			)
+		}
+	}
+
+	slices.SortStableFunc(
+		i.report.Migrations, func(a Migration, b Migration) int {
+			return cmp.Or(cmp.Compare(a.Version, b.Version), cmp.Compare(a.File, b.File))
+		},
+	)
+	slices.SortStableFunc(
+		i.report.Problems, func(a Problem, b Problem) int {
+			return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line))
+		},
+	)
+	return i.report
+}
+
+// collectDeclarations collects the types, the methods and the integer constants of the file
+func (i *inspector) collectDeclarations(file *ast.File) {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					i.collectType(decl, spec)
+				case *ast.ValueSpec:
+					if decl.Tok != token.CONST {
+						continue
+					}
+					for index, name := range spec.Names {
+						if index < len(spec.Values) {
+							if value, ok := i.uintValue(spec.Values[index]); ok {
+								i.constants[name.Name] = value
+							}
+						}
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Recv == nil || len(decl.Recv.List) == 0 {
+				continue
+			}
+			typeName := receiverType(decl.Recv.List[0].Type)
+			if i.methods[typeName] == nil {
+				i.methods[typeName] = make(map[string]*ast.FuncDecl)
+			}
+			i.methods[typeName][decl.Name.Name] = decl
+		}
+	}
+}
+
+// collectType collects the type declaration, as a candidate migration
+func (i *inspector) collectType(decl *ast.GenDecl, spec *ast.TypeSpec) {
+	pos := i.fileSet.Position(spec.Pos())
+	fileName := filepath.Base(pos.Filename)
+	fileVersion, _ := migration.VersionFromFileName(fileName)
+
+	doc := spec.Doc
+	if doc == nil && len(decl.Specs) == 1 {
+		doc = decl.Doc
+	}
+
+	mig := &Migration{
+		File: fileName, Line: pos.Line, Type: spec.Name.Name, FileVersion: fileVersion,
+	}
+	if doc != nil {
+		var description []string
+		for _, line := range strings.Split(strings.TrimSpace(doc.Text()), "\n") {
+			if author, ok := strings.CutPrefix(line, "Author: "); ok {
+				mig.Metadata.Author = strings.TrimSpace(author)
+			} else if ticket, ok := strings.CutPrefix(line, "Ticket: "); ok {
+				mig.Metadata.Ticket = strings.TrimSpace(ticket)
+			} else if !strings.HasPrefix(line, "Previous version: ") {
+				description = append(description, line)
+			}
+		}
+		mig.Description = strings.TrimSpace(strings.Join(description, " "))
+	}
+	i.types[spec.Name.Name] = mig
+}
+
+// collectRegistrations collects the types registered by the migration.Register calls of the
+// file (or the migration.DefaultRegistry.Register ones)
+func (i *inspector) collectRegistrations(file *ast.File) {
+	migrationName := ""
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if path != migrationImport {
+			continue
+		}
+		migrationName = "migration"
+		if spec.Name != nil {
+			migrationName = spec.Name.Name
+		}
+	}
+	if migrationName == "" {
+		return
+	}
+
+	ast.Inspect(
+		file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			selector, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || (selector.Sel.Name != "Register" && selector.Sel.Name != "RegisterFromFile") {
+				return true
+			}
+
+			switch receiver := selector.X.(type) {
+			case *ast.Ident:
+				if receiver.Name != migrationName || selector.Sel.Name != "Register" {
+					return true
+				}
+			case *ast.SelectorExpr:
+				pkg, ok := receiver.X.(*ast.Ident)
+				if !ok || pkg.Name != migrationName || receiver.Sel.Name != "DefaultRegistry" {
+					return true
+				}
+			default:
+				return true
+			}
+
+			if typeName := instanceType(call.Args[0]); typeName != "" {
+				i.registered[typeName] = true
+			}
+			return true
+		},
+	)
+}
+
+// readVersion reads the version returned by the Version method of the migration
+func (i *inspector) readVersion(mig *Migration, method *ast.FuncDecl) {
+	if method.Body != nil && len(method.Body.List) == 1 {
+		if ret, ok := method.Body.List[0].(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+			if version, ok := i.uintValue(ret.Results[0]); ok {
+				mig.Version = version
+				return
+			}
+		}
+	}
+	i.problem(
+		method.Pos(),
+		"the version of %s is not a constant, it can't be read statically", mig.Type,
+	)
+}
+
+// readDescription reads the string literal returned by the Description method of the migration
+// (see migration.Describable), if any. The description of its metadata takes precedence.
+func (i *inspector) readDescription(mig *Migration, method *ast.FuncDecl) {
+	if method == nil || method.Body == nil || len(method.Body.List) != 1 {
+		return
+	}
+	ret, ok := method.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return
+	}
+	if value, ok := stringValue(ret.Results[0]); ok {
+		mig.Metadata.Description = strings.TrimSpace(value)
+		mig.Description = mig.Metadata.Description
+	}
+}
+
+// readMetadata reads the constant fields of the migration.Metadata literal returned by the
+// Metadata method of the migration, if any
+func (i *inspector) readMetadata(mig *Migration, method *ast.FuncDecl) {
+	if method == nil || method.Body == nil || len(method.Body.List) != 1 {
+		return
+	}
+	ret, ok := method.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return
+	}
+	literal, ok := ret.Results[0].(*ast.CompositeLit)
+	if !ok {
+		return
+	}
+
+	for _, element := range literal.Elts {
+		field, ok := element.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := field.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+
+		if key.Name == "Tags" {
+			if tags, ok := field.Value.(*ast.CompositeLit); ok {
+				mig.Metadata.Tags = nil
+				for _, tag := range tags.Elts {
+					if value, ok := stringValue(tag); ok {
+						mig.Metadata.Tags = append(mig.Metadata.Tags, value)
+					}
+				}
+			}
+			continue
+		}
+
+		value, ok := stringValue(field.Value)
+		if !ok {
+			continue
+		}
+		switch key.Name {
+		case "Author":
+			mig.Metadata.Author = value
+		case "Ticket":
+			mig.Metadata.Ticket = value
+		case "Description":
+			mig.Metadata.Description = value
+			mig.Description = value
+		case "Risk":
+			mig.Metadata.Risk = value
+		case "Owner":
+			mig.Metadata.Owner = value
+		}
+	}
+}
+
+// uintValue returns the value of the unsigned integer literal, or of the constant of the
+// package, the expression is
+func (i *inspector) uintValue(expr ast.Expr) (uint64, bool) {
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return i.uintValue(expr.X)
+	case *ast.BasicLit:
+		if expr.Kind != token.INT {
+			return 0, false
+		}
+		value, err := strconv.ParseUint(strings.ReplaceAll(expr.Value, "_", ""), 0, 64)
+		return value, err == nil
+	case *ast.Ident:
+		value, ok := i.constants[expr.Name]
+		return value, ok
+	case *ast.CallExpr:
+		// a conversion, like uint64(1712953077)
+		if ident, ok := expr.Fun.(*ast.Ident); ok && strings.HasPrefix(ident.Name, "uint") &&
+			len(expr.Args) == 1 {
+			return i.uintValue(expr.Args[0])
+		}
+	}
+	return 0, false
+}
+
+// problem reports a problem at the position of the file set
+func (i *inspector) problem(pos token.Pos, format string, args ...any) {
+	i.problemAt(i.fileSet.Position(pos), format, args...)
+}
+
+// problemAt reports a problem at the position
+func (i *inspector) problemAt(pos token.Position, format string, args ...any) {
+	i.report.Problems = append(
+		i.report.Problems,
+		Problem{
+			File: filepath.Base(pos.Filename), Line: pos.Line,
+			Message: fmt.Sprintf(format, args...),
+		},
+	)
+}
+
+// stringValue returns the value of the string literal the expression is
+func stringValue(expr ast.Expr) (string, bool) {
+	literal, ok := expr.(*ast.BasicLit)
+	if !ok || literal.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(literal.Value)
+	return value, err == nil
+}
+
+// receiverType returns the name of the type of the method receiver (T or *T)
+func receiverType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	case *ast.IndexExpr:
+		return receiverType(expr.X)
+	}
+	return ""
+}
+
+// instanceType returns the name of the type of the instance built by the expression:
+// &T{...}, T{...} or new(T)
+func instanceType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.UnaryExpr:
+		if expr.Op == token.AND {
+			return instanceType(expr.X)
+		}
+	case *ast.CompositeLit:
+		if ident, ok := expr.Type.(*ast.Ident); ok {
+			return ident.Name
+		}
+	case *ast.CallExpr:
+		if ident, ok := expr.Fun.(*ast.Ident); ok && ident.Name == "new" && len(expr.Args) == 1 {
+			if typeIdent, ok := expr.Args[0].(*ast.Ident); ok {
+				return typeIdent.Name
+			}
+		}
+	case *ast.ParenExpr:
+		return instanceType(expr.X)
+	}
+	return ""
+}
@@ -0,0 +1,206 @@
+package inspect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type InspectTestSuite struct {
+	suite.Suite
+}
+
+func TestInspectTestSuite(t *testing.T) {
+	suite.Run(t, new(InspectTestSuite))
+}
+
+const generatedMigration = `package migrations
+
+import (
+	"context"
+	"github.com/golibry/go-migrations/migration"
+)
+
+func init() {
+	migration.Register(&Migration1712953077{})
+}
+
+// Author: jane
+// Ticket: DB-1
+// Previous version: 1712953000
+type Migration1712953077 struct {}
+
+func(migration *Migration1712953077) Version() uint64 {
+	return 1712953077
+}
+
+func(migration *Migration1712953077) Up(ctx context.Context, db any) error {
+	return nil
+}
+
+func(migration *Migration1712953077) Down(ctx context.Context, db any) error {
+	return nil
+}
+`
+
+const describedMigrations = `package migrations
+
+import (
+	"context"
+	mig "github.com/golibry/go-migrations/migration"
+)
+
+const usersVersion = 1712953080
+
+func init() {
+	mig.DefaultRegistry.Register(new(UsersMigration))
+}
+
+// UsersMigration creates the users table
+type UsersMigration struct{}
+
+func (m UsersMigration) Version() uint64 { return usersVersion }
+func (m UsersMigration) Up(ctx context.Context, db any) error { return nil }
+func (m UsersMigration) Down(ctx context.Context, db any) error { return nil }
+
+func (m UsersMigration) Metadata() mig.Metadata {
+	return mig.Metadata{
+		Description: "create the users table", Risk: mig.RiskLow, Tags: []string{"users"},
+		Owner: "accounts",
+	}
+}
+
+// ForgottenMigration is not registered, and copied the version of UsersMigration
+type ForgottenMigration struct{}
+
+func (m *ForgottenMigration) Version() uint64 { return uint64(1712953080) }
+func (m *ForgottenMigration) Up(ctx context.Context, db any) error { return nil }
+func (m *ForgottenMigration) Down(ctx context.Context, db any) error { return nil }
+
+// ComputedMigration computes its version
+type ComputedMigration struct{}
+
+func (m *ComputedMigration) Version() uint64 { return computeVersion() }
+func (m *ComputedMigration) Up(ctx context.Context, db any) error { return nil }
+func (m *ComputedMigration) Down(ctx context.Context, db any) error { return nil }
+
+// helper is not a migration
+type helper struct{}
+
+func (h helper) Version() uint64 { return 1 }
+
+func (m *ForgottenMigration) Description() string { return "add users phone index" }
+`
+
+func (suite *InspectTestSuite) writeFile(dir string, name string, source string) {
+	suite.Require().NoError(os.MkdirAll(dir, 0755))
+	suite.Require().NoError(os.WriteFile(filepath.Join(dir, name), []byte(source), 0644))
+}
+
+func (suite *InspectTestSuite) TestItListsTheMigrationsOfADirectory() {
+	dir := suite.T().TempDir()
+	suite.writeFile(dir, "version_1712953077.go", generatedMigration)
+	suite.writeFile(dir, "version_1712953080.go", describedMigrations)
+	suite.writeFile(dir, "version_1712953077_test.go", "package migrations\n\nfunc broken(")
+
+	report, err := Dir(dir)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("migrations", report.Package)
+	suite.Require().Len(report.Migrations, 4)
+
+	suite.Assert().Equal(
+		Migration{
+			File: "version_1712953080.go", Line: 36, Type: "ComputedMigration",
+			FileVersion: 1712953080, Description: "ComputedMigration computes its version",
+		},
+		report.Migrations[0],
+	)
+	suite.Assert().Equal(
+		Migration{
+			File: "version_1712953077.go", Line: 15, Type: "Migration1712953077",
+			Version: 1712953077, FileVersion: 1712953077,
+			Metadata:   migration.Metadata{Author: "jane", Ticket: "DB-1"},
+			Registered: true,
+		},
+		report.Migrations[1],
+	)
+	suite.Assert().Equal(
+		Migration{
+			File: "version_1712953080.go", Line: 15, Type: "UsersMigration",
+			Version: 1712953080, FileVersion: 1712953080, Description: "create the users table",
+			Metadata: migration.Metadata{
+				Description: "create the users table", Tags: []string{"users"}, Owner: "accounts",
+			},
+			Registered: true,
+		},
+		report.Migrations[2],
+	)
+	suite.Assert().Equal("ForgottenMigration", report.Migrations[3].Type)
+	suite.Assert().Equal("add users phone index", report.Migrations[3].Description)
+	suite.Assert().Equal(uint64(1712953080), report.Migrations[3].Version)
+	suite.Assert().False(report.Migrations[3].Registered)
+
+	var problems []string
+	for _, problem := range report.Problems {
+		problems = append(problems, problem.String())
+	}
+	suite.Assert().Equal(
+		[]string{
+			"version_1712953080.go:29: ForgottenMigration is not registered " +
+				"(see migration.Register)",
+			"version_1712953080.go:29: ForgottenMigration declares the version 1712953080, " +
+				"already declared by UsersMigration",
+			"version_1712953080.go:36: ComputedMigration is not registered " +
+				"(see migration.Register)",
+			"version_1712953080.go:38: the version of ComputedMigration is not a constant, " +
+				"it can't be read statically",
+		},
+		problems,
+	)
+}
+
+func (suite *InspectTestSuite) TestItReportsTheMisplacedMigrationsAndTheSyntaxErrors() {
+	dir := suite.T().TempDir()
+	suite.writeFile(dir, "version_1.go", generatedMigration)
+	suite.writeFile(dir, "users.go", "package migrations\n\nfunc broken(")
+
+	report, err := Dir(dir)
+	suite.Require().NoError(err)
+	suite.Require().Len(report.Migrations, 1)
+	suite.Require().Len(report.Problems, 2)
+	suite.Assert().Equal("users.go", report.Problems[0].File)
+	suite.Assert().Equal(3, report.Problems[0].Line)
+	suite.Assert().Equal(
+		"Migration1712953077 declares the version 1712953077, but its file name has the "+
+			"version 1",
+		report.Problems[1].Message,
+	)
+
+	_, err = Dir(filepath.Join(dir, "missing"))
+	suite.Assert().ErrorContains(err, "failed to read the directory")
+}
+
+func (suite *InspectTestSuite) TestItFindsTheMigrationsOfAModule() {
+	root := suite.T().TempDir()
+	suite.writeFile(root, "main.go", "package main\n\nfunc main() {}\n")
+	suite.writeFile(
+		filepath.Join(root, "db", "migrations"), "version_1712953077.go", generatedMigration,
+	)
+	suite.writeFile(
+		filepath.Join(root, "_examples", "migrations"), "version_1712953077.go",
+		generatedMigration,
+	)
+	suite.writeFile(
+		filepath.Join(root, "vendor", "migrations"), "version_1712953077.go", generatedMigration,
+	)
+
+	reports, err := Module(root)
+	suite.Require().NoError(err)
+	suite.Require().Len(reports, 1)
+	suite.Assert().Equal(filepath.Join(root, "db", "migrations"), reports[0].Dir)
+	suite.Assert().Len(reports[0].Migrations, 1)
+	suite.Assert().Empty(reports[0].Problems)
+}
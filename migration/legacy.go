@@ -0,0 +1,119 @@
+package migration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+)
+
+// LegacyRegistrations reports what AdoptLegacyRegistrations did with the migrations registered
+// to DefaultRegistry, so the remaining init() registrations can be tracked across the releases
+// of a codebase moving to explicit registrations.
+type LegacyRegistrations struct {
+	// Adopted holds the versions of the migrations adopted from DefaultRegistry, in order
+	Adopted []uint64
+
+	// Redundant holds the versions registered both to the registry and to DefaultRegistry, by
+	// the same migration type, in order. Their Register calls can be removed.
+	Redundant []uint64
+
+	// Foreign holds the versions registered to DefaultRegistry by the files of other
+	// directories (for example, the ones of other modules), which were not adopted, in order
+	Foreign []uint64
+}
+
+// Remaining returns the versions, in order, which are still registered with Register calls
+// from the directory of the registry. Once it is empty, the registry no longer depends on
+// DefaultRegistry, so a CI pipeline can fail on it to forbid the new init() registrations.
+func (r *LegacyRegistrations) Remaining() []uint64 {
+	remaining := slices.Concat(r.Adopted, r.Redundant)
+	slices.Sort(remaining)
+	return remaining
+}
+
+// AdoptLegacyRegistrations merges into the registry the migrations registered to DefaultRegistry
+// (typically by the Register calls from the init() functions of the migration files), so the
+// packages can move from the init() registrations to explicit ones (for example, with
+// NewEmptyDirMigrationsRegistry and Register) one migration at a time.
+//
+// Only the migrations declared in the directory of the registry are adopted: the ones whose
+// source file is in the directory or, when their source file is unknown, whose version file
+// (version_<version>.go, named or not) is in the directory. The migrations already registered
+// to the registry with the same type are skipped, while the ones with a different type
+// collide, with the collision policy of the registry. The collisions between the migrations of
+// DefaultRegistry are handled with the same policy (a *CollisionError for CollisionFail).
+//
+// Finally, the registry is validated against its directory (see Validate). The report is
+// returned even if the validation fails.
+func (registry *DirMigrationsRegistry) AdoptLegacyRegistrations() (*LegacyRegistrations, error) {
+	if err := DefaultRegistry.applyCollisionPolicy(registry.CollisionPolicy()); err != nil {
+		return nil, fmt.Errorf("failed to adopt the legacy registrations with error: %w", err)
+	}
+
+	report := &LegacyRegistrations{}
+	for _, mig := range DefaultRegistry.OrderedMigrations() {
+		version := mig.Version()
+		files := []string{DefaultRegistry.sources[version]}
+		if quarantined, ok := mig.(*QuarantinedMigration); ok {
+			files = quarantined.Files
+		}
+
+		if !registry.declares(version, files) {
+			report.Foreign = append(report.Foreign, version)
+			continue
+		}
+
+		existing := registry.Get(version)
+		if existing != nil && reflect.TypeOf(existing) == reflect.TypeOf(mig) {
+			report.Redundant = append(report.Redundant, version)
+			continue
+		}
+
+		if err := registry.RegisterFromFile(mig, files[0]); err != nil {
+			return nil, fmt.Errorf(
+				"failed to adopt the legacy migration %d with error: %w", version, err,
+			)
+		}
+		report.Adopted = append(report.Adopted, version)
+	}
+
+	return report, registry.Validate()
+}
+
+// declares checks if one of the source files (or the version file, when they are unknown)
+// belongs to the directory of the registry
+func (registry *DirMigrationsRegistry) declares(version uint64, files []string) bool {
+	dirInfo, err := os.Stat(string(registry.dirPath))
+	if err != nil {
+		return false
+	}
+
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+
+		// the source files of the binaries built elsewhere (or with -trimpath) are matched by
+		// their names
+		if sourceDirInfo, err := os.Stat(filepath.Dir(file)); err == nil {
+			if os.SameFile(dirInfo, sourceDirInfo) {
+				return true
+			}
+		} else if registry.hasFile(filepath.Base(file)) {
+			return true
+		}
+	}
+
+	if slices.ContainsFunc(files, func(file string) bool { return file != "" }) {
+		return false
+	}
+	_, ok := FileNameInDir(registry.dirPath, version)
+	return ok
+}
+
+func (registry *DirMigrationsRegistry) hasFile(name string) bool {
+	info, err := os.Stat(filepath.Join(string(registry.dirPath), name))
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,101 @@
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LegacyTestSuite struct {
+	suite.Suite
+	dirPath         string
+	defaultRegistry *GenericRegistry
+}
+
+func TestLegacyTestSuite(t *testing.T) {
+	suite.Run(t, new(LegacyTestSuite))
+}
+
+func (suite *LegacyTestSuite) SetupTest() {
+	suite.dirPath = suite.T().TempDir()
+	suite.defaultRegistry = DefaultRegistry
+	DefaultRegistry = NewGenericRegistryWithCollisionPolicy(CollisionQuarantine)
+	DefaultRegistry.policy = ""
+}
+
+func (suite *LegacyTestSuite) TearDownTest() {
+	DefaultRegistry = suite.defaultRegistry
+}
+
+// writeFiles creates the migration files of the versions, returning their paths
+func (suite *LegacyTestSuite) writeFiles(dirPath string, versions ...string) []string {
+	var paths []string
+	for _, version := range versions {
+		path := filepath.Join(dirPath, FileNamePrefix+FileNameSeparator+version+".go")
+		suite.Require().NoError(os.WriteFile(path, []byte("package migrations\n"), 0600))
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func (suite *LegacyTestSuite) TestItAdoptsTheLegacyRegistrationsOfTheDirectory() {
+	files := suite.writeFiles(suite.dirPath, "1", "2", "3", "4")
+	otherFiles := suite.writeFiles(suite.T().TempDir(), "5")
+	suite.Require().NoError(DefaultRegistry.RegisterFromFile(&DummyMigration{2}, files[1]))
+	suite.Require().NoError(DefaultRegistry.RegisterFromFile(&DummyMigration{1}, files[0]))
+	suite.Require().NoError(DefaultRegistry.Register(&DummyMigration{3}))
+	suite.Require().NoError(DefaultRegistry.RegisterFromFile(&DummyMigration{5}, otherFiles[0]))
+	suite.Require().NoError(
+		DefaultRegistry.RegisterFromFile(&DummyMigration{4}, "/build/migrations/version_4.go"),
+	)
+
+	dirPath, _ := NewMigrationsDirPath(suite.dirPath)
+	registry := NewEmptyDirMigrationsRegistry(dirPath)
+	explicit := &DummyMigration{1}
+	suite.Require().NoError(registry.Register(explicit))
+
+	report, err := registry.AdoptLegacyRegistrations()
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]uint64{2, 3, 4}, report.Adopted)
+	suite.Assert().Equal([]uint64{1}, report.Redundant)
+	suite.Assert().Equal([]uint64{5}, report.Foreign)
+	suite.Assert().Equal([]uint64{1, 2, 3, 4}, report.Remaining())
+	suite.Assert().Equal([]uint64{1, 2, 3, 4}, registry.OrderedVersions())
+	suite.Assert().Same(explicit, registry.Get(1))
+}
+
+func (suite *LegacyTestSuite) TestItValidatesTheMergedRegistry() {
+	files := suite.writeFiles(suite.dirPath, "1", "2")
+	suite.Require().NoError(DefaultRegistry.RegisterFromFile(&DummyMigration{1}, files[0]))
+
+	dirPath, _ := NewMigrationsDirPath(suite.dirPath)
+	registry := NewEmptyDirMigrationsRegistry(dirPath)
+	report, err := registry.AdoptLegacyRegistrations()
+
+	var registryErr *RegistryError
+	suite.Require().ErrorAs(err, &registryErr)
+	suite.Assert().Equal([]string{"version_2.go"}, registryErr.NotRegistered)
+	suite.Assert().Equal([]uint64{1}, report.Adopted)
+
+	suite.Require().NoError(registry.Register(&DummyMigration{2}))
+	suite.Assert().NoError(registry.Validate())
+}
+
+func (suite *LegacyTestSuite) TestItFailsOnTheCollidingLegacyRegistrations() {
+	files := suite.writeFiles(suite.dirPath, "1", "2")
+	suite.Require().NoError(DefaultRegistry.RegisterFromFile(&DummyMigration{1}, files[0]))
+	dirPath, _ := NewMigrationsDirPath(suite.dirPath)
+
+	registry := NewEmptyDirMigrationsRegistry(dirPath)
+	suite.Require().NoError(registry.Register(&describedMigration{DummyMigration{1}, Metadata{}}))
+	_, err := registry.AdoptLegacyRegistrations()
+	suite.Assert().ErrorContains(err, "failed to adopt the legacy migration 1 with error")
+
+	suite.Require().NoError(DefaultRegistry.RegisterFromFile(&DummyMigration{2}, files[1]))
+	suite.Require().NoError(DefaultRegistry.RegisterFromFile(&DummyMigration{2}, files[1]))
+	_, err = NewEmptyDirMigrationsRegistry(dirPath).AdoptLegacyRegistrations()
+	var collisionErr *CollisionError
+	suite.Assert().ErrorAs(err, &collisionErr)
+}
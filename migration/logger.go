@@ -0,0 +1,26 @@
+package migration
+
+import (
+	"context"
+	"log/slog"
+)
+
+// loggerCtxKey is the context key used to pass the logger of a run
+type loggerCtxKey struct{}
+
+// WithLogger returns a copy of ctx which carries the logger. The migrations handler logs each
+// migration with the logger carried by the run context, and passes it to the migrations with
+// the version and the attempt as attributes.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, logger)
+}
+
+// LoggerFrom returns the logger carried by ctx, or a logger which discards everything if ctx
+// carries none. Migrations can use it to log details (for example, the executed statements)
+// at the debug level, shown with the --verbose flag of the cli.
+func LoggerFrom(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerCtxKey{}).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	return slog.New(slog.DiscardHandler)
+}
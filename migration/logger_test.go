@@ -0,0 +1,32 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LoggerTestSuite struct {
+	suite.Suite
+}
+
+func TestLoggerTestSuite(t *testing.T) {
+	suite.Run(t, new(LoggerTestSuite))
+}
+
+func (suite *LoggerTestSuite) TestItReturnsTheLoggerCarriedByTheContext() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	LoggerFrom(WithLogger(context.Background(), logger)).Info("migrating")
+	suite.Assert().Contains(buf.String(), "msg=migrating")
+}
+
+func (suite *LoggerTestSuite) TestItDiscardsTheLogsWhenTheContextHasNoLogger() {
+	logger := LoggerFrom(context.Background())
+	suite.Require().NotNil(logger)
+	suite.Assert().False(logger.Enabled(context.Background(), slog.LevelError))
+}
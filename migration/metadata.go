@@ -0,0 +1,104 @@
+package migration
+
+import (
+	"slices"
+	"strings"
+)
+
+// Risk levels which can be declared in a migration Metadata. Any other value is allowed,
+// these are only the conventional ones.
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// Metadata is a structured description of a migration, giving tooling and compliance teams
+// a single source of change information.
+type Metadata struct {
+	Author      string   `json:"author,omitempty"`
+	Ticket      string   `json:"ticket,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Risk        string   `json:"risk,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+
+	// Owner is the team which owns the migration, the one notified of its failures (see the
+	// notify package)
+	Owner string `json:"owner,omitempty"`
+}
+
+// IsEmpty checks if no metadata field is set
+func (m Metadata) IsEmpty() bool {
+	return m.Author == "" && m.Ticket == "" && m.Description == "" && m.Risk == "" &&
+		len(m.Tags) == 0 && m.Owner == ""
+}
+
+// String builds a short, human-readable, single line summary of the metadata
+func (m Metadata) String() string {
+	var parts []string
+	if m.Description != "" {
+		parts = append(parts, m.Description)
+	}
+	if m.Ticket != "" {
+		parts = append(parts, "ticket: "+m.Ticket)
+	}
+	if m.Author != "" {
+		parts = append(parts, "author: "+m.Author)
+	}
+	if m.Owner != "" {
+		parts = append(parts, "owner: "+m.Owner)
+	}
+	if m.Risk != "" {
+		parts = append(parts, "risk: "+m.Risk)
+	}
+	if len(m.Tags) > 0 {
+		parts = append(parts, "tags: "+strings.Join(m.Tags, ","))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// MetadataProvider is an optional interface migrations can implement to describe themselves
+type MetadataProvider interface {
+	Metadata() Metadata
+}
+
+// Describable is an optional interface for the migrations which only describe what they do
+// (for example, "add users phone index"), so the listings show it next to their versions. It
+// is lighter than MetadataProvider, whose Description takes precedence.
+type Describable interface {
+	Description() string
+}
+
+// MetadataOf returns the metadata of the migration, with the description of a Describable
+// migration if the metadata has none. The second return value is false if the migration
+// implements neither MetadataProvider nor Describable, nor wraps one which does (see As).
+func MetadataOf(mig Migration) (Metadata, bool) {
+	provider, isProvider := As[MetadataProvider](mig)
+	describable, isDescribable := As[Describable](mig)
+
+	var metadata Metadata
+	if isProvider {
+		metadata = provider.Metadata()
+	}
+	if isDescribable && metadata.Description == "" {
+		metadata.Description = strings.TrimSpace(describable.Description())
+	}
+	return metadata, isProvider || isDescribable
+}
+
+// DescriptionOf returns the description of the migration (see MetadataOf), empty if it has
+// none
+func DescriptionOf(mig Migration) string {
+	metadata, _ := MetadataOf(mig)
+	return metadata.Description
+}
+
+// TagContract is the metadata tag of the contract migrations of an expand/contract (blue/green)
+// rollout: the ones which remove or change the schema the previous app versions depend on
+const TagContract = "contract"
+
+// IsContract checks if the migration metadata is tagged with TagContract
+func IsContract(mig Migration) bool {
+	metadata, _ := MetadataOf(mig)
+	return slices.Contains(metadata.Tags, TagContract)
+}
@@ -0,0 +1,89 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MetadataTestSuite struct {
+	suite.Suite
+}
+
+func TestMetadataTestSuite(t *testing.T) {
+	suite.Run(t, new(MetadataTestSuite))
+}
+
+type describedMigration struct {
+	DummyMigration
+	metadata Metadata
+}
+
+func (m *describedMigration) Metadata() Metadata {
+	return m.metadata
+}
+
+func (suite *MetadataTestSuite) TestItCanGetMetadataOfMigrations() {
+	metadata := Metadata{Author: "Jane", Ticket: "JIRA-1", Risk: RiskHigh, Tags: []string{"a"}}
+
+	actual, ok := MetadataOf(&describedMigration{DummyMigration{1}, metadata})
+	suite.Assert().True(ok)
+	suite.Assert().Equal(metadata, actual)
+
+	actual, ok = MetadataOf(NewDummyMigration(1))
+	suite.Assert().False(ok)
+	suite.Assert().True(actual.IsEmpty())
+}
+
+type describableMigration struct {
+	describedMigration
+	description string
+}
+
+func (m *describableMigration) Description() string {
+	return m.description
+}
+
+func (suite *MetadataTestSuite) TestItCanGetTheDescriptionOfDescribableMigrations() {
+	mig := &describableMigration{describedMigration{DummyMigration{1}, Metadata{}}, " add index "}
+	actual, ok := MetadataOf(mig)
+	suite.Assert().True(ok)
+	suite.Assert().Equal(Metadata{Description: "add index"}, actual)
+	suite.Assert().Equal("add index", DescriptionOf(mig))
+
+	// the description of the metadata takes precedence
+	mig.metadata = Metadata{Description: "add users phone index", Ticket: "JIRA-1"}
+	suite.Assert().Equal("add users phone index", DescriptionOf(mig))
+	suite.Assert().Empty(DescriptionOf(NewDummyMigration(1)))
+}
+
+func (suite *MetadataTestSuite) TestItCanSummarizeMetadata() {
+	scenarios := map[string]struct {
+		metadata Metadata
+		expected string
+	}{
+		"empty": {Metadata{}, ""},
+		"all fields": {
+			Metadata{
+				"Jane", "JIRA-1", "add users phone index", RiskLow, []string{"a", "b"}, "payments",
+			},
+			"add users phone index; ticket: JIRA-1; author: Jane; owner: payments; risk: low; " +
+				"tags: a,b",
+		},
+		"some fields": {Metadata{Ticket: "JIRA-1", Risk: RiskMedium}, "ticket: JIRA-1; risk: medium"},
+	}
+
+	for name, scenario := range scenarios {
+		suite.Assert().Equal(scenario.expected, scenario.metadata.String(), "failed scenario: %s", name)
+		suite.Assert().Equal(
+			scenario.expected == "", scenario.metadata.IsEmpty(), "failed scenario: %s", name,
+		)
+	}
+}
+
+func (suite *MetadataTestSuite) TestItCanDetectContractMigrations() {
+	contract := Metadata{Tags: []string{"cleanup", TagContract}}
+	suite.Assert().True(IsContract(&describedMigration{DummyMigration{1}, contract}))
+	suite.Assert().False(IsContract(&describedMigration{DummyMigration{1}, Metadata{}}))
+	suite.Assert().False(IsContract(NewDummyMigration(1)))
+}
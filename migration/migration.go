@@ -15,7 +15,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strconv"
+	"strings"
 	"text/template"
 	"time"
 )
@@ -32,6 +35,9 @@ const FileNamePrefix = "version"
 // FileNameSeparator A separator used to separate words in a migration file.
 const FileNameSeparator = "_"
 
+// testFileSuffix is the suffix of the Go test files, which are never migration files
+const testFileSuffix = "_test.go"
+
 // Migration Represents the base behavior a migration should include
 type Migration interface {
 	// Version must be a static, globally unique value which identifies the migration file
@@ -105,8 +111,46 @@ func (dm *DummyMigration) Down(ctx context.Context, db any) error { return nil }
 
 // migrationTemplateData holds the data needed to generate a new migration file from a template.
 type migrationTemplateData struct {
-	Version     uint64 // The unique version identifier for the migration
-	PackageName string // The package name for the migration file
+	Version         uint64 // The unique version identifier for the migration
+	PackageName     string // The package name for the migration file
+	PreviousVersion uint64 // The latest version found in the migrations directory, 0 if none
+	Author          string // The migration author, may be empty
+	Ticket          string // The ticket/issue ID the migration belongs to, may be empty
+
+	// The statements executed by Up(), if generated from a SQL script
+	UpStatements []string
+
+	// The down-script stub executed by Down(), if requested (see GenerateOptions.DownStub)
+	DownStatements []ReverseStatement
+}
+
+// GenerateOptions holds the optional metadata injected in a generated migration file, so the
+// file carries traceability information without manual editing.
+type GenerateOptions struct {
+	// Author of the migration (for example, the git author)
+	Author string
+
+	// Ticket is the ticket/issue ID the migration belongs to
+	Ticket string
+
+	// Template is an optional text/template used instead of the built-in TmplContents (for
+	// example, with the company header, imports and helper wrappers of a team). It can use
+	// the .Version, .PackageName, .PreviousVersion, .Author, .Ticket, .UpStatements and
+	// .DownStatements fields and the quote function (strconv.Quote).
+	Template string
+
+	// UpSql is an optional SQL script executed by the generated Up(), statement by statement,
+	// through the sqlhelper package. The db passed to the migration must be a sqlhelper.Execer
+	// (for example, *sql.DB).
+	UpSql string
+
+	// DownStub enables the generation of a best-effort down-script stub for UpSql (see
+	// ReverseSqlStatements), clearly marked for human review, as the Down() starting point
+	DownStub bool
+
+	// Name is an optional, human-readable name of the migration (for example, "add users"),
+	// added to the file name as a slug (see FileName). It must be valid (see ValidateName).
+	Name string
 }
 
 // MigrationsDirPath represents a directory path where migration files are stored.
@@ -150,16 +194,184 @@ func NewMigrationsDirPath(dirPath string) (MigrationsDirPath, error) {
 }
 
 // newMigrationTemplateData creates template data for a new migration file.
-// It generates a version number based on the current Unix timestamp and
-// extracts the package name from the directory path.
+// It generates a version number based on the current Unix timestamp,
+// extracts the package name from the directory path and finds the previous
+// (latest) version from the directory.
 //
 // Parameters:
 //   - dirPath: The migrations directory path
+//   - opts: The optional metadata to be injected in the migration file
 //
 // Returns:
 //   - migrationTemplateData: Data to be used in the migration file template
-func newMigrationTemplateData(dirPath MigrationsDirPath) migrationTemplateData {
-	return migrationTemplateData{uint64(time.Now().Unix()), filepath.Base(string(dirPath))}
+func newMigrationTemplateData(
+	dirPath MigrationsDirPath,
+	opts GenerateOptions,
+) migrationTemplateData {
+	data := migrationTemplateData{
+		Version:         uint64(time.Now().Unix()),
+		PackageName:     filepath.Base(string(dirPath)),
+		PreviousVersion: latestVersionInDir(dirPath),
+		Author:          strings.TrimSpace(opts.Author),
+		Ticket:          strings.TrimSpace(opts.Ticket),
+		UpStatements:    SplitSqlStatements(opts.UpSql),
+	}
+
+	if opts.DownStub {
+		data.DownStatements = ReverseSqlStatements(data.UpStatements)
+	}
+
+	return data
+}
+
+// FileName returns the name of the file of the migration version: version_<version>.go or,
+// with a name, version_<version>_<slug>.go, where the slug is the name in lowercase, with the
+// characters other than ASCII letters and digits replaced by the separator (see Slug).
+func FileName(version uint64, name string) string {
+	fileName := FileNamePrefix + FileNameSeparator + strconv.FormatUint(version, 10)
+	if slug := Slug(name); slug != "" {
+		fileName += FileNameSeparator + slug
+	}
+	return fileName + ".go"
+}
+
+// Slug returns the name in lowercase, with each run of characters other than ASCII letters
+// and digits replaced by a single separator, for example "add_users" for "Add users!". It is
+// empty if the name has no letters or digits.
+func Slug(name string) string {
+	var slug strings.Builder
+	for _, char := range strings.ToLower(name) {
+		switch {
+		case char >= 'a' && char <= 'z' || char >= '0' && char <= '9':
+			slug.WriteRune(char)
+		case slug.Len() > 0 && !strings.HasSuffix(slug.String(), FileNameSeparator):
+			slug.WriteString(FileNameSeparator)
+		}
+	}
+	return strings.TrimSuffix(slug.String(), FileNameSeparator)
+}
+
+// buildSuffixes are the last words of the file names which the go build treats as constraints:
+// the test files and the known GOOS and GOARCH values (see go/build)
+var buildSuffixes = map[string]bool{
+	"test": true,
+	// GOOS
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+	"hurd": true, "illumos": true, "ios": true, "js": true, "linux": true, "nacl": true,
+	"netbsd": true, "openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+	"windows": true, "zos": true,
+	// GOARCH
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+	"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true,
+	"mips64le": true, "mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+	"ppc64le": true, "riscv": true, "riscv64": true, "s390": true, "s390x": true,
+	"sparc": true, "sparc64": true, "wasm": true,
+}
+
+// ValidateName checks that the name of a migration can be added to its file name (see
+// FileName): its slug must not be empty, and must not end with a word the go build treats as
+// a constraint, like test (the test files are never built with the migrations) or a GOOS or
+// GOARCH value (for example, windows or arm64, which restrict the file to a platform).
+func ValidateName(name string) error {
+	slug := Slug(name)
+	if slug == "" {
+		return fmt.Errorf("the name %q has no letters or digits", name)
+	}
+
+	words := strings.Split(slug, FileNameSeparator)
+	if last := words[len(words)-1]; buildSuffixes[last] {
+		return fmt.Errorf(
+			"the name %q ends with %q, which the go build treats as a file name constraint",
+			name, last,
+		)
+	}
+	return nil
+}
+
+// VersionFromFileName extracts the migration version from a migration file name
+// (version_<num>.go or version_<num>_<slug>.go). The second return value is false if the name
+// does not follow the convention. The Go test files (*_test.go) are not migration files.
+func VersionFromFileName(fileName string) (uint64, bool) {
+	fname, ok := strings.CutPrefix(fileName, FileNamePrefix+FileNameSeparator)
+	if !ok || !strings.HasSuffix(fname, ".go") || strings.HasSuffix(fname, testFileSuffix) {
+		return 0, false
+	}
+
+	rawVersion, slug, named := strings.Cut(strings.TrimSuffix(fname, ".go"), FileNameSeparator)
+	if named && slug == "" {
+		return 0, false
+	}
+
+	version, err := strconv.ParseUint(rawVersion, 10, 64)
+
+	if err != nil {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// FileNameInDir returns the name of the file of the migration version in the directory,
+// named or not (see FileName). The second return value is false if there is no such file (or
+// the directory can't be read).
+func FileNameInDir(dirPath MigrationsDirPath, version uint64) (string, bool) {
+	dirEntries, err := os.ReadDir(string(dirPath))
+	if err != nil {
+		return "", false
+	}
+
+	for _, item := range dirEntries {
+		if item.IsDir() {
+			continue
+		}
+
+		if fileVersion, ok := VersionFromFileName(item.Name()); ok && fileVersion == version {
+			return item.Name(), true
+		}
+	}
+
+	return "", false
+}
+
+// FileNameOf returns the name of the file declaring the Version() method of the migration,
+// found from the debug information of the binary, so the named files are reported by their
+// actual names. It falls back to the unnamed file name of the version (see FileName) if the
+// file is unknown or its name doesn't match the version (for example, for the migrations
+// wrapped by other types).
+func FileNameOf(mig Migration) string {
+	version := mig.Version()
+	if method, ok := reflect.TypeOf(mig).MethodByName("Version"); ok {
+		if fn := runtime.FuncForPC(method.Func.Pointer()); fn != nil {
+			file, _ := fn.FileLine(fn.Entry())
+			fileName := filepath.Base(file)
+			if fileVersion, ok := VersionFromFileName(fileName); ok && fileVersion == version {
+				return fileName
+			}
+		}
+	}
+	return FileName(version, "")
+}
+
+// latestVersionInDir returns the highest migration version found in the directory, or 0 if
+// there are no migration files (or the directory can't be read).
+func latestVersionInDir(dirPath MigrationsDirPath) uint64 {
+	dirEntries, err := os.ReadDir(string(dirPath))
+	if err != nil {
+		return 0
+	}
+
+	var latest uint64
+	for _, item := range dirEntries {
+		if item.IsDir() {
+			continue
+		}
+
+		if version, ok := VersionFromFileName(item.Name()); ok && version > latest {
+			latest = version
+		}
+	}
+
+	return latest
 }
 
 // GenerateBlankMigration creates a new blank migration file in the specified directory.
@@ -173,7 +385,36 @@ func newMigrationTemplateData(dirPath MigrationsDirPath) migrationTemplateData {
 //   - fileName: The name of the generated migration file
 //   - err: An error if template processing or file creation fails
 func GenerateBlankMigration(dirPath MigrationsDirPath) (fileName string, err error) {
-	tmpl, err := template.New("migration").Parse(TmplContents)
+	return GenerateMigration(dirPath, GenerateOptions{})
+}
+
+// GenerateMigration works like GenerateBlankMigration, but also injects the provided
+// metadata (author, ticket) and the previous migration version in the generated file, which
+// can be generated from a custom template. With a name, the file is named
+// "version_[timestamp]_[slug].go" (see FileName).
+//
+// Parameters:
+//   - dirPath: The directory where the migration file should be created
+//   - opts: The optional metadata to be injected in the migration file
+//
+// Returns:
+//   - fileName: The name of the generated migration file
+//   - err: An error if template processing or file creation fails
+func GenerateMigration(dirPath MigrationsDirPath, opts GenerateOptions) (fileName string, err error) {
+	tmplContents := TmplContents
+	if opts.Template != "" {
+		tmplContents = opts.Template
+	}
+
+	if opts.Name != "" {
+		if err := ValidateName(opts.Name); err != nil {
+			return "", fmt.Errorf("%w, %w", ErrBlankMigration, err)
+		}
+	}
+
+	tmpl, err := template.New("migration").
+		Funcs(template.FuncMap{"quote": strconv.Quote}).
+		Parse(tmplContents)
 
 	if err != nil {
 		return "", fmt.Errorf(
@@ -181,8 +422,8 @@ func GenerateBlankMigration(dirPath MigrationsDirPath) (fileName string, err err
 		)
 	}
 
-	tmplData := newMigrationTemplateData(dirPath)
-	fileName = FileNamePrefix + FileNameSeparator + strconv.Itoa(int(tmplData.Version)) + ".go"
+	tmplData := newMigrationTemplateData(dirPath, opts)
+	fileName = FileName(tmplData.Version, opts.Name)
 	filePath := filepath.Join(string(dirPath), fileName)
 
 	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
@@ -1,6 +1,7 @@
 package migration
 
 import (
+	"go/format"
 	"os"
 	"path"
 	"path/filepath"
@@ -136,3 +137,153 @@ func (suite *MigrationTestSuite) TestItFailsToGenerateBlankMigrationWhenNewFileC
 	expectedErr := &os.PathError{}
 	suite.Assert().ErrorAs(err, &expectedErr)
 }
+
+func (suite *MigrationTestSuite) TestItCanGenerateMigrationFileWithMetadata() {
+	for _, existing := range []string{"version_7.go", "version_100.go", "version_abc.go"} {
+		fp, _ := os.Create(filepath.Join(suite.migrationsDirPath, existing))
+		_ = fp.Close()
+	}
+
+	migDir, _ := NewMigrationsDirPath(suite.migrationsDirPath)
+	fileName, err := GenerateMigration(
+		migDir, GenerateOptions{Author: " Jane Doe <jane@example.com> ", Ticket: "JIRA-123"},
+	)
+	fileContents, _ := os.ReadFile(filepath.Join(suite.migrationsDirPath, fileName))
+
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(fileContents), "// Author: Jane Doe <jane@example.com>\n")
+	suite.Assert().Contains(string(fileContents), "// Ticket: JIRA-123\n")
+	suite.Assert().Contains(string(fileContents), "// Previous version: 100\n")
+}
+
+func (suite *MigrationTestSuite) TestItOmitsMissingMetadataFromGeneratedMigrationFile() {
+	migDir, _ := NewMigrationsDirPath(suite.migrationsDirPath)
+	fileName, err := GenerateBlankMigration(migDir)
+	fileContents, _ := os.ReadFile(filepath.Join(suite.migrationsDirPath, fileName))
+
+	suite.Require().NoError(err)
+	suite.Assert().NotContains(string(fileContents), "// Author:")
+	suite.Assert().NotContains(string(fileContents), "// Ticket:")
+	suite.Assert().NotContains(string(fileContents), "// Previous version:")
+}
+
+func (suite *MigrationTestSuite) TestItCanGenerateMigrationFileFromCustomTemplate() {
+	migDir, _ := NewMigrationsDirPath(suite.migrationsDirPath)
+	fileName, err := GenerateMigration(
+		migDir,
+		GenerateOptions{
+			Ticket:   "JIRA-123",
+			Template: "// Copyright ACME\npackage {{.PackageName}}\n// {{.Ticket}} {{.Version}}\n",
+		},
+	)
+	fileContents, _ := os.ReadFile(filepath.Join(suite.migrationsDirPath, fileName))
+
+	suite.Require().NoError(err)
+	version := strings.TrimSuffix(strings.TrimPrefix(fileName, "version_"), ".go")
+	suite.Assert().Equal(
+		"// Copyright ACME\npackage "+filepath.Base(suite.migrationsDirPath)+"\n// JIRA-123 "+
+			version+"\n",
+		string(fileContents),
+	)
+
+	_, err = GenerateMigration(migDir, GenerateOptions{Template: "{{.Missing"})
+	suite.Assert().ErrorContains(err, "template parsing failed")
+}
+
+func (suite *MigrationTestSuite) TestItCanGenerateSqlMigrationFileWithDownStub() {
+	migDir, _ := NewMigrationsDirPath(suite.migrationsDirPath)
+	fileName, err := GenerateMigration(
+		migDir,
+		GenerateOptions{
+			UpSql:    "CREATE TABLE users (id INT);\nUPDATE settings SET value = \"a\";",
+			DownStub: true,
+		},
+	)
+	fileContents, _ := os.ReadFile(filepath.Join(suite.migrationsDirPath, fileName))
+
+	suite.Require().NoError(err)
+	_, err = format.Source(fileContents)
+	suite.Require().NoError(err, string(fileContents))
+	suite.Assert().Contains(string(fileContents), `"github.com/golibry/go-migrations/sqlhelper"`)
+	suite.Assert().Contains(
+		string(fileContents),
+		`sqlhelper.Exec(ctx, db.(sqlhelper.Execer), "CREATE TABLE users (id INT)")`,
+	)
+	suite.Assert().Contains(string(fileContents), "// REVIEW: best-effort reverse")
+	suite.Assert().Contains(
+		string(fileContents),
+		"// TODO: no automatic reverse for: UPDATE settings SET value = \"a\"\n",
+	)
+	suite.Assert().Contains(
+		string(fileContents),
+		`sqlhelper.Exec(ctx, db.(sqlhelper.Execer), "DROP TABLE IF EXISTS users")`,
+	)
+	suite.Assert().Contains(string(fileContents), "CaptureCapable() bool {\n\treturn true")
+}
+
+func (suite *MigrationTestSuite) TestItParsesTheNamedMigrationFileNames() {
+	scenarios := map[string]struct {
+		version uint64
+		ok      bool
+	}{
+		"version_1712953077.go":             {1712953077, true},
+		"version_1712953077_add_users.go":   {1712953077, true},
+		"version_7_add_users_phone_idx.go":  {7, true},
+		"version_7_.go":                     {0, false},
+		"version_7_test.go":                 {0, false},
+		"version_7_add_users_test.go":       {0, false},
+		"version_abc_add_users.go":          {0, false},
+		"version_1712953077_add_users.sql":  {0, false},
+		"migration_1712953077_add_users.go": {0, false},
+	}
+
+	for fileName, scenario := range scenarios {
+		version, ok := VersionFromFileName(fileName)
+		suite.Assert().Equal(scenario.version, version, "scenario %s", fileName)
+		suite.Assert().Equal(scenario.ok, ok, "scenario %s", fileName)
+	}
+
+	suite.Assert().Equal("version_7.go", FileName(7, ""))
+	suite.Assert().Equal("version_7_add_users_phone_idx.go", FileName(7, " Add users' phone-idx!"))
+	suite.Assert().Equal("", Slug("--"))
+	suite.Assert().Equal("version_7.go", FileNameOf(NewDummyMigration(7)))
+}
+
+func (suite *MigrationTestSuite) TestItCanGenerateNamedMigrationFile() {
+	fp, _ := os.Create(filepath.Join(suite.migrationsDirPath, "version_100_add_users.go"))
+	_ = fp.Close()
+
+	migDir, _ := NewMigrationsDirPath(suite.migrationsDirPath)
+	fileName, err := GenerateMigration(migDir, GenerateOptions{Name: "Add users' phone index"})
+	fileContents, _ := os.ReadFile(filepath.Join(suite.migrationsDirPath, fileName))
+
+	suite.Require().NoError(err)
+	suite.Assert().Regexp(`^version_\d+_add_users_phone_index\.go$`, fileName)
+	suite.Assert().Contains(string(fileContents), "// Previous version: 100\n")
+
+	version, _ := VersionFromFileName(fileName)
+	suite.Assert().Contains(string(fileContents), "type Migration"+strconv.FormatUint(version, 10))
+	named, ok := FileNameInDir(migDir, version)
+	suite.Assert().True(ok)
+	suite.Assert().Equal(fileName, named)
+
+	_, err = GenerateMigration(migDir, GenerateOptions{Name: "!?"})
+	suite.Assert().ErrorIs(err, ErrBlankMigration)
+	_, err = GenerateMigration(migDir, GenerateOptions{Name: "add users test"})
+	suite.Assert().ErrorContains(err, `the name "add users test" ends with "test"`)
+	entries, _ := os.ReadDir(suite.migrationsDirPath)
+	suite.Assert().Len(entries, 2)
+}
+
+func (suite *MigrationTestSuite) TestItRejectsTheNamesEndingWithBuildConstraints() {
+	for _, name := range []string{"test", "Add users_test", "fix linux", "drop arm64", "ios"} {
+		suite.Assert().ErrorContains(
+			ValidateName(name), "which the go build treats as a file name constraint",
+			"scenario %s", name,
+		)
+	}
+	for _, name := range []string{"add users", "testing", "linux users", "Add Windows fonts"} {
+		suite.Assert().NoError(ValidateName(name), "scenario %s", name)
+	}
+	suite.Assert().ErrorContains(ValidateName("--"), "has no letters or digits")
+}
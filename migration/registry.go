@@ -4,19 +4,31 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 	"slices"
 	"sort"
-	"strconv"
 	"strings"
 )
 
-// DefaultRegistry is a global registry that migrations can self-register to.
-var DefaultRegistry = NewGenericRegistry()
+// DefaultRegistry is a global registry that migrations can self-register to. The migrations
+// declaring the same version are quarantined until a collision policy is applied (see
+// NewAutoDirMigrationsRegistryWithPolicy). Without one, the collisions fail the up runs
+// before any migration is executed.
+var DefaultRegistry = &GenericRegistry{
+	migrations:    make(map[uint64]Migration),
+	sources:       make(map[uint64]string),
+	shadowedFiles: make(map[string]bool),
+	collided:      make(map[uint64]bool),
+	collecting:    true,
+}
 
-// Register adds a migration to the global DefaultRegistry.
+// Register adds a migration to the global DefaultRegistry, with the file it is called from as
+// the source file of the migration.
 // This is typically called from an init() function in a migration file.
 func Register(migration Migration) {
-	if err := DefaultRegistry.Register(migration); err != nil {
+	_, file, _, _ := runtime.Caller(1)
+	if err := DefaultRegistry.RegisterFromFile(migration, file); err != nil {
 		panic(fmt.Errorf("failed to register migration to DefaultRegistry: %w", err))
 	}
 }
@@ -49,24 +61,170 @@ type MigrationsRegistry interface {
 // GenericRegistry is a generic implementation for MigrationsRegistry
 type GenericRegistry struct {
 	migrations map[uint64]Migration
+
+	// sources holds the source files of the migrations, when known
+	sources map[uint64]string
+
+	// shadowedFiles holds the base names of the files declaring a colliding version, which
+	// the DirMigrationsRegistry doesn't check against the registered migrations
+	shadowedFiles map[string]bool
+
+	// collided holds the colliding versions, quarantined or resolved
+	collided map[uint64]bool
+
+	// collecting is true if the colliding migrations are quarantined, instead of failing
+	collecting bool
+	policy     CollisionPolicy
 }
 
 // NewGenericRegistry creates a new, empty registry
 func NewGenericRegistry() *GenericRegistry {
-	return &GenericRegistry{make(map[uint64]Migration)}
+	return NewGenericRegistryWithCollisionPolicy(CollisionFail)
+}
+
+// NewGenericRegistryWithCollisionPolicy creates a new, empty registry which handles the
+// migrations declaring an already registered version with the policy
+func NewGenericRegistryWithCollisionPolicy(policy CollisionPolicy) *GenericRegistry {
+	return &GenericRegistry{
+		migrations:    make(map[uint64]Migration),
+		sources:       make(map[uint64]string),
+		shadowedFiles: make(map[string]bool),
+		collided:      make(map[uint64]bool),
+		collecting:    policy != CollisionFail,
+		policy:        policy,
+	}
 }
 
 func (registry *GenericRegistry) Register(migration Migration) error {
-	if _, ok := registry.migrations[migration.Version()]; ok {
+	return registry.RegisterFromFile(migration, "")
+}
+
+// RegisterFromFile is the Register method, which also records the source file of the
+// migration, if known (empty otherwise), for the collision policies
+func (registry *GenericRegistry) RegisterFromFile(migration Migration, file string) error {
+	version := migration.Version()
+	existing, ok := registry.migrations[version]
+	if quarantined, isQuarantined := migration.(*QuarantinedMigration); isQuarantined && !ok {
+		registry.migrations[version] = quarantined
+		registry.collided[version] = true
+		registry.shadow(quarantined.Files...)
+		return nil
+	}
+
+	if !ok {
+		registry.migrations[version] = migration
+		if file != "" {
+			registry.sources[version] = file
+		}
+		return nil
+	}
+
+	if !registry.collecting {
 		return errors.New(
 			"failed to register new migration. The migration is already registered",
 		)
 	}
 
-	registry.migrations[migration.Version()] = migration
+	quarantined, isQuarantined := existing.(*QuarantinedMigration)
+	if !isQuarantined {
+		quarantined = &QuarantinedMigration{
+			version:    version,
+			Candidates: []Migration{existing},
+			Files:      []string{registry.sources[version]},
+		}
+		delete(registry.sources, version)
+		registry.migrations[version] = quarantined
+	}
+	quarantined.Candidates = append(quarantined.Candidates, migration)
+	quarantined.Files = append(quarantined.Files, file)
+	registry.collided[version] = true
+	registry.shadow(quarantined.Files...)
+
+	if registry.policy == CollisionPreferNewestFile {
+		return registry.resolveNewest(quarantined)
+	}
 	return nil
 }
 
+// CollisionPolicy returns the policy applied to the migrations declaring the same version
+func (registry *GenericRegistry) CollisionPolicy() CollisionPolicy {
+	if registry.policy == "" {
+		return CollisionFail
+	}
+	return registry.policy
+}
+
+// Collisions returns the quarantined versions, in version order
+func (registry *GenericRegistry) Collisions() []*QuarantinedMigration {
+	var collisions []*QuarantinedMigration
+	for _, mig := range registry.OrderedMigrations() {
+		if quarantined, ok := mig.(*QuarantinedMigration); ok {
+			collisions = append(collisions, quarantined)
+		}
+	}
+	return collisions
+}
+
+// ResolveCollision keeps the candidate with the given index for the quarantined version
+func (registry *GenericRegistry) ResolveCollision(version uint64, candidate int) error {
+	quarantined, ok := registry.migrations[version].(*QuarantinedMigration)
+	if !ok {
+		return fmt.Errorf("migration version %d is not quarantined", version)
+	}
+
+	if candidate < 0 || candidate >= len(quarantined.Candidates) {
+		return fmt.Errorf(
+			"migration version %d has no candidate %d (%d candidates)",
+			version, candidate, len(quarantined.Candidates),
+		)
+	}
+
+	registry.migrations[version] = quarantined.Candidates[candidate]
+	if file := quarantined.Files[candidate]; file != "" {
+		registry.sources[version] = file
+	}
+	return nil
+}
+
+// applyCollisionPolicy handles the quarantined versions with the policy: CollisionFail fails
+// with a *CollisionError, CollisionPreferNewestFile resolves them, while the other policies
+// leave them quarantined
+func (registry *GenericRegistry) applyCollisionPolicy(policy CollisionPolicy) error {
+	switch policy {
+	case CollisionFail, CollisionQuarantine, CollisionInteractive:
+	case CollisionPreferNewestFile:
+		for _, quarantined := range registry.Collisions() {
+			if err := registry.resolveNewest(quarantined); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown migration version collision policy %q", policy)
+	}
+
+	registry.policy = policy
+	if collisions := registry.Collisions(); policy == CollisionFail && len(collisions) > 0 {
+		return &CollisionError{Quarantined: collisions}
+	}
+	return nil
+}
+
+func (registry *GenericRegistry) resolveNewest(quarantined *QuarantinedMigration) error {
+	newest, err := quarantined.newestCandidate()
+	if err != nil {
+		return err
+	}
+	return registry.ResolveCollision(quarantined.Version(), newest)
+}
+
+func (registry *GenericRegistry) shadow(files ...string) {
+	for _, file := range files {
+		if file != "" {
+			registry.shadowedFiles[filepath.Base(file)] = true
+		}
+	}
+}
+
 func (registry *GenericRegistry) OrderedVersions() []uint64 {
 	var versions []uint64
 	for _, mig := range registry.migrations {
@@ -118,8 +276,54 @@ func NewEmptyDirMigrationsRegistry(dirPath MigrationsDirPath) *DirMigrationsRegi
 
 // NewAutoDirMigrationsRegistry builds a migrations registry using migrations
 // from DefaultRegistry and validates them against the specified directory.
+// Panics if several migrations declare the same version (see
+// NewAutoDirMigrationsRegistryWithPolicy).
 func NewAutoDirMigrationsRegistry(dirPath MigrationsDirPath) *DirMigrationsRegistry {
-	return NewDirMigrationsRegistry(dirPath, DefaultRegistry.OrderedMigrations())
+	return NewAutoDirMigrationsRegistryWithPolicy(dirPath, CollisionFail)
+}
+
+// NewAutoDirMigrationsRegistryWithPolicy builds a migrations registry using migrations from
+// DefaultRegistry, like NewAutoDirMigrationsRegistry, handling the migrations which declare
+// the same version with the policy. The files declaring a colliding version are not validated
+// against the registered migrations.
+func NewAutoDirMigrationsRegistryWithPolicy(
+	dirPath MigrationsDirPath,
+	policy CollisionPolicy,
+) *DirMigrationsRegistry {
+	if err := DefaultRegistry.applyCollisionPolicy(policy); err != nil {
+		panic(fmt.Errorf("failed to register migration to DefaultRegistry: %w", err))
+	}
+
+	migRegistry := newAutoDirMigrationsRegistry(dirPath)
+	migRegistry.AssertValidRegistry()
+	return migRegistry
+}
+
+// NewUncheckedAutoDirMigrationsRegistry builds a migrations registry using migrations from
+// DefaultRegistry, like NewAutoDirMigrationsRegistry, but without validating them against the
+// specified directory, so the divergences can be reported with Validate (for example, by the
+// validate command of the cli package) instead of panicking
+func NewUncheckedAutoDirMigrationsRegistry(dirPath MigrationsDirPath) *DirMigrationsRegistry {
+	return newAutoDirMigrationsRegistry(dirPath)
+}
+
+// newAutoDirMigrationsRegistry copies DefaultRegistry, with its collisions and policy, into a
+// new DirMigrationsRegistry
+func newAutoDirMigrationsRegistry(dirPath MigrationsDirPath) *DirMigrationsRegistry {
+	migRegistry := NewEmptyDirMigrationsRegistry(dirPath)
+	migRegistry.collecting = true
+	migRegistry.policy = DefaultRegistry.policy
+	for file := range DefaultRegistry.shadowedFiles {
+		migRegistry.shadowedFiles[file] = true
+	}
+	for version := range DefaultRegistry.collided {
+		migRegistry.collided[version] = true
+	}
+
+	for _, mig := range DefaultRegistry.OrderedMigrations() {
+		_ = migRegistry.RegisterFromFile(mig, DefaultRegistry.sources[mig.Version()])
+	}
+	return migRegistry
 }
 
 // NewDirMigrationsRegistry builds a migrations registry with all migrations available
@@ -149,6 +353,9 @@ func NewDirMigrationsRegistry(
 // registered in the registry.
 // If it returns false, the next 2 return values show which file names are missing and which
 // file names are extra, compared to the registered migrations.
+// The files and versions of the migrations declaring the same version are skipped.
+// The migration files can be named (version_<version>_<slug>.go, see FileName); the extra
+// file names are the unnamed ones of the versions.
 // Errors if reading the directory fails (maybe insufficient permissions?)
 func (registry *DirMigrationsRegistry) HasAllMigrationsRegistered() (
 	bool, []string, []string, error,
@@ -163,63 +370,87 @@ func (registry *DirMigrationsRegistry) HasAllMigrationsRegistered() (
 
 	registeredCopy := make(map[uint64]Migration)
 	for _, mig := range registry.migrations {
-		registeredCopy[mig.Version()] = mig
+		if !registry.collided[mig.Version()] {
+			registeredCopy[mig.Version()] = mig
+		}
 	}
 
 	var missing, extra []string
 	for _, item := range dirEntries {
-		if item.IsDir() || !strings.HasPrefix(item.Name(), FileNamePrefix+FileNameSeparator) {
+		if item.IsDir() || registry.shadowedFiles[item.Name()] {
 			continue
 		}
 
-		fname := strings.TrimPrefix(item.Name(), FileNamePrefix+FileNameSeparator)
-		version, err := strconv.Atoi(strings.TrimSuffix(fname, ".go"))
-
-		if err != nil {
+		version, ok := VersionFromFileName(item.Name())
+		if !ok {
 			continue
 		}
 
-		if _, ok := registeredCopy[uint64(version)]; ok {
-			delete(registeredCopy, uint64(version))
+		if _, ok := registeredCopy[version]; ok {
+			delete(registeredCopy, version)
 		} else {
 			missing = append(missing, item.Name())
 		}
 	}
 
 	for version := range registeredCopy {
-		extra = append(extra, FileNamePrefix+FileNameSeparator+strconv.Itoa(int(version))+".go")
+		extra = append(extra, FileName(version, ""))
 	}
 
 	return len(missing) == 0 && len(extra) == 0, missing, extra, nil
 }
 
-// AssertValidRegistry checks if there are any issues with the list of registered
-// migrations and panics if it finds any
-func (registry *DirMigrationsRegistry) AssertValidRegistry() {
+// RegistryError describes the divergences between the migration files from the directory and
+// the registered migrations
+type RegistryError struct {
+	// NotRegistered holds the names of the migration files which are not registered
+	NotRegistered []string
+
+	// Extra holds the file names of the registered migrations without a file
+	Extra []string
+}
+
+func (e *RegistryError) Error() string {
+	notRegisteredMigrations := strings.Join(e.NotRegistered, ", ")
+	extraMigrations := strings.Join(e.Extra, ", ")
+	if notRegisteredMigrations == "" {
+		notRegisteredMigrations = "none"
+	}
+	if extraMigrations == "" {
+		extraMigrations = "none"
+	}
+
+	return fmt.Sprintf(
+		"registry has invalid state. %s. Not registered: %s. Extra migrations: %s",
+		"You must register all migrations before running migrations",
+		notRegisteredMigrations,
+		extraMigrations,
+	)
+}
+
+// Validate checks if the registered migrations match the migration files from the directory.
+// It returns a *RegistryError with the divergences, if any, so they can be reported (for
+// example, by a CI pipeline) instead of panicking.
+func (registry *DirMigrationsRegistry) Validate() error {
 	allRegistered, notRegistered, extraRegistered, registryErr :=
 		registry.HasAllMigrationsRegistered()
 
 	if registryErr != nil {
-		panic(fmt.Errorf("registry has invalid state: %w", registryErr))
+		return fmt.Errorf("registry has invalid state: %w", registryErr)
 	}
 
 	if !allRegistered {
-		notRegisteredMigrations := strings.Join(notRegistered, ", ")
-		extraMigrations := strings.Join(extraRegistered, ", ")
-		if notRegisteredMigrations == "" {
-			notRegisteredMigrations = "none"
-		}
-		if extraMigrations == "" {
-			extraMigrations = "none"
-		}
+		slices.Sort(notRegistered)
+		slices.Sort(extraRegistered)
+		return &RegistryError{NotRegistered: notRegistered, Extra: extraRegistered}
+	}
+	return nil
+}
 
-		panic(
-			fmt.Errorf(
-				"registry has invalid state. %s. Not registered: %s. Extra migrations: %s",
-				"You must register all migrations before running migrations",
-				notRegisteredMigrations,
-				extraMigrations,
-			),
-		)
+// AssertValidRegistry checks if there are any issues with the list of registered
+// migrations and panics if it finds any (see Validate)
+func (registry *DirMigrationsRegistry) AssertValidRegistry() {
+	if err := registry.Validate(); err != nil {
+		panic(err)
 	}
 }
@@ -149,3 +149,49 @@ func (suite *RegistryTestSuite) TestItCanComputeExtraAndMissingRegisteredMigrati
 	suite.Assert().Equal(expectedExtra, extra)
 }
 
+func (suite *RegistryTestSuite) TestItReportsTheRegistryDivergences() {
+	migDir, _ := NewMigrationsDirPath(suite.migrationsDirPath)
+	dirRegistry := NewEmptyDirMigrationsRegistry(migDir)
+	for _, version := range []string{"1", "2"} {
+		migFn := FileNamePrefix + FileNameSeparator + version + ".go"
+		fp, _ := os.Create(filepath.Join(suite.migrationsDirPath, migFn))
+		_ = fp.Close()
+	}
+
+	_ = dirRegistry.Register(&DummyMigration{1})
+	_ = dirRegistry.Register(&DummyMigration{5})
+
+	var registryErr *RegistryError
+	suite.Require().ErrorAs(dirRegistry.Validate(), &registryErr)
+	suite.Assert().Equal(
+		[]string{FileNamePrefix + FileNameSeparator + "2.go"}, registryErr.NotRegistered,
+	)
+	suite.Assert().Equal([]string{FileNamePrefix + FileNameSeparator + "5.go"}, registryErr.Extra)
+	suite.Assert().PanicsWithError(registryErr.Error(), dirRegistry.AssertValidRegistry)
+
+	_ = dirRegistry.Register(&DummyMigration{2})
+	suite.Assert().ErrorContains(dirRegistry.Validate(), "Not registered: none")
+}
+
+func (suite *RegistryTestSuite) TestItValidatesTheNamedMigrationFiles() {
+	migDir, _ := NewMigrationsDirPath(suite.migrationsDirPath)
+	dirRegistry := NewEmptyDirMigrationsRegistry(migDir)
+	for _, migFn := range []string{"version_1_add_users.go", "version_2.go", "version_3_x_test.go"} {
+		fp, _ := os.Create(filepath.Join(suite.migrationsDirPath, migFn))
+		_ = fp.Close()
+	}
+
+	_ = dirRegistry.Register(&DummyMigration{2})
+	_ = dirRegistry.Register(&DummyMigration{4})
+
+	allRegistered, missing, extra, err := dirRegistry.HasAllMigrationsRegistered()
+	suite.Require().NoError(err)
+	suite.Assert().False(allRegistered)
+	suite.Assert().Equal([]string{"version_1_add_users.go"}, missing)
+	suite.Assert().Equal([]string{"version_4.go"}, extra)
+
+	_ = dirRegistry.Register(&DummyMigration{1})
+	fp, _ := os.Create(filepath.Join(suite.migrationsDirPath, "version_4_drop_users.go"))
+	_ = fp.Close()
+	suite.Assert().NoError(dirRegistry.Validate())
+}
@@ -0,0 +1,174 @@
+package migration
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ReverseStatement is a statement of a down-script stub, generated by ReverseSqlStatements
+type ReverseStatement struct {
+	// Sql is the statement which reverses Source, empty if none could be derived
+	Sql string
+
+	// Source is the reversed statement, on a single line
+	Source string
+}
+
+// sqlIdent matches a, possibly qualified and quoted, SQL identifier
+const sqlIdent = "([\\w.\"`\\[\\]]+)"
+
+// reverseRules derive the reverse of the statements they match, from the submatches
+var reverseRules = []struct {
+	pattern *regexp.Regexp
+	reverse func(match []string) string
+}{
+	{
+		regexp.MustCompile(
+			`(?is)^CREATE\s+(?:TEMPORARY\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?` + sqlIdent,
+		),
+		func(match []string) string { return "DROP TABLE IF EXISTS " + match[1] },
+	},
+	{
+		regexp.MustCompile(
+			`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?` +
+				sqlIdent + `\s+ON\s`,
+		),
+		func(match []string) string { return "DROP INDEX IF EXISTS " + match[1] },
+	},
+	{
+		regexp.MustCompile(
+			`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?` + sqlIdent,
+		),
+		func(match []string) string { return "DROP VIEW IF EXISTS " + match[1] },
+	},
+	{
+		regexp.MustCompile(
+			`(?is)^ALTER\s+TABLE\s+` + sqlIdent + `\s+ADD\s+CONSTRAINT\s+` + sqlIdent,
+		),
+		func(match []string) string {
+			return "ALTER TABLE " + match[1] + " DROP CONSTRAINT " + match[2]
+		},
+	},
+	{
+		regexp.MustCompile(
+			`(?is)^ALTER\s+TABLE\s+` + sqlIdent +
+				`\s+ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?` + sqlIdent,
+		),
+		func(match []string) string {
+			switch strings.ToUpper(match[2]) {
+			case "INDEX", "KEY", "UNIQUE", "PRIMARY", "FOREIGN", "CHECK", "FULLTEXT", "SPATIAL":
+				return ""
+			}
+			return "ALTER TABLE " + match[1] + " DROP COLUMN " + match[2]
+		},
+	},
+	{
+		regexp.MustCompile(
+			`(?is)^ALTER\s+TABLE\s+` + sqlIdent + `\s+RENAME\s+TO\s+` + sqlIdent + `$`,
+		),
+		func(match []string) string {
+			return "ALTER TABLE " + match[2] + " RENAME TO " + match[1]
+		},
+	},
+	{
+		regexp.MustCompile(
+			`(?is)^ALTER\s+TABLE\s+` + sqlIdent + `\s+RENAME\s+(?:COLUMN\s+)?` + sqlIdent +
+				`\s+TO\s+` + sqlIdent + `$`,
+		),
+		func(match []string) string {
+			return "ALTER TABLE " + match[1] + " RENAME COLUMN " + match[3] + " TO " + match[2]
+		},
+	},
+	{
+		regexp.MustCompile(`(?is)^RENAME\s+TABLE\s+` + sqlIdent + `\s+TO\s+` + sqlIdent + `$`),
+		func(match []string) string { return "RENAME TABLE " + match[2] + " TO " + match[1] },
+	},
+}
+
+// SplitSqlStatements splits a SQL script into its statements, on the semicolons which are not
+// quoted. Empty statements are dropped. Scripts with procedural blocks (for example, Postgres
+// dollar-quoted function bodies) are not supported.
+func SplitSqlStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	var quote rune
+
+	for _, char := range script {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"' || char == '`':
+			quote = char
+		case char == ';':
+			if statement := strings.TrimSpace(current.String()); statement != "" {
+				statements = append(statements, statement)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteRune(char)
+	}
+
+	if statement := strings.TrimSpace(current.String()); statement != "" {
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+// ReverseSqlStatements builds a best-effort down-script stub for the statements of an Up()
+// script, in the reverse order: DROP for CREATE TABLE/INDEX/VIEW, DROP COLUMN/CONSTRAINT for
+// single ALTER TABLE ... ADD statements and the reverse of the renames. The statements which
+// can't be reversed (for example, data changes or DROP statements) have an empty Sql. The stub
+// is only a starting point and must be reviewed: for example, DROP INDEX is generated in the
+// Postgres/SQLite syntax, MySQL also needs the table name.
+func ReverseSqlStatements(statements []string) []ReverseStatement {
+	reversed := make([]ReverseStatement, 0, len(statements))
+	for i := len(statements) - 1; i >= 0; i-- {
+		source := strings.Join(strings.Fields(statements[i]), " ")
+		reversed = append(
+			reversed, ReverseStatement{Sql: reverseSqlStatement(source), Source: source},
+		)
+	}
+	return reversed
+}
+
+// reverseSqlStatement returns the statement which reverses the (single line) statement, or an
+// empty string if none can be derived
+func reverseSqlStatement(statement string) string {
+	// ALTER TABLE statements with multiple actions are not reversed
+	if strings.HasPrefix(strings.ToUpper(statement), "ALTER") && hasTopLevelComma(statement) {
+		return ""
+	}
+
+	for _, rule := range reverseRules {
+		if match := rule.pattern.FindStringSubmatch(statement); match != nil {
+			return rule.reverse(match)
+		}
+	}
+	return ""
+}
+
+// hasTopLevelComma checks if the statement has a comma outside parentheses and quotes
+func hasTopLevelComma(statement string) bool {
+	depth := 0
+	var quote rune
+	for _, char := range statement {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"' || char == '`':
+			quote = char
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+		case char == ',' && depth == 0:
+			return true
+		}
+	}
+	return false
+}
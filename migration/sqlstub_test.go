@@ -0,0 +1,86 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SqlStubTestSuite struct {
+	suite.Suite
+}
+
+func TestSqlStubTestSuite(t *testing.T) {
+	suite.Run(t, new(SqlStubTestSuite))
+}
+
+func (suite *SqlStubTestSuite) TestItSplitsSqlScriptsOnUnquotedSemicolons() {
+	statements := SplitSqlStatements(
+		"CREATE TABLE a (id INT);\n\nINSERT INTO a VALUES ('x;y');  ;UPDATE a SET id = 2",
+	)
+
+	suite.Assert().Equal(
+		[]string{"CREATE TABLE a (id INT)", "INSERT INTO a VALUES ('x;y')", "UPDATE a SET id = 2"},
+		statements,
+	)
+	suite.Assert().Empty(SplitSqlStatements(" ;\n"))
+}
+
+func (suite *SqlStubTestSuite) TestItReversesSqlStatements() {
+	scenarios := map[string]struct {
+		statement string
+		expected  string
+	}{
+		"create table": {
+			"CREATE TABLE IF NOT EXISTS users (\n  id INT,\n  name TEXT\n)",
+			"DROP TABLE IF EXISTS users",
+		},
+		"create index": {
+			"create unique index concurrently users_name_idx on users (name)",
+			"DROP INDEX IF EXISTS users_name_idx",
+		},
+		"create view": {
+			"CREATE OR REPLACE VIEW active_users AS SELECT 1", "DROP VIEW IF EXISTS active_users",
+		},
+		"add column": {
+			"ALTER TABLE users ADD COLUMN price DECIMAL(10,2) NOT NULL",
+			"ALTER TABLE users DROP COLUMN price",
+		},
+		"add constraint": {
+			"ALTER TABLE users ADD CONSTRAINT users_name_uq UNIQUE (name)",
+			"ALTER TABLE users DROP CONSTRAINT users_name_uq",
+		},
+		"rename table": {
+			"ALTER TABLE users RENAME TO customers", "ALTER TABLE customers RENAME TO users",
+		},
+		"rename column": {
+			"ALTER TABLE users RENAME COLUMN name TO full_name",
+			"ALTER TABLE users RENAME COLUMN full_name TO name",
+		},
+		"mysql rename": {"RENAME TABLE `a` TO `b`", "RENAME TABLE `b` TO `a`"},
+		"add index":    {"ALTER TABLE users ADD INDEX idx (name)", ""},
+		"many actions": {"ALTER TABLE users ADD a INT, ADD b INT", ""},
+		"data change":  {"UPDATE users SET name = ''", ""},
+		"drop":         {"DROP TABLE users", ""},
+	}
+
+	for name, scenario := range scenarios {
+		reversed := ReverseSqlStatements([]string{scenario.statement})
+		suite.Require().Len(reversed, 1)
+		suite.Assert().Equal(scenario.expected, reversed[0].Sql, "failed scenario: %s", name)
+	}
+}
+
+func (suite *SqlStubTestSuite) TestItReversesTheStatementsOrder() {
+	reversed := ReverseSqlStatements(
+		[]string{"CREATE TABLE a (id INT)", "CREATE INDEX a_idx\n  ON a (id)"},
+	)
+
+	suite.Assert().Equal(
+		[]ReverseStatement{
+			{Sql: "DROP INDEX IF EXISTS a_idx", Source: "CREATE INDEX a_idx ON a (id)"},
+			{Sql: "DROP TABLE IF EXISTS a", Source: "CREATE TABLE a (id INT)"},
+		},
+		reversed,
+	)
+}
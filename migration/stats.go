@@ -0,0 +1,48 @@
+package migration
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// rowsCounterCtxKey is the context key used to pass a RowsCounter to a migration
+type rowsCounterCtxKey struct{}
+
+// RowsCounter accumulates the number of rows affected by the statements executed by a
+// migration. It is safe for concurrent use.
+type RowsCounter struct {
+	rows     atomic.Int64
+	recorded atomic.Bool
+}
+
+// Add adds n affected rows to the counter
+func (c *RowsCounter) Add(n int64) {
+	c.rows.Add(n)
+	c.recorded.Store(true)
+}
+
+// Count returns the total number of affected rows recorded so far
+func (c *RowsCounter) Count() int64 {
+	return c.rows.Load()
+}
+
+// Recorded returns true if at least one affected rows count was recorded (even if 0)
+func (c *RowsCounter) Recorded() bool {
+	return c.recorded.Load()
+}
+
+// WithRowsCounter returns a copy of ctx which carries a new RowsCounter, along with the
+// counter. The migrations handler uses it to collect the affected rows of each migration.
+func WithRowsCounter(ctx context.Context) (context.Context, *RowsCounter) {
+	counter := &RowsCounter{}
+	return context.WithValue(ctx, rowsCounterCtxKey{}, counter), counter
+}
+
+// RecordRowsAffected adds n affected rows to the RowsCounter carried by ctx. It does nothing
+// if ctx does not carry a counter. Migrations which don't use the sqlhelper package can call
+// it directly to have their affected rows reported.
+func RecordRowsAffected(ctx context.Context, n int64) {
+	if counter, ok := ctx.Value(rowsCounterCtxKey{}).(*RowsCounter); ok {
+		counter.Add(n)
+	}
+}
@@ -0,0 +1,36 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StatsTestSuite struct {
+	suite.Suite
+}
+
+func TestStatsTestSuite(t *testing.T) {
+	suite.Run(t, new(StatsTestSuite))
+}
+
+func (suite *StatsTestSuite) TestItCanRecordRowsAffectedInContextCounter() {
+	ctx, counter := WithRowsCounter(context.Background())
+	suite.Assert().False(counter.Recorded())
+
+	RecordRowsAffected(ctx, 0)
+	suite.Assert().True(counter.Recorded())
+
+	RecordRowsAffected(ctx, 3)
+	RecordRowsAffected(ctx, 4)
+	suite.Assert().Equal(int64(7), counter.Count())
+}
+
+func (suite *StatsTestSuite) TestItIgnoresRowsAffectedWhenContextHasNoCounter() {
+	suite.Assert().NotPanics(
+		func() {
+			RecordRowsAffected(context.Background(), 3)
+		},
+	)
+}
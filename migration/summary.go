@@ -0,0 +1,126 @@
+package migration
+
+import (
+	"slices"
+	"time"
+)
+
+// minDatedVersion is the smallest version read as a Unix timestamp (2001-09-09). The smaller
+// versions (for example, 1, 2, 3...) are undated.
+const minDatedVersion = 1_000_000_000
+
+// IsDatedVersion checks if the version is large enough to be read as a Unix timestamp, like the
+// versions of the generated migrations
+func IsDatedVersion(version uint64) bool {
+	return version >= minDatedVersion
+}
+
+// PeriodCount is the number of migrations whose version falls in a period
+type PeriodCount struct {
+	// Period is the year (2024) or the month (2024-04) of the versions
+	Period string `json:"period"`
+	Count  int    `json:"count"`
+}
+
+// VersionGap is the distance between two consecutive versions of a registry
+type VersionGap struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+
+	// Size is the difference of the versions, in seconds for the timestamp versions
+	Size uint64 `json:"size"`
+}
+
+// RegistrySummary describes the composition of a registry, to plan squashes and audits
+type RegistrySummary struct {
+	Count        int    `json:"count"`
+	FirstVersion uint64 `json:"firstVersion"`
+	LastVersion  uint64 `json:"lastVersion"`
+
+	// PerYear and PerMonth count the migrations per period of their version, read as a Unix
+	// timestamp (in UTC), like the versions of the generated migrations. They are in
+	// chronological order and skip the periods without migrations.
+	PerYear  []PeriodCount `json:"perYear"`
+	PerMonth []PeriodCount `json:"perMonth"`
+
+	// Undated counts the versions which are too small to be timestamps (see IsDatedVersion)
+	Undated int `json:"undated"`
+
+	// LargestGaps holds the largest distances between consecutive versions, largest first
+	LargestGaps []VersionGap `json:"largestGaps"`
+
+	// PerTag counts the migrations per metadata tag (see MetadataProvider). A migration with
+	// several tags is counted once for each.
+	PerTag   map[string]int `json:"perTag"`
+	Untagged int            `json:"untagged"`
+}
+
+// Summarize builds the summary of the registered migrations, with at most maxGaps of the
+// largest gaps between their versions
+func Summarize(registry MigrationsRegistry, maxGaps int) RegistrySummary {
+	migrations := registry.OrderedMigrations()
+	summary := RegistrySummary{
+		Count:       len(migrations),
+		PerYear:     []PeriodCount{},
+		PerMonth:    []PeriodCount{},
+		LargestGaps: []VersionGap{},
+		PerTag:      make(map[string]int),
+	}
+	if len(migrations) == 0 {
+		return summary
+	}
+
+	summary.FirstVersion = migrations[0].Version()
+	summary.LastVersion = migrations[len(migrations)-1].Version()
+
+	var gaps []VersionGap
+	for i, mig := range migrations {
+		version := mig.Version()
+		if IsDatedVersion(version) {
+			date := time.Unix(int64(version), 0).UTC()
+			summary.PerYear = countPeriod(summary.PerYear, date.Format("2006"))
+			summary.PerMonth = countPeriod(summary.PerMonth, date.Format("2006-01"))
+		} else {
+			summary.Undated++
+		}
+
+		metadata, _ := MetadataOf(mig)
+		if len(metadata.Tags) == 0 {
+			summary.Untagged++
+		}
+		for _, tag := range metadata.Tags {
+			summary.PerTag[tag]++
+		}
+
+		if i > 0 {
+			previous := migrations[i-1].Version()
+			gaps = append(gaps, VersionGap{From: previous, To: version, Size: version - previous})
+		}
+	}
+
+	// The stable sort keeps the older of the equal gaps first
+	slices.SortStableFunc(
+		gaps, func(a, b VersionGap) int {
+			switch {
+			case a.Size > b.Size:
+				return -1
+			case a.Size < b.Size:
+				return 1
+			}
+			return 0
+		},
+	)
+	summary.LargestGaps = append(summary.LargestGaps, gaps[:min(max(maxGaps, 0), len(gaps))]...)
+
+	return summary
+}
+
+// countPeriod increments the count of the period, which is the last one or a new one, since
+// the versions are visited in order
+func countPeriod(counts []PeriodCount, period string) []PeriodCount {
+	if len(counts) > 0 && counts[len(counts)-1].Period == period {
+		counts[len(counts)-1].Count++
+		return counts
+	}
+	return append(counts, PeriodCount{Period: period, Count: 1})
+}
@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SummaryTestSuite struct {
+	suite.Suite
+}
+
+func TestSummaryTestSuite(t *testing.T) {
+	suite.Run(t, new(SummaryTestSuite))
+}
+
+func (suite *SummaryTestSuite) TestItCanSummarizeTheRegistryComposition() {
+	registry := NewGenericRegistry()
+	_ = registry.Register(NewDummyMigration(5))
+	_ = registry.Register(
+		&describedMigration{DummyMigration{1704067200}, Metadata{Tags: []string{"users"}}},
+	)
+	_ = registry.Register(
+		&describedMigration{
+			DummyMigration{1706745600}, Metadata{Tags: []string{"users", TagContract}},
+		},
+	)
+	_ = registry.Register(NewDummyMigration(1706832000))
+	_ = registry.Register(NewDummyMigration(1735689600))
+
+	summary := Summarize(registry, 2)
+
+	suite.Assert().Equal(5, summary.Count)
+	suite.Assert().Equal(uint64(5), summary.FirstVersion)
+	suite.Assert().Equal(uint64(1735689600), summary.LastVersion)
+	suite.Assert().Equal(1, summary.Undated)
+	suite.Assert().Equal([]PeriodCount{{"2024", 3}, {"2025", 1}}, summary.PerYear)
+	suite.Assert().Equal(
+		[]PeriodCount{{"2024-01", 1}, {"2024-02", 2}, {"2025-01", 1}}, summary.PerMonth,
+	)
+	suite.Assert().Equal(
+		[]VersionGap{
+			{From: 5, To: 1704067200, Size: 1704067195},
+			{From: 1706832000, To: 1735689600, Size: 28857600},
+		},
+		summary.LargestGaps,
+	)
+	suite.Assert().Equal(map[string]int{"users": 2, TagContract: 1}, summary.PerTag)
+	suite.Assert().Equal(3, summary.Untagged)
+}
+
+func (suite *SummaryTestSuite) TestItCanSummarizeAnEmptyRegistry() {
+	summary := Summarize(NewGenericRegistry(), 5)
+
+	suite.Assert().Zero(summary.Count)
+	suite.Assert().Empty(summary.PerMonth)
+	suite.Assert().Empty(summary.LargestGaps)
+	suite.Assert().NotNil(summary.LargestGaps)
+}
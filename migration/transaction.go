@@ -0,0 +1,18 @@
+package migration
+
+// Transactional is an optional interface for migrations which declare whether Up() and Down()
+// run all their statements in a single transaction (see the guidance on transactions of the
+// Migration interface), so the plan of a run shows which migrations leave no partial changes
+// when they fail. The library doesn't start the transactions of the migrations.
+type Transactional interface {
+	Transactional() bool
+}
+
+// TransactionalOf returns whether the migration runs in a transaction. The second return value
+// is false if neither the migration nor the ones it wraps implement Transactional (see As).
+func TransactionalOf(mig Migration) (bool, bool) {
+	if declarer, ok := As[Transactional](mig); ok {
+		return declarer.Transactional(), true
+	}
+	return false, false
+}
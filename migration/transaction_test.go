@@ -0,0 +1,37 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TransactionTestSuite struct {
+	suite.Suite
+}
+
+func TestTransactionTestSuite(t *testing.T) {
+	suite.Run(t, new(TransactionTestSuite))
+}
+
+type transactionalMigration struct {
+	DummyMigration
+	transactional bool
+}
+
+func (m *transactionalMigration) Transactional() bool {
+	return m.transactional
+}
+
+func (suite *TransactionTestSuite) TestItCanGetWhetherMigrationsRunInATransaction() {
+	transactional, declared := TransactionalOf(&transactionalMigration{DummyMigration{1}, true})
+	suite.Assert().True(declared)
+	suite.Assert().True(transactional)
+
+	transactional, declared = TransactionalOf(&transactionalMigration{DummyMigration{2}, false})
+	suite.Assert().True(declared)
+	suite.Assert().False(transactional)
+
+	_, declared = TransactionalOf(NewDummyMigration(3))
+	suite.Assert().False(declared)
+}
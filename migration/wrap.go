@@ -0,0 +1,31 @@
+package migration
+
+// Wrapper is an optional interface for the migrations which wrap another migration (for
+// example, featureflag.FlaggedMigration), so the optional interfaces of the wrapped migration
+// (Irreversible, Hotfix, Transactional, CaptureCapable, MetadataProvider and Describable) are
+// still found by the helpers of this package (see As). A wrapper which implements one of them
+// itself overrides the wrapped migration.
+type Wrapper interface {
+	// Unwrap returns the wrapped migration
+	Unwrap() Migration
+}
+
+// As returns the first migration of the chain of the wrapped migrations (see Wrapper),
+// starting with mig itself, which implements T. The second return value is false if none
+// does.
+func As[T any](mig Migration) (T, bool) {
+	for mig != nil {
+		if target, ok := mig.(T); ok {
+			return target, true
+		}
+
+		wrapper, ok := mig.(Wrapper)
+		if !ok {
+			break
+		}
+		mig = wrapper.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
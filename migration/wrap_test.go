@@ -0,0 +1,51 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type WrapTestSuite struct {
+	suite.Suite
+}
+
+func TestWrapTestSuite(t *testing.T) {
+	suite.Run(t, new(WrapTestSuite))
+}
+
+type wrappingMigration struct {
+	Migration
+}
+
+func (m *wrappingMigration) Unwrap() Migration {
+	return m.Migration
+}
+
+type nonTransactionalWrapper struct {
+	wrappingMigration
+}
+
+func (m *nonTransactionalWrapper) Transactional() bool {
+	return false
+}
+
+func (suite *WrapTestSuite) TestItFindsTheOptionalInterfacesOfTheWrappedMigrations() {
+	wrapped := &wrappingMigration{
+		&wrappingMigration{&transactionalMigration{DummyMigration{1}, true}},
+	}
+	transactional, declared := TransactionalOf(wrapped)
+	suite.Assert().True(declared)
+	suite.Assert().True(transactional)
+
+	overriding := &nonTransactionalWrapper{wrappingMigration{wrapped}}
+	transactional, declared = TransactionalOf(overriding)
+	suite.Assert().True(declared)
+	suite.Assert().False(transactional)
+
+	_, declared = TransactionalOf(&wrappingMigration{NewDummyMigration(2)})
+	suite.Assert().False(declared)
+
+	_, declared = TransactionalOf(&wrappingMigration{})
+	suite.Assert().False(declared)
+}
@@ -0,0 +1,65 @@
+// Package migrations provides application level helpers for integrating with migrations
+// which are executed by another process (for example, a designated deployment job).
+//
+// Use the cli package to build the migrations runner and this package in the applications
+// that depend on the migrated state.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// DefaultPollInterval is used by WaitUntilCurrent when a non-positive poll interval is provided
+const DefaultPollInterval = time.Second
+
+// WaitUntilCurrent blocks until all migrations from the registry have been executed (by another
+// runner) and their executions are finished, or until the context is done. It is meant to gate
+// application startup in deployments where only one designated job runs the migrations.
+//
+// The repository is polled every pollInterval. Errors while loading the executions (or an
+// inconsistent executions state) are not fatal, as they may be transient while the runner is
+// working, but the last one is reported if the context is done before the state is current.
+func WaitUntilCurrent(
+	ctx context.Context,
+	registry migration.MigrationsRegistry,
+	repository execution.Repository,
+	pollInterval time.Duration,
+) error {
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		plan, err := handler.NewPlan(registry, repository)
+		if err == nil && len(plan.AllToBeExecuted()) == 0 {
+			return nil
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			if plan != nil {
+				lastErr = fmt.Errorf(
+					"%d migrations are not executed yet", len(plan.AllToBeExecuted()),
+				)
+			}
+
+			return fmt.Errorf(
+				"failed to wait for migrations to be executed: %w",
+				errors.Join(ctx.Err(), lastErr),
+			)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationsTestSuite struct {
+	suite.Suite
+}
+
+func TestMigrationsTestSuite(t *testing.T) {
+	suite.Run(t, new(MigrationsTestSuite))
+}
+
+// syncRepository guards the in memory repository so it can be written by a fake runner
+// while being polled
+type syncRepository struct {
+	mu sync.Mutex
+	execution.InMemoryRepository
+}
+
+func (repo *syncRepository) LoadExecutions() ([]execution.MigrationExecution, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return append([]execution.MigrationExecution{}, repo.PersistedExecutions...), repo.LoadErr
+}
+
+func (repo *syncRepository) Save(exec execution.MigrationExecution) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return repo.InMemoryRepository.Save(exec)
+}
+
+func (suite *MigrationsTestSuite) TestItReturnsImmediatelyWhenAllMigrationsAreExecuted() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	repo := &execution.InMemoryRepository{}
+	repo.SaveAll([]execution.MigrationExecution{{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2}})
+
+	err := WaitUntilCurrent(context.Background(), registry, repo, time.Hour)
+	suite.Assert().NoError(err)
+}
+
+func (suite *MigrationsTestSuite) TestItWaitsUntilPendingMigrationsAreExecuted() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &syncRepository{}
+	_ = repo.Save(execution.MigrationExecution{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2})
+	_ = repo.Save(execution.MigrationExecution{Version: 2, ExecutedAtMs: 3})
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		_ = repo.Save(execution.MigrationExecution{Version: 2, ExecutedAtMs: 3, FinishedAtMs: 4})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	suite.Assert().NoError(WaitUntilCurrent(ctx, registry, repo, 5*time.Millisecond))
+}
+
+func (suite *MigrationsTestSuite) TestItFailsWhenTheContextIsDoneBeforeMigrationsAreExecuted() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+
+	scenarios := map[string]struct {
+		repo        *execution.InMemoryRepository
+		expectedErr string
+	}{
+		"pending migrations": {&execution.InMemoryRepository{}, "1 migrations are not executed"},
+		"load failure": {
+			&execution.InMemoryRepository{LoadErr: errors.New("load failed")},
+			"load failed",
+		},
+	}
+
+	for name, scenario := range scenarios {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		err := WaitUntilCurrent(ctx, registry, scenario.repo, 5*time.Millisecond)
+		cancel()
+
+		suite.Assert().ErrorIs(err, context.DeadlineExceeded, "failed scenario: %s", name)
+		suite.Assert().ErrorContains(err, scenario.expectedErr, "failed scenario: %s", name)
+	}
+}
@@ -0,0 +1,170 @@
+// Package notify routes the failures of the migrations to the team which owns them, so the
+// on-call for a failed backfill lands with its authors rather than with the platform team.
+//
+// A migration declares its owner in its metadata (see migration.Metadata.Owner). The migrations
+// without a declared owner get one from the ownership rules of the Router, CODEOWNERS-style:
+// the last rule whose pattern matches the migration wins. The notification of a failure is sent
+// to the Notifier of the owner, or to the fallback one (for example, the platform team).
+//
+// Notifications are produced by a handler.ExecutionListener, see NewListener.
+//
+// Example:
+//
+//	router := notify.NewRouter(notify.NewWebhookNotifier(platformHookUrl, nil))
+//	router.Route("payments", notify.NewWebhookNotifier(paymentsHookUrl, nil))
+//	_ = router.Assign("2024*", "payments")
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// Notification describes a failed migration Up()/Down() call
+type Notification struct {
+	Time      time.Time          `json:"time"`
+	Owner     string             `json:"owner,omitempty"`
+	RunId     string             `json:"runId,omitempty"`
+	Version   uint64             `json:"version"`
+	Direction string             `json:"direction"`
+	Forced    bool               `json:"forced"`
+	Error     string             `json:"error"`
+	Metadata  migration.Metadata `json:"metadata"`
+}
+
+// String builds a short, human-readable, single line summary of the notification
+func (n Notification) String() string {
+	summary := fmt.Sprintf("migration %d %s failed: %s", n.Version, n.Direction, n.Error)
+	if n.Metadata.Description != "" {
+		summary += " (" + n.Metadata.Description + ")"
+	}
+	return summary
+}
+
+// Notifier sends the notifications to a team channel or endpoint
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// ownershipRule assigns an owner to the migrations matched by its pattern
+type ownershipRule struct {
+	matcher *handler.Matcher
+	owner   string
+}
+
+// Router sends each notification to the Notifier of the owner of the failed migration
+type Router struct {
+	mu        sync.Mutex
+	fallback  Notifier
+	notifiers map[string]Notifier
+	rules     []ownershipRule
+}
+
+// NewRouter builds a new Router. The fallback notifier gets the notifications of the
+// migrations without an owner, or whose owner has no notifier. It can be nil, to drop them.
+func NewRouter(fallback Notifier) *Router {
+	return &Router{fallback: fallback, notifiers: make(map[string]Notifier)}
+}
+
+// Route sets the notifier of the owner's notifications
+func (r *Router) Route(owner string, notifier Notifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifiers[owner] = notifier
+}
+
+// Assign adds an ownership rule: the migrations without a declared owner which match the
+// pattern (see handler.NewMatcher: a version or description prefix ending with "*", or a
+// regular expression) are owned by owner. Like in CODEOWNERS files, the last matching rule
+// wins, so add the broad rules first.
+func (r *Router) Assign(pattern string, owner string) error {
+	matcher, err := handler.NewMatcher(pattern)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append(r.rules, ownershipRule{matcher, owner})
+	return nil
+}
+
+// OwnerOf returns the owner of the migration: the one declared in its metadata or, if none, the
+// one of the last matching ownership rule. It is empty if the migration has no owner.
+func (r *Router) OwnerOf(mig migration.Migration) string {
+	if metadata, _ := migration.MetadataOf(mig); metadata.Owner != "" {
+		return metadata.Owner
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.rules) - 1; i >= 0; i-- {
+		if r.rules[i].matcher.Matches(mig) {
+			return r.rules[i].owner
+		}
+	}
+	return ""
+}
+
+// Notify implements the Notifier.Notify method, sending the notification to the notifier of
+// its owner, or to the fallback one
+func (r *Router) Notify(ctx context.Context, notification Notification) error {
+	r.mu.Lock()
+	notifier, ok := r.notifiers[notification.Owner]
+	if !ok || notification.Owner == "" {
+		notifier = r.fallback
+	}
+	r.mu.Unlock()
+
+	if notifier == nil {
+		return nil
+	}
+	return notifier.Notify(ctx, notification)
+}
+
+// NewNotification builds the notification of a failed execution event, owned by the owner of
+// its migration (see Router.OwnerOf)
+func (r *Router) NewNotification(event handler.ExecutionEvent) Notification {
+	notification := Notification{
+		Time:      time.Now(),
+		RunId:     event.RunId,
+		Direction: event.Direction,
+		Forced:    event.Forced,
+	}
+
+	if mig := event.Migration.Migration; mig != nil {
+		notification.Version = mig.Version()
+		notification.Metadata, _ = migration.MetadataOf(mig)
+		notification.Owner = r.OwnerOf(mig)
+	}
+
+	if event.Err != nil {
+		notification.Error = event.Err.Error()
+	}
+	return notification
+}
+
+// NewListener builds a handler.ExecutionListener which routes a notification for each failed
+// migration Up()/Down() call. The successful calls are not notified. If the notifier fails,
+// its error is returned along with the one of the migration.
+func NewListener(router *Router) handler.ExecutionListener {
+	return func(ctx context.Context, event handler.ExecutionEvent) error {
+		if event.Succeeded() {
+			return nil
+		}
+
+		notification := router.NewNotification(event)
+		if err := router.Notify(ctx, notification); err != nil {
+			return fmt.Errorf(
+				"failed to notify the owner %q of migration %d with error: %w",
+				notification.Owner, notification.Version, err,
+			)
+		}
+		return nil
+	}
+}
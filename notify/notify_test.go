@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type NotifyTestSuite struct {
+	suite.Suite
+}
+
+func TestNotifyTestSuite(t *testing.T) {
+	suite.Run(t, new(NotifyTestSuite))
+}
+
+type recordingNotifier struct {
+	notifications []Notification
+	err           error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, notification Notification) error {
+	n.notifications = append(n.notifications, notification)
+	return n.err
+}
+
+type ownedMigration struct {
+	migration.DummyMigration
+	owner string
+}
+
+func (m *ownedMigration) Metadata() migration.Metadata {
+	return migration.Metadata{Description: "backfill orders totals", Owner: m.owner}
+}
+
+func (m *ownedMigration) Up(context.Context, any) error {
+	return errors.New("deadlock found")
+}
+
+func (suite *NotifyTestSuite) TestItResolvesTheOwnersOfTheMigrations() {
+	router := NewRouter(nil)
+	suite.Require().NoError(router.Assign("17*", "platform"))
+	suite.Require().NoError(router.Assign("^1712", "payments"))
+	suite.Assert().Error(router.Assign("(", "broken"))
+
+	suite.Assert().Equal(
+		"search", router.OwnerOf(&ownedMigration{*migration.NewDummyMigration(1712), "search"}),
+	)
+	suite.Assert().Equal("payments", router.OwnerOf(migration.NewDummyMigration(1712)))
+	suite.Assert().Equal("platform", router.OwnerOf(migration.NewDummyMigration(1799)))
+	suite.Assert().Empty(router.OwnerOf(migration.NewDummyMigration(2024)))
+}
+
+func (suite *NotifyTestSuite) TestItRoutesTheFailuresToTheirOwners() {
+	fallback, payments := &recordingNotifier{}, &recordingNotifier{}
+	router := NewRouter(fallback)
+	router.Route("payments", payments)
+
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(&ownedMigration{*migration.NewDummyMigration(2), "payments"})
+	migrationsHandler, _ := handler.NewHandler(registry, &execution.InMemoryRepository{}, nil)
+	migrationsHandler.AddListener(NewListener(router))
+
+	_, err := migrationsHandler.MigrateUp(context.Background(), handler.NumOfRuns(2))
+	suite.Require().ErrorContains(err, "deadlock found")
+
+	suite.Assert().Empty(fallback.notifications)
+	suite.Require().Len(payments.notifications, 1)
+	notification := payments.notifications[0]
+	suite.Assert().Equal("payments", notification.Owner)
+	suite.Assert().Equal(uint64(2), notification.Version)
+	suite.Assert().Equal(handler.DirectionUp, notification.Direction)
+	suite.Assert().NotEmpty(notification.RunId)
+	suite.Assert().Equal(
+		"migration 2 up failed: deadlock found (backfill orders totals)", notification.String(),
+	)
+
+	payments.err = errors.New("channel archived")
+	_, err = migrationsHandler.ForceUp(context.Background(), 2)
+	suite.Assert().ErrorContains(err, `failed to notify the owner "payments" of migration 2`)
+
+	_, err = migrationsHandler.ForceDown(context.Background(), 1)
+	suite.Require().NoError(err)
+	suite.Assert().Empty(fallback.notifications)
+	_ = router.Notify(context.Background(), Notification{Owner: "search"})
+	suite.Assert().Len(fallback.notifications, 1)
+}
+
+func (suite *NotifyTestSuite) TestItPostsTheNotificationsToWebhooks() {
+	var actualReq *http.Request
+	var actualBody map[string]any
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				actualReq = r
+				body, _ := io.ReadAll(r.Body)
+				_ = json.Unmarshal(body, &actualBody)
+			},
+		),
+	)
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL, nil)
+	err := notifier.Notify(
+		context.Background(),
+		Notification{Owner: "payments", Version: 3, Direction: handler.DirectionDown, Error: "x"},
+	)
+
+	suite.Require().NoError(err)
+	suite.Assert().Equal(http.MethodPost, actualReq.Method)
+	suite.Assert().Equal("application/json", actualReq.Header.Get("Content-Type"))
+	suite.Assert().Equal("migration 3 down failed: x", actualBody["text"])
+	suite.Assert().Equal("payments", actualBody["owner"])
+	suite.Assert().Equal(float64(3), actualBody["version"])
+}
+
+func (suite *NotifyTestSuite) TestItFailsOnUnexpectedWebhookResponseStatus() {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte("no_service"))
+			},
+		),
+	)
+	defer server.Close()
+
+	err := NewWebhookNotifier(server.URL, nil).Notify(context.Background(), Notification{})
+	suite.Assert().ErrorContains(err, "unexpected webhook response status 404: no_service")
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// webhookPayload is the JSON body posted by the WebhookNotifier: the notification fields, along
+// with a text summary, which chat incoming webhooks (Slack, Mattermost...) display
+type webhookPayload struct {
+	Text string `json:"text"`
+	Notification
+}
+
+// WebhookNotifier is a Notifier which posts each notification as JSON to an endpoint (a team
+// chat incoming webhook, an incident management integration...)
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier builds a new WebhookNotifier. If client is nil, http.DefaultClient is used.
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookNotifier{url: url, client: client}
+}
+
+// Notify implements the Notifier.Notify method
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(webhookPayload{notification.String(), notification})
+	if err != nil {
+		return fmt.Errorf("failed to build the webhook body with error: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build the webhook request with error: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed with error: %w", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected webhook response status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+// Package plan exports the plan of a migrations run as a signed artifact, and checks that an
+// artifact still matches the registry and the executions state before it is run, for
+// compliance replays: what was reviewed and approved is provably what runs in production.
+//
+// An Artifact holds the versions a run executes, in order, along with the fingerprints of the
+// registered migrations and of the applied versions it was computed on. It is signed with an
+// Ed25519 key by the approver (see Artifact.Sign) and verified with the matching public key
+// by the production run (see Artifact.Verify and Artifact.Check).
+//
+// The registry fingerprint covers the checksums of the migrations: their versions, types and
+// metadata, along with the hash of their content computed by a lockfile hashing strategy (see
+// lockfile.Hasher). With the file or go-ast strategies, a change of the code of a migration
+// between the export and the run is detected, as long as the migration files are available
+// where the artifact is checked.
+package plan
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/lockfile"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// FormatVersion is the version of the artifact format written by this package
+const FormatVersion = 2
+
+var (
+	// ErrInvalidSignature is returned by Artifact.Verify when the artifact is not signed, or was
+	// changed after it was signed, or was signed with another key
+	ErrInvalidSignature = errors.New("the plan artifact signature is invalid")
+
+	// ErrMismatch is returned by Artifact.Check when the artifact no longer matches the registry,
+	// the executions state or the current plan
+	ErrMismatch = errors.New("the plan artifact does not match")
+)
+
+// Artifact is the exported plan of a run
+type Artifact struct {
+	FormatVersion int       `json:"formatVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+
+	// Direction is handler.DirectionUp or handler.DirectionDown
+	Direction string `json:"direction"`
+
+	// Versions of the planned migrations, in the order they are executed
+	Versions []uint64 `json:"versions"`
+
+	// Hashing is the name of the hashing strategy of the migrations content covered by the
+	// registry fingerprint (see lockfile.Hasher)
+	Hashing string `json:"hashing"`
+
+	// RegistryHash is the fingerprint of the registered migrations (see RegistryHash)
+	RegistryHash string `json:"registryHash"`
+
+	// StateHash is the fingerprint of the applied versions (see StateHash)
+	StateHash string `json:"stateHash"`
+
+	// Signature is the base64 encoded Ed25519 signature of all the other fields
+	Signature string `json:"signature,omitempty"`
+}
+
+// New builds the (unsigned) artifact of the planned migrations, computed on the given registry
+// and executions, with the content of the migrations hashed by the hashing strategy (the
+// lockfile.DeclaredHasher, if nil)
+func New(
+	direction string,
+	planned []migration.Migration,
+	registry migration.MigrationsRegistry,
+	executions []execution.MigrationExecution,
+	hasher lockfile.Hasher,
+) (Artifact, error) {
+	hasher = defaultHasher(hasher)
+	registryHash, err := RegistryHash(registry, hasher)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	return Artifact{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Direction:     direction,
+		Versions:      versionsOf(planned),
+		Hashing:       hasher.Name(),
+		RegistryHash:  registryHash,
+		StateHash:     StateHash(executions),
+	}, nil
+}
+
+// RegistryHash computes the hex encoded SHA-256 fingerprint of the registered migrations: their
+// checksums (see lockfile.ChecksumWith), in order, with the content of the migrations hashed by
+// the hashing strategy (the lockfile.DeclaredHasher, if nil)
+func RegistryHash(registry migration.MigrationsRegistry, hasher lockfile.Hasher) (string, error) {
+	hasher = defaultHasher(hasher)
+	hash := sha256.New()
+	for _, mig := range registry.OrderedMigrations() {
+		checksum, err := lockfile.ChecksumWith(mig, hasher)
+		if err != nil {
+			return "", err
+		}
+		_, _ = fmt.Fprintf(hash, "%d %s\n", mig.Version(), checksum)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// defaultHasher returns the hasher, or the lockfile.DeclaredHasher if it is nil
+func defaultHasher(hasher lockfile.Hasher) lockfile.Hasher {
+	if hasher == nil {
+		return lockfile.DeclaredHasher{}
+	}
+	return hasher
+}
+
+// StateHash computes the hex encoded SHA-256 fingerprint of the applied versions (the ones with
+// a finished execution)
+func StateHash(executions []execution.MigrationExecution) string {
+	var applied []uint64
+	for _, exec := range executions {
+		if exec.Finished() {
+			applied = append(applied, exec.Version)
+		}
+	}
+	slices.Sort(applied)
+
+	hash := sha256.New()
+	for _, version := range applied {
+		_, _ = io.WriteString(hash, strconv.FormatUint(version, 10)+"\n")
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// payload returns the signed content of the artifact: its JSON encoding, without the signature
+func (a Artifact) payload() []byte {
+	a.Signature = ""
+	encoded, _ := json.Marshal(a)
+	return encoded
+}
+
+// Sign signs the artifact with the private key
+func (a *Artifact) Sign(key ed25519.PrivateKey) {
+	a.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, a.payload()))
+}
+
+// Verify checks that the artifact was signed with the private key of the public key, and was
+// not changed since. Fails with ErrInvalidSignature otherwise.
+func (a Artifact) Verify(key ed25519.PublicKey) error {
+	signature, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil || a.Signature == "" || len(key) != ed25519.PublicKeySize ||
+		!ed25519.Verify(key, a.payload(), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Check verifies that the artifact matches the registry, the executions and the migrations the
+// run would execute now, with the content of the migrations hashed by the hashing strategy (the
+// lockfile.DeclaredHasher, if nil), which must be the one of the artifact. Fails with
+// ErrMismatch, describing the first difference, otherwise.
+func (a Artifact) Check(
+	registry migration.MigrationsRegistry,
+	executions []execution.MigrationExecution,
+	planned []migration.Migration,
+	hasher lockfile.Hasher,
+) error {
+	if a.FormatVersion != FormatVersion {
+		return fmt.Errorf("%w: unsupported format version %d", ErrMismatch, a.FormatVersion)
+	}
+
+	hasher = defaultHasher(hasher)
+	if a.Hashing != hasher.Name() {
+		return fmt.Errorf(
+			"%w: the migrations are hashed with the %s hashing strategy, not %s",
+			ErrMismatch, a.Hashing, hasher.Name(),
+		)
+	}
+
+	hash, err := RegistryHash(registry, hasher)
+	if err != nil {
+		return err
+	}
+	if hash != a.RegistryHash {
+		return fmt.Errorf(
+			"%w: the registered migrations changed (registry hash %s, expected %s)",
+			ErrMismatch, hash, a.RegistryHash,
+		)
+	}
+
+	if hash := StateHash(executions); hash != a.StateHash {
+		return fmt.Errorf(
+			"%w: the applied migrations changed (state hash %s, expected %s)",
+			ErrMismatch, hash, a.StateHash,
+		)
+	}
+
+	versions := versionsOf(planned)
+	if !slices.Equal(versions, a.Versions) {
+		return fmt.Errorf(
+			"%w: the run would execute the versions %v, expected %v",
+			ErrMismatch, versions, a.Versions,
+		)
+	}
+	return nil
+}
+
+func versionsOf(migrations []migration.Migration) []uint64 {
+	versions := make([]uint64, len(migrations))
+	for i, mig := range migrations {
+		versions[i] = mig.Version()
+	}
+	return versions
+}
+
+// Write writes the artifact as indented JSON
+func Write(w io.Writer, artifact Artifact) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(artifact); err != nil {
+		return fmt.Errorf("failed to write the plan artifact with error: %w", err)
+	}
+	return nil
+}
+
+// Read reads an artifact written by Write
+func Read(r io.Reader) (Artifact, error) {
+	var artifact Artifact
+	if err := json.NewDecoder(r).Decode(&artifact); err != nil {
+		return Artifact{}, fmt.Errorf("failed to read the plan artifact with error: %w", err)
+	}
+
+	if artifact.Direction != handler.DirectionUp && artifact.Direction != handler.DirectionDown {
+		return Artifact{}, fmt.Errorf(
+			"invalid plan artifact direction %q, expected up or down", artifact.Direction,
+		)
+	}
+	return artifact, nil
+}
@@ -0,0 +1,120 @@
+package plan
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/lockfile"
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type PlanTestSuite struct {
+	suite.Suite
+	registry   *migration.GenericRegistry
+	executions []execution.MigrationExecution
+}
+
+func TestPlanTestSuite(t *testing.T) {
+	suite.Run(t, new(PlanTestSuite))
+}
+
+func (suite *PlanTestSuite) SetupTest() {
+	suite.registry = migration.NewGenericRegistry()
+	for _, version := range []uint64{1, 2, 3} {
+		_ = suite.registry.Register(migration.NewDummyMigration(version))
+	}
+	suite.executions = []execution.MigrationExecution{
+		{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2},
+	}
+}
+
+func (suite *PlanTestSuite) planned() []migration.Migration {
+	return []migration.Migration{suite.registry.Get(2), suite.registry.Get(3)}
+}
+
+func (suite *PlanTestSuite) TestItSignsAndVerifiesArtifacts() {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	suite.Require().NoError(err)
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+
+	artifact, err := New(handler.DirectionUp, suite.planned(), suite.registry, suite.executions, nil)
+	suite.Require().NoError(err)
+	suite.Assert().ErrorIs(artifact.Verify(publicKey), ErrInvalidSignature)
+
+	artifact.Sign(privateKey)
+	var buf bytes.Buffer
+	suite.Require().NoError(Write(&buf, artifact))
+	read, err := Read(&buf)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]uint64{2, 3}, read.Versions)
+	suite.Assert().NoError(read.Verify(publicKey))
+	suite.Assert().ErrorIs(read.Verify(otherKey), ErrInvalidSignature)
+
+	read.Versions = []uint64{2}
+	suite.Assert().ErrorIs(read.Verify(publicKey), ErrInvalidSignature)
+}
+
+func (suite *PlanTestSuite) TestItChecksArtifactsMatchTheRegistryAndTheState() {
+	artifact, err := New(handler.DirectionUp, suite.planned(), suite.registry, suite.executions, nil)
+	suite.Require().NoError(err)
+	suite.Assert().NoError(artifact.Check(suite.registry, suite.executions, suite.planned(), nil))
+
+	err = artifact.Check(suite.registry, suite.executions, suite.planned()[:1], nil)
+	suite.Assert().ErrorIs(err, ErrMismatch)
+	suite.Assert().ErrorContains(err, "would execute the versions [2], expected [2 3]")
+
+	executions := append(
+		suite.executions,
+		execution.MigrationExecution{Version: 2, ExecutedAtMs: 1, FinishedAtMs: 2},
+	)
+	err = artifact.Check(suite.registry, executions, suite.planned(), nil)
+	suite.Assert().ErrorContains(err, "the applied migrations changed")
+
+	// unfinished executions are not applied
+	executions[1].FinishedAtMs = 0
+	suite.Assert().NoError(artifact.Check(suite.registry, executions, suite.planned(), nil))
+
+	_ = suite.registry.Register(migration.NewDummyMigration(4))
+	err = artifact.Check(suite.registry, suite.executions, suite.planned(), nil)
+	suite.Assert().ErrorContains(err, "the registered migrations changed")
+}
+
+func (suite *PlanTestSuite) TestItChecksTheCodeOfTheMigrationsDidNotChange() {
+	dir, _ := migration.NewMigrationsDirPath(suite.T().TempDir())
+	for _, version := range []uint64{1, 2, 3} {
+		path := filepath.Join(string(dir), migration.FileName(version, ""))
+		suite.Require().NoError(os.WriteFile(path, []byte("package migrations\n"), 0o644))
+	}
+	hasher := lockfile.FileHasher{Dir: dir}
+
+	artifact, err := New(
+		handler.DirectionUp, suite.planned(), suite.registry, suite.executions, hasher,
+	)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(lockfile.HashingFile, artifact.Hashing)
+	suite.Assert().NoError(artifact.Check(suite.registry, suite.executions, suite.planned(), hasher))
+
+	err = artifact.Check(suite.registry, suite.executions, suite.planned(), nil)
+	suite.Assert().ErrorIs(err, ErrMismatch)
+	suite.Assert().ErrorContains(err, "hashed with the file hashing strategy, not declared")
+
+	path := filepath.Join(string(dir), migration.FileName(3, ""))
+	suite.Require().NoError(os.WriteFile(path, []byte("package migrations // changed\n"), 0o644))
+	err = artifact.Check(suite.registry, suite.executions, suite.planned(), hasher)
+	suite.Assert().ErrorIs(err, ErrMismatch)
+	suite.Assert().ErrorContains(err, "the registered migrations changed")
+}
+
+func (suite *PlanTestSuite) TestItFailsToReadInvalidArtifacts() {
+	_, err := Read(bytes.NewBufferString(`{"direction": "sideways"}`))
+	suite.Assert().ErrorContains(err, `invalid plan artifact direction "sideways"`)
+
+	_, err = Read(bytes.NewBufferString(`{`))
+	suite.Assert().ErrorContains(err, "failed to read the plan artifact")
+}
@@ -0,0 +1,229 @@
+// Package repeatable manages the database objects which are replaced instead of being altered
+// incrementally (views, functions, stored procedures and triggers) as repeatable migrations:
+// each object is (re)created whenever its definition changes, detected by its content hash.
+//
+// The definitions live in the code, in a Registry, so they can be reviewed and diffed like any
+// source file, instead of being copied to a new versioned migration on each change. Sync
+// applies the objects whose hash differs from the one recorded in the Store at their last
+// apply, in the registration order, usually after the versioned migrations (see the
+// repeatable:sync command of the cli package, or a CommandHooks.After of the up command).
+//
+// Example:
+//
+//	registry := repeatable.NewRegistry()
+//	_ = registry.Register(
+//		repeatable.Object{
+//			Kind:   repeatable.KindView,
+//			Name:   "active_users",
+//			Drop:   "DROP VIEW IF EXISTS active_users",
+//			Create: "CREATE VIEW active_users AS SELECT * FROM users WHERE active = 1",
+//		},
+//	)
+package repeatable
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+
+	"github.com/golibry/go-migrations/sqlhelper"
+)
+
+// Kinds of the repeatable objects
+const (
+	KindView      = "view"
+	KindFunction  = "function"
+	KindProcedure = "procedure"
+	KindTrigger   = "trigger"
+)
+
+// Object is a database object which is dropped and created again when its definition changes
+type Object struct {
+	// Kind is one of the Kind constants (or any other kind of object), used in the Key
+	Kind string
+
+	// Name of the object, unique for its kind
+	Name string
+
+	// Drop is the optional statement which drops the object if it exists (for example,
+	// DROP TRIGGER IF EXISTS ...), executed before Create. It can be empty if Create replaces
+	// the object (for example, CREATE OR REPLACE VIEW ...).
+	Drop string
+
+	// Create is the statement which creates the object. It is executed as a single statement,
+	// so it can hold procedural bodies.
+	Create string
+}
+
+// Key identifies the object in the Store: its kind and name, separated by a colon
+func (o Object) Key() string {
+	return o.Kind + ":" + o.Name
+}
+
+// Hash computes the hash of the object definition (the Drop and Create statements). Changes of
+// the leading/trailing whitespace don't change it.
+func (o Object) Hash() string {
+	sum := sha256.Sum256(
+		[]byte(strings.TrimSpace(o.Drop) + "\x00" + strings.TrimSpace(o.Create)),
+	)
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrDuplicateObject is returned by Registry.Register when an object with the same key is
+// already registered
+var ErrDuplicateObject = errors.New("repeatable object already registered")
+
+// Registry holds the repeatable objects, in their registration order, which is the order
+// they are applied in. Register the objects which others depend on first (for example, the
+// functions used by the views).
+type Registry struct {
+	mu      sync.Mutex
+	objects []Object
+}
+
+// NewRegistry builds a new, empty Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds the object to the registry. Fails with ErrDuplicateObject if the key of the
+// object is already registered, or if it has no name or Create statement.
+func (r *Registry) Register(object Object) error {
+	if strings.TrimSpace(object.Name) == "" || strings.TrimSpace(object.Create) == "" {
+		return fmt.Errorf(
+			"repeatable object %q must have a name and a create statement", object.Key(),
+		)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, registered := range r.objects {
+		if registered.Key() == object.Key() {
+			return fmt.Errorf("%w: %s", ErrDuplicateObject, object.Key())
+		}
+	}
+
+	r.objects = append(r.objects, object)
+	return nil
+}
+
+// Objects returns the registered objects, in their registration order
+func (r *Registry) Objects() []Object {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Object(nil), r.objects...)
+}
+
+// Store persists the hash of each object at its last apply
+type Store interface {
+	// Init must create the storage (for example, a table), if it doesn't exist
+	Init(ctx context.Context) error
+
+	// Load must return the hashes of the applied objects, indexed by their Key
+	Load(ctx context.Context) (map[string]string, error)
+
+	// Save must upsert the hash of the object with the given key
+	Save(ctx context.Context, key string, hash string) error
+}
+
+// Changed returns the objects of the registry whose hash differs from the recorded one (or
+// which were never applied), in the registration order
+func Changed(ctx context.Context, store Store, registry *Registry) ([]Object, error) {
+	if err := store.Init(ctx); err != nil {
+		return nil, fmt.Errorf("failed to init the repeatable objects store with error: %w", err)
+	}
+
+	hashes, err := store.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the repeatable objects hashes with error: %w", err)
+	}
+
+	var changed []Object
+	for _, object := range registry.Objects() {
+		if hashes[object.Key()] != object.Hash() {
+			changed = append(changed, object)
+		}
+	}
+	return changed, nil
+}
+
+// Sync applies the changed objects (see Changed): it executes the Drop and Create statements of
+// each one, through the sqlhelper package, and records its new hash. It stops at the first
+// failure and returns the objects applied until then.
+func Sync(
+	ctx context.Context,
+	db sqlhelper.Execer,
+	store Store,
+	registry *Registry,
+) ([]Object, error) {
+	changed, err := Changed(ctx, store, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Object
+	for _, object := range changed {
+		for _, statement := range []string{object.Drop, object.Create} {
+			if strings.TrimSpace(statement) == "" {
+				continue
+			}
+
+			if _, err = sqlhelper.Exec(ctx, db, statement); err != nil {
+				return applied, fmt.Errorf(
+					"failed to apply the repeatable object %s with error: %w", object.Key(), err,
+				)
+			}
+		}
+
+		if err = store.Save(ctx, object.Key(), object.Hash()); err != nil {
+			return applied, fmt.Errorf(
+				"repeatable object %s applied, but saving its hash failed with error: %w",
+				object.Key(), err,
+			)
+		}
+		applied = append(applied, object)
+	}
+	return applied, nil
+}
+
+// InMemoryStore is an in-memory implementation of the Store interface.
+// It's primarily intended for use in unit tests.
+type InMemoryStore struct {
+	mu     sync.Mutex
+	hashes map[string]string
+
+	// SaveErr is returned by the Save method if set
+	SaveErr error
+}
+
+// Init implements the Store.Init method
+func (s *InMemoryStore) Init(_ context.Context) error {
+	return nil
+}
+
+// Load implements the Store.Load method
+func (s *InMemoryStore) Load(_ context.Context) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.hashes), nil
+}
+
+// Save implements the Store.Save method
+func (s *InMemoryStore) Save(_ context.Context, key string, hash string) error {
+	if s.SaveErr != nil {
+		return s.SaveErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hashes == nil {
+		s.hashes = make(map[string]string)
+	}
+	s.hashes[key] = hash
+	return nil
+}
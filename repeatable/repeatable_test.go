@@ -0,0 +1,114 @@
+package repeatable
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RepeatableTestSuite struct {
+	suite.Suite
+}
+
+func TestRepeatableTestSuite(t *testing.T) {
+	suite.Run(t, new(RepeatableTestSuite))
+}
+
+// recordingExecer records the executed statements, failing on failOn
+type recordingExecer struct {
+	statements []string
+	failOn     string
+}
+
+func (e *recordingExecer) ExecContext(
+	_ context.Context,
+	query string,
+	_ ...any,
+) (sql.Result, error) {
+	if query == e.failOn {
+		return nil, errors.New("syntax error")
+	}
+	e.statements = append(e.statements, query)
+	return nil, nil
+}
+
+func (suite *RepeatableTestSuite) TestItAppliesOnlyTheChangedObjects() {
+	ctx := context.Background()
+	view := Object{
+		Kind: KindView, Name: "active_users", Create: "CREATE OR REPLACE VIEW active_users AS 1",
+	}
+	trigger := Object{
+		Kind: KindTrigger, Name: "users_audit", Drop: "DROP TRIGGER IF EXISTS users_audit",
+		Create: "CREATE TRIGGER users_audit",
+	}
+	registry := NewRegistry()
+	suite.Require().NoError(registry.Register(view))
+	suite.Require().NoError(registry.Register(trigger))
+	store := &InMemoryStore{}
+	db := &recordingExecer{}
+
+	applied, err := Sync(ctx, db, store, registry)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]Object{view, trigger}, applied)
+	suite.Assert().Equal(
+		[]string{view.Create, trigger.Drop, trigger.Create}, db.statements,
+	)
+
+	applied, err = Sync(ctx, db, store, registry)
+	suite.Require().NoError(err)
+	suite.Assert().Empty(applied)
+
+	changedRegistry := NewRegistry()
+	changedView := view
+	changedView.Create = "CREATE OR REPLACE VIEW active_users AS 2"
+	suite.Require().NoError(changedRegistry.Register(changedView))
+	suite.Require().NoError(changedRegistry.Register(trigger))
+
+	changed, err := Changed(ctx, store, changedRegistry)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]Object{changedView}, changed)
+
+	// whitespace changes are not definition changes
+	trigger.Create += "\n"
+	suite.Assert().Equal(trigger.Hash(), changedRegistry.Objects()[1].Hash())
+}
+
+func (suite *RepeatableTestSuite) TestItStopsAtTheFirstObjectWhichFails() {
+	registry := NewRegistry()
+	for _, name := range []string{"a", "b", "c"} {
+		suite.Require().NoError(
+			registry.Register(Object{Kind: KindFunction, Name: name, Create: "CREATE " + name}),
+		)
+	}
+	store := &InMemoryStore{}
+
+	applied, err := Sync(
+		context.Background(), &recordingExecer{failOn: "CREATE b"}, store, registry,
+	)
+	suite.Assert().ErrorContains(err, "failed to apply the repeatable object function:b")
+	suite.Assert().Len(applied, 1)
+
+	hashes, _ := store.Load(context.Background())
+	suite.Assert().Equal(map[string]string{"function:a": applied[0].Hash()}, hashes)
+
+	store.SaveErr = errors.New("store down")
+	_, err = Sync(context.Background(), &recordingExecer{}, store, registry)
+	suite.Assert().ErrorIs(err, store.SaveErr)
+}
+
+func (suite *RepeatableTestSuite) TestItRefusesInvalidOrDuplicateObjects() {
+	registry := NewRegistry()
+	object := Object{Kind: KindProcedure, Name: "cleanup", Create: "CREATE PROCEDURE cleanup()"}
+
+	suite.Require().NoError(registry.Register(object))
+	suite.Assert().ErrorIs(registry.Register(object), ErrDuplicateObject)
+	suite.Assert().ErrorContains(
+		registry.Register(Object{Kind: KindView, Name: "empty"}), "must have a name and a create",
+	)
+
+	object.Kind = KindFunction
+	suite.Assert().NoError(registry.Register(object))
+}
@@ -0,0 +1,102 @@
+package repeatable
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Dialect is the SQL dialect of the table of a SqlStore
+type Dialect string
+
+const (
+	DialectMysql    Dialect = "mysql"
+	DialectPostgres Dialect = "postgres"
+	DialectSqlite   Dialect = "sqlite"
+)
+
+// SqlStore is a Store which keeps the hashes in a table of the database the objects are
+// applied to, so each environment tracks its own objects
+type SqlStore struct {
+	db        *sql.DB
+	tableName string
+	dialect   Dialect
+}
+
+// NewSqlStore builds a new SqlStore, which keeps the hashes in the given table
+func NewSqlStore(db *sql.DB, tableName string, dialect Dialect) *SqlStore {
+	return &SqlStore{db: db, tableName: tableName, dialect: dialect}
+}
+
+// Init implements the Store.Init method
+func (s *SqlStore) Init(ctx context.Context) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s (object_key VARCHAR(255) NOT NULL PRIMARY KEY, "+
+				"hash CHAR(64) NOT NULL, applied_at_ms BIGINT NOT NULL)",
+			s.tableName,
+		),
+	)
+	return err
+}
+
+// Load implements the Store.Load method
+func (s *SqlStore) Load(ctx context.Context) (map[string]string, error) {
+	rows, err := s.db.QueryContext(
+		ctx, fmt.Sprintf("SELECT object_key, hash FROM %s", s.tableName),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var key, hash string
+		if err = rows.Scan(&key, &hash); err != nil {
+			return nil, err
+		}
+		hashes[key] = hash
+	}
+	return hashes, rows.Err()
+}
+
+// Save implements the Store.Save method. The previous hash of the object is replaced in a
+// transaction, which works the same in all the dialects.
+func (s *SqlStore) Save(ctx context.Context, key string, hash string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE object_key = %s", s.tableName, s.placeholder(1)),
+		key,
+	)
+	if err == nil {
+		_, err = tx.ExecContext(
+			ctx,
+			fmt.Sprintf(
+				"INSERT INTO %s (object_key, hash, applied_at_ms) VALUES (%s, %s, %s)",
+				s.tableName, s.placeholder(1), s.placeholder(2), s.placeholder(3),
+			),
+			key, hash, time.Now().UnixMilli(),
+		)
+	}
+
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SqlStore) placeholder(position int) string {
+	if s.dialect == DialectPostgres {
+		return fmt.Sprintf("$%d", position)
+	}
+	return "?"
+}
@@ -0,0 +1,61 @@
+//go:build sqlite
+
+package repeatable
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func (suite *RepeatableTestSuite) TestItKeepsTheHashesInASqliteTable() {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	suite.Require().NoError(err)
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, active INTEGER)")
+	suite.Require().NoError(err)
+
+	store := NewSqlStore(db, "repeatable_objects", DialectSqlite)
+	registry := NewRegistry()
+	suite.Require().NoError(
+		registry.Register(
+			Object{
+				Kind: KindView, Name: "active_users", Drop: "DROP VIEW IF EXISTS active_users",
+				Create: "CREATE VIEW active_users AS SELECT id FROM users WHERE active = 1",
+			},
+		),
+	)
+
+	applied, err := Sync(ctx, db, store, registry)
+	suite.Require().NoError(err)
+	suite.Assert().Len(applied, 1)
+
+	// the view is created again with its new definition
+	registry = NewRegistry()
+	suite.Require().NoError(
+		registry.Register(
+			Object{
+				Kind: KindView, Name: "active_users", Drop: "DROP VIEW IF EXISTS active_users",
+				Create: "CREATE VIEW active_users AS SELECT id, active FROM users WHERE active = 1",
+			},
+		),
+	)
+	applied, err = Sync(ctx, db, store, registry)
+	suite.Require().NoError(err)
+	suite.Assert().Len(applied, 1)
+
+	hashes, err := store.Load(ctx)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(map[string]string{"view:active_users": applied[0].Hash()}, hashes)
+
+	_, err = db.Exec("SELECT active FROM active_users")
+	suite.Assert().NoError(err)
+
+	applied, err = Sync(ctx, db, store, registry)
+	suite.Require().NoError(err)
+	suite.Assert().Empty(applied)
+}
@@ -0,0 +1,206 @@
+package schemadiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// schemaQueries holds the queries which read a schema, per dialect. The queries filter by the
+// schema (database) name argument, the current schema if it is empty.
+var schemaQueries = map[Dialect]struct {
+	columns string
+	indexes string
+}{
+	DialectMysql: {
+		columns: `SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE = 'YES',
+				COLUMN_DEFAULT, EXTRA
+			FROM information_schema.COLUMNS
+			WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE())
+				AND TABLE_NAME IN (
+					SELECT TABLE_NAME FROM information_schema.TABLES
+					WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE())
+						AND TABLE_TYPE = 'BASE TABLE'
+				)
+			ORDER BY TABLE_NAME, ORDINAL_POSITION`,
+		indexes: `SELECT TABLE_NAME, INDEX_NAME, NON_UNIQUE = 0, INDEX_NAME = 'PRIMARY', COLUMN_NAME
+			FROM information_schema.STATISTICS
+			WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND COLUMN_NAME IS NOT NULL
+			ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`,
+	},
+	DialectPostgres: {
+		columns: `SELECT c.relname, a.attname, format_type(a.atttypid, a.atttypmod),
+				NOT a.attnotnull, pg_get_expr(d.adbin, d.adrelid), ''
+			FROM pg_attribute a
+			JOIN pg_class c ON c.oid = a.attrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+			WHERE n.nspname = COALESCE(NULLIF($1, ''), current_schema()) AND c.relkind = 'r'
+				AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY c.relname, a.attnum`,
+		indexes: `SELECT t.relname, i.relname, ix.indisunique, ix.indisprimary, a.attname
+			FROM pg_index ix
+			JOIN pg_class t ON t.oid = ix.indrelid
+			JOIN pg_class i ON i.oid = ix.indexrelid
+			JOIN pg_namespace n ON n.oid = t.relnamespace
+			JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+			JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+			WHERE n.nspname = COALESCE(NULLIF($1, ''), current_schema()) AND t.relkind = 'r'
+			ORDER BY t.relname, i.relname, k.ord`,
+	},
+}
+
+// DbSource is a Source which reads the schema of a database, from its catalog: the live
+// database of the migrations or another database declaring the target schema (for example,
+// a reference database provisioned from the models of the app)
+type DbSource struct {
+	db         *sql.DB
+	dialect    Dialect
+	schemaName string
+}
+
+// NewDbSource builds a new DbSource, which reads the schema (for MySQL, the database) with
+// the given name, or the current one if schemaName is empty
+func NewDbSource(db *sql.DB, dialect Dialect, schemaName string) *DbSource {
+	return &DbSource{db: db, dialect: dialect, schemaName: schemaName}
+}
+
+// Schema implements the Source.Schema method
+func (s *DbSource) Schema(ctx context.Context) (Schema, error) {
+	queries, ok := schemaQueries[s.dialect]
+	if !ok {
+		return Schema{}, fmt.Errorf("unsupported schema dialect %q", s.dialect)
+	}
+
+	// the MySQL columns query uses the schema name twice
+	args := []any{s.schemaName}
+	if s.dialect == DialectMysql {
+		args = append(args, s.schemaName)
+	}
+
+	schema := Schema{Dialect: s.dialect, Tables: []Table{}}
+	if err := s.readColumns(ctx, &schema, queries.columns, args); err != nil {
+		return Schema{}, err
+	}
+	if err := s.readIndexes(ctx, &schema, queries.indexes); err != nil {
+		return Schema{}, err
+	}
+	return schema, nil
+}
+
+func (s *DbSource) readColumns(
+	ctx context.Context,
+	schema *Schema,
+	query string,
+	args []any,
+) error {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to read the schema columns with error: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	for rows.Next() {
+		var tableName, extra string
+		var column Column
+		var defaultValue sql.NullString
+		err = rows.Scan(
+			&tableName, &column.Name, &column.Type, &column.Nullable, &defaultValue, &extra,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan the schema columns with error: %w", err)
+		}
+
+		column.Default, column.Extra = s.columnDefault(defaultValue, extra), columnExtra(extra)
+
+		table := schema.Table(tableName)
+		if table == nil {
+			schema.Tables = append(
+				schema.Tables, Table{Name: tableName, Columns: []Column{}, Indexes: []Index{}},
+			)
+			table = &schema.Tables[len(schema.Tables)-1]
+		}
+		table.Columns = append(table.Columns, column)
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to read the schema columns with error: %w", err)
+	}
+	return nil
+}
+
+func (s *DbSource) readIndexes(ctx context.Context, schema *Schema, query string) error {
+	rows, err := s.db.QueryContext(ctx, query, s.schemaName)
+	if err != nil {
+		return fmt.Errorf("failed to read the schema indexes with error: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	for rows.Next() {
+		var tableName, indexName, columnName string
+		var unique, primary bool
+		if err = rows.Scan(&tableName, &indexName, &unique, &primary, &columnName); err != nil {
+			return fmt.Errorf("failed to scan the schema indexes with error: %w", err)
+		}
+
+		table := schema.Table(tableName)
+		if table == nil {
+			continue
+		}
+
+		if primary {
+			table.PrimaryKey = append(table.PrimaryKey, columnName)
+			continue
+		}
+
+		index := table.Index(indexName)
+		if index == nil {
+			table.Indexes = append(table.Indexes, Index{Name: indexName, Unique: unique})
+			index = &table.Indexes[len(table.Indexes)-1]
+		}
+		index.Columns = append(index.Columns, columnName)
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to read the schema indexes with error: %w", err)
+	}
+	return nil
+}
+
+// columnDefault returns the SQL expression of the default value. MySQL reports the literal
+// values unquoted, and the expressions with the DEFAULT_GENERATED extra.
+func (s *DbSource) columnDefault(value sql.NullString, extra string) string {
+	if !value.Valid {
+		return ""
+	}
+
+	if s.dialect != DialectMysql || strings.Contains(strings.ToUpper(extra), "DEFAULT_GENERATED") {
+		return value.String
+	}
+	if _, err := strconv.ParseFloat(value.String, 64); err == nil {
+		return value.String
+	}
+	if strings.EqualFold(value.String, "CURRENT_TIMESTAMP") {
+		return value.String
+	}
+	return "'" + strings.ReplaceAll(value.String, "'", "''") + "'"
+}
+
+// columnExtra returns the MySQL extra attributes which are rendered in a column definition
+func columnExtra(extra string) string {
+	var extras []string
+	for _, field := range strings.Fields(strings.ToLower(extra)) {
+		if field != "default_generated" {
+			extras = append(extras, field)
+		}
+	}
+	return strings.Join(extras, " ")
+}
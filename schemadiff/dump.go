@@ -0,0 +1,351 @@
+package schemadiff
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// DumpSource is a Source which reads the schema from a schema dump file (for example, the
+// output of mysqldump --no-data or pg_dump --schema-only), or from any file declaring the
+// target schema with CREATE TABLE, CREATE INDEX and ALTER TABLE ... ADD CONSTRAINT statements
+type DumpSource struct {
+	path    string
+	dialect Dialect
+}
+
+// NewDumpSource builds a new DumpSource, for a dump in the given dialect
+func NewDumpSource(path string, dialect Dialect) *DumpSource {
+	return &DumpSource{path: path, dialect: dialect}
+}
+
+// Schema implements the Source.Schema method
+func (s *DumpSource) Schema(context.Context) (Schema, error) {
+	contents, err := os.ReadFile(s.path)
+	if err != nil {
+		return Schema{}, fmt.Errorf("failed to read the schema dump with error: %w", err)
+	}
+
+	return ParseDump(string(contents), s.dialect), nil
+}
+
+var (
+	createTablePattern = regexp.MustCompile(
+		`(?is)^CREATE\s+(?:UNLOGGED\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([^\s(]+)\s*` +
+			`\((.*)\)[^)]*$`,
+	)
+	createIndexPattern = regexp.MustCompile(
+		`(?is)^CREATE\s+(UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(\S+)` +
+			`\s+ON\s+(?:ONLY\s+)?([^\s(]+)\s*(?:USING\s+\w+\s*)?\((.*)\)`,
+	)
+	addConstraintPattern = regexp.MustCompile(
+		`(?is)^ALTER\s+TABLE\s+(?:ONLY\s+)?(?:IF\s+EXISTS\s+)?(\S+)\s+ADD\s+CONSTRAINT\s+(\S+)` +
+			`\s+(PRIMARY\s+KEY|UNIQUE)\s*\((.*)\)$`,
+	)
+	setDefaultPattern = regexp.MustCompile(
+		`(?is)^ALTER\s+TABLE\s+(?:ONLY\s+)?(?:IF\s+EXISTS\s+)?(\S+)\s+ALTER\s+(?:COLUMN\s+)?(\S+)` +
+			`\s+SET\s+DEFAULT\s+(.*)$`,
+	)
+	tableIndexPattern = regexp.MustCompile(
+		`(?is)^(?:CONSTRAINT\s+(\S+)\s+)?(PRIMARY\s+KEY|UNIQUE|KEY|INDEX)` +
+			`(?:\s+(?:KEY|INDEX))?(?:\s+([^\s(]+))?\s*(?:USING\s+\w+\s*)?\((.*)\)`,
+	)
+	skippedDefinitionPattern = regexp.MustCompile(
+		`(?i)^(?:CONSTRAINT\s+\S+\s+)?(?:FOREIGN\s+KEY|CHECK|FULLTEXT|SPATIAL|EXCLUDE)\b`,
+	)
+)
+
+// ParseDump reads the tables, the columns and the indexes declared by a schema dump. The
+// statements it doesn't understand (for example, SET, INSERT or CREATE VIEW) are ignored.
+func ParseDump(dump string, dialect Dialect) Schema {
+	schema := Schema{Dialect: dialect, Tables: []Table{}}
+
+	for _, statement := range migration.SplitSqlStatements(stripSqlComments(dump)) {
+		if match := createTablePattern.FindStringSubmatch(statement); match != nil {
+			schema.Tables = append(schema.Tables, parseTable(dialect, match[1], match[2]))
+			continue
+		}
+
+		if match := createIndexPattern.FindStringSubmatch(statement); match != nil {
+			if table := schema.Table(unquoteIdent(match[3])); table != nil {
+				table.Indexes = append(
+					table.Indexes,
+					Index{
+						Name:    unquoteIdent(match[2]),
+						Unique:  match[1] != "",
+						Columns: parseIndexColumns(match[4]),
+					},
+				)
+			}
+			continue
+		}
+
+		if match := addConstraintPattern.FindStringSubmatch(statement); match != nil {
+			if table := schema.Table(unquoteIdent(match[1])); table != nil {
+				addConstraint(table, unquoteIdent(match[2]), match[3], match[4])
+			}
+			continue
+		}
+
+		// pg_dump declares the defaults of the serial columns after the table
+		if match := setDefaultPattern.FindStringSubmatch(statement); match != nil {
+			if table := schema.Table(unquoteIdent(match[1])); table != nil {
+				if column := table.Column(unquoteIdent(match[2])); column != nil {
+					column.Default = strings.TrimSpace(match[3])
+				}
+			}
+		}
+	}
+
+	return schema
+}
+
+// parseTable reads the columns and the inline constraints of a CREATE TABLE statement body
+func parseTable(dialect Dialect, rawName, body string) Table {
+	table := Table{Name: unquoteIdent(rawName), Columns: []Column{}, Indexes: []Index{}}
+
+	for _, definition := range splitTopLevel(body, ',') {
+		if definition == "" || skippedDefinitionPattern.MatchString(definition) {
+			continue
+		}
+
+		if match := tableIndexPattern.FindStringSubmatch(definition); match != nil {
+			name := unquoteIdent(match[1])
+			if name == "" {
+				name = unquoteIdent(match[3])
+			}
+			addConstraint(&table, name, match[2], match[4])
+			continue
+		}
+
+		table.Columns = append(table.Columns, parseColumn(dialect, &table, definition))
+	}
+
+	return table
+}
+
+// addConstraint adds the primary key or the index, of the kind (PRIMARY KEY, UNIQUE, KEY or
+// INDEX), to the table
+func addConstraint(table *Table, name, kind, columns string) {
+	kind = strings.ToUpper(strings.Join(strings.Fields(kind), " "))
+	if kind == "PRIMARY KEY" {
+		table.PrimaryKey = parseIndexColumns(columns)
+		for _, columnName := range table.PrimaryKey {
+			if column := table.Column(columnName); column != nil {
+				column.Nullable = false
+			}
+		}
+		return
+	}
+
+	table.Indexes = append(
+		table.Indexes,
+		Index{Name: name, Unique: kind == "UNIQUE", Columns: parseIndexColumns(columns)},
+	)
+}
+
+// columnKeywords end the type and the default value of a column definition
+var columnKeywords = []string{
+	"NOT", "NULL", "DEFAULT", "PRIMARY", "UNIQUE", "AUTO_INCREMENT", "COLLATE", "COMMENT",
+	"REFERENCES", "CHECK", "GENERATED", "CONSTRAINT", "ON",
+}
+
+// parseColumn reads a column definition of a CREATE TABLE statement. The inline primary key
+// and unique constraints are added to the table.
+func parseColumn(dialect Dialect, table *Table, definition string) Column {
+	tokens := splitTopLevel(definition, ' ')
+	column := Column{Name: unquoteIdent(tokens[0]), Nullable: true}
+
+	isKeyword := func(i int) bool {
+		token := strings.ToUpper(tokens[i])
+		// CHARACTER is also the start of Postgres types, like character varying
+		if token == "CHARACTER" || token == "CHARSET" {
+			return token == "CHARSET" || i+1 < len(tokens) && strings.EqualFold(tokens[i+1], "SET")
+		}
+		return slices.Contains(columnKeywords, token)
+	}
+	// valueEnd returns the index of the keyword after the value starting at i
+	valueEnd := func(i int) int {
+		for i < len(tokens) && !isKeyword(i) {
+			i++
+		}
+		return i
+	}
+
+	i := valueEnd(1)
+	column.Type = strings.Join(tokens[1:i], " ")
+
+	var extras []string
+	for i < len(tokens) {
+		token := strings.ToUpper(tokens[i])
+		next := ""
+		if i+1 < len(tokens) {
+			next = strings.ToUpper(tokens[i+1])
+		}
+
+		switch {
+		case token == "NOT" && next == "NULL":
+			column.Nullable = false
+			i += 2
+		case token == "NULL":
+			i++
+		case token == "DEFAULT":
+			end := valueEnd(i + 1)
+			column.Default = strings.Join(tokens[i+1:end], " ")
+			i = end
+		case token == "PRIMARY":
+			table.PrimaryKey = []string{column.Name}
+			column.Nullable = false
+			i = valueEnd(i + 2)
+		case token == "UNIQUE":
+			table.Indexes = append(
+				table.Indexes,
+				Index{
+					Name:    inlineUniqueName(dialect, table.Name, column.Name),
+					Unique:  true,
+					Columns: []string{column.Name},
+				},
+			)
+			i = valueEnd(i + 1)
+		case token == "AUTO_INCREMENT":
+			extras = append(extras, "auto_increment")
+			i++
+		case token == "ON" && next == "UPDATE":
+			end := valueEnd(i + 2)
+			extras = append(extras, "on update "+strings.Join(tokens[i+2:end], " "))
+			i = end
+		default:
+			// the other attributes (COLLATE, COMMENT, REFERENCES...) and their values are
+			// not compared
+			i = valueEnd(i + 1)
+		}
+	}
+	column.Extra = strings.Join(extras, " ")
+
+	if dialect == DialectPostgres {
+		switch strings.ToLower(column.Type) {
+		case "serial", "bigserial", "smallserial":
+			column.Nullable = false
+			column.Default = fmt.Sprintf("nextval('%s_%s_seq'::regclass)", table.Name, column.Name)
+		}
+	}
+
+	return column
+}
+
+// inlineUniqueName returns the name the database gives to an inline unique constraint
+func inlineUniqueName(dialect Dialect, tableName, columnName string) string {
+	if dialect == DialectPostgres {
+		return tableName + "_" + columnName + "_key"
+	}
+	return columnName
+}
+
+// parseIndexColumns reads the column list of an index, without the sort orders and the
+// prefix lengths
+func parseIndexColumns(columns string) []string {
+	names := []string{}
+	for _, column := range splitTopLevel(columns, ',') {
+		fields := strings.Fields(column)
+		if len(fields) == 0 {
+			continue
+		}
+
+		name, _, _ := strings.Cut(fields[0], "(")
+		names = append(names, unquoteIdent(name))
+	}
+	return names
+}
+
+// unquoteIdent removes the quotes and the schema qualifier of an identifier
+func unquoteIdent(ident string) string {
+	parts := strings.Split(ident, ".")
+	return strings.Trim(parts[len(parts)-1], "`\"[]")
+}
+
+// splitTopLevel splits the text on the separator, outside parentheses and quotes, trimming
+// the parts and dropping the empty ones (if the separator is a space, it also splits on the
+// other whitespaces)
+func splitTopLevel(text string, separator rune) []string {
+	var parts []string
+	var current strings.Builder
+	var quote rune
+	depth := 0
+
+	flush := func() {
+		if part := strings.TrimSpace(current.String()); part != "" {
+			parts = append(parts, part)
+		}
+		current.Reset()
+	}
+
+	for _, char := range text {
+		isSeparator := char == separator || separator == ' ' && strings.ContainsRune("\t\r\n", char)
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"' || char == '`':
+			quote = char
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+		case isSeparator && depth == 0:
+			flush()
+			continue
+		}
+		current.WriteRune(char)
+	}
+	flush()
+
+	return parts
+}
+
+// stripSqlComments removes the line (--) and the block (/* */) comments of a script, outside
+// quotes. MySQL conditional comments (/*! */) are removed too.
+func stripSqlComments(script string) string {
+	var stripped strings.Builder
+	var quote rune
+	runes := []rune(script)
+
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"' || char == '`':
+			quote = char
+		case char == '-' && next == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			stripped.WriteRune('\n')
+			continue
+		case char == '/' && next == '*':
+			i += 2
+			for i+1 < len(runes) && (runes[i] != '*' || runes[i+1] != '/') {
+				i++
+			}
+			i++
+			stripped.WriteRune(' ')
+			continue
+		}
+		stripped.WriteRune(char)
+	}
+
+	return stripped.String()
+}
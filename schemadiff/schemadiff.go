@@ -0,0 +1,414 @@
+// Package schemadiff compares a live database schema against a declared target schema (a
+// schema dump file or another database) and drafts the DDL statements which bring the live
+// schema to the target, for teams moving toward declarative schema management. The drafted
+// statements are meant to be reviewed in a generated migration file (see the diff command).
+//
+// MySQL and Postgres are supported. Tables, columns (type, nullability, default) and indexes
+// are compared; the primary keys of the existing tables, the foreign keys, the views and the
+// routines are not.
+package schemadiff
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Dialect is the SQL dialect of a schema, which determines how it is read and how the DDL
+// statements are rendered
+type Dialect string
+
+const (
+	DialectMysql    Dialect = "mysql"
+	DialectPostgres Dialect = "postgres"
+)
+
+// Column describes a table column
+type Column struct {
+	Name string
+
+	// Type is the column type, as declared (for example, varchar(255) or character varying)
+	Type string
+
+	Nullable bool
+
+	// Default is the SQL expression of the default value, empty if the column has none
+	Default string
+
+	// Extra holds the other attributes rendered after the default (for example, the MySQL
+	// auto_increment), empty if none
+	Extra string
+}
+
+// Index describes a secondary index (or a unique constraint) of a table
+type Index struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+// Table describes a table, with the columns in their declared order
+type Table struct {
+	Name       string
+	Columns    []Column
+	PrimaryKey []string
+	Indexes    []Index
+}
+
+// Column returns the column with the name, or nil if the table has none
+func (t *Table) Column(name string) *Column {
+	for i := range t.Columns {
+		if strings.EqualFold(t.Columns[i].Name, name) {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// Index returns the index with the name, or nil if the table has none
+func (t *Table) Index(name string) *Index {
+	for i := range t.Indexes {
+		if strings.EqualFold(t.Indexes[i].Name, name) {
+			return &t.Indexes[i]
+		}
+	}
+	return nil
+}
+
+// Schema describes the tables of a database schema
+type Schema struct {
+	Dialect Dialect
+	Tables  []Table
+}
+
+// Table returns the table with the name, or nil if the schema has none
+func (s *Schema) Table(name string) *Table {
+	for i := range s.Tables {
+		if strings.EqualFold(s.Tables[i].Name, name) {
+			return &s.Tables[i]
+		}
+	}
+	return nil
+}
+
+// Source provides a schema to compare: the live database schema or the declared target
+type Source interface {
+	// Schema reads the schema. The tables which must be ignored (for example, the migrations
+	// executions table) are still returned, they are excluded by the caller.
+	Schema(ctx context.Context) (Schema, error)
+}
+
+// Hash returns a fingerprint of the schema, which is the same for the schemas without
+// differences (see Diff), regardless of the declaration order of the tables and indexes and of
+// the type aliases. The tables named in ignoredTables are not hashed.
+func Hash(schema Schema, ignoredTables ...string) string {
+	tables := slices.Clone(schema.Tables)
+	slices.SortFunc(
+		tables, func(a, b Table) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		},
+	)
+
+	hash := sha256.New()
+	for _, table := range tables {
+		if slices.ContainsFunc(
+			ignoredTables, func(name string) bool { return strings.EqualFold(name, table.Name) },
+		) {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(hash, "table %s\n", strings.ToLower(table.Name))
+		for _, column := range table.Columns {
+			_, _ = fmt.Fprintf(
+				hash, "column %s %s %t %s %s\n",
+				strings.ToLower(column.Name), normalizeType(schema.Dialect, column.Type),
+				column.Nullable, normalizeDefault(column.Default), strings.ToLower(column.Extra),
+			)
+		}
+
+		indexes := slices.Clone(table.Indexes)
+		slices.SortFunc(
+			indexes, func(a, b Index) int {
+				return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+			},
+		)
+		for _, index := range indexes {
+			_, _ = fmt.Fprintf(
+				hash, "index %s %t %s\n", strings.ToLower(index.Name), index.Unique,
+				strings.ToLower(strings.Join(index.Columns, ",")),
+			)
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Change is a drafted DDL statement
+type Change struct {
+	Sql string
+
+	// Destructive is true for the statements which drop data (tables, columns or a column
+	// type change which may truncate values) and must be explicitly allowed
+	Destructive bool
+}
+
+// Diff drafts the DDL statements which bring the current schema to the target one, in the
+// dialect of the current schema: the new tables and columns first, then the changed columns
+// and indexes, then the dropped columns and tables. The tables named in ignoredTables (for
+// example, the migrations executions table) are not compared.
+func Diff(current, target Schema, ignoredTables ...string) []Change {
+	ignored := func(name string) bool {
+		return slices.ContainsFunc(
+			ignoredTables, func(ignoredTable string) bool {
+				return strings.EqualFold(ignoredTable, name)
+			},
+		)
+	}
+
+	dialect := current.Dialect
+	var creates, alters, drops []Change
+
+	for _, targetTable := range target.Tables {
+		if ignored(targetTable.Name) {
+			continue
+		}
+
+		currentTable := current.Table(targetTable.Name)
+		if currentTable == nil {
+			creates = append(creates, Change{Sql: createTableSql(dialect, targetTable)})
+			for _, index := range targetTable.Indexes {
+				creates = append(
+					creates, Change{Sql: createIndexSql(targetTable.Name, index)},
+				)
+			}
+			continue
+		}
+
+		tableCreates, tableAlters, tableDrops := diffTable(dialect, *currentTable, targetTable)
+		creates = append(creates, tableCreates...)
+		alters = append(alters, tableAlters...)
+		drops = append(drops, tableDrops...)
+	}
+
+	for _, currentTable := range current.Tables {
+		if !ignored(currentTable.Name) && target.Table(currentTable.Name) == nil {
+			drops = append(
+				drops, Change{Sql: "DROP TABLE " + currentTable.Name, Destructive: true},
+			)
+		}
+	}
+
+	return append(append(creates, alters...), drops...)
+}
+
+// diffTable drafts the statements which bring the columns and the indexes of an existing table
+// to the target ones
+func diffTable(dialect Dialect, current, target Table) (creates, alters, drops []Change) {
+	prefix := "ALTER TABLE " + target.Name + " "
+
+	for _, column := range target.Columns {
+		currentColumn := current.Column(column.Name)
+		if currentColumn == nil {
+			creates = append(
+				creates, Change{Sql: prefix + "ADD COLUMN " + columnSql(dialect, column)},
+			)
+			continue
+		}
+
+		alters = append(alters, alterColumnSql(dialect, target.Name, *currentColumn, column)...)
+	}
+
+	for _, index := range current.Indexes {
+		targetIndex := target.Index(index.Name)
+		if targetIndex == nil || !sameIndex(index, *targetIndex) {
+			alters = append(alters, Change{Sql: dropIndexSql(dialect, target.Name, index)})
+		}
+	}
+
+	for _, index := range target.Indexes {
+		currentIndex := current.Index(index.Name)
+		if currentIndex == nil || !sameIndex(*currentIndex, index) {
+			alters = append(alters, Change{Sql: createIndexSql(target.Name, index)})
+		}
+	}
+
+	for _, column := range current.Columns {
+		if target.Column(column.Name) == nil {
+			drops = append(
+				drops, Change{Sql: prefix + "DROP COLUMN " + column.Name, Destructive: true},
+			)
+		}
+	}
+
+	return creates, alters, drops
+}
+
+// alterColumnSql drafts the statements which change the current column to the target one, if
+// they differ
+func alterColumnSql(dialect Dialect, tableName string, current, target Column) []Change {
+	sameType := normalizeType(dialect, current.Type) == normalizeType(dialect, target.Type)
+	sameDefault := normalizeDefault(current.Default) == normalizeDefault(target.Default)
+	sameExtra := strings.EqualFold(current.Extra, target.Extra)
+
+	if sameType && sameDefault && sameExtra && current.Nullable == target.Nullable {
+		return nil
+	}
+
+	prefix := "ALTER TABLE " + tableName + " "
+	if dialect == DialectMysql {
+		return []Change{
+			{Sql: prefix + "MODIFY COLUMN " + columnSql(dialect, target), Destructive: !sameType},
+		}
+	}
+
+	prefix += "ALTER COLUMN " + target.Name + " "
+	var changes []Change
+	if !sameType {
+		changes = append(changes, Change{Sql: prefix + "TYPE " + target.Type, Destructive: true})
+	}
+	if current.Nullable != target.Nullable {
+		if target.Nullable {
+			changes = append(changes, Change{Sql: prefix + "DROP NOT NULL"})
+		} else {
+			changes = append(changes, Change{Sql: prefix + "SET NOT NULL"})
+		}
+	}
+	if !sameDefault {
+		if target.Default == "" {
+			changes = append(changes, Change{Sql: prefix + "DROP DEFAULT"})
+		} else {
+			changes = append(changes, Change{Sql: prefix + "SET DEFAULT " + target.Default})
+		}
+	}
+	return changes
+}
+
+// columnSql renders the column definition
+func columnSql(dialect Dialect, column Column) string {
+	parts := []string{column.Name, column.Type}
+	if !column.Nullable {
+		parts = append(parts, "NOT NULL")
+	}
+	if column.Default != "" {
+		parts = append(parts, "DEFAULT "+column.Default)
+	}
+	if column.Extra != "" && dialect == DialectMysql {
+		parts = append(parts, column.Extra)
+	}
+	return strings.Join(parts, " ")
+}
+
+// createTableSql renders the CREATE TABLE statement of the table, without its indexes
+func createTableSql(dialect Dialect, table Table) string {
+	definitions := make([]string, 0, len(table.Columns)+1)
+	for _, column := range table.Columns {
+		definitions = append(definitions, "  "+columnSql(dialect, column))
+	}
+	if len(table.PrimaryKey) > 0 {
+		definitions = append(
+			definitions, "  PRIMARY KEY ("+strings.Join(table.PrimaryKey, ", ")+")",
+		)
+	}
+
+	return "CREATE TABLE " + table.Name + " (\n" + strings.Join(definitions, ",\n") + "\n)"
+}
+
+func createIndexSql(tableName string, index Index) string {
+	unique := ""
+	if index.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf(
+		"CREATE %sINDEX %s ON %s (%s)",
+		unique, index.Name, tableName, strings.Join(index.Columns, ", "),
+	)
+}
+
+func dropIndexSql(dialect Dialect, tableName string, index Index) string {
+	if dialect == DialectMysql {
+		return "DROP INDEX " + index.Name + " ON " + tableName
+	}
+	return "DROP INDEX " + index.Name
+}
+
+func sameIndex(a, b Index) bool {
+	return a.Unique == b.Unique && slices.EqualFunc(a.Columns, b.Columns, strings.EqualFold)
+}
+
+// typeAliases maps the type names of each dialect to the names the database reports
+var typeAliases = map[Dialect]map[string]string{
+	DialectMysql: {
+		"integer": "int",
+		"bool":    "tinyint(1)",
+		"boolean": "tinyint(1)",
+		"dec":     "decimal",
+		"numeric": "decimal",
+	},
+	DialectPostgres: {
+		"int":         "integer",
+		"int4":        "integer",
+		"int8":        "bigint",
+		"int2":        "smallint",
+		"serial":      "integer",
+		"bigserial":   "bigint",
+		"smallserial": "smallint",
+		"bool":        "boolean",
+		"varchar":     "character varying",
+		"char":        "character",
+		"decimal":     "numeric",
+		"float8":      "double precision",
+		"float4":      "real",
+		"timestamp":   "timestamp without time zone",
+		"timestamptz": "timestamp with time zone",
+		"time":        "time without time zone",
+		"timetz":      "time with time zone",
+	},
+}
+
+// mysqlIntWidth matches the display width of the MySQL integer types, which is not reported
+// by MySQL 8
+var mysqlIntWidth = regexp.MustCompile(`^((?:tiny|small|medium|big)?int)\(\d+\)`)
+
+// normalizeType returns the comparable form of the type, in the names the database reports
+func normalizeType(dialect Dialect, columnType string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(columnType), " "))
+	normalized = strings.ReplaceAll(normalized, ", ", ",")
+
+	if dialect == DialectMysql && normalized != "tinyint(1)" {
+		normalized = mysqlIntWidth.ReplaceAllString(normalized, "$1")
+	}
+
+	// the arguments, like (255) in varchar(255), are kept after the alias
+	name, args := normalized, ""
+	open, end := strings.Index(normalized, "("), strings.LastIndex(normalized, ")")
+	if open > 0 && end > open {
+		name = strings.TrimSpace(normalized[:open] + normalized[end+1:])
+		args = normalized[open : end+1]
+	}
+
+	if alias, ok := typeAliases[dialect][name]; ok {
+		return alias + args
+	}
+	return normalized
+}
+
+// defaultCast matches the Postgres casts of the default values, like ::character varying
+var defaultCast = regexp.MustCompile(`::[a-z ]+(\[\])?$`)
+
+// normalizeDefault returns the comparable form of the default value expression
+func normalizeDefault(value string) string {
+	normalized := strings.TrimSpace(value)
+	for strings.HasPrefix(normalized, "(") && strings.HasSuffix(normalized, ")") {
+		normalized = strings.TrimSpace(normalized[1 : len(normalized)-1])
+	}
+
+	normalized = defaultCast.ReplaceAllString(strings.ToLower(normalized), "")
+	if normalized == "null" {
+		return ""
+	}
+	return strings.Trim(normalized, "'")
+}
@@ -0,0 +1,207 @@
+package schemadiff
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SchemaDiffTestSuite struct {
+	suite.Suite
+}
+
+func TestSchemaDiffTestSuite(t *testing.T) {
+	suite.Run(t, new(SchemaDiffTestSuite))
+}
+
+func (suite *SchemaDiffTestSuite) TestItParsesMysqlDumps() {
+	schema := ParseDump(
+		"-- MySQL dump\n/*!40101 SET NAMES utf8mb4 */;\n"+
+			"CREATE TABLE `users` (\n"+
+			"  `id` int(11) NOT NULL AUTO_INCREMENT,\n"+
+			"  `email` varchar(255) COLLATE utf8mb4_bin NOT NULL COMMENT 'login; unique',\n"+
+			"  `status` enum('a','b') DEFAULT 'a',\n"+
+			"  `price` decimal(10, 2) DEFAULT NULL,\n"+
+			"  PRIMARY KEY (`id`),\n"+
+			"  UNIQUE KEY `users_email_uq` (`email`),\n"+
+			"  KEY `users_status_idx` (`status`, `price`) USING BTREE,\n"+
+			"  CONSTRAINT `users_fk` FOREIGN KEY (`id`) REFERENCES `accounts` (`id`)\n"+
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n"+
+			"INSERT INTO `users` VALUES (1, 'a@b.c', 'a', NULL);",
+		DialectMysql,
+	)
+
+	suite.Require().Len(schema.Tables, 1)
+	users := schema.Tables[0]
+	suite.Assert().Equal("users", users.Name)
+	suite.Assert().Equal(
+		[]Column{
+			{Name: "id", Type: "int(11)", Extra: "auto_increment"},
+			{Name: "email", Type: "varchar(255)"},
+			{Name: "status", Type: "enum('a','b')", Nullable: true, Default: "'a'"},
+			{Name: "price", Type: "decimal(10, 2)", Nullable: true},
+		},
+		users.Columns,
+	)
+	suite.Assert().Equal([]string{"id"}, users.PrimaryKey)
+	suite.Assert().Equal(
+		[]Index{
+			{Name: "users_email_uq", Unique: true, Columns: []string{"email"}},
+			{Name: "users_status_idx", Columns: []string{"status", "price"}},
+		},
+		users.Indexes,
+	)
+}
+
+func (suite *SchemaDiffTestSuite) TestItParsesPostgresDumps() {
+	schema := ParseDump(
+		"CREATE TABLE public.users (\n"+
+			"    id integer NOT NULL,\n"+
+			"    name character varying(100) DEFAULT 'x'::character varying,\n"+
+			"    code text UNIQUE\n"+
+			");\n"+
+			"ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT "+
+			"nextval('public.users_id_seq'::regclass);\n"+
+			"ALTER TABLE ONLY public.users ADD CONSTRAINT users_pkey PRIMARY KEY (id);\n"+
+			"CREATE INDEX users_name_idx ON public.users USING btree (name DESC);\n"+
+			"CREATE TABLE tags (id serial PRIMARY KEY)",
+		DialectPostgres,
+	)
+
+	suite.Require().Len(schema.Tables, 2)
+	users := schema.Tables[0]
+	suite.Assert().Equal(
+		[]Column{
+			{Name: "id", Type: "integer", Default: "nextval('public.users_id_seq'::regclass)"},
+			{
+				Name: "name", Type: "character varying(100)", Nullable: true,
+				Default: "'x'::character varying",
+			},
+			{Name: "code", Type: "text", Nullable: true},
+		},
+		users.Columns,
+	)
+	suite.Assert().Equal([]string{"id"}, users.PrimaryKey)
+	suite.Assert().Equal(
+		[]Index{
+			{Name: "users_code_key", Unique: true, Columns: []string{"code"}},
+			{Name: "users_name_idx", Columns: []string{"name"}},
+		},
+		users.Indexes,
+	)
+
+	suite.Assert().Equal(
+		[]Column{{Name: "id", Type: "serial", Default: "nextval('tags_id_seq'::regclass)"}},
+		schema.Tables[1].Columns,
+	)
+}
+
+func (suite *SchemaDiffTestSuite) TestItDraftsTheMysqlDdlOfTheDifferences() {
+	current := Schema{
+		Dialect: DialectMysql,
+		Tables: []Table{
+			{
+				Name: "users",
+				Columns: []Column{
+					{Name: "id", Type: "int", Extra: "auto_increment"},
+					{Name: "email", Type: "varchar(100)"},
+					{Name: "legacy", Type: "text", Nullable: true},
+					{Name: "status", Type: "varchar(10)", Default: "'a'"},
+				},
+				PrimaryKey: []string{"id"},
+				Indexes: []Index{
+					{Name: "users_legacy_idx", Columns: []string{"legacy"}},
+					{Name: "users_email_idx", Columns: []string{"email"}},
+				},
+			},
+			{Name: "old", Columns: []Column{{Name: "id", Type: "int"}}},
+			{Name: "migration_executions", Columns: []Column{{Name: "version", Type: "bigint"}}},
+		},
+	}
+	target := ParseDump(
+		"CREATE TABLE users (\n"+
+			"  id INT(11) NOT NULL AUTO_INCREMENT PRIMARY KEY,\n"+
+			"  email VARCHAR(255) NOT NULL,\n"+
+			"  status varchar(10) NOT NULL DEFAULT 'a',\n"+
+			"  age int DEFAULT 0,\n"+
+			"  UNIQUE KEY users_email_idx (email)\n"+
+			");\n"+
+			"CREATE TABLE posts (\n"+
+			"  id int NOT NULL, title text, PRIMARY KEY (id), KEY posts_title (title)\n"+
+			")",
+		DialectMysql,
+	)
+
+	suite.Assert().Equal(
+		[]Change{
+			{Sql: "ALTER TABLE users ADD COLUMN age int DEFAULT 0"},
+			{Sql: "CREATE TABLE posts (\n  id int NOT NULL,\n  title text,\n  PRIMARY KEY (id)\n)"},
+			{Sql: "CREATE INDEX posts_title ON posts (title)"},
+			{
+				Sql:         "ALTER TABLE users MODIFY COLUMN email VARCHAR(255) NOT NULL",
+				Destructive: true,
+			},
+			{Sql: "DROP INDEX users_legacy_idx ON users"},
+			{Sql: "DROP INDEX users_email_idx ON users"},
+			{Sql: "CREATE UNIQUE INDEX users_email_idx ON users (email)"},
+			{Sql: "ALTER TABLE users DROP COLUMN legacy", Destructive: true},
+			{Sql: "DROP TABLE old", Destructive: true},
+		},
+		Diff(current, target, "migration_executions"),
+	)
+}
+
+func (suite *SchemaDiffTestSuite) TestItDraftsThePostgresDdlOfTheDifferences() {
+	current := Schema{
+		Dialect: DialectPostgres,
+		Tables: []Table{
+			{
+				Name: "users",
+				Columns: []Column{
+					{Name: "id", Type: "integer", Default: "nextval('users_id_seq'::regclass)"},
+					{Name: "name", Type: "character varying(50)", Nullable: true},
+					{
+						Name: "status", Type: "text", Nullable: true,
+						Default: "'active'::text",
+					},
+					{Name: "created_at", Type: "timestamp with time zone"},
+				},
+			},
+		},
+	}
+	target := ParseDump(
+		"CREATE TABLE users (\n"+
+			"  id serial,\n"+
+			"  name varchar(100) NOT NULL,\n"+
+			"  status text,\n"+
+			"  created_at timestamptz NOT NULL\n"+
+			")",
+		DialectPostgres,
+	)
+
+	suite.Assert().Equal(
+		[]Change{
+			{Sql: "ALTER TABLE users ALTER COLUMN name TYPE varchar(100)", Destructive: true},
+			{Sql: "ALTER TABLE users ALTER COLUMN name SET NOT NULL"},
+			{Sql: "ALTER TABLE users ALTER COLUMN status DROP DEFAULT"},
+		},
+		Diff(current, target),
+	)
+	suite.Assert().Empty(Diff(target, target))
+}
+
+func (suite *SchemaDiffTestSuite) TestItReadsDumpFiles() {
+	path := filepath.Join(suite.T().TempDir(), "schema.sql")
+	suite.Require().NoError(os.WriteFile(path, []byte("CREATE TABLE a (id int)"), 0644))
+
+	schema, err := NewDumpSource(path, DialectMysql).Schema(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(DialectMysql, schema.Dialect)
+	suite.Assert().NotNil(schema.Table("A"))
+
+	_, err = NewDumpSource(path+".missing", DialectMysql).Schema(context.Background())
+	suite.Assert().ErrorContains(err, "failed to read the schema dump with error")
+}
@@ -0,0 +1,125 @@
+package sqlhelper
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// DefaultSeqScanRowsThreshold is the estimated number of rows above which a sequential scan
+// is flagged, when no threshold is provided to ExplainPostgres
+const DefaultSeqScanRowsThreshold = 100000
+
+// explainableStatement matches the DML statements Postgres can EXPLAIN
+var explainableStatement = regexp.MustCompile(`(?is)^\s*(SELECT|INSERT|UPDATE|DELETE|MERGE|WITH)\b`)
+
+// SeqScan is a sequential scan found in a statement plan
+type SeqScan struct {
+	Relation      string
+	EstimatedRows float64
+}
+
+// ExplainResult holds the cost estimates of a captured statement
+type ExplainResult struct {
+	Statement migration.CapturedStatement
+
+	// StartupCost and TotalCost are the planner cost estimates of the statement
+	StartupCost float64
+	TotalCost   float64
+
+	// EstimatedRows is the number of rows the planner estimates the statement will handle
+	EstimatedRows float64
+
+	// SeqScans lists the sequential scans estimated to read more rows than the threshold
+	SeqScans []SeqScan
+}
+
+// Flagged returns true if the statement would trigger sequential scans over huge tables
+func (r ExplainResult) Flagged() bool {
+	return len(r.SeqScans) > 0
+}
+
+type postgresPlanNode struct {
+	NodeType     string             `json:"Node Type"`
+	RelationName string             `json:"Relation Name"`
+	StartupCost  float64            `json:"Startup Cost"`
+	TotalCost    float64            `json:"Total Cost"`
+	PlanRows     float64            `json:"Plan Rows"`
+	Plans        []postgresPlanNode `json:"Plans"`
+}
+
+// ExplainPostgres runs EXPLAIN (without ANALYZE, so nothing is executed) for each of the
+// captured DML statements and returns their cost estimates. Non DML statements (DDL, for
+// example) are skipped. Sequential scans estimated to read at least seqScanRowsThreshold
+// rows are flagged; a non-positive threshold means DefaultSeqScanRowsThreshold.
+func ExplainPostgres(
+	ctx context.Context,
+	db *sql.DB,
+	statements []migration.CapturedStatement,
+	seqScanRowsThreshold float64,
+) ([]ExplainResult, error) {
+	if seqScanRowsThreshold <= 0 {
+		seqScanRowsThreshold = DefaultSeqScanRowsThreshold
+	}
+
+	var results []ExplainResult
+	for _, statement := range statements {
+		if !explainableStatement.MatchString(statement.Query) {
+			continue
+		}
+
+		var rawPlan []byte
+		err := db.QueryRowContext(
+			ctx, "EXPLAIN (FORMAT JSON) "+statement.Query, statement.Args...,
+		).Scan(&rawPlan)
+		if err != nil {
+			return results, fmt.Errorf(
+				"failed to explain statement %q with error: %w", statement.Query, err,
+			)
+		}
+
+		var plans []struct {
+			Plan postgresPlanNode `json:"Plan"`
+		}
+		if err = json.Unmarshal(rawPlan, &plans); err != nil {
+			return results, fmt.Errorf(
+				"failed to parse plan of statement %q with error: %w", statement.Query, err,
+			)
+		}
+
+		if len(plans) == 0 {
+			return results, fmt.Errorf("empty plan for statement %q", statement.Query)
+		}
+
+		root := plans[0].Plan
+		results = append(
+			results, ExplainResult{
+				Statement:     statement,
+				StartupCost:   root.StartupCost,
+				TotalCost:     root.TotalCost,
+				EstimatedRows: root.PlanRows,
+				SeqScans:      findSeqScans(root, seqScanRowsThreshold),
+			},
+		)
+	}
+
+	return results, nil
+}
+
+// findSeqScans walks the plan tree and collects the sequential scans reaching the threshold
+func findSeqScans(node postgresPlanNode, threshold float64) []SeqScan {
+	var scans []SeqScan
+	if node.NodeType == "Seq Scan" && node.PlanRows >= threshold {
+		scans = append(scans, SeqScan{node.RelationName, node.PlanRows})
+	}
+
+	for _, child := range node.Plans {
+		scans = append(scans, findSeqScans(child, threshold)...)
+	}
+
+	return scans
+}
@@ -0,0 +1,89 @@
+package sqlhelper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExplainTestSuite struct {
+	suite.Suite
+}
+
+func TestExplainTestSuite(t *testing.T) {
+	suite.Run(t, new(ExplainTestSuite))
+}
+
+const seqScanPlan = `[{"Plan": {
+	"Node Type": "ModifyTable", "Startup Cost": 0, "Total Cost": 250000.5, "Plan Rows": 0,
+	"Plans": [{
+		"Node Type": "Seq Scan", "Relation Name": "users", "Startup Cost": 0,
+		"Total Cost": 250000.5, "Plan Rows": 5000000
+	}]
+}}]`
+
+const indexScanPlan = `[{"Plan": {
+	"Node Type": "Index Scan", "Relation Name": "users", "Startup Cost": 0.42,
+	"Total Cost": 8.44, "Plan Rows": 1
+}}]`
+
+func (suite *ExplainTestSuite) TestItCanExplainCapturedDmlStatements() {
+	d := &fakeDriver{queryRows: [][]byte{[]byte(seqScanPlan), []byte(indexScanPlan)}}
+	db := openFakeDb(d)
+
+	statements := []migration.CapturedStatement{
+		{Query: "CREATE INDEX idx ON users (name)"},
+		{Query: "UPDATE users SET active = $1", Args: []any{true}},
+		{Query: "\n  select * from users where id = 1"},
+	}
+	results, err := ExplainPostgres(context.Background(), db, statements, 0)
+
+	suite.Require().NoError(err)
+	suite.Require().Len(results, 2)
+	suite.Assert().Equal(
+		[]string{
+			"EXPLAIN (FORMAT JSON) UPDATE users SET active = $1",
+			"EXPLAIN (FORMAT JSON) \n  select * from users where id = 1",
+		},
+		d.statements,
+	)
+	suite.Assert().Len(d.queryArgs[0], 1)
+
+	suite.Assert().Equal(statements[1], results[0].Statement)
+	suite.Assert().Equal(250000.5, results[0].TotalCost)
+	suite.Assert().True(results[0].Flagged())
+	suite.Assert().Equal([]SeqScan{{"users", 5000000}}, results[0].SeqScans)
+
+	suite.Assert().Equal(8.44, results[1].TotalCost)
+	suite.Assert().Equal(0.42, results[1].StartupCost)
+	suite.Assert().Equal(float64(1), results[1].EstimatedRows)
+	suite.Assert().False(results[1].Flagged())
+}
+
+func (suite *ExplainTestSuite) TestItDoesNotFlagSeqScansBelowThreshold() {
+	d := &fakeDriver{queryRows: [][]byte{[]byte(seqScanPlan)}}
+	db := openFakeDb(d)
+
+	results, err := ExplainPostgres(
+		context.Background(), db,
+		[]migration.CapturedStatement{{Query: "DELETE FROM users"}},
+		10000000,
+	)
+
+	suite.Require().NoError(err)
+	suite.Assert().False(results[0].Flagged())
+}
+
+func (suite *ExplainTestSuite) TestItFailsToExplainWhenTheQueryFails() {
+	db := openFakeDb(&fakeDriver{})
+
+	_, err := ExplainPostgres(
+		context.Background(), db,
+		[]migration.CapturedStatement{{Query: "DELETE FROM users"}},
+		0,
+	)
+
+	suite.Assert().ErrorContains(err, "failed to explain statement")
+}
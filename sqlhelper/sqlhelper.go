@@ -0,0 +1,204 @@
+// Package sqlhelper provides helpers for writing SQL migrations: a transaction wrapper and
+// a batch executor. Statements executed through these helpers have their affected rows
+// recorded (see migration.RecordRowsAffected), so they are reported per migration and in
+// the run summary.
+//
+// The helpers also honor the capture mode (see migration.WithCapture): when the context
+// carries a capture, statements are recorded instead of being executed. Migrations which
+// use only these helpers can declare themselves migration.CaptureCapable.
+package sqlhelper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golibry/go-migrations/migration"
+)
+
+// Execer is implemented by *sql.DB, *sql.Conn, *sql.Tx and *Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Tx wraps a *sql.Tx and counts the rows affected by the statements executed through it.
+// The count is recorded for the migration only when the transaction is committed.
+type Tx struct {
+	*sql.Tx
+	rows int64
+}
+
+// ExecContext executes the query in the transaction and counts the affected rows
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if capture, ok := migration.CaptureFromContext(ctx); ok {
+		capture.Add(query, args...)
+		return capturedResult{}, nil
+	}
+
+	start := time.Now()
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	if err == nil {
+		tx.rows += rowsAffected(result)
+	}
+	logStatement(ctx, query, start, result, err)
+	return result, err
+}
+
+// Exec executes the query in the transaction and counts the affected rows. Prefer
+// ExecContext, as only it can honor the capture mode.
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+// RowsAffected returns the number of rows affected so far in the transaction
+func (tx *Tx) RowsAffected() int64 {
+	return tx.rows
+}
+
+// InTx runs fn in a new transaction. The transaction is committed if fn succeeds and rolled
+// back otherwise. Affected rows counted by the transaction are recorded after the commit.
+//
+// Example:
+//
+//	return sqlhelper.InTx(ctx, db.(*sql.DB), nil, func(tx *sqlhelper.Tx) error {
+//		_, err := tx.ExecContext(ctx, "UPDATE users SET active = 1")
+//		return err
+//	})
+func InTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx *Tx) error) error {
+	if _, ok := migration.CaptureFromContext(ctx); ok {
+		// No transaction is started in capture mode, statements are only recorded
+		return fn(&Tx{})
+	}
+
+	sqlTx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction with error: %w", err)
+	}
+
+	tx := &Tx{Tx: sqlTx}
+	if err = fn(tx); err != nil {
+		if rollbackErr := sqlTx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w, with rollback error: %w", err, rollbackErr)
+		}
+		return err
+	}
+
+	if err = sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction with error: %w", err)
+	}
+
+	migration.RecordRowsAffected(ctx, tx.rows)
+	return nil
+}
+
+// Exec executes the query and records the affected rows. When execer is a *Tx, the rows
+// are recorded on commit instead, by InTx.
+func Exec(ctx context.Context, execer Execer, query string, args ...any) (sql.Result, error) {
+	if capture, ok := migration.CaptureFromContext(ctx); ok {
+		capture.Add(query, args...)
+		return capturedResult{}, nil
+	}
+
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args...)
+	if _, isTx := execer.(*Tx); !isTx {
+		if err == nil {
+			migration.RecordRowsAffected(ctx, rowsAffected(result))
+		}
+		logStatement(ctx, query, start, result, err)
+	}
+	return result, err
+}
+
+// logStatement logs the executed statement at the debug level, with the logger carried by ctx
+// (see migration.LoggerFrom)
+func logStatement(
+	ctx context.Context,
+	query string,
+	start time.Time,
+	result sql.Result,
+	err error,
+) {
+	logger := migration.LoggerFrom(ctx)
+	if err != nil {
+		logger.DebugContext(
+			ctx, "statement failed", "query", query, "duration", time.Since(start), "error", err,
+		)
+		return
+	}
+
+	logger.DebugContext(
+		ctx, "statement executed", "query", query, "duration", time.Since(start),
+		"rows_affected", rowsAffected(result),
+	)
+}
+
+// ErrBatchLimitReached is returned by ExecInBatches when the maximum number of batches
+// was executed and the last batch still affected rows
+var ErrBatchLimitReached = errors.New("batch limit reached")
+
+// ExecInBatches executes the query repeatedly until it affects no rows, which is the usual
+// way of running large backfills in small chunks (for example, an UPDATE ... LIMIT 1000 in
+// MySQL or an UPDATE ... WHERE id IN (SELECT ... LIMIT 1000) in Postgres). The query must
+// eventually stop matching rows. maxBatches guards against queries which never do; use 0
+// for no limit. Returns the total number of affected rows. In capture mode, the query is
+// recorded once.
+func ExecInBatches(
+	ctx context.Context,
+	execer Execer,
+	maxBatches int,
+	query string,
+	args ...any,
+) (int64, error) {
+	var total int64
+	for batch := 1; ; batch++ {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		result, err := Exec(ctx, execer, query, args...)
+		if err != nil {
+			return total, fmt.Errorf("batch %d failed with error: %w", batch, err)
+		}
+
+		affected := rowsAffected(result)
+		total += affected
+
+		if affected == 0 {
+			return total, nil
+		}
+
+		if maxBatches > 0 && batch >= maxBatches {
+			return total, fmt.Errorf(
+				"%w after %d batches (%d rows affected)", ErrBatchLimitReached, batch, total,
+			)
+		}
+	}
+}
+
+// rowsAffected returns the affected rows of the result, or 0 if the driver does not
+// support it
+func rowsAffected(result sql.Result) int64 {
+	if result == nil {
+		return 0
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+	return affected
+}
+
+// capturedResult is the result returned for statements recorded in capture mode
+type capturedResult struct{}
+
+func (capturedResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (capturedResult) RowsAffected() (int64, error) {
+	return 0, nil
+}
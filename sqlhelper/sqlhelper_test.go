@@ -0,0 +1,270 @@
+package sqlhelper
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeDriver returns, for each executed statement, the next affected rows count from its
+// script. Transactions are tracked to assert commits and rollbacks.
+type fakeDriver struct {
+	mu         sync.Mutex
+	script     []int64
+	execErr    error
+	commits    int
+	rollbacks  int
+	statements []string
+	queryRows  [][]byte
+	queryArgs  [][]driver.NamedValue
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{d}, nil
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return &fakeTx{c.driver}, nil
+}
+
+func (c *fakeConn) ExecContext(
+	_ context.Context,
+	query string,
+	_ []driver.NamedValue,
+) (driver.Result, error) {
+	d := c.driver
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.execErr != nil {
+		return nil, d.execErr
+	}
+
+	d.statements = append(d.statements, query)
+	var affected int64
+	if len(d.script) > 0 {
+		affected, d.script = d.script[0], d.script[1:]
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+func (c *fakeConn) QueryContext(
+	_ context.Context,
+	query string,
+	args []driver.NamedValue,
+) (driver.Rows, error) {
+	d := c.driver
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, query)
+	d.queryArgs = append(d.queryArgs, args)
+	if len(d.queryRows) == 0 {
+		return nil, errors.New("no query rows")
+	}
+
+	var value []byte
+	value, d.queryRows = d.queryRows[0], d.queryRows[1:]
+	return &fakeRows{values: [][]byte{value}}, nil
+}
+
+type fakeRows struct {
+	values [][]byte
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"value"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+type fakeTx struct {
+	driver *fakeDriver
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.driver.commits++
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.driver.rollbacks++
+	return nil
+}
+
+var fakeDriversCount = 0
+
+func openFakeDb(d *fakeDriver) *sql.DB {
+	fakeDriversCount++
+	name := "sqlhelper-fake-" + strconv.Itoa(fakeDriversCount)
+	sql.Register(name, d)
+	db, _ := sql.Open(name, "")
+	return db
+}
+
+type SqlHelperTestSuite struct {
+	suite.Suite
+}
+
+func TestSqlHelperTestSuite(t *testing.T) {
+	suite.Run(t, new(SqlHelperTestSuite))
+}
+
+func (suite *SqlHelperTestSuite) TestItRecordsRowsAffectedOnCommit() {
+	d := &fakeDriver{script: []int64{2, 3}}
+	db := openFakeDb(d)
+	ctx, counter := migration.WithRowsCounter(context.Background())
+
+	err := InTx(
+		ctx, db, nil, func(tx *Tx) error {
+			_, _ = tx.ExecContext(ctx, "UPDATE a")
+			_, _ = Exec(ctx, tx, "UPDATE b")
+			suite.Assert().Equal(int64(5), tx.RowsAffected())
+			suite.Assert().False(counter.Recorded())
+			return nil
+		},
+	)
+
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(1, d.commits)
+	suite.Assert().True(counter.Recorded())
+	suite.Assert().Equal(int64(5), counter.Count())
+}
+
+func (suite *SqlHelperTestSuite) TestItRollsBackAndDoesNotRecordRowsWhenTxFails() {
+	d := &fakeDriver{script: []int64{2}}
+	db := openFakeDb(d)
+	ctx, counter := migration.WithRowsCounter(context.Background())
+	fnErr := errors.New("fn failed")
+
+	err := InTx(
+		ctx, db, nil, func(tx *Tx) error {
+			_, _ = tx.Exec("UPDATE a")
+			return fnErr
+		},
+	)
+
+	suite.Assert().ErrorIs(err, fnErr)
+	suite.Assert().Equal(1, d.rollbacks)
+	suite.Assert().Equal(0, d.commits)
+	suite.Assert().False(counter.Recorded())
+}
+
+func (suite *SqlHelperTestSuite) TestItCanExecInBatchesUntilNoRowsAreAffected() {
+	d := &fakeDriver{script: []int64{10, 10, 4, 0}}
+	db := openFakeDb(d)
+	ctx, counter := migration.WithRowsCounter(context.Background())
+
+	total, err := ExecInBatches(ctx, db, 0, "UPDATE a LIMIT 10")
+
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(int64(24), total)
+	suite.Assert().Equal(int64(24), counter.Count())
+	suite.Assert().Len(d.statements, 4)
+}
+
+func (suite *SqlHelperTestSuite) TestItStopsExecutingBatchesWhenLimitIsReached() {
+	d := &fakeDriver{script: []int64{10, 10, 10, 10}}
+	db := openFakeDb(d)
+
+	total, err := ExecInBatches(context.Background(), db, 2, "UPDATE a LIMIT 10")
+
+	suite.Assert().ErrorIs(err, ErrBatchLimitReached)
+	suite.Assert().Equal(int64(20), total)
+	suite.Assert().Len(d.statements, 2)
+}
+
+func (suite *SqlHelperTestSuite) TestItFailsToExecBatchesWhenStatementFails() {
+	d := &fakeDriver{execErr: errors.New("exec failed")}
+	db := openFakeDb(d)
+
+	_, err := ExecInBatches(context.Background(), db, 0, "UPDATE a LIMIT 10")
+
+	suite.Assert().ErrorContains(err, "batch 1 failed")
+	suite.Assert().ErrorContains(err, "exec failed")
+}
+
+func (suite *SqlHelperTestSuite) TestItLogsTheExecutedStatementsAtTheDebugLevel() {
+	d := &fakeDriver{script: []int64{2, 3}}
+	db := openFakeDb(d)
+	var logs bytes.Buffer
+	ctx := migration.WithLogger(
+		context.Background(),
+		slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	)
+
+	_, _ = Exec(ctx, db, "UPDATE a")
+	_ = InTx(
+		ctx, db, nil, func(tx *Tx) error {
+			_, err := Exec(ctx, tx, "UPDATE b")
+			return err
+		},
+	)
+
+	suite.Assert().Equal(2, strings.Count(logs.String(), "statement executed"))
+	suite.Assert().Contains(logs.String(), `query="UPDATE a"`)
+	suite.Assert().Contains(logs.String(), "rows_affected=3")
+}
+
+func (suite *SqlHelperTestSuite) TestItCapturesStatementsInsteadOfExecutingThem() {
+	d := &fakeDriver{script: []int64{5, 5, 5}}
+	db := openFakeDb(d)
+	ctx, capture := migration.WithCapture(context.Background())
+	ctx, counter := migration.WithRowsCounter(ctx)
+
+	err := InTx(
+		ctx, db, nil, func(tx *Tx) error {
+			_, err := tx.ExecContext(ctx, "UPDATE a SET b = $1", 1)
+			return err
+		},
+	)
+	suite.Require().NoError(err)
+	_, err = Exec(ctx, db, "DELETE FROM a")
+	suite.Require().NoError(err)
+	total, err := ExecInBatches(ctx, db, 0, "UPDATE c LIMIT 10")
+	suite.Require().NoError(err)
+
+	suite.Assert().Equal(int64(0), total)
+	suite.Assert().Empty(d.statements)
+	suite.Assert().Equal(0, d.commits)
+	suite.Assert().False(counter.Recorded())
+	suite.Assert().Equal(
+		[]migration.CapturedStatement{
+			{Query: "UPDATE a SET b = $1", Args: []any{1}},
+			{Query: "DELETE FROM a"},
+			{Query: "UPDATE c LIMIT 10"},
+		},
+		capture.Statements(),
+	)
+}
@@ -0,0 +1,153 @@
+package migrations
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+)
+
+// Status describes if the migrated state is current, as reported by the StatusHandler
+type Status struct {
+	// Current is true if all the migrations from the registry are executed and finished
+	Current bool `json:"current"`
+
+	// Pending is the number of migrations not executed yet
+	Pending int `json:"pending"`
+
+	// Error is the error of loading the executions (or of an inconsistent executions state)
+	Error string `json:"error,omitempty"`
+
+	// CheckedAt is the time the executions were loaded, older than the request if cached
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// StatusOption configures the optional behaviour of a StatusHandler
+type StatusOption func(*StatusHandler)
+
+// WithStatusCacheTTL caches the status for the given duration, so high-frequency readiness
+// probes don't load the executions on each request. Concurrent requests which find the cache
+// expired share a single refresh. A zero or negative ttl disables the cache, which is the
+// default.
+func WithStatusCacheTTL(ttl time.Duration) StatusOption {
+	return func(h *StatusHandler) {
+		h.ttl = ttl
+	}
+}
+
+// WithStatusExecutionPlan builds the plans the status is computed from with the given builder,
+// for example handler.NewOutOfOrderPlan when the migrations run out of order. Defaults to
+// handler.NewPlan.
+func WithStatusExecutionPlan(newExecutionPlan handler.ExecutionPlanBuilder) StatusOption {
+	return func(h *StatusHandler) {
+		h.newExecutionPlan = newExecutionPlan
+	}
+}
+
+// StatusHandler is an http.Handler reporting the migrated state, for the readiness probes of
+// the applications which depend on it. It responds with the Status as JSON, with the 200 code
+// if the state is current and 503 otherwise.
+type StatusHandler struct {
+	registry         migration.MigrationsRegistry
+	repository       execution.Repository
+	newExecutionPlan handler.ExecutionPlanBuilder
+	ttl              time.Duration
+	now              func() time.Time
+
+	mu         sync.Mutex
+	cached     *Status
+	refreshing chan struct{}
+}
+
+// NewStatusHandler builds a new StatusHandler for the migrations from the registry
+func NewStatusHandler(
+	registry migration.MigrationsRegistry,
+	repository execution.Repository,
+	opts ...StatusOption,
+) *StatusHandler {
+	h := &StatusHandler{
+		registry: registry, repository: repository, newExecutionPlan: handler.NewPlan,
+		now: time.Now,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Status returns the migrated state, from the cache if it is enabled and not expired
+func (h *StatusHandler) Status() Status {
+	if h.ttl <= 0 {
+		return h.load()
+	}
+
+	h.mu.Lock()
+	for {
+		if h.cached != nil && h.now().Sub(h.cached.CheckedAt) < h.ttl {
+			status := *h.cached
+			h.mu.Unlock()
+			return status
+		}
+		if h.refreshing == nil {
+			break
+		}
+
+		// another request is refreshing the cache, wait for it and check the cache again
+		refreshing := h.refreshing
+		h.mu.Unlock()
+		<-refreshing
+		h.mu.Lock()
+	}
+
+	refreshing := make(chan struct{})
+	h.refreshing = refreshing
+	h.mu.Unlock()
+
+	// the waiting requests are released even if the load panics, one of them refreshes then
+	defer func() {
+		h.mu.Lock()
+		h.refreshing = nil
+		h.mu.Unlock()
+		close(refreshing)
+	}()
+
+	status := h.load()
+
+	h.mu.Lock()
+	h.cached = &status
+	h.mu.Unlock()
+
+	return status
+}
+
+func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	status := h.Status()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if status.Current {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// load builds the status from the executions
+func (h *StatusHandler) load() Status {
+	status := Status{CheckedAt: h.now()}
+
+	plan, err := h.newExecutionPlan(h.registry, h.repository)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	status.Pending = len(plan.AllToBeExecuted())
+	status.Current = status.Pending == 0
+	return status
+}
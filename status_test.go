@@ -0,0 +1,153 @@
+package migrations
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type StatusTestSuite struct {
+	suite.Suite
+}
+
+func TestStatusTestSuite(t *testing.T) {
+	suite.Run(t, new(StatusTestSuite))
+}
+
+// countingRepository counts the (slow) loads of the executions
+type countingRepository struct {
+	syncRepository
+	loads atomic.Int32
+}
+
+func (repo *countingRepository) LoadExecutions() ([]execution.MigrationExecution, error) {
+	repo.loads.Add(1)
+	time.Sleep(10 * time.Millisecond)
+	return repo.syncRepository.LoadExecutions()
+}
+
+func (suite *StatusTestSuite) TestItReportsTheMigratedState() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &syncRepository{}
+	_ = repo.Save(execution.MigrationExecution{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2})
+	statusHandler := NewStatusHandler(registry, repo)
+
+	serve := func() (int, Status) {
+		recorder := httptest.NewRecorder()
+		statusHandler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+		var status Status
+		suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &status))
+		return recorder.Code, status
+	}
+
+	code, status := serve()
+	suite.Assert().Equal(http.StatusServiceUnavailable, code)
+	suite.Assert().False(status.Current)
+	suite.Assert().Equal(1, status.Pending)
+
+	_ = repo.Save(execution.MigrationExecution{Version: 2, ExecutedAtMs: 3, FinishedAtMs: 4})
+	code, status = serve()
+	suite.Assert().Equal(http.StatusOK, code)
+	suite.Assert().True(status.Current)
+
+	repo.LoadErr = errors.New("connection refused")
+	code, status = serve()
+	suite.Assert().Equal(http.StatusServiceUnavailable, code)
+	suite.Assert().Contains(status.Error, "connection refused")
+}
+
+func (suite *StatusTestSuite) TestItReportsTheStateOfTheOutOfOrderExecutions() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	_ = registry.Register(migration.NewDummyMigration(2))
+	repo := &syncRepository{}
+	_ = repo.Save(execution.MigrationExecution{Version: 2, ExecutedAtMs: 1, FinishedAtMs: 2})
+
+	status := NewStatusHandler(registry, repo).Status()
+	suite.Assert().Contains(status.Error, "Migrations and executions are out of order")
+
+	status = NewStatusHandler(
+		registry, repo, WithStatusExecutionPlan(handler.NewOutOfOrderPlan),
+	).Status()
+	suite.Assert().Empty(status.Error)
+	suite.Assert().Equal(1, status.Pending)
+}
+
+func (suite *StatusTestSuite) TestItCachesTheStatusAndRefreshesItOnce() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	repo := &countingRepository{}
+	now := time.Now()
+	statusHandler := NewStatusHandler(registry, repo, WithStatusCacheTTL(time.Second))
+	statusHandler.now = func() time.Time { return now }
+
+	probe := func(current bool) {
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				suite.Assert().Equal(current, statusHandler.Status().Current)
+			}()
+		}
+		wg.Wait()
+	}
+
+	probe(false)
+	suite.Assert().EqualValues(1, repo.loads.Load())
+
+	now = now.Add(999 * time.Millisecond)
+	probe(false)
+	suite.Assert().EqualValues(1, repo.loads.Load())
+
+	now = now.Add(time.Millisecond)
+	_ = repo.Save(execution.MigrationExecution{Version: 1, ExecutedAtMs: 1, FinishedAtMs: 2})
+	probe(true)
+	suite.Assert().EqualValues(2, repo.loads.Load())
+}
+
+// panickingRepository panics on the first load of the executions
+type panickingRepository struct {
+	syncRepository
+	panicked atomic.Bool
+}
+
+func (repo *panickingRepository) LoadExecutions() ([]execution.MigrationExecution, error) {
+	if repo.panicked.CompareAndSwap(false, true) {
+		panic("driver bug")
+	}
+	return repo.syncRepository.LoadExecutions()
+}
+
+func (suite *StatusTestSuite) TestItRefreshesTheCacheAfterAPanickedRefresh() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	statusHandler := NewStatusHandler(
+		registry, &panickingRepository{}, WithStatusCacheTTL(time.Second),
+	)
+
+	suite.Assert().Panics(func() { statusHandler.Status() })
+
+	status := make(chan Status)
+	go func() { status <- statusHandler.Status() }()
+	select {
+	case loaded := <-status:
+		suite.Assert().False(loaded.Current)
+		suite.Assert().Equal(1, loaded.Pending)
+	case <-time.After(time.Second):
+		suite.Fail("the status request waits for the panicked refresh")
+	}
+}
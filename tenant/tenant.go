@@ -0,0 +1,193 @@
+// Package tenant runs the migrations of several tenants (databases, schemas or namespaces
+// migrated with the same registry), each with its own handler and its own lock, so tenants
+// are migrated concurrently, with a parallelism limit, instead of excluding each other.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/lock"
+)
+
+// DefaultParallelism is the number of tenants migrated concurrently when none is configured
+const DefaultParallelism = 4
+
+// Tenant is a migrated target with its own executions state
+type Tenant struct {
+	Name    string
+	Handler *handler.MigrationsHandler
+}
+
+// Operation is what the Runner executes for each tenant (see Up and Down)
+type Operation func(
+	ctx context.Context,
+	migrationsHandler *handler.MigrationsHandler,
+) ([]handler.ExecutedMigration, error)
+
+// Up builds an Operation which executes Up() for the next numOfRuns migrations of a tenant
+func Up(numOfRuns handler.NumOfRuns) Operation {
+	return func(
+		ctx context.Context,
+		migrationsHandler *handler.MigrationsHandler,
+	) ([]handler.ExecutedMigration, error) {
+		return migrationsHandler.MigrateUp(ctx, numOfRuns)
+	}
+}
+
+// Down builds an Operation which executes Down() for the last numOfRuns executed migrations
+// of a tenant
+func Down(numOfRuns handler.NumOfRuns) Operation {
+	return func(
+		ctx context.Context,
+		migrationsHandler *handler.MigrationsHandler,
+	) ([]handler.ExecutedMigration, error) {
+		return migrationsHandler.MigrateDown(ctx, numOfRuns)
+	}
+}
+
+// Result is the outcome of an operation for one tenant
+type Result struct {
+	Tenant     string
+	Migrations []handler.ExecutedMigration
+
+	// Skipped is true if the tenant lock was held by another process
+	Skipped bool
+
+	// Err is the error of the operation, joined with the failure to release the tenant lock
+	Err      error
+	Duration time.Duration
+}
+
+// Summary aggregates the results of all tenants
+type Summary struct {
+	// Results holds the result of each tenant, in the order the tenants were provided
+	Results []Result
+
+	Succeeded int
+	Failed    int
+	Skipped   int
+
+	// MigrationsCount is the number of migrations handled across all tenants
+	MigrationsCount int
+}
+
+// Err joins the errors of the failed tenants, nil if none failed
+func (s Summary) Err() error {
+	var errs []error
+	for _, result := range s.Results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", result.Tenant, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Runner executes an operation for all tenants, with at most parallelism tenants at a time.
+// Each tenant is locked separately, so runners started from different processes can work on
+// different tenants at the same time.
+type Runner struct {
+	tenants     []Tenant
+	parallelism int
+	newLocker   func(tenant string) lock.Locker
+}
+
+// NewRunner builds a new Runner. If parallelism is not positive, DefaultParallelism is used.
+// newLocker builds the locker of a tenant (usually scoping a base lock name to the tenant
+// name). It can be nil, or return nil for a tenant, when tenants must not be locked.
+func NewRunner(
+	tenants []Tenant,
+	parallelism int,
+	newLocker func(tenant string) lock.Locker,
+) *Runner {
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+
+	return &Runner{tenants: tenants, parallelism: parallelism, newLocker: newLocker}
+}
+
+// Run executes the operation for all tenants and aggregates the results. A failing tenant
+// does not stop the others. Tenants whose lock is held by another process are skipped. All
+// tenants share the run ID of the context (a new one is generated if it has none).
+func (r *Runner) Run(ctx context.Context, operation Operation) Summary {
+	ctx, _ = execution.EnsureRunId(ctx)
+
+	results := make([]Result, len(r.tenants))
+	slots := make(chan struct{}, r.parallelism)
+	var wg sync.WaitGroup
+
+	for i, tenant := range r.tenants {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			results[i] = r.runTenant(ctx, tenant, operation)
+		}()
+	}
+
+	wg.Wait()
+
+	summary := Summary{Results: results}
+	for _, result := range results {
+		summary.MigrationsCount += len(result.Migrations)
+		switch {
+		case result.Skipped:
+			summary.Skipped++
+		case result.Err != nil:
+			summary.Failed++
+		default:
+			summary.Succeeded++
+		}
+	}
+
+	return summary
+}
+
+func (r *Runner) runTenant(
+	ctx context.Context, tenant Tenant, operation Operation,
+) (result Result) {
+	result.Tenant = tenant.Name
+	start := time.Now()
+
+	var locker lock.Locker
+	if r.newLocker != nil {
+		locker = r.newLocker(tenant.Name)
+	}
+
+	if locker != nil {
+		if err := locker.Lock(ctx); err != nil {
+			if errors.Is(err, lock.ErrLockHeld) {
+				result.Skipped = true
+				return result
+			}
+
+			result.Err = fmt.Errorf("failed to acquire tenant lock with error: %w", err)
+			return result
+		}
+
+		defer func() {
+			// the lock is released even when the run was cancelled or exceeded its deadline
+			if err := locker.Unlock(context.WithoutCancel(ctx)); err != nil {
+				result.Err = errors.Join(
+					result.Err,
+					fmt.Errorf("failed to release tenant lock with error: %w", err),
+				)
+			}
+		}()
+	}
+
+	result.Migrations, result.Err = operation(ctx, tenant.Handler)
+	result.Duration = time.Since(start)
+	return result
+}
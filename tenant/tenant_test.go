@@ -0,0 +1,200 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golibry/go-migrations/execution"
+	"github.com/golibry/go-migrations/handler"
+	"github.com/golibry/go-migrations/lock"
+	"github.com/golibry/go-migrations/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+type TenantTestSuite struct {
+	suite.Suite
+}
+
+func TestTenantTestSuite(t *testing.T) {
+	suite.Run(t, new(TenantTestSuite))
+}
+
+// slowMigration tracks how many migrations run at the same time
+type slowMigration struct {
+	migration.DummyMigration
+	running    *atomic.Int32
+	maxRunning *atomic.Int32
+	runIds     *sync.Map
+}
+
+func (m *slowMigration) Up(ctx context.Context, _ any) error {
+	running := m.running.Add(1)
+	defer m.running.Add(-1)
+
+	for {
+		maxRunning := m.maxRunning.Load()
+		if running <= maxRunning || m.maxRunning.CompareAndSwap(maxRunning, running) {
+			break
+		}
+	}
+
+	m.runIds.Store(execution.RunIdFrom(ctx), true)
+	time.Sleep(20 * time.Millisecond)
+	return nil
+}
+
+type heldLocker struct{}
+
+func (l heldLocker) Lock(context.Context) error { return lock.ErrLockHeld }
+
+func (l heldLocker) Unlock(context.Context) error { return lock.ErrNotLocked }
+
+type countingLocker struct {
+	locks   *atomic.Int32
+	unlocks *atomic.Int32
+}
+
+func (l countingLocker) Lock(context.Context) error {
+	l.locks.Add(1)
+	return nil
+}
+
+func (l countingLocker) Unlock(context.Context) error {
+	l.unlocks.Add(1)
+	return nil
+}
+
+// remoteLocker fails to release the lock when its context is done, like the lockers of the
+// remote stores (Redis, Consul, etcd)
+type remoteLocker struct {
+	unlockErr error
+	unlocked  *atomic.Bool
+}
+
+func (l remoteLocker) Lock(context.Context) error { return nil }
+
+func (l remoteLocker) Unlock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.unlocked.Store(true)
+	return l.unlockErr
+}
+
+func (suite *TenantTestSuite) TestItMigratesTenantsConcurrentlyWithinTheParallelismLimit() {
+	var running, maxRunning, locks, unlocks atomic.Int32
+	var runIds sync.Map
+
+	var tenants []Tenant
+	for _, name := range []string{"t1", "t2", "t3", "t4", "t5"} {
+		registry := migration.NewGenericRegistry()
+		_ = registry.Register(
+			&slowMigration{*migration.NewDummyMigration(1), &running, &maxRunning, &runIds},
+		)
+		migrationsHandler, _ := handler.NewHandler(
+			registry, &execution.InMemoryRepository{}, nil,
+		)
+		tenants = append(tenants, Tenant{name, migrationsHandler})
+	}
+
+	var lockedTenants sync.Map
+	summary := NewRunner(
+		tenants, 2, func(tenant string) lock.Locker {
+			lockedTenants.Store(tenant, true)
+			return countingLocker{&locks, &unlocks}
+		},
+	).Run(context.Background(), Up(handler.NumOfRuns(1)))
+
+	suite.Assert().NoError(summary.Err())
+	suite.Assert().Equal(5, summary.Succeeded)
+	suite.Assert().Equal(5, summary.MigrationsCount)
+	suite.Assert().Equal(int32(2), maxRunning.Load())
+	suite.Assert().Equal(int32(5), locks.Load())
+	suite.Assert().Equal(int32(5), unlocks.Load())
+	for i, result := range summary.Results {
+		suite.Assert().Equal(tenants[i].Name, result.Tenant)
+		_, locked := lockedTenants.Load(result.Tenant)
+		suite.Assert().True(locked)
+	}
+
+	// all tenants share the same run ID
+	runIdsCount := 0
+	runIds.Range(
+		func(_, _ any) bool {
+			runIdsCount++
+			return true
+		},
+	)
+	suite.Assert().Equal(1, runIdsCount)
+}
+
+func (suite *TenantTestSuite) TestItAggregatesFailedAndSkippedTenants() {
+	newHandler := func(repo *execution.InMemoryRepository) *handler.MigrationsHandler {
+		registry := migration.NewGenericRegistry()
+		_ = registry.Register(migration.NewDummyMigration(1))
+		migrationsHandler, _ := handler.NewHandler(registry, repo, nil)
+		return migrationsHandler
+	}
+
+	saveErr := errors.New("save failed")
+	tenants := []Tenant{
+		{"ok", newHandler(&execution.InMemoryRepository{})},
+		{"failing", newHandler(&execution.InMemoryRepository{SaveErr: saveErr})},
+		{"locked", newHandler(&execution.InMemoryRepository{})},
+	}
+
+	summary := NewRunner(
+		tenants, 0, func(tenant string) lock.Locker {
+			if tenant == "locked" {
+				return heldLocker{}
+			}
+			return nil
+		},
+	).Run(context.Background(), Up(handler.NumOfRuns(1)))
+
+	suite.Assert().Equal(1, summary.Succeeded)
+	suite.Assert().Equal(1, summary.Failed)
+	suite.Assert().Equal(1, summary.Skipped)
+	suite.Assert().True(summary.Results[2].Skipped)
+	suite.Assert().ErrorIs(summary.Err(), saveErr)
+	suite.Assert().ErrorContains(summary.Err(), "tenant failing: ")
+}
+
+func (suite *TenantTestSuite) TestItReleasesTheTenantLocksOfTheCancelledRuns() {
+	registry := migration.NewGenericRegistry()
+	_ = registry.Register(migration.NewDummyMigration(1))
+	migrationsHandler, _ := handler.NewHandler(registry, &execution.InMemoryRepository{}, nil)
+	tenants := []Tenant{{"t1", migrationsHandler}}
+
+	var unlocked atomic.Bool
+	ctx, cancel := context.WithCancel(context.Background())
+	summary := NewRunner(
+		tenants, 1, func(string) lock.Locker { return remoteLocker{unlocked: &unlocked} },
+	).Run(
+		ctx, func(
+			ctx context.Context, _ *handler.MigrationsHandler,
+		) ([]handler.ExecutedMigration, error) {
+			cancel()
+			return nil, ctx.Err()
+		},
+	)
+
+	suite.Assert().True(unlocked.Load())
+	suite.Assert().ErrorIs(summary.Err(), context.Canceled)
+	suite.Assert().NotContains(summary.Err().Error(), "release")
+
+	unlockErr := errors.New("connection reset")
+	summary = NewRunner(
+		tenants, 1, func(string) lock.Locker {
+			return remoteLocker{unlockErr: unlockErr, unlocked: &unlocked}
+		},
+	).Run(context.Background(), Up(handler.NumOfRuns(1)))
+
+	suite.Assert().Equal(1, summary.Failed)
+	suite.Assert().ErrorIs(summary.Err(), unlockErr)
+	suite.Assert().ErrorContains(summary.Err(), "failed to release tenant lock")
+}